@@ -111,6 +111,31 @@ type TableName struct {
 	TableInfo *model.TableInfo
 
 	IndexHints []*IndexHint
+
+	// AsOf is the AS OF TIMESTAMP clause, the table is read at the timestamp if it's not nil.
+	AsOf *AsOfClause
+}
+
+// AsOfClause is the AS OF TIMESTAMP clause of a stale read.
+type AsOfClause struct {
+	node
+
+	TsExpr ExprNode
+}
+
+// Accept implements Node Accept interface.
+func (n *AsOfClause) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*AsOfClause)
+	node, ok := n.TsExpr.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.TsExpr = node.(ExprNode)
+	return v.Leave(n)
 }
 
 // IndexHintType is the type for index hint use, ignore or force.
@@ -148,6 +173,13 @@ func (n *TableName) Accept(v Visitor) (Node, bool) {
 		return v.Leave(newNode)
 	}
 	n = newNode.(*TableName)
+	if n.AsOf != nil {
+		node, ok := n.AsOf.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.AsOf = node.(*AsOfClause)
+	}
 	return v.Leave(n)
 }
 
@@ -940,6 +972,7 @@ const (
 	ShowProcessList
 	ShowCreateDatabase
 	ShowEvents
+	ShowBindings
 )
 
 // ShowStmt is a statement to provide information about databases, tables, columns and so on.
@@ -955,6 +988,8 @@ type ShowStmt struct {
 	Flag   int         // Some flag parsed from sql, such as FULL.
 	Full   bool
 	User   string // Used for show grants.
+	// Roles are the roles whose privileges are shown with the user in SHOW GRANTS ... USING.
+	Roles []string
 
 	// Used by show variables
 	GlobalScope bool
@@ -218,6 +218,7 @@ const (
 	Collation    = "collation"
 	ConnectionID = "connection_id"
 	CurrentUser  = "current_user"
+	CurrentRole  = "current_role"
 	Database     = "database"
 	FoundRows    = "found_rows"
 	LastInsertId = "last_insert_id"
@@ -276,6 +277,12 @@ const (
 	Uncompress               = "uncompress"
 	UncompressedLength       = "uncompressed_length"
 	ValidatePasswordStrength = "validate_password_strength"
+
+	// data masking functions
+	MaskEmail = "mask_email"
+	MaskInner = "mask_inner"
+	MaskNull  = "mask_null"
+	MaskOuter = "mask_outer"
 )
 
 // FuncCallExpr is for function expression.
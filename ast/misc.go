@@ -27,15 +27,23 @@ var (
 	_ StmtNode = &BeginStmt{}
 	_ StmtNode = &BinlogStmt{}
 	_ StmtNode = &CommitStmt{}
+	_ StmtNode = &CreateBindingStmt{}
+	_ StmtNode = &CreateMaskingPolicyStmt{}
 	_ StmtNode = &CreateUserStmt{}
 	_ StmtNode = &DeallocateStmt{}
 	_ StmtNode = &DoStmt{}
+	_ StmtNode = &DropBindingStmt{}
+	_ StmtNode = &DropMaskingPolicyStmt{}
 	_ StmtNode = &ExecuteStmt{}
 	_ StmtNode = &ExplainStmt{}
 	_ StmtNode = &GrantStmt{}
+	_ StmtNode = &GrantRoleStmt{}
 	_ StmtNode = &PrepareStmt{}
+	_ StmtNode = &RevokeRoleStmt{}
 	_ StmtNode = &RollbackStmt{}
+	_ StmtNode = &SetDefaultRoleStmt{}
 	_ StmtNode = &SetPwdStmt{}
+	_ StmtNode = &SetRoleStmt{}
 	_ StmtNode = &SetStmt{}
 	_ StmtNode = &UseStmt{}
 	_ StmtNode = &AnalyzeTableStmt{}
@@ -65,7 +73,8 @@ type AuthOption struct {
 	ByAuthString bool
 	AuthString   string
 	HashString   string
-	// TODO: support auth_plugin
+	// AuthPlugin is the auth plugin specified by IDENTIFIED WITH, it is empty if not specified.
+	AuthPlugin string
 }
 
 // ExplainStmt is a statement to provide information about how is SQL statement executed
@@ -164,10 +173,23 @@ func (n *ExecuteStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// Transaction modes of BeginStmt.
+const (
+	OptimisticMode  = "optimistic"
+	PessimisticMode = "pessimistic"
+)
+
 // BeginStmt is a statement to start a new transaction.
 // See https://dev.mysql.com/doc/refman/5.7/en/commit.html
 type BeginStmt struct {
 	stmtNode
+
+	// Mode is the transaction mode, the tidb_txn_mode variable decides the mode if it's empty.
+	Mode string
+	// ReadOnly is true if the transaction can't write.
+	ReadOnly bool
+	// AsOf is the AS OF TIMESTAMP clause of a read only transaction, which reads the data at the timestamp.
+	AsOf *AsOfClause
 }
 
 // Accept implements Node Accept interface.
@@ -177,6 +199,13 @@ func (n *BeginStmt) Accept(v Visitor) (Node, bool) {
 		return v.Leave(newNode)
 	}
 	n = newNode.(*BeginStmt)
+	if n.AsOf != nil {
+		node, ok := n.AsOf.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.AsOf = node.(*AsOfClause)
+	}
 	return v.Leave(n)
 }
 
@@ -323,12 +352,10 @@ type KillStmt struct {
 	Query        bool
 	ConnectionID uint64
 	// When the SQL grammar is "KILL TIDB [CONNECTION | QUERY] connectionID", TiDBExtension will be set.
-	// It's a special grammar extension in TiDB. This extension exists because, when the connection is:
-	// client -> LVS proxy -> TiDB, and type Ctrl+C in client, the following action will be executed:
-	// new a connection; kill xxx;
-	// kill command may send to the wrong TiDB, because the exists of LVS proxy, and kill the wrong session.
-	// So, "KILL TIDB" grammar is introduced, and it REQUIRES DIRECT client -> TiDB TOPOLOGY.
-	// TODO: The standard KILL grammar will be supported once we have global connectionID.
+	// It's a special grammar extension in TiDB, it only kills the connection of the TiDB which the
+	// statement is sent to. The connection IDs are unique in the cluster, and the standard KILL grammar
+	// kills the connection of any TiDB in the cluster, so it works when the connection is:
+	// client -> LVS proxy -> TiDB.
 	TiDBExtension bool
 }
 
@@ -406,19 +433,129 @@ func (n *SetPwdStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// SetRoleStmtType is the type of the roles in SET ROLE and SET DEFAULT ROLE statements.
+type SetRoleStmtType int
+
+// SetRole statement types.
+const (
+	// SetRoleDefault activates the default roles of the user.
+	SetRoleDefault SetRoleStmtType = iota
+	// SetRoleNone deactivates all the roles.
+	SetRoleNone
+	// SetRoleAll activates all the roles granted to the user.
+	SetRoleAll
+	// SetRoleAllExcept activates all the roles granted to the user except RoleList.
+	SetRoleAllExcept
+	// SetRoleRegular activates the roles in RoleList.
+	SetRoleRegular
+)
+
+// SetRoleStmt is the statement to change the active roles of the current session.
+// See https://dev.mysql.com/doc/refman/8.0/en/set-role.html
+type SetRoleStmt struct {
+	stmtNode
+
+	SetRoleOpt SetRoleStmtType
+	RoleList   []string
+}
+
+// Accept implements Node Accept interface.
+func (n *SetRoleStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*SetRoleStmt)
+	return v.Leave(n)
+}
+
+// SetDefaultRoleStmt is the statement to set the roles which are activated when the users log in,
+// SetRoleOpt is one of SetRoleNone, SetRoleAll and SetRoleRegular.
+// See https://dev.mysql.com/doc/refman/8.0/en/set-default-role.html
+type SetDefaultRoleStmt struct {
+	stmtNode
+
+	SetRoleOpt SetRoleStmtType
+	RoleList   []string
+	UserList   []string
+}
+
+// Accept implements Node Accept interface.
+func (n *SetDefaultRoleStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*SetDefaultRoleStmt)
+	return v.Leave(n)
+}
+
 // UserSpec is used for parsing create user statement.
 type UserSpec struct {
 	User    string
 	AuthOpt *AuthOption
 }
 
+// RequireType is the TLS requirement of the user account in the REQUIRE clause.
+type RequireType int
+
+// Require types.
+const (
+	// RequireUnspecified means there is no REQUIRE clause.
+	RequireUnspecified RequireType = iota
+	// RequireNone is REQUIRE NONE, the user can connect without TLS.
+	RequireNone
+	// RequireSSL is REQUIRE SSL, the user must connect with TLS.
+	RequireSSL
+	// RequireX509 is REQUIRE X509, the user must connect with TLS and a valid client certificate.
+	RequireX509
+)
+
+// PasswordOrLockOptionType is the type of the password expiration or account locking option.
+type PasswordOrLockOptionType int
+
+// Password expiration and account locking option types.
+const (
+	// PasswordExpire is PASSWORD EXPIRE, the password is marked expired immediately.
+	PasswordExpire PasswordOrLockOptionType = iota + 1
+	// PasswordExpireDefault is PASSWORD EXPIRE DEFAULT, the password lifetime is default_password_lifetime.
+	PasswordExpireDefault
+	// PasswordExpireNever is PASSWORD EXPIRE NEVER, the password never expires.
+	PasswordExpireNever
+	// PasswordExpireInterval is PASSWORD EXPIRE INTERVAL n DAY, the password lifetime is n days.
+	PasswordExpireInterval
+	// Lock is ACCOUNT LOCK.
+	Lock
+	// Unlock is ACCOUNT UNLOCK.
+	Unlock
+	// FailedLoginAttempts is FAILED_LOGIN_ATTEMPTS n, the account is locked temporarily after n
+	// consecutive failed logins, 0 disables the tracking.
+	FailedLoginAttempts
+	// PasswordLockTime is PASSWORD_LOCK_TIME n, the account is locked for n days after too many failed logins.
+	PasswordLockTime
+	// PasswordLockTimeUnbounded is PASSWORD_LOCK_TIME UNBOUNDED, the account stays locked until it's unlocked.
+	PasswordLockTimeUnbounded
+)
+
+// PasswordOrLockOption is the password expiration or account locking option of CREATE USER and ALTER USER.
+// See https://dev.mysql.com/doc/refman/8.0/en/create-user.html#create-user-password-management
+type PasswordOrLockOption struct {
+	Type PasswordOrLockOptionType
+	// Count is the number of days or failed logins.
+	Count int64
+}
+
 // CreateUserStmt creates user account.
 // See https://dev.mysql.com/doc/refman/5.7/en/create-user.html
 type CreateUserStmt struct {
 	stmtNode
 
-	IfNotExists bool
-	Specs       []*UserSpec
+	// IsCreateRole is true for CREATE ROLE statement, the roles are the locked accounts without password.
+	IsCreateRole          bool
+	IfNotExists           bool
+	Specs                 []*UserSpec
+	Require               RequireType
+	PasswordOrLockOptions []*PasswordOrLockOption
 }
 
 // Accept implements Node Accept interface.
@@ -436,9 +573,10 @@ func (n *CreateUserStmt) Accept(v Visitor) (Node, bool) {
 type AlterUserStmt struct {
 	stmtNode
 
-	IfExists    bool
-	CurrentAuth *AuthOption
-	Specs       []*UserSpec
+	IfExists              bool
+	CurrentAuth           *AuthOption
+	Specs                 []*UserSpec
+	PasswordOrLockOptions []*PasswordOrLockOption
 }
 
 // Accept implements Node Accept interface.
@@ -456,8 +594,10 @@ func (n *AlterUserStmt) Accept(v Visitor) (Node, bool) {
 type DropUserStmt struct {
 	stmtNode
 
-	IfExists bool
-	UserList []string
+	// IsDropRole is true for DROP ROLE statement.
+	IsDropRole bool
+	IfExists   bool
+	UserList   []string
 }
 
 // Accept implements Node Accept interface.
@@ -470,6 +610,103 @@ func (n *DropUserStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// CreateBindingStmt creates a SQL binding, which pins the hints of HintedSel
+// to the statements that have the same normalized form as OriginSel.
+type CreateBindingStmt struct {
+	stmtNode
+
+	GlobalScope bool
+	OriginSel   *SelectStmt
+	HintedSel   *SelectStmt
+}
+
+// Accept implements Node Accept interface.
+func (n *CreateBindingStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*CreateBindingStmt)
+	node, ok := n.OriginSel.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.OriginSel = node.(*SelectStmt)
+	node, ok = n.HintedSel.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.HintedSel = node.(*SelectStmt)
+	return v.Leave(n)
+}
+
+// DropBindingStmt drops the SQL binding of OriginSel.
+type DropBindingStmt struct {
+	stmtNode
+
+	GlobalScope bool
+	OriginSel   *SelectStmt
+}
+
+// Accept implements Node Accept interface.
+func (n *DropBindingStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	// OriginSel is only used to find the binding, the tables in it may have been dropped,
+	// so we don't visit it to avoid resolving error.
+	n = newNode.(*DropBindingStmt)
+	return v.Leave(n)
+}
+
+// CreateMaskingPolicyStmt creates a masking policy, which replaces Column of Table by MaskExpr
+// in the queries of the users that aren't exempted by ExemptRoles.
+type CreateMaskingPolicyStmt struct {
+	stmtNode
+
+	IfNotExists bool
+	Name        string
+	Table       *TableName
+	Column      *ColumnName
+	MaskExpr    ExprNode
+	ExemptRoles []string
+}
+
+// Accept implements Node Accept interface.
+func (n *CreateMaskingPolicyStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*CreateMaskingPolicyStmt)
+	node, ok := n.Table.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Table = node.(*TableName)
+	// MaskExpr refers to Column, which isn't in any result set, so it's checked when the policy is created.
+	return v.Leave(n)
+}
+
+// DropMaskingPolicyStmt drops a masking policy.
+type DropMaskingPolicyStmt struct {
+	stmtNode
+
+	IfExists bool
+	Name     string
+}
+
+// Accept implements Node Accept interface.
+func (n *DropMaskingPolicyStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*DropMaskingPolicyStmt)
+	return v.Leave(n)
+}
+
 // DoStmt is the struct for DO statement.
 type DoStmt struct {
 	stmtNode
@@ -621,6 +858,7 @@ type GrantStmt struct {
 	ObjectType ObjectTypeType
 	Level      *GrantLevel
 	Users      []*UserSpec
+	Require    RequireType
 	WithGrant  bool
 }
 
@@ -716,3 +954,41 @@ func (n *TableOptimizerHint) Accept(v Visitor) (Node, bool) {
 	n = newNode.(*TableOptimizerHint)
 	return v.Leave(n)
 }
+
+// GrantRoleStmt is the statement to grant roles to users.
+// See https://dev.mysql.com/doc/refman/8.0/en/grant.html#grant-roles
+type GrantRoleStmt struct {
+	stmtNode
+
+	Roles []string
+	Users []string
+}
+
+// Accept implements Node Accept interface.
+func (n *GrantRoleStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*GrantRoleStmt)
+	return v.Leave(n)
+}
+
+// RevokeRoleStmt is the statement to revoke roles from users.
+// See https://dev.mysql.com/doc/refman/8.0/en/revoke.html#revoke-roles
+type RevokeRoleStmt struct {
+	stmtNode
+
+	Roles []string
+	Users []string
+}
+
+// Accept implements Node Accept interface.
+func (n *RevokeRoleStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*RevokeRoleStmt)
+	return v.Leave(n)
+}
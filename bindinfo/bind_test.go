@@ -0,0 +1,114 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bindinfo
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	CustomVerboseFlag = true
+	TestingT(t)
+}
+
+var _ = Suite(&testBindSuite{})
+
+type testBindSuite struct {
+}
+
+func parseSelect(c *C, sql string) *ast.SelectStmt {
+	stmt, err := parser.New().ParseOneStmt(sql, "", "")
+	c.Assert(err, IsNil)
+	return stmt.(*ast.SelectStmt)
+}
+
+func (s *testBindSuite) TestApplyHints(c *C) {
+	defer testleak.AfterTest(c)()
+	origin := parseSelect(c, "select * from t1, t2 where t1.a = t2.a and t1.b in (select b from t3)")
+	hinted := parseSelect(c, "select /*+ TIDB_INLJ(t1) */ * from t1 use index(idx), t2 where t1.a = t2.a and t1.b in (select /*+ TIDB_SMJ(t3) */ b from t3 ignore index(idx))")
+	c.Assert(CheckBinding(origin, hinted), IsNil)
+
+	restore, err := ApplyHints(origin, hinted)
+	c.Assert(err, IsNil)
+	c.Assert(origin.TableHints, HasLen, 1)
+	c.Assert(origin.TableHints[0].HintName.L, Equals, "tidb_inlj")
+	var nodes hintNodes
+	origin.Accept(&nodes)
+	c.Assert(nodes.sels, HasLen, 2)
+	c.Assert(nodes.sels[1].TableHints[0].HintName.L, Equals, "tidb_smj")
+	c.Assert(nodes.tables, HasLen, 3)
+	c.Assert(nodes.tables[0].IndexHints, HasLen, 1)
+	c.Assert(nodes.tables[0].IndexHints[0].HintType, Equals, ast.HintUse)
+	c.Assert(nodes.tables[1].IndexHints, HasLen, 0)
+	c.Assert(nodes.tables[2].IndexHints[0].HintType, Equals, ast.HintIgnore)
+
+	restore()
+	c.Assert(origin.TableHints, HasLen, 0)
+	for _, sel := range nodes.sels {
+		c.Assert(sel.TableHints, HasLen, 0)
+	}
+	for _, tn := range nodes.tables {
+		c.Assert(tn.IndexHints, HasLen, 0)
+	}
+}
+
+func (s *testBindSuite) TestCheckBinding(c *C) {
+	defer testleak.AfterTest(c)()
+	tests := []struct {
+		origin string
+		hinted string
+		ok     bool
+	}{
+		{"select * from t", "select * from t use index(idx)", true},
+		{"select * from t where a = 1", "select * from test.t where a = 2", true},
+		{"select * from t", "select * from t1", false},
+		{"select * from t", "select * from t, t1", false},
+		{"select * from db1.t", "select * from db2.t", false},
+		{"select * from t where a in (select a from t1)", "select * from t where a = 1", false},
+	}
+	for _, t := range tests {
+		err := CheckBinding(parseSelect(c, t.origin), parseSelect(c, t.hinted))
+		if t.ok {
+			c.Assert(err, IsNil, Commentf("origin %s hinted %s", t.origin, t.hinted))
+		} else {
+			c.Assert(ErrBindingMismatch.Equal(err), IsTrue, Commentf("origin %s hinted %s", t.origin, t.hinted))
+		}
+	}
+}
+
+func (s *testBindSuite) TestSessionHandle(c *C) {
+	defer testleak.AfterTest(c)()
+	h := NewSessionHandle()
+	h.AddBindRecord(&BindRecord{OriginalSQL: "select * from t", BindSQL: "select * from t use index(a)", Db: "test"})
+	h.AddBindRecord(&BindRecord{OriginalSQL: "select * from t", BindSQL: "select * from t use index(b)", Db: "test"})
+	h.AddBindRecord(&BindRecord{OriginalSQL: "select * from t", BindSQL: "select * from t use index(a)", Db: "db"})
+	c.Assert(h.Get("select * from t", "test").BindSQL, Equals, "select * from t use index(b)")
+	c.Assert(h.Get("select * from t1", "test"), IsNil)
+	records := h.GetAll()
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[0].Db, Equals, "db")
+	h.DropBindRecord("select * from t", "test")
+	c.Assert(h.Get("select * from t", "test"), IsNil)
+	c.Assert(h.GetAll(), HasLen, 1)
+}
+
+func (s *testBindSuite) TestQuote(c *C) {
+	defer testleak.AfterTest(c)()
+	c.Assert(quote(`select * from t where a = 'x\y'`), Equals, `'select * from t where a = \'x\\y\''`)
+}
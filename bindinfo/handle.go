@@ -0,0 +1,273 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bindinfo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/util/sqlexec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// BindRecord is a SQL binding, the hints of BindSQL are applied to the statements
+// whose normalized form is OriginalSQL when the current database is Db.
+type BindRecord struct {
+	OriginalSQL string
+	BindSQL     string
+	Db          string
+	Charset     string
+	Collation   string
+	CreateTime  types.Time
+
+	// HintedSel is the parsed BindSQL.
+	HintedSel *ast.SelectStmt
+}
+
+type bindKey struct {
+	originalSQL string
+	db          string
+}
+
+// bindCache maps the normalized SQL and database to the binding.
+// It is never modified after being published, updating makes a copy.
+type bindCache map[bindKey]*BindRecord
+
+func (c bindCache) copy() bindCache {
+	newCache := make(bindCache, len(c))
+	for k, v := range c {
+		newCache[k] = v
+	}
+	return newCache
+}
+
+func (c bindCache) records() []*BindRecord {
+	records := make([]*BindRecord, 0, len(c))
+	for _, record := range c {
+		records = append(records, record)
+	}
+	sort.Sort(byOriginalSQL(records))
+	return records
+}
+
+type byOriginalSQL []*BindRecord
+
+func (s byOriginalSQL) Len() int      { return len(s) }
+func (s byOriginalSQL) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byOriginalSQL) Less(i, j int) bool {
+	if s[i].Db != s[j].Db {
+		return s[i].Db < s[j].Db
+	}
+	return s[i].OriginalSQL < s[j].OriginalSQL
+}
+
+// Handle caches the global SQL bindings stored in mysql.bind_info.
+type Handle struct {
+	ctx   context.Context
+	cache atomic.Value
+	// mu serializes the writers of cache.
+	mu sync.Mutex
+}
+
+// NewHandle creates a Handle, ctx is used to load the bindings.
+func NewHandle(ctx context.Context) *Handle {
+	h := &Handle{ctx: ctx}
+	h.cache.Store(make(bindCache))
+	return h
+}
+
+func (h *Handle) get() bindCache {
+	return h.cache.Load().(bindCache)
+}
+
+// Update reloads all the global bindings from mysql.bind_info.
+func (h *Handle) Update() error {
+	sql := fmt.Sprintf("SELECT original_sql, bind_sql, default_db, charset, collation, create_time FROM %s.%s",
+		mysql.SystemDB, mysql.BindInfoTable)
+	tmp, err := h.ctx.(sqlexec.SQLExecutor).Execute(sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	rs := tmp[0]
+	defer rs.Close()
+
+	newCache := make(bindCache)
+	p := parser.New()
+	for {
+		row, err := rs.Next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		record := &BindRecord{
+			OriginalSQL: row.Data[0].GetString(),
+			BindSQL:     row.Data[1].GetString(),
+			Db:          row.Data[2].GetString(),
+			Charset:     row.Data[3].GetString(),
+			Collation:   row.Data[4].GetString(),
+			CreateTime:  row.Data[5].GetMysqlTime(),
+		}
+		record.HintedSel, err = parseBindSQL(p, record)
+		if err != nil {
+			// A broken binding shouldn't stop the others from being loaded.
+			log.Errorf("[bindinfo] parse bind sql %s error %v", record.BindSQL, err)
+			continue
+		}
+		newCache[bindKey{record.OriginalSQL, record.Db}] = record
+	}
+	h.mu.Lock()
+	h.cache.Store(newCache)
+	h.mu.Unlock()
+	return nil
+}
+
+func parseBindSQL(p *parser.Parser, record *BindRecord) (*ast.SelectStmt, error) {
+	stmt, err := p.ParseOneStmt(record.BindSQL, record.Charset, record.Collation)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	sel, ok := stmt.(*ast.SelectStmt)
+	if !ok {
+		return nil, errors.Errorf("bind sql %s is not a select statement", record.BindSQL)
+	}
+	return sel, nil
+}
+
+// Get returns the global binding of the normalized SQL in database db, nil if it doesn't exist.
+func (h *Handle) Get(normalizedSQL, db string) *BindRecord {
+	return h.get()[bindKey{normalizedSQL, db}]
+}
+
+// GetAll returns all the global bindings.
+func (h *Handle) GetAll() []*BindRecord {
+	return h.get().records()
+}
+
+// AddBindRecord stores the binding into mysql.bind_info through ctx, replacing the
+// existing binding of the same statement, and adds it to the cache.
+func (h *Handle) AddBindRecord(ctx context.Context, record *BindRecord) error {
+	exec := ctx.(sqlexec.SQLExecutor)
+	_, err := exec.Execute(deleteBindSQL(record.OriginalSQL, record.Db))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	sql := fmt.Sprintf("INSERT INTO %s.%s (original_sql, bind_sql, default_db, charset, collation, create_time) VALUES (%s, %s, %s, %s, %s, %s)",
+		mysql.SystemDB, mysql.BindInfoTable,
+		quote(record.OriginalSQL), quote(record.BindSQL), quote(record.Db),
+		quote(record.Charset), quote(record.Collation), quote(record.CreateTime.String()))
+	_, err = exec.Execute(sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	h.mu.Lock()
+	newCache := h.get().copy()
+	newCache[bindKey{record.OriginalSQL, record.Db}] = record
+	h.cache.Store(newCache)
+	h.mu.Unlock()
+	return nil
+}
+
+// DropBindRecord removes the binding of the normalized SQL in database db from
+// mysql.bind_info through ctx, and removes it from the cache.
+func (h *Handle) DropBindRecord(ctx context.Context, normalizedSQL, db string) error {
+	_, err := ctx.(sqlexec.SQLExecutor).Execute(deleteBindSQL(normalizedSQL, db))
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	h.mu.Lock()
+	newCache := h.get().copy()
+	delete(newCache, bindKey{normalizedSQL, db})
+	h.cache.Store(newCache)
+	h.mu.Unlock()
+	return nil
+}
+
+func deleteBindSQL(normalizedSQL, db string) string {
+	return fmt.Sprintf("DELETE FROM %s.%s WHERE original_sql = %s AND default_db = %s",
+		mysql.SystemDB, mysql.BindInfoTable, quote(normalizedSQL), quote(db))
+}
+
+// quote returns s as a quoted SQL string literal.
+func quote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `'`, `\'`, -1)
+	return "'" + s + "'"
+}
+
+// SessionHandle caches the SQL bindings that only take effect in one session.
+type SessionHandle struct {
+	cache bindCache
+}
+
+// NewSessionHandle creates a SessionHandle.
+func NewSessionHandle() *SessionHandle {
+	return &SessionHandle{cache: make(bindCache)}
+}
+
+// Get returns the session binding of the normalized SQL in database db, nil if it doesn't exist.
+func (h *SessionHandle) Get(normalizedSQL, db string) *BindRecord {
+	return h.cache[bindKey{normalizedSQL, db}]
+}
+
+// GetAll returns all the session bindings.
+func (h *SessionHandle) GetAll() []*BindRecord {
+	return h.cache.records()
+}
+
+// AddBindRecord adds the binding, replacing the existing binding of the same statement.
+func (h *SessionHandle) AddBindRecord(record *BindRecord) {
+	h.cache[bindKey{record.OriginalSQL, record.Db}] = record
+}
+
+// DropBindRecord removes the binding of the normalized SQL in database db.
+func (h *SessionHandle) DropBindRecord(normalizedSQL, db string) {
+	delete(h.cache, bindKey{normalizedSQL, db})
+}
+
+// A dummy type to avoid naming collision in context.
+type sessionHandleKeyType int
+
+// String defines a Stringer function for debugging and pretty printing.
+func (k sessionHandleKeyType) String() string {
+	return "session_bindinfo"
+}
+
+const sessionHandleKey sessionHandleKeyType = 0
+
+// BindSessionHandle binds the session bindings to context.
+func BindSessionHandle(ctx context.Context, h *SessionHandle) {
+	ctx.SetValue(sessionHandleKey, h)
+}
+
+// GetSessionHandle gets the session bindings from context.
+func GetSessionHandle(ctx context.Context) *SessionHandle {
+	h, ok := ctx.Value(sessionHandleKey).(*SessionHandle)
+	if !ok {
+		return nil
+	}
+	return h
+}
@@ -0,0 +1,117 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bindinfo
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/terror"
+)
+
+// Error codes.
+const (
+	codeBindingMismatch terror.ErrCode = 1
+)
+
+// ErrBindingMismatch is returned when the hinted statement of a binding doesn't
+// match the original statement.
+var ErrBindingMismatch = terror.ClassBindInfo.New(codeBindingMismatch,
+	"the bind sql doesn't match the original sql, they should select from the same tables in the same structure")
+
+func init() {
+	bindInfoMySQLErrCodes := map[terror.ErrCode]uint16{
+		codeBindingMismatch: mysql.ErrUnknown,
+	}
+	terror.ErrClassToMySQLCodes[terror.ClassBindInfo] = bindInfoMySQLErrCodes
+}
+
+// hintNodes collects the nodes that carry optimizer hints in visiting order.
+type hintNodes struct {
+	sels   []*ast.SelectStmt
+	tables []*ast.TableName
+}
+
+// Enter implements ast.Visitor interface.
+func (h *hintNodes) Enter(in ast.Node) (ast.Node, bool) {
+	switch x := in.(type) {
+	case *ast.SelectStmt:
+		h.sels = append(h.sels, x)
+	case *ast.TableName:
+		h.tables = append(h.tables, x)
+	}
+	return in, false
+}
+
+// Leave implements ast.Visitor interface.
+func (h *hintNodes) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+// matchHintNodes collects the hint carrying nodes of both statements, and checks
+// that they have the same shape, so the hints can be copied one by one.
+func matchHintNodes(origin, hinted *ast.SelectStmt) (*hintNodes, *hintNodes, error) {
+	var originNodes, hintedNodes hintNodes
+	origin.Accept(&originNodes)
+	hinted.Accept(&hintedNodes)
+	if len(originNodes.sels) != len(hintedNodes.sels) || len(originNodes.tables) != len(hintedNodes.tables) {
+		return nil, nil, ErrBindingMismatch
+	}
+	for i, tn := range originNodes.tables {
+		htn := hintedNodes.tables[i]
+		if tn.Name.L != htn.Name.L {
+			return nil, nil, ErrBindingMismatch
+		}
+		// The original statement may be resolved, whose table names are filled with the current database.
+		if tn.Schema.L != "" && htn.Schema.L != "" && tn.Schema.L != htn.Schema.L {
+			return nil, nil, ErrBindingMismatch
+		}
+	}
+	return &originNodes, &hintedNodes, nil
+}
+
+// CheckBinding checks whether the hints of hinted can be applied to origin.
+func CheckBinding(origin, hinted *ast.SelectStmt) error {
+	_, _, err := matchHintNodes(origin, hinted)
+	return errors.Trace(err)
+}
+
+// ApplyHints replaces the optimizer hints and index hints of origin with the ones
+// of hinted. Only the hints are taken from hinted, so the semantic of origin never
+// changes. It returns a function which restores the hints of origin.
+func ApplyHints(origin, hinted *ast.SelectStmt) (func(), error) {
+	originNodes, hintedNodes, err := matchHintNodes(origin, hinted)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	tableHints := make([][]*ast.TableOptimizerHint, len(originNodes.sels))
+	for i, sel := range originNodes.sels {
+		tableHints[i] = sel.TableHints
+		sel.TableHints = hintedNodes.sels[i].TableHints
+	}
+	indexHints := make([][]*ast.IndexHint, len(originNodes.tables))
+	for i, tn := range originNodes.tables {
+		indexHints[i] = tn.IndexHints
+		tn.IndexHints = hintedNodes.tables[i].IndexHints
+	}
+	restore := func() {
+		for i, sel := range originNodes.sels {
+			sel.TableHints = tableHints[i]
+		}
+		for i, tn := range originNodes.tables {
+			tn.IndexHints = indexHints[i]
+		}
+	}
+	return restore, nil
+}
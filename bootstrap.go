@@ -51,6 +51,18 @@ const (
 		Execute_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
 		Index_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
 		Create_user_priv	ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		Process_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		Reload_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		File_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		ssl_type		ENUM('','ANY','X509','SPECIFIED') NOT NULL  DEFAULT '',
+		plugin			CHAR(64) NOT NULL  DEFAULT 'mysql_native_password',
+		authentication_string	TEXT,
+		account_locked		ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		password_expired	ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		password_last_changed	TIMESTAMP NULL  DEFAULT NULL,
+		password_lifetime	SMALLINT UNSIGNED NULL  DEFAULT NULL,
+		failed_login_attempts	SMALLINT UNSIGNED NOT NULL  DEFAULT 0,
+		password_lock_time	SMALLINT NOT NULL  DEFAULT 0,
 		PRIMARY KEY (Host, User));`
 	// CreateDBPrivTable is the SQL statement creates DB scope privilege table in system db.
 	CreateDBPrivTable = `CREATE TABLE if not exists mysql.db (
@@ -89,6 +101,21 @@ const (
 		Timestamp	Timestamp DEFAULT CURRENT_TIMESTAMP,
 		Column_priv	SET('Select','Insert','Update'),
 		PRIMARY KEY (Host, DB, User, Table_name, Column_name));`
+	// CreateRoleEdgesTable is the SQL statement creates the table of the roles granted to the users in system db.
+	CreateRoleEdgesTable = `CREATE TABLE if not exists mysql.role_edges (
+		FROM_HOST		CHAR(60) NOT NULL DEFAULT '',
+		FROM_USER		CHAR(32) NOT NULL DEFAULT '',
+		TO_HOST			CHAR(60) NOT NULL DEFAULT '',
+		TO_USER			CHAR(32) NOT NULL DEFAULT '',
+		WITH_ADMIN_OPTION	ENUM('N','Y') NOT NULL DEFAULT 'N',
+		PRIMARY KEY (FROM_HOST, FROM_USER, TO_HOST, TO_USER));`
+	// CreateDefaultRolesTable is the SQL statement creates the table of the roles activated when the users log in.
+	CreateDefaultRolesTable = `CREATE TABLE if not exists mysql.default_roles (
+		HOST			CHAR(60) NOT NULL DEFAULT '',
+		USER			CHAR(32) NOT NULL DEFAULT '',
+		DEFAULT_ROLE_HOST	CHAR(60) NOT NULL DEFAULT '%',
+		DEFAULT_ROLE_USER	CHAR(32) NOT NULL DEFAULT '',
+		PRIMARY KEY (HOST, USER, DEFAULT_ROLE_HOST, DEFAULT_ROLE_USER));`
 	// CreateGloablVariablesTable is the SQL statement creates global variable table in system db.
 	// TODO: MySQL puts GLOBAL_VARIABLES table in INFORMATION_SCHEMA db.
 	// INFORMATION_SCHEMA is a virtual db in TiDB. So we put this table in system db.
@@ -151,6 +178,39 @@ const (
 		value blob NOT NULL,
 		unique index tbl(table_id, is_index, hist_id, bucket_id)
 	);`
+
+	// CreateBindInfoTable stores the global SQL bindings.
+	CreateBindInfoTable = `CREATE TABLE if not exists mysql.bind_info (
+		original_sql text NOT NULL,
+		bind_sql text NOT NULL,
+		default_db text NOT NULL,
+		charset text NOT NULL,
+		collation text NOT NULL,
+		create_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// CreateAuditLogFilterTable stores the filter rules of the audit log.
+	CreateAuditLogFilterTable = `CREATE TABLE if not exists mysql.audit_log_filter (
+		user char(32) NOT NULL DEFAULT '%',
+		db char(64) NOT NULL DEFAULT '%',
+		class char(32) NOT NULL DEFAULT '%',
+		action enum('include','exclude') NOT NULL DEFAULT 'include',
+		PRIMARY KEY (user, db, class)
+	);`
+
+	// CreateMaskingPolicyTable stores the column masking policies, exempt_roles are
+	// the comma separated accounts and roles which see the real values.
+	CreateMaskingPolicyTable = `CREATE TABLE if not exists mysql.masking_policy (
+		name char(64) NOT NULL,
+		db char(64) NOT NULL,
+		table_name char(64) NOT NULL,
+		column_name char(64) NOT NULL,
+		mask_expr text NOT NULL,
+		exempt_roles text NOT NULL,
+		create_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (name),
+		UNIQUE KEY col (db, table_name, column_name)
+	);`
 )
 
 // Bootstrap initiates system DB for a store.
@@ -177,11 +237,19 @@ const (
 	// It is used for getting the version of the TiDB server which bootstrapped the store.
 	tidbServerVersionVar = "tidb_server_version" //
 	// Const for TiDB server version 2.
-	version2 = 2
-	version3 = 3
-	version4 = 4
-	version5 = 5
-	version6 = 6
+	version2  = 2
+	version3  = 3
+	version4  = 4
+	version5  = 5
+	version6  = 6
+	version7  = 7
+	version8  = 8
+	version9  = 9
+	version10 = 10
+	version11 = 11
+	version12 = 12
+	version13 = 13
+	version14 = 14
 )
 
 func checkBootstrapped(s Session) (bool, error) {
@@ -260,6 +328,38 @@ func upgrade(s Session) {
 		upgradeToVer6(s)
 	}
 
+	if ver < version7 {
+		upgradeToVer7(s)
+	}
+
+	if ver < version8 {
+		upgradeToVer8(s)
+	}
+
+	if ver < version9 {
+		upgradeToVer9(s)
+	}
+
+	if ver < version10 {
+		upgradeToVer10(s)
+	}
+
+	if ver < version11 {
+		upgradeToVer11(s)
+	}
+
+	if ver < version12 {
+		upgradeToVer12(s)
+	}
+
+	if ver < version13 {
+		upgradeToVer13(s)
+	}
+
+	if ver < version14 {
+		upgradeToVer14(s)
+	}
+
 	updateBootstrapVer(s)
 	_, err = s.Execute("COMMIT")
 
@@ -320,6 +420,64 @@ func upgradeToVer6(s Session) {
 	s.Execute("UPDATE mysql.user SET Super_priv='Y'")
 }
 
+func upgradeToVer7(s Session) {
+	mustExecute(s, CreateBindInfoTable)
+}
+
+func upgradeToVer8(s Session) {
+	s.Execute("ALTER TABLE mysql.user ADD COLUMN `ssl_type` enum('','ANY','X509','SPECIFIED') CHARACTER SET utf8 NOT NULL DEFAULT '' AFTER `Create_user_priv`")
+}
+
+func upgradeToVer9(s Session) {
+	s.Execute("ALTER TABLE mysql.user ADD COLUMN `plugin` char(64) CHARACTER SET utf8 NOT NULL DEFAULT 'mysql_native_password' AFTER `ssl_type`")
+	s.Execute("ALTER TABLE mysql.user ADD COLUMN `authentication_string` text CHARACTER SET utf8 AFTER `plugin`")
+}
+
+func upgradeToVer10(s Session) {
+	s.Execute("ALTER TABLE mysql.user ADD COLUMN `account_locked` enum('N','Y') CHARACTER SET utf8 NOT NULL DEFAULT 'N' AFTER `authentication_string`")
+	mustExecute(s, CreateRoleEdgesTable)
+	mustExecute(s, CreateDefaultRolesTable)
+}
+
+func upgradeToVer11(s Session) {
+	s.Execute("ALTER TABLE mysql.user ADD COLUMN `password_expired` enum('N','Y') CHARACTER SET utf8 NOT NULL DEFAULT 'N' AFTER `account_locked`")
+	s.Execute("ALTER TABLE mysql.user ADD COLUMN `password_last_changed` timestamp NULL DEFAULT NULL AFTER `password_expired`")
+	s.Execute("ALTER TABLE mysql.user ADD COLUMN `password_lifetime` smallint unsigned NULL DEFAULT NULL AFTER `password_last_changed`")
+	s.Execute("ALTER TABLE mysql.user ADD COLUMN `failed_login_attempts` smallint unsigned NOT NULL DEFAULT 0 AFTER `password_lifetime`")
+	s.Execute("ALTER TABLE mysql.user ADD COLUMN `password_lock_time` smallint NOT NULL DEFAULT 0 AFTER `failed_login_attempts`")
+	// The password variables are empty before version 11, they are set to the default values unless they
+	// have been set.
+	passwordVars := []string{variable.DefaultPasswordLifetime, variable.ValidatePasswordPolicy,
+		variable.ValidatePasswordLength, variable.ValidatePasswordMixedCaseCount, variable.ValidatePasswordNumberCount,
+		variable.ValidatePasswordSpecialCharCount, variable.TiDBValidatePasswordEnable}
+	values := make([]string, 0, len(passwordVars))
+	for _, v := range passwordVars {
+		values = append(values, fmt.Sprintf(`("%s", "%s")`, v, variable.SysVars[v].Value))
+		sql := fmt.Sprintf(`UPDATE %s.%s SET variable_value = "%s" WHERE variable_name = "%s" AND variable_value = "";`,
+			mysql.SystemDB, mysql.GlobalVariablesTable, variable.SysVars[v].Value, v)
+		mustExecute(s, sql)
+	}
+	sql := fmt.Sprintf("INSERT IGNORE INTO %s.%s VALUES %s;", mysql.SystemDB, mysql.GlobalVariablesTable,
+		strings.Join(values, ", "))
+	mustExecute(s, sql)
+}
+
+func upgradeToVer12(s Session) {
+	mustExecute(s, CreateAuditLogFilterTable)
+}
+
+func upgradeToVer13(s Session) {
+	s.Execute("ALTER TABLE mysql.user ADD COLUMN `Process_priv` enum('N','Y') CHARACTER SET utf8 NOT NULL DEFAULT 'N' AFTER `Create_user_priv`")
+	s.Execute("ALTER TABLE mysql.user ADD COLUMN `Reload_priv` enum('N','Y') CHARACTER SET utf8 NOT NULL DEFAULT 'N' AFTER `Process_priv`")
+	s.Execute("ALTER TABLE mysql.user ADD COLUMN `File_priv` enum('N','Y') CHARACTER SET utf8 NOT NULL DEFAULT 'N' AFTER `Reload_priv`")
+	// The super users could do everything the new privileges allow before, so they keep the abilities.
+	mustExecute(s, "UPDATE mysql.user SET Process_priv='Y', Reload_priv='Y', File_priv='Y' WHERE Super_priv='Y'")
+}
+
+func upgradeToVer14(s Session) {
+	mustExecute(s, CreateMaskingPolicyTable)
+}
+
 // Update boostrap version variable in mysql.TiDB table.
 func updateBootstrapVer(s Session) {
 	// Update bootstrap version.
@@ -352,6 +510,9 @@ func doDDLWorks(s Session) {
 	mustExecute(s, CreateDBPrivTable)
 	mustExecute(s, CreateTablePrivTable)
 	mustExecute(s, CreateColumnPrivTable)
+	// Create role tables.
+	mustExecute(s, CreateRoleEdgesTable)
+	mustExecute(s, CreateDefaultRolesTable)
 	// Create global system variable table.
 	mustExecute(s, CreateGloablVariablesTable)
 	// Create TiDB table.
@@ -364,6 +525,12 @@ func doDDLWorks(s Session) {
 	mustExecute(s, CreateStatsColsTable)
 	// Create stats_buckets table.
 	mustExecute(s, CreateStatsBucketsTable)
+	// Create bind_info table.
+	mustExecute(s, CreateBindInfoTable)
+	// Create audit_log_filter table.
+	mustExecute(s, CreateAuditLogFilterTable)
+	// Create masking_policy table.
+	mustExecute(s, CreateMaskingPolicyTable)
 }
 
 // Execute DML statements in bootstrap stage.
@@ -373,7 +540,7 @@ func doDMLWorks(s Session) {
 
 	// Insert a default user with empty password.
 	mustExecute(s, `INSERT INTO mysql.user VALUES
-		("%", "root", "", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")`)
+		("%", "root", "", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "", "mysql_native_password", "", "N", "N", NULL, NULL, 0, 0)`)
 
 	// Init global system variables table.
 	values := make([]string, 0, len(variable.SysVars))
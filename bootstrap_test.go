@@ -48,7 +48,7 @@ func (s *testBootstrapSuite) TestBootstrap(c *C) {
 	row, err := r.Next()
 	c.Assert(err, IsNil)
 	c.Assert(row, NotNil)
-	match(c, row.Data, []byte("%"), []byte("root"), []byte(""), "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")
+	match(c, row.Data, []byte("%"), []byte("root"), []byte(""), "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "", []byte("mysql_native_password"), []byte(""), "N", "N", nil, nil, 0, 0)
 
 	c.Assert(se.Auth("root@anyhost", []byte(""), []byte("")), IsTrue)
 	mustExecSQL(c, se, "USE test;")
@@ -195,6 +195,13 @@ func (s *testBootstrapSuite) TestUpgrade(c *C) {
 	mustExecSQL(c, se1, `delete from mysql.TiDB where VARIABLE_NAME="tidb_server_version";`)
 	mustExecSQL(c, se1, fmt.Sprintf(`delete from mysql.global_variables where VARIABLE_NAME="%s";`,
 		variable.TiDBDistSQLScanConcurrency))
+	// The password variables are empty or missing before version 11.
+	mustExecSQL(c, se1, fmt.Sprintf(`update mysql.global_variables set VARIABLE_VALUE="" where VARIABLE_NAME in ("%s", "%s");`,
+		variable.ValidatePasswordPolicy, variable.DefaultPasswordLifetime))
+	mustExecSQL(c, se1, fmt.Sprintf(`update mysql.global_variables set VARIABLE_VALUE="12" where VARIABLE_NAME="%s";`,
+		variable.ValidatePasswordLength))
+	mustExecSQL(c, se1, fmt.Sprintf(`delete from mysql.global_variables where VARIABLE_NAME="%s";`,
+		variable.TiDBValidatePasswordEnable))
 	mustExecSQL(c, se1, `commit;`)
 	delete(storeBootstrapped, store.UUID())
 	// Make sure the version is downgraded.
@@ -220,4 +227,15 @@ func (s *testBootstrapSuite) TestUpgrade(c *C) {
 	ver, err = getBootstrapVersion(se2)
 	c.Assert(err, IsNil)
 	c.Assert(ver, Equals, int64(currentBootstrapVersion))
+
+	// The empty password variables are set to the default values, the others are kept.
+	r = mustExecSQL(c, se2, fmt.Sprintf(`SELECT VARIABLE_VALUE from mysql.global_variables where VARIABLE_NAME in ("%s", "%s", "%s", "%s") order by VARIABLE_NAME;`,
+		variable.DefaultPasswordLifetime, variable.TiDBValidatePasswordEnable, variable.ValidatePasswordLength,
+		variable.ValidatePasswordPolicy))
+	for _, expect := range []string{"0", "0", "12", "MEDIUM"} {
+		row, err = r.Next()
+		c.Assert(err, IsNil)
+		c.Assert(row, NotNil)
+		c.Assert(row.Data[0].GetString(), Equals, expect)
+	}
 }
@@ -0,0 +1,402 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/juju/errors"
+)
+
+// Config is the configuration of tidb-server, it is loaded from a TOML file.
+type Config struct {
+	Server      Server      `toml:"server"`
+	Storage     Storage     `toml:"storage"`
+	Log         Log         `toml:"log"`
+	Security    Security    `toml:"security"`
+	Audit       Audit       `toml:"audit"`
+	Performance Performance `toml:"performance"`
+	Binlog      Binlog      `toml:"binlog"`
+	Status      Status      `toml:"status"`
+}
+
+// Server is the [server] section of the configuration.
+type Server struct {
+	Host       string `toml:"host"`
+	Port       uint   `toml:"port"`
+	Socket     string `toml:"socket"`
+	RunDDL     bool   `toml:"run-ddl"`
+	PerfSchema bool   `toml:"perfschema"`
+	// GracefulShutdownTimeout is the time given to the transactions to finish when tidb-server is shutting
+	// down, a number without unit is in seconds.
+	GracefulShutdownTimeout string `toml:"graceful-shutdown-timeout"`
+	// ProxyProtocolNetworks is the comma separated CIDRs of the trusted proxies, the connections from them
+	// must send the PROXY protocol header which has the address of the client.
+	ProxyProtocolNetworks string `toml:"proxy-protocol-networks"`
+}
+
+// Storage is the [storage] section of the configuration.
+type Storage struct {
+	Store string `toml:"store"`
+	Path  string `toml:"path"`
+	// Lease is the schema lease duration, a number without unit is in seconds.
+	Lease string `toml:"lease"`
+	// EncryptionKeyFile is the file of the master keys which encrypt the data of goleveldb and boltdb, the data
+	// isn't encrypted if it's empty.
+	EncryptionKeyFile string `toml:"encryption-key-file"`
+	// EncryptionKeyMode decides how the keys are stored, it's one of plain, prefix and order-preserving.
+	EncryptionKeyMode string `toml:"encryption-key-mode"`
+	// EncryptionKeyPrefixLength is the number of the mapped bytes at the beginning of the keys of the prefix mode.
+	EncryptionKeyPrefixLength uint `toml:"encryption-key-prefix-length"`
+}
+
+// Log is the [log] section of the configuration.
+type Log struct {
+	Level string `toml:"level"`
+	File  string `toml:"file"`
+	// SlowQueryFile is the slow query log file, the slow queries are written to the general log if it's empty.
+	SlowQueryFile string `toml:"slow-query-file"`
+	// SlowThreshold is the default value of tidb_slow_log_threshold in milliseconds, the statements slower
+	// than it are written to the slow query log.
+	SlowThreshold uint `toml:"slow-threshold"`
+}
+
+// Security is the [security] section of the configuration.
+type Security struct {
+	SkipGrantTable bool   `toml:"skip-grant-table"`
+	SSLCA          string `toml:"ssl-ca"`
+	SSLCert        string `toml:"ssl-cert"`
+	SSLKey         string `toml:"ssl-key"`
+}
+
+// Audit is the [audit] section of the configuration.
+type Audit struct {
+	// File is the audit log file, the audit log is disabled if it's empty.
+	File string `toml:"file"`
+	// MaxSize is the size in MB of the audit log file, the file is rotated when it's exceeded.
+	MaxSize uint `toml:"max-size"`
+	// MaxBackups is the number of the rotated audit log files to keep.
+	MaxBackups uint `toml:"max-backups"`
+	// FullSQL logs the full SQL text, otherwise the literals in the SQL text are replaced by "?".
+	FullSQL bool `toml:"full-sql"`
+}
+
+// Performance is the [performance] section of the configuration.
+type Performance struct {
+	JoinConcurrency   uint `toml:"join-concurrency"`
+	CrossJoin         bool `toml:"cross-join"`
+	RetryLimit        uint `toml:"retry-limit"`
+	PlanCache         bool `toml:"plan-cache"`
+	PlanCacheCapacity uint `toml:"plan-cache-capacity"`
+	// TokenLimit is the number of client commands which are handled concurrently.
+	TokenLimit uint `toml:"token-limit"`
+	// TxnMemQuota is the memory quota in bytes of the write buffer of a transaction, the buffer spills to
+	// TxnSpillDir when it's exceeded. It must be less than LargeTxnTotalSizeLimit.
+	TxnMemQuota uint64 `toml:"txn-mem-quota"`
+	// TxnEntryCountLimit is the max number of the entries written by a transaction which is committed from memory.
+	TxnEntryCountLimit uint64 `toml:"txn-entry-count-limit"`
+	// TxnTotalSizeLimit is the max size in bytes of the entries written by a transaction which is committed
+	// from memory.
+	TxnTotalSizeLimit uint64 `toml:"txn-total-size-limit"`
+	// LargeTxnEntryCountLimit is the max number of the entries written by a transaction whose write buffer is
+	// prewritten in the background when it spills, only the optimistic transactions of tikv do so.
+	LargeTxnEntryCountLimit uint64 `toml:"large-txn-entry-count-limit"`
+	// LargeTxnTotalSizeLimit is the max size in bytes of the entries written by a transaction whose write buffer
+	// is prewritten in the background when it spills.
+	LargeTxnTotalSizeLimit uint64 `toml:"large-txn-total-size-limit"`
+	// TxnSpillDir is the directory which the write buffers of the transactions spill to, it's the directory
+	// for temporary files of the OS if it's empty.
+	TxnSpillDir string `toml:"txn-spill-dir"`
+}
+
+// Binlog is the [binlog] section of the configuration.
+type Binlog struct {
+	Socket string `toml:"socket"`
+}
+
+// Status is the [status] section of the configuration.
+type Status struct {
+	ReportStatus    bool   `toml:"report-status"`
+	Port            uint   `toml:"port"`
+	MetricsAddr     string `toml:"metrics-addr"`
+	MetricsInterval uint   `toml:"metrics-interval"`
+}
+
+// NewConfig creates a Config with the default settings.
+func NewConfig() *Config {
+	return &Config{
+		Server: Server{
+			Host:                    "0.0.0.0",
+			Port:                    4000,
+			RunDDL:                  true,
+			GracefulShutdownTimeout: "30s",
+		},
+		Storage: Storage{
+			Store:                     "goleveldb",
+			Path:                      "/tmp/tidb",
+			Lease:                     "1s",
+			EncryptionKeyMode:         "plain",
+			EncryptionKeyPrefixLength: 18,
+		},
+		Log: Log{
+			Level:         "info",
+			SlowQueryFile: "tidb-slow.log",
+			SlowThreshold: 300,
+		},
+		Audit: Audit{
+			MaxSize:    100,
+			MaxBackups: 10,
+		},
+		Performance: Performance{
+			JoinConcurrency:         5,
+			CrossJoin:               true,
+			RetryLimit:              10,
+			PlanCacheCapacity:       100,
+			TokenLimit:              1000,
+			TxnMemQuota:             100 * 1024 * 1024,
+			TxnEntryCountLimit:      300 * 1000,
+			TxnTotalSizeLimit:       100 * 1024 * 1024,
+			LargeTxnEntryCountLimit: 100 * 1000 * 1000,
+			LargeTxnTotalSizeLimit:  10 * 1024 * 1024 * 1024,
+		},
+		Status: Status{
+			ReportStatus:    true,
+			Port:            10080,
+			MetricsInterval: 15,
+		},
+	}
+}
+
+// ReloadableKeys are the settings which can be changed without restarting tidb-server.
+var ReloadableKeys = map[string]bool{
+	"log.level":                               true,
+	"log.slow-threshold":                      true,
+	"performance.token-limit":                 true,
+	"performance.txn-mem-quota":               true,
+	"performance.txn-entry-count-limit":       true,
+	"performance.txn-total-size-limit":        true,
+	"performance.large-txn-entry-count-limit": true,
+	"performance.large-txn-total-size-limit":  true,
+}
+
+// Load loads the TOML file of path into c, the settings which are absent in the file are left unchanged.
+// Unknown keys and values of the wrong type are reported with the full key name.
+func (c *Config) Load(path string) error {
+	var table map[string]toml.Primitive
+	md, err := toml.DecodeFile(path, &table)
+	if err != nil {
+		return errors.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	err = decodeTable(md, table, reflect.ValueOf(c).Elem(), nil)
+	if err != nil {
+		return errors.Errorf("config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// decodeTable decodes the keys of the TOML table of path into the fields of the struct v which have the
+// same toml tags.
+func decodeTable(md toml.MetaData, table map[string]toml.Primitive, v reflect.Value, path []string) error {
+	fields := tomlFields(v)
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		keyPath := append(path[:len(path):len(path)], k)
+		key := strings.Join(keyPath, ".")
+		field, ok := fields[k]
+		if !ok {
+			return errors.Errorf("unknown configuration option %s", key)
+		}
+		if field.Kind() == reflect.Struct {
+			if md.Type(keyPath...) != "Hash" {
+				return errors.Errorf("%s should be a table", key)
+			}
+			var sub map[string]toml.Primitive
+			if err := md.PrimitiveDecode(table[k], &sub); err != nil {
+				return errors.Errorf("invalid value of %s: %v", key, err)
+			}
+			if err := decodeTable(md, sub, field, keyPath); err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		}
+		if kind := field.Kind(); kind == reflect.Uint || kind == reflect.Uint64 {
+			// The TOML decoder doesn't check the range of unsigned integers.
+			var num int64
+			if err := md.PrimitiveDecode(table[k], &num); err != nil {
+				return errors.Errorf("invalid value of %s: %v", key, err)
+			}
+			if num < 0 {
+				return errors.Errorf("invalid value of %s: %d is negative", key, num)
+			}
+			field.SetUint(uint64(num))
+			continue
+		}
+		if err := md.PrimitiveDecode(table[k], field.Addr().Interface()); err != nil {
+			return errors.Errorf("invalid value of %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// tomlFields returns the fields of the struct v by their toml tags.
+func tomlFields(v reflect.Value) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		fields[v.Type().Field(i).Tag.Get("toml")] = v.Field(i)
+	}
+	return fields
+}
+
+// Validate checks the values of the settings.
+func (c *Config) Validate() error {
+	switch c.Log.Level {
+	case "debug", "info", "warn", "warning", "error", "fatal":
+	default:
+		return errors.Errorf("invalid log.level %q, it should be one of debug, info, warn, error and fatal", c.Log.Level)
+	}
+	switch c.Storage.Store {
+	case "memory", "goleveldb", "boltdb", "tikv":
+	default:
+		return errors.Errorf("invalid storage.store %q, it should be one of memory, goleveldb, boltdb and tikv", c.Storage.Store)
+	}
+	if _, err := c.Storage.LeaseDuration(); err != nil {
+		return errors.Trace(err)
+	}
+	switch c.Storage.EncryptionKeyMode {
+	case "plain", "prefix", "order-preserving":
+	default:
+		return errors.Errorf("invalid storage.encryption-key-mode %q, it should be one of plain, prefix and order-preserving", c.Storage.EncryptionKeyMode)
+	}
+	if c.Storage.EncryptionKeyFile != "" {
+		if c.Storage.Store != "goleveldb" && c.Storage.Store != "boltdb" {
+			return errors.Errorf("storage.encryption-key-file is not supported by storage.store %q", c.Storage.Store)
+		}
+		if c.Storage.EncryptionKeyMode == "prefix" && c.Storage.EncryptionKeyPrefixLength == 0 {
+			return errors.New("invalid storage.encryption-key-prefix-length 0, it should be positive")
+		}
+	}
+	if _, err := c.Server.GracefulShutdownDuration(); err != nil {
+		return errors.Trace(err)
+	}
+	if c.Server.Port > 65535 {
+		return errors.Errorf("invalid server.port %d", c.Server.Port)
+	}
+	if c.Status.Port > 65535 {
+		return errors.Errorf("invalid status.port %d", c.Status.Port)
+	}
+	if c.Performance.TokenLimit == 0 {
+		return errors.New("invalid performance.token-limit 0, it should be positive")
+	}
+	if c.Performance.TxnMemQuota == 0 {
+		return errors.New("invalid performance.txn-mem-quota 0, it should be positive")
+	}
+	if c.Performance.TxnEntryCountLimit == 0 {
+		return errors.New("invalid performance.txn-entry-count-limit 0, it should be positive")
+	}
+	if c.Performance.TxnTotalSizeLimit == 0 {
+		return errors.New("invalid performance.txn-total-size-limit 0, it should be positive")
+	}
+	if c.Performance.LargeTxnEntryCountLimit == 0 {
+		return errors.New("invalid performance.large-txn-entry-count-limit 0, it should be positive")
+	}
+	if c.Performance.LargeTxnTotalSizeLimit == 0 {
+		return errors.New("invalid performance.large-txn-total-size-limit 0, it should be positive")
+	}
+	if c.Performance.TxnMemQuota >= c.Performance.LargeTxnTotalSizeLimit {
+		return errors.Errorf("invalid performance.txn-mem-quota %d, it should be less than performance.large-txn-total-size-limit %d",
+			c.Performance.TxnMemQuota, c.Performance.LargeTxnTotalSizeLimit)
+	}
+	if c.Audit.MaxSize == 0 {
+		return errors.New("invalid audit.max-size 0, it should be positive")
+	}
+	return nil
+}
+
+// LeaseDuration parses the schema lease, a number without unit is in seconds.
+func (s *Storage) LeaseDuration() (time.Duration, error) {
+	return parseDuration("storage.lease", s.Lease)
+}
+
+// GracefulShutdownDuration parses the graceful shutdown timeout, a number without unit is in seconds.
+func (s *Server) GracefulShutdownDuration() (time.Duration, error) {
+	return parseDuration("server.graceful-shutdown-timeout", s.GracefulShutdownTimeout)
+}
+
+func parseDuration(key, value string) (time.Duration, error) {
+	dur, err := time.ParseDuration(value)
+	if err != nil {
+		dur, err = time.ParseDuration(value + "s")
+	}
+	if err != nil || dur < 0 {
+		return 0, errors.Errorf("invalid %s %q", key, value)
+	}
+	return dur, nil
+}
+
+// ChangedKeys returns the keys of the settings which are different in c and nc.
+func (c *Config) ChangedKeys(nc *Config) []string {
+	return changedKeys(reflect.ValueOf(c).Elem(), reflect.ValueOf(nc).Elem(), "")
+}
+
+func changedKeys(a, b reflect.Value, prefix string) []string {
+	var keys []string
+	for i := 0; i < a.NumField(); i++ {
+		key := prefix + a.Type().Field(i).Tag.Get("toml")
+		if a.Field(i).Kind() == reflect.Struct {
+			keys = append(keys, changedKeys(a.Field(i), b.Field(i), key+".")...)
+		} else if a.Field(i).Interface() != b.Field(i).Interface() {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Reload copies the reloadable settings of nc to c. It returns the keys of the changed settings which
+// are reloaded, and the keys of the changed settings which require restarting tidb-server.
+func (c *Config) Reload(nc *Config) (reloaded []string, ignored []string) {
+	for _, key := range c.ChangedKeys(nc) {
+		if !ReloadableKeys[key] {
+			ignored = append(ignored, key)
+			continue
+		}
+		reloaded = append(reloaded, key)
+	}
+	c.Log.Level = nc.Log.Level
+	c.Log.SlowThreshold = nc.Log.SlowThreshold
+	c.Performance.TokenLimit = nc.Performance.TokenLimit
+	c.Performance.TxnMemQuota = nc.Performance.TxnMemQuota
+	c.Performance.TxnEntryCountLimit = nc.Performance.TxnEntryCountLimit
+	c.Performance.TxnTotalSizeLimit = nc.Performance.TxnTotalSizeLimit
+	c.Performance.LargeTxnEntryCountLimit = nc.Performance.LargeTxnEntryCountLimit
+	c.Performance.LargeTxnTotalSizeLimit = nc.Performance.LargeTxnTotalSizeLimit
+	return reloaded, ignored
+}
+
+// String returns the keys and values of all the settings, it's used to print the configuration in logs.
+func (c *Config) String() string {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(c); err != nil {
+		return err.Error()
+	}
+	return buf.String()
+}
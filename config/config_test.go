@@ -0,0 +1,159 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testConfigSuite{})
+
+type testConfigSuite struct{}
+
+func writeConfigFile(c *C, content string) string {
+	f, err := ioutil.TempFile("", "tidb-config")
+	c.Assert(err, IsNil)
+	_, err = f.WriteString(content)
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+	return f.Name()
+}
+
+func (s *testConfigSuite) TestExampleFile(c *C) {
+	// The example file should contain the default settings.
+	conf := NewConfig()
+	conf.Log.Level = "debug"
+	c.Assert(conf.Load("config.toml.example"), IsNil)
+	c.Assert(conf.Validate(), IsNil)
+	c.Assert(NewConfig().ChangedKeys(conf), HasLen, 0)
+}
+
+func (s *testConfigSuite) TestLoad(c *C) {
+	path := writeConfigFile(c, `
+[server]
+port = 4001
+
+[log]
+level = "warn"
+slow-threshold = 1000
+
+[performance]
+token-limit = 10
+`)
+	defer os.Remove(path)
+	conf := NewConfig()
+	c.Assert(conf.Load(path), IsNil)
+	c.Assert(conf.Validate(), IsNil)
+	c.Assert(conf.Server.Port, Equals, uint(4001))
+	c.Assert(conf.Log.Level, Equals, "warn")
+	c.Assert(conf.Log.SlowThreshold, Equals, uint(1000))
+	c.Assert(conf.Performance.TokenLimit, Equals, uint(10))
+	// The absent settings keep the default values.
+	c.Assert(conf.Server.Host, Equals, "0.0.0.0")
+	c.Assert(conf.Storage.Store, Equals, "goleveldb")
+
+	tbl := []struct {
+		content string
+		errMsg  string
+	}{
+		{"[log]\nlevle = \"warn\"", "unknown configuration option log.levle"},
+		{"[logs]\nlevel = \"warn\"", "unknown configuration option logs"},
+		{"host = \"127.0.0.1\"", "unknown configuration option host"},
+		{"log = 1", "log should be a table"},
+		{"[server]\nport = \"4000\"", "invalid value of server.port: .*"},
+		{"[performance]\ncross-join = 1", "invalid value of performance.cross-join: .*"},
+		{"[status]\nport = -1", "invalid value of status.port: .*"},
+		{"[server\nport = 4000", "failed to parse config file .*"},
+	}
+	for _, t := range tbl {
+		path := writeConfigFile(c, t.content)
+		err := NewConfig().Load(path)
+		os.Remove(path)
+		c.Assert(err, ErrorMatches, "config file .*"+t.errMsg+"|"+t.errMsg, Commentf("%s", t.content))
+	}
+}
+
+func (s *testConfigSuite) TestValidate(c *C) {
+	tbl := []struct {
+		modify func(conf *Config)
+		errMsg string
+	}{
+		{func(conf *Config) { conf.Log.Level = "verbose" }, "invalid log.level \"verbose\".*"},
+		{func(conf *Config) { conf.Storage.Store = "mysql" }, "invalid storage.store \"mysql\".*"},
+		{func(conf *Config) { conf.Storage.Lease = "1x" }, "invalid storage.lease \"1x\""},
+		{func(conf *Config) { conf.Storage.EncryptionKeyMode = "ope" }, "invalid storage.encryption-key-mode \"ope\".*"},
+		{func(conf *Config) {
+			conf.Storage.Store = "memory"
+			conf.Storage.EncryptionKeyFile = "keys"
+		}, "storage.encryption-key-file is not supported by storage.store \"memory\""},
+		{func(conf *Config) {
+			conf.Storage.EncryptionKeyFile = "keys"
+			conf.Storage.EncryptionKeyMode = "prefix"
+			conf.Storage.EncryptionKeyPrefixLength = 0
+		}, "invalid storage.encryption-key-prefix-length 0.*"},
+		{func(conf *Config) { conf.Server.GracefulShutdownTimeout = "-1s" }, "invalid server.graceful-shutdown-timeout \"-1s\""},
+		{func(conf *Config) { conf.Server.Port = 65536 }, "invalid server.port 65536"},
+		{func(conf *Config) { conf.Status.Port = 65536 }, "invalid status.port 65536"},
+		{func(conf *Config) { conf.Performance.TokenLimit = 0 }, "invalid performance.token-limit 0.*"},
+		{func(conf *Config) { conf.Performance.TxnMemQuota = 0 }, "invalid performance.txn-mem-quota 0.*"},
+		{func(conf *Config) { conf.Performance.TxnEntryCountLimit = 0 }, "invalid performance.txn-entry-count-limit 0.*"},
+		{func(conf *Config) { conf.Performance.TxnTotalSizeLimit = 0 }, "invalid performance.txn-total-size-limit 0.*"},
+		{func(conf *Config) { conf.Performance.LargeTxnEntryCountLimit = 0 }, "invalid performance.large-txn-entry-count-limit 0.*"},
+		{func(conf *Config) { conf.Performance.LargeTxnTotalSizeLimit = 0 }, "invalid performance.large-txn-total-size-limit 0.*"},
+		{func(conf *Config) { conf.Performance.TxnMemQuota = conf.Performance.LargeTxnTotalSizeLimit }, "invalid performance.txn-mem-quota .* less than .*"},
+		{func(conf *Config) { conf.Audit.MaxSize = 0 }, "invalid audit.max-size 0.*"},
+	}
+	for _, t := range tbl {
+		conf := NewConfig()
+		t.modify(conf)
+		c.Assert(conf.Validate(), ErrorMatches, t.errMsg)
+	}
+
+	conf := NewConfig()
+	conf.Storage.Lease = "2"
+	lease, err := conf.Storage.LeaseDuration()
+	c.Assert(err, IsNil)
+	c.Assert(lease, Equals, 2*time.Second)
+	timeout, err := conf.Server.GracefulShutdownDuration()
+	c.Assert(err, IsNil)
+	c.Assert(timeout, Equals, 30*time.Second)
+}
+
+func (s *testConfigSuite) TestReload(c *C) {
+	conf := NewConfig()
+	nc := NewConfig()
+	nc.Log.Level = "error"
+	nc.Log.SlowThreshold = 10
+	nc.Performance.TokenLimit = 5
+	nc.Performance.TxnTotalSizeLimit = 1 << 30
+	nc.Storage.Path = "/tmp/tidb2"
+	reloaded, ignored := conf.Reload(nc)
+	c.Assert(reloaded, DeepEquals, []string{"log.level", "log.slow-threshold", "performance.token-limit",
+		"performance.txn-total-size-limit"})
+	c.Assert(ignored, DeepEquals, []string{"storage.path"})
+	c.Assert(conf.Log.Level, Equals, "error")
+	c.Assert(conf.Log.SlowThreshold, Equals, uint(10))
+	c.Assert(conf.Performance.TokenLimit, Equals, uint(5))
+	c.Assert(conf.Performance.TxnTotalSizeLimit, Equals, uint64(1<<30))
+	c.Assert(conf.Storage.Path, Equals, "/tmp/tidb")
+}
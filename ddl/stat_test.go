@@ -28,7 +28,7 @@ type testStatSuite struct {
 }
 
 func (s *testStatSuite) getDDLSchemaVer(c *C, d *ddl) int64 {
-	m, err := d.Stats()
+	m, err := d.Stats(nil)
 	c.Assert(err, IsNil)
 	v := m[ddlSchemaVersion]
 	return v.(int64)
@@ -47,7 +47,7 @@ func (s *testStatSuite) TestStat(c *C) {
 	dbInfo := testSchemaInfo(c, d, "test")
 	testCreateSchema(c, testNewContext(d), d, dbInfo)
 
-	m, err := d.Stats()
+	m, err := d.Stats(nil)
 	c.Assert(err, IsNil)
 	c.Assert(m[ddlOwnerID], Equals, d.uuid)
 
@@ -78,7 +78,7 @@ LOOP:
 			d.start()
 		case err := <-done:
 			c.Assert(err, IsNil)
-			m, err := d.Stats()
+			m, err := d.Stats(nil)
 			c.Assert(err, IsNil)
 			c.Assert(m[bgOwnerID], Equals, d.uuid)
 			break LOOP
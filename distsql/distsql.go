@@ -21,6 +21,7 @@ import (
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/execdetails"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
 	goctx "golang.org/x/net/context"
@@ -60,6 +61,8 @@ type selectResult struct {
 	label     string
 	aggregate bool
 	resp      kv.Response
+	// execDetails records the coprocessor tasks of the statement, it may be nil.
+	execDetails *execdetails.ExecDetails
 
 	results chan resultWithErr
 	closed  chan struct{}
@@ -92,6 +95,9 @@ func (r *selectResult) fetch(ctx goctx.Context) {
 		}
 		pr := &partialResult{}
 		pr.unmarshal(resultSubset)
+		if r.execDetails != nil {
+			r.execDetails.AddCopTask(pr.rowCount())
+		}
 
 		select {
 		case r.results <- resultWithErr{result: pr}:
@@ -175,6 +181,18 @@ func (pr *partialResult) getChunk() *tipb.Chunk {
 	}
 }
 
+// rowCount returns the number of the rows in the partial result.
+func (pr *partialResult) rowCount() int {
+	if pr.resp == nil {
+		return 0
+	}
+	count := len(pr.resp.Rows)
+	for _, chunk := range pr.resp.Chunks {
+		count += len(chunk.RowsMeta)
+	}
+	return count
+}
+
 // Close closes the sub result.
 func (pr *partialResult) Close() error {
 	return nil
@@ -184,7 +202,9 @@ func (pr *partialResult) Close() error {
 // concurrency: The max concurrency for underlying coprocessor request.
 // keepOrder: If the result should returned in key order. For example if we need keep data in order by
 //            scan index, we should set keepOrder to true.
-func Select(client kv.Client, ctx goctx.Context, req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int, keepOrder bool) (SelectResult, error) {
+// execDetails: The coprocessor tasks are recorded in it if it's not nil.
+func Select(client kv.Client, ctx goctx.Context, req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int,
+	keepOrder bool, execDetails *execdetails.ExecDetails) (SelectResult, error) {
 	var err error
 	defer func() {
 		// Add metrics
@@ -208,9 +228,10 @@ func Select(client kv.Client, ctx goctx.Context, req *tipb.SelectRequest, keyRan
 		return nil, err
 	}
 	result := &selectResult{
-		resp:    resp,
-		results: make(chan resultWithErr, 5),
-		closed:  make(chan struct{}),
+		resp:        resp,
+		results:     make(chan resultWithErr, 5),
+		closed:      make(chan struct{}),
+		execDetails: execDetails,
 	}
 	// If Aggregates is not nil, we should set result fields latter.
 	if len(req.Aggregates) == 0 && len(req.GroupBy) == 0 {
@@ -20,17 +20,20 @@ import (
 	"github.com/coreos/etcd/clientv3"
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/bindinfo"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/ddl"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/perfschema"
 	"github.com/pingcap/tidb/privilege/privileges"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/statistics"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/audit"
 	goctx "golang.org/x/net/context"
 )
 
@@ -41,12 +44,20 @@ type Domain struct {
 	infoHandle      *infoschema.Handle
 	privHandle      *privileges.Handle
 	statsHandle     *statistics.Handle
+	bindHandle      *bindinfo.Handle
 	ddl             ddl.DDL
 	m               sync.Mutex
 	SchemaValidator SchemaValidator
 	sysSessionPool  *sync.Pool
 	exit            chan struct{}
 	etcdClient      *clientv3.Client
+	// auditFilter is the context to load the filter rules of the audit log, it's used under the lock.
+	auditFilter struct {
+		sync.Mutex
+		ctx context.Context
+	}
+	// serverID is the ID of the tidb-server in the cluster, it's accessed atomically.
+	serverID uint64
 
 	MockReloadFailed MockFailure // It mocks reload failed.
 }
@@ -201,11 +212,16 @@ func (do *Domain) InfoSchema() infoschema.InfoSchema {
 
 // GetSnapshotInfoSchema gets a snapshot information schema.
 func (do *Domain) GetSnapshotInfoSchema(snapshotTS uint64) (infoschema.InfoSchema, error) {
+	is := do.infoHandle.Get()
 	snapHandle := do.infoHandle.EmptyClone()
-	_, err := do.loadInfoSchema(snapHandle, do.infoHandle.Get().SchemaMetaVersion(), snapshotTS)
+	schemaVersion, err := do.loadInfoSchema(snapHandle, is.SchemaMetaVersion(), snapshotTS)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	if schemaVersion == is.SchemaMetaVersion() {
+		// loadInfoSchema doesn't load anything if the schema is not changed since snapshotTS.
+		return is, nil
+	}
 	return snapHandle.Get(), nil
 }
 
@@ -304,6 +320,7 @@ func (do *Domain) loadSchemaInLoop(lease time.Duration) {
 func (do *Domain) Close() {
 	do.ddl.Stop()
 	close(do.exit)
+	do.releaseServerID()
 	if do.etcdClient != nil {
 		do.etcdClient.Close()
 	}
@@ -441,6 +458,76 @@ func (do *Domain) StatsHandle() *statistics.Handle {
 	return do.statsHandle
 }
 
+// LoadBindInfoLoop creates a goroutine loads the global SQL bindings in a loop, it
+// should be called only once in BootstrapSession.
+func (do *Domain) LoadBindInfoLoop(ctx context.Context) error {
+	do.bindHandle = bindinfo.NewHandle(ctx)
+	err := do.bindHandle.Update()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	var watchCh clientv3.WatchChan
+	duration := 5 * time.Minute
+	if do.etcdClient != nil {
+		watchCh = do.etcdClient.Watch(goctx.Background(), bindInfoKey)
+		duration = 10 * time.Minute
+	}
+
+	go func() {
+		for {
+			select {
+			case <-do.exit:
+				return
+			case <-watchCh:
+			case <-time.After(duration):
+			}
+			err := do.bindHandle.Update()
+			if err != nil {
+				log.Error("load bind info fail:", errors.ErrorStack(err))
+			}
+		}
+	}()
+	return nil
+}
+
+// BindHandle returns the global SQL bindings handle.
+func (do *Domain) BindHandle() *bindinfo.Handle {
+	return do.bindHandle
+}
+
+// LoadAuditFilterLoop creates a goroutine loads the filter rules of the audit log in a loop, it
+// should be called only once in BootstrapSession.
+func (do *Domain) LoadAuditFilterLoop(ctx context.Context) error {
+	do.auditFilter.ctx = ctx
+	err := do.ReloadAuditFilter()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	go func() {
+		for {
+			select {
+			case <-do.exit:
+				return
+			case <-time.After(5 * time.Minute):
+			}
+			err := do.ReloadAuditFilter()
+			if err != nil {
+				log.Error("load audit log filter fail:", errors.ErrorStack(err))
+			}
+		}
+	}()
+	return nil
+}
+
+// ReloadAuditFilter reloads the filter rules of the audit log from mysql.audit_log_filter.
+func (do *Domain) ReloadAuditFilter() error {
+	do.auditFilter.Lock()
+	defer do.auditFilter.Unlock()
+	return errors.Trace(audit.LoadFilter(do.auditFilter.ctx))
+}
+
 // UpdateTableStatsLoop creates a goroutine loads stats info and updates stats info in a loop. It
 // should be called only once in BootstrapSession.
 func (do *Domain) UpdateTableStatsLoop(ctx context.Context) error {
@@ -498,10 +585,28 @@ func (do *Domain) NotifyUpdatePrivilege(ctx context.Context) {
 	}
 }
 
+const bindInfoKey = "/tidb/bindinfo"
+
+// NotifyUpdateBindInfo updates bind info key in etcd, TiDB client that watches
+// the key will get notification.
+func (do *Domain) NotifyUpdateBindInfo() {
+	if do.etcdClient != nil {
+		kv := do.etcdClient.KV
+		_, err := kv.Put(goctx.Background(), bindInfoKey, "")
+		if err != nil {
+			log.Warn("notify update bind info failed:", err)
+		}
+	}
+}
+
 // Domain error codes.
 const (
 	codeInfoSchemaExpired terror.ErrCode = 1
 	codeInfoSchemaChanged terror.ErrCode = 2
+	codeNoServerID        terror.ErrCode = 3
+	codeServerIDLost      terror.ErrCode = 4
+
+	codeNoSuchThread terror.ErrCode = terror.ErrCode(mysql.ErrNoSuchThread)
 )
 
 var (
@@ -510,3 +615,10 @@ var (
 	// ErrInfoSchemaChanged returns the error that information schema is changed.
 	ErrInfoSchemaChanged = terror.ClassDomain.New(codeInfoSchemaChanged, "Information schema is changed.")
 )
+
+func init() {
+	domainMySQLErrCodes := map[terror.ErrCode]uint16{
+		codeNoSuchThread: mysql.ErrNoSuchThread,
+	}
+	terror.ErrClassToMySQLCodes[terror.ClassDomain] = domainMySQLErrCodes
+}
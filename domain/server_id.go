@@ -0,0 +1,260 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package domain
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util"
+)
+
+var (
+	// serverIDLease is the lease of the server ID, it's renewed every third of the lease. An expired server ID
+	// isn't taken by another server within a lease after it expires, so the previous server can find that the
+	// lease is lost and close the connections which have the ID.
+	serverIDLease = 30 * time.Second
+	// serverIDMaxClockSkew is the max difference between the local clock and the clock of the TSO, the leases
+	// are compared with the TSO, the local clock is only used if the TSO can't be got.
+	serverIDMaxClockSkew = 5 * time.Second
+	// killPollInterval is the interval of checking the kill requests sent by the other servers.
+	killPollInterval = time.Second
+)
+
+var (
+	// ErrNoSuchThread returns the error that the connection to kill doesn't exist.
+	ErrNoSuchThread = terror.ClassDomain.New(codeNoSuchThread, "Unknown thread id: %d")
+	errNoServerID   = terror.ClassDomain.New(codeNoServerID, "no server ID is available")
+	errServerIDLost = terror.ClassDomain.New(codeServerIDLost, "the lease of server ID %d is lost")
+)
+
+// AcquireServerID allocates a server ID which isn't used by the other alive tidb-servers in the cluster,
+// the server ID is encoded in the connection IDs to make them unique in the cluster. The lease of the ID
+// is renewed in the background until the domain is closed, and the kill requests sent to the server
+// by SendKillRequest are handled by sm.
+//
+// If the lease isn't renewed in time, the other servers may take the ID. The server ID is set to 0 by
+// setServerID then, so the new connection IDs are local, until a new server ID is acquired and set, and
+// the connections which have the ID are killed, so the kill requests to the server which takes the ID
+// can't be mistaken for them.
+func (do *Domain) AcquireServerID(sm util.SessionManager, setServerID func(serverID uint64)) (uint64, error) {
+	serverID, expire, err := do.acquireServerID()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	atomic.StoreUint64(&do.serverID, serverID)
+	log.Infof("[domain] acquire server ID %d", serverID)
+
+	go func() {
+		leaseTicker := time.NewTicker(serverIDLease / 3)
+		defer leaseTicker.Stop()
+		killTicker := time.NewTicker(killPollInterval)
+		defer killTicker.Stop()
+		for {
+			select {
+			case <-do.exit:
+				return
+			case <-leaseTicker.C:
+				serverID, expire = do.keepServerID(serverID, expire, sm, setServerID)
+			case <-killTicker.C:
+				if serverID == 0 {
+					continue
+				}
+				err := do.handleKillRequests(serverID, sm)
+				if err != nil {
+					log.Errorf("[domain] handle kill requests error %v", errors.ErrorStack(err))
+				}
+			}
+		}
+	}()
+	return serverID, nil
+}
+
+// ServerID returns the server ID acquired by AcquireServerID, it's 0 if no server ID is acquired.
+func (do *Domain) ServerID() uint64 {
+	return atomic.LoadUint64(&do.serverID)
+}
+
+// leaseNow returns the time in nanoseconds of the TSO of txn, the times of the leases are got from the TSO,
+// so they are comparable between the servers.
+func leaseNow(txn kv.Transaction) int64 {
+	return oracle.ExtractPhysical(txn.StartTS()) * int64(time.Millisecond)
+}
+
+// acquireServerID takes a server ID whose lease is expired for a lease, it returns the ID and the expire
+// time of its lease.
+func (do *Domain) acquireServerID() (uint64, int64, error) {
+	var (
+		serverID uint64
+		expire   int64
+	)
+	err := kv.RunInNewTxn(do.store, true, func(txn kv.Transaction) error {
+		m := meta.NewMeta(txn)
+		leases, err := m.GetServerLeases()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		now := leaseNow(txn)
+		for id := uint64(1); id <= util.MaxServerID; id++ {
+			if e, ok := leases[id]; ok && e+int64(serverIDLease) > now {
+				continue
+			}
+			serverID, expire = id, now+int64(serverIDLease)
+			err = m.SetServerLease(id, expire)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			// The kill requests to the previous server of the ID are stale.
+			_, err = m.TakeKillRequests(id)
+			return errors.Trace(err)
+		}
+		return errNoServerID
+	})
+	if err != nil {
+		return 0, 0, errors.Trace(err)
+	}
+	return serverID, expire, nil
+}
+
+// keepServerID renews the lease of the server ID, which expires at expire. If the lease is lost, or it
+// can't be renewed before it expires, the server stops using the ID, kills the connections which have the
+// ID, and acquires a new one. It returns the server ID and the expire time of its lease, the server ID is 0
+// if no ID is acquired.
+func (do *Domain) keepServerID(serverID uint64, expire int64, sm util.SessionManager, setServerID func(uint64)) (uint64, int64) {
+	if serverID != 0 {
+		newExpire, err := do.renewServerID(serverID, expire)
+		if err == nil {
+			return serverID, newExpire
+		}
+		log.Errorf("[domain] renew server ID %d error %v", serverID, errors.ErrorStack(err))
+		if !terror.ErrorEqual(err, errServerIDLost) && do.beforeExpire(expire) {
+			return serverID, expire
+		}
+		// The other servers may take the ID after a lease, their connection IDs may be the same as ours.
+		log.Warnf("[domain] stop using server ID %d", serverID)
+		do.setServerID(0, setServerID)
+		killConnsOfServer(serverID, sm)
+	}
+	serverID, expire, err := do.acquireServerID()
+	if err != nil {
+		log.Errorf("[domain] acquire server ID error %v", errors.ErrorStack(err))
+		return 0, 0
+	}
+	log.Infof("[domain] acquire server ID %d", serverID)
+	do.setServerID(serverID, setServerID)
+	return serverID, expire
+}
+
+// beforeExpire checks whether the lease which expires at expire isn't expired. The local clock is used with
+// serverIDMaxClockSkew if the TSO can't be got.
+func (do *Domain) beforeExpire(expire int64) bool {
+	ver, err := do.store.CurrentVersion()
+	if err != nil {
+		log.Errorf("[domain] get current version error %v", errors.ErrorStack(err))
+		return time.Now().Add(serverIDMaxClockSkew).UnixNano() < expire
+	}
+	return oracle.ExtractPhysical(ver.Ver)*int64(time.Millisecond) < expire
+}
+
+// killConnsOfServer kills the connections whose connection IDs have the server ID.
+func killConnsOfServer(serverID uint64, sm util.SessionManager) {
+	for _, pi := range sm.ShowProcessList() {
+		if util.ServerIDOfConn(pi.ID) == serverID {
+			log.Warnf("[domain] kill connection %d of lost server ID %d", pi.ID, serverID)
+			sm.Kill(pi.ID, false)
+		}
+	}
+}
+
+func (do *Domain) setServerID(serverID uint64, setServerID func(uint64)) {
+	atomic.StoreUint64(&do.serverID, serverID)
+	if setServerID != nil {
+		setServerID(serverID)
+	}
+}
+
+// renewServerID extends the lease of the server ID, which expires at expire. It returns errServerIDLost if
+// the lease is expired or taken by another server.
+func (do *Domain) renewServerID(serverID uint64, expire int64) (int64, error) {
+	var newExpire int64
+	err := kv.RunInNewTxn(do.store, true, func(txn kv.Transaction) error {
+		m := meta.NewMeta(txn)
+		leases, err := m.GetServerLeases()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		now := leaseNow(txn)
+		if leases[serverID] != expire || expire < now {
+			return errServerIDLost.GenByArgs(serverID)
+		}
+		newExpire = now + int64(serverIDLease)
+		return errors.Trace(m.SetServerLease(serverID, newExpire))
+	})
+	return newExpire, errors.Trace(err)
+}
+
+func (do *Domain) handleKillRequests(serverID uint64, sm util.SessionManager) error {
+	var requests map[uint64]bool
+	err := kv.RunInNewTxn(do.store, true, func(txn kv.Transaction) error {
+		var err error
+		requests, err = meta.NewMeta(txn).TakeKillRequests(serverID)
+		return errors.Trace(err)
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for connID, query := range requests {
+		log.Infof("[domain] kill connection %d, query %v", connID, query)
+		sm.Kill(connID, query)
+	}
+	return nil
+}
+
+// releaseServerID releases the server ID, so it can be used by the other servers at once.
+func (do *Domain) releaseServerID() {
+	serverID := do.ServerID()
+	if serverID == 0 {
+		return
+	}
+	err := kv.RunInNewTxn(do.store, true, func(txn kv.Transaction) error {
+		return errors.Trace(meta.NewMeta(txn).RemoveServerLease(serverID))
+	})
+	if err != nil {
+		log.Errorf("[domain] release server ID %d error %v", serverID, errors.ErrorStack(err))
+	}
+}
+
+// SendKillRequest sends the request to kill the connection to the tidb-server which the connection
+// belongs to, the server handles the request in killPollInterval.
+func (do *Domain) SendKillRequest(connID uint64, query bool) error {
+	serverID := util.ServerIDOfConn(connID)
+	err := kv.RunInNewTxn(do.store, true, func(txn kv.Transaction) error {
+		m := meta.NewMeta(txn)
+		leases, err := m.GetServerLeases()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if leases[serverID] < leaseNow(txn) {
+			return ErrNoSuchThread.GenByArgs(connID)
+		}
+		return errors.Trace(m.AddKillRequest(serverID, connID, query))
+	})
+	return errors.Trace(err)
+}
@@ -0,0 +1,173 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package domain
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/store/localstore"
+	"github.com/pingcap/tidb/store/localstore/goleveldb"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+type mockSessionManager struct {
+	sync.Mutex
+	conns  []uint64
+	killed map[uint64]bool
+}
+
+func (sm *mockSessionManager) ShowProcessList() []util.ProcessInfo {
+	sm.Lock()
+	defer sm.Unlock()
+	pl := make([]util.ProcessInfo, 0, len(sm.conns))
+	for _, connID := range sm.conns {
+		pl = append(pl, util.ProcessInfo{ID: connID})
+	}
+	return pl
+}
+
+func (sm *mockSessionManager) Kill(connID uint64, query bool) {
+	sm.Lock()
+	defer sm.Unlock()
+	sm.killed[connID] = query
+}
+
+func (*testSuite) TestServerID(c *C) {
+	defer testleak.AfterTest(c)()
+	driver := localstore.Driver{Driver: goleveldb.MemoryDriver{}}
+	store, err := driver.Open("memory")
+	c.Assert(err, IsNil)
+	defer store.Close()
+	dom1, err := NewDomain(store, 0)
+	c.Assert(err, IsNil)
+	dom2, err := NewDomain(store, 0)
+	c.Assert(err, IsNil)
+	defer dom2.Close()
+
+	c.Assert(dom1.ServerID(), Equals, uint64(0))
+	sm1 := &mockSessionManager{killed: make(map[uint64]bool)}
+	serverID1, err := dom1.AcquireServerID(sm1, nil)
+	c.Assert(err, IsNil)
+	c.Assert(serverID1, Equals, uint64(1))
+	c.Assert(dom1.ServerID(), Equals, serverID1)
+	sm2 := &mockSessionManager{killed: make(map[uint64]bool)}
+	serverID2, err := dom2.AcquireServerID(sm2, nil)
+	c.Assert(err, IsNil)
+	c.Assert(serverID2, Equals, uint64(2))
+
+	// The kill requests are sent to the server of the connection.
+	connID1 := util.EncodeConnID(serverID1, 5)
+	connID2 := util.EncodeConnID(serverID2, 6)
+	c.Assert(dom2.SendKillRequest(connID1, true), IsNil)
+	c.Assert(dom1.SendKillRequest(connID2, false), IsNil)
+	c.Assert(dom1.handleKillRequests(serverID1, sm1), IsNil)
+	c.Assert(dom2.handleKillRequests(serverID2, sm2), IsNil)
+	c.Assert(sm1.killed, DeepEquals, map[uint64]bool{connID1: true})
+	c.Assert(sm2.killed, DeepEquals, map[uint64]bool{connID2: false})
+	// The requests are handled only once.
+	sm1.killed = make(map[uint64]bool)
+	c.Assert(dom1.handleKillRequests(serverID1, sm1), IsNil)
+	c.Assert(sm1.killed, HasLen, 0)
+
+	// The connection of a server which isn't alive doesn't exist.
+	err = dom1.SendKillRequest(util.EncodeConnID(3, 1), false)
+	c.Assert(terror.ErrorEqual(err, ErrNoSuchThread), IsTrue, Commentf("err %v", err))
+
+	// The server ID is released when the domain is closed.
+	dom1.Close()
+	err = dom2.SendKillRequest(connID1, false)
+	c.Assert(terror.ErrorEqual(err, ErrNoSuchThread), IsTrue, Commentf("err %v", err))
+	err = kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		leases, err1 := meta.NewMeta(txn).GetServerLeases()
+		c.Assert(err1, IsNil)
+		c.Assert(leases, HasLen, 1)
+		c.Assert(leases[serverID2] > time.Now().UnixNano(), IsTrue)
+		return nil
+	})
+	c.Assert(err, IsNil)
+}
+
+func (*testSuite) TestServerIDLost(c *C) {
+	defer testleak.AfterTest(c)()
+	driver := localstore.Driver{Driver: goleveldb.MemoryDriver{}}
+	store, err := driver.Open("memory")
+	c.Assert(err, IsNil)
+	defer store.Close()
+	dom1, err := NewDomain(store, 0)
+	c.Assert(err, IsNil)
+	defer dom1.Close()
+	dom2, err := NewDomain(store, 0)
+	c.Assert(err, IsNil)
+	defer dom2.Close()
+
+	var serverIDs []uint64
+	setServerID := func(serverID uint64) {
+		serverIDs = append(serverIDs, serverID)
+	}
+	sm := &mockSessionManager{killed: make(map[uint64]bool)}
+	serverID1, err := dom1.AcquireServerID(sm, setServerID)
+	c.Assert(err, IsNil)
+	c.Assert(serverID1, Equals, uint64(1))
+	var expire int64
+	err = kv.RunInNewTxn(store, false, func(txn kv.Transaction) error {
+		leases, err1 := meta.NewMeta(txn).GetServerLeases()
+		expire = leases[serverID1]
+		return errors.Trace(err1)
+	})
+	c.Assert(err, IsNil)
+
+	// The lease is renewed if it's kept.
+	serverID, newExpire := dom1.keepServerID(serverID1, expire, sm, setServerID)
+	c.Assert(serverID, Equals, serverID1)
+	c.Assert(newExpire >= expire, IsTrue)
+	c.Assert(serverIDs, HasLen, 0)
+
+	// The ID isn't taken by another server within a lease after it expires.
+	setLease := func(expire time.Time) {
+		err = kv.RunInNewTxn(store, true, func(txn kv.Transaction) error {
+			return meta.NewMeta(txn).SetServerLease(serverID1, expire.UnixNano())
+		})
+		c.Assert(err, IsNil)
+	}
+	setLease(time.Now().Add(-time.Second))
+	serverID, _, err = dom2.acquireServerID()
+	c.Assert(err, IsNil)
+	c.Assert(serverID, Not(Equals), serverID1)
+	c.Assert(kv.RunInNewTxn(store, true, func(txn kv.Transaction) error {
+		return errors.Trace(meta.NewMeta(txn).RemoveServerLease(serverID))
+	}), IsNil)
+
+	// The lease expires for a lease, and another server takes the ID.
+	setLease(time.Now().Add(-serverIDLease - time.Second))
+	serverID2, err := dom2.AcquireServerID(&mockSessionManager{killed: make(map[uint64]bool)}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(serverID2, Equals, serverID1)
+
+	// The server stops using the ID, kills the connections which have the ID, and acquires a new one.
+	sm.Lock()
+	sm.conns = []uint64{util.EncodeConnID(serverID1, 5), util.EncodeConnID(0, 6)}
+	sm.Unlock()
+	serverID, _ = dom1.keepServerID(serverID1, newExpire, sm, setServerID)
+	c.Assert(serverID, Equals, uint64(2))
+	c.Assert(dom1.ServerID(), Equals, uint64(2))
+	c.Assert(serverIDs, DeepEquals, []uint64{0, 2})
+	c.Assert(sm.killed, DeepEquals, map[uint64]bool{util.EncodeConnID(serverID1, 5): false})
+}
@@ -16,6 +16,8 @@ package executor
 import (
 	"fmt"
 	"math"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/juju/errors"
@@ -24,7 +26,14 @@ import (
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/perfschema"
 	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/audit"
+	"github.com/pingcap/tidb/util/slowlog"
 )
 
 type processinfoSetter interface {
@@ -60,15 +69,23 @@ func (a *recordSet) Fields() ([]*ast.ResultField, error) {
 
 func (a *recordSet) Next() (*ast.Row, error) {
 	row, err := a.executor.Next()
-	if err != nil || row == nil {
+	if err != nil {
+		a.err = err
 		return nil, errors.Trace(err)
 	}
+	if row == nil {
+		return nil, nil
+	}
+	// The record sets of the analyze executor have no statement.
+	if a.stmt != nil {
+		a.stmt.rowsSent++
+	}
 	return &ast.Row{Data: row.Data}, nil
 }
 
 func (a *recordSet) Close() error {
 	err := a.executor.Close()
-	a.stmt.logSlowQuery()
+	a.stmt.finish(a.err)
 	if a.processinfo != nil {
 		a.processinfo.SetProcessInfo("")
 	}
@@ -81,8 +98,13 @@ type statement struct {
 	is        infoschema.InfoSchema
 	ctx       context.Context
 	text      string
+	stmtNode  ast.StmtNode
 	plan      plan.Plan
 	startTime time.Time
+	// stmtCtx is the context of the statement, it's kept because the session may run other statements
+	// before the result set of the statement is closed.
+	stmtCtx  *variable.StatementContext
+	rowsSent uint64
 }
 
 func (a *statement) OriginText() string {
@@ -95,7 +117,9 @@ func (a *statement) OriginText() string {
 // result, execution is done after this function returns, in the returned ast.RecordSet Next method.
 func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
 	a.startTime = time.Now()
+	a.rowsSent = 0
 	a.ctx = ctx
+	a.stmtCtx = ctx.GetSessionVars().StmtCtx
 	if _, ok := a.plan.(*plan.Execute); !ok {
 		// Do not sync transaction for Execute statement, because the real optimization work is done in
 		// "ExecuteExec.Build".
@@ -125,6 +149,7 @@ func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
 			return nil, errors.Trace(err)
 		}
 		a.text = executorExec.Stmt.Text()
+		a.stmtNode = executorExec.Stmt
 		a.plan = executorExec.Plan
 		e = executorExec.StmtExec
 	}
@@ -136,6 +161,11 @@ func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
 		pi.SetProcessInfo(a.OriginText())
 	}
 
+	// The statements which read history data are not locking reads, the write statements fail below.
+	if txn := pessimisticTxn(ctx); txn != nil && ctx.GetSessionVars().SnapshotTS == 0 && isPessimisticStmt(a.stmtNode, e) {
+		return a.execPessimistic(ctx, txn, pi)
+	}
+
 	// Fields or Schema are only used for statements that return result set.
 	if e.Schema().Len() == 0 {
 		// Check if "tidb_snapshot" is set for the write executors.
@@ -153,7 +183,6 @@ func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
 				pi.SetProcessInfo("")
 			}
 			e.Close()
-			a.logSlowQuery()
 		}()
 		for {
 			row, err := e.Next()
@@ -177,23 +206,209 @@ func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
 	}, nil
 }
 
-const (
-	queryLogMaxLen = 2048
-	slowThreshold  = 300 * time.Millisecond
-)
+const queryLogMaxLen = 2048
+
+// FinishStatement finishes the statement which doesn't return a result set, it's called after the
+// transaction of the statement is committed, so the commit time is counted. The statements which return
+// a result set are finished when the result set is closed.
+func FinishStatement(st ast.Statement, err error) {
+	if a, ok := st.(*statement); ok && a.stmtCtx != nil {
+		a.finish(err)
+	}
+}
+
+// finish writes the slow query log and the audit log and summarizes the statement, err is the error of the statement.
+func (a *statement) finish(err error) {
+	a.logSlowQuery()
+	a.summarize(err)
+	a.audit(err)
+}
+
+// audit writes the statement to the audit log.
+func (a *statement) audit(err error) {
+	var rowsAffected uint64
+	if err == nil {
+		rowsAffected = a.stmtCtx.AffectedRows()
+	}
+	writeAuditLog(a.ctx, a.stmtNode, a.text, rowsAffected, err)
+}
+
+// AuditCompileError writes the statement which fails to compile to the audit log, e.g. the statement
+// which is denied for the lack of privileges.
+func AuditCompileError(ctx context.Context, node ast.StmtNode, err error) {
+	writeAuditLog(ctx, node, node.Text(), 0, err)
+}
+
+// writeAuditLog writes the statement to the audit log, the internal statements and the statements which
+// don't belong to a client connection are not logged.
+func writeAuditLog(ctx context.Context, node ast.StmtNode, sql string, rowsAffected uint64, err error) {
+	sessVars := ctx.GetSessionVars()
+	if sessVars.InRestrictedSQL || sessVars.ConnectionID == 0 || !audit.Enabled() {
+		return
+	}
+	user, host := audit.SplitUser(sessVars.User)
+	e := &audit.Event{
+		Time:         time.Now(),
+		Type:         audit.TypeQuery,
+		ConnID:       sessVars.ConnectionID,
+		User:         user,
+		Host:         host,
+		DB:           sessVars.CurrentDB,
+		Class:        audit.ClassOther,
+		SQL:          audit.SQL(logText(node, sql)),
+		AffectedRows: rowsAffected,
+		ErrCode:      audit.ErrCode(err),
+	}
+	if node != nil {
+		e.Class = auditClass(node)
+		e.StmtType = strings.TrimSuffix(reflect.TypeOf(node).Elem().Name(), "Stmt")
+		e.System = changesSystemTables(node, sessVars.CurrentDB)
+	}
+	audit.Write(e)
+}
+
+// changesSystemTables returns true if the statement writes the tables of the mysql schema, or reloads the
+// privileges and the audit log filter from them.
+func changesSystemTables(node ast.StmtNode, currentDB string) bool {
+	var target ast.Node
+	switch x := node.(type) {
+	case *ast.FlushStmt:
+		return x.Tp == ast.FlushPrivileges
+	case *ast.InsertStmt:
+		target = x.Table
+	case *ast.UpdateStmt:
+		target = x.TableRefs
+	case *ast.DeleteStmt:
+		target = x.TableRefs
+	case *ast.LoadDataStmt:
+		target = x.Table
+	case ast.DDLNode:
+		target = x
+	}
+	if target == nil {
+		return false
+	}
+	finder := &systemTableFinder{currentDB: currentDB}
+	target.Accept(finder)
+	return finder.found
+}
+
+// systemTableFinder finds the tables of the mysql schema.
+type systemTableFinder struct {
+	currentDB string
+	found     bool
+}
+
+// Enter implements ast.Visitor interface.
+func (f *systemTableFinder) Enter(in ast.Node) (ast.Node, bool) {
+	if tn, ok := in.(*ast.TableName); ok {
+		schema := tn.Schema.L
+		if schema == "" {
+			schema = strings.ToLower(f.currentDB)
+		}
+		f.found = f.found || schema == mysql.SystemDB
+	}
+	return in, f.found
+}
+
+// Leave implements ast.Visitor interface.
+func (f *systemTableFinder) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
 
+func auditClass(node ast.StmtNode) string {
+	switch node.(type) {
+	case ast.DDLNode:
+		return audit.ClassDDL
+	case *ast.CreateUserStmt, *ast.AlterUserStmt, *ast.DropUserStmt, *ast.SetPwdStmt, *ast.GrantStmt,
+		*ast.RevokeStmt, *ast.GrantRoleStmt, *ast.RevokeRoleStmt, *ast.SetDefaultRoleStmt:
+		return audit.ClassDCL
+	case *ast.ShowStmt:
+		return audit.ClassOther
+	case ast.DMLNode:
+		return audit.ClassDML
+	}
+	return audit.ClassOther
+}
+
+// summarize aggregates the statement in performance_schema.events_statements_summary_by_digest.
+func (a *statement) summarize(err error) {
+	if !perfschema.IsEnabled() {
+		return
+	}
+	dom := sessionctx.GetDomain(a.ctx)
+	if dom == nil {
+		return
+	}
+	details := &a.stmtCtx.ExecDetails
+	var planDigest string
+	if a.plan != nil {
+		planDigest = plan.Digest(a.plan)
+	}
+	var rowsAffected uint64
+	// The rows of a failed statement are rolled back.
+	if err == nil {
+		rowsAffected = a.stmtCtx.AffectedRows()
+	}
+	dom.PerfSchema().SummarizeStatement(&perfschema.StatementExecInfo{
+		SchemaName:   a.ctx.GetSessionVars().CurrentDB,
+		SQL:          logText(a.stmtNode, a.text),
+		PlanDigest:   planDigest,
+		StartTime:    a.startTime,
+		Latency:      details.ParseTime + details.CompileTime + time.Since(a.startTime),
+		Failed:       err != nil,
+		Warnings:     uint64(a.stmtCtx.WarningCount()),
+		RowsAffected: rowsAffected,
+		RowsSent:     a.rowsSent,
+		RowsExamined: uint64(details.ProcessKeys()),
+	})
+}
+
+// logSlowQuery writes the statement to the slow query log if its query time exceeds tidb_slow_log_threshold,
+// otherwise the statement is logged at the debug level.
 func (a *statement) logSlowQuery() {
-	costTime := time.Since(a.startTime)
-	sql := a.text
-	if len(sql) > queryLogMaxLen {
-		sql = sql[:queryLogMaxLen] + fmt.Sprintf("(len:%d)", len(sql))
+	sessVars := a.ctx.GetSessionVars()
+	details := &a.stmtCtx.ExecDetails
+	execTime := time.Since(a.startTime)
+	queryTime := details.ParseTime + details.CompileTime + execTime
+	connID := sessVars.ConnectionID
+	sql := logText(a.stmtNode, a.text)
+	if queryTime < time.Duration(sessVars.SlowLogThreshold)*time.Millisecond {
+		if len(sql) > queryLogMaxLen {
+			sql = sql[:queryLogMaxLen] + fmt.Sprintf("(len:%d)", len(sql))
+		}
+		log.Debugf("[%d][TIME_QUERY] %v %s", connID, queryTime, sql)
+		return
+	}
+	var planDigest string
+	if a.plan != nil {
+		planDigest = plan.Digest(a.plan)
 	}
-	connID := a.ctx.GetSessionVars().ConnectionID
-	if costTime < slowThreshold {
-		log.Debugf("[%d][TIME_QUERY] %v %s", connID, costTime, sql)
-	} else {
-		log.Warnf("[%d][TIME_QUERY] %v %s", connID, costTime, sql)
+	slowlog.Write(&slowlog.Entry{
+		Time:        time.Now(),
+		ConnID:      connID,
+		User:        sessVars.User,
+		DB:          sessVars.CurrentDB,
+		QueryTime:   queryTime,
+		ParseTime:   details.ParseTime,
+		CompileTime: details.CompileTime,
+		ExecuteTime: execTime - details.CommitTime,
+		CommitTime:  details.CommitTime,
+		ProcessKeys: details.ProcessKeys(),
+		CopTasks:    details.CopTasks(),
+		PlanDigest:  planDigest,
+		SQL:         sql,
+	})
+}
+
+// logText returns the text of the statement which is written to the logs and performance_schema, the
+// passwords in the statements which create or alter users, set passwords or grant privileges are redacted.
+func logText(node ast.StmtNode, text string) string {
+	switch node.(type) {
+	case *ast.CreateUserStmt, *ast.AlterUserStmt, *ast.SetPwdStmt, *ast.GrantStmt:
+		return parser.RedactPasswords(text)
 	}
+	return text
 }
 
 // IsPointGetWithPKOrUniqueKeyByAutoCommit returns true when meets following conditions:
@@ -0,0 +1,139 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/audit"
+	"github.com/pingcap/tidb/util/slowlog"
+	"github.com/pingcap/tidb/util/testkit"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func readAuditEvents(c *C, path string) []string {
+	f, err := os.Open(path)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	var events []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e audit.Event
+		c.Assert(json.Unmarshal(scanner.Bytes(), &e), IsNil)
+		events = append(events, fmt.Sprintf("%s %s %s %s %d %d", e.Class, e.StmtType, e.DB, e.SQL, e.AffectedRows, e.ErrCode))
+	}
+	c.Assert(scanner.Err(), IsNil)
+	return events
+}
+
+func (s *testSuite) TestAuditLog(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)
+	}()
+	f, err := ioutil.TempFile("", "tidb-audit")
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+	defer os.Remove(f.Name())
+	c.Assert(audit.Setup(audit.Config{File: f.Name()}), IsNil)
+	defer audit.Setup(audit.Config{})
+
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("create table audit_t (a int)")
+	// The DML statements are not logged by default.
+	tk.MustExec("insert audit_t values (1), (2)")
+	tk.MustQuery("select * from audit_t").Check(testkit.Rows("1", "2"))
+	_, err = tk.Exec("drop table audit_not_exist")
+	c.Assert(err, NotNil)
+	tk.MustExec("create user 'audit_user'@'localhost' identified by 'secret'")
+	c.Assert(readAuditEvents(c, f.Name()), DeepEquals, []string{
+		"DDL CreateTable test create table audit_t ( a int ) 0 0",
+		"DDL DropTable test drop table audit_not_exist 0 1051",
+		"DCL CreateUser test create user ? @ ? identified by ? 0 0",
+	})
+
+	// The rules of mysql.audit_log_filter are reloaded by FLUSH PRIVILEGES. The changes of the system tables,
+	// FLUSH PRIVILEGES and the DCL statements are logged even if they match an exclude rule.
+	tk.MustExec("insert mysql.audit_log_filter (class) values ('Delete'), ('%')")
+	tk.MustExec("update mysql.audit_log_filter set action = 'exclude' where class = '%'")
+	tk.MustExec("flush privileges")
+	tk.MustExec("delete from audit_t where a = 1")
+	tk.MustExec("drop user 'audit_user'@'localhost'")
+	tk.MustExec("drop table audit_t")
+	tk.MustExec("delete from mysql.audit_log_filter")
+	tk.MustExec("flush privileges")
+	events := readAuditEvents(c, f.Name())
+	c.Assert(events[3:], DeepEquals, []string{
+		"DML Insert test insert mysql . audit_log_filter ( class ) values ( ? ) , ( ? ) 2 0",
+		"DML Update test update mysql . audit_log_filter set action = ? where class = ? 1 0",
+		"Other Flush test flush privileges 0 0",
+		"DCL DropUser test drop user ? @ ? 0 0",
+		"DML Delete test delete from mysql . audit_log_filter 2 0",
+		"Other Flush test flush privileges 0 0",
+	})
+	logFile, err := os.Open(f.Name())
+	c.Assert(err, IsNil)
+	defer logFile.Close()
+	c.Assert(audit.Verify(logFile, ""), IsNil)
+}
+
+func (s *testSuite) TestAuditLogRedactPasswords(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)
+	}()
+	f, err := ioutil.TempFile("", "tidb-audit")
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+	defer os.Remove(f.Name())
+	c.Assert(audit.Setup(audit.Config{File: f.Name(), FullSQL: true}), IsNil)
+	defer audit.Setup(audit.Config{})
+	slowFile, err := ioutil.TempFile("", "tidb-slow")
+	c.Assert(err, IsNil)
+	c.Assert(slowFile.Close(), IsNil)
+	defer os.Remove(slowFile.Name())
+	c.Assert(slowlog.SetFile(slowFile.Name()), IsNil)
+	defer slowlog.SetFile("")
+
+	// The passwords are redacted in the audit log and the slow log even if the full SQL is logged.
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("set tidb_slow_log_threshold = 0")
+	tk.MustExec("create user 'audit_pwd'@'%' identified by 'secret1'")
+	tk.MustExec("alter user 'audit_pwd'@'%' identified by 'secret2'")
+	tk.MustExec("set password for 'audit_pwd'@'%' = 'secret3'")
+	tk.MustExec("grant select on test.* to 'audit_pwd'@'%' identified by 'secret4'")
+	tk.MustExec("drop user 'audit_pwd'@'%'")
+	tk.MustExec("set tidb_slow_log_threshold = 300")
+	c.Assert(readAuditEvents(c, f.Name()), DeepEquals, []string{
+		"DCL CreateUser test create user 'audit_pwd'@'%' identified by '***' 0 0",
+		"DCL AlterUser test alter user 'audit_pwd'@'%' identified by '***' 0 0",
+		"DCL SetPwd test set password for 'audit_pwd'@'%' = '***' 0 0",
+		"DCL Grant test grant select on test.* to 'audit_pwd'@'%' identified by '***' 0 0",
+		"DCL DropUser test drop user 'audit_pwd'@'%' 0 0",
+	})
+	tk.MustQuery("select `query` from information_schema.slow_query where `query` like '%audit_pwd%'").Check(testkit.Rows(
+		"create user 'audit_pwd'@'%' identified by '***'",
+		"alter user 'audit_pwd'@'%' identified by '***'",
+		"set password for 'audit_pwd'@'%' = '***'",
+		"grant select on test.* to 'audit_pwd'@'%' identified by '***'",
+		"drop user 'audit_pwd'@'%'",
+	))
+}
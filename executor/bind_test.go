@@ -0,0 +1,180 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/util/slowlog"
+	"github.com/pingcap/tidb/util/testkit"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+// explainScan returns the type of the first operator in the plan of sql.
+func explainScan(tk *testkit.TestKit, sql string) string {
+	result := tk.MustQuery("explain " + sql)
+	rowStr := fmt.Sprintf("%s", result.Rows())
+	return strings.TrimPrefix(strings.Split(rowStr, "_")[0], "[[")
+}
+
+func (s *testSuite) TestSessionBinding(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, index idx_a(a))")
+	tk.MustExec("insert into t values (1), (2), (3)")
+
+	c.Assert(explainScan(tk, "select * from t where a = 1"), Equals, "IndexScan")
+
+	tk.MustExec("create binding for select * from t where a = 1 using select * from t ignore index(idx_a) where a = 1")
+	tk.MustQuery("show global bindings").Check(testkit.Rows())
+	rows := tk.MustQuery("show bindings").Rows()
+	c.Assert(rows, HasLen, 1)
+	c.Assert(rows[0][0], Equals, "select * from t where a = ?")
+	c.Assert(rows[0][1], Equals, "select * from t ignore index(idx_a) where a = 1")
+	c.Assert(rows[0][2], Equals, "test")
+
+	// Statements differing in literals, spaces and letter case share the binding.
+	c.Assert(explainScan(tk, "select * from t where a = 1"), Equals, "TableScan")
+	c.Assert(explainScan(tk, "SELECT * FROM t  WHERE a = 3"), Equals, "TableScan")
+	tk.MustQuery("select * from t where a = 2").Check(testkit.Rows("2"))
+	c.Assert(explainScan(tk, "select * from t where a > 1"), Equals, "IndexScan")
+
+	// The binding only takes effect in the database where it is created.
+	tk.MustExec("create database if not exists binding_db")
+	tk.MustExec("use binding_db")
+	c.Assert(explainScan(tk, "select * from test.t where a = 1"), Equals, "IndexScan")
+	tk.MustExec("use test")
+
+	// The binding doesn't leak to other sessions.
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	c.Assert(explainScan(tk1, "select * from t where a = 1"), Equals, "IndexScan")
+
+	// The bind sql must select from the same tables.
+	_, err := tk.Exec("create binding for select * from t where a = 1 using select * from t, t t1 where t.a = 1")
+	c.Assert(err, NotNil)
+
+	tk.MustExec("drop binding for select * from t where a = 2")
+	tk.MustQuery("show session bindings").Check(testkit.Rows())
+	c.Assert(explainScan(tk, "select * from t where a = 1"), Equals, "IndexScan")
+	tk.MustExec("drop database binding_db")
+}
+
+func (s *testSuite) TestGlobalBinding(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, index idx_a(a))")
+	tk.MustExec("insert into t values (1), (2), (3)")
+
+	tk.MustExec("create global binding for select * from t where a = 1 using select * from t ignore index(idx_a) where a = 1")
+	rows := tk.MustQuery("show global bindings").Rows()
+	c.Assert(rows, HasLen, 1)
+	c.Assert(rows[0][0], Equals, "select * from t where a = ?")
+	tk.MustQuery("select count(*) from mysql.bind_info").Check(testkit.Rows("1"))
+
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	c.Assert(explainScan(tk1, "select * from t where a = 10"), Equals, "TableScan")
+
+	// Prepared statements match the binding too.
+	tk1.MustExec(`prepare stmt from "select * from t where a = ?"`)
+	tk1.MustExec("set @a = 2")
+	tk1.MustQuery("execute stmt using @a").Check(testkit.Rows("2"))
+
+	// A session binding takes precedence over the global one.
+	tk1.MustExec("create binding for select * from t where a = 1 using select * from t use index(idx_a) where a = 1")
+	c.Assert(explainScan(tk1, "select * from t where a = 1"), Equals, "IndexScan")
+	tk1.MustExec("drop session binding for select * from t where a = 1")
+	c.Assert(explainScan(tk1, "select * from t where a = 1"), Equals, "TableScan")
+
+	// Creating the binding again replaces the old one.
+	tk.MustExec("create global binding for select * from t where a = 1 using select * from t use index(idx_a) where a = 1")
+	tk.MustQuery("select count(*) from mysql.bind_info").Check(testkit.Rows("1"))
+	c.Assert(explainScan(tk1, "select * from t where a = 1"), Equals, "IndexScan")
+
+	tk.MustExec("drop global binding for select * from t where a = 1")
+	tk.MustQuery("show global bindings").Check(testkit.Rows())
+	tk.MustQuery("select count(*) from mysql.bind_info").Check(testkit.Rows("0"))
+}
+
+func (s *testSuite) TestBindingPlanCache(c *C) {
+	orgEnable := plan.PreparedPlanCacheEnabled
+	defer func() {
+		plan.PreparedPlanCacheEnabled = orgEnable
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	plan.PreparedPlanCacheEnabled = true
+	f, err := ioutil.TempFile("", "tidb-slow")
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+	defer os.Remove(f.Name())
+	c.Assert(slowlog.SetFile(f.Name()), IsNil)
+	defer slowlog.SetFile("")
+
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, index idx_a(a))")
+	tk.MustExec("insert into t values (1), (2), (3)")
+	tk.MustExec("create global binding for select * from t where a = 1 using select * from t ignore index(idx_a) where a = 1")
+
+	// The plans are logged in the slow log, the plan of every execution is compared with the plan of
+	// the same query.
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	tk1.MustExec("set tidb_slow_log_threshold = 0")
+	tk1.MustExec(`prepare stmt from "select * from t where a = ?"`)
+	tk1.MustExec("set @a = 2")
+	execute := func() {
+		tk1.MustQuery("execute stmt using @a").Check(testkit.Rows("2"))
+		tk1.MustQuery("select * from t where a = 2").Check(testkit.Rows("2"))
+	}
+	execute()
+	execute()
+	c.Assert(tk1.Se.PreparedPlanCache().Size(), Equals, 1)
+	// The cached plan is rebuilt after the binding is dropped or created.
+	tk.MustExec("drop global binding for select * from t where a = 1")
+	execute()
+	tk.MustExec("create global binding for select * from t where a = 1 using select * from t ignore index(idx_a) where a = 1")
+	execute()
+	tk1.MustExec("set tidb_slow_log_threshold = 300")
+	tk.MustExec("drop global binding for select * from t where a = 1")
+
+	// The executions are logged with the text of the prepared statement.
+	rows := tk.MustQuery("select plan_digest from information_schema.slow_query where `query` like 'select * from t where a = %'").Rows()
+	c.Assert(rows, HasLen, 8)
+	for i := 0; i < len(rows); i += 2 {
+		c.Assert(rows[i][0], Equals, rows[i+1][0])
+	}
+	c.Assert(rows[0][0], Equals, rows[2][0])
+	c.Assert(rows[2][0], Not(Equals), rows[4][0])
+	c.Assert(rows[4][0], Not(Equals), rows[6][0])
+	c.Assert(rows[2][0], Equals, rows[6][0])
+}
@@ -26,6 +26,8 @@ import (
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/table/tables"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -46,6 +48,13 @@ func newExecutorBuilder(ctx context.Context, is infoschema.InfoSchema) *executor
 }
 
 func (b *executorBuilder) build(p plan.Plan) Executor {
+	switch p.(type) {
+	case *plan.Insert, *plan.Update, *plan.Delete, *plan.LoadData:
+		if b.ctx.GetSessionVars().TxnCtx.ReadOnly {
+			b.err = ErrCantExecuteInReadOnlyTxn
+			return nil
+		}
+	}
 	switch v := p.(type) {
 	case nil:
 		return nil
@@ -105,6 +114,8 @@ func (b *executorBuilder) build(p plan.Plan) Executor {
 		return b.buildTableScan(v)
 	case *plan.PhysicalIndexScan:
 		return b.buildIndexScan(v)
+	case *plan.PhysicalIndexMergeReader:
+		return b.buildIndexMergeReader(v)
 	case *plan.TableDual:
 		return b.buildTableDual(v)
 	case *plan.PhysicalApply:
@@ -216,6 +227,7 @@ func (b *executorBuilder) buildShow(v *plan.Show) Executor {
 		Table:       v.Table,
 		Column:      v.Column,
 		User:        v.User,
+		Roles:       v.Roles,
 		Flag:        v.Flag,
 		Full:        v.Full,
 		GlobalScope: v.GlobalScope,
@@ -225,6 +237,7 @@ func (b *executorBuilder) buildShow(v *plan.Show) Executor {
 	}
 	if e.Tp == ast.ShowGrants && len(e.User) == 0 {
 		e.User = e.ctx.GetSessionVars().User
+		e.Roles = e.ctx.GetSessionVars().ActiveRoles
 	}
 	return e
 }
@@ -303,6 +316,7 @@ func (b *executorBuilder) buildGrant(grant *ast.GrantStmt) Executor {
 		ObjectType: grant.ObjectType,
 		Level:      grant.Level,
 		Users:      grant.Users,
+		Require:    grant.Require,
 		WithGrant:  grant.WithGrant,
 		is:         b.is,
 	}
@@ -522,12 +536,40 @@ func (b *executorBuilder) buildTableDual(v *plan.TableDual) Executor {
 
 func (b *executorBuilder) getStartTS() uint64 {
 	startTS := b.ctx.GetSessionVars().SnapshotTS
+	if startTS == 0 {
+		startTS = b.ctx.GetSessionVars().TxnCtx.StaleReadTS
+	}
+	if startTS == 0 {
+		// The statements of a pessimistic transaction which write or lock the rows read at the forUpdateTS.
+		startTS = b.ctx.GetSessionVars().StmtCtx.ForUpdateTS
+	}
 	if startTS == 0 {
 		startTS = b.ctx.Txn().StartTS()
 	}
 	return startTS
 }
 
+// getReadTS returns the timestamp to read the table source at.
+func (b *executorBuilder) getReadTS(asOfTS uint64) uint64 {
+	if asOfTS != 0 {
+		return asOfTS
+	}
+	return b.getStartTS()
+}
+
+// getTable returns the table to read, the table read AS OF a timestamp may not exist in the current schema.
+func (b *executorBuilder) getTable(tblInfo *model.TableInfo, asOfTS uint64) table.Table {
+	if asOfTS == 0 {
+		tbl, _ := b.is.TableByID(tblInfo.ID)
+		return tbl
+	}
+	tbl, err := tables.TableFromMeta(nil, tblInfo)
+	if err != nil {
+		b.err = errors.Trace(err)
+	}
+	return tbl
+}
+
 func (b *executorBuilder) buildMemTable(v *plan.PhysicalMemTable) Executor {
 	table, _ := b.is.TableByID(v.Table.ID)
 	ts := &TableScanExec{
@@ -544,11 +586,11 @@ func (b *executorBuilder) buildMemTable(v *plan.PhysicalMemTable) Executor {
 }
 
 func (b *executorBuilder) buildTableScan(v *plan.PhysicalTableScan) Executor {
-	startTS := b.getStartTS()
+	startTS := b.getReadTS(v.AsOfTS)
+	table := b.getTable(v.Table, v.AsOfTS)
 	if b.err != nil {
 		return nil
 	}
-	table, _ := b.is.TableByID(v.Table.ID)
 	client := b.ctx.GetClient()
 	supportDesc := client.SupportRequestType(kv.ReqTypeSelect, kv.ReqSubTypeDesc)
 	e := &XSelectTableExec{
@@ -574,11 +616,11 @@ func (b *executorBuilder) buildTableScan(v *plan.PhysicalTableScan) Executor {
 }
 
 func (b *executorBuilder) buildIndexScan(v *plan.PhysicalIndexScan) Executor {
-	startTS := b.getStartTS()
+	startTS := b.getReadTS(v.AsOfTS)
+	table := b.getTable(v.Table, v.AsOfTS)
 	if b.err != nil {
 		return nil
 	}
-	table, _ := b.is.TableByID(v.Table.ID)
 	client := b.ctx.GetClient()
 	supportDesc := client.SupportRequestType(kv.ReqTypeIndex, kv.ReqSubTypeDesc)
 	e := &XSelectIndexExec{
@@ -611,6 +653,59 @@ func (b *executorBuilder) buildIndexScan(v *plan.PhysicalIndexScan) Executor {
 	return e
 }
 
+func (b *executorBuilder) buildIndexMergeReader(v *plan.PhysicalIndexMergeReader) Executor {
+	// Only the selection can be pushed to the table lookup.
+	var conds []expression.Expression
+	var tablePlan plan.Plan = v.TablePlan
+	if sel, ok := tablePlan.(*plan.Selection); ok {
+		conds = sel.Conditions
+		tablePlan = sel.Children()[0]
+	}
+	ts, ok := tablePlan.(*plan.PhysicalTableScan)
+	if !ok {
+		b.err = ErrUnknownPlan.Gen("Unknown Plan %T in index merge", v.TablePlan)
+		return nil
+	}
+	e := &IndexMergeReaderExec{
+		ctx:          b.ctx,
+		intersection: v.Intersection,
+	}
+	for _, partial := range v.PartialPlans {
+		is := partial.Copy().(*plan.PhysicalIndexScan)
+		is.DoubleRead = true
+		exec := b.buildIndexScan(is)
+		if b.err != nil {
+			return nil
+		}
+		e.partialExecs = append(e.partialExecs, exec.(*XSelectIndexExec))
+	}
+	lookup := v.PartialPlans[0].Copy().(*plan.PhysicalIndexScan)
+	lookup.Columns = ts.Columns
+	lookup.TableAsName = ts.TableAsName
+	lookup.OutOfOrder = true
+	lookup.DoubleRead = true
+	lookup.SetSchema(ts.Schema())
+	for _, cond := range conds {
+		cond.ResolveIndices(ts.Schema())
+	}
+	var remained []expression.Expression
+	lookup.TableConditionPBExpr, _, remained = plan.ExpressionsToPB(b.ctx.GetSessionVars().StmtCtx, conds, b.ctx.GetClient())
+	exec := b.buildIndexScan(lookup)
+	if b.err != nil {
+		return nil
+	}
+	e.lookupExec = exec.(*XSelectIndexExec)
+	if len(remained) > 0 {
+		return &SelectionExec{
+			Src:        e,
+			schema:     v.Schema(),
+			ctx:        b.ctx,
+			Conditions: remained,
+		}
+	}
+	return e
+}
+
 func (b *executorBuilder) buildSort(v *plan.Sort) Executor {
 	src := b.build(v.Children()[0])
 	if v.ExecLimit != nil {
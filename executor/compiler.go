@@ -44,9 +44,10 @@ func (c *Compiler) Compile(ctx context.Context, node ast.StmtNode) (ast.Statemen
 	}
 	stmtCount(node, p)
 	sa := &statement{
-		is:   is,
-		plan: p,
-		text: node.Text(),
+		is:       is,
+		plan:     p,
+		text:     node.Text(),
+		stmtNode: node,
 	}
 	return sa, nil
 }
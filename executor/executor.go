@@ -55,17 +55,24 @@ var (
 
 // Error instances.
 var (
-	ErrUnknownPlan     = terror.ClassExecutor.New(codeUnknownPlan, "Unknown plan")
-	ErrPrepareMulti    = terror.ClassExecutor.New(codePrepareMulti, "Can not prepare multiple statements")
-	ErrStmtNotFound    = terror.ClassExecutor.New(codeStmtNotFound, "Prepared statement not found")
-	ErrSchemaChanged   = terror.ClassExecutor.New(codeSchemaChanged, "Schema has changed")
-	ErrWrongParamCount = terror.ClassExecutor.New(codeWrongParamCount, "Wrong parameter count")
-	ErrRowKeyCount     = terror.ClassExecutor.New(codeRowKeyCount, "Wrong row key entry count")
-	ErrPrepareDDL      = terror.ClassExecutor.New(codePrepareDDL, "Can not prepare DDL statements")
-	ErrPasswordNoMatch = terror.ClassExecutor.New(CodePasswordNoMatch, "Can't find any matching row in the user table")
-	ErrResultIsEmpty   = terror.ClassExecutor.New(codeResultIsEmpty, "result is empty")
-	ErrBuildExecutor   = terror.ClassExecutor.New(codeErrBuildExec, "Failed to build executor")
-	ErrBatchInsertFail = terror.ClassExecutor.New(codeBatchInsertFail, "Batch insert failed, please clean the table and try again.")
+	ErrUnknownPlan       = terror.ClassExecutor.New(codeUnknownPlan, "Unknown plan")
+	ErrPrepareMulti      = terror.ClassExecutor.New(codePrepareMulti, "Can not prepare multiple statements")
+	ErrStmtNotFound      = terror.ClassExecutor.New(codeStmtNotFound, "Prepared statement not found")
+	ErrSchemaChanged     = terror.ClassExecutor.New(codeSchemaChanged, "Schema has changed")
+	ErrWrongParamCount   = terror.ClassExecutor.New(codeWrongParamCount, "Wrong parameter count")
+	ErrRowKeyCount       = terror.ClassExecutor.New(codeRowKeyCount, "Wrong row key entry count")
+	ErrPrepareDDL        = terror.ClassExecutor.New(codePrepareDDL, "Can not prepare DDL statements")
+	ErrPasswordNoMatch   = terror.ClassExecutor.New(CodePasswordNoMatch, "Can't find any matching row in the user table")
+	ErrPluginIsNotLoaded = terror.ClassExecutor.New(CodePluginIsNotLoaded, "Plugin '%-.192s' is not loaded")
+	ErrResultIsEmpty     = terror.ClassExecutor.New(codeResultIsEmpty, "result is empty")
+	ErrBuildExecutor     = terror.ClassExecutor.New(codeErrBuildExec, "Failed to build executor")
+	ErrBatchInsertFail   = terror.ClassExecutor.New(codeBatchInsertFail, "Batch insert failed, please clean the table and try again.")
+	ErrRoleNotGranted    = terror.ClassExecutor.New(CodeRoleNotGranted, mysql.MySQLErrName[mysql.ErrRoleNotGranted])
+	ErrNotValidPassword  = terror.ClassExecutor.New(CodeNotValidPassword, mysql.MySQLErrName[mysql.ErrNotValidPassword])
+	ErrWrongValue        = terror.ClassExecutor.New(CodeWrongValue, mysql.MySQLErrName[mysql.ErrWrongValue])
+	ErrKillDenied        = terror.ClassExecutor.New(CodeKillDenied, "You are not owner of thread %d")
+
+	ErrCantExecuteInReadOnlyTxn = terror.ClassExecutor.New(CodeCantExecuteInReadOnlyTxn, mysql.MySQLErrName[mysql.ErrCantExecuteInReadOnlyTransaction])
 )
 
 // Error codes.
@@ -81,8 +88,15 @@ const (
 	codeErrBuildExec    terror.ErrCode = 9
 	codeBatchInsertFail terror.ErrCode = 10
 	// MySQL error code
-	CodePasswordNoMatch terror.ErrCode = 1133
-	CodeCannotUser      terror.ErrCode = 1396
+	CodeKillDenied        terror.ErrCode = 1095
+	CodePasswordNoMatch   terror.ErrCode = 1133
+	CodeCannotUser        terror.ErrCode = 1396
+	CodePluginIsNotLoaded terror.ErrCode = 1524
+	CodeWrongValue        terror.ErrCode = 1525
+	CodeNotValidPassword  terror.ErrCode = 1819
+	CodeRoleNotGranted    terror.ErrCode = 3530
+
+	CodeCantExecuteInReadOnlyTxn terror.ErrCode = 1792
 )
 
 // Row represents a result set row, it may be returned from a table, a join, or a projection.
@@ -377,8 +391,15 @@ func init() {
 		}
 	}
 	tableMySQLErrCodes := map[terror.ErrCode]uint16{
-		CodeCannotUser:      mysql.ErrCannotUser,
-		CodePasswordNoMatch: mysql.ErrPasswordNoMatch,
+		CodeKillDenied:        mysql.ErrKillDenied,
+		CodeCannotUser:        mysql.ErrCannotUser,
+		CodePasswordNoMatch:   mysql.ErrPasswordNoMatch,
+		CodePluginIsNotLoaded: mysql.ErrPluginIsNotLoaded,
+		CodeWrongValue:        mysql.ErrWrongValue,
+		CodeNotValidPassword:  mysql.ErrNotValidPassword,
+		CodeRoleNotGranted:    mysql.ErrRoleNotGranted,
+
+		CodeCantExecuteInReadOnlyTxn: mysql.ErrCantExecuteInReadOnlyTransaction,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassExecutor] = tableMySQLErrCodes
 }
@@ -33,6 +33,7 @@ import (
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/store/tikv"
+	"github.com/pingcap/tidb/util/gcutil"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
@@ -1313,6 +1314,99 @@ func (s *testSuite) TestHistoryRead(c *C) {
 	tk.MustQuery("select * from history_read order by a").Check(testkit.Rows("2 <nil>", "4 <nil>", "8 8", "9 9"))
 }
 
+func (s *testSuite) TestStaleRead(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists stale_read")
+	tk.MustExec("create table stale_read (a int primary key, b int, index idx_b(b))")
+	tk.MustExec("insert stale_read values (1, 1)")
+	time.Sleep(time.Millisecond)
+	asOfTime := time.Now().Format("2006-01-02 15:04:05.999999")
+	asOf := " as of timestamp '" + asOfTime + "'"
+	time.Sleep(time.Millisecond)
+	tk.MustExec("insert stale_read values (2, 2)")
+	tk.MustExec("alter table stale_read add column c int")
+	tk.MustExec("update stale_read set c = a")
+
+	// The table is read with the schema and the data at the timestamp, and can be joined with the current data.
+	tk.MustQuery("select * from stale_read" + asOf).Check(testkit.Rows("1 1"))
+	tk.MustQuery("select b from stale_read" + asOf + " t where t.b > 0").Check(testkit.Rows("1"))
+	tk.MustQuery("select t1.b, t2.c from stale_read" + asOf + " t1 join stale_read t2 on t1.a = t2.a").Check(testkit.Rows("1 1"))
+	tk.MustExec("begin")
+	tk.MustExec("insert stale_read values (3, 3, 3)")
+	tk.MustQuery("select a from stale_read" + asOf).Check(testkit.Rows("1"))
+	tk.MustQuery("select a from stale_read").Check(testkit.Rows("1", "2", "3"))
+	tk.MustExec("rollback")
+
+	_, err := tk.Exec("select * from stale_read" + asOf + " for update")
+	c.Assert(plan.ErrStaleReadUnsupported.Equal(err), IsTrue)
+	_, err = tk.Exec("update stale_read" + asOf + " set b = 3")
+	c.Assert(plan.ErrStaleReadUnsupported.Equal(err), IsTrue)
+	_, err = tk.Exec("select * from information_schema.tables" + asOf)
+	c.Assert(plan.ErrStaleReadUnsupported.Equal(err), IsTrue)
+	_, err = tk.Exec("select * from stale_read as of timestamp date_add(now(), interval 1 hour)")
+	c.Assert(plan.ErrInvalidAsOfTS.Equal(err), IsTrue)
+
+	// The statements of a read only transaction read the data at its timestamp.
+	tk.MustExec("start transaction read only" + asOf)
+	tk.MustQuery("select * from stale_read").Check(testkit.Rows("1 1"))
+	tk.MustQuery("select a from stale_read where b = 1").Check(testkit.Rows("1"))
+	_, err = tk.Exec("insert stale_read values (4, 4)")
+	c.Assert(executor.ErrCantExecuteInReadOnlyTxn.Equal(err), IsTrue)
+	tk.MustQuery("select * from stale_read").Check(testkit.Rows("1 1"))
+	_, err = tk.Exec("select * from stale_read for update")
+	c.Assert(plan.ErrStaleReadUnsupported.Equal(err), IsTrue)
+	tk.MustExec("commit")
+	tk.MustQuery("select * from stale_read").Check(testkit.Rows("1 1 1", "2 2 2"))
+	tk.MustExec("start transaction read only")
+	tk.MustQuery("select * from stale_read").Check(testkit.Rows("1 1 1", "2 2 2"))
+	_, err = tk.Exec("delete from stale_read")
+	c.Assert(executor.ErrCantExecuteInReadOnlyTxn.Equal(err), IsTrue)
+	tk.MustExec("commit")
+	tk.MustExec("delete from stale_read where a = 2")
+
+	// The current schema is used if no DDL runs after the timestamp.
+	time.Sleep(time.Millisecond)
+	noDDLTime := time.Now().Format("2006-01-02 15:04:05.999999")
+	noDDLAsOf := " as of timestamp '" + noDDLTime + "'"
+	time.Sleep(time.Millisecond)
+	tk.MustExec("insert stale_read values (5, 5, 5)")
+	tk.MustQuery("select * from stale_read" + noDDLAsOf).Check(testkit.Rows("1 1 1"))
+	tk.MustExec("start transaction read only" + noDDLAsOf)
+	tk.MustQuery("select a from stale_read").Check(testkit.Rows("1"))
+	tk.MustExec("commit")
+
+	// The timestamp of a prepared statement is evaluated in each execution.
+	tk.MustExec("prepare stmt from 'select a from stale_read as of timestamp ?'")
+	tk.MustExec("set @ts = '" + noDDLTime + "'")
+	tk.MustQuery("execute stmt using @ts").Check(testkit.Rows("1"))
+	tk.MustExec("set @ts = '" + asOfTime + "'")
+	_, err = tk.Exec("execute stmt using @ts")
+	c.Assert(plan.ErrInvalidAsOfTS.Equal(err), IsTrue)
+	tk.MustExec("prepare now_stmt from 'select a from stale_read as of timestamp now(6)'")
+	time.Sleep(time.Millisecond)
+	tk.MustQuery("execute now_stmt").Check(testkit.Rows("1", "5"))
+	tk.MustExec("delete from stale_read where a = 5")
+	time.Sleep(time.Millisecond)
+	tk.MustQuery("execute now_stmt").Check(testkit.Rows("1"))
+	tk.MustExec("set @ts = '" + noDDLTime + "'")
+
+	// The data before the GC safe point can't be read.
+	safePoint := time.Now().Add(time.Second).Format(gcutil.TimeFormat)
+	tk.MustExec(fmt.Sprintf("insert mysql.tidb values ('%s', '%s', '')", gcutil.SafePointKey, safePoint))
+	defer tk.MustExec(fmt.Sprintf("delete from mysql.tidb where variable_name = '%s'", gcutil.SafePointKey))
+	_, err = tk.Exec("select * from stale_read" + asOf)
+	c.Assert(plan.ErrSnapshotTooOld.Equal(err), IsTrue)
+	_, err = tk.Exec("start transaction read only" + asOf)
+	c.Assert(plan.ErrSnapshotTooOld.Equal(err), IsTrue)
+	_, err = tk.Exec("execute stmt using @ts")
+	c.Assert(plan.ErrSnapshotTooOld.Equal(err), IsTrue)
+}
+
 func (s *testSuite) TestScanControlSelection(c *C) {
 	defer func() {
 		s.cleanEnv(c)
@@ -37,7 +37,15 @@ func (e *ExplainExec) Schema() *expression.Schema {
 }
 
 func (e *ExplainExec) prepareExplainInfo(p plan.Plan, parent plan.Plan) error {
-	for _, child := range p.Children() {
+	children := p.Children()
+	if reader, ok := p.(*plan.PhysicalIndexMergeReader); ok {
+		children = make([]plan.Plan, 0, len(reader.PartialPlans)+1)
+		for _, partial := range reader.PartialPlans {
+			children = append(children, partial)
+		}
+		children = append(children, reader.TablePlan)
+	}
+	for _, child := range children {
 		err := e.prepareExplainInfo(child, p)
 		if err != nil {
 			return errors.Trace(err)
@@ -282,10 +282,10 @@ func (s *testSuite) TestExplain(c *C) {
 		{
 			"select count(b.c2) from t1 a, t2 b where a.c1 = b.c2 group by a.c1",
 			[]string{
-				"TableScan_11", "TableScan_12", "HashAgg_13", "HashLeftJoin_10", "Projection_9",
+				"TableScan_10", "TableScan_11", "HashAgg_12", "HashLeftJoin_9", "Projection_8",
 			},
 			[]string{
-				"HashLeftJoin_10", "HashAgg_13", "HashLeftJoin_10", "Projection_9", "",
+				"HashLeftJoin_9", "HashAgg_12", "HashLeftJoin_9", "Projection_8", "",
 			},
 			[]string{`{
     "db": "test",
@@ -327,7 +327,7 @@ func (s *testSuite) TestExplain(c *C) {
     "GroupByItems": [
         "[b.c2]"
     ],
-    "child": "TableScan_12"
+    "child": "TableScan_11"
 }`,
 				`{
     "eqCond": [
@@ -336,14 +336,14 @@ func (s *testSuite) TestExplain(c *C) {
     "leftCond": null,
     "rightCond": null,
     "otherCond": null,
-    "leftPlan": "TableScan_11",
-    "rightPlan": "HashAgg_13"
+    "leftPlan": "TableScan_10",
+    "rightPlan": "HashAgg_12"
 }`,
 				`{
     "exprs": [
         "cast(join_agg_0)"
     ],
-    "child": "HashLeftJoin_10"
+    "child": "HashLeftJoin_9"
 }`,
 			},
 		},
@@ -26,7 +26,6 @@ import (
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/table"
-	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/sqlexec"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -45,6 +44,7 @@ type GrantExec struct {
 	ObjectType ast.ObjectTypeType
 	Level      *ast.GrantLevel
 	Users      []*ast.UserSpec
+	Require    ast.RequireType
 	WithGrant  bool
 
 	ctx  context.Context
@@ -75,18 +75,19 @@ func (e *GrantExec) Next() (*Row, error) {
 			return nil, errors.Trace(err)
 		}
 		if !exists {
-			pwd := ""
-			if user.AuthOpt != nil {
-				if user.AuthOpt.ByAuthString {
-					pwd = util.EncodePassword(user.AuthOpt.AuthString)
-				} else {
-					pwd = util.EncodePassword(user.AuthOpt.HashString)
-				}
+			plugin, pwd, authString, err := encodeAuthOption(user.AuthOpt, mysql.AuthNativePassword)
+			if err != nil {
+				return nil, errors.Trace(err)
 			}
-
-			user := fmt.Sprintf(`("%s", "%s", "%s")`, host, userName, pwd)
-			sql := fmt.Sprintf(`INSERT INTO %s.%s (Host, User, Password) VALUES %s;`, mysql.SystemDB, mysql.UserTable, user)
-			_, err := e.ctx.(sqlexec.SQLExecutor).Execute(sql)
+			user := fmt.Sprintf(`("%s", "%s", "%s", "%s", "%s")`, host, userName, pwd, plugin, authString)
+			sql := fmt.Sprintf(`INSERT INTO %s.%s (Host, User, Password, plugin, authentication_string) VALUES %s;`, mysql.SystemDB, mysql.UserTable, user)
+			_, _, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		if e.Require != ast.RequireUnspecified {
+			err = e.grantRequire(userName, host)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
@@ -232,6 +233,24 @@ func (e *GrantExec) grantPriv(priv *ast.PrivElem, user *ast.UserSpec) error {
 	}
 }
 
+// Manipulate the ssl_type column of mysql.user table.
+func (e *GrantExec) grantRequire(userName, host string) error {
+	sql := fmt.Sprintf(`UPDATE %s.%s SET ssl_type="%s" WHERE User="%s" AND Host="%s"`, mysql.SystemDB, mysql.UserTable, requireSSLType(e.Require), userName, host)
+	_, _, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+	return errors.Trace(err)
+}
+
+// requireSSLType returns the value of the ssl_type column in mysql.user table for the REQUIRE clause.
+func requireSSLType(require ast.RequireType) string {
+	switch require {
+	case ast.RequireSSL:
+		return mysql.SSLTypeAny
+	case ast.RequireX509:
+		return mysql.SSLTypeX509
+	}
+	return mysql.SSLTypeNone
+}
+
 // Manipulate mysql.user table.
 func (e *GrantExec) grantGlobalPriv(priv *ast.PrivElem, user *ast.UserSpec) error {
 	asgns, err := composeGlobalPrivUpdate(priv.Priv, "Y")
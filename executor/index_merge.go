@@ -0,0 +1,147 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+)
+
+// IndexMergeReaderExec reads the handles by several index scans, and merges them by union or intersection.
+// Then it looks up the table by the merged handles, which works like the double read of XSelectIndexExec.
+type IndexMergeReaderExec struct {
+	ctx          context.Context
+	partialExecs []*XSelectIndexExec
+	// lookupExec is only used to look up the table, its index request is never sent.
+	lookupExec   *XSelectIndexExec
+	intersection bool
+
+	tasks  []*lookupTableTask
+	cursor int
+}
+
+// Schema implements the Executor Schema interface.
+func (e *IndexMergeReaderExec) Schema() *expression.Schema {
+	return e.lookupExec.Schema()
+}
+
+// Close implements the Executor Close interface.
+func (e *IndexMergeReaderExec) Close() error {
+	// Wait for the running tasks, so no worker is left behind.
+	for _, task := range e.tasks {
+		if !task.done {
+			<-task.doneCh
+			task.done = true
+		}
+	}
+	e.tasks = nil
+	e.cursor = 0
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *IndexMergeReaderExec) Next() (*Row, error) {
+	if e.tasks == nil {
+		handles, err := e.fetchHandles()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		e.startLookup(handles)
+	}
+	for e.cursor < len(e.tasks) {
+		row, err := e.tasks[e.cursor].getRow()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if row != nil {
+			return row, nil
+		}
+		e.cursor++
+	}
+	return nil, nil
+}
+
+// fetchHandles reads the handles of all the partial index scans, and returns the merged handles in order.
+func (e *IndexMergeReaderExec) fetchHandles() ([]int64, error) {
+	var merged map[int64]struct{}
+	for i, partial := range e.partialExecs {
+		handles, err := partial.fetchAllHandles()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if i == 0 || !e.intersection {
+			if merged == nil {
+				merged = make(map[int64]struct{}, len(handles))
+			}
+			for _, h := range handles {
+				merged[h] = struct{}{}
+			}
+			continue
+		}
+		intersected := make(map[int64]struct{}, len(merged))
+		for _, h := range handles {
+			if _, ok := merged[h]; ok {
+				intersected[h] = struct{}{}
+			}
+		}
+		merged = intersected
+	}
+	handles := make([]int64, 0, len(merged))
+	for h := range merged {
+		handles = append(handles, h)
+	}
+	sort.Sort(int64Slice(handles))
+	return handles, nil
+}
+
+// startLookup builds the lookup table tasks of the handles and executes them in background workers.
+func (e *IndexMergeReaderExec) startLookup(handles []int64) {
+	e.tasks = e.lookupExec.buildTableTasks(handles)
+	workCh := make(chan *lookupTableTask, len(e.tasks))
+	for _, task := range e.tasks {
+		workCh <- task
+	}
+	close(workCh)
+	concurrency := e.ctx.GetSessionVars().IndexLookupConcurrency
+	if concurrency > len(e.tasks) {
+		concurrency = len(e.tasks)
+	}
+	for i := 0; i < concurrency; i++ {
+		go e.lookupExec.pickAndExecTask(workCh)
+	}
+}
+
+// fetchAllHandles sends the index request and reads all the handles from it.
+func (e *XSelectIndexExec) fetchAllHandles() ([]int64, error) {
+	idxResult, err := e.doIndexRequest()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	idxResult.Fetch(e.ctx.GoCtx())
+	defer idxResult.Close()
+	var handles []int64
+	for {
+		partialHandles, finish, err := extractHandlesFromIndexResult(idxResult)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if finish {
+			return handles, nil
+		}
+		handles = append(handles, partialHandles...)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor_test
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/util/testkit"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func (s *testSuite) TestIndexMerge(c *C) {
+	useDAG := plan.UseDAGPlanBuilder
+	defer func() {
+		plan.UseDAGPlanBuilder = useDAG
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b int, c int, index idx_a(a), index idx_b(b))")
+	tk.MustExec("insert into t values (1, 1, 1), (1, 2, 2), (2, 2, 3), (3, 3, 4), (2, 1, 5)")
+	// Index merge is only planned by the DAG plan builder.
+	plan.UseDAGPlanBuilder = true
+
+	rows := tk.MustQuery("explain select * from t where a = 1 or b = 2").Rows()
+	// The two index scans, the table scan with the selection over it, and the index merge reader.
+	c.Assert(rows, HasLen, 5)
+	reader := rows[4][0].(string)
+	c.Assert(reader, Matches, "IndexMerge_.*")
+	for _, row := range rows[:4] {
+		c.Assert(row[0], Not(Equals), reader)
+	}
+	c.Assert(rows[0][2], Equals, reader)
+	c.Assert(rows[1][2], Equals, reader)
+
+	tk.MustQuery("select c from t where a = 1 or b = 2 order by c").Check(testkit.Rows("1", "2", "3"))
+	tk.MustQuery("select c from t where (a = 1 or b = 2) and c > 1 order by c").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select c from t where a = 2 and b = 1").Check(testkit.Rows("5"))
+	tk.MustQuery("select c from t where a = 3 or b = 4").Check(testkit.Rows("4"))
+	tk.MustQuery("select c from t where a = 4 or b = 4").Check(testkit.Rows())
+}
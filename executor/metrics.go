@@ -40,11 +40,19 @@ var (
 			Name:      "expensive_query_total",
 			Help:      "Counter of expensive query.",
 		}, []string{"type"})
+	planCacheCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "executor",
+			Name:      "plan_cache_total",
+			Help:      "Counter of the hits and misses of the prepared plan cache.",
+		}, []string{"type"})
 )
 
 func init() {
 	prometheus.MustRegister(stmtNodeCounter)
 	prometheus.MustRegister(expensiveQueryCounter)
+	prometheus.MustRegister(planCacheCounter)
 }
 
 func stmtCount(node ast.StmtNode, p plan.Plan) {
@@ -146,7 +154,7 @@ func StatementLabel(node ast.StmtNode, p plan.Plan) string {
 		return RollBack
 	case *ast.SelectStmt:
 		return getSelectStmtLabel(x, p)
-	case *ast.SetStmt, *ast.SetPwdStmt:
+	case *ast.SetStmt, *ast.SetPwdStmt, *ast.SetRoleStmt, *ast.SetDefaultRoleStmt:
 		return Set
 	case *ast.ShowStmt:
 		return Show
@@ -154,9 +162,9 @@ func StatementLabel(node ast.StmtNode, p plan.Plan) string {
 		return TruncateTable
 	case *ast.UpdateStmt:
 		return getUpdateStmtLabel(x, p)
-	case *ast.GrantStmt:
+	case *ast.GrantStmt, *ast.GrantRoleStmt:
 		return Grant
-	case *ast.RevokeStmt:
+	case *ast.RevokeStmt, *ast.RevokeRoleStmt:
 		return Revoke
 	case *ast.DeallocateStmt, *ast.ExecuteStmt, *ast.PrepareStmt, *ast.UseStmt:
 		return IGNORE
@@ -0,0 +1,161 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/terror"
+)
+
+// SetTxnMode sets the mode of the explicit transaction which is just started, mode is ast.PessimisticMode or
+// ast.OptimisticMode, the tidb_txn_mode variable decides the mode if it's empty. The transaction stays
+// optimistic if the store doesn't support pessimistic transactions.
+func SetTxnMode(ctx context.Context, mode string) {
+	vars := ctx.GetSessionVars()
+	if mode == "" {
+		mode = vars.TxnMode
+	}
+	txn, ok := ctx.Txn().(kv.PessimisticTxn)
+	if !ok {
+		vars.TxnCtx.IsPessimistic = false
+		return
+	}
+	txn.SetOption(kv.Pessimistic, mode == ast.PessimisticMode)
+	txn.SetOption(kv.LockWaitTimeout, vars.LockWaitTimeout)
+	vars.TxnCtx.IsPessimistic = txn.IsPessimistic()
+}
+
+// pessimisticTxn returns the transaction of ctx if it's pessimistic, otherwise it returns nil.
+func pessimisticTxn(ctx context.Context) kv.PessimisticTxn {
+	if !ctx.GetSessionVars().TxnCtx.IsPessimistic {
+		return nil
+	}
+	txn, ok := ctx.Txn().(kv.PessimisticTxn)
+	if !ok || !txn.IsPessimistic() {
+		return nil
+	}
+	return txn
+}
+
+// isPessimisticStmt checks if the statement locks the rows it writes or locks in a pessimistic transaction,
+// e is the executor of the statement.
+func isPessimisticStmt(node ast.StmtNode, e Executor) bool {
+	switch e.(type) {
+	case *DeleteExec, *InsertExec, *UpdateExec, *ReplaceExec:
+		return true
+	}
+	sel, ok := node.(*ast.SelectStmt)
+	return ok && sel.LockTp == ast.SelectLockForUpdate
+}
+
+// execPessimistic executes a statement of a pessimistic transaction which writes or locks rows. The statement
+// reads at a new forUpdateTS and locks the rows when it finishes, it's executed again with a newer forUpdateTS
+// if a row is written by others after it's read. The rows of SELECT FOR UPDATE are returned after they are locked.
+func (a *statement) execPessimistic(ctx context.Context, txn kv.PessimisticTxn, pi processinfoSetter) (ast.RecordSet, error) {
+	rows, schema, err := a.runPessimistic(ctx, txn)
+	if err != nil || schema.Len() == 0 {
+		if pi != nil {
+			pi.SetProcessInfo("")
+		}
+		return nil, errors.Trace(err)
+	}
+	return &recordSet{
+		executor:    &lockedRowsExec{schema: schema, rows: rows},
+		stmt:        a,
+		processinfo: pi,
+	}, nil
+}
+
+func (a *statement) runPessimistic(ctx context.Context, txn kv.PessimisticTxn) ([]*Row, *expression.Schema, error) {
+	sessVars := ctx.GetSessionVars()
+	udb := getDirtyDB(ctx)
+	for {
+		ver, err := sessionctx.GetDomain(ctx).Store().CurrentVersion()
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		sessVars.StmtCtx.ForUpdateTS = ver.Ver
+		txn.StartStmt(ver.Ver)
+		udb.startStmt()
+		rows, schema, err := a.drain(ctx)
+		if err == nil {
+			err = txn.FinishStmt(true)
+		} else {
+			txn.FinishStmt(false)
+		}
+		udb.finishStmt(err == nil)
+		if !terror.ErrorEqual(err, kv.ErrWriteConflict) {
+			return rows, schema, errors.Trace(err)
+		}
+		log.Infof("[%d] pessimistic statement meets write conflict, retry: %s", sessVars.ConnectionID, a.text)
+		sessVars.StmtCtx.ResetForRetry()
+	}
+}
+
+// drain builds the executor of the statement and reads all the rows of it.
+func (a *statement) drain(ctx context.Context) ([]*Row, *expression.Schema, error) {
+	b := newExecutorBuilder(ctx, a.is)
+	e := b.build(a.plan)
+	if b.err != nil {
+		return nil, nil, errors.Trace(b.err)
+	}
+	var rows []*Row
+	for {
+		row, err := e.Next()
+		if err != nil {
+			e.Close()
+			return nil, nil, errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		if e.Schema().Len() > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return rows, e.Schema(), errors.Trace(e.Close())
+}
+
+// lockedRowsExec returns the rows of SELECT FOR UPDATE in a pessimistic transaction, which are read and locked.
+type lockedRowsExec struct {
+	schema *expression.Schema
+	rows   []*Row
+	cursor int
+}
+
+// Schema implements the Executor Schema interface.
+func (e *lockedRowsExec) Schema() *expression.Schema {
+	return e.schema
+}
+
+// Next implements the Executor Next interface.
+func (e *lockedRowsExec) Next() (*Row, error) {
+	if e.cursor >= len(e.rows) {
+		return nil, nil
+	}
+	row := e.rows[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+// Close implements the Executor Close interface.
+func (e *lockedRowsExec) Close() error {
+	return nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/testkit"
+)
+
+func mustErrCode(c *C, err error, code uint16) {
+	c.Assert(err, NotNil)
+	tErr, ok := errors.Cause(err).(*terror.Error)
+	c.Assert(ok, IsTrue, Commentf("err: %v", err))
+	c.Assert(tErr.ToSQLError().Code, Equals, code, Commentf("err: %v", err))
+}
+
+// execAsync executes the statement in another goroutine, the error is sent to the returned channel.
+func execAsync(tk *testkit.TestKit, sql string) <-chan error {
+	ch := make(chan error, 1)
+	go func() {
+		_, err := tk.Exec(sql)
+		ch <- err
+	}()
+	return ch
+}
+
+func (s *testSuite) TestPessimisticTxn(c *C) {
+	if !*mockTikv {
+		c.Skip("pessimistic transactions are only supported by mock-tikv")
+	}
+	defer s.cleanEnv(c)
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (k int primary key, v int)")
+	tk.MustExec("insert into t values (1, 1), (2, 2)")
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+
+	// The statements read the rows committed after the transaction starts.
+	tk.MustExec("begin pessimistic")
+	tk1.MustExec("update t set v = 10 where k = 1")
+	tk.MustQuery("select v from t where k = 1").Check(testkit.Rows("1"))
+	tk.MustExec("update t set v = v + 1 where k = 1")
+	tk.MustQuery("select v from t where k = 1").Check(testkit.Rows("11"))
+
+	// The other transaction waits for the lock, then it updates the row committed by the first one.
+	tk1.MustExec("begin pessimistic")
+	ch := execAsync(tk1, "update t set v = v + 1 where k = 1")
+	select {
+	case err := <-ch:
+		c.Fatalf("the update doesn't wait for the lock, err: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	tk.MustExec("commit")
+	c.Assert(<-ch, IsNil)
+	tk1.MustExec("commit")
+	tk.MustQuery("select v from t where k = 1").Check(testkit.Rows("12"))
+
+	// SELECT FOR UPDATE locks the rows and reads the latest version.
+	tk.MustExec("begin pessimistic")
+	tk1.MustExec("update t set v = 20 where k = 2")
+	tk.MustQuery("select v from t where k = 2 for update").Check(testkit.Rows("20"))
+	tk1.MustExec("set innodb_lock_wait_timeout = 1")
+	tk1.MustExec("begin pessimistic")
+	_, err := tk1.Exec("update t set v = 21 where k = 2")
+	mustErrCode(c, err, mysql.ErrLockWaitTimeout)
+	tk1.MustExec("rollback")
+	tk.MustExec("commit")
+	tk1.MustExec("update t set v = 21 where k = 2")
+
+	// The inserts check the duplicated keys at the latest version.
+	tk.MustExec("begin pessimistic")
+	tk1.MustExec("insert into t values (3, 3)")
+	_, err = tk.Exec("insert into t values (3, 30)")
+	mustErrCode(c, err, mysql.ErrDupEntry)
+	tk.MustExec("insert into t values (4, 4)")
+	tk.MustExec("rollback")
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 12", "2 21", "3 3"))
+
+	// The locks of a read-only transaction are released when it commits.
+	tk.MustExec("begin pessimistic")
+	tk.MustQuery("select * from t where k = 3 for update").Check(testkit.Rows("3 3"))
+	tk.MustExec("commit")
+	tk1.MustExec("update t set v = 30 where k = 3")
+}
+
+func (s *testSuite) TestPessimisticDeadlock(c *C) {
+	if !*mockTikv {
+		c.Skip("pessimistic transactions are only supported by mock-tikv")
+	}
+	defer s.cleanEnv(c)
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (k int primary key, v int)")
+	tk.MustExec("insert into t values (1, 1), (2, 2)")
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+
+	tk.MustExec("begin pessimistic")
+	tk.MustExec("update t set v = 10 where k = 1")
+	tk1.MustExec("begin pessimistic")
+	tk1.MustExec("update t set v = 20 where k = 2")
+	ch := execAsync(tk, "update t set v = 10 where k = 2")
+	time.Sleep(100 * time.Millisecond)
+	_, err := tk1.Exec("update t set v = 20 where k = 1")
+	mustErrCode(c, err, mysql.ErrLockDeadlock)
+	c.Assert(terror.ErrorEqual(err, kv.ErrDeadlock), IsTrue)
+	// The deadlock victim is rolled back, so the other transaction gets the lock.
+	c.Assert(<-ch, IsNil)
+	tk.MustExec("commit")
+	tk1.MustQuery("select * from t").Check(testkit.Rows("1 10", "2 10"))
+}
+
+func (s *testSuite) TestTxnMode(c *C) {
+	if !*mockTikv {
+		c.Skip("pessimistic transactions are only supported by mock-tikv")
+	}
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	_, err := tk.Exec("set tidb_txn_mode = 'unknown'")
+	c.Assert(err, NotNil)
+
+	tk.MustExec("set tidb_txn_mode = 'PESSIMISTIC'")
+	tk.MustQuery("select @@tidb_txn_mode").Check(testkit.Rows("pessimistic"))
+	tk.MustExec("begin")
+	c.Assert(tk.Se.GetSessionVars().TxnCtx.IsPessimistic, IsTrue)
+	tk.MustExec("commit")
+	tk.MustExec("begin optimistic")
+	c.Assert(tk.Se.GetSessionVars().TxnCtx.IsPessimistic, IsFalse)
+	tk.MustExec("commit")
+
+	// The transactions started implicitly when autocommit is off use tidb_txn_mode too.
+	tk.MustExec("set autocommit = 0")
+	tk.MustQuery("select 1")
+	c.Assert(tk.Se.GetSessionVars().TxnCtx.IsPessimistic, IsTrue)
+	tk.MustExec("rollback")
+	tk.MustExec("set autocommit = 1")
+
+	tk.MustExec("set tidb_txn_mode = 'optimistic'")
+	tk.MustExec("begin")
+	c.Assert(tk.Se.GetSessionVars().TxnCtx.IsPessimistic, IsFalse)
+	tk.MustExec("commit")
+	tk.MustExec("begin pessimistic")
+	c.Assert(tk.Se.GetSessionVars().TxnCtx.IsPessimistic, IsTrue)
+	tk.MustExec("commit")
+}
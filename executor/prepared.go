@@ -18,6 +18,7 @@ import (
 	"sort"
 
 	"github.com/juju/errors"
+	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
@@ -69,6 +70,7 @@ type Prepared struct {
 	Stmt          ast.StmtNode
 	Params        []*ast.ParamMarkerExpr
 	SchemaVersion int64
+	UseCache      bool
 }
 
 // PrepareExec represents a PREPARE executor.
@@ -136,6 +138,10 @@ func (e *PrepareExec) DoPrepare() {
 		e.Err = errors.Trace(ErrPrepareDDL)
 		return
 	}
+	if err = plan.CheckPasswordExpired(e.Ctx, stmt); err != nil {
+		e.Err = errors.Trace(err)
+		return
+	}
 	var extractor paramMarkerExtractor
 	stmt.Accept(&extractor)
 	err = plan.Preprocess(stmt, e.IS, e.Ctx)
@@ -157,6 +163,7 @@ func (e *PrepareExec) DoPrepare() {
 		Stmt:          stmt,
 		Params:        sorter.markers,
 		SchemaVersion: e.IS.SchemaMetaVersion(),
+		UseCache:      plan.PreparedPlanCacheEnabled && plan.Cacheable(stmt),
 	}
 
 	err = plan.PrepareStmt(e.IS, e.Ctx, stmt)
@@ -240,7 +247,7 @@ func (e *ExecuteExec) Build() error {
 		}
 		prepared.SchemaVersion = e.IS.SchemaMetaVersion()
 	}
-	p, err := plan.Optimize(e.Ctx, prepared.Stmt, e.IS)
+	p, err := e.getPlan(prepared)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -264,6 +271,46 @@ func (e *ExecuteExec) Build() error {
 	return nil
 }
 
+// getPlan gets the plan of the prepared statement from the plan cache, or optimizes it
+// if the cached plan doesn't exist or can't be reused.
+func (e *ExecuteExec) getPlan(prepared *Prepared) (plan.Plan, error) {
+	if !prepared.UseCache || e.Ctx.PreparedPlanCache() == nil {
+		return plan.Optimize(e.Ctx, prepared.Stmt, e.IS)
+	}
+	// The plans built in a transaction that has written data contain union scans,
+	// they are different from the plans for read only transactions.
+	if txn := e.Ctx.Txn(); txn != nil && !txn.IsReadOnly() {
+		return plan.Optimize(e.Ctx, prepared.Stmt, e.IS)
+	}
+	// The plans of a stale read transaction are built with the schema at its timestamp.
+	if e.Ctx.GetSessionVars().TxnCtx.StaleReadTS != 0 {
+		return plan.Optimize(e.Ctx, prepared.Stmt, e.IS)
+	}
+	cache := e.Ctx.PreparedPlanCache()
+	cacheKey := plan.NewPSTMTPlanCacheKey(e.Ctx.GetSessionVars(), e.ID, prepared.SchemaVersion)
+	if cacheValue, exists := cache.Get(cacheKey); exists {
+		value := cacheValue.(*plan.PSTMTPlanCacheValue)
+		if value.Reusable(e.Ctx) {
+			p, err := value.Rebuild(e.Ctx)
+			if err == nil {
+				planCacheCounter.WithLabelValues("hit").Inc()
+				return p, nil
+			}
+			log.Warnf("[%d] rebuild cached plan error: %v", e.Ctx.GetSessionVars().ConnectionID, err)
+		}
+		cache.Delete(cacheKey)
+	}
+	planCacheCounter.WithLabelValues("miss").Inc()
+	p, value, err := plan.OptimizePrepared(e.Ctx, prepared.Stmt, prepared.Params, e.IS)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if value != nil {
+		cache.Put(cacheKey, value)
+	}
+	return p, nil
+}
+
 // DeallocateExec represent a DEALLOCATE executor.
 type DeallocateExec struct {
 	Name string
@@ -284,6 +331,11 @@ func (e *DeallocateExec) Next() (*Row, error) {
 		return nil, errors.Trace(ErrStmtNotFound)
 	}
 	delete(vars.PreparedStmtNameToID, e.Name)
+	if prepared, ok := vars.PreparedStmts[id].(*Prepared); ok && prepared.UseCache {
+		if cache := e.ctx.PreparedPlanCache(); cache != nil {
+			plan.DeletePSTMTPlanCache(cache, id)
+		}
+	}
 	delete(vars.PreparedStmts, id)
 	return nil, nil
 }
@@ -307,6 +359,7 @@ func CompileExecutePreparedStmt(ctx context.Context, ID uint32, args ...interfac
 	}
 	if prepared, ok := ctx.GetSessionVars().PreparedStmts[ID].(*Prepared); ok {
 		sa.text = prepared.Stmt.Text()
+		sa.stmtNode = prepared.Stmt
 	}
 	return sa
 }
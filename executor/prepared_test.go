@@ -110,6 +110,102 @@ func (s *testSuite) TestPreparedLimitOffset(c *C) {
 
 	stmtID, _, _, err := tk.Se.PrepareStmt("select id from prepare_test limit ?")
 	c.Assert(err, IsNil)
-	_, err = tk.Se.ExecutePreparedStmt(stmtID, 1)
+	rs, err := tk.Se.ExecutePreparedStmt(stmtID, 1)
 	c.Assert(err, IsNil)
+	c.Assert(rs.Close(), IsNil)
+}
+
+func (s *testSuite) TestPreparedPlanCache(c *C) {
+	orgEnable := plan.PreparedPlanCacheEnabled
+	defer func() {
+		plan.PreparedPlanCacheEnabled = orgEnable
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+	}()
+	plan.PreparedPlanCacheEnabled = true
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists prepare_test")
+	tk.MustExec("create table prepare_test (id int PRIMARY KEY AUTO_INCREMENT, c1 int, c2 int, c3 int default 1, index idx(c1))")
+	tk.MustExec("insert prepare_test (c1, c2) values (1, 1), (2, 2), (3, 3), (NULL, 4)")
+	c.Assert(tk.Se.PreparedPlanCache(), NotNil)
+
+	// Point get by primary key, the ranges are rebuilt with the new parameters.
+	tk.MustExec(`prepare stmt_pk from 'select c1 from prepare_test where id = ?'`)
+	tk.MustExec(`set @a = 1`)
+	tk.MustQuery(`execute stmt_pk using @a`).Check(testkit.Rows("1"))
+	c.Assert(tk.Se.PreparedPlanCache().Size(), Equals, 1)
+	tk.MustExec(`set @a = 2`)
+	tk.MustQuery(`execute stmt_pk using @a`).Check(testkit.Rows("2"))
+	tk.MustExec(`set @a = 5`)
+	tk.MustQuery(`execute stmt_pk using @a`).Check(testkit.Rows())
+	c.Assert(tk.Se.PreparedPlanCache().Size(), Equals, 1)
+
+	// Index range and filter conditions.
+	tk.MustExec(`prepare stmt_idx from 'select id from prepare_test where c1 > ? and c2 < ? order by id'`)
+	tk.MustExec(`set @a = 0, @b = 3`)
+	tk.MustQuery(`execute stmt_idx using @a, @b`).Check(testkit.Rows("1", "2"))
+	tk.MustExec(`set @a = 1, @b = 10`)
+	tk.MustQuery(`execute stmt_idx using @a, @b`).Check(testkit.Rows("2", "3"))
+	c.Assert(tk.Se.PreparedPlanCache().Size(), Equals, 2)
+
+	// Parameters are not folded into constants in the cached plan.
+	tk.MustExec(`prepare stmt_expr from 'select c1 + ? from prepare_test where id = ? + 1'`)
+	tk.MustExec(`set @a = 10, @b = 0`)
+	tk.MustQuery(`execute stmt_expr using @a, @b`).Check(testkit.Rows("11"))
+	tk.MustExec(`set @a = 20, @b = 2`)
+	tk.MustQuery(`execute stmt_expr using @a, @b`).Check(testkit.Rows("23"))
+
+	// Parameters of another kind build a new plan.
+	tk.MustExec(`set @a = '2'`)
+	tk.MustQuery(`execute stmt_pk using @a`).Check(testkit.Rows("2"))
+
+	// Aggregation.
+	tk.MustExec(`prepare stmt_agg from 'select count(*), sum(c2) from prepare_test where c1 >= ?'`)
+	tk.MustExec(`set @a = 2`)
+	tk.MustQuery(`execute stmt_agg using @a`).Check(testkit.Rows("2 5"))
+	tk.MustExec(`set @a = 1`)
+	tk.MustQuery(`execute stmt_agg using @a`).Check(testkit.Rows("3 6"))
+
+	// Plans in a transaction that has written data should see the written data.
+	tk.MustExec("begin")
+	tk.MustExec("insert prepare_test (c1, c2) values (5, 5)")
+	tk.MustExec(`set @a = 5`)
+	tk.MustQuery(`execute stmt_pk using @a`).Check(testkit.Rows("5"))
+	tk.MustExec("rollback")
+	tk.MustQuery(`execute stmt_pk using @a`).Check(testkit.Rows())
+
+	// Statements that aren't cacheable.
+	tk.MustExec(`prepare stmt_limit from 'select id from prepare_test order by id limit ?'`)
+	tk.MustExec(`set @a = 1`)
+	tk.MustQuery(`execute stmt_limit using @a`).Check(testkit.Rows("1"))
+	tk.MustExec(`set @a = 2`)
+	tk.MustQuery(`execute stmt_limit using @a`).Check(testkit.Rows("1", "2"))
+
+	// Schema changes build new plans.
+	size := tk.Se.PreparedPlanCache().Size()
+	tk.MustExec("alter table prepare_test add column c4 int")
+	tk.MustExec(`set @a = 3`)
+	tk.MustQuery(`execute stmt_pk using @a`).Check(testkit.Rows("3"))
+	c.Assert(tk.Se.PreparedPlanCache().Size(), Equals, size+1)
+
+	tk.MustExec("set time_zone = '+08:00'")
+	tk.MustQuery(`execute stmt_pk using @a`).Check(testkit.Rows("3"))
+	c.Assert(tk.Se.PreparedPlanCache().Size(), Equals, size+2)
+
+	// Deallocating a statement removes all its cached plans, including the ones built with
+	// the old schema version and other session variables.
+	tk.MustExec(`deallocate prepare stmt_pk`)
+	c.Assert(tk.Se.PreparedPlanCache().Size(), Equals, size-1)
+
+	// Closing a statement by the binary protocol removes its cached plans too.
+	stmtID, _, _, err := tk.Se.PrepareStmt("select c1 from prepare_test where id = ?")
+	c.Assert(err, IsNil)
+	rs, err := tk.Se.ExecutePreparedStmt(stmtID, 1)
+	c.Assert(err, IsNil)
+	c.Assert(rs.Close(), IsNil)
+	c.Assert(tk.Se.PreparedPlanCache().Size(), Equals, size)
+	c.Assert(tk.Se.DropPreparedStmt(stmtID), IsNil)
+	tk.MustExec("select 1")
+	c.Assert(tk.Se.PreparedPlanCache().Size(), Equals, size-1)
 }
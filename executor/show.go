@@ -22,12 +22,14 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/bindinfo"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/sessionctx/varsutil"
 	"github.com/pingcap/tidb/table"
@@ -44,9 +46,10 @@ type ShowExec struct {
 	Column *ast.ColumnName // Used for `desc table column`.
 	Flag   int             // Some flag parsed from sql, such as FULL.
 	Full   bool
-	User   string // Used for show grants.
+	User   string   // Used for show grants.
+	Roles  []string // Used for show grants using.
 
-	// Used by show variables
+	// Used by show variables and show bindings.
 	GlobalScope bool
 
 	schema *expression.Schema
@@ -115,12 +118,35 @@ func (e *ShowExec) fetchAll() error {
 		return e.fetchShowWarnings()
 	case ast.ShowProcessList:
 		return e.fetchShowProcessList()
+	case ast.ShowBindings:
+		return e.fetchShowBindings()
 	case ast.ShowEvents:
 		// empty result
 	}
 	return nil
 }
 
+func (e *ShowExec) fetchShowBindings() error {
+	var records []*bindinfo.BindRecord
+	if e.GlobalScope {
+		records = sessionctx.GetDomain(e.ctx).BindHandle().GetAll()
+	} else {
+		records = bindinfo.GetSessionHandle(e.ctx).GetAll()
+	}
+	for _, record := range records {
+		data := types.MakeDatums(
+			record.OriginalSQL,
+			record.BindSQL,
+			record.Db,
+			record.Charset,
+			record.Collation,
+			record.CreateTime,
+		)
+		e.rows = append(e.rows, &Row{Data: data})
+	}
+	return nil
+}
+
 func (e *ShowExec) fetchShowEngines() error {
 	row := &Row{
 		Data: types.MakeDatums(
@@ -142,7 +168,7 @@ func (e *ShowExec) fetchShowDatabases() error {
 	// TODO: let information_schema be the first database
 	sort.Strings(dbs)
 	for _, d := range dbs {
-		if checker != nil && !checker.DBIsVisible(d) {
+		if checker != nil && !checker.DBIsVisible(e.ctx.GetSessionVars().ActiveRoles, d) {
 			continue
 		}
 		e.rows = append(e.rows, &Row{Data: types.MakeDatums(d)})
@@ -156,8 +182,13 @@ func (e *ShowExec) fetchShowProcessList() error {
 		return nil
 	}
 
+	// The users without the PROCESS privilege can only see their own threads.
+	all := hasGlobalPriv(e.ctx, mysql.ProcessPriv)
 	pl := sm.ShowProcessList()
 	for _, pi := range pl {
+		if !all && !isCurrentUser(e.ctx, pi.User) {
+			continue
+		}
 		var t uint64
 		if len(pi.Info) != 0 {
 			t = uint64(time.Since(pi.Time) / time.Second)
@@ -189,7 +220,7 @@ func (e *ShowExec) fetchShowTables() error {
 	for _, v := range e.is.SchemaTables(e.DBName) {
 		// Test with mysql.AllPrivMask means any privilege would be OK.
 		// TODO: Should consider column privileges, which also make a table visible.
-		if checker != nil && !checker.RequestVerification(e.DBName.O, v.Meta().Name.O, "", mysql.AllPrivMask) {
+		if checker != nil && !checker.RequestVerification(e.ctx.GetSessionVars().ActiveRoles, e.DBName.O, v.Meta().Name.O, "", mysql.AllPrivMask) {
 			continue
 		}
 		tableNames = append(tableNames, v.Meta().Name.O)
@@ -367,7 +398,7 @@ func (e *ShowExec) fetchShowVariables() error {
 }
 
 func (e *ShowExec) fetchShowStatus() error {
-	statusVars, err := variable.GetStatusVars()
+	statusVars, err := variable.GetStatusVars(e.ctx.GetSessionVars())
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -573,7 +604,7 @@ func (e *ShowExec) fetchShowGrants() error {
 	if checker == nil {
 		return errors.New("miss privilege checker")
 	}
-	gs, err := checker.ShowGrants(e.ctx, e.User)
+	gs, err := checker.ShowGrants(e.ctx, e.User, e.Roles)
 	if err != nil {
 		return errors.Trace(err)
 	}
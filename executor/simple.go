@@ -15,21 +15,29 @@ package executor
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/bindinfo"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/pwdvalidate"
 	"github.com/pingcap/tidb/util/sqlexec"
+	"github.com/pingcap/tidb/util/types"
 )
 
 // SimpleExec represents simple statement executor.
@@ -74,8 +82,24 @@ func (e *SimpleExec) Next() (*Row, error) {
 		err = e.executeDropUser(x)
 	case *ast.SetPwdStmt:
 		err = e.executeSetPwd(x)
+	case *ast.GrantRoleStmt:
+		err = e.executeGrantRole(x)
+	case *ast.RevokeRoleStmt:
+		err = e.executeRevokeRole(x)
+	case *ast.SetRoleStmt:
+		err = e.executeSetRole(x)
+	case *ast.SetDefaultRoleStmt:
+		err = e.executeSetDefaultRole(x)
 	case *ast.KillStmt:
 		err = e.executeKillStmt(x)
+	case *ast.CreateBindingStmt:
+		err = e.executeCreateBinding(x)
+	case *ast.DropBindingStmt:
+		err = e.executeDropBinding(x)
+	case *ast.CreateMaskingPolicyStmt:
+		err = e.executeCreateMaskingPolicy(x)
+	case *ast.DropMaskingPolicyStmt:
+		err = e.executeDropMaskingPolicy(x)
 	case *ast.BinlogStmt:
 		// We just ignore it.
 		return nil, nil
@@ -109,6 +133,21 @@ func (e *SimpleExec) executeUse(s *ast.UseStmt) error {
 }
 
 func (e *SimpleExec) executeBegin(s *ast.BeginStmt) error {
+	var (
+		staleReadTS uint64
+		snapshotIS  infoschema.InfoSchema
+	)
+	if s.AsOf != nil {
+		var err error
+		staleReadTS, err = plan.AsOfTS(e.ctx, s.AsOf)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		snapshotIS, err = sessionctx.GetDomain(e.ctx).GetSnapshotInfoSchema(staleReadTS)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
 	// If BEGIN is the first statement in TxnCtx, we can reuse the existing transaction, without the
 	// need to call NewTxn, which commits the existing transaction and begins a new one.
 	txnCtx := e.ctx.GetSessionVars().TxnCtx
@@ -118,10 +157,18 @@ func (e *SimpleExec) executeBegin(s *ast.BeginStmt) error {
 			return errors.Trace(err)
 		}
 	}
+	if snapshotIS != nil {
+		txnCtx.InfoSchema = snapshotIS
+	} else if txnCtx.StaleReadTS != 0 {
+		// The committed transaction read the schema at its timestamp.
+		txnCtx.InfoSchema = sessionctx.GetDomain(e.ctx).InfoSchema()
+	}
+	txnCtx.ReadOnly, txnCtx.StaleReadTS = s.ReadOnly, staleReadTS
 	// With START TRANSACTION, autocommit remains disabled until you end
 	// the transaction with COMMIT or ROLLBACK. The autocommit mode then
 	// reverts to its previous state.
 	e.ctx.GetSessionVars().SetStatusFlag(mysql.ServerStatusInTrans, true)
+	SetTxnMode(e.ctx, s.Mode)
 	return nil
 }
 
@@ -140,6 +187,15 @@ func (e *SimpleExec) executeRollback(s *ast.RollbackStmt) error {
 }
 
 func (e *SimpleExec) executeCreateUser(s *ast.CreateUserStmt) error {
+	// The roles are locked, they can't be used to connect.
+	accountLocked := `"N"`
+	if s.IsCreateRole {
+		accountLocked = `"Y"`
+	}
+	cols, values, err := passwordOrLockColumns([]string{"account_locked"}, []string{accountLocked}, s.PasswordOrLockOptions)
+	if err != nil {
+		return errors.Trace(err)
+	}
 	users := make([]string, 0, len(s.Specs))
 	for _, spec := range s.Specs {
 		userName, host := parseUser(spec.User)
@@ -153,22 +209,28 @@ func (e *SimpleExec) executeCreateUser(s *ast.CreateUserStmt) error {
 			}
 			continue
 		}
-		pwd := ""
-		if spec.AuthOpt != nil {
-			if spec.AuthOpt.ByAuthString {
-				pwd = util.EncodePassword(spec.AuthOpt.AuthString)
-			} else {
-				pwd = util.EncodePassword(spec.AuthOpt.HashString)
+		if !s.IsCreateRole && (spec.AuthOpt == nil || spec.AuthOpt.ByAuthString) {
+			var pwd string
+			if spec.AuthOpt != nil {
+				pwd = spec.AuthOpt.AuthString
+			}
+			if err1 = e.validatePassword(pwd, userName); err1 != nil {
+				return errors.Trace(err1)
 			}
 		}
-		user := fmt.Sprintf(`("%s", "%s", "%s")`, host, userName, pwd)
+		plugin, pwd, authString, err1 := encodeAuthOption(spec.AuthOpt, mysql.AuthNativePassword)
+		if err1 != nil {
+			return errors.Trace(err1)
+		}
+		user := fmt.Sprintf(`("%s", "%s", "%s", "%s", "%s", "%s", NOW(), %s)`, host, userName, pwd, plugin, authString, requireSSLType(s.Require), strings.Join(values, ", "))
 		users = append(users, user)
 	}
 	if len(users) == 0 {
 		return nil
 	}
-	sql := fmt.Sprintf(`INSERT INTO %s.%s (Host, User, Password) VALUES %s;`, mysql.SystemDB, mysql.UserTable, strings.Join(users, ", "))
-	_, err := e.ctx.(sqlexec.SQLExecutor).Execute(sql)
+	sql := fmt.Sprintf(`INSERT INTO %s.%s (Host, User, Password, plugin, authentication_string, ssl_type, password_last_changed, %s) VALUES %s;`,
+		mysql.SystemDB, mysql.UserTable, strings.Join(cols, ", "), strings.Join(users, ", "))
+	_, _, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -189,6 +251,7 @@ func (e *SimpleExec) executeAlterUser(s *ast.AlterUserStmt) error {
 		s.Specs = []*ast.UserSpec{spec}
 	}
 
+	vars := e.ctx.GetSessionVars()
 	failedUsers := make([]string, 0, len(s.Specs))
 	for _, spec := range s.Specs {
 		userName, host := parseUser(spec.User)
@@ -203,21 +266,57 @@ func (e *SimpleExec) executeAlterUser(s *ast.AlterUserStmt) error {
 			}
 			continue
 		}
-		pwd := ""
+		var cols, values []string
 		if spec.AuthOpt != nil {
 			if spec.AuthOpt.ByAuthString {
-				pwd = util.EncodePassword(spec.AuthOpt.AuthString)
-			} else {
-				pwd = util.EncodePassword(spec.AuthOpt.HashString)
+				if err = e.validatePassword(spec.AuthOpt.AuthString, userName); err != nil {
+					return errors.Trace(err)
+				}
+			}
+			plugin, err := userAuthPlugin(e.ctx, userName, host)
+			if err != nil {
+				return errors.Trace(err)
 			}
+			plugin, pwd, authString, err := encodeAuthOption(spec.AuthOpt, plugin)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			// Changing the password resets the expiration.
+			cols = []string{"Password", "plugin", "authentication_string", "password_last_changed", "password_expired"}
+			values = []string{fmt.Sprintf(`"%s"`, pwd), fmt.Sprintf(`"%s"`, plugin), fmt.Sprintf(`"%s"`, authString), "NOW()", `"N"`}
+		}
+		cols, values, err = passwordOrLockColumns(cols, values, s.PasswordOrLockOptions)
+		if err != nil {
+			return errors.Trace(err)
 		}
-		sql := fmt.Sprintf(`UPDATE %s.%s SET Password = "%s" WHERE Host = "%s" and User = "%s";`,
-			mysql.SystemDB, mysql.UserTable, pwd, host, userName)
+		if len(cols) == 0 {
+			continue
+		}
+		assignments := make([]string, 0, len(cols))
+		for i, col := range cols {
+			assignments = append(assignments, fmt.Sprintf("%s = %s", col, values[i]))
+		}
+		sql := fmt.Sprintf(`UPDATE %s.%s SET %s WHERE Host = "%s" and User = "%s";`,
+			mysql.SystemDB, mysql.UserTable, strings.Join(assignments, ", "), host, userName)
 		_, _, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
 		if err != nil {
 			failedUsers = append(failedUsers, spec.User)
+			continue
+		}
+		for i, col := range cols {
+			switch col {
+			case "account_locked", "failed_login_attempts", "password_lock_time":
+				// Like MySQL, unlocking the account or changing the options resets the failed login tracking,
+				// it's kept in the memory of each TiDB server, so only the tracking of this server is reset.
+				sessionctx.GetDomain(e.ctx).PrivilegeHandle().ResetLoginFailure(userName, host)
+			case "password_expired":
+				if values[i] == `"N"` && vars.User == userName+"@"+host {
+					vars.PasswordExpired = false
+				}
+			}
 		}
 	}
+	sessionctx.GetDomain(e.ctx).NotifyUpdatePrivilege(e.ctx)
 	if len(failedUsers) > 0 {
 		// Commit the transaction even if we returns error
 		err := e.ctx.Txn().Commit()
@@ -244,10 +343,20 @@ func (e *SimpleExec) executeDropUser(s *ast.DropUserStmt) error {
 			}
 			continue
 		}
-		sql := fmt.Sprintf(`DELETE FROM %s.%s WHERE Host = "%s" and User = "%s";`, mysql.SystemDB, mysql.UserTable, host, userName)
-		_, _, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
-		if err != nil {
-			failedUsers = append(failedUsers, user)
+		sqls := []string{
+			fmt.Sprintf(`DELETE FROM %s.%s WHERE Host = "%s" and User = "%s";`, mysql.SystemDB, mysql.UserTable, host, userName),
+			// Revoke the roles granted to the user and revoke the role from the other users.
+			fmt.Sprintf(`DELETE FROM %s.%s WHERE (TO_HOST = "%s" and TO_USER = "%s") or (FROM_HOST = "%s" and FROM_USER = "%s");`,
+				mysql.SystemDB, mysql.RoleEdgeTable, host, userName, host, userName),
+			fmt.Sprintf(`DELETE FROM %s.%s WHERE (HOST = "%s" and USER = "%s") or (DEFAULT_ROLE_HOST = "%s" and DEFAULT_ROLE_USER = "%s");`,
+				mysql.SystemDB, mysql.DefaultRoleTable, host, userName, host, userName),
+		}
+		for _, sql := range sqls {
+			_, _, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+			if err != nil {
+				failedUsers = append(failedUsers, user)
+				break
+			}
 		}
 	}
 	if len(failedUsers) > 0 {
@@ -257,11 +366,174 @@ func (e *SimpleExec) executeDropUser(s *ast.DropUserStmt) error {
 			return errors.Trace(err)
 		}
 		errMsg := "Operation DROP USER failed for " + strings.Join(failedUsers, ",")
+		if s.IsDropRole {
+			errMsg = "Operation DROP ROLE failed for " + strings.Join(failedUsers, ",")
+		}
+		return terror.ClassExecutor.New(CodeCannotUser, errMsg)
+	}
+	sessionctx.GetDomain(e.ctx).NotifyUpdatePrivilege(e.ctx)
+	return nil
+}
+
+// checkUsersExist returns the error of operation op if any of the users doesn't exist.
+func (e *SimpleExec) checkUsersExist(op string, users []string) error {
+	var failedUsers []string
+	for _, user := range users {
+		userName, host := parseUser(user)
+		exists, err := userExists(e.ctx, userName, host)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !exists {
+			failedUsers = append(failedUsers, user)
+		}
+	}
+	if len(failedUsers) > 0 {
+		errMsg := fmt.Sprintf("Operation %s failed for %s", op, strings.Join(failedUsers, ","))
 		return terror.ClassExecutor.New(CodeCannotUser, errMsg)
 	}
 	return nil
 }
 
+func (e *SimpleExec) executeGrantRole(s *ast.GrantRoleStmt) error {
+	if err := e.checkUsersExist("GRANT ROLE", append(s.Roles, s.Users...)); err != nil {
+		return errors.Trace(err)
+	}
+	for _, user := range s.Users {
+		userName, host := parseUser(user)
+		for _, role := range s.Roles {
+			roleName, roleHost := parseUser(role)
+			sql := fmt.Sprintf(`REPLACE INTO %s.%s (FROM_HOST, FROM_USER, TO_HOST, TO_USER) VALUES ("%s", "%s", "%s", "%s");`,
+				mysql.SystemDB, mysql.RoleEdgeTable, roleHost, roleName, host, userName)
+			if _, _, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	sessionctx.GetDomain(e.ctx).NotifyUpdatePrivilege(e.ctx)
+	return nil
+}
+
+func (e *SimpleExec) executeRevokeRole(s *ast.RevokeRoleStmt) error {
+	if err := e.checkUsersExist("REVOKE ROLE", append(s.Roles, s.Users...)); err != nil {
+		return errors.Trace(err)
+	}
+	for _, user := range s.Users {
+		userName, host := parseUser(user)
+		for _, role := range s.Roles {
+			roleName, roleHost := parseUser(role)
+			sql := fmt.Sprintf(`DELETE FROM %s.%s WHERE FROM_HOST = "%s" and FROM_USER = "%s" and TO_HOST = "%s" and TO_USER = "%s";`,
+				mysql.SystemDB, mysql.RoleEdgeTable, roleHost, roleName, host, userName)
+			if _, _, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	sessionctx.GetDomain(e.ctx).NotifyUpdatePrivilege(e.ctx)
+	return nil
+}
+
+// executeSetRole changes the active roles of the session, the roles must be granted to the current user.
+func (e *SimpleExec) executeSetRole(s *ast.SetRoleStmt) error {
+	sessionVars := e.ctx.GetSessionVars()
+	pm := privilege.GetPrivilegeManager(e.ctx)
+	if pm == nil {
+		return nil
+	}
+	var userName, host string
+	if sessionVars.User != "" {
+		userName, host = parseUser(sessionVars.User)
+	}
+	granted := pm.GetAllRoles(userName, host)
+	isGranted := func(role string) bool {
+		for _, r := range granted {
+			if r == role {
+				return true
+			}
+		}
+		return false
+	}
+	for _, role := range s.RoleList {
+		if !isGranted(role) {
+			return ErrRoleNotGranted.GenByArgs(role, sessionVars.User)
+		}
+	}
+
+	var roles []string
+	switch s.SetRoleOpt {
+	case ast.SetRoleDefault:
+		roles = pm.GetDefaultRoles(userName, host)
+	case ast.SetRoleAll:
+		roles = granted
+	case ast.SetRoleAllExcept:
+		excepted := make(map[string]bool, len(s.RoleList))
+		for _, role := range s.RoleList {
+			excepted[role] = true
+		}
+		for _, role := range granted {
+			if !excepted[role] {
+				roles = append(roles, role)
+			}
+		}
+	case ast.SetRoleRegular:
+		roles = s.RoleList
+	}
+	sessionVars.ActiveRoles = roles
+	return nil
+}
+
+// executeSetDefaultRole replaces the default roles of the users, the roles must be granted to the users.
+func (e *SimpleExec) executeSetDefaultRole(s *ast.SetDefaultRoleStmt) error {
+	if err := e.checkUsersExist("SET DEFAULT ROLE", s.UserList); err != nil {
+		return errors.Trace(err)
+	}
+	exec := e.ctx.(sqlexec.RestrictedSQLExecutor)
+	for _, user := range s.UserList {
+		userName, host := parseUser(user)
+		sql := fmt.Sprintf(`SELECT FROM_HOST, FROM_USER FROM %s.%s WHERE TO_HOST = "%s" and TO_USER = "%s";`,
+			mysql.SystemDB, mysql.RoleEdgeTable, host, userName)
+		rows, _, err := exec.ExecRestrictedSQL(e.ctx, sql)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		granted := make(map[string]bool, len(rows))
+		var allRoles []string
+		for _, row := range rows {
+			role := row.Data[1].GetString() + "@" + row.Data[0].GetString()
+			granted[role] = true
+			allRoles = append(allRoles, role)
+		}
+
+		var roles []string
+		switch s.SetRoleOpt {
+		case ast.SetRoleAll:
+			roles = allRoles
+		case ast.SetRoleRegular:
+			for _, role := range s.RoleList {
+				if !granted[role] {
+					return ErrRoleNotGranted.GenByArgs(role, user)
+				}
+			}
+			roles = s.RoleList
+		}
+
+		sql = fmt.Sprintf(`DELETE FROM %s.%s WHERE HOST = "%s" and USER = "%s";`, mysql.SystemDB, mysql.DefaultRoleTable, host, userName)
+		if _, _, err = exec.ExecRestrictedSQL(e.ctx, sql); err != nil {
+			return errors.Trace(err)
+		}
+		for _, role := range roles {
+			roleName, roleHost := parseUser(role)
+			sql = fmt.Sprintf(`INSERT INTO %s.%s (HOST, USER, DEFAULT_ROLE_HOST, DEFAULT_ROLE_USER) VALUES ("%s", "%s", "%s", "%s");`,
+				mysql.SystemDB, mysql.DefaultRoleTable, host, userName, roleHost, roleName)
+			if _, _, err = exec.ExecRestrictedSQL(e.ctx, sql); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	sessionctx.GetDomain(e.ctx).NotifyUpdatePrivilege(e.ctx)
+	return nil
+}
+
 // parse user string into username and host
 // root@localhost -> root, localhost
 func parseUser(user string) (string, string) {
@@ -278,6 +550,50 @@ func userExists(ctx context.Context, name string, host string) (bool, error) {
 	return len(rows) > 0, nil
 }
 
+// userAuthPlugin returns the auth plugin of the account, the account should exist.
+func userAuthPlugin(ctx context.Context, name string, host string) (string, error) {
+	sql := fmt.Sprintf(`SELECT plugin FROM %s.%s WHERE User="%s" AND Host="%s";`, mysql.SystemDB, mysql.UserTable, name, host)
+	rows, _, err := ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(ctx, sql)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if len(rows) == 0 || rows[0].Data[0].GetString() == "" {
+		return mysql.AuthNativePassword, nil
+	}
+	return rows[0].Data[0].GetString(), nil
+}
+
+// encodeAuthOption returns the auth plugin and the password hashes stored in the Password and authentication_string
+// columns of mysql.user table. plugin is used if the auth option doesn't specify the auth plugin.
+func encodeAuthOption(authOpt *ast.AuthOption, plugin string) (authPlugin, pwd, authString string, err error) {
+	if authOpt == nil {
+		return plugin, "", "", nil
+	}
+	if authOpt.AuthPlugin != "" {
+		plugin = strings.ToLower(authOpt.AuthPlugin)
+	}
+	switch plugin {
+	case mysql.AuthNativePassword:
+		if authOpt.ByAuthString {
+			pwd = util.EncodePassword(authOpt.AuthString)
+		} else {
+			pwd = util.EncodePassword(authOpt.HashString)
+		}
+	case mysql.AuthCachingSha2Password, mysql.AuthSha256Password:
+		if authOpt.ByAuthString {
+			authString, err = util.EncodeSHA256Password(authOpt.AuthString)
+			if err != nil {
+				return "", "", "", errors.Trace(err)
+			}
+		} else {
+			authString = authOpt.HashString
+		}
+	default:
+		return "", "", "", ErrPluginIsNotLoaded.GenByArgs(plugin)
+	}
+	return plugin, pwd, authString, nil
+}
+
 func (e *SimpleExec) executeSetPwd(s *ast.SetPwdStmt) error {
 	if len(s.User) == 0 {
 		vars := e.ctx.GetSessionVars()
@@ -295,22 +611,157 @@ func (e *SimpleExec) executeSetPwd(s *ast.SetPwdStmt) error {
 		return errors.Trace(ErrPasswordNoMatch)
 	}
 
-	// update mysql.user
-	sql := fmt.Sprintf(`UPDATE %s.%s SET password="%s" WHERE User="%s" AND Host="%s";`, mysql.SystemDB, mysql.UserTable, util.EncodePassword(s.Password), userName, host)
+	// The password is hashed in the format of the auth plugin of the account.
+	plugin, err := userAuthPlugin(e.ctx, userName, host)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = e.validatePassword(s.Password, userName); err != nil {
+		return errors.Trace(err)
+	}
+	authOpt := &ast.AuthOption{ByAuthString: true, AuthString: s.Password}
+	_, pwd, authString, err := encodeAuthOption(authOpt, plugin)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	// update mysql.user, changing the password resets the expiration.
+	sql := fmt.Sprintf(`UPDATE %s.%s SET password="%s", authentication_string="%s", password_last_changed=NOW(), password_expired="N" WHERE User="%s" AND Host="%s";`,
+		mysql.SystemDB, mysql.UserTable, pwd, authString, userName, host)
 	_, _, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if vars := e.ctx.GetSessionVars(); vars.User == s.User {
+		vars.PasswordExpired = false
+	}
 	sessionctx.GetDomain(e.ctx).NotifyUpdatePrivilege(e.ctx)
-	return errors.Trace(err)
+	return nil
+}
+
+// validatePassword checks the password of user against the password validation policy
+// if tidb_validate_password_enable is on.
+func (e *SimpleExec) validatePassword(pwd, user string) error {
+	vars := e.ctx.GetSessionVars()
+	enabled, err := pwdvalidate.Enabled(vars)
+	if err != nil || !enabled {
+		return errors.Trace(err)
+	}
+	policy, err := pwdvalidate.LoadPolicy(vars)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	ok, err := policy.Validate(pwd, user)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !ok {
+		return ErrNotValidPassword
+	}
+	return nil
+}
+
+// passwordOrLockColumns appends the mysql.user columns and values set by the password expiration and account
+// locking options to cols and values, the value of a column which is already in cols is overridden.
+func passwordOrLockColumns(cols, values []string, opts []*ast.PasswordOrLockOption) ([]string, []string, error) {
+	set := func(col, value string) {
+		for i := range cols {
+			if cols[i] == col {
+				values[i] = value
+				return
+			}
+		}
+		cols = append(cols, col)
+		values = append(values, value)
+	}
+	for _, opt := range opts {
+		count := strconv.FormatInt(opt.Count, 10)
+		switch opt.Type {
+		case ast.PasswordExpire:
+			set("password_expired", `"Y"`)
+		case ast.PasswordExpireDefault:
+			set("password_lifetime", "NULL")
+		case ast.PasswordExpireNever:
+			set("password_lifetime", "0")
+		case ast.PasswordExpireInterval:
+			if opt.Count == 0 || opt.Count > math.MaxUint16 {
+				return nil, nil, ErrWrongValue.GenByArgs("DAY", count)
+			}
+			set("password_lifetime", count)
+		case ast.Lock:
+			set("account_locked", `"Y"`)
+		case ast.Unlock:
+			set("account_locked", `"N"`)
+		case ast.FailedLoginAttempts:
+			if opt.Count > math.MaxInt16 {
+				return nil, nil, ErrWrongValue.GenByArgs("FAILED_LOGIN_ATTEMPTS", count)
+			}
+			set("failed_login_attempts", count)
+		case ast.PasswordLockTime:
+			if opt.Count > math.MaxInt16 {
+				return nil, nil, ErrWrongValue.GenByArgs("PASSWORD_LOCK_TIME", count)
+			}
+			set("password_lock_time", count)
+		case ast.PasswordLockTimeUnbounded:
+			set("password_lock_time", "-1")
+		}
+	}
+	return cols, values, nil
 }
 
 func (e *SimpleExec) executeKillStmt(s *ast.KillStmt) error {
-	if s.TiDBExtension {
-		sm := e.ctx.GetSessionManager()
-		if sm == nil {
-			return nil
+	sm := e.ctx.GetSessionManager()
+	if sm == nil {
+		return nil
+	}
+	// The connection IDs of a server which isn't registered in the cluster have no server ID.
+	// "KILL TIDB" always kills the connection of the current server.
+	serverID := util.ServerIDOfConn(s.ConnectionID)
+	dom := sessionctx.GetDomain(e.ctx)
+	if s.TiDBExtension || serverID == 0 || serverID == dom.ServerID() {
+		if !isConnOwner(e.ctx, sm, s.ConnectionID) && !hasGlobalPriv(e.ctx, mysql.SuperPriv) {
+			return ErrKillDenied.GenByArgs(s.ConnectionID)
 		}
 		sm.Kill(s.ConnectionID, s.Query)
+		return nil
 	}
-	return nil
+	// The owner of a connection of another server can't be verified here.
+	if !hasGlobalPriv(e.ctx, mysql.SuperPriv) {
+		return ErrKillDenied.GenByArgs(s.ConnectionID)
+	}
+	return errors.Trace(dom.SendKillRequest(s.ConnectionID, s.Query))
+}
+
+// isConnOwner returns true if the connection of connID belongs to the current user.
+func isConnOwner(ctx context.Context, sm util.SessionManager, connID uint64) bool {
+	if connID == ctx.GetSessionVars().ConnectionID {
+		return true
+	}
+	for _, pi := range sm.ShowProcessList() {
+		if pi.ID == connID {
+			return isCurrentUser(ctx, pi.User)
+		}
+	}
+	return false
+}
+
+// isCurrentUser returns true if user is the name of the current user.
+func isCurrentUser(ctx context.Context, user string) bool {
+	current := ctx.GetSessionVars().User
+	if current == "" {
+		return false
+	}
+	name, _ := parseUser(current)
+	return name == user
+}
+
+// hasGlobalPriv returns true if the current user has the global privilege priv.
+func hasGlobalPriv(ctx context.Context, priv mysql.PrivilegeType) bool {
+	pm := privilege.GetPrivilegeManager(ctx)
+	if pm == nil {
+		return true
+	}
+	return pm.RequestVerification(ctx.GetSessionVars().ActiveRoles, "", "", "", priv)
 }
 
 func (e *SimpleExec) executeFlush(s *ast.FlushStmt) error {
@@ -320,7 +771,137 @@ func (e *SimpleExec) executeFlush(s *ast.FlushStmt) error {
 	case ast.FlushPrivileges:
 		dom := sessionctx.GetDomain(e.ctx)
 		err := dom.PrivilegeHandle().Update()
+		dom.PrivilegeHandle().ResetLoginFailures()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		return errors.Trace(dom.ReloadAuditFilter())
+	}
+	return nil
+}
+
+func (e *SimpleExec) executeCreateBinding(s *ast.CreateBindingStmt) error {
+	err := bindinfo.CheckBinding(s.OriginSel, s.HintedSel)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	sessVars := e.ctx.GetSessionVars()
+	charset, collation := sessVars.GetCharsetInfo()
+	record := &bindinfo.BindRecord{
+		OriginalSQL: parser.Normalize(s.OriginSel.Text()),
+		BindSQL:     s.HintedSel.Text(),
+		Db:          sessVars.CurrentDB,
+		Charset:     charset,
+		Collation:   collation,
+		CreateTime:  types.CurrentTime(mysql.TypeDatetime),
+		HintedSel:   s.HintedSel,
+	}
+	if !s.GlobalScope {
+		bindinfo.GetSessionHandle(e.ctx).AddBindRecord(record)
+		return nil
+	}
+	do := sessionctx.GetDomain(e.ctx)
+	err = do.BindHandle().AddBindRecord(e.ctx, record)
+	if err != nil {
 		return errors.Trace(err)
 	}
+	do.NotifyUpdateBindInfo()
 	return nil
 }
+
+func (e *SimpleExec) executeDropBinding(s *ast.DropBindingStmt) error {
+	normalizedSQL := parser.Normalize(s.OriginSel.Text())
+	db := e.ctx.GetSessionVars().CurrentDB
+	if !s.GlobalScope {
+		bindinfo.GetSessionHandle(e.ctx).DropBindRecord(normalizedSQL, db)
+		return nil
+	}
+	do := sessionctx.GetDomain(e.ctx)
+	err := do.BindHandle().DropBindRecord(e.ctx, normalizedSQL, db)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	do.NotifyUpdateBindInfo()
+	return nil
+}
+
+// executeCreateMaskingPolicy stores the masking policy in mysql.masking_policy, the masking expression must be
+// a data masking function of the column.
+func (e *SimpleExec) executeCreateMaskingPolicy(s *ast.CreateMaskingPolicyStmt) error {
+	tblInfo := s.Table.TableInfo
+	var column *model.ColumnInfo
+	for _, col := range tblInfo.Columns {
+		if col.Name.L == s.Column.Name.L {
+			column = col
+			break
+		}
+	}
+	if column == nil {
+		return plan.ErrUnknownColumn.GenByArgs(s.Column.Name.O, tblInfo.Name.O)
+	}
+	funcName, args, err := privilege.CheckMaskExpr(s.MaskExpr, column.Name.O)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	exists, err := maskingPolicyExists(e.ctx, s.Name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if exists {
+		if s.IfNotExists {
+			return nil
+		}
+		return errors.Errorf("masking policy %s already exists", s.Name)
+	}
+	policy := &privilege.MaskingPolicy{
+		Name:     s.Name,
+		DB:       s.Table.Schema.O,
+		Table:    tblInfo.Name.O,
+		Column:   column.Name.O,
+		FuncName: funcName,
+		Args:     args,
+	}
+	sql := fmt.Sprintf(`INSERT INTO %s.%s (name, db, table_name, column_name, mask_expr, exempt_roles) VALUES (%s, %s, %s, %s, %s, %s);`,
+		mysql.SystemDB, mysql.MaskingPolicyTable, quoteString(policy.Name), quoteString(policy.DB), quoteString(policy.Table),
+		quoteString(policy.Column), quoteString(policy.MaskExpr()), quoteString(strings.Join(s.ExemptRoles, ",")))
+	if _, _, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql); err != nil {
+		return errors.Trace(err)
+	}
+	sessionctx.GetDomain(e.ctx).NotifyUpdatePrivilege(e.ctx)
+	return nil
+}
+
+func (e *SimpleExec) executeDropMaskingPolicy(s *ast.DropMaskingPolicyStmt) error {
+	exists, err := maskingPolicyExists(e.ctx, s.Name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !exists {
+		if s.IfExists {
+			return nil
+		}
+		return errors.Errorf("masking policy %s doesn't exist", s.Name)
+	}
+	sql := fmt.Sprintf(`DELETE FROM %s.%s WHERE name = %s;`, mysql.SystemDB, mysql.MaskingPolicyTable, quoteString(s.Name))
+	if _, _, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql); err != nil {
+		return errors.Trace(err)
+	}
+	sessionctx.GetDomain(e.ctx).NotifyUpdatePrivilege(e.ctx)
+	return nil
+}
+
+func maskingPolicyExists(ctx context.Context, name string) (bool, error) {
+	sql := fmt.Sprintf(`SELECT name FROM %s.%s WHERE name = %s;`, mysql.SystemDB, mysql.MaskingPolicyTable, quoteString(name))
+	rows, _, err := ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(ctx, sql)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return len(rows) > 0, nil
+}
+
+// quoteString returns s as a quoted SQL string literal.
+func quoteString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `'`, `\'`, -1)
+	return "'" + s + "'"
+}
@@ -21,7 +21,6 @@ import (
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/mysql"
-	"github.com/pingcap/tidb/privilege/privileges"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/testkit"
@@ -217,11 +216,98 @@ func (s *testSuite) TestSetPwd(c *C) {
 	result.Check(testkit.Rows(rowStr))
 }
 
+func (s *testSuite) TestAuthPlugin(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+
+	tk.MustExec(`CREATE USER 'testplugin'@'localhost' IDENTIFIED WITH caching_sha2_password BY '123';`)
+	result := tk.MustQuery(`SELECT Password, plugin FROM mysql.User WHERE User="testplugin" and Host="localhost"`)
+	result.Check(testkit.Rows(fmt.Sprintf("%v %v", []byte(""), []byte(mysql.AuthCachingSha2Password))))
+	checkSHA256Password := func(pwd string) {
+		rows := tk.MustQuery(`SELECT authentication_string FROM mysql.User WHERE User="testplugin" and Host="localhost"`).Rows()
+		ok, err := util.CheckSHA256Password(fmt.Sprintf("%s", rows[0][0]), pwd)
+		c.Assert(err, IsNil)
+		c.Assert(ok, IsTrue)
+	}
+	checkSHA256Password("123")
+
+	// The password is hashed in the format of the auth plugin of the user.
+	tk.MustExec(`SET PASSWORD FOR 'testplugin'@'localhost' = '456';`)
+	checkSHA256Password("456")
+	tk.MustExec(`ALTER USER 'testplugin'@'localhost' IDENTIFIED BY '789';`)
+	checkSHA256Password("789")
+
+	tk.MustExec(`ALTER USER 'testplugin'@'localhost' IDENTIFIED WITH 'mysql_native_password' BY '123';`)
+	result = tk.MustQuery(`SELECT Password, plugin, authentication_string FROM mysql.User WHERE User="testplugin" and Host="localhost"`)
+	result.Check(testkit.Rows(fmt.Sprintf("%v %v %v", []byte(util.EncodePassword("123")), []byte(mysql.AuthNativePassword), []byte(""))))
+
+	_, err := tk.Exec(`CREATE USER 'testplugin1'@'localhost' IDENTIFIED WITH unknown_plugin BY '123';`)
+	c.Assert(terror.ErrorEqual(err, executor.ErrPluginIsNotLoaded), IsTrue)
+	tk.MustExec(`DROP USER 'testplugin'@'localhost';`)
+}
+
+func (s *testSuite) TestPasswordOrLockOptions(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+
+	checkOptions := func(expected string) {
+		tk.MustQuery(`SELECT account_locked, password_expired, password_lifetime, failed_login_attempts, password_lock_time
+			FROM mysql.User WHERE User="testopt" and Host="localhost"`).Check(testkit.Rows(expected))
+	}
+	tk.MustExec(`CREATE USER 'testopt'@'localhost' IDENTIFIED BY '123' PASSWORD EXPIRE INTERVAL 90 DAY ACCOUNT LOCK ACCOUNT UNLOCK;`)
+	checkOptions("N N 90 0 0")
+	tk.MustQuery(`SELECT password_last_changed IS NOT NULL FROM mysql.User WHERE User="testopt" and Host="localhost"`).Check(testkit.Rows("1"))
+	tk.MustExec(`ALTER USER 'testopt'@'localhost' PASSWORD EXPIRE NEVER ACCOUNT LOCK FAILED_LOGIN_ATTEMPTS 3 PASSWORD_LOCK_TIME UNBOUNDED;`)
+	checkOptions("Y N 0 3 -1")
+	tk.MustExec(`ALTER USER 'testopt'@'localhost' PASSWORD EXPIRE DEFAULT PASSWORD EXPIRE PASSWORD_LOCK_TIME 2;`)
+	checkOptions("Y Y <nil> 3 2")
+	// Changing the password resets the expiration, the password isn't changed without IDENTIFIED BY.
+	tk.MustQuery(`SELECT Password FROM mysql.User WHERE User="testopt" and Host="localhost"`).Check(testkit.Rows(fmt.Sprintf("%v", []byte(util.EncodePassword("123")))))
+	tk.MustExec(`ALTER USER 'testopt'@'localhost' IDENTIFIED BY '456';`)
+	checkOptions("Y N <nil> 3 2")
+	tk.MustExec(`ALTER USER 'testopt'@'localhost' PASSWORD EXPIRE;`)
+	tk.MustExec(`SET PASSWORD FOR 'testopt'@'localhost' = '789';`)
+	checkOptions("Y N <nil> 3 2")
+
+	_, err := tk.Exec(`ALTER USER 'testopt'@'localhost' PASSWORD EXPIRE INTERVAL 0 DAY;`)
+	c.Assert(terror.ErrorEqual(err, executor.ErrWrongValue), IsTrue)
+	_, err = tk.Exec(`ALTER USER 'testopt'@'localhost' FAILED_LOGIN_ATTEMPTS 32768;`)
+	c.Assert(terror.ErrorEqual(err, executor.ErrWrongValue), IsTrue)
+	tk.MustExec(`DROP USER 'testopt'@'localhost';`)
+}
+
+func (s *testSuite) TestValidatePassword(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+
+	tk.MustQuery(`SELECT VALIDATE_PASSWORD_STRENGTH('abc'), VALIDATE_PASSWORD_STRENGTH('abcdef'), VALIDATE_PASSWORD_STRENGTH('abcdefgh'),
+		VALIDATE_PASSWORD_STRENGTH('Abcdef1!'), VALIDATE_PASSWORD_STRENGTH(NULL)`).Check(testkit.Rows("0 25 50 100 <nil>"))
+
+	// The policy is not enforced by default.
+	tk.MustExec(`CREATE USER 'testvalidate'@'localhost' IDENTIFIED BY '123';`)
+	tk.MustExec(`SET GLOBAL tidb_validate_password_enable = 1;`)
+	defer tk.MustExec(`SET GLOBAL tidb_validate_password_enable = 0;`)
+	_, err := tk.Exec(`CREATE USER 'testvalidate1'@'localhost' IDENTIFIED BY '123';`)
+	c.Assert(terror.ErrorEqual(err, executor.ErrNotValidPassword), IsTrue)
+	_, err = tk.Exec(`CREATE USER 'testvalidate1'@'localhost';`)
+	c.Assert(terror.ErrorEqual(err, executor.ErrNotValidPassword), IsTrue)
+	_, err = tk.Exec(`ALTER USER 'testvalidate'@'localhost' IDENTIFIED BY 'abcdefgh';`)
+	c.Assert(terror.ErrorEqual(err, executor.ErrNotValidPassword), IsTrue)
+	_, err = tk.Exec(`SET PASSWORD FOR 'testvalidate'@'localhost' = 'abcdefgh';`)
+	c.Assert(terror.ErrorEqual(err, executor.ErrNotValidPassword), IsTrue)
+	tk.MustExec(`ALTER USER 'testvalidate'@'localhost' IDENTIFIED BY 'Abcdef1!';`)
+	tk.MustExec(`SET PASSWORD FOR 'testvalidate'@'localhost' = 'Abcdef2!';`)
+
+	// The policy is configurable.
+	tk.MustExec(`SET GLOBAL validate_password_policy = 'LOW';`)
+	defer tk.MustExec(`SET GLOBAL validate_password_policy = 'MEDIUM';`)
+	tk.MustExec(`SET PASSWORD FOR 'testvalidate'@'localhost' = 'abcdefgh';`)
+	tk.MustQuery(`SELECT VALIDATE_PASSWORD_STRENGTH('abcdefgh')`).Check(testkit.Rows("50"))
+	tk.MustExec(`DROP USER 'testvalidate'@'localhost';`)
+}
+
 func (s *testSuite) TestFlushPrivileges(c *C) {
 	defer testleak.AfterTest(c)()
-	// Global variables is really bad, when the test cases run concurrently.
-	save := privileges.Enable
-	privileges.Enable = true
 	tk := testkit.NewTestKit(c, s.store)
 
 	tk.MustExec(`CREATE USER 'testflush'@'localhost' IDENTIFIED BY '';`)
@@ -243,6 +329,4 @@ func (s *testSuite) TestFlushPrivileges(c *C) {
 	// After flush.
 	_, err = se.Execute(`SELECT Password FROM mysql.User WHERE User="testflush" and Host="localhost"`)
 	c.Check(err, IsNil)
-
-	privileges.Enable = save
 }
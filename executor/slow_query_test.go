@@ -0,0 +1,73 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/slowlog"
+	"github.com/pingcap/tidb/util/testkit"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func (s *testSuite) TestSlowQuery(c *C) {
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)
+	}()
+	f, err := ioutil.TempFile("", "tidb-slow")
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+	defer os.Remove(f.Name())
+	c.Assert(slowlog.SetFile(f.Name()), IsNil)
+	defer slowlog.SetFile("")
+
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustQuery("select @@tidb_slow_log_threshold").Check(testkit.Rows("300"))
+	tk.MustExec("create table slow_t (a int)")
+	tk.MustExec("insert slow_t values (1), (2), (3)")
+	// The statements faster than the threshold are not logged.
+	tk.MustQuery("select * from slow_t where a > 1").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select count(*) from information_schema.slow_query where `query` like '%slow_t%'").Check(testkit.Rows("0"))
+
+	tk.MustExec("set tidb_slow_log_threshold = 0")
+	tk.MustExec("insert slow_t values (4)")
+	tk.MustQuery("select * from slow_t where a > 1").Check(testkit.Rows("2", "3", "4"))
+	tk.MustQuery("select * from slow_t where a > 3").Check(testkit.Rows("4"))
+	tk.MustExec("set tidb_slow_log_threshold = 300")
+
+	tk.MustQuery("select `query`, db, cop_tasks > 0, process_keys from information_schema.slow_query where `query` like '%slow_t%'").Check(testkit.Rows(
+		"insert slow_t values (4) test 0 0",
+		"select * from slow_t where a > 1 test 1 3",
+		"select * from slow_t where a > 3 test 1 1",
+	))
+
+	// The coprocessor tasks of the index reads are collected too.
+	tk.MustExec("create table slow_idx (a int, b int, index a (a))")
+	tk.MustExec("insert slow_idx values (1, 1), (2, 2), (3, 3)")
+	tk.MustExec("set tidb_slow_log_threshold = 0")
+	tk.MustQuery("select a from slow_idx use index (a) where a > 1").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select b from slow_idx use index (a) where a > 2").Check(testkit.Rows("3"))
+	tk.MustExec("set tidb_slow_log_threshold = 300")
+	tk.MustQuery("select `query`, cop_tasks > 0, process_keys > 0 from information_schema.slow_query where `query` like 'select % from slow_idx%'").Check(testkit.Rows(
+		"select a from slow_idx use index (a) where a > 1 1 1",
+		"select b from slow_idx use index (a) where a > 2 1 1",
+	))
+	// The plans which only differ in constants have the same digest.
+	tk.MustQuery("select count(distinct plan_digest) from information_schema.slow_query where `query` like 'select * from slow_t%'").Check(testkit.Rows("1"))
+	tk.MustQuery("select count(*) from information_schema.slow_query where `query` like 'insert slow_t%' and commit_time > 0").Check(testkit.Rows("1"))
+}
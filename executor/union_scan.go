@@ -29,10 +29,14 @@ import (
 type dirtyDB struct {
 	// Key is tableID.
 	tables map[int64]*dirtyTable
+	// undo reverts the changes of the running statement of a pessimistic transaction in reverse order,
+	// it's nil if the changes don't need to be reverted.
+	undo []func()
 }
 
 func (udb *dirtyDB) addRow(tid, handle int64, row []types.Datum) {
 	dt := udb.getDirtyTable(tid)
+	udb.saveRow(dt, handle)
 	for i := range row {
 		if row[i].Kind() == types.KindString {
 			row[i].SetBytes(row[i].GetBytes())
@@ -43,16 +47,59 @@ func (udb *dirtyDB) addRow(tid, handle int64, row []types.Datum) {
 
 func (udb *dirtyDB) deleteRow(tid int64, handle int64) {
 	dt := udb.getDirtyTable(tid)
+	udb.saveRow(dt, handle)
 	delete(dt.addedRows, handle)
 	dt.deletedRows[handle] = struct{}{}
 }
 
 func (udb *dirtyDB) truncateTable(tid int64) {
 	dt := udb.getDirtyTable(tid)
+	if udb.undo != nil {
+		addedRows, truncated := dt.addedRows, dt.truncated
+		udb.undo = append(udb.undo, func() {
+			dt.addedRows, dt.truncated = addedRows, truncated
+		})
+	}
 	dt.addedRows = make(map[int64][]types.Datum)
 	dt.truncated = true
 }
 
+// saveRow records how to restore the row of the handle if the changes need to be reverted.
+func (udb *dirtyDB) saveRow(dt *dirtyTable, handle int64) {
+	if udb.undo == nil {
+		return
+	}
+	row, added := dt.addedRows[handle]
+	_, deleted := dt.deletedRows[handle]
+	udb.undo = append(udb.undo, func() {
+		if added {
+			dt.addedRows[handle] = row
+		} else {
+			delete(dt.addedRows, handle)
+		}
+		if deleted {
+			dt.deletedRows[handle] = struct{}{}
+		} else {
+			delete(dt.deletedRows, handle)
+		}
+	})
+}
+
+// startStmt starts to record the changes of a statement of a pessimistic transaction.
+func (udb *dirtyDB) startStmt() {
+	udb.undo = make([]func(), 0)
+}
+
+// finishStmt finishes the statement, the changes of the statement are reverted if commit is false.
+func (udb *dirtyDB) finishStmt(commit bool) {
+	if !commit {
+		for i := len(udb.undo) - 1; i >= 0; i-- {
+			udb.undo[i]()
+		}
+	}
+	udb.undo = nil
+}
+
 func (udb *dirtyDB) getDirtyTable(tid int64) *dirtyTable {
 	dt, ok := udb.tables[tid]
 	if !ok {
@@ -855,15 +855,19 @@ func makeLoadDataInfo(column int, ctx context.Context, c *C) (ld *executor.LoadD
 
 func (s *testSuite) TestBatchInsert(c *C) {
 	originLimit := atomic.LoadUint64(&kv.TxnEntryCountLimit)
+	originLargeLimit := atomic.LoadUint64(&kv.LargeTxnEntryCountLimit)
 	originBatch := executor.BatchInsertSize
 	defer func() {
 		s.cleanEnv(c)
 		testleak.AfterTest(c)()
 		atomic.StoreUint64(&kv.TxnEntryCountLimit, originLimit)
+		atomic.StoreUint64(&kv.LargeTxnEntryCountLimit, originLargeLimit)
 		executor.BatchInsertSize = originBatch
 	}()
-	// Set the limitation to a small value, make it easier to reach the limitation.
+	// Set the limitation to a small value, make it easier to reach the limitation. The optimistic transactions
+	// of mock-tikv have the limits of large transactions.
 	atomic.StoreUint64(&kv.TxnEntryCountLimit, 100)
+	atomic.StoreUint64(&kv.LargeTxnEntryCountLimit, 100)
 	executor.BatchInsertSize = 50
 	tk := testkit.NewTestKit(c, s.store)
 	tk.MustExec("use test")
@@ -912,3 +916,40 @@ func (s *testSuite) TestBatchInsert(c *C) {
 	r = tk.MustQuery("select count(*) from batch_insert;")
 	r.Check(testkit.Rows("320"))
 }
+
+func (s *testSuite) TestLargeTxn(c *C) {
+	originQuota := atomic.LoadUint64(&kv.TxnMemBufferQuota)
+	originLimit := atomic.LoadUint64(&kv.LargeTxnEntryCountLimit)
+	defer func() {
+		s.cleanEnv(c)
+		testleak.AfterTest(c)()
+		atomic.StoreUint64(&kv.TxnMemBufferQuota, originQuota)
+		atomic.StoreUint64(&kv.LargeTxnEntryCountLimit, originLimit)
+	}()
+	// Set the quota to a small value, make the write buffer spill to disk. The limits of the transaction are
+	// the default ones of large transactions.
+	atomic.StoreUint64(&kv.TxnMemBufferQuota, 4096)
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("create table large_txn (a int primary key, b int, index idx(b))")
+	tk.MustExec("insert into large_txn values (1, 1), (2, 2), (3, 3), (4, 4)")
+
+	tk.MustExec("begin")
+	for i := 0; i < 5; i++ {
+		tk.MustExec(fmt.Sprintf("insert into large_txn select a + %d, b from large_txn", 4<<uint(i)))
+	}
+	tk.MustExec("update large_txn set b = b + 1 where a > 64")
+	tk.MustExec("delete from large_txn where a <= 4")
+	tk.MustQuery("select count(*), sum(b) from large_txn").Check(testkit.Rows("124 374"))
+	tk.MustExec("commit")
+	tk.MustQuery("select count(*), sum(b) from large_txn").Check(testkit.Rows("124 374"))
+	tk.MustQuery("select count(*) from large_txn use index(idx) where b = 1").Check(testkit.Rows("15"))
+
+	// The transaction still fails if it's larger than the limit.
+	atomic.StoreUint64(&kv.LargeTxnEntryCountLimit, 100)
+	tk.MustExec("begin")
+	_, err := tk.Exec("insert into large_txn select a + 128, b from large_txn")
+	c.Assert(kv.ErrTxnTooLarge.Equal(err), IsTrue)
+	tk.MustExec("rollback")
+	tk.MustQuery("select count(*) from large_txn").Check(testkit.Rows("124"))
+}
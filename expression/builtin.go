@@ -275,6 +275,7 @@ var funcs = map[string]functionClass{
 	// information functions
 	ast.ConnectionID: &connectionIDFunctionClass{baseFunctionClass{ast.ConnectionID, 0, 0}},
 	ast.CurrentUser:  &currentUserFunctionClass{baseFunctionClass{ast.CurrentUser, 0, 0}},
+	ast.CurrentRole:  &currentRoleFunctionClass{baseFunctionClass{ast.CurrentRole, 0, 0}},
 	ast.Database:     &databaseFunctionClass{baseFunctionClass{ast.Database, 0, 0}},
 	// This function is a synonym for DATABASE().
 	// See http://dev.mysql.com/doc/refman/5.7/en/information-functions.html#function_schema
@@ -376,4 +377,10 @@ var funcs = map[string]functionClass{
 	ast.Uncompress:               &uncompressFunctionClass{baseFunctionClass{ast.Uncompress, 1, 1}},
 	ast.UncompressedLength:       &uncompressedLengthFunctionClass{baseFunctionClass{ast.UncompressedLength, 1, 1}},
 	ast.ValidatePasswordStrength: &validatePasswordStrengthFunctionClass{baseFunctionClass{ast.ValidatePasswordStrength, 1, 1}},
+
+	// data masking functions
+	ast.MaskEmail: &maskEmailFunctionClass{baseFunctionClass{ast.MaskEmail, 1, 1}},
+	ast.MaskInner: &maskInnerFunctionClass{baseFunctionClass{ast.MaskInner, 3, 4}},
+	ast.MaskNull:  &maskNullFunctionClass{baseFunctionClass{ast.MaskNull, 1, 1}},
+	ast.MaskOuter: &maskOuterFunctionClass{baseFunctionClass{ast.MaskOuter, 3, 4}},
 }
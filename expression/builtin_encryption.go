@@ -23,11 +23,13 @@ import (
 	"crypto/sha512"
 	"fmt"
 	"hash"
+	"strings"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/encrypt"
+	"github.com/pingcap/tidb/util/pwdvalidate"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -545,7 +547,10 @@ type validatePasswordStrengthFunctionClass struct {
 }
 
 func (c *validatePasswordStrengthFunctionClass) getFunction(args []Expression, ctx context.Context) (builtinFunc, error) {
-	return &builtinValidatePasswordStrengthSig{newBaseBuiltinFunc(args, ctx)}, errors.Trace(c.verifyArgs(args))
+	bt := &builtinValidatePasswordStrengthSig{newBaseBuiltinFunc(args, ctx)}
+	// The result depends on the validate_password_* global variables.
+	bt.deterministic = false
+	return bt, errors.Trace(c.verifyArgs(args))
 }
 
 type builtinValidatePasswordStrengthSig struct {
@@ -554,5 +559,31 @@ type builtinValidatePasswordStrengthSig struct {
 
 // See https://dev.mysql.com/doc/refman/5.7/en/encryption-functions.html#function_validate-password-strength
 func (b *builtinValidatePasswordStrengthSig) eval(row []types.Datum) (d types.Datum, err error) {
-	return d, errFunctionNotExists.GenByArgs("VALIDATE_PASSWORD_STRENGTH")
+	args, err := b.evalArgs(row)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	arg := args[0]
+	if arg.IsNull() {
+		return
+	}
+	pwd, err := arg.ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	vars := b.ctx.GetSessionVars()
+	policy, err := pwdvalidate.LoadPolicy(vars)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	user := vars.User
+	if pos := strings.LastIndex(user, "@"); pos >= 0 {
+		user = user[:pos]
+	}
+	strength, err := policy.Strength(pwd, user)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetInt64(int64(strength))
+	return d, nil
 }
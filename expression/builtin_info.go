@@ -18,6 +18,10 @@
 package expression
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/mysql"
@@ -28,6 +32,7 @@ var (
 	_ functionClass = &databaseFunctionClass{}
 	_ functionClass = &foundRowsFunctionClass{}
 	_ functionClass = &currentUserFunctionClass{}
+	_ functionClass = &currentRoleFunctionClass{}
 	_ functionClass = &userFunctionClass{}
 	_ functionClass = &connectionIDFunctionClass{}
 	_ functionClass = &lastInsertIDFunctionClass{}
@@ -43,6 +48,7 @@ var (
 	_ builtinFunc = &builtinDatabaseSig{}
 	_ builtinFunc = &builtinFoundRowsSig{}
 	_ builtinFunc = &builtinCurrentUserSig{}
+	_ builtinFunc = &builtinCurrentRoleSig{}
 	_ builtinFunc = &builtinUserSig{}
 	_ builtinFunc = &builtinConnectionIDSig{}
 	_ builtinFunc = &builtinLastInsertIDSig{}
@@ -132,6 +138,42 @@ func (b *builtinCurrentUserSig) eval(_ []types.Datum) (d types.Datum, err error)
 	return d, nil
 }
 
+type currentRoleFunctionClass struct {
+	baseFunctionClass
+}
+
+func (c *currentRoleFunctionClass) getFunction(args []Expression, ctx context.Context) (builtinFunc, error) {
+	err := errors.Trace(c.verifyArgs(args))
+	bt := &builtinCurrentRoleSig{newBaseBuiltinFunc(args, ctx)}
+	bt.deterministic = false
+	return bt, errors.Trace(err)
+}
+
+type builtinCurrentRoleSig struct {
+	baseBuiltinFunc
+}
+
+// See https://dev.mysql.com/doc/refman/8.0/en/information-functions.html#function_current-role
+func (b *builtinCurrentRoleSig) eval(_ []types.Datum) (d types.Datum, err error) {
+	data := b.ctx.GetSessionVars()
+	if data == nil {
+		return d, errors.Errorf("Missing session variable when evalue builtin")
+	}
+
+	if len(data.ActiveRoles) == 0 {
+		d.SetString("NONE")
+		return d, nil
+	}
+	roles := make([]string, 0, len(data.ActiveRoles))
+	for _, role := range data.ActiveRoles {
+		pos := strings.LastIndex(role, "@")
+		roles = append(roles, fmt.Sprintf("`%s`@`%s`", role[:pos], role[pos+1:]))
+	}
+	sort.Strings(roles)
+	d.SetString(strings.Join(roles, ","))
+	return d, nil
+}
+
 type userFunctionClass struct {
 	baseFunctionClass
 }
@@ -84,6 +84,24 @@ func (s *testEvaluatorSuite) TestCurrentUser(c *C) {
 	c.Assert(d.GetString(), Equals, "root@localhost")
 }
 
+func (s *testEvaluatorSuite) TestCurrentRole(c *C) {
+	defer testleak.AfterTest(c)()
+	ctx := mock.NewContext()
+	sessionVars := ctx.GetSessionVars()
+
+	fc := funcs[ast.CurrentRole]
+	f, err := fc.getFunction(nil, ctx)
+	c.Assert(err, IsNil)
+	d, err := f.eval(nil)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetString(), Equals, "NONE")
+
+	sessionVars.ActiveRoles = []string{"r2@%", "r1@localhost"}
+	d, err = f.eval(nil)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetString(), Equals, "`r1`@`localhost`,`r2`@`%`")
+}
+
 func (s *testEvaluatorSuite) TestConnectionID(c *C) {
 	defer testleak.AfterTest(c)()
 	ctx := mock.NewContext()
@@ -0,0 +1,209 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expression
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/util/types"
+)
+
+var (
+	_ functionClass = &maskEmailFunctionClass{}
+	_ functionClass = &maskInnerFunctionClass{}
+	_ functionClass = &maskNullFunctionClass{}
+	_ functionClass = &maskOuterFunctionClass{}
+)
+
+var (
+	_ builtinFunc = &builtinMaskEmailSig{}
+	_ builtinFunc = &builtinMaskInnerSig{}
+	_ builtinFunc = &builtinMaskNullSig{}
+	_ builtinFunc = &builtinMaskOuterSig{}
+)
+
+// defaultMaskChar is the character which replaces the masked characters.
+const defaultMaskChar = "X"
+
+type maskEmailFunctionClass struct {
+	baseFunctionClass
+}
+
+func (c *maskEmailFunctionClass) getFunction(args []Expression, ctx context.Context) (builtinFunc, error) {
+	return &builtinMaskEmailSig{newBaseBuiltinFunc(args, ctx)}, errors.Trace(c.verifyArgs(args))
+}
+
+type builtinMaskEmailSig struct {
+	baseBuiltinFunc
+}
+
+// eval masks the local part of an email address except its first character, e.g. "jdoe@example.com"
+// becomes "jXXX@example.com". The string is masked entirely if it isn't an email address.
+func (b *builtinMaskEmailSig) eval(row []types.Datum) (d types.Datum, err error) {
+	args, err := b.evalArgs(row)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	if args[0].IsNull() {
+		return
+	}
+	str, err := args[0].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	pos := strings.LastIndex(str, "@")
+	if pos <= 0 {
+		d.SetString(maskRunes([]rune(str), 0, len([]rune(str)), defaultMaskChar))
+		return d, nil
+	}
+	local := []rune(str[:pos])
+	d.SetString(maskRunes(local, 1, len(local), defaultMaskChar) + str[pos:])
+	return d, nil
+}
+
+type maskInnerFunctionClass struct {
+	baseFunctionClass
+}
+
+func (c *maskInnerFunctionClass) getFunction(args []Expression, ctx context.Context) (builtinFunc, error) {
+	return &builtinMaskInnerSig{newBaseBuiltinFunc(args, ctx)}, errors.Trace(c.verifyArgs(args))
+}
+
+type builtinMaskInnerSig struct {
+	baseBuiltinFunc
+}
+
+// eval masks the characters of the string except margin1 characters at the beginning and margin2
+// characters at the end, the string is unchanged if it isn't longer than the margins.
+// See https://dev.mysql.com/doc/refman/5.7/en/data-masking-reference.html#function_mask-inner
+func (b *builtinMaskInnerSig) eval(row []types.Datum) (d types.Datum, err error) {
+	args, err := b.evalArgs(row)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	str, margin1, margin2, maskChar, isNull, err := maskArgs(b.ctx, args)
+	if isNull || err != nil {
+		return d, errors.Trace(err)
+	}
+	runes := []rune(str)
+	if margin1+margin2 >= len(runes) {
+		d.SetString(str)
+		return d, nil
+	}
+	d.SetString(maskRunes(runes, margin1, len(runes)-margin2, maskChar))
+	return d, nil
+}
+
+type maskOuterFunctionClass struct {
+	baseFunctionClass
+}
+
+func (c *maskOuterFunctionClass) getFunction(args []Expression, ctx context.Context) (builtinFunc, error) {
+	return &builtinMaskOuterSig{newBaseBuiltinFunc(args, ctx)}, errors.Trace(c.verifyArgs(args))
+}
+
+type builtinMaskOuterSig struct {
+	baseBuiltinFunc
+}
+
+// eval masks margin1 characters at the beginning and margin2 characters at the end of the string,
+// the whole string is masked if it isn't longer than the margins.
+// See https://dev.mysql.com/doc/refman/5.7/en/data-masking-reference.html#function_mask-outer
+func (b *builtinMaskOuterSig) eval(row []types.Datum) (d types.Datum, err error) {
+	args, err := b.evalArgs(row)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	str, margin1, margin2, maskChar, isNull, err := maskArgs(b.ctx, args)
+	if isNull || err != nil {
+		return d, errors.Trace(err)
+	}
+	runes := []rune(str)
+	if margin1+margin2 >= len(runes) {
+		d.SetString(maskRunes(runes, 0, len(runes), maskChar))
+		return d, nil
+	}
+	masked := maskRunes(runes, 0, margin1, maskChar)
+	d.SetString(maskRunes([]rune(masked), len(runes)-margin2, len(runes), maskChar))
+	return d, nil
+}
+
+// maskArgs evaluates the arguments of mask_inner and mask_outer, which are the string, the two
+// margins and the optional mask character.
+func maskArgs(ctx context.Context, args []types.Datum) (str string, margin1, margin2 int, maskChar string, isNull bool, err error) {
+	for _, arg := range args {
+		if arg.IsNull() {
+			return "", 0, 0, "", true, nil
+		}
+	}
+	str, err = args[0].ToString()
+	if err != nil {
+		return "", 0, 0, "", false, errors.Trace(err)
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	m1, err := args[1].ToInt64(sc)
+	if err != nil {
+		return "", 0, 0, "", false, errors.Trace(err)
+	}
+	m2, err := args[2].ToInt64(sc)
+	if err != nil {
+		return "", 0, 0, "", false, errors.Trace(err)
+	}
+	if m1 < 0 || m2 < 0 {
+		return "", 0, 0, "", false, errors.Errorf("the margins of the masking function must not be negative")
+	}
+	maskChar = defaultMaskChar
+	if len(args) == 4 {
+		maskChar, err = args[3].ToString()
+		if err != nil {
+			return "", 0, 0, "", false, errors.Trace(err)
+		}
+		if len([]rune(maskChar)) != 1 {
+			return "", 0, 0, "", false, errors.Errorf("the mask character must be a single character")
+		}
+	}
+	// The margins are bounded by the length of the string, so they fit in int.
+	runeLen := int64(len([]rune(str)))
+	if m1 > runeLen {
+		m1 = runeLen
+	}
+	if m2 > runeLen {
+		m2 = runeLen
+	}
+	return str, int(m1), int(m2), maskChar, false, nil
+}
+
+// maskRunes replaces the runes in [start, end) with maskChar.
+func maskRunes(runes []rune, start, end int, maskChar string) string {
+	return string(runes[:start]) + strings.Repeat(maskChar, end-start) + string(runes[end:])
+}
+
+type maskNullFunctionClass struct {
+	baseFunctionClass
+}
+
+func (c *maskNullFunctionClass) getFunction(args []Expression, ctx context.Context) (builtinFunc, error) {
+	return &builtinMaskNullSig{newBaseBuiltinFunc(args, ctx)}, errors.Trace(c.verifyArgs(args))
+}
+
+type builtinMaskNullSig struct {
+	baseBuiltinFunc
+}
+
+// eval hides the value entirely by returning NULL.
+func (b *builtinMaskNullSig) eval(row []types.Datum) (d types.Datum, err error) {
+	return d, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expression
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/types"
+)
+
+func (s *testEvaluatorSuite) TestMaskEmail(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		in  interface{}
+		out interface{}
+	}{
+		{"jdoe@example.com", "jXXX@example.com"},
+		{"j@example.com", "j@example.com"},
+		{"a.b@c@example.com", "aXXXX@example.com"},
+		{"中文名@example.com", "中XX@example.com"},
+		{"not an email", "XXXXXXXXXXXX"},
+		{"@example.com", "XXXXXXXXXXXX"},
+		{"", ""},
+		{nil, nil},
+	}
+	fc := funcs[ast.MaskEmail]
+	for _, t := range tbl {
+		f, err := fc.getFunction(datumsToConstants(types.MakeDatums(t.in)), s.ctx)
+		c.Assert(err, IsNil)
+		d, err := f.eval(nil)
+		c.Assert(err, IsNil)
+		c.Assert(d, DeepEquals, types.NewDatum(t.out), Commentf("%v", t.in))
+	}
+}
+
+func (s *testEvaluatorSuite) TestMaskInnerOuter(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		args  []interface{}
+		inner interface{}
+		outer interface{}
+	}{
+		{[]interface{}{"abcdef", 1, 2}, "aXXXef", "XbcdXX"},
+		{[]interface{}{"abcdef", 0, 0}, "XXXXXX", "abcdef"},
+		{[]interface{}{"abcdef", 3, 3}, "abcdef", "XXXXXX"},
+		{[]interface{}{"abcdef", 10, 1}, "abcdef", "XXXXXX"},
+		{[]interface{}{"abcdef", 1, 1, "*"}, "a****f", "*bcde*"},
+		{[]interface{}{"身份证号码", 1, 1, "#"}, "身###码", "#份证号#"},
+		{[]interface{}{"abcdef", "1", "2"}, "aXXXef", "XbcdXX"},
+		{[]interface{}{nil, 1, 2}, nil, nil},
+		{[]interface{}{"abcdef", nil, 2}, nil, nil},
+		{[]interface{}{"abcdef", 1, 2, nil}, nil, nil},
+	}
+	inner, outer := funcs[ast.MaskInner], funcs[ast.MaskOuter]
+	for _, t := range tbl {
+		args := datumsToConstants(types.MakeDatums(t.args...))
+		f, err := inner.getFunction(args, s.ctx)
+		c.Assert(err, IsNil)
+		d, err := f.eval(nil)
+		c.Assert(err, IsNil)
+		c.Assert(d, DeepEquals, types.NewDatum(t.inner), Commentf("%v", t.args))
+		f, err = outer.getFunction(args, s.ctx)
+		c.Assert(err, IsNil)
+		d, err = f.eval(nil)
+		c.Assert(err, IsNil)
+		c.Assert(d, DeepEquals, types.NewDatum(t.outer), Commentf("%v", t.args))
+	}
+
+	for _, args := range [][]interface{}{
+		{"abcdef", -1, 2},
+		{"abcdef", 1, 2, "**"},
+		{"abcdef", 1, 2, ""},
+	} {
+		f, err := inner.getFunction(datumsToConstants(types.MakeDatums(args...)), s.ctx)
+		c.Assert(err, IsNil)
+		_, err = f.eval(nil)
+		c.Assert(err, NotNil, Commentf("%v", args))
+	}
+}
+
+func (s *testEvaluatorSuite) TestMaskNull(c *C) {
+	defer testleak.AfterTest(c)()
+	f, err := funcs[ast.MaskNull].getFunction(datumsToConstants(types.MakeDatums("secret")), s.ctx)
+	c.Assert(err, IsNil)
+	d, err := f.eval(nil)
+	c.Assert(err, IsNil)
+	c.Assert(d.IsNull(), IsTrue)
+}
@@ -28,7 +28,7 @@ func FoldConstant(expr Expression) Expression {
 	for i := 0; i < len(args); i++ {
 		foldedArg := FoldConstant(args[i])
 		scalarFunc.GetArgs()[i] = foldedArg
-		if _, ok := foldedArg.(*Constant); !ok {
+		if con, ok := foldedArg.(*Constant); !ok || con.DeferredParam != nil {
 			canFold = false
 		}
 	}
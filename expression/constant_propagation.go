@@ -173,7 +173,7 @@ func (s *propagateConstantSolver) pickNewEQConds(visited []bool) (retMapper map[
 		// Then we check if this CNF item is a false constant. If so, we will set the whole condition to false.
 		ok := false
 		if col == nil {
-			if con, ok = cond.(*Constant); ok {
+			if con, ok = cond.(*Constant); ok && con.DeferredParam == nil {
 				value, _ := EvalBool(con, nil, s.ctx)
 				if !value {
 					s.setConds2ConstFalse()
@@ -198,6 +198,10 @@ func (s *propagateConstantSolver) pickNewEQConds(visited []bool) (retMapper map[
 // tryToUpdateEQList tries to update the eqList. When the eqList has store this column with a different constant, like
 // a = 1 and a = 2, we set the second return value to false.
 func (s *propagateConstantSolver) tryToUpdateEQList(col *Column, con *Constant) (bool, bool) {
+	if con.DeferredParam != nil {
+		// The value of a parameter is unknown until the cached plan is executed, so we can't propagate it.
+		return false, false
+	}
 	if con.Value.IsNull() {
 		return false, true
 	}
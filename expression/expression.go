@@ -113,6 +113,9 @@ var Null = &Constant{
 type Constant struct {
 	Value   types.Datum
 	RetType *types.FieldType
+	// DeferredParam is the parameter marker the constant comes from, it is only set when the plan
+	// is going to be cached. The Value is refreshed from it before the cached plan is reused.
+	DeferredParam *ast.ParamMarkerExpr
 }
 
 // String implements fmt.Stringer interface.
@@ -145,7 +148,7 @@ func (c *Constant) Eval(_ []types.Datum) (types.Datum, error) {
 // Equal implements Expression interface.
 func (c *Constant) Equal(b Expression, ctx context.Context) bool {
 	y, ok := b.(*Constant)
-	if !ok {
+	if !ok || c.DeferredParam != y.DeferredParam {
 		return false
 	}
 	con, err := c.Value.CompareDatum(ctx.GetSessionVars().StmtCtx, y.Value)
@@ -16,6 +16,7 @@ package infoschema
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/context"
@@ -28,6 +29,7 @@ import (
 	"github.com/pingcap/tidb/sessionctx/varsutil"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/slowlog"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -50,6 +52,7 @@ const (
 	tableTriggers       = "TRIGGERS"
 	tableUserPrivileges = "USER_PRIVILEGES"
 	tableEngines        = "ENGINES"
+	tableSlowQuery      = "SLOW_QUERY"
 )
 
 type columnInfo struct {
@@ -341,6 +344,23 @@ var tableEnginesCols = []columnInfo{
 	{"SAVEPOINTS", mysql.TypeVarchar, 3, 0, nil, nil},
 }
 
+// tableSlowQueryCols are the fields of the slow query log file.
+var tableSlowQueryCols = []columnInfo{
+	{"TIME", mysql.TypeDatetime, 26, 0, nil, nil},
+	{"CONN_ID", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"USER", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"DB", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"QUERY_TIME", mysql.TypeDouble, 22, 0, nil, nil},
+	{"PARSE_TIME", mysql.TypeDouble, 22, 0, nil, nil},
+	{"COMPILE_TIME", mysql.TypeDouble, 22, 0, nil, nil},
+	{"EXECUTE_TIME", mysql.TypeDouble, 22, 0, nil, nil},
+	{"COMMIT_TIME", mysql.TypeDouble, 22, 0, nil, nil},
+	{"PROCESS_KEYS", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"COP_TASKS", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"PLAN_DIGEST", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"QUERY", mysql.TypeBlob, types.UnspecifiedLength, 0, nil, nil},
+}
+
 func dataForCharacterSets() (records [][]types.Datum) {
 	records = append(records,
 		types.MakeDatums("ascii", "ascii_general_ci", "US ASCII", 1),
@@ -435,6 +455,42 @@ var filesCols = []columnInfo{
 	{"EXTRA", mysql.TypeVarchar, 255, 0, nil, nil},
 }
 
+func dataForSlowQuery(ctx context.Context) ([][]types.Datum, error) {
+	entries, err := slowlog.ParseFile()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// The users without the PROCESS privilege can only see their own queries.
+	sessVars := ctx.GetSessionVars()
+	pm := privilege.GetPrivilegeManager(ctx)
+	all := pm == nil || pm.RequestVerification(sessVars.ActiveRoles, "", "", "", mysql.ProcessPriv)
+	currentUser := strings.Split(sessVars.User, "@")[0]
+	records := make([][]types.Datum, 0, len(entries))
+	for _, e := range entries {
+		if !all && strings.Split(e.User, "@")[0] != currentUser {
+			continue
+		}
+		t := types.Time{Time: types.FromGoTime(e.Time), Type: mysql.TypeDatetime, Fsp: types.MaxFsp}
+		record := types.MakeDatums(
+			t,
+			e.ConnID,
+			e.User,
+			e.DB,
+			e.QueryTime.Seconds(),
+			e.ParseTime.Seconds(),
+			e.CompileTime.Seconds(),
+			e.ExecuteTime.Seconds(),
+			e.CommitTime.Seconds(),
+			e.ProcessKeys,
+			e.CopTasks,
+			e.PlanDigest,
+			e.SQL,
+		)
+		records = append(records, record)
+	}
+	return records, nil
+}
+
 func dataForSchemata(schemas []*model.DBInfo) [][]types.Datum {
 	rows := [][]types.Datum{}
 	for _, schema := range schemas {
@@ -686,6 +742,7 @@ var tableNameToColumns = map[string]([]columnInfo){
 	tableTriggers:       tableTriggersCols,
 	tableUserPrivileges: tableUserPrivilegesCols,
 	tableEngines:        tableEnginesCols,
+	tableSlowQuery:      tableSlowQueryCols,
 }
 
 func createInfoSchemaTable(handle *Handle, meta *model.TableInfo) *infoschemaTable {
@@ -753,6 +810,8 @@ func (it *infoschemaTable) getRows(ctx context.Context, cols []*table.Column) (f
 		fullRows = dataForUserPrivileges(ctx)
 	case tableEngines:
 		fullRows = dataForEngines()
+	case tableSlowQuery:
+		fullRows, err = dataForSlowQuery(ctx)
 	}
 	if err != nil {
 		return nil, errors.Trace(err)
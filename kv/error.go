@@ -34,8 +34,12 @@ const (
 	codeNotImplemented                            = 10
 	codeTxnTooLarge                               = 11
 	codeEntryTooLarge                             = 12
+	codeWriteConflict                             = 13
+	codeResultUndetermined                        = 14
 
-	codeKeyExists = 1062
+	codeKeyExists       = 1062
+	codeLockWaitTimeout = 1205
+	codeDeadlock        = 1213
 )
 
 var (
@@ -67,13 +71,25 @@ var (
 
 	// ErrKeyExists returns when key is already exist.
 	ErrKeyExists = terror.ClassKV.New(codeKeyExists, "key already exist")
+	// ErrWriteConflict is returned when a key locked by a pessimistic transaction is committed by another
+	// transaction after the statement read it, the statement should be executed again.
+	ErrWriteConflict = terror.ClassKV.New(codeWriteConflict, "write conflict")
+	// ErrLockWaitTimeout is returned when a pessimistic transaction waits for a lock too long.
+	ErrLockWaitTimeout = terror.ClassKV.New(codeLockWaitTimeout, "Lock wait timeout exceeded; try restarting transaction")
+	// ErrDeadlock is returned when waiting for a lock causes a deadlock, the transaction should be restarted.
+	ErrDeadlock = terror.ClassKV.New(codeDeadlock, "Deadlock found when trying to get lock; try restarting transaction")
+	// ErrResultUndetermined is returned when it's unknown whether the transaction is committed, the response of
+	// the request to commit it may be lost.
+	ErrResultUndetermined = terror.ClassKV.New(codeResultUndetermined, "result undetermined")
 	// ErrNotImplemented returns when a function is not implemented yet.
 	ErrNotImplemented = terror.ClassKV.New(codeNotImplemented, "not implemented")
 )
 
 func init() {
 	kvMySQLErrCodes := map[terror.ErrCode]uint16{
-		codeKeyExists: mysql.ErrDupEntry,
+		codeKeyExists:       mysql.ErrDupEntry,
+		codeLockWaitTimeout: mysql.ErrLockWaitTimeout,
+		codeDeadlock:        mysql.ErrLockDeadlock,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassKV] = kvMySQLErrCodes
 }
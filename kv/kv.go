@@ -14,6 +14,8 @@
 package kv
 
 import (
+	"sync/atomic"
+
 	goctx "golang.org/x/net/context"
 )
 
@@ -33,6 +35,13 @@ const (
 	SkipCheckForWrite
 	// SchemaLeaseChecker is used for schema lease check.
 	SchemaLeaseChecker
+	// Pessimistic makes the transaction lock the keys when the statements write or lock them, instead of
+	// checking the conflicts when it commits. It's ignored if the store doesn't support pessimistic transactions.
+	Pessimistic
+	// LockWaitTimeout is the time.Duration a pessimistic transaction waits for the locks of other transactions.
+	LockWaitTimeout
+	// OnSpill is a SpillFunc which is called when the write buffer of the transaction spills to disk.
+	OnSpill
 )
 
 // Those limits is enforced to make sure the transaction can be well handled by TiKV.
@@ -42,9 +51,39 @@ var (
 	// The limit of number of entries in the MemBuffer.
 	TxnEntryCountLimit uint64 = 300 * 1000
 	// The limit of the sum of all entry size.
-	TxnTotalSizeLimit = 100 * 1024 * 1024
+	TxnTotalSizeLimit uint64 = 100 * 1024 * 1024
+)
+
+// The limits of the transactions whose write buffers spill and are prewritten in the background, they are
+// larger than TxnMemBufferQuota so the write buffers spill before they're reached. The other transactions are
+// committed from memory, they have the limits above.
+var (
+	LargeTxnEntryCountLimit uint64 = 100 * 1000 * 1000
+	LargeTxnTotalSizeLimit  uint64 = 10 * 1024 * 1024 * 1024
+)
+
+var (
+	// TxnMemBufferQuota is the size of the entries the write buffer of a transaction keeps in memory, they
+	// spill to a sorted store on disk when it's exceeded.
+	TxnMemBufferQuota uint64 = 100 * 1024 * 1024
+	// TxnSpillDir is the directory the write buffers spill to, it's os.TempDir() if it's empty.
+	TxnSpillDir string
 )
 
+// TxnSizeLimits returns the limits of the number and the total size of the entries written by a transaction,
+// large is true if the write buffer of the transaction is prewritten in the background when it spills.
+func TxnSizeLimits(large bool) (entryCount uint64, totalSize uint64) {
+	if large {
+		return atomic.LoadUint64(&LargeTxnEntryCountLimit), atomic.LoadUint64(&LargeTxnTotalSizeLimit)
+	}
+	return atomic.LoadUint64(&TxnEntryCountLimit), atomic.LoadUint64(&TxnTotalSizeLimit)
+}
+
+// SpillFunc is called with the entries which spill from the write buffer of a transaction to disk, the
+// entries are in the order of the keys. The deleted keys have empty values. The iterator is invalid after
+// the func returns, an error returned by the func is returned by the write which causes the spill.
+type SpillFunc func(it Iterator) error
+
 // Retriever is the interface wraps the basic Get and Seek methods.
 type Retriever interface {
 	// Get gets the value for key k from kv store.
@@ -111,6 +150,22 @@ type Transaction interface {
 	Valid() bool
 }
 
+// PessimisticTxn is a Transaction which supports the pessimistic mode, in which the writes of a statement are
+// staged until the statement finishes, then the written keys are locked before the writes are saved into
+// the transaction.
+type PessimisticTxn interface {
+	Transaction
+	// IsPessimistic checks if the transaction is in the pessimistic mode.
+	IsPessimistic() bool
+	// StartStmt starts a statement which reads at forUpdateTS and stages its writes.
+	StartStmt(forUpdateTS uint64)
+	// FinishStmt finishes the running statement, if commit is true, it locks the written keys and the keys
+	// passed to LockKeys by the statement, then saves the writes into the transaction. The writes are
+	// discarded if commit is false or an error is returned, ErrWriteConflict means the statement can be
+	// executed again with a newer forUpdateTS.
+	FinishStmt(commit bool) error
+}
+
 // Client is used to send request to KV layer.
 type Client interface {
 	// Send sends request to KV layer, returns a Response.
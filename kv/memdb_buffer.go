@@ -31,7 +31,7 @@ type memDbBuffer struct {
 	db              *memdb.DB
 	entrySizeLimit  int
 	bufferLenLimit  uint64
-	bufferSizeLimit int
+	bufferSizeLimit uint64
 }
 
 type memDbIter struct {
@@ -45,7 +45,7 @@ func NewMemDbBuffer() MemBuffer {
 		db:              memdb.New(comparer.DefaultComparer, 4*1024),
 		entrySizeLimit:  TxnEntrySizeLimit,
 		bufferLenLimit:  atomic.LoadUint64(&TxnEntryCountLimit),
-		bufferSizeLimit: TxnTotalSizeLimit,
+		bufferSizeLimit: atomic.LoadUint64(&TxnTotalSizeLimit),
 	}
 }
 
@@ -91,7 +91,7 @@ func (m *memDbBuffer) Set(k Key, v []byte) error {
 	}
 
 	err := m.db.Put(k, v)
-	if m.Size() > m.bufferSizeLimit {
+	if uint64(m.Size()) > m.bufferSizeLimit {
 		return ErrTxnTooLarge.Gen("transaction too large, size:%d", m.Size())
 	}
 	if m.Len() > int(m.bufferLenLimit) {
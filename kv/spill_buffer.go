@@ -0,0 +1,293 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"sync/atomic"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/goleveldb/leveldb"
+	"github.com/pingcap/goleveldb/leveldb/comparer"
+	"github.com/pingcap/goleveldb/leveldb/memdb"
+	"github.com/pingcap/goleveldb/leveldb/opt"
+	"github.com/pingcap/goleveldb/leveldb/util"
+)
+
+// spillBatchSize is the size of a write batch when the entries spill to disk.
+const spillBatchSize = 4 * 1024 * 1024
+
+// spillBuffer is the write buffer of a transaction. It keeps the recent writes in memory, and moves them to a
+// sorted store on disk when they exceed TxnMemBufferQuota, so a transaction isn't limited by the memory.
+type spillBuffer struct {
+	mem  *memDbBuffer
+	opts Options
+	// disk is nil until the buffer spills.
+	disk *leveldb.DB
+	dir  string
+	// diskLen and diskSize are the number and the size of the entries on disk.
+	diskLen  int
+	diskSize int
+}
+
+func newSpillBuffer(opts Options) *spillBuffer {
+	return &spillBuffer{
+		mem: &memDbBuffer{
+			db:              memdb.New(comparer.DefaultComparer, 4*1024),
+			entrySizeLimit:  TxnEntrySizeLimit,
+			bufferLenLimit:  math.MaxInt32,
+			bufferSizeLimit: math.MaxUint64,
+		},
+		opts: opts,
+	}
+}
+
+// Get returns the value associated with key.
+func (b *spillBuffer) Get(k Key) ([]byte, error) {
+	v, err := b.mem.Get(k)
+	if b.disk == nil || !IsErrNotFound(err) {
+		return v, err
+	}
+	v, err = b.disk.Get(k, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, ErrNotExist
+	}
+	return v, errors.Trace(err)
+}
+
+// Set associates key with value.
+func (b *spillBuffer) Set(k Key, v []byte) error {
+	if len(v) == 0 {
+		return errors.Trace(ErrCannotSetNilValue)
+	}
+	if len(k)+len(v) > b.mem.entrySizeLimit {
+		return ErrEntryTooLarge.Gen("entry too large, size: %d", len(k)+len(v))
+	}
+	if err := b.mem.db.Put(k, v); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(b.checkSize())
+}
+
+// Delete removes the entry from buffer with provided key.
+func (b *spillBuffer) Delete(k Key) error {
+	if err := b.mem.db.Put(k, nil); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(b.checkSize())
+}
+
+// checkSize checks the limits of the transaction, and spills the entries in memory if they exceed the quota.
+func (b *spillBuffer) checkSize() error {
+	_, large := b.opts.Get(OnSpill)
+	entryCountLimit, totalSizeLimit := TxnSizeLimits(large)
+	if uint64(b.Size()) > totalSizeLimit {
+		return ErrTxnTooLarge.Gen("transaction too large, size:%d", b.Size())
+	}
+	if uint64(b.Len()) > entryCountLimit {
+		return ErrTxnTooLarge.Gen("transaction too large, len:%d", b.Len())
+	}
+	if uint64(b.mem.Size()) > atomic.LoadUint64(&TxnMemBufferQuota) {
+		return errors.Trace(b.spill())
+	}
+	return nil
+}
+
+// spill moves the entries in memory to disk, and calls the OnSpill func with them.
+func (b *spillBuffer) spill() error {
+	if b.disk == nil {
+		dir, err := ioutil.TempDir(TxnSpillDir, "tidb-txn-")
+		if err != nil {
+			return errors.Trace(err)
+		}
+		db, err := leveldb.OpenFile(dir, &opt.Options{NoSync: true})
+		if err != nil {
+			os.RemoveAll(dir)
+			return errors.Trace(err)
+		}
+		b.disk, b.dir = db, dir
+	}
+
+	iter := b.mem.db.NewIterator(&util.Range{})
+	batch := new(leveldb.Batch)
+	var batchSize int
+	for iter.Next() {
+		k, v := iter.Key(), iter.Value()
+		has, err := b.disk.Has(k, nil)
+		if err != nil {
+			iter.Release()
+			return errors.Trace(err)
+		}
+		if !has {
+			b.diskLen++
+		}
+		b.diskSize += len(k) + len(v)
+		batch.Put(k, v)
+		batchSize += len(k) + len(v)
+		if batchSize >= spillBatchSize {
+			if err = b.disk.Write(batch, nil); err != nil {
+				iter.Release()
+				return errors.Trace(err)
+			}
+			batch.Reset()
+			batchSize = 0
+		}
+	}
+	iter.Release()
+	if err := b.disk.Write(batch, nil); err != nil {
+		return errors.Trace(err)
+	}
+
+	var err error
+	if f, ok := b.opts.Get(OnSpill); ok && f != nil {
+		it, _ := b.mem.Seek(nil)
+		err = f.(SpillFunc)(it)
+		it.Close()
+	}
+	// The entries are on disk, so the memory is released even if the func fails.
+	b.mem.db.Reset()
+	return errors.Trace(err)
+}
+
+// Seek creates an Iterator.
+func (b *spillBuffer) Seek(k Key) (Iterator, error) {
+	memIt, err := b.mem.Seek(k)
+	if err != nil || b.disk == nil {
+		return memIt, errors.Trace(err)
+	}
+	diskIt := &memDbIter{iter: b.disk.NewIterator(&util.Range{Start: k}, nil)}
+	diskIt.Next()
+	return newSpillIter(memIt, diskIt, false), nil
+}
+
+// SeekReverse creates a reversed Iterator positioned on the first entry which key is less than k.
+func (b *spillBuffer) SeekReverse(k Key) (Iterator, error) {
+	memIt, err := b.mem.SeekReverse(k)
+	if err != nil || b.disk == nil {
+		return memIt, errors.Trace(err)
+	}
+	diskIt := &memDbIter{iter: b.disk.NewIterator(&util.Range{Limit: k}, nil), reverse: true}
+	diskIt.iter.Last()
+	return newSpillIter(memIt, diskIt, true), nil
+}
+
+// Size returns sum of keys and values length, the entries overwritten after they spill are counted again.
+func (b *spillBuffer) Size() int {
+	return b.mem.Size() + b.diskSize
+}
+
+// Len returns the number of entries, the entries overwritten after they spill are counted again.
+func (b *spillBuffer) Len() int {
+	return b.mem.Len() + b.diskLen
+}
+
+// spilled returns true if the buffer has spilled.
+func (b *spillBuffer) spilled() bool {
+	return b.disk != nil
+}
+
+// release removes the entries on disk.
+func (b *spillBuffer) release() {
+	if b.disk == nil {
+		return
+	}
+	if err := b.disk.Close(); err != nil {
+		log.Warnf("[kv] close spilled buffer %s error: %v", b.dir, err)
+	}
+	if err := os.RemoveAll(b.dir); err != nil {
+		log.Warnf("[kv] remove spilled buffer %s error: %v", b.dir, err)
+	}
+	b.disk = nil
+	b.mem.db.Reset()
+	b.diskLen, b.diskSize = 0, 0
+}
+
+// spillIter merges the iterators of the entries in memory and on disk, the entries in memory are newer.
+type spillIter struct {
+	memIt   Iterator
+	diskIt  Iterator
+	reverse bool
+	curMem  bool
+}
+
+func newSpillIter(memIt, diskIt Iterator, reverse bool) *spillIter {
+	it := &spillIter{
+		memIt:   memIt,
+		diskIt:  diskIt,
+		reverse: reverse,
+	}
+	it.update()
+	return it
+}
+
+// update chooses the iterator of the current entry, and skips the entry on disk which is overwritten in memory.
+func (it *spillIter) update() {
+	if !it.memIt.Valid() || !it.diskIt.Valid() {
+		it.curMem = it.memIt.Valid()
+		return
+	}
+	cmp := it.memIt.Key().Cmp(it.diskIt.Key())
+	if it.reverse {
+		cmp = -cmp
+	}
+	if cmp == 0 {
+		it.diskIt.Next()
+	}
+	it.curMem = cmp <= 0
+}
+
+// Valid implements the Iterator Valid.
+func (it *spillIter) Valid() bool {
+	if it.curMem {
+		return it.memIt.Valid()
+	}
+	return it.diskIt.Valid()
+}
+
+// Next implements the Iterator Next.
+func (it *spillIter) Next() error {
+	var err error
+	if it.curMem {
+		err = it.memIt.Next()
+	} else {
+		err = it.diskIt.Next()
+	}
+	it.update()
+	return errors.Trace(err)
+}
+
+// Key implements the Iterator Key.
+func (it *spillIter) Key() Key {
+	if it.curMem {
+		return it.memIt.Key()
+	}
+	return it.diskIt.Key()
+}
+
+// Value implements the Iterator Value.
+func (it *spillIter) Value() []byte {
+	if it.curMem {
+		return it.memIt.Value()
+	}
+	return it.diskIt.Value()
+}
+
+// Close implements the Iterator Close.
+func (it *spillIter) Close() {
+	it.memIt.Close()
+	it.diskIt.Close()
+}
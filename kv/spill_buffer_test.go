@@ -0,0 +1,212 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+var _ = Suite(&testSpillBufferSuite{})
+
+type testSpillBufferSuite struct {
+	quota uint64
+	dir   string
+}
+
+func (s *testSpillBufferSuite) SetUpTest(c *C) {
+	s.quota = TxnMemBufferQuota
+	TxnMemBufferQuota = 100
+	dir, err := ioutil.TempDir("", "spill-test")
+	c.Assert(err, IsNil)
+	s.dir = dir
+	TxnSpillDir = dir
+}
+
+func (s *testSpillBufferSuite) TearDownTest(c *C) {
+	TxnMemBufferQuota = s.quota
+	TxnSpillDir = ""
+	os.RemoveAll(s.dir)
+}
+
+func checkBufferEntries(c *C, buf MemBuffer, expect map[string]string) {
+	keys := make([]string, 0, len(expect))
+	for k, v := range expect {
+		keys = append(keys, k)
+		val, err := buf.Get(Key(k))
+		c.Assert(err, IsNil)
+		c.Assert(string(val), Equals, v)
+	}
+	sort.Strings(keys)
+
+	it, err := buf.Seek(nil)
+	c.Assert(err, IsNil)
+	for _, k := range keys {
+		c.Assert(it.Valid(), IsTrue)
+		c.Assert(string(it.Key()), Equals, k)
+		c.Assert(string(it.Value()), Equals, expect[k])
+		c.Assert(it.Next(), IsNil)
+	}
+	c.Assert(it.Valid(), IsFalse)
+	it.Close()
+
+	it, err = buf.SeekReverse(nil)
+	c.Assert(err, IsNil)
+	for i := len(keys) - 1; i >= 0; i-- {
+		c.Assert(it.Valid(), IsTrue)
+		c.Assert(string(it.Key()), Equals, keys[i])
+		c.Assert(it.Next(), IsNil)
+	}
+	c.Assert(it.Valid(), IsFalse)
+	it.Close()
+}
+
+func (s *testSpillBufferSuite) TestSpill(c *C) {
+	defer testleak.AfterTest(c)()
+	var spilled []map[string]string
+	opts := options{OnSpill: SpillFunc(func(it Iterator) error {
+		entries := make(map[string]string)
+		for ; it.Valid(); it.Next() {
+			entries[string(it.Key())] = string(it.Value())
+		}
+		spilled = append(spilled, entries)
+		return nil
+	})}
+	buf := newSpillBuffer(opts)
+	expect := make(map[string]string)
+	for i := 0; i < 20; i += 2 {
+		k, v := encodeInt(i), encodeInt(i*10)
+		c.Assert(buf.Set(k, v), IsNil)
+		expect[string(k)] = string(v)
+	}
+	c.Assert(buf.spilled(), IsTrue)
+	c.Assert(len(spilled), Greater, 0)
+	c.Assert(buf.mem.Len(), Less, 10)
+
+	// The entries in memory are newer than the ones on disk.
+	for i := 0; i < 20; i += 4 {
+		k, v := encodeInt(i), encodeInt(i*100)
+		c.Assert(buf.Set(k, v), IsNil)
+		expect[string(k)] = string(v)
+	}
+	c.Assert(buf.Delete(encodeInt(2)), IsNil)
+	expect[string(encodeInt(2))] = ""
+	c.Assert(buf.Set(encodeInt(1), encodeInt(1)), IsNil)
+	expect[string(encodeInt(1))] = string(encodeInt(1))
+	checkBufferEntries(c, buf, expect)
+	_, err := buf.Get(encodeInt(3))
+	c.Assert(IsErrNotFound(err), IsTrue)
+
+	it, err := buf.Seek(encodeInt(5))
+	c.Assert(err, IsNil)
+	c.Assert([]byte(it.Key()), BytesEquals, encodeInt(6))
+	it.Close()
+	it, err = buf.SeekReverse(encodeInt(5))
+	c.Assert(err, IsNil)
+	c.Assert([]byte(it.Key()), BytesEquals, encodeInt(4))
+	it.Close()
+
+	// All the entries are spilled exactly once, the last spill has the newest ones.
+	c.Assert(buf.spill(), IsNil)
+	c.Assert(buf.mem.Len(), Equals, 0)
+	checkBufferEntries(c, buf, expect)
+	merged := make(map[string]string)
+	for _, entries := range spilled {
+		for k, v := range entries {
+			merged[k] = v
+		}
+	}
+	c.Assert(merged, DeepEquals, expect)
+
+	dir := buf.dir
+	_, err = os.Stat(dir)
+	c.Assert(err, IsNil)
+	buf.release()
+	_, err = os.Stat(dir)
+	c.Assert(os.IsNotExist(err), IsTrue)
+}
+
+func (s *testSpillBufferSuite) TestSpillLimit(c *C) {
+	defer testleak.AfterTest(c)()
+	defer func(countLimit uint64) {
+		TxnEntryCountLimit = countLimit
+	}(TxnEntryCountLimit)
+	TxnEntryCountLimit = 30
+
+	buf := newSpillBuffer(options{})
+	defer buf.release()
+	var err error
+	for i := 0; i < 40 && err == nil; i++ {
+		err = buf.Set(encodeInt(i), encodeInt(i))
+	}
+	c.Assert(ErrTxnTooLarge.Equal(err), IsTrue)
+	c.Assert(buf.spilled(), IsTrue)
+	c.Assert(buf.Len(), Equals, 31)
+
+	// The buffers which are prewritten in the background when they spill have the limits of large transactions.
+	buf = newSpillBuffer(options{OnSpill: SpillFunc(func(it Iterator) error { return nil })})
+	defer buf.release()
+	for i := 0; i < 40; i++ {
+		c.Assert(buf.Set(encodeInt(i), encodeInt(i)), IsNil)
+	}
+	c.Assert(buf.Len(), Equals, 40)
+}
+
+func (s *testSpillBufferSuite) TestSpillWithDefaultLimits(c *C) {
+	defer testleak.AfterTest(c)()
+	// The write buffer spills before the transaction reaches the default limits of large transactions.
+	TxnMemBufferQuota = s.quota
+	buf := newSpillBuffer(options{OnSpill: SpillFunc(func(it Iterator) error { return nil })})
+	defer buf.release()
+	value := make([]byte, TxnEntrySizeLimit/2)
+	for i := 0; !buf.spilled(); i++ {
+		c.Assert(buf.Set(encodeInt(i), value), IsNil)
+	}
+	c.Assert(uint64(buf.Size()), Greater, TxnMemBufferQuota)
+}
+
+func (s *testSpillBufferSuite) TestUnionStoreSpill(c *C) {
+	defer testleak.AfterTest(c)()
+	us := NewUnionStore(&mockSnapshot{NewMemDbBuffer()})
+	var spills int
+	us.SetOption(OnSpill, SpillFunc(func(it Iterator) error {
+		spills++
+		return nil
+	}))
+	c.Assert(us.Set([]byte("a"), []byte("1")), IsNil)
+	spilled, err := us.Spill()
+	c.Assert(err, IsNil)
+	c.Assert(spilled, IsFalse)
+
+	for i := 0; i < 10; i++ {
+		c.Assert(us.Set(encodeInt(i), encodeInt(i)), IsNil)
+	}
+	c.Assert(spills, Equals, 1)
+	spilled, err = us.Spill()
+	c.Assert(err, IsNil)
+	c.Assert(spilled, IsTrue)
+	c.Assert(spills, Equals, 2)
+	val, err := us.Get([]byte("a"))
+	c.Assert(err, IsNil)
+	c.Assert(string(val), Equals, "1")
+
+	us.Release()
+	files, err := ioutil.ReadDir(s.dir)
+	c.Assert(err, IsNil)
+	c.Assert(files, HasLen, 0)
+}
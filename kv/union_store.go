@@ -35,6 +35,13 @@ type UnionStore interface {
 	DelOption(opt Option)
 	// GetOption gets an option.
 	GetOption(opt Option) interface{}
+	// GetMemBuffer returns the buffer of the written kv pairs.
+	GetMemBuffer() MemBuffer
+	// Spill moves all the buffered kv pairs in memory to disk if the buffer has spilled, the OnSpill func is
+	// called with them. It returns false if the buffer hasn't spilled.
+	Spill() (bool, error)
+	// Release releases the buffered kv pairs on disk, it's called when the transaction finishes.
+	Release()
 }
 
 // Option is used for customizing kv store's behaviors during a transaction.
@@ -65,11 +72,15 @@ type unionStore struct {
 
 // NewUnionStore builds a new UnionStore.
 func NewUnionStore(snapshot Snapshot) UnionStore {
+	opts := make(options)
 	return &unionStore{
-		BufferStore:        NewBufferStore(snapshot),
+		BufferStore: &BufferStore{
+			r:         snapshot,
+			MemBuffer: &lazyMemBuffer{opts: opts, spillable: true},
+		},
 		snapshot:           snapshot,
 		lazyConditionPairs: make(map[string](*conditionPair)),
-		opts:               make(map[Option]interface{}),
+		opts:               opts,
 	}
 }
 
@@ -97,6 +108,16 @@ func (it invalidIterator) Close() {}
 
 type lazyMemBuffer struct {
 	mb MemBuffer
+	// spillable is true if the buffer spills to disk, opts are the options of the transaction then.
+	spillable bool
+	opts      Options
+}
+
+func (lmb *lazyMemBuffer) newBuffer() MemBuffer {
+	if lmb.spillable {
+		return newSpillBuffer(lmb.opts)
+	}
+	return NewMemDbBuffer()
 }
 
 func (lmb *lazyMemBuffer) Get(k Key) ([]byte, error) {
@@ -109,7 +130,7 @@ func (lmb *lazyMemBuffer) Get(k Key) ([]byte, error) {
 
 func (lmb *lazyMemBuffer) Set(key Key, value []byte) error {
 	if lmb.mb == nil {
-		lmb.mb = NewMemDbBuffer()
+		lmb.mb = lmb.newBuffer()
 	}
 
 	return lmb.mb.Set(key, value)
@@ -117,7 +138,7 @@ func (lmb *lazyMemBuffer) Set(key Key, value []byte) error {
 
 func (lmb *lazyMemBuffer) Delete(k Key) error {
 	if lmb.mb == nil {
-		lmb.mb = NewMemDbBuffer()
+		lmb.mb = lmb.newBuffer()
 	}
 
 	return lmb.mb.Delete(k)
@@ -151,6 +172,23 @@ func (lmb *lazyMemBuffer) Len() int {
 	return lmb.mb.Len()
 }
 
+func (lmb *lazyMemBuffer) spill() (bool, error) {
+	sb, ok := lmb.mb.(*spillBuffer)
+	if !ok || !sb.spilled() {
+		return false, nil
+	}
+	if sb.mem.Len() == 0 {
+		return true, nil
+	}
+	return true, errors.Trace(sb.spill())
+}
+
+func (lmb *lazyMemBuffer) release() {
+	if sb, ok := lmb.mb.(*spillBuffer); ok {
+		sb.release()
+	}
+}
+
 // Get implements the Retriever interface.
 func (us *unionStore) Get(k Key) ([]byte, error) {
 	v, err := us.MemBuffer.Get(k)
@@ -215,6 +253,27 @@ func (us *unionStore) CheckLazyConditionPairs() error {
 	return nil
 }
 
+// GetMemBuffer implements the UnionStore GetMemBuffer interface.
+func (us *unionStore) GetMemBuffer() MemBuffer {
+	return us.BufferStore.MemBuffer
+}
+
+// Spill implements the UnionStore Spill interface.
+func (us *unionStore) Spill() (bool, error) {
+	if lmb, ok := us.MemBuffer.(*lazyMemBuffer); ok {
+		spilled, err := lmb.spill()
+		return spilled, errors.Trace(err)
+	}
+	return false, nil
+}
+
+// Release implements the UnionStore Release interface.
+func (us *unionStore) Release() {
+	if lmb, ok := us.MemBuffer.(*lazyMemBuffer); ok {
+		lmb.release()
+	}
+}
+
 // SetOption implements the UnionStore SetOption interface.
 func (us *unionStore) SetOption(opt Option, val interface{}) {
 	us.opts[opt] = val
@@ -732,6 +732,91 @@ func (m *Meta) SetSchemaDiff(diff *model.SchemaDiff) error {
 	return errors.Trace(err)
 }
 
+// Server ID structure:
+//	ServerIDs: hash -> {
+//		ServerID -> lease expiration time in unix nanoseconds
+//	}
+//	Kill:ServerID: hash -> {
+//		ConnectionID -> "1" to kill the query, "0" to kill the connection
+//	}
+//
+// The ServerIDs are the allocated server IDs of the tidb-servers in the cluster, an ID can be
+// reused after its lease is expired. The Kill hash is the mailbox of the kill requests which
+// are sent to the server.
+
+var (
+	mServerIDsKey = []byte("ServerIDs")
+	mKillPrefix   = "Kill"
+)
+
+func (m *Meta) killKey(serverID uint64) []byte {
+	return []byte(fmt.Sprintf("%s:%d", mKillPrefix, serverID))
+}
+
+// GetServerLeases gets the lease expiration times of the allocated server IDs.
+func (m *Meta) GetServerLeases() (map[uint64]int64, error) {
+	pairs, err := m.txn.HGetAll(mServerIDsKey)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	leases := make(map[uint64]int64, len(pairs))
+	for _, pair := range pairs {
+		serverID, err := strconv.ParseUint(string(pair.Field), 10, 64)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		expire, err := strconv.ParseInt(string(pair.Value), 10, 64)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		leases[serverID] = expire
+	}
+	return leases, nil
+}
+
+// SetServerLease sets the lease expiration time of the server ID.
+func (m *Meta) SetServerLease(serverID uint64, expire int64) error {
+	field := []byte(strconv.FormatUint(serverID, 10))
+	err := m.txn.HSet(mServerIDsKey, field, []byte(strconv.FormatInt(expire, 10)))
+	return errors.Trace(err)
+}
+
+// RemoveServerLease releases the server ID.
+func (m *Meta) RemoveServerLease(serverID uint64) error {
+	err := m.txn.HDel(mServerIDsKey, []byte(strconv.FormatUint(serverID, 10)))
+	return errors.Trace(err)
+}
+
+// AddKillRequest adds a request to kill the connection to the mailbox of the server.
+func (m *Meta) AddKillRequest(serverID, connID uint64, query bool) error {
+	value := []byte("0")
+	if query {
+		value = []byte("1")
+	}
+	err := m.txn.HSet(m.killKey(serverID), []byte(strconv.FormatUint(connID, 10)), value)
+	return errors.Trace(err)
+}
+
+// TakeKillRequests gets and removes the kill requests in the mailbox of the server, the requests
+// are returned as a map from the connection ID to whether only the query is killed.
+func (m *Meta) TakeKillRequests(serverID uint64) (map[uint64]bool, error) {
+	key := m.killKey(serverID)
+	pairs, err := m.txn.HGetAll(key)
+	if err != nil || len(pairs) == 0 {
+		return nil, errors.Trace(err)
+	}
+	requests := make(map[uint64]bool, len(pairs))
+	for _, pair := range pairs {
+		connID, err := strconv.ParseUint(string(pair.Field), 10, 64)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		requests[connID] = string(pair.Value) == "1"
+	}
+	err = m.txn.HClear(key)
+	return requests, errors.Trace(err)
+}
+
 // meta error codes.
 const (
 	codeInvalidTableKey terror.ErrCode = 1
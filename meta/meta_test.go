@@ -216,6 +216,48 @@ func (s *testSuite) TestSnapshot(c *C) {
 	c.Assert(err, NotNil)
 }
 
+func (s *testSuite) TestServerID(c *C) {
+	defer testleak.AfterTest(c)()
+	driver := localstore.Driver{Driver: goleveldb.MemoryDriver{}}
+	store, err := driver.Open("memory")
+	c.Assert(err, IsNil)
+	defer store.Close()
+
+	txn, err := store.Begin()
+	c.Assert(err, IsNil)
+	defer txn.Rollback()
+	m := meta.NewMeta(txn)
+
+	leases, err := m.GetServerLeases()
+	c.Assert(err, IsNil)
+	c.Assert(leases, HasLen, 0)
+	c.Assert(m.SetServerLease(1, 100), IsNil)
+	c.Assert(m.SetServerLease(2, 200), IsNil)
+	leases, err = m.GetServerLeases()
+	c.Assert(err, IsNil)
+	c.Assert(leases, DeepEquals, map[uint64]int64{1: 100, 2: 200})
+	c.Assert(m.RemoveServerLease(1), IsNil)
+	leases, err = m.GetServerLeases()
+	c.Assert(err, IsNil)
+	c.Assert(leases, DeepEquals, map[uint64]int64{2: 200})
+
+	requests, err := m.TakeKillRequests(1)
+	c.Assert(err, IsNil)
+	c.Assert(requests, HasLen, 0)
+	c.Assert(m.AddKillRequest(1, 10, true), IsNil)
+	c.Assert(m.AddKillRequest(1, 11, false), IsNil)
+	c.Assert(m.AddKillRequest(2, 20, false), IsNil)
+	requests, err = m.TakeKillRequests(1)
+	c.Assert(err, IsNil)
+	c.Assert(requests, DeepEquals, map[uint64]bool{10: true, 11: false})
+	requests, err = m.TakeKillRequests(1)
+	c.Assert(err, IsNil)
+	c.Assert(requests, HasLen, 0)
+	requests, err = m.TakeKillRequests(2)
+	c.Assert(err, IsNil)
+	c.Assert(requests, DeepEquals, map[uint64]bool{20: false})
+}
+
 func (s *testSuite) TestDDL(c *C) {
 	defer testleak.AfterTest(c)()
 	driver := localstore.Driver{Driver: goleveldb.MemoryDriver{}}
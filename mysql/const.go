@@ -28,10 +28,12 @@ const (
 
 // Header informations.
 const (
-	OKHeader          byte = 0x00
-	ErrHeader         byte = 0xff
-	EOFHeader         byte = 0xfe
-	LocalInFileHeader byte = 0xfb
+	OKHeader           byte = 0x00
+	ErrHeader          byte = 0xff
+	EOFHeader          byte = 0xfe
+	LocalInFileHeader  byte = 0xfb
+	AuthSwitchHeader   byte = 0xfe
+	AuthMoreDataHeader byte = 0x01
 )
 
 // Server informations.
@@ -119,6 +121,14 @@ const (
 	ClientPluginAuthLenencClientData
 )
 
+// Cursor types of COM_STMT_EXECUTE.
+const (
+	CursorTypeNoCursor   byte = 0x00
+	CursorTypeReadOnly   byte = 0x01
+	CursorTypeForUpdate  byte = 0x02
+	CursorTypeScrollable byte = 0x04
+)
+
 // Cache type informations.
 const (
 	TypeNoCache byte = 0xff
@@ -126,7 +136,25 @@ const (
 
 // Auth name informations.
 const (
-	AuthName = "mysql_native_password"
+	AuthName = AuthNativePassword
+	// AuthNativePassword is the default auth plugin, the client sends the SHA1 scramble of the password.
+	AuthNativePassword = "mysql_native_password"
+	// AuthCachingSha2Password is the default auth plugin of MySQL 8.0, the password is stored as a SHA256 hash.
+	AuthCachingSha2Password = "caching_sha2_password"
+	// AuthSha256Password sends the password in cleartext over TLS or encrypted with the server RSA public key.
+	AuthSha256Password = "sha256_password"
+)
+
+// Auth data informations used in caching_sha2_password and sha256_password exchanges.
+const (
+	// AuthRequestPublicKey is sent by the client to ask for the server RSA public key.
+	AuthRequestPublicKey byte = 0x02
+	// AuthSha256RequestPublicKey is sent by sha256_password clients to ask for the server RSA public key.
+	AuthSha256RequestPublicKey byte = 0x01
+	// AuthFastAuthSuccess tells the client the cached SHA256 scramble is accepted.
+	AuthFastAuthSuccess byte = 0x03
+	// AuthPerformFullAuthentication asks the client to send the full password.
+	AuthPerformFullAuthentication byte = 0x04
 )
 
 // MySQL database and tables.
@@ -141,12 +169,22 @@ const (
 	TablePrivTable = "Tables_priv"
 	// ColumnPrivTable is the table in system db contains column scope privilege info.
 	ColumnPrivTable = "Columns_priv"
+	// RoleEdgeTable is the table in system db contains the roles granted to the users.
+	RoleEdgeTable = "role_edges"
+	// DefaultRoleTable is the table in system db contains the default roles of the users.
+	DefaultRoleTable = "default_roles"
 	// GlobalVariablesTable is the table contains global system variables.
 	GlobalVariablesTable = "GLOBAL_VARIABLES"
 	// GlobalStatusTable is the table contains global status variables.
 	GlobalStatusTable = "GLOBAL_STATUS"
 	// TiDBTable is the table contains tidb info.
 	TiDBTable = "tidb"
+	// BindInfoTable is the table contains the global SQL bindings.
+	BindInfoTable = "bind_info"
+	// AuditLogFilterTable is the table contains the filter rules of the audit log.
+	AuditLogFilterTable = "audit_log_filter"
+	// MaskingPolicyTable is the table contains the column masking policies.
+	MaskingPolicyTable = "masking_policy"
 )
 
 // PrivilegeType  privilege
@@ -180,6 +218,12 @@ const (
 	ExecutePriv
 	// IndexPriv is the privilege to create/drop index.
 	IndexPriv
+	// ProcessPriv is the privilege to see the threads of other users.
+	ProcessPriv
+	// ReloadPriv is the privilege to run flush statement.
+	ReloadPriv
+	// FilePriv is the privilege to read files on the server host.
+	FilePriv
 	// AllPriv is the privilege for all actions.
 	AllPriv
 )
@@ -202,6 +246,9 @@ var Priv2UserCol = map[PrivilegeType]string{
 	AlterPriv:      "Alter_priv",
 	ExecutePriv:    "Execute_priv",
 	IndexPriv:      "Index_priv",
+	ProcessPriv:    "Process_priv",
+	ReloadPriv:     "Reload_priv",
+	FilePriv:       "File_priv",
 }
 
 // Col2PrivType is the privilege tables column name to privilege type.
@@ -219,10 +266,13 @@ var Col2PrivType = map[string]PrivilegeType{
 	"Alter_priv":       AlterPriv,
 	"Execute_priv":     ExecutePriv,
 	"Index_priv":       IndexPriv,
+	"Process_priv":     ProcessPriv,
+	"Reload_priv":      ReloadPriv,
+	"File_priv":        FilePriv,
 }
 
 // AllGlobalPrivs is all the privileges in global scope.
-var AllGlobalPrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv, DeletePriv, CreatePriv, DropPriv, GrantPriv, AlterPriv, ShowDBPriv, SuperPriv, ExecutePriv, IndexPriv, CreateUserPriv}
+var AllGlobalPrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv, DeletePriv, CreatePriv, DropPriv, GrantPriv, AlterPriv, ShowDBPriv, SuperPriv, ExecutePriv, IndexPriv, CreateUserPriv, ProcessPriv, ReloadPriv, FilePriv}
 
 // Priv2Str is the map for privilege to string.
 var Priv2Str = map[PrivilegeType]string{
@@ -239,6 +289,9 @@ var Priv2Str = map[PrivilegeType]string{
 	AlterPriv:      "Alter",
 	ExecutePriv:    "Execute",
 	IndexPriv:      "Index",
+	ProcessPriv:    "Process",
+	ReloadPriv:     "Reload",
+	FilePriv:       "File",
 }
 
 // Priv2SetStr is the map for privilege to string.
@@ -281,6 +334,16 @@ var AllColumnPrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv}
 // AllPrivilegeLiteral is the string literal for All Privilege.
 const AllPrivilegeLiteral = "ALL PRIVILEGES"
 
+// The values of the ssl_type column in mysql.user table.
+const (
+	// SSLTypeNone means the user can connect without TLS.
+	SSLTypeNone = ""
+	// SSLTypeAny means the user must connect with TLS, it is set by REQUIRE SSL.
+	SSLTypeAny = "ANY"
+	// SSLTypeX509 means the user must connect with TLS and a valid client certificate, it is set by REQUIRE X509.
+	SSLTypeX509 = "X509"
+)
+
 // DefaultLengthOfMysqlTypes is the map for default physical length of MySQL data types.
 // See http://dev.mysql.com/doc/refman/5.7/en/storage-requirements.html
 var DefaultLengthOfMysqlTypes = map[byte]int{
@@ -882,4 +882,7 @@ const (
 	ErrMustChangePasswordLogin                                      = 1862
 	ErrRowInWrongPartition                                          = 1863
 	ErrErrorLast                                                    = 1863
+
+	// MySQL 8.0 error codes.
+	ErrRoleNotGranted = 3530
 )
@@ -136,6 +136,7 @@ func init() {
 
 var tokenMap = map[string]int{
 	"ABS":                        abs,
+	"ACCOUNT":                    account,
 	"ACOS":                       acos,
 	"ADD":                        add,
 	"ADDDATE":                    addDate,
@@ -159,6 +160,8 @@ var tokenMap = map[string]int{
 	"AVG":                        avg,
 	"AVG_ROW_LENGTH":             avgRowLength,
 	"BEGIN":                      begin,
+	"BINDING":                    binding,
+	"BINDINGS":                   bindings,
 	"BETWEEN":                    between,
 	"BIN":                        bin,
 	"BINLOG":                     binlog,
@@ -206,6 +209,7 @@ var tokenMap = map[string]int{
 	"CURTIME":                    curTime,
 	"CURRENT_TIME":               currentTime,
 	"CURRENT_USER":               currentUser,
+	"CURRENT_ROLE":               currentRole,
 	"DATA":                       data,
 	"DATABASE":                   database,
 	"DATABASES":                  databases,
@@ -248,8 +252,12 @@ var tokenMap = map[string]int{
 	"ENUM":                       enum,
 	"ESCAPE":                     escape,
 	"ESCAPED":                    escaped,
+	"EXCEPT":                     except,
 	"EVENTS":                     events,
 	"EXECUTE":                    execute,
+	"EXEMPT":                     exempt,
+	"EXPIRE":                     expire,
+	"FAILED_LOGIN_ATTEMPTS":      failedLoginAttempts,
 	"EXISTS":                     exists,
 	"EXP":                        exp,
 	"EXPLAIN":                    explain,
@@ -258,6 +266,7 @@ var tokenMap = map[string]int{
 	"FALSE":                      falseKwd,
 	"FIELD":                      fieldKwd,
 	"FIELDS":                     fields,
+	"FILE":                       file,
 	"FIND_IN_SET":                findInSet,
 	"FIRST":                      first,
 	"FIXED":                      fixed,
@@ -338,6 +347,7 @@ var tokenMap = map[string]int{
 	"MAKE_SET":                   makeSet,
 	"MAX":                        max,
 	"MAXVALUE":                   maxValue,
+	"MASKING":                    masking,
 	"MAX_ROWS":                   maxRows,
 	"MICROSECOND":                microsecond,
 	"MID":                        mid,
@@ -351,6 +361,7 @@ var tokenMap = map[string]int{
 	"MONTHNAME":                  monthname,
 	"NAMES":                      names,
 	"NATIONAL":                   national,
+	"NEVER":                      never,
 	"NONE":                       none,
 	"NOT":                        not,
 	"NO_WRITE_TO_BINLOG":         noWriteToBinLog,
@@ -358,25 +369,31 @@ var tokenMap = map[string]int{
 	"NULLIF":                     nullIf,
 	"OCT":                        oct,
 	"OCTET_LENGTH":               octetLength,
+	"OF":                         of,
 	"OFFSET":                     offset,
 	"ON":                         on,
 	"ONLY":                       only,
+	"OPTIMISTIC":                 optimistic,
 	"OPTION":                     option,
 	"OR":                         or,
 	"ORD":                        ord,
 	"ORDER":                      order,
 	"OUTER":                      outer,
 	"PASSWORD":                   password,
+	"PASSWORD_LOCK_TIME":         passwordLockTime,
 	"PERIOD_ADD":                 periodAdd,
+	"PESSIMISTIC":                pessimistic,
 	"PERIOD_DIFF":                periodDiff,
 	"PI":                         pi,
 	"POSITION":                   position,
 	"POW":                        pow,
 	"POWER":                      power,
+	"POLICY":                     policy,
 	"PREPARE":                    prepare,
 	"PRIMARY":                    primary,
 	"PRIVILEGES":                 privileges,
 	"PROCEDURE":                  procedure,
+	"PROCESS":                    process,
 	"PROCESSLIST":                processlist,
 	"QUARTER":                    quarter,
 	"QUICK":                      quick,
@@ -387,6 +404,7 @@ var tokenMap = map[string]int{
 	"RAND":                       rand,
 	"READ":                       read,
 	"REDUNDANT":                  redundant,
+	"RELOAD":                     reload,
 	"REFERENCES":                 references,
 	"REGEXP":                     regexpKwd,
 	"RELEASE_LOCK":               releaseLock,
@@ -394,9 +412,11 @@ var tokenMap = map[string]int{
 	"REPEAT":                     repeat,
 	"REPEATABLE":                 repeatable,
 	"REPLACE":                    replace,
+	"REQUIRE":                    require,
 	"REVOKE":                     revoke,
 	"RIGHT":                      right,
 	"RLIKE":                      rlike,
+	"ROLE":                       role,
 	"ROLLBACK":                   rollback,
 	"ROUND":                      round,
 	"ROW":                        row,
@@ -420,6 +440,7 @@ var tokenMap = map[string]int{
 	"SNAPSHOT":                   snapshot,
 	"SOME":                       some,
 	"SPACE":                      space,
+	"SSL":                        ssl,
 	"SQRT":                       sqrt,
 	"START":                      start,
 	"STARTING":                   starting,
@@ -458,6 +479,7 @@ var tokenMap = map[string]int{
 	"TRUE":                       trueKwd,
 	"TRUNCATE":                   truncate,
 	"UNCOMMITTED":                uncommitted,
+	"UNBOUNDED":                  unbounded,
 	"UNKNOWN":                    unknown,
 	"UNION":                      union,
 	"UNIQUE":                     unique,
@@ -485,6 +507,7 @@ var tokenMap = map[string]int{
 	"WITH":                       with,
 	"WRITE":                      write,
 	"XOR":                        xor,
+	"X509":                       x509,
 	"YEARWEEK":                   yearweek,
 	"ZEROFILL":                   zerofill,
 	"SQL_CALC_FOUND_ROWS":        calcFoundRows,
@@ -573,6 +596,10 @@ var tokenMap = map[string]int{
 	"UNCOMPRESS":                 uncompress,
 	"UNCOMPRESSED_LENGTH":        uncompressedLength,
 	"VALIDATE_PASSWORD_STRENGTH": validatePasswordStrength,
+	"MASK_EMAIL":                 maskEmail,
+	"MASK_INNER":                 maskInner,
+	"MASK_NULL":                  maskNull,
+	"MASK_OUTER":                 maskOuter,
 	"ANY_VALUE":                  anyValue,
 	"INET_ATON":                  inetAton,
 	"INET_NTOA":                  inetNtoa,
@@ -0,0 +1,149 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+// Normalize returns the normalized form of a SQL statement, statements that
+// only differ in literal values have the same normalized form.
+// Literals and parameter markers are replaced by "?", comments and optimizer
+// hints are removed, the other tokens are lowercased and separated by a space.
+func Normalize(sql string) string {
+	var (
+		buf    bytes.Buffer
+		lval   yySymType
+		inHint bool
+	)
+	s := NewScanner(sql)
+	for {
+		tok := s.Lex(&lval)
+		if tok == 0 || tok == invalid {
+			break
+		}
+		switch tok {
+		case hintBegin:
+			inHint = true
+			continue
+		case hintEnd:
+			inHint = false
+			continue
+		}
+		if inHint || tok == ';' {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		switch tok {
+		case intLit, floatLit, decLit, hexLit, bitLit, stringLit:
+			buf.WriteByte('?')
+		case at:
+			// The scanner doesn't set the text of the "@" of user@host.
+			buf.WriteByte('@')
+		default:
+			buf.WriteString(strings.ToLower(lval.ident))
+		}
+	}
+	return buf.String()
+}
+
+// NormalizeDigest returns the normalized form of a SQL statement and its digest,
+// the digest is the hex encoded SHA-256 of the normalized form.
+func NormalizeDigest(sql string) (normalized, digest string) {
+	normalized = Normalize(sql)
+	sum := sha256.Sum256([]byte(normalized))
+	return normalized, fmt.Sprintf("%x", sum)
+}
+
+// RedactPasswords returns the SQL statement whose passwords are replaced by '***', it's used to log the
+// statements which create or alter users, set passwords or grant privileges. The passwords are the string
+// literals after IDENTIFIED ... BY/AS, in PASSWORD(...), and after "=" in SET PASSWORD or PASSWORD = ....
+func RedactPasswords(sql string) string {
+	var (
+		buf          bytes.Buffer
+		lval         yySymType
+		prev, prev2  int
+		inIdentified bool
+		setPassword  bool
+		tokenCount   int
+		lastRedacted int
+	)
+	s := NewScanner(sql)
+	for {
+		tok := s.Lex(&lval)
+		if tok == 0 || tok == invalid {
+			break
+		}
+		tokenCount++
+		if tokenCount == 2 && prev == set && tok == password {
+			setPassword = true
+		}
+		switch tok {
+		case identified:
+			inIdentified = true
+		case ',':
+			inIdentified = false
+		case stringLit:
+			isPassword := (inIdentified && (prev == by || prev == password || prev == as)) ||
+				(prev == '(' && prev2 == password) || prev == password ||
+				(prev == eq && (setPassword || prev2 == password))
+			if isPassword {
+				buf.WriteString(sql[lastRedacted:lval.offset])
+				buf.WriteString("'***'")
+				lastRedacted = stringLitEnd(sql, lval.offset)
+				inIdentified = false
+			}
+		}
+		prev2, prev = prev, tok
+	}
+	if lastRedacted == 0 {
+		return sql
+	}
+	buf.WriteString(sql[lastRedacted:])
+	return buf.String()
+}
+
+// stringLitEnd returns the end offset of the string literal starting at offset, the adjacent string literals
+// which are concatenated by the scanner are included.
+func stringLitEnd(sql string, offset int) int {
+	i := offset
+	for i < len(sql) && (sql[i] == '\'' || sql[i] == '"') {
+		quote := sql[i]
+		for i++; i < len(sql); i++ {
+			if sql[i] == '\\' {
+				i++
+			} else if sql[i] == quote {
+				if i+1 < len(sql) && sql[i+1] == quote {
+					i++
+				} else {
+					break
+				}
+			}
+		}
+		i++
+		end := i
+		for i < len(sql) && (sql[i] == ' ' || sql[i] == '\t' || sql[i] == '\n' || sql[i] == '\r') {
+			i++
+		}
+		if i >= len(sql) || (sql[i] != '\'' && sql[i] != '"') {
+			return end
+		}
+	}
+	return i
+}
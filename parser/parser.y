@@ -163,6 +163,7 @@ import (
 	numericType		"NUMERIC"
 	oct			"OCT"
 	octetLength		"OCTET_LENGTH"
+	of			"OF"
 	on			"ON"
 	option			"OPTION"
 	or			"OR"
@@ -184,6 +185,7 @@ import (
 	rename         		"RENAME"
 	repeat			"REPEAT"
 	replace			"REPLACE"
+	require			"REQUIRE"
 	restrict		"RESTRICT"
 	revoke			"REVOKE"
 	right			"RIGHT"
@@ -195,6 +197,7 @@ import (
 	set			"SET"
 	show			"SHOW"
 	smallIntType		"SMALLINT"
+	ssl			"SSL"
 	starting		"STARTING"
 	tableKwd		"TABLE"
 	terminated		"TERMINATED"
@@ -245,6 +248,7 @@ import (
 	concat				"CONCAT"
 	concatWs			"CONCAT_WS"
 	connectionID			"CONNECTION_ID"
+	currentRole			"CURRENT_ROLE"
 	convertTz			"CONVERT_TZ"
 	curTime				"CUR_TIME"
 	cos				"COS"
@@ -379,6 +383,10 @@ import (
 	uncompress			"UNCOMPRESS"
 	uncompressedLength		"UNCOMPRESSED_LENGTH"
 	validatePasswordStrength	"VALIDATE_PASSWORD_STRENGTH"
+	maskEmail			"MASK_EMAIL"
+	maskInner			"MASK_INNER"
+	maskNull			"MASK_NULL"
+	maskOuter			"MASK_OUTER"
 	anyValue			"ANY_VALUE"
 	inetAton			"INET_ATON"
 	inetNtoa			"INET_NTOA"
@@ -407,6 +415,8 @@ import (
 	avgRowLength	"AVG_ROW_LENGTH"
 	avg		"AVG"
 	begin		"BEGIN"
+	binding		"BINDING"
+	bindings	"BINDINGS"
 	binlog		"BINLOG"
 	bitType		"BIT"
 	booleanType	"BOOLEAN"
@@ -424,6 +434,7 @@ import (
 	compressed	"COMPRESSED"
 	compression	"COMPRESSION"
 	connection 	"CONNECTION"
+	account		"ACCOUNT"
 	consistent	"CONSISTENT"
 	data 		"DATA"
 	dateType	"DATE"
@@ -439,8 +450,13 @@ import (
 	engine		"ENGINE"
 	engines		"ENGINES"
 	escape 		"ESCAPE"
+	except		"EXCEPT"
 	execute		"EXECUTE"
+	exempt		"EXEMPT"
+	expire		"EXPIRE"
+	failedLoginAttempts	"FAILED_LOGIN_ATTEMPTS"
 	fields		"FIELDS"
+	file		"FILE"
 	first		"FIRST"
 	fixed		"FIXED"
 	flush		"FLUSH"
@@ -454,25 +470,34 @@ import (
 	local		"LOCAL"
 	less		"LESS"
 	level		"LEVEL"
+	masking		"MASKING"
 	mode		"MODE"
 	modify		"MODIFY"
 	maxRows		"MAX_ROWS"
 	minRows		"MIN_ROWS"
 	names		"NAMES"
 	national	"NATIONAL"
+	never		"NEVER"
 	no		"NO"
 	none		"NONE"
 	offset		"OFFSET"
 	only		"ONLY"
+	optimistic	"OPTIMISTIC"
 	password	"PASSWORD"
+	passwordLockTime	"PASSWORD_LOCK_TIME"
+	pessimistic	"PESSIMISTIC"
+	policy		"POLICY"
 	prepare		"PREPARE"
 	privileges	"PRIVILEGES"
+	process		"PROCESS"
 	processlist	"PROCESSLIST"
 	quarter		"QUARTER"
 	quick		"QUICK"
 	redundant	"REDUNDANT"
+	reload		"RELOAD"
 	repeatable	"REPEATABLE"
 	reverse		"REVERSE"
+	role		"ROLE"
 	rollback	"ROLLBACK"
 	row 		"ROW"
 	rowFormat	"ROW_FORMAT"
@@ -496,9 +521,11 @@ import (
 	timeType	"TIME"
 	timestampType	"TIMESTAMP"
 	timestampDiff	"TIMESTAMPDIFF"
+	x509		"X509"
 	transaction	"TRANSACTION"
 	triggers	"TRIGGERS"
 	truncate	"TRUNCATE"
+	unbounded	"UNBOUNDED"
 	uncommitted	"UNCOMMITTED"
 	unknown 	"UNKNOWN"
 	user		"USER"
@@ -548,6 +575,7 @@ import (
 	Assignment		"assignment"
 	AssignmentList		"assignment list"
 	AssignmentListOpt	"assignment list opt"
+	AsOfClause		"AS OF TIMESTAMP clause"
 	AuthOption		"User auth option"
 	AuthString		"Password string value"
 	BeginTransactionStmt	"BEGIN TRANSACTION statement"
@@ -574,18 +602,24 @@ import (
 	DatabaseOption		"CREATE Database specification"
 	DatabaseOptionList	"CREATE Database specification list"
 	DatabaseOptionListOpt	"CREATE Database specification list opt"
+	CreateBindingStmt	"CREATE BINDING statement"
+	CreateMaskingPolicyStmt	"CREATE MASKING POLICY statement"
 	CreateTableStmt		"CREATE TABLE statement"
 	CreateUserStmt		"CREATE User statement"
+	CreateRoleStmt		"CREATE ROLE statement"
 	DBName			"Database Name"
 	DeallocateStmt		"Deallocate prepared statement"
 	DefaultValueExpr	"DefaultValueExpr(Now or Signed Literal)"
 	DeleteFromStmt		"DELETE FROM statement"
 	DistinctOpt		"Distinct option"
 	DoStmt			"Do statement"
+	DropBindingStmt		"DROP BINDING statement"
+	DropMaskingPolicyStmt	"DROP MASKING POLICY statement"
 	DropDatabaseStmt	"DROP DATABASE statement"
 	DropIndexStmt		"DROP INDEX statement"
 	DropTableStmt		"DROP TABLE statement"
 	DropUserStmt		"DROP USER"
+	DropRoleStmt		"DROP ROLE statement"
 	DropViewStmt		"DROP VIEW statement"
 	EmptyStmt		"empty statement"
 	Enclosed		"Enclosed by"
@@ -618,6 +652,7 @@ import (
 	FuncDatetimePrec	"Function datetime precision"
 	GlobalScope		"The scope of variable"
 	GrantStmt		"Grant statement"
+	GrantRoleStmt		"Grant role statement"
 	GroupByClause		"GROUP BY clause"
 	HashString		"Hashed string"
 	HavingClause		"HAVING clause"
@@ -651,6 +686,7 @@ import (
 	Literal			"literal value"
 	LoadDataStmt		"Load data statement"
 	LocalOpt		"Local opt"
+	MaskingExemptOpt	"Optional EXEMPT clause of masking policy"
 	LockTablesStmt		"Lock tables statement"
 	LowPriorityOptional	"LOW_PRIORITY or empty"
 	NotOpt			"optional NOT"
@@ -673,6 +709,9 @@ import (
 	PartitionOpt		"Partition option"
 	PartitionNumOpt		"PARTITION NUM option"
 	PasswordOpt		"Password option"
+	PasswordOrLockOption	"Password expiration or account locking option"
+	PasswordOrLockOptionList	"Password expiration or account locking option list"
+	PasswordOrLockOptionListOpt	"Optional password expiration or account locking option list"
 	ColumnPosition		"Column position [First|After ColumnName]"
 	PreparedStmt		"PreparedStmt"
 	PrepareSQL		"Prepare statement sql string"
@@ -690,7 +729,10 @@ import (
 	RenameTableStmt         "rename table statement"
 	ReplaceIntoStmt		"REPLACE INTO statement"
 	ReplacePriority		"replace statement priority"
+	RequireClause		"Require clause"
+	RequireClauseOpt	"Require clause opt"
 	RevokeStmt		"Revoke statement"
+	RevokeRoleStmt		"Revoke role statement"
 	RollbackStmt		"ROLLBACK statement"
 	RowFormat		"Row format option"
 	SelectLockOpt		"FOR UPDATE or LOCK IN SHARE MODE,"
@@ -703,6 +745,10 @@ import (
 	SelectStmtOpts		"Select statement options"
 	SelectStmtGroup		"SELECT statement optional GROUP BY clause"
 	SetStmt			"Set variable statement"
+	SetRoleStmt		"Set active role statement"
+	SetDefaultRoleStmt	"Set default role statement"
+	SetRoleOpt		"Set role options"
+	SetDefaultRoleOpt	"Set default role options"
 	ShowStmt		"Show engines/databases/tables/columns/warnings/status statement"
 	ShowTargetFilterable    "Show target that can be filtered by WHERE or LIKE"
 	ShowDatabaseNameOpt	"Show tables/columns statement database name option"
@@ -1126,6 +1172,14 @@ BeginTransactionStmt:
 	{
 		$$ = &ast.BeginStmt{}
 	}
+|	"BEGIN" "PESSIMISTIC"
+	{
+		$$ = &ast.BeginStmt{Mode: ast.PessimisticMode}
+	}
+|	"BEGIN" "OPTIMISTIC"
+	{
+		$$ = &ast.BeginStmt{Mode: ast.OptimisticMode}
+	}
 |	"START" "TRANSACTION"
 	{
 		$$ = &ast.BeginStmt{}
@@ -1134,6 +1188,14 @@ BeginTransactionStmt:
 	{
 		$$ = &ast.BeginStmt{}
 	}
+|	"START" "TRANSACTION" "READ" "ONLY"
+	{
+		$$ = &ast.BeginStmt{ReadOnly: true}
+	}
+|	"START" "TRANSACTION" "READ" "ONLY" AsOfClause
+	{
+		$$ = &ast.BeginStmt{ReadOnly: true, AsOf: $5.(*ast.AsOfClause)}
+	}
 
 BinlogStmt:
 	"BINLOG" stringLit
@@ -1794,6 +1856,99 @@ DropUserStmt:
         $$ = &ast.DropUserStmt{IfExists: true, UserList: $5.([]string)}
     }
 
+DropRoleStmt:
+    "DROP" "ROLE" UsernameList
+    {
+        $$ = &ast.DropUserStmt{IsDropRole: true, IfExists: false, UserList: $3.([]string)}
+    }
+|   "DROP" "ROLE" "IF" "EXISTS" UsernameList
+    {
+        $$ = &ast.DropUserStmt{IsDropRole: true, IfExists: true, UserList: $5.([]string)}
+    }
+
+/*******************************************************************
+ *
+ *  Create Binding Statement
+ *
+ *  Example:
+ *      CREATE GLOBAL BINDING FOR select * from t where a = 1
+ *      USING select * from t use index(idx_a) where a = 1
+ *******************************************************************/
+CreateBindingStmt:
+	"CREATE" GlobalScope "BINDING" "FOR" SelectStmt "USING" SelectStmt
+	{
+		originSel := $5.(*ast.SelectStmt)
+		startOffset := parser.startOffset(&yyS[yypt-2])
+		endOffset := parser.endOffset(&yyS[yypt-1])
+		originSel.SetText(strings.TrimSpace(parser.src[startOffset:endOffset]))
+
+		// The hinted select is the last part of the statement, the lookahead token
+		// is the statement terminator, which ends the text of it.
+		hintedSel := $7.(*ast.SelectStmt)
+		startOffset = parser.startOffset(&yyS[yypt])
+		endOffset = parser.endOffset(&parser.yylval)
+		hintedSel.SetText(strings.TrimSpace(parser.src[startOffset:endOffset]))
+
+		$$ = &ast.CreateBindingStmt{
+			GlobalScope: $2.(bool),
+			OriginSel:   originSel,
+			HintedSel:   hintedSel,
+		}
+	}
+
+DropBindingStmt:
+	"DROP" GlobalScope "BINDING" "FOR" SelectStmt
+	{
+		originSel := $5.(*ast.SelectStmt)
+		startOffset := parser.startOffset(&yyS[yypt])
+		endOffset := parser.endOffset(&parser.yylval)
+		originSel.SetText(strings.TrimSpace(parser.src[startOffset:endOffset]))
+
+		$$ = &ast.DropBindingStmt{
+			GlobalScope: $2.(bool),
+			OriginSel:   originSel,
+		}
+	}
+
+/*******************************************************************
+ *
+ *  Create Masking Policy Statement
+ *
+ *  Example:
+ *      CREATE MASKING POLICY p ON db.t(email) USING mask_email(email)
+ *      EXEMPT 'support'@'%'
+ *******************************************************************/
+CreateMaskingPolicyStmt:
+	"CREATE" "MASKING" "POLICY" IfNotExists Identifier "ON" TableName '(' Identifier ')' "USING" Expression MaskingExemptOpt
+	{
+		$$ = &ast.CreateMaskingPolicyStmt{
+			IfNotExists: $4.(bool),
+			Name:        $5,
+			Table:       $7.(*ast.TableName),
+			Column:      &ast.ColumnName{Name: model.NewCIStr($9)},
+			MaskExpr:    $12.(ast.ExprNode),
+			ExemptRoles: $13.([]string),
+		}
+	}
+
+MaskingExemptOpt:
+	{
+		$$ = []string(nil)
+	}
+|	"EXEMPT" UsernameList
+	{
+		$$ = $2.([]string)
+	}
+
+DropMaskingPolicyStmt:
+	"DROP" "MASKING" "POLICY" IfExists Identifier
+	{
+		$$ = &ast.DropMaskingPolicyStmt{
+			IfExists: $4.(bool),
+			Name:     $5,
+		}
+	}
+
 TableOrTables:
 	"TABLE"
 |	"TABLES"
@@ -1833,7 +1988,13 @@ ExplainStmt:
 	}
 |	ExplainSym ExplainableStmt
 	{
-		$$ = &ast.ExplainStmt{Stmt: $2.(ast.StmtNode)}
+		// Set the text of the explained statement, so it can match the SQL bindings.
+		// The lookahead token is the statement terminator, which ends the text.
+		stmt := $2.(ast.StmtNode)
+		startOffset := parser.startOffset(&yyS[yypt])
+		endOffset := parser.endOffset(&parser.yylval)
+		stmt.SetText(strings.TrimSpace(parser.src[startOffset:endOffset]))
+		$$ = &ast.ExplainStmt{Stmt: stmt}
 	}
 
 LengthNum:
@@ -2259,7 +2420,9 @@ UnReservedKeyword:
 | "MIN_ROWS" | "NATIONAL" | "ROW" | "ROW_FORMAT" | "QUARTER" | "GRANTS" | "TRIGGERS" | "DELAY_KEY_WRITE" | "ISOLATION"
 | "REPEATABLE" | "COMMITTED" | "UNCOMMITTED" | "ONLY" | "SERIALIZABLE" | "LEVEL" | "VARIABLES" | "SQL_CACHE" | "INDEXES" | "PROCESSLIST"
 | "SQL_NO_CACHE" | "DISABLE"  | "ENABLE" | "REVERSE" | "SPACE" | "PRIVILEGES" | "NO" | "BINLOG" | "FUNCTION" | "VIEW" | "MODIFY" | "EVENTS" | "PARTITIONS"
-| "TIMESTAMPDIFF" | "NONE" | "SUPER"
+| "TIMESTAMPDIFF" | "NONE" | "SUPER" | "BINDING" | "BINDINGS" | "X509" | "ROLE" | "EXCEPT" | "ACCOUNT" | "EXPIRE" | "NEVER"
+| "FAILED_LOGIN_ATTEMPTS" | "PASSWORD_LOCK_TIME" | "UNBOUNDED" | "PROCESS" | "RELOAD" | "FILE" | "MASKING" | "POLICY" | "EXEMPT"
+| "OPTIMISTIC" | "PESSIMISTIC"
 
 ReservedKeyword:
 "ADD" | "ALL" | "ALTER" | "ANALYZE" | "AND" | "AS" | "ASC" | "BETWEEN" | "BIGINT"
@@ -2274,9 +2437,9 @@ ReservedKeyword:
 | "INTERVAL" | "IS" | "JOIN" | "KEY" | "KEYS" | "KILL" | "LEADING" | "LEFT" | "LIKE" | "LIMIT" | "LINES" | "LOAD"
 | "LOCALTIME" | "LOCALTIMESTAMP" | "LOCK" | "LONGBLOB" | "LONGTEXT" | "MAXVALUE" | "MEDIUMBLOB" | "MEDIUMINT" | "MEDIUMTEXT"
 | "MINUTE_MICROSECOND" | "MINUTE_SECOND" | "MOD" | "NOT" | "NO_WRITE_TO_BINLOG" | "NULL" | "NUMERIC"
-| "ON" | "OPTION" | "OR" | "ORDER" | "OUTER" | "PARTITION" | "PRECISION" | "PRIMARY" | "PROCEDURE" | "RANGE" | "READ" 
-| "REAL" | "REFERENCES" | "REGEXP" | "RENAME" | "REPEAT" | "REPLACE" | "RESTRICT" | "REVOKE" | "RIGHT" | "RLIKE"
-| "SCHEMA" | "SCHEMAS" | "SECOND_MICROSECOND" | "SELECT" | "SET" | "SHOW" | "SMALLINT"
+| "OF" | "ON" | "OPTION" | "OR" | "ORDER" | "OUTER" | "PARTITION" | "PRECISION" | "PRIMARY" | "PROCEDURE" | "RANGE" | "READ" 
+| "REAL" | "REFERENCES" | "REGEXP" | "RENAME" | "REPEAT" | "REPLACE" | "REQUIRE" | "RESTRICT" | "REVOKE" | "RIGHT" | "RLIKE"
+| "SCHEMA" | "SCHEMAS" | "SECOND_MICROSECOND" | "SELECT" | "SET" | "SHOW" | "SMALLINT" | "SSL"
 | "STARTING" | "TABLE" | "TERMINATED" | "THEN" | "TINYBLOB" | "TINYINT" | "TINYTEXT" | "TO"
 | "TRAILING" | "TRUE" | "UNION" | "UNIQUE" | "UNLOCK" | "UNSIGNED"
 | "UPDATE" | "USE" | "USING" | "UTC_DATE" | "UTC_TIMESTAMP" | "VALUES" | "VARBINARY" | "VARCHAR"
@@ -2287,7 +2450,7 @@ ReservedKeyword:
 
 
 NotKeywordToken:
-	"ABS" | "ACOS" | "ADDTIME" | "ADDDATE" | "ADMIN" | "ASIN" | "ATAN" | "ATAN2" | "BENCHMARK" | "BIN" | "BIT_COUNT" | "BIT_LENGTH" | "COALESCE" | "COERCIBILITY" | "CONCAT" | "CONCAT_WS" | "CONNECTION_ID" | "CONVERT_TZ" | "CUR_TIME"| "COS" | "COT" | "COUNT" | "DAY"
+	"ABS" | "ACOS" | "ADDTIME" | "ADDDATE" | "ADMIN" | "ASIN" | "ATAN" | "ATAN2" | "BENCHMARK" | "BIN" | "BIT_COUNT" | "BIT_LENGTH" | "COALESCE" | "COERCIBILITY" | "CONCAT" | "CONCAT_WS" | "CONNECTION_ID" | "CURRENT_ROLE" | "CONVERT_TZ" | "CUR_TIME"| "COS" | "COT" | "COUNT" | "DAY"
 |	"DATEDIFF" | "DATE_ADD" | "DATE_FORMAT" | "DATE_SUB" | "DAYNAME" | "DAYOFMONTH" | "DAYOFWEEK" | "DAYOFYEAR" | "DEGREES" | "ELT" | "EXP" | "EXPORT_SET" | "FROM_DAYS" | "FROM_BASE64" | "FIND_IN_SET" | "FOUND_ROWS"
 |	"GET_FORMAT" | "GROUP_CONCAT" | "GREATEST" | "LEAST" | "HOUR" | "HEX" | "UNHEX" | "IFNULL" | "INSTR" | "ISNULL" | "LAST_INSERT_ID" | "LCASE" | "LENGTH" | "LOAD_FILE" | "LOCATE" | "LOWER" | "LPAD" | "LTRIM"
 |	"MAKE_SET" | "MAX" | "MAKEDATE" | "MAKETIME" | "MICROSECOND" | "MID" | "MIN" |	"MINUTE" | "NULLIF" | "MONTH" | "MONTHNAME" | "NOW" |  "OCT" | "OCTET_LENGTH" | "ORD" | "POSITION" | "PERIOD_ADD" | "PERIOD_DIFF" | "PI" | "POW" | "POWER" | "RAND" | "RADIANS" | "ROW_COUNT"
@@ -2297,6 +2460,7 @@ NotKeywordToken:
 |	"AES_DECRYPT" | "AES_ENCRYPT" | "QUOTE"
 |	"ANY_VALUE" | "INET_ATON" | "INET_NTOA" | "INET6_ATON" | "INET6_NTOA" | "IS_FREE_LOCK" | "IS_IPV4" | "IS_IPV4_COMPAT" | "IS_IPV4_MAPPED" | "IS_IPV6" | "IS_USED_LOCK" | "MASTER_POS_WAIT" | "NAME_CONST" | "RELEASE_ALL_LOCKS" | "UUID" | "UUID_SHORT"
 |	"COMPRESS" | "DECODE" | "DES_DECRYPT" | "DES_ENCRYPT" | "ENCODE" | "ENCRYPT" | "MD5" | "OLD_PASSWORD" | "RANDOM_BYTES" | "SHA1" | "SHA" | "SHA2" | "UNCOMPRESS" | "UNCOMPRESSED_LENGTH" | "VALIDATE_PASSWORD_STRENGTH"
+|	"MASK_EMAIL" | "MASK_INNER" | "MASK_NULL" | "MASK_OUTER"
 
 /************************************************************************************
  *
@@ -3588,6 +3752,11 @@ FunctionCallNonKeyword:
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1)}
 	}
+|	"CURRENT_ROLE" '(' ')'
+	{
+		// See https://dev.mysql.com/doc/refman/8.0/en/information-functions.html#function_current-role
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1)}
+	}
 |	"ROUND" '(' ExpressionList ')'
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
@@ -3910,6 +4079,28 @@ FunctionCallNonKeyword:
 			Args: []ast.ExprNode{$3.(ast.ExprNode)},
 		}
 	}
+|	"MASK_EMAIL" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr($1),
+			Args: []ast.ExprNode{$3.(ast.ExprNode)},
+		}
+	}
+|	"MASK_INNER" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"MASK_NULL" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr($1),
+			Args: []ast.ExprNode{$3.(ast.ExprNode)},
+		}
+	}
+|	"MASK_OUTER" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
 
 GetFormatSelector:
 	"DATE"
@@ -4567,6 +4758,13 @@ TableFactor:
 		tn.IndexHints = $3.([]*ast.IndexHint)
 		$$ = &ast.TableSource{Source: tn, AsName: $2.(model.CIStr)}
 	}
+|	TableName AsOfClause TableAsNameOpt IndexHintListOpt
+	{
+		tn := $1.(*ast.TableName)
+		tn.AsOf = $2.(*ast.AsOfClause)
+		tn.IndexHints = $4.([]*ast.IndexHint)
+		$$ = &ast.TableSource{Source: tn, AsName: $3.(model.CIStr)}
+	}
 |	'(' SelectStmt ')' TableAsName
 	{
 		st := $2.(*ast.SelectStmt)
@@ -4583,6 +4781,12 @@ TableFactor:
 		$$ = $2
 	}
 
+AsOfClause:
+	"AS" "OF" "TIMESTAMP" Expression
+	{
+		$$ = &ast.AsOfClause{TsExpr: $4.(ast.ExprNode)}
+	}
+
 TableAsNameOpt:
 	{
 		$$ = model.CIStr{}
@@ -4989,6 +5193,8 @@ SetStmt:
 	{
 		$$ = &ast.SetPwdStmt{User: $4.(string), Password: $6.(string)}
 	}
+|	SetRoleStmt
+|	SetDefaultRoleStmt
 |	"SET" "GLOBAL" "TRANSACTION" TransactionChars
 	{
 		// Parsed but ignored
@@ -4998,6 +5204,50 @@ SetStmt:
 		// Parsed but ignored
 	}
 
+/* See https://dev.mysql.com/doc/refman/8.0/en/set-role.html */
+SetRoleStmt:
+	"SET" "ROLE" SetRoleOpt
+	{
+		$$ = $3.(*ast.SetRoleStmt)
+	}
+
+SetRoleOpt:
+	SetDefaultRoleOpt
+|	"ALL" "EXCEPT" UsernameList
+	{
+		$$ = &ast.SetRoleStmt{SetRoleOpt: ast.SetRoleAllExcept, RoleList: $3.([]string)}
+	}
+|	"DEFAULT"
+	{
+		$$ = &ast.SetRoleStmt{SetRoleOpt: ast.SetRoleDefault}
+	}
+
+SetDefaultRoleOpt:
+	"NONE"
+	{
+		$$ = &ast.SetRoleStmt{SetRoleOpt: ast.SetRoleNone}
+	}
+|	"ALL"
+	{
+		$$ = &ast.SetRoleStmt{SetRoleOpt: ast.SetRoleAll}
+	}
+|	UsernameList
+	{
+		$$ = &ast.SetRoleStmt{SetRoleOpt: ast.SetRoleRegular, RoleList: $1.([]string)}
+	}
+
+/* See https://dev.mysql.com/doc/refman/8.0/en/set-default-role.html */
+SetDefaultRoleStmt:
+	"SET" "DEFAULT" "ROLE" SetDefaultRoleOpt "TO" UsernameList
+	{
+		opt := $4.(*ast.SetRoleStmt)
+		$$ = &ast.SetDefaultRoleStmt{
+			SetRoleOpt: opt.SetRoleOpt,
+			RoleList: opt.RoleList,
+			UserList: $6.([]string),
+		}
+	}
+
 TransactionChars:
 	TransactionChar
 |	TransactionChars ',' TransactionChar
@@ -5224,6 +5474,15 @@ ShowStmt:
 			User:	$4.(string),
 		}
 	}
+|	"SHOW" "GRANTS" "FOR" Username "USING" UsernameList
+	{
+		// See https://dev.mysql.com/doc/refman/8.0/en/show-grants.html
+		$$ = &ast.ShowStmt{
+			Tp:	ast.ShowGrants,
+			User:	$4.(string),
+			Roles:	$6.([]string),
+		}
+	}
 |	"SHOW" "PROCESSLIST"
 	{
 		$$ = &ast.ShowStmt{
@@ -5316,6 +5575,13 @@ ShowTargetFilterable:
 			GlobalScope: $1.(bool),
 		}
 	}
+|	GlobalScope "BINDINGS"
+	{
+		$$ = &ast.ShowStmt{
+			Tp: ast.ShowBindings,
+			GlobalScope: $1.(bool),
+		}
+	}
 |	GlobalScope "STATUS"
 	{
 		$$ = &ast.ShowStmt{
@@ -5484,6 +5750,8 @@ Statement:
 |	BeginTransactionStmt
 |	BinlogStmt
 |	CommitStmt
+|	CreateBindingStmt
+|	CreateMaskingPolicyStmt
 |	DeallocateStmt
 |	DeleteFromStmt
 |	ExecuteStmt
@@ -5492,14 +5760,19 @@ Statement:
 |	CreateIndexStmt
 |	CreateTableStmt
 |	CreateUserStmt
+|	CreateRoleStmt
 |	DoStmt
+|	DropBindingStmt
+|	DropMaskingPolicyStmt
 |	DropDatabaseStmt
 |	DropIndexStmt
 |	DropTableStmt
 |	DropViewStmt
 |	DropUserStmt
+|	DropRoleStmt
 |	FlushStmt
 |	GrantStmt
+|	GrantRoleStmt
 |	InsertIntoStmt
 |	KillStmt
 |	LoadDataStmt
@@ -5508,6 +5781,7 @@ Statement:
 |	RenameTableStmt
 |	ReplaceIntoStmt
 |	RevokeStmt
+|	RevokeRoleStmt
 |	SelectStmt
 |	UnionStmt
 |	SetStmt
@@ -6222,22 +6496,40 @@ CommaOpt:
  *  https://dev.mysql.com/doc/refman/5.7/en/account-management-sql.html
  ************************************************************************************/
 CreateUserStmt:
-	"CREATE" "USER" IfNotExists UserSpecList
+	"CREATE" "USER" IfNotExists UserSpecList RequireClauseOpt PasswordOrLockOptionListOpt
 	{
  		// See https://dev.mysql.com/doc/refman/5.7/en/create-user.html
 		$$ = &ast.CreateUserStmt{
 			IfNotExists: $3.(bool),
 			Specs: $4.([]*ast.UserSpec),
+			Require: $5.(ast.RequireType),
+			PasswordOrLockOptions: $6.([]*ast.PasswordOrLockOption),
+		}
+	}
+
+/* See https://dev.mysql.com/doc/refman/8.0/en/create-role.html */
+CreateRoleStmt:
+	"CREATE" "ROLE" IfNotExists UsernameList
+	{
+		var specs []*ast.UserSpec
+		for _, role := range $4.([]string) {
+			specs = append(specs, &ast.UserSpec{User: role})
+		}
+		$$ = &ast.CreateUserStmt{
+			IsCreateRole: true,
+			IfNotExists: $3.(bool),
+			Specs: specs,
 		}
 	}
 
 /* See http://dev.mysql.com/doc/refman/5.7/en/alter-user.html */
 AlterUserStmt:
-	"ALTER" "USER" IfExists UserSpecList
+	"ALTER" "USER" IfExists UserSpecList PasswordOrLockOptionListOpt
 	{
 		$$ = &ast.AlterUserStmt{
 			IfExists: $3.(bool),
 			Specs: $4.([]*ast.UserSpec),
+			PasswordOrLockOptions: $5.([]*ast.PasswordOrLockOption),
 		}
 	}
 | 	"ALTER" "USER" IfExists "USER" '(' ')' "IDENTIFIED" "BY" AuthString
@@ -6252,6 +6544,61 @@ AlterUserStmt:
 		}
 	}
 
+PasswordOrLockOptionListOpt:
+	{
+		$$ = []*ast.PasswordOrLockOption{}
+	}
+|	PasswordOrLockOptionList
+
+PasswordOrLockOptionList:
+	PasswordOrLockOption
+	{
+		$$ = []*ast.PasswordOrLockOption{$1.(*ast.PasswordOrLockOption)}
+	}
+|	PasswordOrLockOptionList PasswordOrLockOption
+	{
+		$$ = append($1.([]*ast.PasswordOrLockOption), $2.(*ast.PasswordOrLockOption))
+	}
+
+/* See https://dev.mysql.com/doc/refman/8.0/en/create-user.html#create-user-password-management */
+PasswordOrLockOption:
+	"ACCOUNT" "LOCK"
+	{
+		$$ = &ast.PasswordOrLockOption{Type: ast.Lock}
+	}
+|	"ACCOUNT" "UNLOCK"
+	{
+		$$ = &ast.PasswordOrLockOption{Type: ast.Unlock}
+	}
+|	"PASSWORD" "EXPIRE"
+	{
+		$$ = &ast.PasswordOrLockOption{Type: ast.PasswordExpire}
+	}
+|	"PASSWORD" "EXPIRE" "INTERVAL" LengthNum "DAY"
+	{
+		$$ = &ast.PasswordOrLockOption{Type: ast.PasswordExpireInterval, Count: int64($4.(uint64))}
+	}
+|	"PASSWORD" "EXPIRE" "NEVER"
+	{
+		$$ = &ast.PasswordOrLockOption{Type: ast.PasswordExpireNever}
+	}
+|	"PASSWORD" "EXPIRE" "DEFAULT"
+	{
+		$$ = &ast.PasswordOrLockOption{Type: ast.PasswordExpireDefault}
+	}
+|	"FAILED_LOGIN_ATTEMPTS" LengthNum
+	{
+		$$ = &ast.PasswordOrLockOption{Type: ast.FailedLoginAttempts, Count: int64($2.(uint64))}
+	}
+|	"PASSWORD_LOCK_TIME" LengthNum
+	{
+		$$ = &ast.PasswordOrLockOption{Type: ast.PasswordLockTime, Count: int64($2.(uint64))}
+	}
+|	"PASSWORD_LOCK_TIME" "UNBOUNDED"
+	{
+		$$ = &ast.PasswordOrLockOption{Type: ast.PasswordLockTimeUnbounded}
+	}
+
 UserSpec:
 	Username AuthOption
 	{
@@ -6291,6 +6638,27 @@ AuthOption:
 			HashString: $4.(string),
 		}
 	}
+|	"IDENTIFIED" "WITH" StringName
+	{
+		$$ = &ast.AuthOption{
+			AuthPlugin: $3.(string),
+		}
+	}
+|	"IDENTIFIED" "WITH" StringName "BY" AuthString
+	{
+		$$ = &ast.AuthOption{
+			AuthPlugin: $3.(string),
+			AuthString: $5.(string),
+			ByAuthString: true,
+		}
+	}
+|	"IDENTIFIED" "WITH" StringName "AS" HashString
+	{
+		$$ = &ast.AuthOption{
+			AuthPlugin: $3.(string),
+			HashString: $5.(string),
+		}
+	}
 
 HashString:
 	stringLit
@@ -6303,17 +6671,52 @@ HashString:
  * See https://dev.mysql.com/doc/refman/5.7/en/grant.html
  *************************************************************************************/
 GrantStmt:
-	 "GRANT" PrivElemList "ON" ObjectType PrivLevel "TO" UserSpecList WithGrantOptionOpt
+	 "GRANT" PrivElemList "ON" ObjectType PrivLevel "TO" UserSpecList RequireClauseOpt WithGrantOptionOpt
 	 {
 		$$ = &ast.GrantStmt{
 			Privs: $2.([]*ast.PrivElem),
 			ObjectType: $4.(ast.ObjectTypeType),
 			Level: $5.(*ast.GrantLevel),
 			Users: $7.([]*ast.UserSpec),
-			WithGrant: $8.(bool),
+			Require: $8.(ast.RequireType),
+			WithGrant: $9.(bool),
 		}
 	 }
 
+/* See https://dev.mysql.com/doc/refman/8.0/en/grant.html#grant-roles */
+GrantRoleStmt:
+	"GRANT" UsernameList "TO" UsernameList
+	{
+		$$ = &ast.GrantRoleStmt{
+			Roles: $2.([]string),
+			Users: $4.([]string),
+		}
+	}
+
+/* See https://dev.mysql.com/doc/refman/5.7/en/create-user.html#create-user-tls */
+RequireClauseOpt:
+	{
+		$$ = ast.RequireUnspecified
+	}
+|	"REQUIRE" RequireClause
+	{
+		$$ = $2
+	}
+
+RequireClause:
+	"NONE"
+	{
+		$$ = ast.RequireNone
+	}
+|	"SSL"
+	{
+		$$ = ast.RequireSSL
+	}
+|	"X509"
+	{
+		$$ = ast.RequireX509
+	}
+
 WithGrantOptionOpt:
 	{
 		$$ = false
@@ -6381,6 +6784,10 @@ PrivType:
 	{
 		$$ = mysql.ExecutePriv
 	}
+|	"FILE"
+	{
+		$$ = mysql.FilePriv
+	}
 |	"INDEX"
 	{
 		$$ = mysql.IndexPriv
@@ -6389,6 +6796,14 @@ PrivType:
 	{
 		$$ = mysql.InsertPriv
 	}
+|	"PROCESS"
+	{
+		$$ = mysql.ProcessPriv
+	}
+|	"RELOAD"
+	{
+		$$ = mysql.ReloadPriv
+	}
 |	"SELECT"
 	{
 		$$ = mysql.SelectPriv
@@ -6469,6 +6884,16 @@ RevokeStmt:
 		}
 	 }
 
+/* See https://dev.mysql.com/doc/refman/8.0/en/revoke.html#revoke-roles */
+RevokeRoleStmt:
+	"REVOKE" UsernameList "FROM" UsernameList
+	{
+		$$ = &ast.RevokeRoleStmt{
+			Roles: $2.([]string),
+			Users: $4.([]string),
+		}
+	}
+
 /**************************************LoadDataStmt*****************************************
  * See https://dev.mysql.com/doc/refman/5.7/en/load-data.html
  *******************************************************************************************/
@@ -53,9 +53,9 @@ func (s *testParserSuite) TestSimple(c *C) {
 		"interval", "is", "join", "key", "keys", "kill", "leading", "left", "like", "limit", "lines", "load",
 		"localtime", "localtimestamp", "lock", "longblob", "longtext", "mediumblob", "maxvalue", "mediumint", "mediumtext",
 		"minute_microsecond", "minute_second", "mod", "not", "no_write_to_binlog", "null", "numeric",
-		"on", "option", "or", "order", "outer", "partition", "precision", "primary", "procedure", "range", "read", "real",
-		"references", "regexp", "rename", "repeat", "replace", "revoke", "restrict", "right", "rlike",
-		"schema", "schemas", "second_microsecond", "select", "set", "show", "smallint",
+		"of", "on", "option", "or", "order", "outer", "partition", "precision", "primary", "procedure", "range", "read", "real",
+		"references", "regexp", "rename", "repeat", "replace", "require", "revoke", "restrict", "right", "rlike",
+		"schema", "schemas", "second_microsecond", "select", "set", "show", "smallint", "ssl",
 		"starting", "table", "terminated", "then", "tinyblob", "tinyint", "tinytext", "to",
 		"trailing", "true", "union", "unique", "unlock", "unsigned",
 		"update", "use", "using", "utc_date", "values", "varbinary", "varchar",
@@ -91,7 +91,8 @@ func (s *testParserSuite) TestSimple(c *C) {
 		"compact", "redundant", "sql_no_cache sql_no_cache", "sql_cache sql_cache", "action", "round",
 		"enable", "disable", "reverse", "space", "privileges", "get_lock", "release_lock", "sleep", "no", "greatest", "least",
 		"binlog", "hex", "unhex", "function", "indexes", "from_unixtime", "processlist", "events", "less", "than", "timediff",
-		"ln", "log", "log2", "log10", "timestampdiff", "pi", "quote", "none", "super",
+		"ln", "log", "log2", "log10", "timestampdiff", "pi", "quote", "none", "super", "x509", "process", "reload", "file",
+		"optimistic", "pessimistic",
 	}
 	for _, kw := range unreservedKws {
 		src := fmt.Sprintf("SELECT %s FROM tbl;", kw)
@@ -292,6 +293,11 @@ func (s *testParserSuite) TestDMLStmt(c *C) {
 		{"SELECT * from t for update", true},
 		{"SELECT * from t lock in share mode", true},
 
+		// for transaction mode
+		{"BEGIN PESSIMISTIC", true},
+		{"BEGIN OPTIMISTIC", true},
+		{"START TRANSACTION PESSIMISTIC", false},
+
 		// from join
 		{"SELECT * from t1, t2, t3", true},
 		{"select * from t1 join t2 left join t3 on t2.id = t3.id", true},
@@ -560,6 +566,7 @@ func (s *testParserSuite) TestBuiltin(c *C) {
 		{"SELECT CURRENT_USER();", true},
 		{"SELECT CURRENT_USER;", true},
 		{"SELECT CONNECTION_ID();", true},
+		{"SELECT CURRENT_ROLE();", true},
 		{"SELECT VERSION();", true},
 		{"SELECT BENCHMARK(1000000, AES_ENCRYPT('text',UNHEX('F3229A0B371ED2D9441B830D21A390C3')));", true},
 		{"SELECT CHARSET('abc');", true},
@@ -958,6 +965,14 @@ func (s *testParserSuite) TestBuiltin(c *C) {
 		{`SELECT UNCOMPRESS('any string');`, true},
 		{`SELECT UNCOMPRESSED_LENGTH(@compressed_string);`, true},
 		{`SELECT VALIDATE_PASSWORD_STRENGTH(@str);`, true},
+
+		// for data masking functions
+		{`SELECT MASK_EMAIL('jdoe@example.com');`, true},
+		{`SELECT MASK_INNER('abcdef', 1, 2);`, true},
+		{`SELECT MASK_INNER('abcdef', 1, 2, '*');`, true},
+		{`SELECT MASK_NULL(a) FROM t;`, true},
+		{`SELECT MASK_OUTER('abcdef', 1, 2);`, true},
+		{`SELECT MASK_EMAIL();`, false},
 	}
 	s.RunTest(c, table)
 }
@@ -1294,18 +1309,58 @@ func (s *testParserSuite) TestPrivilege(c *C) {
 		{`CREATE USER 'root'@'localhost' IDENTIFIED BY 'new-password'`, true},
 		{`CREATE USER 'root'@'localhost' IDENTIFIED BY PASSWORD 'hashstring'`, true},
 		{`CREATE USER 'root'@'localhost' IDENTIFIED BY 'new-password', 'root'@'127.0.0.1' IDENTIFIED BY PASSWORD 'hashstring'`, true},
+		{`CREATE USER 'root'@'localhost' IDENTIFIED BY 'new-password' REQUIRE SSL`, true},
+		{`CREATE USER 'root'@'localhost' REQUIRE X509`, true},
+		{`CREATE USER 'root'@'localhost' REQUIRE NONE`, true},
+		{`CREATE USER 'root'@'localhost' REQUIRE`, false},
+		{`CREATE USER 'root'@'localhost' IDENTIFIED WITH caching_sha2_password`, true},
+		{`CREATE USER 'root'@'localhost' IDENTIFIED WITH 'sha256_password' BY 'new-password'`, true},
+		{`CREATE USER 'root'@'localhost' IDENTIFIED WITH mysql_native_password AS 'hashstring'`, true},
+		{`CREATE USER 'root'@'localhost' IDENTIFIED WITH caching_sha2_password BY PASSWORD 'hashstring'`, false},
 		{`ALTER USER IF EXISTS 'root'@'localhost' IDENTIFIED BY 'new-password'`, true},
 		{`ALTER USER 'root'@'localhost' IDENTIFIED BY 'new-password'`, true},
 		{`ALTER USER 'root'@'localhost' IDENTIFIED BY PASSWORD 'hashstring'`, true},
 		{`ALTER USER 'root'@'localhost' IDENTIFIED BY 'new-password', 'root'@'127.0.0.1' IDENTIFIED BY PASSWORD 'hashstring'`, true},
+		{`ALTER USER 'root'@'localhost' IDENTIFIED WITH caching_sha2_password BY 'new-password'`, true},
 		{`ALTER USER USER() IDENTIFIED BY 'new-password'`, true},
 		{`ALTER USER IF EXISTS USER() IDENTIFIED BY 'new-password'`, true},
+		{`CREATE USER 'u'@'%' IDENTIFIED BY 'new-password' PASSWORD EXPIRE INTERVAL 90 DAY ACCOUNT LOCK`, true},
+		{`CREATE USER 'u'@'%' REQUIRE SSL PASSWORD EXPIRE NEVER FAILED_LOGIN_ATTEMPTS 3 PASSWORD_LOCK_TIME 2`, true},
+		{`CREATE USER 'u'@'%' PASSWORD EXPIRE INTERVAL 90`, false},
+		{`ALTER USER 'u'@'%' PASSWORD EXPIRE`, true},
+		{`ALTER USER 'u'@'%' PASSWORD EXPIRE DEFAULT ACCOUNT UNLOCK`, true},
+		{`ALTER USER 'u'@'%' IDENTIFIED BY 'new-password' FAILED_LOGIN_ATTEMPTS 5 PASSWORD_LOCK_TIME UNBOUNDED`, true},
+		{`ALTER USER 'u'@'%' ACCOUNT`, false},
 		{`DROP USER 'root'@'localhost', 'root1'@'localhost'`, true},
 		{`DROP USER IF EXISTS 'root'@'localhost'`, true},
 
+		// for role statements
+		{`CREATE ROLE 'app_read', 'app_write'@'localhost'`, true},
+		{`CREATE ROLE IF NOT EXISTS 'app_read'`, true},
+		{`CREATE ROLE 'app_read' IDENTIFIED BY 'password'`, false},
+		{`DROP ROLE 'app_read', 'app_write'@'localhost'`, true},
+		{`DROP ROLE IF EXISTS 'app_read'`, true},
+		{`GRANT 'app_read', 'app_write' TO 'u1'@'localhost', 'u2'`, true},
+		{`REVOKE 'app_read' FROM 'u1'@'localhost'`, true},
+		{`SET ROLE DEFAULT`, true},
+		{`SET ROLE NONE`, true},
+		{`SET ROLE ALL`, true},
+		{`SET ROLE ALL EXCEPT 'app_read', 'app_write'`, true},
+		{`SET ROLE 'app_read', 'app_write'@'localhost'`, true},
+		{`SET ROLE EXCEPT 'app_read'`, false},
+		{`SET DEFAULT ROLE NONE TO 'u1'`, true},
+		{`SET DEFAULT ROLE ALL TO 'u1', 'u2'@'localhost'`, true},
+		{`SET DEFAULT ROLE 'app_read', 'app_write' TO 'u1'`, true},
+		{`SET DEFAULT ROLE DEFAULT TO 'u1'`, false},
+		{`SHOW GRANTS FOR 'u1' USING 'app_read', 'app_write'`, true},
+		{`SET role = 1`, true},
+		{`create table role (role int, except int)`, true},
+
 		// for grant statement
 		{"GRANT ALL ON db1.* TO 'jeffrey'@'localhost';", true},
 		{"GRANT ALL ON db1.* TO 'jeffrey'@'localhost' WITH GRANT OPTION;", true},
+		{"GRANT ALL ON db1.* TO 'jeffrey'@'localhost' REQUIRE SSL WITH GRANT OPTION;", true},
+		{"GRANT ALL ON db1.* TO 'jeffrey'@'localhost' REQUIRE X509;", true},
 		{"GRANT SELECT ON db2.invoice TO 'jeffrey'@'localhost';", true},
 		{"GRANT ALL ON *.* TO 'someuser'@'somehost';", true},
 		{"GRANT SELECT, INSERT ON *.* TO 'someuser'@'somehost';", true},
@@ -1316,6 +1371,7 @@ func (s *testParserSuite) TestPrivilege(c *C) {
 		{"GRANT SELECT (col1), INSERT (col1,col2) ON mydb.mytbl TO 'someuser'@'somehost';", true},
 		{"grant all privileges on zabbix.* to 'zabbix'@'localhost' identified by 'password';", true},
 		{"GRANT SELECT ON test.* to 'test'", true}, // For issue 2654.
+		{"GRANT PROCESS, RELOAD, FILE ON *.* TO 'monitor'@'%'", true},
 
 		// for revoke statement
 		{"REVOKE ALL ON db1.* FROM 'jeffrey'@'localhost';", true},
@@ -1584,3 +1640,146 @@ func (s *testParserSuite) TestDDLStatements(c *C) {
 		c.Assert(mysql.HasBinaryFlag(colDef.Tp.Flag), IsTrue)
 	}
 }
+
+func (s *testParserSuite) TestBinding(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{"create binding for select * from t where a = 1 using select * from t use index(idx) where a = 1", true},
+		{"create global binding for select * from t using select /*+ TIDB_INLJ(t) */ * from t", true},
+		{"create session binding for select * from t using select * from t", true},
+		{"create binding for select * from t", false},
+		{"create binding for insert into t values (1) using insert into t values (1)", false},
+		{"drop binding for select * from t where a = 1", true},
+		{"drop global binding for select * from t", true},
+		{"drop session binding for select * from t", true},
+		{"show bindings", true},
+		{"show global bindings", true},
+		{"show session bindings like 'select%'", true},
+		{"create table binding (bindings int)", true},
+	}
+	s.RunTest(c, table)
+
+	parser := New()
+	src := "create global binding for select * from t where a = 1  using select * from t use index(idx) where a = 1 ; drop binding for select * from t"
+	stmts, err := parser.Parse(src, "", "")
+	c.Assert(err, IsNil)
+	c.Assert(stmts, HasLen, 2)
+	create := stmts[0].(*ast.CreateBindingStmt)
+	c.Assert(create.GlobalScope, IsTrue)
+	c.Assert(create.OriginSel.Text(), Equals, "select * from t where a = 1")
+	c.Assert(create.HintedSel.Text(), Equals, "select * from t use index(idx) where a = 1")
+	drop := stmts[1].(*ast.DropBindingStmt)
+	c.Assert(drop.GlobalScope, IsFalse)
+	c.Assert(drop.OriginSel.Text(), Equals, "select * from t")
+}
+
+func (s *testParserSuite) TestMaskingPolicy(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{"create masking policy p on t(email) using mask_email(email)", true},
+		{"create masking policy if not exists p on db.t(phone) using mask_inner(phone, 0, 4, '*') exempt 'support', 'dba'@'localhost'", true},
+		{"create masking policy p on t(a) using mask_null(a) exempt support", false},
+		{"create masking policy p on t(a)", false},
+		{"drop masking policy p", true},
+		{"drop masking policy if exists p", true},
+		{"create table policy (masking int, exempt int)", true},
+	}
+	s.RunTest(c, table)
+
+	parser := New()
+	stmt, err := parser.ParseOneStmt("create masking policy p on db.t(phone) using mask_outer(phone, 3, 0) exempt 'support', 'dba'@'localhost'", "", "")
+	c.Assert(err, IsNil)
+	create := stmt.(*ast.CreateMaskingPolicyStmt)
+	c.Assert(create.IfNotExists, IsFalse)
+	c.Assert(create.Name, Equals, "p")
+	c.Assert(create.Table.Schema.L, Equals, "db")
+	c.Assert(create.Table.Name.L, Equals, "t")
+	c.Assert(create.Column.Name.L, Equals, "phone")
+	c.Assert(create.MaskExpr.(*ast.FuncCallExpr).FnName.L, Equals, ast.MaskOuter)
+	c.Assert(create.ExemptRoles, DeepEquals, []string{"support@%", "dba@localhost"})
+}
+
+func (s *testParserSuite) TestAsOfTimestamp(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{"select * from t as of timestamp '2017-09-01 10:00:00'", true},
+		{"select * from t as of timestamp date_sub(now(), interval 10 second) as t1 where t1.a = 1", true},
+		{"select * from t as of timestamp '2017-09-01 10:00:00' t1 use index (idx)", true},
+		{"select * from t1 as of timestamp @ts join t2 on t1.a = t2.a", true},
+		{"select * from t as of timestamp", false},
+		{"select * from t as t1 as of timestamp '2017-09-01 10:00:00'", false},
+		{"start transaction read only", true},
+		{"start transaction read only as of timestamp '2017-09-01 10:00:00'", true},
+		{"start transaction as of timestamp '2017-09-01 10:00:00'", false},
+		{"start transaction read write", false},
+	}
+	s.RunTest(c, table)
+
+	parser := New()
+	stmt, err := parser.ParseOneStmt("select * from db.t as of timestamp '2017-09-01 10:00:00' t1", "", "")
+	c.Assert(err, IsNil)
+	ts := stmt.(*ast.SelectStmt).From.TableRefs.Left.(*ast.TableSource)
+	c.Assert(ts.AsName.L, Equals, "t1")
+	tn := ts.Source.(*ast.TableName)
+	c.Assert(tn.Name.L, Equals, "t")
+	c.Assert(tn.AsOf, NotNil)
+	c.Assert(tn.AsOf.TsExpr.GetValue(), Equals, "2017-09-01 10:00:00")
+
+	stmt, err = parser.ParseOneStmt("start transaction read only as of timestamp now()", "", "")
+	c.Assert(err, IsNil)
+	begin := stmt.(*ast.BeginStmt)
+	c.Assert(begin.ReadOnly, IsTrue)
+	c.Assert(begin.AsOf.TsExpr.(*ast.FuncCallExpr).FnName.L, Equals, "now")
+}
+
+func (s *testParserSuite) TestNormalize(c *C) {
+	defer testleak.AfterTest(c)()
+	tests := []struct {
+		sql        string
+		normalized string
+	}{
+		{"SELECT * FROM t WHERE a = 1", "select * from t where a = ?"},
+		{"select  *  from T where a=1.5 and b = 'x' and c = \"y\";", "select * from t where a = ? and b = ? and c = ?"},
+		{"select /*+ TIDB_INLJ(t1) */ * from t1 /* comment */ where id in (1, 2)", "select * from t1 where id in ( ? , ? )"},
+		{"select * from `t` where a = ? and b = 0x10 and c is null", "select * from t where a = ? and b = ? and c is null"},
+		{"select @a, @@global.autocommit from t use index(idx) limit 10", "select @a , @@global.autocommit from t use index ( idx ) limit ?"},
+		{"create user 'u'@'%' identified by 'secret'", "create user ? @ ? identified by ?"},
+	}
+	for _, t := range tests {
+		c.Assert(Normalize(t.sql), Equals, t.normalized, Commentf("sql %s", t.sql))
+	}
+
+	normalized1, digest1 := NormalizeDigest("select * from t where a = 1")
+	normalized2, digest2 := NormalizeDigest("SELECT * FROM t WHERE a = 2")
+	c.Assert(normalized1, Equals, normalized2)
+	c.Assert(digest1, Equals, digest2)
+	c.Assert(digest1, HasLen, 64)
+	_, digest3 := NormalizeDigest("select * from t where b = 1")
+	c.Assert(digest3, Not(Equals), digest1)
+}
+
+func (s *testParserSuite) TestRedactPasswords(c *C) {
+	defer testleak.AfterTest(c)()
+	tests := []struct {
+		sql      string
+		redacted string
+	}{
+		{"create user 'u'@'%' identified by 'secret'", "create user 'u'@'%' identified by '***'"},
+		{"CREATE USER u1 IDENTIFIED BY 'a''b', u2 IDENTIFIED BY PASSWORD '*ABC', u3",
+			"CREATE USER u1 IDENTIFIED BY '***', u2 IDENTIFIED BY PASSWORD '***', u3"},
+		{"alter user 'u' identified with 'mysql_native_password' by \"secret\" password expire",
+			"alter user 'u' identified with 'mysql_native_password' by '***' password expire"},
+		{"alter user u identified with 'mysql_native_password' as '*ABC'", "alter user u identified with 'mysql_native_password' as '***'"},
+		{"set password = 'secret'", "set password = '***'"},
+		{"SET PASSWORD FOR 'u'@'%' = PASSWORD('secret')", "SET PASSWORD FOR 'u'@'%' = PASSWORD('***')"},
+		{"set password for u = 'secret';", "set password for u = '***';"},
+		{"set password = 'se' 'cret' ", "set password = '***' "},
+		{"grant select on *.* to 'u'@'%' identified by 'secret' with grant option",
+			"grant select on *.* to 'u'@'%' identified by '***' with grant option"},
+		{"grant all on test.* to 'u'@'%'", "grant all on test.* to 'u'@'%'"},
+		{"select 'secret' from t order by 'a'", "select 'secret' from t order by 'a'"},
+	}
+	for _, t := range tests {
+		c.Assert(RedactPasswords(t.sql), Equals, t.redacted, Commentf("sql %s", t.sql))
+	}
+}
@@ -35,6 +35,9 @@ type StatementInstrument interface {
 	StartStatement(sql string, connID uint64, callerName EnumCallerName, elem interface{}) *StatementState
 
 	EndStatement(state *StatementState)
+
+	// SummarizeStatement aggregates the execution information of a finished statement by its digest.
+	SummarizeStatement(info *StatementExecInfo)
 }
 
 // PerfSchema defines the methods to be invoked by the executor
@@ -56,6 +59,8 @@ type perfSchema struct {
 	mTables     map[string]table.Table // Memory tables for perfSchema
 	stmtHandles []int64
 	stmtInfos   map[reflect.Type]*statementInfo
+	// stmtSummaries holds the rows of events_statements_summary_by_digest.
+	stmtSummaries stmtSummaries
 }
 
 var (
@@ -70,6 +75,11 @@ func EnablePerfSchema() {
 	enablePerfSchema = true
 }
 
+// IsEnabled returns whether perfschema is enabled.
+func IsEnabled() bool {
+	return enablePerfSchema
+}
+
 // NewPerfHandle creates a new perfSchema on store.
 func NewPerfHandle() (PerfSchema, error) {
 	schema := &perfSchema{}
@@ -24,6 +24,7 @@ import (
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/perfschema"
+	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 )
 
@@ -155,6 +156,44 @@ func (p *testPerfSchemaSuit) TestConcurrentStatement(c *C) {
 	wg.Wait()
 }
 
+func (p *testPerfSchemaSuit) TestStatementSummary(c *C) {
+	defer testleak.AfterTest(c)()
+	store, err := tidb.NewStore(tidb.EngineGoLevelDBMemory + "/test_stmt_summary")
+	c.Assert(err, IsNil)
+	defer store.Close()
+	_, err = tidb.BootstrapSession(store)
+	c.Assert(err, IsNil)
+	tk := testkit.NewTestKit(c, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table summary_t (a int primary key)")
+	tk.MustExec("insert summary_t values (1), (2), (3)")
+	tk.MustQuery("select * from summary_t where a > 1").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select * from summary_t where a > 2").Check(testkit.Rows("3"))
+	_, err = tk.Exec("insert summary_t values (1)")
+	c.Assert(err, NotNil)
+	tk.MustExec("insert summary_t values (4)")
+
+	tk.MustQuery(`select schema_name, digest_text, count_star, sum_errors, sum_rows_affected, sum_rows_sent, length(plan_digests)
+		from performance_schema.events_statements_summary_by_digest where digest_text like '%summary_t%' and digest_text not like '%create%'
+		order by digest_text`).Check(testkit.Rows(
+		"test insert summary_t values ( ? ) 2 1 1 0 64",
+		"test insert summary_t values ( ? ) , ( ? ) , ( ? ) 1 0 3 0 64",
+		"test select * from summary_t where a > ? 2 0 0 3 64",
+	))
+	tk.MustQuery(`select sum_timer_wait >= max_timer_wait, max_timer_wait >= avg_timer_wait, avg_timer_wait > 0,
+		summary_begin_time <= first_seen, first_seen <= last_seen, last_seen < summary_end_time, query_sample_text
+		from performance_schema.events_statements_summary_by_digest where digest_text = 'select * from summary_t where a > ?'`).Check(testkit.Rows(
+		"1 1 1 1 1 1 select * from summary_t where a > 1",
+	))
+
+	// The passwords are redacted in the sample text.
+	tk.MustExec("create user 'summary_u'@'%' identified by 'secret'")
+	tk.MustQuery(`select query_sample_text from performance_schema.events_statements_summary_by_digest
+		where digest_text = 'create user ? @ ? identified by ?'`).Check(testkit.Rows(
+		"create user 'summary_u'@'%' identified by '***'",
+	))
+}
+
 func exec(se tidb.Session, sql string, args ...interface{}) (ast.RecordSet, error) {
 	if len(args) == 0 {
 		rs, err := se.Execute(sql)
@@ -309,12 +309,15 @@ func (ps *perfSchema) registerStatements() {
 	ps.RegisterStatement("sql", "execute", (*ast.ExecuteStmt)(nil))
 	ps.RegisterStatement("sql", "explain", (*ast.ExplainStmt)(nil))
 	ps.RegisterStatement("sql", "grant", (*ast.GrantStmt)(nil))
+	ps.RegisterStatement("sql", "grant_roles", (*ast.GrantRoleStmt)(nil))
 	ps.RegisterStatement("sql", "insert", (*ast.InsertStmt)(nil))
 	ps.RegisterStatement("sql", "prepare", (*ast.PrepareStmt)(nil))
 	ps.RegisterStatement("sql", "rollback", (*ast.RollbackStmt)(nil))
 	ps.RegisterStatement("sql", "select", (*ast.SelectStmt)(nil))
 	ps.RegisterStatement("sql", "set", (*ast.SetStmt)(nil))
 	ps.RegisterStatement("sql", "set_password", (*ast.SetPwdStmt)(nil))
+	ps.RegisterStatement("sql", "set_role", (*ast.SetRoleStmt)(nil))
+	ps.RegisterStatement("sql", "alter_user_default_role", (*ast.SetDefaultRoleStmt)(nil))
 	ps.RegisterStatement("sql", "show", (*ast.ShowStmt)(nil))
 	ps.RegisterStatement("sql", "truncate", (*ast.TruncateTableStmt)(nil))
 	ps.RegisterStatement("sql", "union", (*ast.UnionStmt)(nil))
@@ -0,0 +1,215 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package perfschema
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/types"
+)
+
+const (
+	// The statements are aggregated in time windows of summaryWindow.
+	summaryWindow = 30 * time.Minute
+	// Maximum number of the distinct plan digests recorded for a statement digest.
+	summaryPlanDigestsMax = 16
+	// Maximum length of the sample SQL text of a statement digest.
+	summarySampleTextMax = 4096
+)
+
+// StatementExecInfo is the execution information of a finished statement, the statements are aggregated
+// by their digests in table events_statements_summary_by_digest.
+type StatementExecInfo struct {
+	SchemaName   string
+	SQL          string
+	PlanDigest   string
+	StartTime    time.Time
+	Latency      time.Duration
+	Failed       bool
+	Warnings     uint64
+	RowsAffected uint64
+	RowsSent     uint64
+	RowsExamined uint64
+}
+
+type stmtSummaryKey struct {
+	beginTime  int64
+	schemaName string
+	digest     string
+}
+
+// stmtSummary is a row of table events_statements_summary_by_digest.
+type stmtSummary struct {
+	key             stmtSummaryKey
+	handle          int64
+	digestText      string
+	sampleText      string
+	execCount       uint64
+	sumLatency      time.Duration
+	maxLatency      time.Duration
+	sumErrors       uint64
+	sumWarnings     uint64
+	sumRowsAffected uint64
+	sumRowsSent     uint64
+	sumRowsExamined uint64
+	firstSeen       time.Time
+	lastSeen        time.Time
+	planDigests     []string
+}
+
+// stmtSummaries holds the rows of table events_statements_summary_by_digest, the oldest row is evicted
+// when a row is added to the full table.
+type stmtSummaries struct {
+	sync.Mutex
+	elems map[stmtSummaryKey]*list.Element
+	// order is the list of the summaries in the order of creation.
+	order *list.List
+}
+
+func (ps *perfSchema) SummarizeStatement(info *StatementExecInfo) {
+	if !enablePerfSchema {
+		return
+	}
+	err := ps.updateStmtsSummary(info)
+	if err != nil {
+		log.Errorf("Unable to update events_statements_summary_by_digest table %v", errors.ErrorStack(err))
+	}
+}
+
+func (ps *perfSchema) updateStmtsSummary(info *StatementExecInfo) error {
+	tbl := ps.mTables[TableStmtsSummaryByDigest]
+	if tbl == nil {
+		return nil
+	}
+	normalized, digest := parser.NormalizeDigest(info.SQL)
+	key := stmtSummaryKey{
+		beginTime:  info.StartTime.Truncate(summaryWindow).UnixNano(),
+		schemaName: info.SchemaName,
+		digest:     digest,
+	}
+
+	s := &ps.stmtSummaries
+	s.Lock()
+	defer s.Unlock()
+	if s.elems == nil {
+		s.elems = make(map[stmtSummaryKey]*list.Element)
+		s.order = list.New()
+	}
+	if elem, ok := s.elems[key]; ok {
+		summary := elem.Value.(*stmtSummary)
+		summary.add(info)
+		err := tbl.UpdateRecord(nil, summary.handle, nil, summary.toRecord(), nil)
+		if !terror.ErrorEqual(err, table.ErrRowNotFound) {
+			return errors.Trace(err)
+		}
+		// The row is deleted by the user, start a new summary.
+		s.order.Remove(elem)
+		delete(s.elems, key)
+	}
+
+	for s.order.Len() >= summaryElemMax {
+		oldest := s.order.Remove(s.order.Front()).(*stmtSummary)
+		delete(s.elems, oldest.key)
+		err := tbl.RemoveRecord(nil, oldest.handle, nil)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	summary := &stmtSummary{
+		key:        key,
+		digestText: normalized,
+		sampleText: info.SQL,
+		firstSeen:  info.StartTime,
+	}
+	if len(summary.sampleText) > summarySampleTextMax {
+		summary.sampleText = summary.sampleText[:summarySampleTextMax]
+	}
+	summary.add(info)
+	handle, err := tbl.AddRecord(nil, summary.toRecord())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	summary.handle = handle
+	s.elems[key] = s.order.PushBack(summary)
+	return nil
+}
+
+func (s *stmtSummary) add(info *StatementExecInfo) {
+	s.execCount++
+	s.sumLatency += info.Latency
+	if info.Latency > s.maxLatency {
+		s.maxLatency = info.Latency
+	}
+	if info.Failed {
+		s.sumErrors++
+	}
+	s.sumWarnings += info.Warnings
+	s.sumRowsAffected += info.RowsAffected
+	s.sumRowsSent += info.RowsSent
+	s.sumRowsExamined += info.RowsExamined
+	if info.StartTime.After(s.lastSeen) {
+		s.lastSeen = info.StartTime
+	}
+	if info.PlanDigest == "" || len(s.planDigests) >= summaryPlanDigestsMax {
+		return
+	}
+	for _, planDigest := range s.planDigests {
+		if planDigest == info.PlanDigest {
+			return
+		}
+	}
+	s.planDigests = append(s.planDigests, info.PlanDigest)
+}
+
+func (s *stmtSummary) toRecord() []types.Datum {
+	beginTime := time.Unix(0, s.key.beginTime)
+	avgLatency := s.sumLatency / time.Duration(s.execCount)
+	return types.MakeDatums(
+		toDatetime(beginTime),                    // SUMMARY_BEGIN_TIME
+		toDatetime(beginTime.Add(summaryWindow)), // SUMMARY_END_TIME
+		s.key.schemaName,                         // SCHEMA_NAME
+		s.key.digest,                             // DIGEST
+		s.digestText,                             // DIGEST_TEXT
+		s.execCount,                              // COUNT_STAR
+		toPicoseconds(s.sumLatency),              // SUM_TIMER_WAIT
+		toPicoseconds(s.maxLatency),              // MAX_TIMER_WAIT
+		toPicoseconds(avgLatency),                // AVG_TIMER_WAIT
+		s.sumErrors,                              // SUM_ERRORS
+		s.sumWarnings,                            // SUM_WARNINGS
+		s.sumRowsAffected,                        // SUM_ROWS_AFFECTED
+		s.sumRowsSent,                            // SUM_ROWS_SENT
+		s.sumRowsExamined,                        // SUM_ROWS_EXAMINED
+		toDatetime(s.firstSeen),                  // FIRST_SEEN
+		toDatetime(s.lastSeen),                   // LAST_SEEN
+		strings.Join(s.planDigests, ","),         // PLAN_DIGESTS
+		s.sampleText,                             // QUERY_SAMPLE_TEXT
+	)
+}
+
+func toDatetime(t time.Time) types.Time {
+	return types.Time{Time: types.FromGoTime(t), Type: mysql.TypeDatetime}
+}
+
+func toPicoseconds(d time.Duration) uint64 {
+	return uint64(d.Nanoseconds()) * 1000
+}
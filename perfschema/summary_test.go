@@ -0,0 +1,98 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package perfschema
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+type testSummarySuite struct {
+}
+
+var _ = Suite(&testSummarySuite{})
+
+func (s *testSummarySuite) TestStmtsSummary(c *C) {
+	defer testleak.AfterTest(c)()
+	// The uninitialized perfSchema ignores the statements.
+	ps := &perfSchema{}
+	c.Assert(ps.updateStmtsSummary(&StatementExecInfo{SQL: "select 1"}), IsNil)
+
+	handle, err := NewPerfHandle()
+	c.Assert(err, IsNil)
+	ps = handle.(*perfSchema)
+	tbl := ps.mTables[TableStmtsSummaryByDigest]
+	now := time.Now()
+	info := &StatementExecInfo{
+		SchemaName:   "test",
+		SQL:          "select * from t where a = 1",
+		PlanDigest:   "p1",
+		StartTime:    now,
+		Latency:      time.Millisecond,
+		RowsSent:     1,
+		RowsExamined: 2,
+	}
+	c.Assert(ps.updateStmtsSummary(info), IsNil)
+	info.SQL = "select * from t where a = 2"
+	info.PlanDigest = "p2"
+	info.Latency = 3 * time.Millisecond
+	info.Failed = true
+	c.Assert(ps.updateStmtsSummary(info), IsNil)
+	info.PlanDigest = "p1"
+	c.Assert(ps.updateStmtsSummary(info), IsNil)
+	c.Assert(ps.stmtSummaries.elems, HasLen, 1)
+
+	summary := ps.stmtSummaries.order.Front().Value.(*stmtSummary)
+	row, err := tbl.Row(nil, summary.handle)
+	c.Assert(err, IsNil)
+	c.Assert(row[2].GetString(), Equals, "test")
+	c.Assert(row[4].GetString(), Equals, "select * from t where a = ?")
+	c.Assert(row[5].GetUint64(), Equals, uint64(3))
+	c.Assert(row[6].GetUint64(), Equals, uint64(7*time.Millisecond)*1000)
+	c.Assert(row[7].GetUint64(), Equals, uint64(3*time.Millisecond)*1000)
+	c.Assert(row[9].GetUint64(), Equals, uint64(2))
+	c.Assert(row[12].GetUint64(), Equals, uint64(3))
+	c.Assert(row[13].GetUint64(), Equals, uint64(6))
+	c.Assert(row[16].GetString(), Equals, "p1,p2")
+	c.Assert(row[17].GetString(), Equals, "select * from t where a = 1")
+
+	// The statements of another window are aggregated in another row.
+	info.StartTime = now.Add(summaryWindow)
+	c.Assert(ps.updateStmtsSummary(info), IsNil)
+	c.Assert(ps.stmtSummaries.elems, HasLen, 2)
+
+	// A new summary is started if the row is deleted.
+	c.Assert(tbl.RemoveRecord(nil, summary.handle, nil), IsNil)
+	info.StartTime = now
+	c.Assert(ps.updateStmtsSummary(info), IsNil)
+	c.Assert(ps.stmtSummaries.elems, HasLen, 2)
+	summary = ps.stmtSummaries.order.Back().Value.(*stmtSummary)
+	c.Assert(summary.execCount, Equals, uint64(1))
+
+	// The oldest rows are evicted when the table is full.
+	oldest := ps.stmtSummaries.order.Front().Value.(*stmtSummary)
+	for i := 0; i < summaryElemMax; i++ {
+		info.SQL = fmt.Sprintf("select * from t%d", i)
+		c.Assert(ps.updateStmtsSummary(info), IsNil)
+	}
+	c.Assert(ps.stmtSummaries.elems, HasLen, summaryElemMax)
+	c.Assert(ps.stmtSummaries.order.Len(), Equals, summaryElemMax)
+	_, err = tbl.Row(nil, oldest.handle)
+	c.Assert(terror.ErrorEqual(err, table.ErrRowNotFound), IsTrue)
+}
@@ -0,0 +1,415 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"math"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/statistics"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/hack"
+	"github.com/pingcap/tidb/util/kvcache"
+	"github.com/pingcap/tidb/util/types"
+)
+
+var (
+	// PreparedPlanCacheEnabled indicates whether to cache the plans of prepared statements.
+	PreparedPlanCacheEnabled = false
+	// PreparedPlanCacheCapacity is the number of plans every session can cache.
+	PreparedPlanCacheCapacity uint = 100
+)
+
+// planCacheStatsChangeRatio is the ratio of row count changing of a table, above which the cached
+// plans reading the table are considered stale and will be built again.
+const planCacheStatsChangeRatio = 0.3
+
+// Cacheable checks whether the plan of a prepared statement can be cached.
+// Only SELECT statements whose plans don't depend on the value of the parameters, user variables
+// or subqueries are cacheable.
+func Cacheable(node ast.Node) bool {
+	if _, ok := node.(*ast.SelectStmt); !ok {
+		return false
+	}
+	checker := cacheableChecker{cacheable: true}
+	node.Accept(&checker)
+	return checker.cacheable
+}
+
+// cacheableChecker checks whether a query's plan can be cached. Queries that have subqueries,
+// variables, parameter markers in the LIMIT clause or AS OF TIMESTAMP will not be cached currently.
+type cacheableChecker struct {
+	cacheable bool
+}
+
+// Enter implements Visitor interface.
+func (checker *cacheableChecker) Enter(in ast.Node) (out ast.Node, skipChildren bool) {
+	switch node := in.(type) {
+	case *ast.VariableExpr, *ast.ExistsSubqueryExpr, *ast.SubqueryExpr, *ast.CompareSubqueryExpr, *ast.AsOfClause:
+		checker.cacheable = false
+		return in, true
+	case *ast.Limit:
+		if _, ok := node.Count.(*ast.ParamMarkerExpr); ok {
+			checker.cacheable = false
+			return in, true
+		}
+		if _, ok := node.Offset.(*ast.ParamMarkerExpr); ok {
+			checker.cacheable = false
+			return in, true
+		}
+	}
+	return in, false
+}
+
+// Leave implements Visitor interface.
+func (checker *cacheableChecker) Leave(in ast.Node) (out ast.Node, ok bool) {
+	return in, checker.cacheable
+}
+
+// pstmtPlanCacheKey is the key of a cached plan of a prepared statement.
+// The session variables that affect how a plan is built are part of the key.
+type pstmtPlanCacheKey struct {
+	database         string
+	connID           uint64
+	pstmtID          uint32
+	snapshot         uint64
+	schemaVersion    int64
+	sqlMode          int64
+	timezone         string
+	allowAggPushDown bool
+
+	hash []byte
+}
+
+// Hash implements Key interface.
+func (key *pstmtPlanCacheKey) Hash() []byte {
+	if key.hash == nil {
+		key.hash = codec.EncodeCompactBytes(key.hash, hack.Slice(key.database))
+		key.hash = codec.EncodeUint(key.hash, key.connID)
+		key.hash = codec.EncodeUint(key.hash, uint64(key.pstmtID))
+		key.hash = codec.EncodeUint(key.hash, key.snapshot)
+		key.hash = codec.EncodeInt(key.hash, key.schemaVersion)
+		key.hash = codec.EncodeInt(key.hash, key.sqlMode)
+		key.hash = codec.EncodeCompactBytes(key.hash, hack.Slice(key.timezone))
+		if key.allowAggPushDown {
+			key.hash = codec.EncodeInt(key.hash, 1)
+		} else {
+			key.hash = codec.EncodeInt(key.hash, 0)
+		}
+	}
+	return key.hash
+}
+
+// NewPSTMTPlanCacheKey creates a new pstmtPlanCacheKey object.
+func NewPSTMTPlanCacheKey(sessionVars *variable.SessionVars, pstmtID uint32, schemaVersion int64) kvcache.Key {
+	timezone := ""
+	if sessionVars.TimeZone != nil {
+		timezone = sessionVars.TimeZone.String()
+	}
+	return &pstmtPlanCacheKey{
+		database:         sessionVars.CurrentDB,
+		connID:           sessionVars.ConnectionID,
+		pstmtID:          pstmtID,
+		snapshot:         sessionVars.SnapshotTS,
+		schemaVersion:    schemaVersion,
+		sqlMode:          int64(sessionVars.SQLMode),
+		timezone:         timezone,
+		allowAggPushDown: sessionVars.AllowAggPushDown,
+	}
+}
+
+// DeletePSTMTPlanCache deletes all the cached plans of the prepared statement, which may be built with
+// different databases, schema versions or session variables.
+func DeletePSTMTPlanCache(cache *kvcache.SimpleLRUCache, pstmtID uint32) {
+	cache.DeleteIf(func(key kvcache.Key, _ kvcache.Value) bool {
+		k, ok := key.(*pstmtPlanCacheKey)
+		return ok && k.pstmtID == pstmtID
+	})
+}
+
+// PSTMTPlanCacheValue stores the cached plan of a prepared statement.
+type PSTMTPlanCacheValue struct {
+	Plan Plan
+
+	node   ast.Node
+	params []*ast.ParamMarkerExpr
+	// paramKinds are the kinds of the parameters when the plan was built. The
+	// plan can't be reused if the parameters are of different kinds now.
+	paramKinds []byte
+	visitInfo  []visitInfo
+	// maskingInfo are the masking policies of the tables when the plan was built.
+	maskingInfo []maskingInfo
+	// bindSQL is the bind SQL of the binding matching the statement when the plan was built, it's empty
+	// if there is no binding.
+	bindSQL string
+	// tableCounts are the row counts of the tables in statistics when the plan was built.
+	tableCounts map[int64]int64
+}
+
+// OptimizePrepared optimizes a prepared statement as Optimize does. If the
+// returned plan can be cached, the returned PSTMTPlanCacheValue is not nil.
+func OptimizePrepared(ctx context.Context, node ast.Node, params []*ast.ParamMarkerExpr, is infoschema.InfoSchema) (Plan, *PSTMTPlanCacheValue, error) {
+	sc := ctx.GetSessionVars().StmtCtx
+	sc.UseCache = true
+	defer func() {
+		sc.UseCache = false
+	}()
+	// The binding is got before the plan is built, so the plan is rebuilt if the binding changes meanwhile.
+	bindSQL := bindSQLOf(ctx, node)
+	p, visitInfo, maskingInfo, err := optimize(ctx, node, is)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	if !isCacheablePlan(p) {
+		return p, nil, nil
+	}
+	value := &PSTMTPlanCacheValue{
+		Plan:        p,
+		node:        node,
+		params:      params,
+		paramKinds:  paramKinds(params),
+		visitInfo:   visitInfo,
+		maskingInfo: maskingInfo,
+		bindSQL:     bindSQL,
+		tableCounts: make(map[int64]int64),
+	}
+	if handle := statsHandle(ctx); handle != nil {
+		for _, id := range collectTableIDs(p, nil) {
+			value.tableCounts[id] = handle.GetTableStats(id).Count
+		}
+	}
+	return p, value, nil
+}
+
+// Reusable checks whether the cached plan is still valid for the current parameters,
+// privileges, bindings and statistics.
+func (v *PSTMTPlanCacheValue) Reusable(ctx context.Context) bool {
+	if string(paramKinds(v.params)) != string(v.paramKinds) {
+		return false
+	}
+	if bindSQLOf(ctx, v.node) != v.bindSQL {
+		return false
+	}
+	if pm := privilege.GetPrivilegeManager(ctx); pm != nil {
+		activeRoles := ctx.GetSessionVars().ActiveRoles
+		if !checkPrivilege(pm, activeRoles, v.visitInfo) || !checkMasking(pm, activeRoles, v.maskingInfo) {
+			return false
+		}
+	}
+	if handle := statsHandle(ctx); handle != nil {
+		for id, count := range v.tableCounts {
+			newCount := handle.GetTableStats(id).Count
+			if math.Abs(float64(newCount-count)) > planCacheStatsChangeRatio*math.Max(float64(count), 1) {
+				log.Debugf("[plan cache] row count of table %d changes from %d to %d", id, count, newCount)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Rebuild refreshes the cached plan with the current parameters, the constants
+// from parameter markers get the new values, then the ranges and the pushed
+// down expressions are built again.
+func (v *PSTMTPlanCacheValue) Rebuild(ctx context.Context) (Plan, error) {
+	sc := ctx.GetSessionVars().StmtCtx
+	// The types of the parameters are used by the constants in the plan.
+	err := InferType(sc, v.node)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = rebuildPlan(sc, v.Plan)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return v.Plan, nil
+}
+
+// checkMasking checks whether the masking policies of the tables are the same as the ones the plan is built with.
+func checkMasking(pm privilege.Manager, activeRoles []string, ms []maskingInfo) bool {
+	for _, m := range ms {
+		if maskedColumns(pm.MaskingPolicies(activeRoles, m.db, m.table)) != m.maskedColumns {
+			return false
+		}
+	}
+	return true
+}
+
+// bindSQLOf returns the bind SQL of the binding matching the statement, it's empty if there is no binding.
+func bindSQLOf(ctx context.Context, node ast.Node) string {
+	sel, ok := node.(*ast.SelectStmt)
+	if !ok {
+		return ""
+	}
+	if record := matchBinding(ctx, sel); record != nil {
+		return record.BindSQL
+	}
+	return ""
+}
+
+func paramKinds(params []*ast.ParamMarkerExpr) []byte {
+	kinds := make([]byte, 0, len(params))
+	for _, param := range params {
+		kinds = append(kinds, param.Kind())
+	}
+	return kinds
+}
+
+func statsHandle(ctx context.Context) *statistics.Handle {
+	do := sessionctx.GetDomain(ctx)
+	if do == nil {
+		return nil
+	}
+	return do.StatsHandle()
+}
+
+// isCacheablePlan checks whether all the operators in the physical plan can be rebuilt
+// with new parameters.
+func isCacheablePlan(p Plan) bool {
+	switch x := p.(type) {
+	case *Projection, *Limit, *Sort, *PhysicalAggregation, *TableDual, *PhysicalTableScan, *PhysicalIndexScan:
+	case *Selection:
+		if x.ScanController {
+			return false
+		}
+	default:
+		return false
+	}
+	for _, child := range p.Children() {
+		if !isCacheablePlan(child) {
+			return false
+		}
+	}
+	return true
+}
+
+func collectTableIDs(p Plan, ids []int64) []int64 {
+	switch x := p.(type) {
+	case *PhysicalTableScan:
+		ids = append(ids, x.Table.ID)
+	case *PhysicalIndexScan:
+		ids = append(ids, x.Table.ID)
+	}
+	for _, child := range p.Children() {
+		ids = collectTableIDs(child, ids)
+	}
+	return ids
+}
+
+func rebuildPlan(sc *variable.StatementContext, p Plan) error {
+	switch x := p.(type) {
+	case *Projection:
+		refreshParams(x.Exprs...)
+	case *Selection:
+		refreshParams(x.Conditions...)
+	case *Sort:
+		for _, item := range x.ByItems {
+			refreshParams(item.Expr)
+		}
+	case *PhysicalAggregation:
+		refreshParams(x.GroupByItems...)
+		for _, af := range x.AggFuncs {
+			refreshParams(af.GetArgs()...)
+			af.Clear()
+		}
+	case *PhysicalTableScan:
+		refreshParams(x.AccessCondition...)
+		if len(x.AccessCondition) > 0 {
+			ranges, err := BuildTableRange(x.AccessCondition, sc)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			x.Ranges = ranges
+		}
+		err := x.rebuildPushedDownExprs(sc)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	case *PhysicalIndexScan:
+		refreshParams(x.AccessCondition...)
+		if len(x.AccessCondition) > 0 {
+			err := BuildIndexRange(sc, x)
+			if err != nil && !terror.ErrorEqual(err, types.ErrTruncated) {
+				return errors.Trace(err)
+			}
+		}
+		err := x.rebuildPushedDownExprs(sc)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for _, child := range p.Children() {
+		err := rebuildPlan(sc, child)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// rebuildPushedDownExprs converts the expressions pushed down to the coprocessor to pb again.
+func (p *physicalTableSource) rebuildPushedDownExprs(sc *variable.StatementContext) error {
+	refreshParams(p.indexFilterConditions...)
+	refreshParams(p.tableFilterConditions...)
+	refreshParams(p.gbyItems...)
+	if len(p.indexFilterConditions) > 0 {
+		pbExpr, _, remained := ExpressionsToPB(sc, p.indexFilterConditions, p.client)
+		if len(remained) > 0 {
+			return errors.New("can't push down the index filter conditions of the cached plan")
+		}
+		p.IndexConditionPBExpr = pbExpr
+	}
+	if len(p.tableFilterConditions) > 0 {
+		pbExpr, _, remained := ExpressionsToPB(sc, p.tableFilterConditions, p.client)
+		if len(remained) > 0 {
+			return errors.New("can't push down the table filter conditions of the cached plan")
+		}
+		p.TableConditionPBExpr = pbExpr
+	}
+	for i, af := range p.aggFuncs {
+		refreshParams(af.GetArgs()...)
+		p.AggFuncsPB[i] = aggFuncToPBExpr(sc, p.client, af)
+	}
+	for i, item := range p.gbyItems {
+		p.GbyItemsPB[i] = groupByItemToPB(sc, p.client, item)
+	}
+	for i, item := range p.sortItems {
+		refreshParams(item.Expr)
+		p.SortItemsPB[i] = sortByItemToPB(sc, p.client, item.Expr, item.Desc)
+	}
+	return nil
+}
+
+// refreshParams sets the values of the constants from parameter markers to the current values of the parameters.
+func refreshParams(exprs ...expression.Expression) {
+	for _, expr := range exprs {
+		switch x := expr.(type) {
+		case *expression.Constant:
+			if x.DeferredParam != nil {
+				x.Value = x.DeferredParam.Datum
+			}
+		case *expression.ScalarFunction:
+			refreshParams(x.GetArgs()...)
+		}
+	}
+}
@@ -114,6 +114,26 @@ func (s *testPlanSuite) TestDAGPlanBuilderSimpleCase(c *C) {
 			sql:  "select c, b from t where c = 1 and e = 1 and b = 1 limit 1",
 			best: "IndexLookUp(Index(t.c_d_e)[[1,1]]->Sel([eq(test.t.e, 1)]), Table(t)->Sel([eq(test.t.b, 1)])->Limit)->Limit->Projection",
 		},
+		// Test index merge union.
+		{
+			sql:  "select * from t where c = 1 or f = 2",
+			best: "IndexMerge(union{Index(t.c_d_e)[[1,1]], Index(t.f)[[2,2]]}, Table(t)->Sel([or(eq(test.t.c, 1), eq(test.t.f, 2))]))",
+		},
+		// Test no index merge when a DNF item can't use any index.
+		{
+			sql:  "select * from t where c = 1 or b = 2",
+			best: "TableReader(Table(t)->Sel([or(eq(test.t.c, 1), eq(test.t.b, 2))]))",
+		},
+		// Test index merge intersection.
+		{
+			sql:  "select * from t where c = 1 and f = 2",
+			best: "IndexMerge(intersection{Index(t.c_d_e)[[1,1]], Index(t.f)[[2,2]]}, Table(t)->Sel([eq(test.t.c, 1) eq(test.t.f, 2)]))",
+		},
+		// Test Limit isn't pushed down to index merge.
+		{
+			sql:  "select * from t where c = 1 or f = 2 limit 1",
+			best: "IndexMerge(union{Index(t.c_d_e)[[1,1]], Index(t.f)[[2,2]]}, Table(t)->Sel([or(eq(test.t.c, 1), eq(test.t.f, 2))]))->Limit",
+		},
 	}
 	for _, ca := range cases {
 		comment := Commentf("for %s", ca.sql)
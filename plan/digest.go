@@ -0,0 +1,81 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// NormalizePlan returns the normalized form of a plan tree, plans that only differ in the constants of
+// conditions and ranges have the same normalized form. Every operator is written as its type, followed by
+// the scanned table or index, and its children in braces.
+func NormalizePlan(p Plan) string {
+	var buf bytes.Buffer
+	normalizePlan(p, &buf)
+	return buf.String()
+}
+
+// Digest returns the hex encoded SHA-256 of the normalized plan.
+func Digest(p Plan) string {
+	sum := sha256.Sum256([]byte(NormalizePlan(p)))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizePlan(p Plan, buf *bytes.Buffer) {
+	if p == nil {
+		return
+	}
+	// The ID is the plan type followed by "_" and a number which is different in every build.
+	id := p.ID()
+	if idx := strings.LastIndex(id, "_"); idx > 0 {
+		id = id[:idx]
+	}
+	buf.WriteString(id)
+	switch x := p.(type) {
+	case *PhysicalTableScan:
+		buf.WriteString("(" + x.DBName.L + "." + x.Table.Name.L + ")")
+	case *PhysicalIndexScan:
+		buf.WriteString("(" + x.DBName.L + "." + x.Table.Name.L + "." + x.Index.Name.L + ")")
+	}
+	children := p.Children()
+	// The plans pushed down to the coprocessor are not the children of the readers.
+	switch x := p.(type) {
+	case *PhysicalTableReader:
+		children = []Plan{x.copPlan}
+	case *PhysicalIndexReader:
+		children = []Plan{x.copPlan}
+	case *PhysicalIndexLookUpReader:
+		children = []Plan{x.indexPlan, x.tablePlan}
+	case *PhysicalIndexMergeReader:
+		children = make([]Plan, 0, len(x.PartialPlans)+1)
+		for _, partial := range x.PartialPlans {
+			children = append(children, partial)
+		}
+		children = append(children, x.TablePlan)
+	}
+	if len(children) == 0 {
+		return
+	}
+	buf.WriteByte('{')
+	for i, child := range children {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		normalizePlan(child, buf)
+	}
+	buf.WriteByte('}')
+}
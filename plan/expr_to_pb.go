@@ -176,7 +176,7 @@ func (pc pbConverter) likeToPBExpr(expr *expression.ScalarFunction) *tipb.Expr {
 		return nil
 	}
 	pattern, ok := expr.GetArgs()[1].(*expression.Constant)
-	if !ok || pattern.Value.Kind() != types.KindString {
+	if !ok || pattern.DeferredParam != nil || pattern.Value.Kind() != types.KindString {
 		return nil
 	}
 	for i, b := range pattern.Value.GetString() {
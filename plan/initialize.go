@@ -80,6 +80,8 @@ const (
 	TypeTableReader = "TableReader"
 	// TypeIndexReader is the type of IndexReader.
 	TypeIndexReader = "IndexReader"
+	// TypeIndexMerge is the type of IndexMerge.
+	TypeIndexMerge = "IndexMerge"
 )
 
 func (p LogicalAggregation) init(allocator *idAllocator, ctx context.Context) *LogicalAggregation {
@@ -289,3 +291,9 @@ func (p PhysicalIndexReader) init(allocator *idAllocator, ctx context.Context) *
 	p.basePhysicalPlan = newBasePhysicalPlan(p.basePlan)
 	return &p
 }
+
+func (p PhysicalIndexMergeReader) init(allocator *idAllocator, ctx context.Context) *PhysicalIndexMergeReader {
+	p.basePlan = newBasePlan(TypeIndexMerge, allocator, ctx, &p)
+	p.basePhysicalPlan = newBasePhysicalPlan(p.basePlan)
+	return &p
+}
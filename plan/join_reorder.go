@@ -14,6 +14,7 @@
 package plan
 
 import (
+	"math"
 	"sort"
 
 	"github.com/ngaut/log"
@@ -22,23 +23,46 @@ import (
 	"github.com/pingcap/tidb/expression"
 )
 
-// tryToGetJoinGroup tries to fetch a whole join group, which all joins is cartesian join.
-func tryToGetJoinGroup(j *LogicalJoin) ([]LogicalPlan, bool) {
-	// Ignore reorder if:
-	// 1. already reordered
-	// 2. not inner join
-	// 3. forced merge join
-	// 4. forced index nested loop join
-	if j.reordered || !j.cartesianJoin || j.preferMergeJoin || j.preferINLJ > 0 {
-		return nil, false
+// joinReorderDPThreshold is the max size of a join group which is reordered by dynamic programming.
+// The larger groups are reordered by a greedy algorithm.
+const joinReorderDPThreshold = 10
+
+// canBeReordered checks whether the join can take part in join reorder.
+// Ignore reorder if:
+// 1. already reordered
+// 2. not inner join
+// 3. forced merge join
+// 4. forced index nested loop join
+func (p *LogicalJoin) canBeReordered() bool {
+	return !p.reordered && p.JoinType == InnerJoin && !p.anti && !p.preferMergeJoin && p.preferINLJ == 0
+}
+
+// tryToGetJoinGroup tries to fetch a whole inner join group in the left deep tree rooted at j, and returns the
+// leaves of the group in their original order together with the joins of the group. The joins that can't be
+// reordered, like outer joins and hinted joins, are regarded as leaves, so nothing is moved across them.
+func tryToGetJoinGroup(j *LogicalJoin) ([]LogicalPlan, []*LogicalJoin, bool) {
+	if !j.canBeReordered() {
+		return nil, nil, false
 	}
 	lChild := j.children[0].(LogicalPlan)
 	rChild := j.children[1].(LogicalPlan)
-	if nj, ok := lChild.(*LogicalJoin); ok {
-		plans, valid := tryToGetJoinGroup(nj)
-		return append(plans, rChild), valid
+	if nj, ok := lChild.(*LogicalJoin); ok && nj.canBeReordered() {
+		plans, joins, _ := tryToGetJoinGroup(nj)
+		return append(plans, rChild), append(joins, j), true
+	}
+	return []LogicalPlan{lChild, rChild}, []*LogicalJoin{j}, true
+}
+
+// joinGroupConditions returns all the conditions of the joins in a join group.
+func joinGroupConditions(joins []*LogicalJoin) []expression.Expression {
+	var conds []expression.Expression
+	for _, join := range joins {
+		conds = append(conds, expression.ScalarFuncs2Exprs(join.EqualConditions)...)
+		conds = append(conds, join.LeftConditions...)
+		conds = append(conds, join.RightConditions...)
+		conds = append(conds, join.OtherConditions...)
 	}
-	return []LogicalPlan{lChild, rChild}, true
+	return conds
 }
 
 func findColumnIndexByGroup(groups []LogicalPlan, col *expression.Column) int {
@@ -51,138 +75,335 @@ func findColumnIndexByGroup(groups []LogicalPlan, col *expression.Column) int {
 	return -1
 }
 
-type joinReOrderSolver struct {
-	graph      []edgeList
-	group      []LogicalPlan
-	visited    []bool
-	resultJoin LogicalPlan
-	groupRank  []*rankInfo
-	allocator  *idAllocator
-	ctx        context.Context
+// estimateRowCount roughly estimates the row count of a join group member, whose predicates haven't been
+// pushed down yet.
+func estimateRowCount(p LogicalPlan) float64 {
+	var childCount float64
+	if len(p.Children()) > 0 {
+		childCount = estimateRowCount(p.Children()[0].(LogicalPlan))
+	}
+	switch x := p.(type) {
+	case *DataSource:
+		return float64(x.statisticTable.Count)
+	case *TableDual:
+		return float64(x.RowCount)
+	case *Limit:
+		return math.Min(childCount, float64(x.Count))
+	case *Selection:
+		return childCount * selectionFactor
+	case *LogicalAggregation:
+		return childCount * aggFactor
+	case *LogicalJoin:
+		return estimateJoinCount(childCount, estimateRowCount(x.children[1].(LogicalPlan)))
+	case *Union:
+		count := childCount
+		for _, child := range x.children[1:] {
+			count += estimateRowCount(child.(LogicalPlan))
+		}
+		return count
+	}
+	return childCount
 }
 
-type edgeList []*rankInfo
+// joinNode is a node of the join tree chosen by the join reorder algorithms.
+type joinNode struct {
+	// members are the ids of the group members joined by this node, in ascending order.
+	members     []int
+	cost        float64
+	left, right *joinNode
+}
 
-func (l edgeList) Len() int {
-	return len(l)
+type joinReOrderSolver struct {
+	group []LogicalPlan
+	// rowCounts are the estimated row counts of the group members after applying the conditions on them.
+	rowCounts []float64
+	// selectivity[i][j] is the selectivity of the equal conditions between the i-th and j-th member,
+	// it is zero if they are not connected by any equal condition.
+	selectivity [][]float64
+	resultJoin  LogicalPlan
+	allocator   *idAllocator
+	ctx         context.Context
 }
 
-func (l edgeList) Less(i, j int) bool {
-	return l[i].rate < l[j].rate
+// ndv estimates the number of distinct values of col, which belongs to the id-th member of the group.
+func (e *joinReOrderSolver) ndv(id int, col *expression.Column) float64 {
+	ndv := e.rowCounts[id]
+	if ds, ok := e.group[id].(*DataSource); ok && !ds.statisticTable.Pseudo {
+		if idx := ds.Schema().ColumnIndex(col); idx != -1 {
+			if c, ok := ds.statisticTable.Columns[ds.Columns[idx].ID]; ok && c.NDV > 0 {
+				ndv = math.Min(ndv, float64(c.NDV))
+			}
+		}
+	}
+	return math.Max(ndv, 1)
 }
 
-func (l edgeList) Swap(i, j int) {
-	l[i], l[j] = l[j], l[i]
+// buildGraph extracts the equal conditions between the group members as the edges of a graph, and
+// estimates the row count of every member from the statistics and the conditions on it.
+func (e *joinReOrderSolver) buildGraph(conds []expression.Expression) {
+	e.rowCounts = make([]float64, len(e.group))
+	e.selectivity = make([][]float64, len(e.group))
+	for i, p := range e.group {
+		e.rowCounts[i] = estimateRowCount(p)
+		e.selectivity[i] = make([]float64, len(e.group))
+	}
+	type edge struct {
+		lID, rID   int
+		lCol, rCol *expression.Column
+	}
+	var edges []edge
+	for _, cond := range conds {
+		f, ok := cond.(*expression.ScalarFunction)
+		if !ok {
+			continue
+		}
+		if f.FuncName.L == ast.EQ {
+			lCol, lok := f.GetArgs()[0].(*expression.Column)
+			rCol, rok := f.GetArgs()[1].(*expression.Column)
+			if lok && rok {
+				lID := findColumnIndexByGroup(e.group, lCol)
+				rID := findColumnIndexByGroup(e.group, rCol)
+				if lID != -1 && rID != -1 && lID != rID {
+					edges = append(edges, edge{lID: lID, rID: rID, lCol: lCol, rCol: rCol})
+					continue
+				}
+			}
+		}
+		id := -1
+		rate := 1.0
+		cols := expression.ExtractColumns(f)
+		for _, col := range cols {
+			idx := findColumnIndexByGroup(e.group, col)
+			if idx == -1 {
+				id = -1
+				break
+			}
+			if id == -1 {
+				switch f.FuncName.L {
+				case ast.EQ:
+					rate *= 0.1
+				case ast.LT, ast.LE, ast.GE, ast.GT:
+					rate *= 0.3
+				// TODO: Estimate it more precisely in future.
+				default:
+					rate *= 0.9
+				}
+				id = idx
+			} else if id != idx {
+				id = -1
+				break
+			}
+		}
+		if id != -1 {
+			e.rowCounts[id] *= rate
+		}
+	}
+	for i := range e.rowCounts {
+		e.rowCounts[i] = math.Max(e.rowCounts[i], 1)
+	}
+	// The row count of joining two members by an equal condition is estimated as
+	// rowCount(l) * rowCount(r) / max(ndv(l.col), ndv(r.col)).
+	for _, edge := range edges {
+		rate := 1 / math.Max(e.ndv(edge.lID, edge.lCol), e.ndv(edge.rID, edge.rCol))
+		for _, pair := range [][2]int{{edge.lID, edge.rID}, {edge.rID, edge.lID}} {
+			if e.selectivity[pair[0]][pair[1]] == 0 {
+				e.selectivity[pair[0]][pair[1]] = rate
+			} else {
+				e.selectivity[pair[0]][pair[1]] *= rate
+			}
+		}
+	}
 }
 
-type rankInfo struct {
-	nodeID int
-	rate   float64
+// rowCount estimates the row count of joining the members. The members are in ascending order, so the
+// estimation doesn't depend on the shape of the join tree.
+func (e *joinReOrderSolver) rowCount(members []int) float64 {
+	count := 1.0
+	for i, u := range members {
+		count *= e.rowCounts[u]
+		for _, v := range members[:i] {
+			if rate := e.selectivity[u][v]; rate > 0 {
+				count *= rate
+			}
+		}
+	}
+	return count
 }
 
-func (e *joinReOrderSolver) Less(i, j int) bool {
-	return e.groupRank[i].rate < e.groupRank[j].rate
+func (e *joinReOrderSolver) connected(l, r []int) bool {
+	for _, u := range l {
+		for _, v := range r {
+			if e.selectivity[u][v] > 0 {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-func (e *joinReOrderSolver) Swap(i, j int) {
-	e.groupRank[i], e.groupRank[j] = e.groupRank[j], e.groupRank[i]
+func (e *joinReOrderSolver) newLeaf(id int) *joinNode {
+	return &joinNode{members: []int{id}}
 }
 
-func (e *joinReOrderSolver) Len() int {
-	return len(e.groupRank)
+// newJoinNode joins two nodes, the cost of a join tree is the total row count of its joins.
+func (e *joinReOrderSolver) newJoinNode(l, r *joinNode) *joinNode {
+	members := make([]int, 0, len(l.members)+len(r.members))
+	members = append(members, l.members...)
+	members = append(members, r.members...)
+	sort.Ints(members)
+	return &joinNode{
+		members: members,
+		cost:    l.cost + r.cost + e.rowCount(members),
+		left:    l,
+		right:   r,
+	}
 }
 
-// reorderJoin implements a simple join reorder algorithm. It will extract all the equal conditions and compose them to a graph.
-// Then walk through the graph and pick the nodes connected by some edges to compose a join tree.
-// We will pick the node with least result set as early as possible.
-func (e *joinReOrderSolver) reorderJoin(group []LogicalPlan, conds []expression.Expression) {
-	e.graph = make([]edgeList, len(group))
+// reorderJoin reorders the inner join group by the estimated row counts of the joins. It builds a graph whose
+// edges are the equal conditions between the group members, then finds the cheapest join tree for each connected
+// part of the graph, by dynamic programming over the subsets of members for a small group, and greedily joining
+// the pair with the least result otherwise. The parts are joined by cartesian joins at last. The original order
+// is kept unless the new one is cheaper, and it returns whether the order is changed.
+func (e *joinReOrderSolver) reorderJoin(group []LogicalPlan, conds []expression.Expression) bool {
 	e.group = group
-	e.visited = make([]bool, len(group))
-	e.resultJoin = nil
-	e.groupRank = make([]*rankInfo, len(group))
-	for i := 0; i < len(e.groupRank); i++ {
-		e.groupRank[i] = &rankInfo{
-			nodeID: i,
-			rate:   1.0,
+	e.buildGraph(conds)
+
+	var parts []*joinNode
+	if len(group) <= joinReorderDPThreshold {
+		parts = e.solveByDP()
+	} else {
+		parts = e.solveByGreedy()
+	}
+	// Join the small parts first.
+	sort.Stable(byRowCount{parts: parts, solver: e})
+	for len(parts) > 1 {
+		newParts := make([]*joinNode, 0, len(parts))
+		for i := 0; i < len(parts); i += 2 {
+			if i+1 == len(parts) {
+				newParts = append(newParts, parts[i])
+				break
+			}
+			newParts = append(newParts, e.newJoinNode(parts[i], parts[i+1]))
 		}
+		parts = newParts
 	}
-	for _, cond := range conds {
-		if f, ok := cond.(*expression.ScalarFunction); ok {
-			if f.FuncName.L == ast.EQ {
-				lCol, lok := f.GetArgs()[0].(*expression.Column)
-				rCol, rok := f.GetArgs()[1].(*expression.Column)
-				if lok && rok {
-					lID := findColumnIndexByGroup(group, lCol)
-					rID := findColumnIndexByGroup(group, rCol)
-					if lID != rID {
-						e.graph[lID] = append(e.graph[lID], &rankInfo{nodeID: rID})
-						e.graph[rID] = append(e.graph[rID], &rankInfo{nodeID: lID})
-						continue
-					}
-				}
+
+	origin := e.newLeaf(0)
+	for i := 1; i < len(group); i++ {
+		origin = e.newJoinNode(origin, e.newLeaf(i))
+	}
+	if !costLess(parts[0].cost, origin.cost) {
+		return false
+	}
+	e.resultJoin = e.buildJoinTree(parts[0])
+	return true
+}
+
+// costLess compares two costs, ignoring the tiny differences caused by float computation.
+func costLess(a, b float64) bool {
+	return a < b*(1-1e-9)
+}
+
+type byRowCount struct {
+	parts  []*joinNode
+	solver *joinReOrderSolver
+}
+
+func (s byRowCount) Len() int      { return len(s.parts) }
+func (s byRowCount) Swap(i, j int) { s.parts[i], s.parts[j] = s.parts[j], s.parts[i] }
+func (s byRowCount) Less(i, j int) bool {
+	return s.solver.rowCount(s.parts[i].members) < s.solver.rowCount(s.parts[j].members)
+}
+
+// solveByDP finds the cheapest join tree of every connected part of the group by dynamic programming.
+func (e *joinReOrderSolver) solveByDP() []*joinNode {
+	n := uint(len(e.group))
+	best := make([]*joinNode, 1<<n)
+	membersOf := func(set uint) []int {
+		var members []int
+		for i := uint(0); i < n; i++ {
+			if set&(1<<i) != 0 {
+				members = append(members, int(i))
 			}
-			id := -1
-			rate := 1.0
-			cols := expression.ExtractColumns(f)
-			for _, col := range cols {
-				idx := findColumnIndexByGroup(group, col)
-				if id == -1 {
-					switch f.FuncName.L {
-					case ast.EQ:
-						rate *= 0.1
-					case ast.LT, ast.LE, ast.GE, ast.GT:
-						rate *= 0.3
-					// TODO: Estimate it more precisely in future.
-					default:
-						rate *= 0.9
-					}
-					id = idx
-				} else {
-					id = -1
-					break
-				}
+		}
+		return members
+	}
+	for i := uint(0); i < n; i++ {
+		best[1<<i] = e.newLeaf(int(i))
+	}
+	for set := uint(1); set < 1<<n; set++ {
+		if set&(set-1) == 0 {
+			continue
+		}
+		rowCount := e.rowCount(membersOf(set))
+		for sub := (set - 1) & set; sub > 0; sub = (sub - 1) & set {
+			l, r := best[sub], best[set^sub]
+			// Only the connected subsets are joined, so no cartesian join is introduced.
+			if l == nil || r == nil || !e.connected(l.members, r.members) {
+				continue
 			}
-			if id != -1 {
-				e.groupRank[id].rate *= rate
+			cost := l.cost + r.cost + rowCount
+			// On a tie, prefer the tree which is closer to the original left deep one, whose right child
+			// holds the later members.
+			cur := best[set]
+			if cur == nil || costLess(cost, cur.cost) || (!costLess(cur.cost, cost) && r.members[0] > cur.right.members[0]) {
+				best[set] = &joinNode{members: membersOf(set), cost: cost, left: l, right: r}
 			}
 		}
 	}
-	for _, node := range e.graph {
-		for _, edge := range node {
-			edge.rate = e.groupRank[edge.nodeID].rate
+	// Split the group into the connected parts, each of them is the largest connected set containing its
+	// first member.
+	var parts []*joinNode
+	for remain := uint(1)<<n - 1; remain > 0; {
+		first := remain & -remain
+		part := best[first]
+		for sub := remain; sub > 0; sub = (sub - 1) & remain {
+			if sub&first != 0 && best[sub] != nil && len(best[sub].members) > len(part.members) {
+				part = best[sub]
+			}
 		}
-	}
-	sort.Sort(e)
-	for _, edge := range e.graph {
-		sort.Sort(edge)
-	}
-	var cartesianJoinGroup []LogicalPlan
-	for j := 0; j < len(e.groupRank); j++ {
-		i := e.groupRank[j].nodeID
-		if !e.visited[i] {
-			e.resultJoin = e.group[i]
-			e.walkGraphAndComposeJoin(i)
-			cartesianJoinGroup = append(cartesianJoinGroup, e.resultJoin)
+		parts = append(parts, part)
+		for _, id := range part.members {
+			remain &^= 1 << uint(id)
 		}
 	}
-	e.makeBushyJoin(cartesianJoinGroup)
+	return parts
 }
 
-// Make cartesian join as bushy tree.
-func (e *joinReOrderSolver) makeBushyJoin(cartesianJoinGroup []LogicalPlan) {
-	for len(cartesianJoinGroup) > 1 {
-		resultJoinGroup := make([]LogicalPlan, 0, len(cartesianJoinGroup))
-		for i := 0; i < len(cartesianJoinGroup); i += 2 {
-			if i+1 == len(cartesianJoinGroup) {
-				resultJoinGroup = append(resultJoinGroup, cartesianJoinGroup[i])
-				break
+// solveByGreedy repeatedly joins the pair of connected join trees with the least result, until there isn't
+// any connected pair.
+func (e *joinReOrderSolver) solveByGreedy() []*joinNode {
+	parts := make([]*joinNode, 0, len(e.group))
+	for i := range e.group {
+		parts = append(parts, e.newLeaf(i))
+	}
+	for {
+		var bestJoin *joinNode
+		bestI, bestJ := -1, -1
+		for i := range parts {
+			for j := i + 1; j < len(parts); j++ {
+				if !e.connected(parts[i].members, parts[j].members) {
+					continue
+				}
+				join := e.newJoinNode(parts[i], parts[j])
+				if bestJoin == nil || costLess(join.cost, bestJoin.cost) {
+					bestJoin, bestI, bestJ = join, i, j
+				}
 			}
-			resultJoinGroup = append(resultJoinGroup, e.newJoin(cartesianJoinGroup[i], cartesianJoinGroup[i+1]))
 		}
-		cartesianJoinGroup = resultJoinGroup
+		if bestJoin == nil {
+			return parts
+		}
+		parts[bestI] = bestJoin
+		parts = append(parts[:bestJ], parts[bestJ+1:]...)
+	}
+}
+
+func (e *joinReOrderSolver) buildJoinTree(node *joinNode) LogicalPlan {
+	if node.left == nil {
+		return e.group[node.members[0]]
 	}
-	e.resultJoin = cartesianJoinGroup[0]
+	return e.newJoin(e.buildJoinTree(node.left), e.buildJoinTree(node.right))
 }
 
 func (e *joinReOrderSolver) newJoin(lChild, rChild LogicalPlan) *LogicalJoin {
@@ -196,15 +417,3 @@ func (e *joinReOrderSolver) newJoin(lChild, rChild LogicalPlan) *LogicalJoin {
 	rChild.SetParents(join)
 	return join
 }
-
-// walkGraph implements a dfs algorithm. Each time it picks a edge with lowest rate, which has been sorted before.
-func (e *joinReOrderSolver) walkGraphAndComposeJoin(u int) {
-	e.visited[u] = true
-	for _, edge := range e.graph[u] {
-		v := edge.nodeID
-		if !e.visited[v] {
-			e.resultJoin = e.newJoin(e.resultJoin, e.group[v])
-			e.walkGraphAndComposeJoin(v)
-		}
-	}
-}
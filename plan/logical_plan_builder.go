@@ -14,16 +14,20 @@
 package plan
 
 import (
+	"bytes"
 	"fmt"
+	"strings"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/statistics"
+	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -126,6 +130,8 @@ func (b *planBuilder) buildResultSetNode(node ast.ResultSetNode) LogicalPlan {
 		}
 		if v, ok := p.(*DataSource); ok {
 			v.TableAsName = &x.AsName
+		} else if v, ok := p.(*Projection); ok && v.masking {
+			v.children[0].(*DataSource).TableAsName = &x.AsName
 		}
 		if x.AsName.L != "" {
 			for _, col := range p.Schema().Columns {
@@ -254,6 +260,11 @@ func (b *planBuilder) buildJoin(join *ast.Join) LogicalPlan {
 	} else if joinPlan.JoinType == InnerJoin {
 		joinPlan.cartesianJoin = true
 	}
+	if join.Tp == ast.LeftJoin || join.Tp == ast.RightJoin {
+		b.optFlag = b.optFlag | flagBuildKeyInfo
+		b.optFlag = b.optFlag | flagSimplifyOuterJoin
+		b.optFlag = b.optFlag | flagEliminateOuterJoin
+	}
 	if join.Tp == ast.LeftJoin {
 		joinPlan.JoinType = LeftOuterJoin
 		joinPlan.DefaultValues = make([]types.Datum, rightPlan.Schema().Len())
@@ -885,6 +896,10 @@ func (b *planBuilder) buildSelect(sel *ast.SelectStmt) LogicalPlan {
 		havingMap, orderMap, totalMap map[*ast.AggregateFuncExpr]int
 		gbyCols                       []expression.Expression
 	)
+	if err := checkStaleReadLock(b.ctx, sel); err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
 	if sel.From != nil {
 		p = b.buildResultSetNode(sel.From.TableRefs)
 	} else {
@@ -993,21 +1008,47 @@ func (b *planBuilder) buildDataSource(tn *ast.TableName) LogicalPlan {
 	if schemaName.L == "" {
 		schemaName = model.NewCIStr(b.ctx.GetSessionVars().CurrentDB)
 	}
-	tbl, err := b.is.TableByName(schemaName, tn.Name)
-	if err != nil {
-		b.err = errors.Trace(err)
-		return nil
+	var tableInfo *model.TableInfo
+	var asOfTS uint64
+	if tn.AsOf != nil {
+		if b.isDMLTarget(tn) {
+			b.err = ErrStaleReadUnsupported.GenByArgs("the table to update or delete")
+			return nil
+		}
+		var err error
+		tableInfo, asOfTS, err = b.asOfTableInfo(tn, schemaName)
+		if err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+	} else {
+		tbl, err := b.is.TableByName(schemaName, tn.Name)
+		if err != nil {
+			b.err = errors.Trace(err)
+			return nil
+		}
+		tableInfo = tbl.Meta()
 	}
-	tableInfo := tbl.Meta()
 
 	p := DataSource{
 		indexHints:     tn.IndexHints,
 		tableInfo:      tableInfo,
 		statisticTable: statisticTable,
 		DBName:         schemaName,
+		asOfTS:         asOfTS,
 	}.init(b.allocator, b.ctx)
 
 	b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SelectPriv, schemaName.L, tableInfo.Name.L, "")
+	policies := b.maskingPolicies(schemaName.L, tableInfo.Name.L)
+	b.maskingInfo = append(b.maskingInfo, maskingInfo{
+		db:            schemaName.L,
+		table:         tableInfo.Name.L,
+		maskedColumns: maskedColumns(policies),
+	})
+	if b.isDMLTarget(tn) {
+		// The executors write the rows back, checkMaskedTargetRead has checked the masked columns are not read.
+		policies = nil
+	}
 
 	// Equal condition contains a column from previous joined table.
 	schema := expression.NewSchema(make([]*expression.Column, 0, len(tableInfo.Columns))...)
@@ -1032,9 +1073,126 @@ func (b *planBuilder) buildDataSource(tn *ast.TableName) LogicalPlan {
 			ID:       col.ID})
 	}
 	p.SetSchema(schema)
+	if len(policies) > 0 {
+		return b.buildMaskingProjection(p, policies)
+	}
 	return p
 }
 
+func (b *planBuilder) isDMLTarget(tn *ast.TableName) bool {
+	for _, target := range b.dmlTargets {
+		if target == tn {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMaskedTargetRead checks that the statement doesn't read the masked columns of the DML targets,
+// which are read without masking policies.
+func (b *planBuilder) checkMaskedTargetRead(stmt ast.StmtNode) error {
+	checker := &maskedColumnChecker{policies: make(map[*ast.TableName][]*privilege.MaskingPolicy)}
+	for _, tn := range b.dmlTargets {
+		dbName := tn.Schema.L
+		if dbName == "" {
+			dbName = b.ctx.GetSessionVars().CurrentDB
+		}
+		if policies := b.maskingPolicies(dbName, tn.Name.L); len(policies) > 0 {
+			checker.policies[tn] = policies
+		}
+	}
+	if len(checker.policies) == 0 {
+		return nil
+	}
+	stmt.Accept(checker)
+	return checker.err
+}
+
+// maskedColumnChecker finds the column names which refer to the masked columns of the tables.
+type maskedColumnChecker struct {
+	policies map[*ast.TableName][]*privilege.MaskingPolicy
+	err      error
+}
+
+// Enter implements ast.Visitor interface.
+func (c *maskedColumnChecker) Enter(in ast.Node) (ast.Node, bool) {
+	cn, ok := in.(*ast.ColumnNameExpr)
+	if !ok || cn.Refer == nil || cn.Refer.Column == nil {
+		return in, c.err != nil
+	}
+	for _, policy := range c.policies[cn.Refer.TableName] {
+		if strings.EqualFold(policy.Column, cn.Refer.Column.Name.L) {
+			c.err = ErrMaskedColumnRead.GenByArgs(cn.Refer.Table.Name.O, cn.Refer.Column.Name.O)
+			return in, true
+		}
+	}
+	return in, false
+}
+
+// Leave implements ast.Visitor interface.
+func (c *maskedColumnChecker) Leave(in ast.Node) (ast.Node, bool) {
+	return in, c.err == nil
+}
+
+func (b *planBuilder) maskingPolicies(db, table string) []*privilege.MaskingPolicy {
+	pm := privilege.GetPrivilegeManager(b.ctx)
+	if pm == nil {
+		return nil
+	}
+	return pm.MaskingPolicies(b.ctx.GetSessionVars().ActiveRoles, db, table)
+}
+
+// maskedColumns describes the masking policies, the plan built with them can't be used with other policies.
+func maskedColumns(policies []*privilege.MaskingPolicy) string {
+	var buf bytes.Buffer
+	for _, policy := range policies {
+		fmt.Fprintf(&buf, "%s=%s;", policy.Column, policy.MaskExpr())
+	}
+	return buf.String()
+}
+
+// buildMaskingProjection adds a projection above the DataSource, which replaces the masked columns by the
+// masking functions of the policies, so all the operators above read the masked values.
+func (b *planBuilder) buildMaskingProjection(p *DataSource, policies []*privilege.MaskingPolicy) LogicalPlan {
+	proj := Projection{Exprs: make([]expression.Expression, 0, p.Schema().Len()), masking: true}.init(b.allocator, b.ctx)
+	schema := expression.NewSchema(make([]*expression.Column, 0, p.Schema().Len())...)
+	for i, col := range p.Schema().Columns {
+		var expr expression.Expression = col
+		for _, policy := range policies {
+			if !strings.EqualFold(policy.Column, col.ColName.L) {
+				continue
+			}
+			args := []expression.Expression{col}
+			for _, arg := range policy.Args {
+				argType := &types.FieldType{}
+				types.DefaultTypeForValue(arg.GetValue(), argType)
+				args = append(args, &expression.Constant{Value: arg, RetType: argType})
+			}
+			retType := types.NewFieldType(mysql.TypeVarString)
+			retType.Charset, retType.Collate = charset.CharsetUTF8, charset.CollationUTF8
+			var err error
+			expr, err = expression.NewFunction(b.ctx, policy.FuncName, retType, args...)
+			if err != nil {
+				b.err = errors.Trace(err)
+				return nil
+			}
+			break
+		}
+		proj.Exprs = append(proj.Exprs, expr)
+		schema.Append(&expression.Column{
+			FromID:   proj.id,
+			ColName:  col.ColName,
+			TblName:  col.TblName,
+			DBName:   col.DBName,
+			RetType:  expr.GetType(),
+			Position: i,
+		})
+	}
+	proj.SetSchema(schema)
+	addChild(proj, p)
+	return proj
+}
+
 // ApplyConditionChecker checks whether all or any output of apply matches a condition.
 type ApplyConditionChecker struct {
 	Condition expression.Expression
@@ -1140,6 +1298,11 @@ func (b *planBuilder) buildSemiJoin(outerPlan, innerPlan LogicalPlan, onConditio
 func (b *planBuilder) buildUpdate(update *ast.UpdateStmt) LogicalPlan {
 	b.inUpdateStmt = true
 	sel := &ast.SelectStmt{Fields: &ast.FieldList{}, From: update.TableRefs, Where: update.Where, OrderBy: update.Order, Limit: update.Limit}
+	b.dmlTargets = updateTargets(update)
+	if err := b.checkMaskedTargetRead(update); err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
 	p := b.buildResultSetNode(sel.From.TableRefs)
 	if b.err != nil {
 		return nil
@@ -1216,6 +1379,11 @@ func (b *planBuilder) buildUpdateLists(list []*ast.Assignment, p LogicalPlan) ([
 
 func (b *planBuilder) buildDelete(delete *ast.DeleteStmt) LogicalPlan {
 	sel := &ast.SelectStmt{Fields: &ast.FieldList{}, From: delete.TableRefs, Where: delete.Where, OrderBy: delete.Order, Limit: delete.Limit}
+	b.dmlTargets = deleteTargets(delete)
+	if err := b.checkMaskedTargetRead(delete); err != nil {
+		b.err = errors.Trace(err)
+		return nil
+	}
 	p := b.buildResultSetNode(sel.From.TableRefs)
 	if b.err != nil {
 		return nil
@@ -1275,6 +1443,73 @@ func (b *planBuilder) buildDelete(delete *ast.DeleteStmt) LogicalPlan {
 	return del
 }
 
+// updateTargets returns the tables whose columns are assigned by the UPDATE.
+func updateTargets(update *ast.UpdateStmt) []*ast.TableName {
+	var targets []*ast.TableName
+	for _, ts := range extractTableSources(update.TableRefs.TableRefs, nil) {
+		tn, ok := ts.Source.(*ast.TableName)
+		if !ok {
+			continue
+		}
+		for _, assign := range update.List {
+			if isColumnOfTable(assign.Column, ts.AsName, tn) {
+				targets = append(targets, tn)
+				break
+			}
+		}
+	}
+	return targets
+}
+
+// isColumnOfTable checks whether the column name refers to a column of the table tn aliased as asName.
+func isColumnOfTable(col *ast.ColumnName, asName model.CIStr, tn *ast.TableName) bool {
+	if col.Table.L != "" {
+		if asName.L != "" {
+			if col.Schema.L != "" || col.Table.L != asName.L {
+				return false
+			}
+		} else if col.Table.L != tn.Name.L || (col.Schema.L != "" && col.Schema.L != tn.Schema.L) {
+			return false
+		}
+	}
+	for _, c := range tn.TableInfo.Columns {
+		if c.Name.L == col.Name.L {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteTargets returns the tables whose rows are deleted by the DELETE.
+func deleteTargets(delete *ast.DeleteStmt) []*ast.TableName {
+	tableList := extractTableList(delete.TableRefs.TableRefs, nil)
+	if delete.Tables == nil {
+		return tableList
+	}
+	var targets []*ast.TableName
+	for _, tn := range tableList {
+		// The executor finds the rows to delete by table ID.
+		for _, t := range delete.Tables.Tables {
+			if t.TableInfo.ID == tn.TableInfo.ID {
+				targets = append(targets, tn)
+				break
+			}
+		}
+	}
+	return targets
+}
+
+func extractTableSources(node ast.ResultSetNode, input []*ast.TableSource) []*ast.TableSource {
+	switch x := node.(type) {
+	case *ast.Join:
+		input = extractTableSources(x.Left, input)
+		input = extractTableSources(x.Right, input)
+	case *ast.TableSource:
+		input = append(input, x)
+	}
+	return input
+}
+
 func extractTableList(node ast.ResultSetNode, input []*ast.TableName) []*ast.TableName {
 	switch x := node.(type) {
 	case *ast.Join:
@@ -374,7 +374,7 @@ func (s *testPlanSuite) TestPredicatePushDown(c *C) {
 		},
 		{
 			sql:  "select * from t t1, t t2 where t1.a = t2.b and t2.b > 0 and t1.a = t1.c and t1.d like 'abc' and t2.d = t1.d",
-			best: "Join{DataScan(t2)->Selection->DataScan(t1)->Selection}(t2.b,t1.a)(t2.d,t1.d)->Projection",
+			best: "Join{DataScan(t1)->Selection->DataScan(t2)->Selection}(t1.a,t2.b)(t1.d,t2.d)->Projection",
 		},
 		{
 			sql:  "select * from t ta join t tb on ta.d = tb.d and ta.d > 1 where tb.a = 0",
@@ -634,7 +634,7 @@ func (s *testPlanSuite) TestJoinReOrder(c *C) {
 	}{
 		{
 			sql:  "select * from t t1, t t2, t t3, t t4, t t5, t t6 where t1.a = t2.b and t2.a = t3.b and t3.c = t4.a and t4.d = t2.c and t5.d = t6.d",
-			best: "Join{Join{Join{Join{DataScan(t1)->DataScan(t2)}(t1.a,t2.b)->DataScan(t3)}(t2.a,t3.b)->DataScan(t4)}(t3.c,t4.a)(t2.c,t4.d)->Join{DataScan(t5)->DataScan(t6)}(t5.d,t6.d)}->Projection",
+			best: "Join{Join{DataScan(t1)->Join{Join{DataScan(t2)->DataScan(t3)}(t2.a,t3.b)->DataScan(t4)}(t3.c,t4.a)(t2.c,t4.d)}(t1.a,t2.b)->Join{DataScan(t5)->DataScan(t6)}(t5.d,t6.d)}->Projection",
 		},
 		{
 			sql:  "select * from t t1, t t2, t t3, t t4, t t5, t t6, t t7, t t8 where t1.a = t8.a",
@@ -642,20 +642,40 @@ func (s *testPlanSuite) TestJoinReOrder(c *C) {
 		},
 		{
 			sql:  "select * from t t1, t t2, t t3, t t4, t t5 where t1.a = t5.a and t5.a = t4.a and t4.a = t3.a and t3.a = t2.a and t2.a = t1.a and t1.a = t3.a and t2.a = t4.a and t5.b < 8",
-			best: "Join{Join{Join{Join{DataScan(t5)->Selection->DataScan(t1)}(t5.a,t1.a)->DataScan(t2)}(t1.a,t2.a)->DataScan(t3)}(t2.a,t3.a)(t1.a,t3.a)->DataScan(t4)}(t5.a,t4.a)(t3.a,t4.a)(t2.a,t4.a)->Projection",
+			best: "Join{Join{Join{Join{DataScan(t1)->DataScan(t5)->Selection}(t1.a,t5.a)->DataScan(t2)}(t1.a,t2.a)->DataScan(t3)}(t2.a,t3.a)(t1.a,t3.a)->DataScan(t4)}(t5.a,t4.a)(t3.a,t4.a)(t2.a,t4.a)->Projection",
 		},
 		{
 			sql:  "select * from t t1, t t2, t t3, t t4, t t5 where t1.a = t5.a and t5.a = t4.a and t4.a = t3.a and t3.a = t2.a and t2.a = t1.a and t1.a = t3.a and t2.a = t4.a and t3.b = 1 and t4.a = 1",
-			best: "Join{Join{Join{Join{DataScan(t3)->Selection->DataScan(t4)->Selection}->DataScan(t5)->Selection}->DataScan(t1)->Selection}->DataScan(t2)->Selection}->Projection",
+			best: "Join{Join{Join{Join{DataScan(t1)->Selection->DataScan(t3)->Selection}->DataScan(t2)->Selection}->DataScan(t4)->Selection}->DataScan(t5)->Selection}->Projection",
 		},
 		{
 			sql:  "select * from t o where o.b in (select t3.c from t t1, t t2, t t3 where t1.a = t3.a and t2.a = t3.a and t2.a = o.a)",
-			best: "Apply{DataScan(o)->Join{Join{DataScan(t2)->Selection->DataScan(t3)}(t2.a,t3.a)->DataScan(t1)}(t3.a,t1.a)->Projection}->Projection",
+			best: "Apply{DataScan(o)->Join{DataScan(t1)->Join{DataScan(t2)->Selection->DataScan(t3)}(t2.a,t3.a)}(t1.a,t3.a)->Projection}->Projection",
 		},
 		{
 			sql:  "select * from t o where o.b in (select t3.c from t t1, t t2, t t3 where t1.a = t3.a and t2.a = t3.a and t2.a = o.a and t1.a = 1)",
 			best: "Apply{DataScan(o)->Join{Join{DataScan(t1)->Selection->DataScan(t3)->Selection}->DataScan(t2)->Selection}->Projection}->Projection",
 		},
+		{
+			sql:  "select * from t t1 join t t2 on t1.a = t2.a join t t3 on t3.b = t1.b where t3.c = 1",
+			best: "Join{Join{DataScan(t1)->DataScan(t3)->Selection}(t1.b,t3.b)->DataScan(t2)}(t1.a,t2.a)->Projection",
+		},
+		{
+			sql:  "select * from t t1 join t t2 on t1.a = t2.a join t t3 on t3.b = t1.b",
+			best: "Join{Join{DataScan(t1)->DataScan(t2)}(t1.a,t2.a)->DataScan(t3)}(t1.b,t3.b)->Projection",
+		},
+		{
+			sql:  "select /*+ TIDB_SMJ(t3) */ * from t t1 join t t2 on t1.a = t2.a join t t3 on t3.b = t1.b where t3.c = 1",
+			best: "Join{Join{DataScan(t1)->DataScan(t2)}(t1.a,t2.a)->DataScan(t3)->Selection}(t1.b,t3.b)->Projection",
+		},
+		{
+			sql:  "select * from t t1 left join t t2 on t1.a = t2.a join t t3 on t3.b = t1.b where t3.c = 1",
+			best: "Join{Join{DataScan(t1)->DataScan(t2)}(t1.a,t2.a)->DataScan(t3)->Selection}(t1.b,t3.b)->Projection",
+		},
+		{
+			sql:  "select * from t t1, t t2, t t3, t t4, t t5, t t6, t t7, t t8, t t9, t t10, t t11 where t1.a = t2.a and t2.b = t3.b and t3.c = t4.c and t4.d = t5.d and t5.a = t6.a and t6.b = t7.b and t7.c = t8.c and t8.d = t9.d and t9.a = t10.a and t10.b = t11.b and t11.c = 1",
+			best: "Join{DataScan(t1)->Join{DataScan(t2)->Join{DataScan(t3)->Join{DataScan(t4)->Join{DataScan(t5)->Join{DataScan(t6)->Join{DataScan(t7)->Join{DataScan(t8)->Join{DataScan(t9)->Join{DataScan(t10)->DataScan(t11)->Selection}(t10.b,t11.b)}(t9.a,t10.a)}(t8.d,t9.d)}(t7.c,t8.c)}(t6.b,t7.b)}(t5.a,t6.a)}(t4.d,t5.d)}(t3.c,t4.c)}(t2.b,t3.b)}(t1.a,t2.a)->Projection",
+		},
 	}
 	for _, ca := range cases {
 		comment := Commentf("for %s", ca.sql)
@@ -787,6 +807,132 @@ func (s *testPlanSuite) TestAggPushDown(c *C) {
 	}
 }
 
+func (s *testPlanSuite) TestOuterJoinSimplify(c *C) {
+	defer testleak.AfterTest(c)()
+	tests := []struct {
+		sql      string
+		joinType JoinType
+	}{
+		{
+			sql:      "select * from t ta left join t tb on ta.a = tb.a where tb.b > 1",
+			joinType: InnerJoin,
+		},
+		{
+			sql:      "select * from t ta right join t tb on ta.a = tb.a where ta.b is not null",
+			joinType: InnerJoin,
+		},
+		{
+			sql:      "select * from t ta left join t tb on ta.a = tb.a where tb.b is null",
+			joinType: LeftOuterJoin,
+		},
+		{
+			sql:      "select * from t ta left join t tb on ta.a = tb.a where ta.b > 1",
+			joinType: LeftOuterJoin,
+		},
+		{
+			sql:      "select * from t ta left join t tb on ta.a = tb.a where tb.b > 1 or ta.b > 1",
+			joinType: LeftOuterJoin,
+		},
+	}
+	for _, tt := range tests {
+		comment := Commentf("for %s", tt.sql)
+		stmt, err := s.ParseOneStmt(tt.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		is, err := mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mockContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+			is:        is,
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		c.Assert(builder.optFlag&flagSimplifyOuterJoin, Greater, uint64(0))
+		// The predicates aren't pushed down, so the join is only simplified by the WHERE conditions above it.
+		lp, err := logicalOptimize(flagPrunColumns|flagSimplifyOuterJoin, p.(LogicalPlan), builder.ctx, builder.allocator)
+		c.Assert(err, IsNil)
+		for lp != nil {
+			if join, ok := lp.(*LogicalJoin); ok {
+				c.Assert(join.JoinType, Equals, tt.joinType, comment)
+				break
+			}
+			lp = lp.Children()[0].(LogicalPlan)
+		}
+		c.Assert(lp, NotNil, comment)
+	}
+}
+
+func (s *testPlanSuite) TestOuterJoinEliminate(c *C) {
+	defer testleak.AfterTest(c)()
+	tests := []struct {
+		sql  string
+		best string
+	}{
+		{
+			sql:  "select ta.b from t ta left join t tb on ta.a = tb.a",
+			best: "DataScan(ta)->Projection",
+		},
+		{
+			sql:  "select ta.b from t ta left join t tb on ta.b = tb.f and tb.c > 1",
+			best: "DataScan(ta)->Projection",
+		},
+		{
+			sql:  "select tb.b from t ta right join t tb on ta.a = tb.a where tb.c > 1",
+			best: "DataScan(tb)->Selection->Projection",
+		},
+		{
+			sql:  "select count(*) from t ta left join t tb on ta.a = tb.a group by ta.c",
+			best: "DataScan(ta)->Aggr(count(1))->Projection",
+		},
+		{
+			sql:  "select ta.b from t ta left join t tb on ta.a = tb.a left join t tc on tb.f = tc.f",
+			best: "DataScan(ta)->Projection",
+		},
+		{
+			// tb.b isn't unique.
+			sql:  "select ta.b from t ta left join t tb on ta.a = tb.b",
+			best: "Join{DataScan(ta)->DataScan(tb)}(ta.a,tb.b)->Projection",
+		},
+		{
+			// The columns of tb are used.
+			sql:  "select ta.b, tb.c from t ta left join t tb on ta.a = tb.a",
+			best: "Join{DataScan(ta)->DataScan(tb)}(ta.a,tb.a)->Projection",
+		},
+		{
+			sql:  "select ta.b from t ta left join t tb on ta.a = tb.a order by tb.c",
+			best: "Join{DataScan(ta)->DataScan(tb)}(ta.a,tb.a)->Projection->Sort->Projection",
+		},
+		{
+			// The join is simplified to inner join.
+			sql:  "select ta.b from t ta left join t tb on ta.a = tb.a where tb.b > 1",
+			best: "Join{DataScan(ta)->DataScan(tb)->Selection}(ta.a,tb.a)->Projection",
+		},
+	}
+	for _, tt := range tests {
+		comment := Commentf("for %s", tt.sql)
+		stmt, err := s.ParseOneStmt(tt.sql, "", "")
+		c.Assert(err, IsNil, comment)
+
+		is, err := mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mockContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+			is:        is,
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp, err := logicalOptimize(builder.optFlag, p.(LogicalPlan), builder.ctx, builder.allocator)
+		c.Assert(err, IsNil)
+		c.Assert(ToString(lp), Equals, tt.best, comment)
+	}
+}
+
 func (s *testPlanSuite) TestRefine(c *C) {
 	defer testleak.AfterTest(c)()
 	cases := []struct {
@@ -1472,9 +1618,84 @@ func (s *testPlanSuite) TestVisitInfo(c *C) {
 		{
 			sql: "truncate table t",
 			ans: []visitInfo{
+				{mysql.DropPriv, "test", "t", ""},
+			},
+		},
+		{
+			sql: "rename table t to t1",
+			ans: []visitInfo{
+				{mysql.AlterPriv, "test", "t", ""},
+				{mysql.DropPriv, "test", "t", ""},
+				{mysql.CreatePriv, "test", "t1", ""},
+				{mysql.InsertPriv, "test", "t1", ""},
+			},
+		},
+		{
+			sql: "replace into t values (1)",
+			ans: []visitInfo{
+				{mysql.InsertPriv, "test", "t", ""},
 				{mysql.DeletePriv, "test", "t", ""},
 			},
 		},
+		{
+			sql: "insert into t values (1) on duplicate key update a = 2",
+			ans: []visitInfo{
+				{mysql.InsertPriv, "test", "t", ""},
+				{mysql.UpdatePriv, "test", "t", ""},
+			},
+		},
+		{
+			sql: "load data local infile '/tmp/t.csv' into table t",
+			ans: []visitInfo{
+				{mysql.InsertPriv, "test", "t", ""},
+			},
+		},
+		{
+			sql: "load data infile '/tmp/t.csv' into table t",
+			ans: []visitInfo{
+				{mysql.InsertPriv, "test", "t", ""},
+				{mysql.FilePriv, "", "", ""},
+			},
+		},
+		{
+			sql: "analyze table t",
+			ans: []visitInfo{
+				{mysql.SelectPriv, "test", "t", ""},
+				{mysql.InsertPriv, "test", "t", ""},
+			},
+		},
+		{
+			sql: "admin show ddl",
+			ans: []visitInfo{
+				{mysql.SuperPriv, "", "", ""},
+			},
+		},
+		{
+			sql: "flush privileges",
+			ans: []visitInfo{
+				{mysql.ReloadPriv, "", "", ""},
+			},
+		},
+		{
+			sql: "set global autocommit = 1, @@session.autocommit = 1",
+			ans: []visitInfo{
+				{mysql.SuperPriv, "", "", ""},
+			},
+		},
+		{
+			sql: "set autocommit = 1",
+			ans: []visitInfo{},
+		},
+		{
+			sql: "show grants for 'test'@'%'",
+			ans: []visitInfo{
+				{mysql.SelectPriv, "mysql", "", ""},
+			},
+		},
+		{
+			sql: "kill 1",
+			ans: []visitInfo{},
+		},
 		{
 			sql: "drop table t",
 			ans: []visitInfo{
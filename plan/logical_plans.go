@@ -133,6 +133,8 @@ type Projection struct {
 	basePhysicalPlan
 
 	Exprs []expression.Expression
+	// masking is true if the projection replaces the columns of the DataSource below it by masking functions.
+	masking bool
 }
 
 func (p *Projection) extractCorrelatedCols() []*expression.CorrelatedColumn {
@@ -262,6 +264,9 @@ type DataSource struct {
 	pushedDownConds []expression.Expression
 
 	statisticTable *statistics.Table
+
+	// asOfTS is the timestamp of AS OF TIMESTAMP, the table is read at the start ts of the transaction if it's 0.
+	asOfTS uint64
 }
 
 // Union represents Union plan.
@@ -15,8 +15,10 @@ package plan
 
 import (
 	"math"
+	"sort"
 
 	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
@@ -298,9 +300,202 @@ func (p *DataSource) convert2NewPhysicalPlan(prop *requiredProp) (taskProfile, e
 			task = idxTask
 		}
 	}
+	if prop.isEmpty() {
+		mergeTask, err := p.convertToIndexMerge(indices)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		// The index lookup of the other tasks is not costed until they are finished.
+		if mergeTask != nil && (task == nil || mergeTask.finishedCost() < task.(*copTaskProfile).finishedCost()) {
+			task = mergeTask
+		}
+	}
 	return task, p.storeTaskProfile(prop, task)
 }
 
+// convertToIndexMerge converts the DataSource to index merge, which reads the handles by several index scans and
+// looks up the table by their union or intersection. It returns nil if no index merge is available.
+func (p *DataSource) convertToIndexMerge(indices []*model.IndexInfo) (*copTaskProfile, error) {
+	if len(p.pushedDownConds) == 0 || len(indices) < 2 {
+		return nil, nil
+	}
+	var best *copTaskProfile
+	for _, cond := range p.pushedDownConds {
+		if sf, ok := cond.(*expression.ScalarFunction); !ok || sf.FuncName.L != ast.OrOr {
+			continue
+		}
+		task, err := p.convertToIndexUnion(cond, indices)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if task != nil && (best == nil || task.cost() < best.cost()) {
+			best = task
+		}
+	}
+	task, err := p.convertToIndexIntersection(indices)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if task != nil && (best == nil || task.cost() < best.cost()) {
+		best = task
+	}
+	return best, nil
+}
+
+// convertToIndexUnion reads every item of the DNF condition by the cheapest index that can access it, and unions
+// the handles. It returns nil if some item can't be accessed by any index.
+func (p *DataSource) convertToIndexUnion(cond expression.Expression, indices []*model.IndexInfo) (*copTaskProfile, error) {
+	items := expression.SplitDNFItems(cond)
+	partials := make([]*PhysicalIndexScan, 0, len(items))
+	counts := make([]float64, 0, len(items))
+	usedIndices := make(map[*model.IndexInfo]struct{})
+	for _, item := range items {
+		var best *PhysicalIndexScan
+		var bestCount float64
+		for _, idx := range indices {
+			is, cnt, err := p.convertToPartialIndexScan([]expression.Expression{item}, idx)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if is != nil && (best == nil || cnt < bestCount) {
+				best, bestCount = is, cnt
+			}
+		}
+		if best == nil {
+			return nil, nil
+		}
+		partials = append(partials, best)
+		counts = append(counts, bestCount)
+		usedIndices[best.Index] = struct{}{}
+	}
+	if len(usedIndices) < 2 {
+		return nil, nil
+	}
+	total := float64(p.statisticTable.Count)
+	var merged float64
+	for _, cnt := range counts {
+		merged += cnt
+	}
+	return p.newIndexMergeTask(partials, counts, math.Min(merged, total), false), nil
+}
+
+// convertToIndexIntersection reads the handles by the indices which can access the CNF conditions, and intersects
+// them. The indices are added from the most selective one as long as the cost decreases.
+func (p *DataSource) convertToIndexIntersection(indices []*model.IndexInfo) (*copTaskProfile, error) {
+	var candidates partialIndexScans
+	for _, idx := range indices {
+		is, cnt, err := p.convertToPartialIndexScan(p.pushedDownConds, idx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if is != nil {
+			candidates.scans = append(candidates.scans, is)
+			candidates.counts = append(candidates.counts, cnt)
+		}
+	}
+	if len(candidates.scans) < 2 {
+		return nil, nil
+	}
+	total := float64(p.statisticTable.Count)
+	if total <= 0 {
+		return nil, nil
+	}
+	sort.Stable(&candidates)
+	merged := candidates.counts[0]
+	n := 1
+	for ; n < len(candidates.scans); n++ {
+		// Scanning one more index is worthwhile only if it saves more table lookups than it costs.
+		newMerged := merged * candidates.counts[n] / total
+		scanCost := candidates.counts[n] * (scanFactor + netWorkFactor)
+		lookupCost := (merged - newMerged) * (lookupFactor + netWorkFactor)
+		if scanCost >= lookupCost {
+			break
+		}
+		merged = newMerged
+	}
+	if n < 2 {
+		return nil, nil
+	}
+	return p.newIndexMergeTask(candidates.scans[:n], candidates.counts[:n], merged, true), nil
+}
+
+// partialIndexScans sorts the partial index scans by their row counts.
+type partialIndexScans struct {
+	scans  []*PhysicalIndexScan
+	counts []float64
+}
+
+func (s *partialIndexScans) Len() int           { return len(s.scans) }
+func (s *partialIndexScans) Less(i, j int) bool { return s.counts[i] < s.counts[j] }
+func (s *partialIndexScans) Swap(i, j int) {
+	s.scans[i], s.scans[j] = s.scans[j], s.scans[i]
+	s.counts[i], s.counts[j] = s.counts[j], s.counts[i]
+}
+
+// convertToPartialIndexScan builds the index scan of idx which reads the handles by conds. It returns nil if conds
+// can't be used to access idx.
+func (p *DataSource) convertToPartialIndexScan(conds []expression.Expression, idx *model.IndexInfo) (*PhysicalIndexScan, float64, error) {
+	is := PhysicalIndexScan{
+		Table:       p.tableInfo,
+		TableAsName: p.TableAsName,
+		DBName:      p.DBName,
+		Columns:     p.Columns,
+		Index:       idx,
+		OutOfOrder:  true,
+	}.init(p.allocator, p.ctx)
+	is.AsOfTS = p.asOfTS
+	clonedConds := make([]expression.Expression, 0, len(conds))
+	for _, cond := range conds {
+		clonedConds = append(clonedConds, cond.Clone())
+	}
+	is.AccessCondition, _, is.accessEqualCount, is.accessInAndEqCount = DetachIndexScanConditions(clonedConds, idx)
+	if len(is.AccessCondition) == 0 {
+		return nil, 0, nil
+	}
+	sc := p.ctx.GetSessionVars().StmtCtx
+	err := BuildIndexRange(sc, is)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	rowCount, err := p.statisticTable.GetRowCountByIndexRanges(sc, is.Index.ID, is.Ranges, is.accessInAndEqCount)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	// We only need the handles.
+	is.SetSchema(expression.NewSchema())
+	return is, rowCount, nil
+}
+
+// newIndexMergeTask creates the cop task of index merge, all the pushed down conditions are checked again after
+// looking up the table.
+func (p *DataSource) newIndexMergeTask(partials []*PhysicalIndexScan, counts []float64, merged float64, intersection bool) *copTaskProfile {
+	ts := PhysicalTableScan{
+		Table:       p.tableInfo,
+		Columns:     p.Columns,
+		TableAsName: p.TableAsName,
+		DBName:      p.DBName,
+	}.init(p.allocator, p.ctx)
+	ts.AsOfTS = p.asOfTS
+	ts.SetSchema(p.schema)
+	for _, cond := range p.pushedDownConds {
+		ts.filterCondition = append(ts.filterCondition, cond.Clone())
+	}
+	partialPlans := make([]PhysicalPlan, 0, len(partials))
+	var cost float64
+	for i, is := range partials {
+		partialPlans = append(partialPlans, is)
+		cost += counts[i] * (scanFactor + netWorkFactor)
+	}
+	return &copTaskProfile{
+		cnt:               merged,
+		cst:               cost + merged*lookupFactor,
+		tablePlan:         ts,
+		indexPlanFinished: true,
+		partialPlans:      partialPlans,
+		intersection:      intersection,
+	}
+}
+
 // convert2IndexScanner converts the DataSource to index scan with idx.
 func (p *DataSource) convertToIndexScan(prop *requiredProp, idx *model.IndexInfo) (task taskProfile, err error) {
 	is := PhysicalIndexScan{
@@ -310,6 +505,7 @@ func (p *DataSource) convertToIndexScan(prop *requiredProp, idx *model.IndexInfo
 		Columns:     p.Columns,
 		Index:       idx,
 	}.init(p.allocator, p.ctx)
+	is.AsOfTS = p.asOfTS
 	statsTbl := p.statisticTable
 	rowCount := float64(statsTbl.Count)
 	sc := p.ctx.GetSessionVars().StmtCtx
@@ -338,7 +534,9 @@ func (p *DataSource) convertToIndexScan(prop *requiredProp, idx *model.IndexInfo
 	}
 	if !isCoveringIndex(is.Columns, is.Index.Columns, is.Table.PKIsHandle) {
 		// On this way, it's double read case.
-		copTask.tablePlan = PhysicalTableScan{Columns: p.Columns, Table: is.Table}.init(p.allocator, p.ctx)
+		ts := PhysicalTableScan{Columns: p.Columns, Table: is.Table}.init(p.allocator, p.ctx)
+		ts.AsOfTS = p.asOfTS
+		copTask.tablePlan = ts
 		copTask.tablePlan.SetSchema(p.schema)
 		var indexCols []*expression.Column
 		for _, col := range idx.Columns {
@@ -397,6 +595,7 @@ func (p *DataSource) convertToTableScan(prop *requiredProp) (task taskProfile, e
 		TableAsName: p.TableAsName,
 		DBName:      p.DBName,
 	}.init(p.allocator, p.ctx)
+	ts.AsOfTS = p.asOfTS
 	ts.SetSchema(p.schema)
 	sc := p.ctx.GetSessionVars().StmtCtx
 	if len(p.pushedDownConds) > 0 {
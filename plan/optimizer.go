@@ -31,6 +31,8 @@ const (
 	flagPrunColumns uint64 = 1 << iota
 	flagBuildKeyInfo
 	flagDecorrelate
+	flagSimplifyOuterJoin
+	flagEliminateOuterJoin
 	flagPredicatePushDown
 	flagAggregationOptimize
 	flagPushDownTopN
@@ -40,6 +42,8 @@ var optRuleList = []logicalOptRule{
 	&columnPruner{},
 	&buildKeySolver{},
 	&decorrelateSolver{},
+	&outerJoinSimplifier{},
+	&outerJoinEliminator{},
 	&ppdSolver{},
 	&aggregationOptimizer{},
 	&pushDownTopNOptimizer{},
@@ -53,9 +57,17 @@ type logicalOptRule interface {
 // Optimize does optimization and creates a Plan.
 // The node must be prepared first.
 func Optimize(ctx context.Context, node ast.Node, is infoschema.InfoSchema) (Plan, error) {
+	p, _, _, err := optimize(ctx, node, is)
+	return p, errors.Trace(err)
+}
+
+func optimize(ctx context.Context, node ast.Node, is infoschema.InfoSchema) (Plan, []visitInfo, []maskingInfo, error) {
+	if err := CheckPasswordExpired(ctx, node); err != nil {
+		return nil, nil, nil, errors.Trace(err)
+	}
 	// We have to infer type again because after parameter is set, the expression type may change.
 	if err := InferType(ctx.GetSessionVars().StmtCtx, node); err != nil {
-		return nil, errors.Trace(err)
+		return nil, nil, nil, errors.Trace(err)
 	}
 	allocator := new(idAllocator)
 	builder := &planBuilder{
@@ -66,26 +78,41 @@ func Optimize(ctx context.Context, node ast.Node, is infoschema.InfoSchema) (Pla
 	}
 	p := builder.build(node)
 	if builder.err != nil {
-		return nil, errors.Trace(builder.err)
+		return nil, nil, nil, errors.Trace(builder.err)
 	}
 
 	// Maybe it's better to move this to Preprocess, but check privilege need table
 	// information, which is collected into visitInfo during logical plan builder.
 	if pm := privilege.GetPrivilegeManager(ctx); pm != nil {
-		if !checkPrivilege(pm, builder.visitInfo) {
-			return nil, errors.New("privilege check fail")
+		if !checkPrivilege(pm, ctx.GetSessionVars().ActiveRoles, builder.visitInfo) {
+			return nil, nil, nil, ErrPrivilegeCheckFail
 		}
 	}
 
 	if logic, ok := p.(LogicalPlan); ok {
-		return doOptimize(builder.optFlag, logic, ctx, allocator)
+		pp, err := doOptimize(builder.optFlag, logic, ctx, allocator)
+		return pp, builder.visitInfo, builder.maskingInfo, errors.Trace(err)
 	}
-	return p, nil
+	return p, builder.visitInfo, builder.maskingInfo, nil
 }
 
-func checkPrivilege(pm privilege.Manager, vs []visitInfo) bool {
+// CheckPasswordExpired returns ErrMustChangePassword if the password of the current user is expired and
+// the statement doesn't change it.
+// See https://dev.mysql.com/doc/refman/5.7/en/expired-password-handling.html
+func CheckPasswordExpired(ctx context.Context, node ast.Node) error {
+	if !ctx.GetSessionVars().PasswordExpired {
+		return nil
+	}
+	switch node.(type) {
+	case *ast.SetPwdStmt, *ast.AlterUserStmt, *ast.SetStmt:
+		return nil
+	}
+	return ErrMustChangePassword
+}
+
+func checkPrivilege(pm privilege.Manager, activeRoles []string, vs []visitInfo) bool {
 	for _, v := range vs {
-		if !pm.RequestVerification(v.db, v.table, v.column, v.privilege) {
+		if !pm.RequestVerification(activeRoles, v.db, v.table, v.column, v.privilege) {
 			return false
 		}
 	}
@@ -177,6 +204,13 @@ const (
 	CodeUnsupported         terror.ErrCode = 4
 	CodeInvalidGroupFuncUse terror.ErrCode = 5
 	CodeIllegalReference    terror.ErrCode = 6
+	CodePrivilegeCheckFail  terror.ErrCode = 7
+
+	CodeStaleReadUnsupported terror.ErrCode = 8
+	CodeInvalidAsOfTS        terror.ErrCode = 9
+	CodeSnapshotTooOld       terror.ErrCode = 10
+	CodeMustChangePassword   terror.ErrCode = 11
+	CodeMaskedColumnRead     terror.ErrCode = 12
 )
 
 // Optimizer base errors.
@@ -186,6 +220,12 @@ var (
 	ErrCartesianProductUnsupported = terror.ClassOptimizer.New(CodeUnsupported, "Cartesian product is unsupported")
 	ErrInvalidGroupFuncUse         = terror.ClassOptimizer.New(CodeInvalidGroupFuncUse, "Invalid use of group function")
 	ErrIllegalReference            = terror.ClassOptimizer.New(CodeIllegalReference, "Illegal reference")
+	ErrPrivilegeCheckFail          = terror.ClassOptimizer.New(CodePrivilegeCheckFail, "privilege check fail")
+	ErrStaleReadUnsupported        = terror.ClassOptimizer.New(CodeStaleReadUnsupported, "AS OF TIMESTAMP is unsupported for %s")
+	ErrInvalidAsOfTS               = terror.ClassOptimizer.New(CodeInvalidAsOfTS, "invalid AS OF TIMESTAMP: %s")
+	ErrSnapshotTooOld              = terror.ClassOptimizer.New(CodeSnapshotTooOld, "snapshot is older than GC safe point %s")
+	ErrMustChangePassword          = terror.ClassOptimizer.New(CodeMustChangePassword, mysql.MySQLErrName[mysql.ErrMustChangePassword])
+	ErrMaskedColumnRead            = terror.ClassOptimizer.New(CodeMaskedColumnRead, "masked column %s.%s can't be read by the table to update or delete")
 )
 
 func init() {
@@ -194,6 +234,8 @@ func init() {
 		CodeInvalidWildCard:     mysql.ErrParse,
 		CodeInvalidGroupFuncUse: mysql.ErrInvalidGroupFuncUse,
 		CodeIllegalReference:    mysql.ErrIllegalReference,
+		CodePrivilegeCheckFail:  mysql.ErrSpecificAccessDenied,
+		CodeMustChangePassword:  mysql.ErrMustChangePassword,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassOptimizer] = mySQLErrCodes
 	expression.EvalAstExpr = evalAstExpr
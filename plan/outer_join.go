@@ -0,0 +1,160 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+)
+
+// outerJoinSimplifier converts an outer join to inner join, if the WHERE conditions above it reject the null values
+// of its inner side.
+type outerJoinSimplifier struct{}
+
+// optimize implements logicalOptRule interface.
+func (s *outerJoinSimplifier) optimize(p LogicalPlan, _ context.Context, _ *idAllocator) (LogicalPlan, error) {
+	var err error
+	switch x := p.(type) {
+	case *Selection:
+		if join, ok := x.children[0].(*LogicalJoin); ok {
+			err = outerJoinSimplify(join, x.Conditions)
+		}
+	case *LogicalJoin:
+		err = outerJoinSimplify(x, nil)
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, child := range p.Children() {
+		_, err = s.optimize(child.(LogicalPlan), nil, nil)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return p, nil
+}
+
+// outerJoinEliminator removes an outer join, if its inner side is unique on the join keys and none of the inner
+// columns is used above it. Every row of the outer side matches at most one row, so the join returns the outer rows.
+type outerJoinEliminator struct{}
+
+// optimize implements logicalOptRule interface.
+func (s *outerJoinEliminator) optimize(p LogicalPlan, _ context.Context, _ *idAllocator) (LogicalPlan, error) {
+	np, eliminated := s.eliminate(p, p.Schema().Columns)
+	if eliminated {
+		// The plans above the eliminated joins may still output the inner columns, so prune them again.
+		np.PruneColumns(np.Schema().Columns)
+		np.buildKeyInfo()
+	}
+	return np, nil
+}
+
+// eliminate eliminates the outer joins in p, parentUsedCols are the columns of p used by its ancestors.
+func (s *outerJoinEliminator) eliminate(p LogicalPlan, parentUsedCols []*expression.Column) (LogicalPlan, bool) {
+	if join, ok := p.(*LogicalJoin); ok {
+		if outerPlan := join.eliminableOuterPlan(parentUsedCols); outerPlan != nil {
+			np, _ := s.eliminate(outerPlan, parentUsedCols)
+			return np, true
+		}
+	}
+	eliminated := false
+	usedCols := childrenUsedCols(p, parentUsedCols)
+	newChildren := make([]Plan, 0, len(p.Children()))
+	for _, child := range p.Children() {
+		childUsedCols := usedCols
+		if childUsedCols == nil {
+			childUsedCols = child.Schema().Columns
+		}
+		np, childEliminated := s.eliminate(child.(LogicalPlan), childUsedCols)
+		newChildren = append(newChildren, np)
+		np.SetParents(p)
+		eliminated = eliminated || childEliminated
+	}
+	p.SetChildren(newChildren...)
+	return p, eliminated
+}
+
+// childrenUsedCols returns the columns of p's children which are used by p and its ancestors. It returns nil if we
+// don't know, and all the columns of the children are regarded as used.
+func childrenUsedCols(p LogicalPlan, parentUsedCols []*expression.Column) []*expression.Column {
+	var exprs []expression.Expression
+	switch x := p.(type) {
+	case *Projection:
+		parentUsedCols = nil
+		exprs = x.Exprs
+	case *LogicalAggregation:
+		parentUsedCols = nil
+		exprs = x.GroupByItems
+		for _, fun := range x.AggFuncs {
+			exprs = append(exprs, fun.GetArgs()...)
+		}
+	case *Selection:
+		exprs = x.Conditions
+	case *Sort:
+		for _, item := range x.ByItems {
+			exprs = append(exprs, item.Expr)
+		}
+	case *LogicalJoin:
+		exprs = append(expression.ScalarFuncs2Exprs(x.EqualConditions), x.LeftConditions...)
+		exprs = append(exprs, x.RightConditions...)
+		exprs = append(exprs, x.OtherConditions...)
+	case *Limit, *SelectLock:
+	default:
+		return nil
+	}
+	usedCols := make([]*expression.Column, 0, len(parentUsedCols)+len(exprs))
+	usedCols = append(usedCols, parentUsedCols...)
+	for _, expr := range exprs {
+		usedCols = append(usedCols, expression.ExtractColumns(expr)...)
+	}
+	return usedCols
+}
+
+// eliminableOuterPlan returns the outer side of the join if it can be eliminated, otherwise it returns nil.
+func (p *LogicalJoin) eliminableOuterPlan(parentUsedCols []*expression.Column) LogicalPlan {
+	var outerPlan, innerPlan LogicalPlan
+	switch p.JoinType {
+	case LeftOuterJoin:
+		outerPlan, innerPlan = p.children[0].(LogicalPlan), p.children[1].(LogicalPlan)
+	case RightOuterJoin:
+		outerPlan, innerPlan = p.children[1].(LogicalPlan), p.children[0].(LogicalPlan)
+	default:
+		return nil
+	}
+	innerSchema := innerPlan.Schema()
+	for _, col := range parentUsedCols {
+		if innerSchema.Contains(col) {
+			return nil
+		}
+	}
+	if innerSchema.MaxOneRow {
+		return outerPlan
+	}
+	joinKeys := make([]*expression.Column, 0, len(p.EqualConditions))
+	for _, eqCond := range p.EqualConditions {
+		for _, arg := range eqCond.GetArgs() {
+			if col, ok := arg.(*expression.Column); ok && innerSchema.Contains(col) {
+				joinKeys = append(joinKeys, col)
+			}
+		}
+	}
+	joinKeySchema := expression.NewSchema(joinKeys...)
+	for _, key := range innerSchema.Keys {
+		if joinKeySchema.ColumnsIndices(key) != nil {
+			return outerPlan
+		}
+	}
+	return nil
+}
@@ -32,6 +32,7 @@ const (
 	netWorkFactor   = 1.5
 	scanFactor      = 2.0
 	descScanFactor  = 5 * scanFactor
+	lookupFactor    = 5 * scanFactor
 	memoryFactor    = 5.0
 	selectionFactor = 0.8
 	cpuFactor       = 0.9
@@ -49,7 +50,7 @@ func (p *DataSource) convert2TableScan(prop *requiredProperty) (*physicalPlanInf
 		Columns:             p.Columns,
 		TableAsName:         p.TableAsName,
 		DBName:              p.DBName,
-		physicalTableSource: physicalTableSource{client: client},
+		physicalTableSource: physicalTableSource{client: client, AsOfTS: p.asOfTS},
 	}.init(p.allocator, p.ctx)
 	ts.SetSchema(p.Schema())
 	if p.ctx.Txn() != nil {
@@ -114,7 +115,7 @@ func (p *DataSource) convert2IndexScan(prop *requiredProperty, index *model.Inde
 		TableAsName:         p.TableAsName,
 		OutOfOrder:          true,
 		DBName:              p.DBName,
-		physicalTableSource: physicalTableSource{client: client},
+		physicalTableSource: physicalTableSource{client: client, AsOfTS: p.asOfTS},
 	}.init(p.allocator, p.ctx)
 	is.SetSchema(p.schema)
 	if p.ctx.Txn() != nil {
@@ -258,7 +259,7 @@ func (p *DataSource) tryToConvert2DummyScan(prop *requiredProperty) (*physicalPl
 	}
 
 	for _, cond := range sel.Conditions {
-		if con, ok := cond.(*expression.Constant); ok {
+		if con, ok := cond.(*expression.Constant); ok && con.DeferredParam == nil {
 			result, err := expression.EvalBool(con, nil, p.ctx)
 			if err != nil {
 				return nil, errors.Trace(err)
@@ -1222,7 +1223,7 @@ func (p *Selection) makeScanController() *physicalPlanInfo {
 			Columns:             ds.Columns,
 			TableAsName:         ds.TableAsName,
 			DBName:              ds.DBName,
-			physicalTableSource: physicalTableSource{client: ds.ctx.GetClient()},
+			physicalTableSource: physicalTableSource{client: ds.ctx.GetClient(), AsOfTS: ds.asOfTS},
 		}.init(p.allocator, p.ctx)
 		ts.SetSchema(ds.schema)
 		if ds.ctx.Txn() != nil {
@@ -1250,7 +1251,7 @@ func (p *Selection) makeScanController() *physicalPlanInfo {
 					TableAsName:         ds.TableAsName,
 					OutOfOrder:          true,
 					DBName:              ds.DBName,
-					physicalTableSource: physicalTableSource{client: ds.ctx.GetClient()},
+					physicalTableSource: physicalTableSource{client: ds.ctx.GetClient(), AsOfTS: ds.asOfTS},
 				}.init(p.allocator, p.ctx)
 				is.SetSchema(ds.schema)
 				if is.ctx.Txn() != nil {
@@ -117,6 +117,46 @@ func (p *PhysicalIndexLookUpReader) Copy() PhysicalPlan {
 	return &np
 }
 
+// PhysicalIndexMergeReader is the index merge reader in tidb. It reads the handles by several index scans, merges
+// them, and then looks up the table by the merged handles.
+type PhysicalIndexMergeReader struct {
+	*basePlan
+	basePhysicalPlan
+
+	// PartialPlans are the index scans which read the handles.
+	PartialPlans []PhysicalPlan
+	// TablePlan looks up the table by the merged handles.
+	TablePlan PhysicalPlan
+	// Intersection means the handles are intersected, otherwise they are unioned.
+	Intersection bool
+}
+
+// Copy implements the PhysicalPlan Copy interface.
+func (p *PhysicalIndexMergeReader) Copy() PhysicalPlan {
+	np := *p
+	np.basePlan = p.basePlan.copy()
+	np.basePhysicalPlan = newBasePhysicalPlan(np.basePlan)
+	return &np
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (p *PhysicalIndexMergeReader) MarshalJSON() ([]byte, error) {
+	partialIDs := make([]string, 0, len(p.PartialPlans))
+	for _, partial := range p.PartialPlans {
+		partialIDs = append(partialIDs, partial.ID())
+	}
+	partialPlans, err := json.Marshal(partialIDs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	buffer := bytes.NewBufferString("{")
+	buffer.WriteString(fmt.Sprintf(""+
+		" \"intersection\": %v,\n"+
+		" \"partialPlans\": %s,\n"+
+		" \"tablePlan\": \"%s\"\n}", p.Intersection, partialPlans, p.TablePlan.ID()))
+	return buffer.Bytes(), nil
+}
+
 // PhysicalIndexScan represents an index scan plan.
 type PhysicalIndexScan struct {
 	physicalTableSource
@@ -228,6 +268,9 @@ type physicalTableSource struct {
 
 	// filterCondition is only used by new planner.
 	filterCondition []expression.Expression
+
+	// AsOfTS is the timestamp of AS OF TIMESTAMP, the data is read at the start ts of the transaction if it's 0.
+	AsOfTS uint64
 }
 
 // MarshalJSON implements json.Marshaler interface.
@@ -301,7 +344,8 @@ func needValue(af expression.AggregationFunction) bool {
 }
 
 func (p *physicalTableSource) tryToAddUnionScan(resultPlan PhysicalPlan) PhysicalPlan {
-	if p.readOnly {
+	// The data read AS OF a timestamp doesn't include the changes in the transaction.
+	if p.readOnly || p.AsOfTS != 0 {
 		return resultPlan
 	}
 	conditions := append(p.indexFilterConditions, p.tableFilterConditions...)
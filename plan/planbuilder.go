@@ -15,15 +15,20 @@ package plan
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/juju/errors"
+	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/bindinfo"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/parser/opcode"
+	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/types"
@@ -63,6 +68,14 @@ type visitInfo struct {
 	column    string
 }
 
+// maskingInfo records the masking policies which a table is read with, a plan built with them
+// can't be used by the users with other policies.
+type maskingInfo struct {
+	db            string
+	table         string
+	maskedColumns string
+}
+
 type tableHintInfo struct {
 	INLJTables          []model.CIStr
 	sortMergeJoinTables []model.CIStr
@@ -114,10 +127,16 @@ type planBuilder struct {
 	is           infoschema.InfoSchema
 	outerSchemas []*expression.Schema
 	inUpdateStmt bool
+	// dmlTargets are the tables written by UPDATE and DELETE, which are read without masking policies because
+	// the executors write their rows back.
+	dmlTargets []*ast.TableName
+	// snapshotSchemas are the schemas of the tables read AS OF timestamps.
+	snapshotSchemas map[uint64]infoschema.InfoSchema
 	// colMapper stores the column that must be pre-resolved.
 	colMapper map[*ast.ColumnNameExpr]int
 	// Collect the visit information for privilege check.
 	visitInfo     []visitInfo
+	maskingInfo   []maskingInfo
 	tableHintInfo []tableHintInfo
 	optFlag       uint64
 }
@@ -142,6 +161,9 @@ func (b *planBuilder) build(node ast.Node) Plan {
 	case *ast.PrepareStmt:
 		return b.buildPrepare(x)
 	case *ast.SelectStmt:
+		if restore := b.applyBinding(x); restore != nil {
+			defer restore()
+		}
 		return b.buildSelect(x)
 	case *ast.UnionStmt:
 		return b.buildUnion(x)
@@ -157,7 +179,9 @@ func (b *planBuilder) build(node ast.Node) Plan {
 		return b.buildAnalyze(x)
 	case *ast.BinlogStmt, *ast.FlushStmt, *ast.UseStmt,
 		*ast.BeginStmt, *ast.CommitStmt, *ast.RollbackStmt, *ast.CreateUserStmt, *ast.SetPwdStmt,
-		*ast.GrantStmt, *ast.DropUserStmt, *ast.AlterUserStmt, *ast.RevokeStmt, *ast.KillStmt:
+		*ast.GrantStmt, *ast.DropUserStmt, *ast.AlterUserStmt, *ast.RevokeStmt, *ast.KillStmt,
+		*ast.CreateBindingStmt, *ast.DropBindingStmt, *ast.GrantRoleStmt, *ast.RevokeRoleStmt,
+		*ast.SetRoleStmt, *ast.SetDefaultRoleStmt, *ast.CreateMaskingPolicyStmt, *ast.DropMaskingPolicyStmt:
 		return b.buildSimple(node.(ast.StmtNode))
 	case ast.DDLNode:
 		return b.buildDDL(x)
@@ -166,6 +190,42 @@ func (b *planBuilder) build(node ast.Node) Plan {
 	return nil
 }
 
+// applyBinding applies the hints of the SQL binding matching the statement, session
+// bindings take precedence over global bindings. It returns a function restoring the
+// hints of the statement, or nil if no binding is applied.
+func (b *planBuilder) applyBinding(sel *ast.SelectStmt) func() {
+	record := matchBinding(b.ctx, sel)
+	if record == nil {
+		return nil
+	}
+	restore, err := bindinfo.ApplyHints(sel, record.HintedSel)
+	if err != nil {
+		// The tables may have been changed after the binding is created, just ignore it.
+		log.Warnf("[%d] apply binding %s error %v", b.ctx.GetSessionVars().ConnectionID, record.BindSQL, err)
+		return nil
+	}
+	return restore
+}
+
+// matchBinding returns the SQL binding matching the statement, nil if there is none.
+func matchBinding(ctx context.Context, sel *ast.SelectStmt) *bindinfo.BindRecord {
+	// Only the top level statement has text.
+	if sel.Text() == "" {
+		return nil
+	}
+	normalizedSQL := parser.Normalize(sel.Text())
+	db := ctx.GetSessionVars().CurrentDB
+	if h := bindinfo.GetSessionHandle(ctx); h != nil {
+		if record := h.Get(normalizedSQL, db); record != nil {
+			return record
+		}
+	}
+	if do := sessionctx.GetDomain(ctx); do != nil && do.BindHandle() != nil {
+		return do.BindHandle().Get(normalizedSQL, db)
+	}
+	return nil
+}
+
 func (b *planBuilder) buildExecute(v *ast.ExecuteStmt) Plan {
 	vars := make([]expression.Expression, 0, len(v.UsingVars))
 	for _, expr := range v.UsingVars {
@@ -221,6 +281,9 @@ func (b *planBuilder) buildSet(v *ast.SetStmt) Plan {
 				RetType: &vars.ExtendValue.Type,
 			}
 		}
+		if vars.IsGlobal {
+			b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SuperPriv, "", "", "")
+		}
 		p.VarAssigns = append(p.VarAssigns, assign)
 	}
 	p.SetSchema(expression.NewSchema())
@@ -358,6 +421,7 @@ func (b *planBuilder) buildAdmin(as *ast.AdminStmt) Plan {
 	default:
 		b.err = ErrUnsupportedType.Gen("Unsupported type %T", as)
 	}
+	b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SuperPriv, "", "", "")
 	return p
 }
 
@@ -405,6 +469,8 @@ func getColsInfo(tn *ast.TableName) (indicesInfo []*model.IndexInfo, colsInfo []
 func (b *planBuilder) buildAnalyze(as *ast.AnalyzeTableStmt) LogicalPlan {
 	p := Analyze{}.init(b.allocator, b.ctx)
 	for _, tbl := range as.TableNames {
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SelectPriv, tbl.Schema.L, tbl.Name.L, "")
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.InsertPriv, tbl.Schema.L, tbl.Name.L, "")
 		idxInfo, colInfo, pkInfo := getColsInfo(tbl)
 		result := Analyze{
 			TableInfo:   tbl.TableInfo,
@@ -485,7 +551,15 @@ func (b *planBuilder) buildShow(show *ast.ShowStmt) Plan {
 		Flag:   show.Flag,
 		Full:   show.Full,
 		User:   show.User,
+		Roles:  show.Roles,
 	}.init(b.allocator, b.ctx)
+	if show.Tp == ast.ShowBindings {
+		p.GlobalScope = show.GlobalScope
+	}
+	if show.Tp == ast.ShowGrants && show.User != "" && !b.isCurrentUser(show.User) {
+		// Showing the grants of other users needs to read the mysql database.
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SelectPriv, mysql.SystemDB, "", "")
+	}
 	resultPlan = p
 	switch show.Tp {
 	case ast.ShowProcedureStatus:
@@ -536,16 +610,52 @@ func (b *planBuilder) buildSimple(node ast.StmtNode) Plan {
 	p.SetSchema(expression.NewSchema())
 
 	switch raw := node.(type) {
-	case *ast.CreateUserStmt, *ast.DropUserStmt, *ast.AlterUserStmt:
+	case *ast.CreateUserStmt, *ast.DropUserStmt, *ast.SetDefaultRoleStmt:
 		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.CreateUserPriv, "", "", "")
+	case *ast.AlterUserStmt:
+		// Any user can change its own password, e.g. when the password is expired.
+		if raw.CurrentAuth == nil {
+			b.visitInfo = appendVisitInfo(b.visitInfo, mysql.CreateUserPriv, "", "", "")
+		}
 	case *ast.GrantStmt:
 		b.visitInfo = collectVisitInfoFromGrantStmt(b.visitInfo, raw)
-	case *ast.SetPwdStmt, *ast.RevokeStmt, *ast.KillStmt:
+	case *ast.SetPwdStmt:
+		if raw.User != "" {
+			b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SuperPriv, "", "", "")
+		}
+	case *ast.RevokeStmt, *ast.GrantRoleStmt, *ast.RevokeRoleStmt, *ast.BinlogStmt,
+		*ast.CreateMaskingPolicyStmt, *ast.DropMaskingPolicyStmt:
 		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SuperPriv, "", "", "")
+	case *ast.FlushStmt:
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.ReloadPriv, "", "", "")
+	case *ast.KillStmt:
+		// KILL is checked when it's executed, any user can kill its own connections.
+	case *ast.CreateBindingStmt:
+		if raw.GlobalScope {
+			b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SuperPriv, "", "", "")
+		}
+	case *ast.DropBindingStmt:
+		if raw.GlobalScope {
+			b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SuperPriv, "", "", "")
+		}
 	}
 	return p
 }
 
+// isCurrentUser returns true if userHost, which is like "user@host", is the account which the current user
+// is authenticated as. The accounts with the same name and different hosts are different users.
+func (b *planBuilder) isCurrentUser(userHost string) bool {
+	vars := b.ctx.GetSessionVars()
+	if vars.User == "" {
+		return false
+	}
+	strs := strings.Split(userHost, "@")
+	if len(strs) != 2 {
+		return false
+	}
+	return strings.Split(vars.User, "@")[0] == strs[0] && strings.EqualFold(vars.AuthHost, strs[1])
+}
+
 func collectVisitInfoFromGrantStmt(vi []visitInfo, stmt *ast.GrantStmt) []visitInfo {
 	// To use GRANT, you must have the GRANT OPTION privilege,
 	// and you must have the privileges that you are granting.
@@ -627,6 +737,13 @@ func (b *planBuilder) buildInsert(insert *ast.InsertStmt) Plan {
 		db:        tn.DBInfo.Name.L,
 		table:     tableInfo.Name.L,
 	})
+	// REPLACE deletes the conflicted rows, and ON DUPLICATE KEY UPDATE updates them.
+	if insert.IsReplace {
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.DeletePriv, tn.DBInfo.Name.L, tableInfo.Name.L, "")
+	}
+	if len(insert.OnDuplicate) > 0 {
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.UpdatePriv, tn.DBInfo.Name.L, tableInfo.Name.L, "")
+	}
 
 	cols := table.Cols()
 	for _, valuesItem := range insert.Lists {
@@ -718,6 +835,11 @@ func (b *planBuilder) buildLoadData(ld *ast.LoadDataStmt) Plan {
 		FieldsInfo: ld.FieldsInfo,
 		LinesInfo:  ld.LinesInfo,
 	}
+	b.visitInfo = appendVisitInfo(b.visitInfo, mysql.InsertPriv, ld.Table.Schema.L, ld.Table.Name.L, "")
+	// Reading the files on the server host needs the FILE privilege.
+	if !ld.IsLocal {
+		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.FilePriv, "", "", "")
+	}
 	p.SetSchema(expression.NewSchema())
 	return p
 }
@@ -775,7 +897,7 @@ func (b *planBuilder) buildDDL(node ast.DDLNode) Plan {
 		}
 	case *ast.TruncateTableStmt:
 		b.visitInfo = append(b.visitInfo, visitInfo{
-			privilege: mysql.DeletePriv,
+			privilege: mysql.DropPriv,
 			db:        v.Table.Schema.L,
 			table:     v.Table.Name.L,
 		})
@@ -786,7 +908,17 @@ func (b *planBuilder) buildDDL(node ast.DDLNode) Plan {
 			table:     v.OldTable.Name.L,
 		})
 		b.visitInfo = append(b.visitInfo, visitInfo{
-			privilege: mysql.AlterPriv,
+			privilege: mysql.DropPriv,
+			db:        v.OldTable.Schema.L,
+			table:     v.OldTable.Name.L,
+		})
+		b.visitInfo = append(b.visitInfo, visitInfo{
+			privilege: mysql.CreatePriv,
+			db:        v.NewTable.Schema.L,
+			table:     v.NewTable.Name.L,
+		})
+		b.visitInfo = append(b.visitInfo, visitInfo{
+			privilege: mysql.InsertPriv,
 			db:        v.NewTable.Schema.L,
 			table:     v.NewTable.Name.L,
 		})
@@ -967,6 +1099,10 @@ func buildShowSchema(s *ast.ShowStmt) (schema *expression.Schema) {
 		ftypes = []byte{mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeVarchar, mysql.TypeLonglong,
 			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeLonglong, mysql.TypeLonglong,
 			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar}
+	case ast.ShowBindings:
+		names = []string{"Original_sql", "Bind_sql", "Default_db", "Charset", "Collation", "Create_time"}
+		ftypes = []byte{mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeVarchar,
+			mysql.TypeVarchar, mysql.TypeVarchar, mysql.TypeDatetime}
 	case ast.ShowProcessList:
 		names = []string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info"}
 		ftypes = []byte{mysql.TypeLonglong, mysql.TypeVarchar, mysql.TypeVarchar,
@@ -87,9 +87,10 @@ type Show struct {
 	Column *ast.ColumnName // Used for `desc table column`.
 	Flag   int             // Some flag parsed from sql, such as FULL.
 	Full   bool
-	User   string // Used for show grants.
+	User   string   // Used for show grants.
+	Roles  []string // Used for show grants using.
 
-	// Used by show variables
+	// Used by show variables and show bindings.
 	GlobalScope bool
 }
 
@@ -65,15 +65,21 @@ func (p *LogicalJoin) PredicatePushDown(predicates []expression.Expression) (ret
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
-	groups, valid := tryToGetJoinGroup(p)
+	groups, joins, valid := tryToGetJoinGroup(p)
 	if valid {
+		conds := append(joinGroupConditions(joins), predicates...)
 		e := joinReOrderSolver{allocator: p.allocator, ctx: p.ctx}
-		e.reorderJoin(groups, predicates)
-		newJoin := e.resultJoin
-		parent := p.parents[0]
-		newJoin.SetParents(parent)
-		parent.ReplaceChild(p, newJoin)
-		return newJoin.PredicatePushDown(predicates)
+		if e.reorderJoin(groups, conds) {
+			newJoin := e.resultJoin
+			parent := p.parents[0]
+			newJoin.SetParents(parent)
+			parent.ReplaceChild(p, newJoin)
+			return newJoin.PredicatePushDown(conds)
+		}
+		// Keep the original order, and don't try to reorder the sub groups again.
+		for _, join := range joins {
+			join.reordered = true
+		}
 	}
 	var leftCond, rightCond []expression.Expression
 	retPlan = p
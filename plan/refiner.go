@@ -409,7 +409,8 @@ func (c *conditionChecker) checkLikeFunc(scalar *expression.ScalarFunction) bool
 		return false
 	}
 	pattern, ok := scalar.GetArgs()[1].(*expression.Constant)
-	if !ok {
+	// Whether the pattern can build a range depends on its value, which is unknown for a parameter.
+	if !ok || pattern.DeferredParam != nil {
 		return false
 	}
 	if pattern.Value.IsNull() {
@@ -57,6 +57,8 @@ type nameResolver struct {
 	DefaultSchema   model.CIStr
 	Err             error
 	useOuterContext bool
+	// snapshotSchemas are the schemas of the tables read AS OF timestamps.
+	snapshotSchemas map[uint64]infoschema.InfoSchema
 
 	contextStack []*resolverContext
 }
@@ -147,6 +149,9 @@ func (nr *nameResolver) Enter(inNode ast.Node) (outNode ast.Node, skipChildren b
 	switch v := inNode.(type) {
 	case *ast.AdminStmt:
 		nr.pushContext()
+	case *ast.AsOfClause:
+		// The timestamp is evaluated without any table.
+		return inNode, true
 	case *ast.AggregateFuncExpr:
 		ctx := nr.currentContext()
 		if ctx.inHaving {
@@ -160,7 +165,7 @@ func (nr *nameResolver) Enter(inNode ast.Node) (outNode ast.Node, skipChildren b
 				break
 			}
 		}
-	case *ast.AnalyzeTableStmt:
+	case *ast.AnalyzeTableStmt, *ast.CreateMaskingPolicyStmt:
 		nr.pushContext()
 	case *ast.ByItem:
 		if _, ok := v.Expr.(*ast.ColumnNameExpr); !ok {
@@ -248,7 +253,7 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 		}
 	case *ast.AlterTableStmt:
 		nr.popContext()
-	case *ast.AnalyzeTableStmt:
+	case *ast.AnalyzeTableStmt, *ast.CreateMaskingPolicyStmt:
 		nr.popContext()
 	case *ast.TableName:
 		nr.handleTableName(v)
@@ -338,6 +343,34 @@ func (nr *nameResolver) Leave(inNode ast.Node) (node ast.Node, ok bool) {
 	return inNode, nr.Err == nil
 }
 
+// asOfInfoSchema evaluates the AS OF TIMESTAMP clause of tn, and returns the schema at the timestamp.
+// The timestamp with parameter markers is unknown until the statement is executed, the current schema
+// is returned for it. The planner evaluates the timestamp again in each execution.
+func (nr *nameResolver) asOfInfoSchema(tn *ast.TableName) (infoschema.InfoSchema, error) {
+	if infoschema.IsMemoryDB(tn.Schema.L) {
+		return nil, ErrStaleReadUnsupported.GenByArgs("memory table " + tn.Name.O)
+	}
+	if hasParamMarker(tn.AsOf.TsExpr) {
+		return nr.Info, nil
+	}
+	ts, err := AsOfTS(nr.Ctx, tn.AsOf)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if is, ok := nr.snapshotSchemas[ts]; ok {
+		return is, nil
+	}
+	is, err := snapshotInfoSchema(nr.Ctx, ts)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if nr.snapshotSchemas == nil {
+		nr.snapshotSchemas = make(map[uint64]infoschema.InfoSchema)
+	}
+	nr.snapshotSchemas[ts] = is
+	return is, nil
+}
+
 // handleTableName looks up and sets the schema information and result fields for table name.
 func (nr *nameResolver) handleTableName(tn *ast.TableName) {
 	if tn.Schema.L == "" {
@@ -362,13 +395,22 @@ func (nr *nameResolver) handleTableName(tn *ast.TableName) {
 		tn.SetResultFields(tableName.GetResultFields())
 		return
 	}
-	table, err := nr.Info.TableByName(tn.Schema, tn.Name)
+	info := nr.Info
+	if tn.AsOf != nil {
+		var err error
+		info, err = nr.asOfInfoSchema(tn)
+		if err != nil {
+			nr.Err = errors.Trace(err)
+			return
+		}
+	}
+	table, err := info.TableByName(tn.Schema, tn.Name)
 	if err != nil {
 		nr.Err = errors.Trace(err)
 		return
 	}
 	tn.TableInfo = table.Meta()
-	dbInfo, _ := nr.Info.SchemaByName(tn.Schema)
+	dbInfo, _ := info.SchemaByName(tn.Schema)
 	tn.DBInfo = dbInfo
 
 	rfs := make([]*ast.ResultField, 0, len(tn.TableInfo.Columns))
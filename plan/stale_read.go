@@ -0,0 +1,178 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	"github.com/pingcap/tidb/util/gcutil"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// AsOfTS evaluates the timestamp of the AS OF TIMESTAMP clause. The timestamp can't be in the future,
+// and the data at the timestamp must not be collected by GC.
+// The GC safe point is only checked here, it may still pass the timestamp before the data is read, e.g.
+// localstore drops the versions older than 20 seconds continuously, so a timestamp close to the safe point
+// may read incomplete data.
+func AsOfTS(ctx context.Context, asOf *ast.AsOfClause) (uint64, error) {
+	v, err := evalAstExpr(asOf.TsExpr, ctx)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if v.IsNull() {
+		return 0, ErrInvalidAsOfTS.GenByArgs("NULL")
+	}
+	sessVars := ctx.GetSessionVars()
+	ft := types.NewFieldType(mysql.TypeDatetime)
+	ft.Decimal = types.MaxFsp
+	v, err = v.ConvertTo(sessVars.StmtCtx, ft)
+	if err != nil {
+		return 0, ErrInvalidAsOfTS.GenByArgs(err.Error())
+	}
+	loc := sessVars.TimeZone
+	if loc == nil {
+		loc = time.Local
+	}
+	t, err := v.GetMysqlTime().Time.GoTime(loc)
+	if err != nil {
+		return 0, ErrInvalidAsOfTS.GenByArgs(err.Error())
+	}
+	if t.After(time.Now()) {
+		return 0, ErrInvalidAsOfTS.GenByArgs("can't read data in the future")
+	}
+	ts := oracle.ComposeTS(oracle.GetPhysical(t), 0)
+
+	dom := sessionctx.GetDomain(ctx)
+	if dom == nil {
+		return 0, errors.New("domain is not set")
+	}
+	safePoint, err := gcutil.GetGCSafePoint(ctx, dom.Store())
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if ts < safePoint {
+		physical := oracle.ExtractPhysical(safePoint) * int64(time.Millisecond)
+		return 0, ErrSnapshotTooOld.GenByArgs(time.Unix(0, physical).Format(gcutil.TimeFormat))
+	}
+	return ts, nil
+}
+
+// snapshotInfoSchema returns the schema at ts.
+func snapshotInfoSchema(ctx context.Context, ts uint64) (infoschema.InfoSchema, error) {
+	dom := sessionctx.GetDomain(ctx)
+	if dom == nil {
+		return nil, errors.New("domain is not set")
+	}
+	is, err := dom.GetSnapshotInfoSchema(ts)
+	return is, errors.Trace(err)
+}
+
+// asOfTableInfo evaluates the AS OF TIMESTAMP clause of tn, and returns the table at the timestamp.
+// It's called whenever the plan is built, so a prepared statement reads the data at the timestamp of
+// each execution, and the GC safe point is checked again.
+func (b *planBuilder) asOfTableInfo(tn *ast.TableName, schemaName model.CIStr) (*model.TableInfo, uint64, error) {
+	ts, err := AsOfTS(b.ctx, tn.AsOf)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	is, ok := b.snapshotSchemas[ts]
+	if !ok {
+		is, err = snapshotInfoSchema(b.ctx, ts)
+		if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+		if b.snapshotSchemas == nil {
+			b.snapshotSchemas = make(map[uint64]infoschema.InfoSchema)
+		}
+		b.snapshotSchemas[ts] = is
+	}
+	tbl, err := is.TableByName(schemaName, tn.Name)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	// The names in the statement are resolved with the columns of tn.TableInfo, which may be a table
+	// in another schema if the timestamp changes after the statement is prepared.
+	if !sameColumns(tbl.Meta(), tn.TableInfo) {
+		return nil, 0, ErrInvalidAsOfTS.GenByArgs(fmt.Sprintf("the columns of table %s at the timestamp are changed", tn.Name.O))
+	}
+	return tbl.Meta(), ts, nil
+}
+
+// sameColumns checks whether the tables have the same columns.
+func sameColumns(a, b *model.TableInfo) bool {
+	if len(a.Columns) != len(b.Columns) {
+		return false
+	}
+	for i, col := range a.Columns {
+		other := b.Columns[i]
+		if col.ID != other.ID || col.Name.L != other.Name.L || col.State != other.State || col.FieldType.String() != other.FieldType.String() {
+			return false
+		}
+	}
+	return true
+}
+
+// hasParamMarker checks whether the expression has parameter markers.
+func hasParamMarker(expr ast.ExprNode) bool {
+	var finder paramMarkerFinder
+	expr.Accept(&finder)
+	return finder.found
+}
+
+// paramMarkerFinder finds the parameter markers of an expression.
+type paramMarkerFinder struct {
+	found bool
+}
+
+// Enter implements Visitor interface.
+func (f *paramMarkerFinder) Enter(in ast.Node) (out ast.Node, skipChildren bool) {
+	if _, ok := in.(*ast.ParamMarkerExpr); ok {
+		f.found = true
+	}
+	return in, f.found
+}
+
+// Leave implements Visitor interface.
+func (f *paramMarkerFinder) Leave(in ast.Node) (out ast.Node, ok bool) {
+	return in, true
+}
+
+// checkStaleReadLock checks that SELECT FOR UPDATE doesn't read data AS OF a timestamp, the rows can't be locked
+// at an old timestamp.
+func checkStaleReadLock(ctx context.Context, sel *ast.SelectStmt) error {
+	if sel.LockTp != ast.SelectLockForUpdate {
+		return nil
+	}
+	if ctx.GetSessionVars().TxnCtx.StaleReadTS != 0 {
+		return ErrStaleReadUnsupported.GenByArgs("SELECT FOR UPDATE")
+	}
+	if sel.From == nil {
+		return nil
+	}
+	for _, tn := range extractTableList(sel.From.TableRefs, nil) {
+		if tn.AsOf != nil {
+			return ErrStaleReadUnsupported.GenByArgs("SELECT FOR UPDATE")
+		}
+	}
+	return nil
+}
@@ -161,6 +161,16 @@ func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 		str = fmt.Sprintf("IndexReader(%s)", ToString(x.copPlan))
 	case *PhysicalIndexLookUpReader:
 		str = fmt.Sprintf("IndexLookUp(%s, %s)", ToString(x.indexPlan), ToString(x.tablePlan))
+	case *PhysicalIndexMergeReader:
+		mergeType := "union"
+		if x.Intersection {
+			mergeType = "intersection"
+		}
+		partials := make([]string, 0, len(x.PartialPlans))
+		for _, partial := range x.PartialPlans {
+			partials = append(partials, ToString(partial))
+		}
+		str = fmt.Sprintf("IndexMerge(%s{%s}, %s)", mergeType, strings.Join(partials, ", "), ToString(x.TablePlan))
 	default:
 		str = fmt.Sprintf("%T", in)
 	}
@@ -38,6 +38,10 @@ type copTaskProfile struct {
 	cnt       float64
 	// indexPlanFinished means we have finished index plan.
 	indexPlanFinished bool
+	// partialPlans are the index scans of index merge, the table plan is looked up by their merged handles.
+	partialPlans []PhysicalPlan
+	// intersection means the handles of partialPlans are intersected, otherwise they are unioned.
+	intersection bool
 }
 
 func (t *copTaskProfile) setCount(cnt float64) {
@@ -96,8 +100,29 @@ func (t *copTaskProfile) finishIndexPlan() {
 	}
 }
 
+// finishedCost returns the cost of the task after it is finished, so that it can be compared with index merge. The
+// cost of index merge includes its table lookups, so they are added for the double read here as well.
+func (t *copTaskProfile) finishedCost() float64 {
+	cst := t.cst
+	if !t.indexPlanFinished {
+		cst += t.cnt * (netWorkFactor + scanFactor)
+	}
+	if t.tablePlan != nil {
+		cst += t.cnt * netWorkFactor
+		if len(t.partialPlans) == 0 {
+			cst += t.cnt * lookupFactor
+		}
+	}
+	return cst
+}
+
 func (p *basePhysicalPlan) attach2TaskProfile(tasks ...taskProfile) taskProfile {
-	return attachPlan2TaskProfile(p.basePlan.self.(PhysicalPlan).Copy(), tasks[0])
+	task := tasks[0]
+	if cop, ok := task.(*copTaskProfile); ok && len(cop.partialPlans) > 0 {
+		// Only the selection can be pushed to the table lookup of index merge.
+		task = cop.copy().(*copTaskProfile).finishTask(p.basePlan.ctx, p.basePlan.allocator)
+	}
+	return attachPlan2TaskProfile(p.basePlan.self.(PhysicalPlan).Copy(), task)
 }
 
 // finishTask means we close the coprocessor task and create a root task.
@@ -112,7 +137,10 @@ func (t *copTaskProfile) finishTask(ctx context.Context, allocator *idAllocator)
 		cst: t.cst,
 		cnt: t.cnt,
 	}
-	if t.indexPlan != nil && t.tablePlan != nil {
+	if len(t.partialPlans) > 0 {
+		newTask.p = PhysicalIndexMergeReader{PartialPlans: t.partialPlans, TablePlan: t.tablePlan, Intersection: t.intersection}.init(allocator, ctx)
+		newTask.p.SetSchema(t.tablePlan.Schema())
+	} else if t.indexPlan != nil && t.tablePlan != nil {
 		newTask.p = PhysicalIndexLookUpReader{tablePlan: t.tablePlan, indexPlan: t.indexPlan}.init(allocator, ctx)
 		newTask.p.SetSchema(t.tablePlan.Schema())
 	} else if t.indexPlan != nil {
@@ -162,7 +190,10 @@ func (t *rootTaskProfile) plan() PhysicalPlan {
 
 func (p *Limit) attach2TaskProfile(profiles ...taskProfile) taskProfile {
 	profile := profiles[0].copy()
-	if cop, ok := profile.(*copTaskProfile); ok {
+	if cop, ok := profile.(*copTaskProfile); ok && len(cop.partialPlans) > 0 {
+		// The table lookup of index merge doesn't support limit.
+		profile = cop.finishTask(p.ctx, p.allocator)
+	} else if ok {
 		// If the task is copTask, the Limit can always be pushed down.
 		// When limit be pushed down, it should remove its offset.
 		pushedDownLimit := Limit{Count: p.Offset + p.Count}.init(p.allocator, p.ctx)
@@ -220,7 +251,7 @@ func (p *Sort) attach2TaskProfile(profiles ...taskProfile) taskProfile {
 		return profile
 	}
 	// This is a topN plan.
-	if copTask, ok := profile.(*copTaskProfile); ok && p.canPushDown() {
+	if copTask, ok := profile.(*copTaskProfile); ok && len(copTask.partialPlans) == 0 && p.canPushDown() {
 		limit := p.ExecLimit
 		pushedDownTopN := p.Copy().(*Sort)
 		// When topN is pushed down, it should remove its offset.
@@ -0,0 +1,103 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package privilege
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// MaskingPolicy replaces a column by a data masking function in the queries of the users
+// who aren't exempted from it.
+type MaskingPolicy struct {
+	Name   string
+	DB     string
+	Table  string
+	Column string
+	// FuncName is the data masking function, its first argument is the column and the rest are Args.
+	FuncName string
+	Args     []types.Datum
+	// ExemptRoles are the accounts and roles in "user@host" format which see the real values.
+	ExemptRoles []string
+}
+
+var maskingFuncs = map[string]struct{ minArgs, maxArgs int }{
+	ast.MaskEmail: {1, 1},
+	ast.MaskInner: {3, 4},
+	ast.MaskNull:  {1, 1},
+	ast.MaskOuter: {3, 4},
+}
+
+// CheckMaskExpr checks that expr is a data masking function whose first argument is column and the
+// other arguments are integer or string constants, it returns the function name and the constants.
+func CheckMaskExpr(expr ast.ExprNode, column string) (string, []types.Datum, error) {
+	fn, ok := expr.(*ast.FuncCallExpr)
+	if !ok {
+		return "", nil, errors.New("the masking expression must be a data masking function")
+	}
+	arity, ok := maskingFuncs[fn.FnName.L]
+	if !ok {
+		return "", nil, errors.Errorf("%s is not a data masking function", fn.FnName.O)
+	}
+	if len(fn.Args) < arity.minArgs || len(fn.Args) > arity.maxArgs {
+		return "", nil, errors.Errorf("incorrect parameter count in the call to %s", fn.FnName.O)
+	}
+	col, ok := fn.Args[0].(*ast.ColumnNameExpr)
+	if !ok || col.Name.Table.L != "" || !strings.EqualFold(col.Name.Name.O, column) {
+		return "", nil, errors.Errorf("the first argument of %s must be the masked column %s", fn.FnName.O, column)
+	}
+	args := make([]types.Datum, 0, len(fn.Args)-1)
+	for _, arg := range fn.Args[1:] {
+		v, ok := arg.(*ast.ValueExpr)
+		if !ok {
+			return "", nil, errors.Errorf("the arguments of %s except the column must be constants", fn.FnName.O)
+		}
+		switch v.Kind() {
+		case types.KindInt64, types.KindUint64, types.KindString:
+		default:
+			return "", nil, errors.Errorf("the arguments of %s except the column must be integers or strings", fn.FnName.O)
+		}
+		args = append(args, v.Datum)
+	}
+	return fn.FnName.L, args, nil
+}
+
+// MaskExpr returns the masking expression in SQL, CheckMaskExpr accepts it after it's parsed.
+func (p *MaskingPolicy) MaskExpr() string {
+	args := []string{quoteIdent(p.Column)}
+	for _, d := range p.Args {
+		switch d.Kind() {
+		case types.KindInt64:
+			args = append(args, strconv.FormatInt(d.GetInt64(), 10))
+		case types.KindUint64:
+			args = append(args, strconv.FormatUint(d.GetUint64(), 10))
+		default:
+			args = append(args, quoteString(d.GetString()))
+		}
+	}
+	return p.FuncName + "(" + strings.Join(args, ", ") + ")"
+}
+
+func quoteIdent(s string) string {
+	return "`" + strings.Replace(s, "`", "``", -1) + "`"
+}
+
+func quoteString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return "'" + strings.Replace(s, "'", `\'`, -1) + "'"
+}
@@ -14,6 +14,8 @@
 package privilege
 
 import (
+	"crypto/tls"
+
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/types"
@@ -27,18 +29,46 @@ func (k keyType) String() string {
 
 // Manager is the interface for providing privilege related operations.
 type Manager interface {
-	// Show granted privileges for user.
-	ShowGrants(ctx context.Context, user string) ([]string, error)
+	// Show granted privileges for user, the privileges of roles are merged into the user's.
+	ShowGrants(ctx context.Context, user string, roles []string) ([]string, error)
 
 	// RequestVerification verifies user privilege for the request.
 	// If table is "", only check global/db scope privileges.
 	// If table is not "", check global/db/table scope privileges.
-	RequestVerification(db, table, column string, priv mysql.PrivilegeType) bool
+	// The privileges of activeRoles and the roles granted to them are checked too.
+	RequestVerification(activeRoles []string, db, table, column string, priv mysql.PrivilegeType) bool
 	// ConnectionVerification verifies user privilege for connection.
-	ConnectionVerification(host, user string, auth, salt []byte) bool
+	// tlsState is the TLS state of the connection, it is nil if the connection is not encrypted.
+	// auth is the scramble of mysql_native_password, or the plaintext password of caching_sha2_password and
+	// sha256_password, which are sent through TLS or encrypted by RSA.
+	ConnectionVerification(host, user string, auth, salt []byte, tlsState *tls.ConnectionState) bool
+
+	// LoginFailed records a failed login of the user, the account is locked temporarily after
+	// FAILED_LOGIN_ATTEMPTS consecutive failed logins.
+	LoginFailed(user, host string)
+
+	// IsPasswordExpired returns true if the password of the user is expired, defaultLifetime is the
+	// default_password_lifetime in days, it's used if the account doesn't have its own lifetime.
+	IsPasswordExpired(user, host string, defaultLifetime int64) bool
+
+	// GetAuthPlugin returns the auth plugin of the user, it returns "" if the user doesn't exist.
+	GetAuthPlugin(user, host string) string
+
+	// GetAuthHost returns the host of the account which the user matches, it returns "" if the user doesn't exist.
+	GetAuthHost(user, host string) string
 
 	// DBIsVisible returns true is the database is visible to current user.
-	DBIsVisible(db string) bool
+	DBIsVisible(activeRoles []string, db string) bool
+
+	// GetDefaultRoles returns the roles activated when the user logs in, the roles are in "user@host" format.
+	GetDefaultRoles(user, host string) []string
+
+	// GetAllRoles returns the roles granted to the user, the roles are in "user@host" format.
+	GetAllRoles(user, host string) []string
+
+	// MaskingPolicies returns the masking policies of the table which neither the user nor
+	// activeRoles and the roles granted to them are exempted from.
+	MaskingPolicies(activeRoles []string, db, table string) []*MaskingPolicy
 
 	// UserPrivilegesTable provide data for INFORMATION_SCHEMA.USERS_PRIVILEGE table.
 	UserPrivilegesTable() [][]types.Datum
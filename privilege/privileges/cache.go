@@ -14,8 +14,11 @@
 package privileges
 
 import (
+	"crypto/tls"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -24,6 +27,8 @@ import (
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/sqlexec"
 	"github.com/pingcap/tidb/util/stringutil"
@@ -50,6 +55,25 @@ type userRecord struct {
 	User       string // max length 16, primary key
 	Password   string // max length 41
 	Privileges mysql.PrivilegeType
+	SSLType    string
+	// AuthPlugin is the auth plugin of the user, the password hash of caching_sha2_password and sha256_password
+	// is stored in AuthenticationString instead of Password.
+	AuthPlugin           string
+	AuthenticationString string
+	// AccountLocked is true for the locked accounts, e.g. the roles, they can't be used to connect.
+	AccountLocked bool
+	// PasswordExpired is true if the password is expired manually by PASSWORD EXPIRE.
+	PasswordExpired bool
+	// PasswordLastChanged is the time when the password was changed, it's zero if unknown.
+	PasswordLastChanged time.Time
+	// PasswordLifetime is the password lifetime in days, 0 means the password never expires,
+	// -1 means default_password_lifetime is used.
+	PasswordLifetime int64
+	// FailedLoginAttempts is the number of consecutive failed logins which lock the account temporarily,
+	// PasswordLockTime is the days the account is locked for, -1 means it's locked until it's unlocked.
+	// The failed login tracking is enabled only if both of them are not 0.
+	FailedLoginAttempts int64
+	PasswordLockTime    int64
 
 	// Compiled from Host, cached for pattern match performance.
 	patChars []byte
@@ -102,6 +126,12 @@ type MySQLPrivilege struct {
 	DB          []dbRecord
 	TablesPriv  []tablesPrivRecord
 	ColumnsPriv []columnsPrivRecord
+	// RoleGraph maps an account to the roles granted to it, the accounts and roles are in "user@host" format.
+	RoleGraph map[string]map[string]bool
+	// DefaultRoles maps an account to the roles activated when it logs in.
+	DefaultRoles map[string][]string
+	// TableMaskingPolicies maps "db.table" in lower case to the masking policies of the table.
+	TableMaskingPolicies map[string][]*privilege.MaskingPolicy
 }
 
 // LoadAll loads the tables from database to memory.
@@ -134,6 +164,30 @@ func (p *MySQLPrivilege) LoadAll(ctx context.Context) error {
 		}
 		log.Warn("mysql.columns_priv missing")
 	}
+
+	err = p.LoadRoleEdgesTable(ctx)
+	if err != nil {
+		if !noSuchTable(err) {
+			return errors.Trace(err)
+		}
+		log.Warn("mysql.role_edges missing")
+	}
+
+	err = p.LoadDefaultRolesTable(ctx)
+	if err != nil {
+		if !noSuchTable(err) {
+			return errors.Trace(err)
+		}
+		log.Warn("mysql.default_roles missing")
+	}
+
+	err = p.LoadMaskingPolicyTable(ctx)
+	if err != nil {
+		if !noSuchTable(err) {
+			return errors.Trace(err)
+		}
+		log.Warn("mysql.masking_policy missing")
+	}
 	return nil
 }
 
@@ -149,7 +203,7 @@ func noSuchTable(err error) bool {
 
 // LoadUserTable loads the mysql.user table from database.
 func (p *MySQLPrivilege) LoadUserTable(ctx context.Context) error {
-	return p.loadTable(ctx, "select Host,User,Password,Select_priv,Insert_priv,Update_priv,Delete_priv,Create_priv,Drop_priv,Grant_priv,Alter_priv,Show_db_priv,Super_priv,Execute_priv,Index_priv,Create_user_priv from mysql.user order by host, user;", p.decodeUserTableRow)
+	return p.loadTable(ctx, "select Host,User,Password,ssl_type,plugin,authentication_string,account_locked,password_expired,password_last_changed,password_lifetime,failed_login_attempts,password_lock_time,Select_priv,Insert_priv,Update_priv,Delete_priv,Create_priv,Drop_priv,Grant_priv,Alter_priv,Show_db_priv,Super_priv,Execute_priv,Index_priv,Create_user_priv,Process_priv,Reload_priv,File_priv from mysql.user order by host, user;", p.decodeUserTableRow)
 }
 
 // LoadDBTable loads the mysql.db table from database.
@@ -167,6 +221,24 @@ func (p *MySQLPrivilege) LoadColumnsPrivTable(ctx context.Context) error {
 	return p.loadTable(ctx, "select Host,DB,User,Table_name,Column_name,Timestamp,Column_priv from mysql.columns_priv", p.decodeColumnsPrivTableRow)
 }
 
+// LoadRoleEdgesTable loads the mysql.role_edges table from database.
+func (p *MySQLPrivilege) LoadRoleEdgesTable(ctx context.Context) error {
+	p.RoleGraph = make(map[string]map[string]bool)
+	return p.loadTable(ctx, "select FROM_HOST,FROM_USER,TO_HOST,TO_USER from mysql.role_edges", p.decodeRoleEdgesTableRow)
+}
+
+// LoadDefaultRolesTable loads the mysql.default_roles table from database.
+func (p *MySQLPrivilege) LoadDefaultRolesTable(ctx context.Context) error {
+	p.DefaultRoles = make(map[string][]string)
+	return p.loadTable(ctx, "select HOST,USER,DEFAULT_ROLE_HOST,DEFAULT_ROLE_USER from mysql.default_roles order by DEFAULT_ROLE_USER, DEFAULT_ROLE_HOST", p.decodeDefaultRolesTableRow)
+}
+
+// LoadMaskingPolicyTable loads the mysql.masking_policy table from database.
+func (p *MySQLPrivilege) LoadMaskingPolicyTable(ctx context.Context) error {
+	p.TableMaskingPolicies = make(map[string][]*privilege.MaskingPolicy)
+	return p.loadTable(ctx, "select name,db,table_name,column_name,mask_expr,exempt_roles from mysql.masking_policy order by name", p.decodeMaskingPolicyTableRow)
+}
+
 func (p *MySQLPrivilege) loadTable(ctx context.Context, sql string,
 	decodeTableRow func(*ast.Row, []*ast.ResultField) error) error {
 	tmp, err := ctx.(sqlexec.SQLExecutor).Execute(sql)
@@ -209,6 +281,33 @@ func (p *MySQLPrivilege) decodeUserTableRow(row *ast.Row, fs []*ast.ResultField)
 			value.patChars, value.patTypes = stringutil.CompilePattern(value.Host, '\\')
 		case f.ColumnAsName.L == "password":
 			value.Password = d.GetString()
+		case f.ColumnAsName.L == "ssl_type":
+			value.SSLType = d.GetMysqlEnum().String()
+		case f.ColumnAsName.L == "plugin":
+			value.AuthPlugin = d.GetString()
+		case f.ColumnAsName.L == "authentication_string":
+			value.AuthenticationString = d.GetString()
+		case f.ColumnAsName.L == "account_locked":
+			value.AccountLocked = d.GetMysqlEnum().String() == "Y"
+		case f.ColumnAsName.L == "password_expired":
+			value.PasswordExpired = d.GetMysqlEnum().String() == "Y"
+		case f.ColumnAsName.L == "password_last_changed":
+			if !d.IsNull() {
+				t, err := d.GetMysqlTime().Time.GoTime(time.Local)
+				if err != nil {
+					return errors.Trace(err)
+				}
+				value.PasswordLastChanged = t
+			}
+		case f.ColumnAsName.L == "password_lifetime":
+			value.PasswordLifetime = -1
+			if !d.IsNull() {
+				value.PasswordLifetime = d.GetInt64()
+			}
+		case f.ColumnAsName.L == "failed_login_attempts":
+			value.FailedLoginAttempts = d.GetInt64()
+		case f.ColumnAsName.L == "password_lock_time":
+			value.PasswordLockTime = d.GetInt64()
 		case d.Kind() == types.KindMysqlEnum:
 			ed := d.GetMysqlEnum()
 			if ed.String() != "Y" {
@@ -303,6 +402,101 @@ func (p *MySQLPrivilege) decodeColumnsPrivTableRow(row *ast.Row, fs []*ast.Resul
 	return nil
 }
 
+func (p *MySQLPrivilege) decodeRoleEdgesTableRow(row *ast.Row, fs []*ast.ResultField) error {
+	var fromUser, fromHost, toUser, toHost string
+	for i, f := range fs {
+		d := row.Data[i]
+		switch f.ColumnAsName.L {
+		case "from_user":
+			fromUser = d.GetString()
+		case "from_host":
+			fromHost = d.GetString()
+		case "to_user":
+			toUser = d.GetString()
+		case "to_host":
+			toHost = d.GetString()
+		}
+	}
+	// The edge is from the role to the account which the role is granted to.
+	account := toUser + "@" + toHost
+	if p.RoleGraph[account] == nil {
+		p.RoleGraph[account] = make(map[string]bool)
+	}
+	p.RoleGraph[account][fromUser+"@"+fromHost] = true
+	return nil
+}
+
+func (p *MySQLPrivilege) decodeDefaultRolesTableRow(row *ast.Row, fs []*ast.ResultField) error {
+	var user, host, roleUser, roleHost string
+	for i, f := range fs {
+		d := row.Data[i]
+		switch f.ColumnAsName.L {
+		case "user":
+			user = d.GetString()
+		case "host":
+			host = d.GetString()
+		case "default_role_user":
+			roleUser = d.GetString()
+		case "default_role_host":
+			roleHost = d.GetString()
+		}
+	}
+	account := user + "@" + host
+	p.DefaultRoles[account] = append(p.DefaultRoles[account], roleUser+"@"+roleHost)
+	return nil
+}
+
+func (p *MySQLPrivilege) decodeMaskingPolicyTableRow(row *ast.Row, fs []*ast.ResultField) error {
+	policy := &privilege.MaskingPolicy{}
+	var maskExpr string
+	for i, f := range fs {
+		d := row.Data[i]
+		switch f.ColumnAsName.L {
+		case "name":
+			policy.Name = d.GetString()
+		case "db":
+			policy.DB = d.GetString()
+		case "table_name":
+			policy.Table = d.GetString()
+		case "column_name":
+			policy.Column = d.GetString()
+		case "mask_expr":
+			maskExpr = d.GetString()
+		case "exempt_roles":
+			if roles := d.GetString(); roles != "" {
+				policy.ExemptRoles = strings.Split(roles, ",")
+			}
+		}
+	}
+	var err error
+	policy.FuncName, policy.Args, err = parseMaskExpr(maskExpr, policy.Column)
+	if err != nil {
+		// The column is hidden rather than revealed if the policy is broken.
+		log.Errorf("invalid mask expression %q of masking policy %s: %v", maskExpr, policy.Name, err)
+		policy.FuncName, policy.Args = ast.MaskNull, nil
+	}
+	key := maskingPolicyKey(policy.DB, policy.Table)
+	p.TableMaskingPolicies[key] = append(p.TableMaskingPolicies[key], policy)
+	return nil
+}
+
+func parseMaskExpr(maskExpr, column string) (string, []types.Datum, error) {
+	stmt, err := parser.New().ParseOneStmt("SELECT "+maskExpr, "", "")
+	if err != nil {
+		return "", nil, errors.Trace(err)
+	}
+	sel, ok := stmt.(*ast.SelectStmt)
+	if !ok || len(sel.Fields.Fields) != 1 || sel.From != nil {
+		return "", nil, errors.New("not an expression")
+	}
+	funcName, args, err := privilege.CheckMaskExpr(sel.Fields.Fields[0].Expr, column)
+	return funcName, args, errors.Trace(err)
+}
+
+func maskingPolicyKey(db, table string) string {
+	return strings.ToLower(db) + "." + strings.ToLower(table)
+}
+
 func decodeSetToPrivilege(s types.Set) mysql.PrivilegeType {
 	var ret mysql.PrivilegeType
 	if s.Name == "" {
@@ -323,6 +517,39 @@ func (record *userRecord) match(user, host string) bool {
 	return record.User == user && patternMatch(host, record.patChars, record.patTypes)
 }
 
+// tlsVerification checks whether the connection meets the TLS requirement of the user.
+func (record *userRecord) tlsVerification(tlsState *tls.ConnectionState) bool {
+	switch record.SSLType {
+	case mysql.SSLTypeAny:
+		return tlsState != nil
+	case mysql.SSLTypeX509:
+		// The client certificate is verified by the CA in the TLS handshake.
+		return tlsState != nil && len(tlsState.VerifiedChains) > 0
+	}
+	return true
+}
+
+// passwordExpired checks whether the password of the user is expired at now, defaultLifetime is the
+// default_password_lifetime in days.
+func (record *userRecord) passwordExpired(defaultLifetime int64, now time.Time) bool {
+	if record.PasswordExpired {
+		return true
+	}
+	lifetime := record.PasswordLifetime
+	if lifetime < 0 {
+		lifetime = defaultLifetime
+	}
+	if lifetime <= 0 || record.PasswordLastChanged.IsZero() {
+		return false
+	}
+	return now.After(record.PasswordLastChanged.Add(time.Duration(lifetime) * 24 * time.Hour))
+}
+
+// account returns the account of the user record in "user@host" format.
+func (record *userRecord) account() string {
+	return record.User + "@" + record.Host
+}
+
 func (record *dbRecord) match(user, host, db string) bool {
 	return record.User == user && strings.EqualFold(record.DB, db) &&
 		patternMatch(host, record.patChars, record.patTypes)
@@ -397,8 +624,129 @@ func (p *MySQLPrivilege) matchColumns(user, host, db, table, column string) *col
 	return nil
 }
 
-// RequestVerification checks whether the user have sufficient privileges to do the operation.
-func (p *MySQLPrivilege) RequestVerification(user, host, db, table, column string, priv mysql.PrivilegeType) bool {
+// accountOf returns the account "user@host" which the user connects as, host of the account may be a pattern.
+func (p *MySQLPrivilege) accountOf(user, host string) string {
+	if record := p.matchUser(user, host); record != nil {
+		return record.User + "@" + record.Host
+	}
+	return user + "@" + host
+}
+
+func (p *MySQLPrivilege) hasAccount(user, host string) bool {
+	for _, record := range p.User {
+		if record.User == user && record.Host == host {
+			return true
+		}
+	}
+	return false
+}
+
+// getAllRoles returns the roles granted to the user in order.
+func (p *MySQLPrivilege) getAllRoles(user, host string) []string {
+	return p.grantedRoles(p.accountOf(user, host))
+}
+
+func (p *MySQLPrivilege) grantedRoles(account string) []string {
+	var roles []string
+	for role := range p.RoleGraph[account] {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// getDefaultRoles returns the default roles of the user which are still granted to it.
+func (p *MySQLPrivilege) getDefaultRoles(user, host string) []string {
+	account := p.accountOf(user, host)
+	var roles []string
+	for _, role := range p.DefaultRoles[account] {
+		if p.RoleGraph[account][role] {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// expandRoles returns roles and the roles granted to them recursively, the roles
+// which aren't granted to account are ignored.
+func (p *MySQLPrivilege) expandRoles(account string, roles []string) []string {
+	visited := make(map[string]bool)
+	var queue []string
+	for _, role := range roles {
+		if p.RoleGraph[account][role] {
+			queue = append(queue, role)
+		}
+	}
+	var expanded []string
+	for len(queue) > 0 {
+		role := queue[0]
+		queue = queue[1:]
+		if visited[role] {
+			continue
+		}
+		visited[role] = true
+		expanded = append(expanded, role)
+		for r := range p.RoleGraph[role] {
+			queue = append(queue, r)
+		}
+	}
+	return expanded
+}
+
+// splitAccount splits "user@host" into user and host.
+func splitAccount(account string) (string, string) {
+	pos := strings.LastIndex(account, "@")
+	if pos < 0 {
+		return account, ""
+	}
+	return account[:pos], account[pos+1:]
+}
+
+// RequestVerification checks whether the user have sufficient privileges to do the operation,
+// the privileges of the active roles are checked too.
+func (p *MySQLPrivilege) RequestVerification(activeRoles []string, user, host, db, table, column string, priv mysql.PrivilegeType) bool {
+	if p.requestVerification(user, host, db, table, column, priv) {
+		return true
+	}
+	for _, role := range p.expandRoles(p.accountOf(user, host), activeRoles) {
+		roleUser, roleHost := splitAccount(role)
+		if p.requestVerification(roleUser, roleHost, db, table, column, priv) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskingPolicies returns the masking policies of the table which the user is not exempted from, the user is
+// exempted if its account, one of the active roles or the roles granted to them is exempted.
+func (p *MySQLPrivilege) MaskingPolicies(activeRoles []string, user, host, db, table string) []*privilege.MaskingPolicy {
+	policies := p.TableMaskingPolicies[maskingPolicyKey(db, table)]
+	if len(policies) == 0 {
+		return nil
+	}
+	account := p.accountOf(user, host)
+	accounts := append([]string{account}, p.expandRoles(account, activeRoles)...)
+	var ret []*privilege.MaskingPolicy
+	for _, policy := range policies {
+		if !isExempted(policy, accounts) {
+			ret = append(ret, policy)
+		}
+	}
+	return ret
+}
+
+func isExempted(policy *privilege.MaskingPolicy, accounts []string) bool {
+	for _, role := range policy.ExemptRoles {
+		for _, account := range accounts {
+			if role == account {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *MySQLPrivilege) requestVerification(user, host, db, table, column string, priv mysql.PrivilegeType) bool {
 	record1 := p.matchUser(user, host)
 	if record1 != nil && record1.Privileges&priv > 0 {
 		return true
@@ -427,8 +775,21 @@ func (p *MySQLPrivilege) RequestVerification(user, host, db, table, column strin
 	return false
 }
 
-// DBIsVisible checks whether the user can see the db.
-func (p *MySQLPrivilege) DBIsVisible(user, host, db string) bool {
+// DBIsVisible checks whether the user or the active roles can see the db.
+func (p *MySQLPrivilege) DBIsVisible(activeRoles []string, user, host, db string) bool {
+	if p.dbIsVisible(user, host, db) {
+		return true
+	}
+	for _, role := range p.expandRoles(p.accountOf(user, host), activeRoles) {
+		roleUser, roleHost := splitAccount(role)
+		if p.dbIsVisible(roleUser, roleHost, db) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *MySQLPrivilege) dbIsVisible(user, host, db string) bool {
 	if record := p.matchUser(user, host); record != nil {
 		if record.Privileges&mysql.ShowDBPriv > 0 {
 			return true
@@ -464,35 +825,86 @@ func (p *MySQLPrivilege) DBIsVisible(user, host, db string) bool {
 	return false
 }
 
-func (p *MySQLPrivilege) showGrants(user, host string) []string {
+// showGrants shows the privileges of the user, the privileges of roles are merged into the user's.
+// If no account is exactly user@host, the account which the user from host connects as is shown.
+func (p *MySQLPrivilege) showGrants(user, host string, roles []string) []string {
+	account := user + "@" + host
+	if !p.hasAccount(user, host) {
+		account = p.accountOf(user, host)
+		user, host = splitAccount(account)
+	}
+	accounts := []string{account}
+	accounts = append(accounts, p.expandRoles(account, roles)...)
+	isGranted := func(recordUser, recordHost string) bool {
+		for _, a := range accounts {
+			if a == recordUser+"@"+recordHost {
+				return true
+			}
+		}
+		return false
+	}
+
 	var gs []string
 	// Show global grants
+	var globalPriv mysql.PrivilegeType
+	var hasGlobal bool
 	for _, record := range p.User {
-		if record.User == user && record.Host == host {
-			g := userPrivToString(record.Privileges)
-			s := fmt.Sprintf(`GRANT %s ON *.* TO '%s'@'%s'`, g, record.User, record.Host)
-			gs = append(gs, s)
-			break // it's unique
+		if isGranted(record.User, record.Host) {
+			globalPriv |= record.Privileges
+			hasGlobal = true
 		}
 	}
+	if hasGlobal {
+		g := userPrivToString(globalPriv)
+		if g == "" {
+			g = "USAGE"
+		}
+		gs = append(gs, fmt.Sprintf(`GRANT %s ON *.* TO '%s'@'%s'`, g, user, host))
+	}
 
 	// Show db scope grants
+	var dbs []string
+	dbPrivs := make(map[string]mysql.PrivilegeType)
 	for _, record := range p.DB {
-		if record.User == user && record.Host == host {
-			g := dbPrivToString(record.Privileges)
-			s := fmt.Sprintf(`GRANT %s ON %s.* TO '%s'@'%s'`, g, record.DB, record.User, record.Host)
-			gs = append(gs, s)
+		if isGranted(record.User, record.Host) {
+			if _, ok := dbPrivs[record.DB]; !ok {
+				dbs = append(dbs, record.DB)
+			}
+			dbPrivs[record.DB] |= record.Privileges
 		}
 	}
+	for _, db := range dbs {
+		g := dbPrivToString(dbPrivs[db])
+		gs = append(gs, fmt.Sprintf(`GRANT %s ON %s.* TO '%s'@'%s'`, g, db, user, host))
+	}
 
 	// Show table scope grants
+	var tables []string
+	tablePrivs := make(map[string]mysql.PrivilegeType)
 	for _, record := range p.TablesPriv {
-		if record.User == user && record.Host == host {
-			g := tablePrivToString(record.TablePriv)
-			s := fmt.Sprintf(`GRANT %s ON %s.%s TO '%s'@'%s'`, g, record.DB, record.TableName, record.User, record.Host)
-			gs = append(gs, s)
+		if isGranted(record.User, record.Host) {
+			table := record.DB + "." + record.TableName
+			if _, ok := tablePrivs[table]; !ok {
+				tables = append(tables, table)
+			}
+			tablePrivs[table] |= record.TablePriv
 		}
 	}
+	for _, table := range tables {
+		g := tablePrivToString(tablePrivs[table])
+		gs = append(gs, fmt.Sprintf(`GRANT %s ON %s TO '%s'@'%s'`, g, table, user, host))
+	}
+
+	// Show the roles granted to the user
+	grantedRoles := p.grantedRoles(account)
+	if len(grantedRoles) > 0 {
+		quoted := make([]string, 0, len(grantedRoles))
+		for _, role := range grantedRoles {
+			roleUser, roleHost := splitAccount(role)
+			quoted = append(quoted, fmt.Sprintf(`'%s'@'%s'`, roleUser, roleHost))
+		}
+		gs = append(gs, fmt.Sprintf(`GRANT %s TO '%s'@'%s'`, strings.Join(quoted, ","), user, host))
+	}
 	return gs
 }
 
@@ -568,13 +980,78 @@ func appendUserPrivilegesTableRow(rows [][]types.Datum, user userRecord) [][]typ
 type Handle struct {
 	ctx  context.Context
 	priv atomic.Value
+
+	// loginFailures tracks the consecutive failed logins of the accounts with FAILED_LOGIN_ATTEMPTS
+	// and PASSWORD_LOCK_TIME, the key is the account in "user@host" format. Like MySQL, it's kept
+	// in memory and isn't reset by the privilege reload, only by FLUSH PRIVILEGES.
+	loginFailures struct {
+		sync.Mutex
+		m map[string]*loginFailure
+	}
+}
+
+type loginFailure struct {
+	count int64
+	// lockedAt is the time when the account is locked, it's zero if the account isn't locked.
+	lockedAt time.Time
 }
 
 // NewHandle returns a Handle.
 func NewHandle(ctx context.Context) *Handle {
-	return &Handle{
+	h := &Handle{
 		ctx: ctx,
 	}
+	h.loginFailures.m = make(map[string]*loginFailure)
+	return h
+}
+
+// loginLocked checks whether the account of the user record is locked temporarily for too many failed logins.
+func (h *Handle) loginLocked(record *userRecord, now time.Time) bool {
+	h.loginFailures.Lock()
+	defer h.loginFailures.Unlock()
+	failure, ok := h.loginFailures.m[record.account()]
+	if !ok || failure.lockedAt.IsZero() {
+		return false
+	}
+	if record.PasswordLockTime < 0 || now.Before(failure.lockedAt.Add(time.Duration(record.PasswordLockTime)*24*time.Hour)) {
+		return true
+	}
+	// The lock time elapses, the tracking starts over.
+	delete(h.loginFailures.m, record.account())
+	return false
+}
+
+// loginFailed records a failed login of the account, it's locked if the failed logins reach FAILED_LOGIN_ATTEMPTS.
+func (h *Handle) loginFailed(record *userRecord, now time.Time) {
+	if record.FailedLoginAttempts == 0 || record.PasswordLockTime == 0 {
+		return
+	}
+	h.loginFailures.Lock()
+	defer h.loginFailures.Unlock()
+	failure, ok := h.loginFailures.m[record.account()]
+	if !ok {
+		failure = &loginFailure{}
+		h.loginFailures.m[record.account()] = failure
+	}
+	failure.count++
+	if failure.count >= record.FailedLoginAttempts && failure.lockedAt.IsZero() {
+		log.Warnf("Account %s is locked for %d consecutive failed logins", record.account(), failure.count)
+		failure.lockedAt = now
+	}
+}
+
+// ResetLoginFailure resets the failed login tracking of the account.
+func (h *Handle) ResetLoginFailure(user, host string) {
+	h.loginFailures.Lock()
+	delete(h.loginFailures.m, user+"@"+host)
+	h.loginFailures.Unlock()
+}
+
+// ResetLoginFailures resets the failed login tracking of all the accounts.
+func (h *Handle) ResetLoginFailures() {
+	h.loginFailures.Lock()
+	h.loginFailures.m = make(map[string]*loginFailure)
+	h.loginFailures.Unlock()
 }
 
 // Get the MySQLPrivilege for read.
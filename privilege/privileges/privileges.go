@@ -15,7 +15,9 @@ package privileges
 
 import (
 	"bytes"
+	"crypto/tls"
 	"strings"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
@@ -27,9 +29,6 @@ import (
 	"github.com/pingcap/tidb/util/types"
 )
 
-// Enable enables the new privilege check feature.
-var Enable = false
-
 // SkipWithGrant causes the server to start without using the privilege system at all.
 var SkipWithGrant = false
 
@@ -55,8 +54,8 @@ type UserPrivileges struct {
 }
 
 // RequestVerification implements the Manager interface.
-func (p *UserPrivileges) RequestVerification(db, table, column string, priv mysql.PrivilegeType) bool {
-	if !Enable || SkipWithGrant {
+func (p *UserPrivileges) RequestVerification(activeRoles []string, db, table, column string, priv mysql.PrivilegeType) bool {
+	if SkipWithGrant {
 		return true
 	}
 
@@ -65,14 +64,14 @@ func (p *UserPrivileges) RequestVerification(db, table, column string, priv mysq
 	}
 
 	mysqlPriv := p.Handle.Get()
-	return mysqlPriv.RequestVerification(p.user, p.host, db, table, column, priv)
+	return mysqlPriv.RequestVerification(activeRoles, p.user, p.host, db, table, column, priv)
 }
 
 // PWDHashLen is the length of password's hash.
 const PWDHashLen = 40
 
 // ConnectionVerification implements the Manager interface.
-func (p *UserPrivileges) ConnectionVerification(user, host string, auth, salt []byte) bool {
+func (p *UserPrivileges) ConnectionVerification(user, host string, auth, salt []byte, tlsState *tls.ConnectionState) bool {
 	if SkipWithGrant {
 		p.user = user
 		p.host = host
@@ -85,33 +84,149 @@ func (p *UserPrivileges) ConnectionVerification(user, host string, auth, salt []
 		log.Errorf("Get user privilege record fail: user %v, host %v", user, host)
 		return false
 	}
-
-	pwd := record.Password
-	if len(pwd) != 0 && len(pwd) != PWDHashLen {
-		log.Errorf("User [%s] password from SystemDB not like a sha1sum", user)
+	if record.AccountLocked {
+		log.Errorf("User [%s] account is locked", user)
+		return false
+	}
+	if p.Handle.loginLocked(record, time.Now()) {
+		log.Errorf("User [%s] account is locked temporarily for too many failed logins", user)
 		return false
 	}
-	hpwd, err := util.DecodePassword(pwd)
-	if err != nil {
-		log.Errorf("Decode password string error %v", err)
+
+	switch record.AuthPlugin {
+	case mysql.AuthNativePassword, "":
+		pwd := record.Password
+		if len(pwd) != 0 && len(pwd) != PWDHashLen {
+			log.Errorf("User [%s] password from SystemDB not like a sha1sum", user)
+			return false
+		}
+		hpwd, err := util.DecodePassword(pwd)
+		if err != nil {
+			log.Errorf("Decode password string error %v", err)
+			return false
+		}
+		checkAuth := util.CalcPassword(salt, hpwd)
+		if !bytes.Equal(auth, checkAuth) {
+			return false
+		}
+	case mysql.AuthCachingSha2Password, mysql.AuthSha256Password:
+		ok, err := util.CheckSHA256Password(record.AuthenticationString, string(auth))
+		if err != nil {
+			log.Errorf("User [%s] authentication string from SystemDB not like a sha256 hash: %v", user, err)
+			return false
+		}
+		if !ok {
+			return false
+		}
+	default:
+		log.Errorf("User [%s] auth plugin %s is not supported", user, record.AuthPlugin)
 		return false
 	}
-	checkAuth := util.CalcPassword(salt, hpwd)
-	if !bytes.Equal(auth, checkAuth) {
+	if !record.tlsVerification(tlsState) {
+		log.Errorf("User [%s] connection doesn't meet the TLS requirement %s", user, record.SSLType)
 		return false
 	}
+	p.Handle.ResetLoginFailure(record.User, record.Host)
 	p.user = user
 	p.host = host
 	return true
 }
 
+// LoginFailed implements the Manager interface.
+func (p *UserPrivileges) LoginFailed(user, host string) {
+	if SkipWithGrant {
+		return
+	}
+	mysqlPriv := p.Handle.Get()
+	record := mysqlPriv.connectionVerification(user, host)
+	if record == nil {
+		return
+	}
+	p.Handle.loginFailed(record, time.Now())
+}
+
+// IsPasswordExpired implements the Manager interface.
+func (p *UserPrivileges) IsPasswordExpired(user, host string, defaultLifetime int64) bool {
+	if SkipWithGrant {
+		return false
+	}
+	mysqlPriv := p.Handle.Get()
+	record := mysqlPriv.connectionVerification(user, host)
+	if record == nil {
+		return false
+	}
+	return record.passwordExpired(defaultLifetime, time.Now())
+}
+
+// GetAuthPlugin implements the Manager interface.
+func (p *UserPrivileges) GetAuthPlugin(user, host string) string {
+	if SkipWithGrant {
+		return ""
+	}
+	mysqlPriv := p.Handle.Get()
+	record := mysqlPriv.connectionVerification(user, host)
+	if record == nil {
+		return ""
+	}
+	if record.AuthPlugin == "" {
+		return mysql.AuthNativePassword
+	}
+	return record.AuthPlugin
+}
+
+// GetAuthHost implements the Manager interface.
+func (p *UserPrivileges) GetAuthHost(user, host string) string {
+	if SkipWithGrant {
+		return ""
+	}
+	mysqlPriv := p.Handle.Get()
+	record := mysqlPriv.connectionVerification(user, host)
+	if record == nil {
+		return ""
+	}
+	return record.Host
+}
+
 // DBIsVisible implements the Manager interface.
-func (p *UserPrivileges) DBIsVisible(db string) bool {
-	if !Enable || SkipWithGrant {
+func (p *UserPrivileges) DBIsVisible(activeRoles []string, db string) bool {
+	if SkipWithGrant {
+		return true
+	}
+	if p.user == "" && p.host == "" {
 		return true
 	}
 	mysqlPriv := p.Handle.Get()
-	return mysqlPriv.DBIsVisible(p.user, p.host, db)
+	return mysqlPriv.DBIsVisible(activeRoles, p.user, p.host, db)
+}
+
+// GetDefaultRoles implements the Manager interface.
+func (p *UserPrivileges) GetDefaultRoles(user, host string) []string {
+	if SkipWithGrant {
+		return nil
+	}
+	mysqlPriv := p.Handle.Get()
+	return mysqlPriv.getDefaultRoles(user, host)
+}
+
+// GetAllRoles implements the Manager interface.
+func (p *UserPrivileges) GetAllRoles(user, host string) []string {
+	if SkipWithGrant {
+		return nil
+	}
+	mysqlPriv := p.Handle.Get()
+	return mysqlPriv.getAllRoles(user, host)
+}
+
+// MaskingPolicies implements the Manager interface.
+func (p *UserPrivileges) MaskingPolicies(activeRoles []string, db, table string) []*privilege.MaskingPolicy {
+	if SkipWithGrant {
+		return nil
+	}
+	if p.user == "" && p.host == "" {
+		return nil
+	}
+	mysqlPriv := p.Handle.Get()
+	return mysqlPriv.MaskingPolicies(activeRoles, p.user, p.host, db, table)
 }
 
 // UserPrivilegesTable implements the Manager interface.
@@ -121,12 +236,12 @@ func (p *UserPrivileges) UserPrivilegesTable() [][]types.Datum {
 }
 
 // ShowGrants implements privilege.Manager ShowGrants interface.
-func (p *UserPrivileges) ShowGrants(ctx context.Context, user string) ([]string, error) {
+func (p *UserPrivileges) ShowGrants(ctx context.Context, user string, roles []string) ([]string, error) {
 	strs := strings.Split(user, "@")
 	if len(strs) != 2 {
 		return nil, errors.Errorf("Invalid format for user: %s", user)
 	}
 	user, host := strs[0], strs[1]
 	mysqlPrivilege := p.Handle.Get()
-	return mysqlPrivilege.showGrants(user, host), nil
+	return mysqlPrivilege.showGrants(user, host, roles), nil
 }
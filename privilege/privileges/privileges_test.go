@@ -22,10 +22,13 @@ import (
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/privilege"
-	"github.com/pingcap/tidb/privilege/privileges"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/testutil"
 )
@@ -59,7 +62,6 @@ func (s *testPrivilegeSuite) SetUpSuit(c *C) {
 }
 
 func (s *testPrivilegeSuite) SetUpTest(c *C) {
-	privileges.Enable = true
 	s.dbName = "test"
 	s.store = newStore(c, s.dbName)
 	se := newSession(c, s.store, s.dbName)
@@ -104,16 +106,16 @@ func (s *testPrivilegeSuite) TestCheckDBPrivilege(c *C) {
 	se := newSession(c, s.store, s.dbName)
 	c.Assert(se.Auth("testcheck@localhost", nil, nil), IsTrue)
 	pc := privilege.GetPrivilegeManager(se)
-	c.Assert(pc.RequestVerification("test", "", "", mysql.SelectPriv), IsFalse)
+	c.Assert(pc.RequestVerification(nil, "test", "", "", mysql.SelectPriv), IsFalse)
 
 	mustExec(c, rootSe, `GRANT SELECT ON *.* TO  'testcheck'@'localhost';`)
 	mustExec(c, rootSe, `FLUSH PRIVILEGES;`)
-	c.Assert(pc.RequestVerification("test", "", "", mysql.SelectPriv), IsTrue)
-	c.Assert(pc.RequestVerification("test", "", "", mysql.UpdatePriv), IsFalse)
+	c.Assert(pc.RequestVerification(nil, "test", "", "", mysql.SelectPriv), IsTrue)
+	c.Assert(pc.RequestVerification(nil, "test", "", "", mysql.UpdatePriv), IsFalse)
 
 	mustExec(c, rootSe, `GRANT Update ON test.* TO  'testcheck'@'localhost';`)
 	mustExec(c, rootSe, `FLUSH PRIVILEGES;`)
-	c.Assert(pc.RequestVerification("test", "", "", mysql.UpdatePriv), IsTrue)
+	c.Assert(pc.RequestVerification(nil, "test", "", "", mysql.UpdatePriv), IsTrue)
 }
 
 func (s *testPrivilegeSuite) TestCheckTablePrivilege(c *C) {
@@ -125,21 +127,21 @@ func (s *testPrivilegeSuite) TestCheckTablePrivilege(c *C) {
 	se := newSession(c, s.store, s.dbName)
 	c.Assert(se.Auth("test1@localhost", nil, nil), IsTrue)
 	pc := privilege.GetPrivilegeManager(se)
-	c.Assert(pc.RequestVerification("test", "test", "", mysql.SelectPriv), IsFalse)
+	c.Assert(pc.RequestVerification(nil, "test", "test", "", mysql.SelectPriv), IsFalse)
 
 	mustExec(c, rootSe, `GRANT SELECT ON *.* TO  'test1'@'localhost';`)
 	mustExec(c, rootSe, `FLUSH PRIVILEGES;`)
-	c.Assert(pc.RequestVerification("test", "test", "", mysql.SelectPriv), IsTrue)
-	c.Assert(pc.RequestVerification("test", "test", "", mysql.UpdatePriv), IsFalse)
+	c.Assert(pc.RequestVerification(nil, "test", "test", "", mysql.SelectPriv), IsTrue)
+	c.Assert(pc.RequestVerification(nil, "test", "test", "", mysql.UpdatePriv), IsFalse)
 
 	mustExec(c, rootSe, `GRANT Update ON test.* TO  'test1'@'localhost';`)
 	mustExec(c, rootSe, `FLUSH PRIVILEGES;`)
-	c.Assert(pc.RequestVerification("test", "test", "", mysql.UpdatePriv), IsTrue)
-	c.Assert(pc.RequestVerification("test", "test", "", mysql.IndexPriv), IsFalse)
+	c.Assert(pc.RequestVerification(nil, "test", "test", "", mysql.UpdatePriv), IsTrue)
+	c.Assert(pc.RequestVerification(nil, "test", "test", "", mysql.IndexPriv), IsFalse)
 
 	mustExec(c, rootSe, `GRANT Index ON test.test TO  'test1'@'localhost';`)
 	mustExec(c, rootSe, `FLUSH PRIVILEGES;`)
-	c.Assert(pc.RequestVerification("test", "test", "", mysql.IndexPriv), IsTrue)
+	c.Assert(pc.RequestVerification(nil, "test", "test", "", mysql.IndexPriv), IsTrue)
 }
 
 func (s *testPrivilegeSuite) TestShowGrants(c *C) {
@@ -150,14 +152,14 @@ func (s *testPrivilegeSuite) TestShowGrants(c *C) {
 	mustExec(c, se, `FLUSH PRIVILEGES;`)
 	pc := privilege.GetPrivilegeManager(se)
 
-	gs, err := pc.ShowGrants(se, `show@localhost`)
+	gs, err := pc.ShowGrants(se, `show@localhost`, nil)
 	c.Assert(err, IsNil)
 	c.Assert(gs, HasLen, 1)
 	c.Assert(gs[0], Equals, `GRANT Index ON *.* TO 'show'@'localhost'`)
 
 	mustExec(c, se, `GRANT Select ON *.* TO  'show'@'localhost';`)
 	mustExec(c, se, `FLUSH PRIVILEGES;`)
-	gs, err = pc.ShowGrants(se, `show@localhost`)
+	gs, err = pc.ShowGrants(se, `show@localhost`, nil)
 	c.Assert(err, IsNil)
 	c.Assert(gs, HasLen, 1)
 	c.Assert(gs[0], Equals, `GRANT Select,Index ON *.* TO 'show'@'localhost'`)
@@ -165,7 +167,7 @@ func (s *testPrivilegeSuite) TestShowGrants(c *C) {
 	// The order of privs is the same with AllGlobalPrivs
 	mustExec(c, se, `GRANT Update ON *.* TO  'show'@'localhost';`)
 	mustExec(c, se, `FLUSH PRIVILEGES;`)
-	gs, err = pc.ShowGrants(se, `show@localhost`)
+	gs, err = pc.ShowGrants(se, `show@localhost`, nil)
 	c.Assert(err, IsNil)
 	c.Assert(gs, HasLen, 1)
 	c.Assert(gs[0], Equals, `GRANT Select,Update,Index ON *.* TO 'show'@'localhost'`)
@@ -173,7 +175,7 @@ func (s *testPrivilegeSuite) TestShowGrants(c *C) {
 	// All privileges
 	mustExec(c, se, `GRANT ALL ON *.* TO  'show'@'localhost';`)
 	mustExec(c, se, `FLUSH PRIVILEGES;`)
-	gs, err = pc.ShowGrants(se, `show@localhost`)
+	gs, err = pc.ShowGrants(se, `show@localhost`, nil)
 	c.Assert(err, IsNil)
 	c.Assert(gs, HasLen, 1)
 	c.Assert(gs[0], Equals, `GRANT ALL PRIVILEGES ON *.* TO 'show'@'localhost'`)
@@ -181,7 +183,7 @@ func (s *testPrivilegeSuite) TestShowGrants(c *C) {
 	// Add db scope privileges
 	mustExec(c, se, `GRANT Select ON test.* TO  'show'@'localhost';`)
 	mustExec(c, se, `FLUSH PRIVILEGES;`)
-	gs, err = pc.ShowGrants(se, `show@localhost`)
+	gs, err = pc.ShowGrants(se, `show@localhost`, nil)
 	c.Assert(err, IsNil)
 	c.Assert(gs, HasLen, 2)
 	expected := []string{`GRANT ALL PRIVILEGES ON *.* TO 'show'@'localhost'`,
@@ -190,7 +192,7 @@ func (s *testPrivilegeSuite) TestShowGrants(c *C) {
 
 	mustExec(c, se, `GRANT Index ON test1.* TO  'show'@'localhost';`)
 	mustExec(c, se, `FLUSH PRIVILEGES;`)
-	gs, err = pc.ShowGrants(se, `show@localhost`)
+	gs, err = pc.ShowGrants(se, `show@localhost`, nil)
 	c.Assert(err, IsNil)
 	c.Assert(gs, HasLen, 3)
 	expected = []string{`GRANT ALL PRIVILEGES ON *.* TO 'show'@'localhost'`,
@@ -200,7 +202,7 @@ func (s *testPrivilegeSuite) TestShowGrants(c *C) {
 
 	mustExec(c, se, `GRANT ALL ON test1.* TO  'show'@'localhost';`)
 	mustExec(c, se, `FLUSH PRIVILEGES;`)
-	gs, err = pc.ShowGrants(se, `show@localhost`)
+	gs, err = pc.ShowGrants(se, `show@localhost`, nil)
 	c.Assert(err, IsNil)
 	c.Assert(gs, HasLen, 3)
 	expected = []string{`GRANT ALL PRIVILEGES ON *.* TO 'show'@'localhost'`,
@@ -211,7 +213,7 @@ func (s *testPrivilegeSuite) TestShowGrants(c *C) {
 	// Add table scope privileges
 	mustExec(c, se, `GRANT Update ON test.test TO  'show'@'localhost';`)
 	mustExec(c, se, `FLUSH PRIVILEGES;`)
-	gs, err = pc.ShowGrants(se, `show@localhost`)
+	gs, err = pc.ShowGrants(se, `show@localhost`, nil)
 	c.Assert(err, IsNil)
 	c.Assert(gs, HasLen, 4)
 	expected = []string{`GRANT ALL PRIVILEGES ON *.* TO 'show'@'localhost'`,
@@ -248,6 +250,409 @@ func (s *testPrivilegeSuite) TestDropTablePriv(c *C) {
 	mustExec(c, se, `DROP TABLE todrop;`)
 }
 
+func (s *testPrivilegeSuite) TestAuthPlugin(c *C) {
+	defer testleak.AfterTest(c)()
+	rootSe := newSession(c, s.store, s.dbName)
+	mustExec(c, rootSe, `CREATE USER 'testsha2'@'localhost' IDENTIFIED WITH caching_sha2_password BY '123';`)
+	mustExec(c, rootSe, `CREATE USER 'testsha256'@'localhost' IDENTIFIED WITH sha256_password;`)
+	mustExec(c, rootSe, `FLUSH PRIVILEGES;`)
+
+	se := newSession(c, s.store, s.dbName)
+	c.Assert(se.AuthPlugin("testsha2@localhost"), Equals, mysql.AuthCachingSha2Password)
+	c.Assert(se.AuthPlugin("testsha256@localhost"), Equals, mysql.AuthSha256Password)
+	c.Assert(se.AuthPlugin("root@localhost"), Equals, mysql.AuthNativePassword)
+	c.Assert(se.AuthPlugin("nonexist@localhost"), Equals, mysql.AuthNativePassword)
+
+	// The plaintext password is verified for caching_sha2_password and sha256_password.
+	c.Assert(se.Auth("testsha2@localhost", []byte("1234"), nil), IsFalse)
+	c.Assert(se.Auth("testsha2@localhost", []byte("123"), nil), IsTrue)
+	c.Assert(se.Auth("testsha256@localhost", []byte("123"), nil), IsFalse)
+	c.Assert(se.Auth("testsha256@localhost", nil, nil), IsTrue)
+}
+
+func (s *testPrivilegeSuite) TestRoles(c *C) {
+	defer testleak.AfterTest(c)()
+	rootSe := newSession(c, s.store, s.dbName)
+	mustExec(c, rootSe, `CREATE USER 'r_user'@'localhost';`)
+	mustExec(c, rootSe, `CREATE ROLE 'r_read', 'r_write';`)
+	mustExec(c, rootSe, `GRANT Select ON test.* TO 'r_read';`)
+	mustExec(c, rootSe, `GRANT Insert ON test.* TO 'r_write';`)
+	mustExec(c, rootSe, `GRANT 'r_read' TO 'r_write';`)
+	mustExec(c, rootSe, `GRANT 'r_write' TO 'r_user'@'localhost';`)
+	mustExec(c, rootSe, `FLUSH PRIVILEGES;`)
+	_, err := rootSe.Execute(`GRANT 'r_none' TO 'r_user'@'localhost';`)
+	c.Assert(err, NotNil)
+
+	// The roles are locked.
+	se := newSession(c, s.store, s.dbName)
+	c.Assert(se.Auth("r_read@localhost", nil, nil), IsFalse)
+	c.Assert(se.Auth("r_user@localhost", nil, nil), IsTrue)
+	c.Assert(queryString(c, se, `SELECT CURRENT_ROLE()`), Equals, "NONE")
+	_, err = se.Execute(`SELECT * FROM test;`)
+	c.Assert(err, NotNil)
+
+	// Only the roles granted to the user can be activated, the privileges of the roles granted to
+	// the active roles are checked too.
+	_, err = se.Execute(`SET ROLE 'r_read';`)
+	c.Assert(executor.ErrRoleNotGranted.Equal(err), IsTrue)
+	mustExec(c, se, `SET ROLE ALL;`)
+	c.Assert(queryString(c, se, `SELECT CURRENT_ROLE()`), Equals, "`r_write`@`%`")
+	mustExec(c, se, `SELECT * FROM test;`)
+	mustExec(c, se, `INSERT INTO test VALUES (1, 'a');`)
+	mustExec(c, se, `SET ROLE ALL EXCEPT 'r_write';`)
+	_, err = se.Execute(`SELECT * FROM test;`)
+	c.Assert(err, NotNil)
+	mustExec(c, se, `SET ROLE 'r_write';`)
+	mustExec(c, se, `SELECT * FROM test;`)
+	mustExec(c, se, `SET ROLE NONE;`)
+	_, err = se.Execute(`SELECT * FROM test;`)
+	c.Assert(err, NotNil)
+
+	pc := privilege.GetPrivilegeManager(rootSe)
+	gs, err := pc.ShowGrants(rootSe, `r_user@localhost`, nil)
+	c.Assert(err, IsNil)
+	c.Assert(gs, DeepEquals, []string{
+		`GRANT USAGE ON *.* TO 'r_user'@'localhost'`,
+		`GRANT 'r_write'@'%' TO 'r_user'@'localhost'`,
+	})
+	gs, err = pc.ShowGrants(rootSe, `r_user@localhost`, []string{"r_write@%"})
+	c.Assert(err, IsNil)
+	c.Assert(gs, DeepEquals, []string{
+		`GRANT USAGE ON *.* TO 'r_user'@'localhost'`,
+		`GRANT Select,Insert ON test.* TO 'r_user'@'localhost'`,
+		`GRANT 'r_write'@'%' TO 'r_user'@'localhost'`,
+	})
+
+	// The default roles are activated when the user logs in.
+	_, err = rootSe.Execute(`SET DEFAULT ROLE 'r_read' TO 'r_user'@'localhost';`)
+	c.Assert(executor.ErrRoleNotGranted.Equal(err), IsTrue)
+	mustExec(c, rootSe, `SET DEFAULT ROLE ALL TO 'r_user'@'localhost';`)
+	mustExec(c, rootSe, `FLUSH PRIVILEGES;`)
+	se = newSession(c, s.store, s.dbName)
+	c.Assert(se.Auth("r_user@localhost", nil, nil), IsTrue)
+	c.Assert(queryString(c, se, `SELECT CURRENT_ROLE()`), Equals, "`r_write`@`%`")
+	mustExec(c, se, `SELECT * FROM test;`)
+	mustExec(c, se, `SET ROLE NONE;`)
+	mustExec(c, se, `SET ROLE DEFAULT;`)
+	mustExec(c, se, `SELECT * FROM test;`)
+
+	// The revoked roles are inactive at once.
+	mustExec(c, rootSe, `REVOKE 'r_write' FROM 'r_user'@'localhost';`)
+	mustExec(c, rootSe, `FLUSH PRIVILEGES;`)
+	_, err = se.Execute(`SELECT * FROM test;`)
+	c.Assert(err, NotNil)
+
+	// Dropping the role removes it from the role graph.
+	mustExec(c, rootSe, `DROP ROLE 'r_read';`)
+	c.Assert(queryString(c, rootSe, `SELECT COUNT(*) FROM mysql.role_edges WHERE FROM_USER = 'r_read' OR TO_USER = 'r_read'`), Equals, "0")
+	mustExec(c, rootSe, `DROP ROLE 'r_write';`)
+	mustExec(c, rootSe, `DROP USER 'r_user'@'localhost';`)
+}
+
+func (s *testPrivilegeSuite) TestMaskingPolicy(c *C) {
+	defer testleak.AfterTest(c)()
+	rootSe := newSession(c, s.store, s.dbName)
+	mustExec(c, rootSe, `CREATE TABLE test.customer (id int, email varchar(64), phone varchar(20))`)
+	mustExec(c, rootSe, `CREATE TABLE test.customer_copy (id int, email varchar(64), phone varchar(20))`)
+	mustExec(c, rootSe, `INSERT INTO test.customer VALUES (1, 'jdoe@example.com', '13812345678')`)
+	mustExec(c, rootSe, `CREATE USER 'analyst'@'localhost', 'support'@'localhost'`)
+	mustExec(c, rootSe, `CREATE ROLE 'support_role'`)
+	mustExec(c, rootSe, `GRANT Select, Insert, Update, Delete ON test.* TO 'analyst'@'localhost'`)
+	mustExec(c, rootSe, `GRANT Select ON test.* TO 'support_role'`)
+	mustExec(c, rootSe, `GRANT 'support_role' TO 'support'@'localhost'`)
+	mustExec(c, rootSe, `CREATE MASKING POLICY email_mask ON test.customer(email) USING mask_email(email) EXEMPT 'support_role'`)
+	mustExec(c, rootSe, `CREATE MASKING POLICY phone_mask ON customer(phone) USING mask_inner(phone, 3, 4, '*')`)
+	mustExec(c, rootSe, `CREATE MASKING POLICY IF NOT EXISTS email_mask ON test.customer(id) USING mask_null(id)`)
+	for _, sql := range []string{
+		`CREATE MASKING POLICY email_mask ON test.customer(id) USING mask_null(id)`,
+		`CREATE MASKING POLICY id_mask ON test.customer(id) USING concat(id, 'x')`,
+		`CREATE MASKING POLICY id_mask ON test.customer(id) USING mask_null(email)`,
+		`CREATE MASKING POLICY id_mask ON test.customer(id) USING mask_outer(id, id, 1)`,
+		`CREATE MASKING POLICY id_mask ON test.customer(no_col) USING mask_null(no_col)`,
+		`CREATE MASKING POLICY id_mask ON test.no_table(id) USING mask_null(id)`,
+		`DROP MASKING POLICY id_mask`,
+	} {
+		_, err := rootSe.Execute(sql)
+		c.Assert(err, NotNil, Commentf("%s", sql))
+	}
+	mustExec(c, rootSe, `DROP MASKING POLICY IF EXISTS id_mask`)
+	mustExec(c, rootSe, `FLUSH PRIVILEGES`)
+
+	// The internal sessions see the real values.
+	c.Assert(queryString(c, rootSe, `SELECT email FROM test.customer`), Equals, "jdoe@example.com")
+
+	// The masked values are read by SELECT and INSERT ... SELECT, the predicates see the masked values too.
+	se := newSession(c, s.store, s.dbName)
+	c.Assert(se.Auth("analyst@localhost", nil, nil), IsTrue)
+	c.Assert(queryString(c, se, `SELECT email FROM test.customer`), Equals, "jXXX@example.com")
+	c.Assert(queryString(c, se, `SELECT c.phone FROM test.customer c WHERE c.id = 1`), Equals, "138****5678")
+	c.Assert(queryString(c, se, `SELECT COUNT(*) FROM test.customer WHERE email = 'jdoe@example.com'`), Equals, "0")
+	c.Assert(queryString(c, se, `SELECT t.email FROM (SELECT * FROM test.customer) t`), Equals, "jXXX@example.com")
+	mustExec(c, se, `INSERT INTO test.customer_copy SELECT * FROM test.customer`)
+	c.Assert(queryString(c, rootSe, `SELECT CONCAT(email, ' ', phone) FROM test.customer_copy`), Equals, "jXXX@example.com 138****5678")
+
+	// UPDATE and DELETE read the masked values of the tables they don't write, and can't read the masked
+	// columns of the tables they write.
+	mustExec(c, se, `UPDATE test.customer_copy t2 JOIN test.customer t ON t2.id = t.id SET t2.phone = t.email`)
+	c.Assert(queryString(c, rootSe, `SELECT phone FROM test.customer_copy`), Equals, "jXXX@example.com")
+	mustExec(c, se, `DELETE test.customer_copy FROM test.customer_copy JOIN test.customer ON customer_copy.id = customer.id WHERE customer.email LIKE 'jd%'`)
+	c.Assert(se.AffectedRows(), Equals, uint64(0))
+	for _, sql := range []string{
+		`UPDATE test.customer SET id = 2 WHERE email LIKE 'jd%'`,
+		`UPDATE test.customer SET phone = email`,
+		`UPDATE test.customer c JOIN test.customer_copy t2 ON c.phone = t2.phone SET c.id = 2`,
+		`DELETE FROM test.customer WHERE email LIKE 'jd%'`,
+		`DELETE c FROM test.customer c JOIN test.customer_copy t2 ON c.id = t2.id WHERE c.phone LIKE '138%'`,
+	} {
+		_, err := se.Execute(sql)
+		c.Assert(plan.ErrMaskedColumnRead.Equal(err), IsTrue, Commentf("%s", sql))
+	}
+	mustExec(c, se, `UPDATE test.customer SET id = 1, email = 'jd@example.com' WHERE id = 1`)
+	c.Assert(queryString(c, rootSe, `SELECT CONCAT(email, ' ', phone) FROM test.customer`), Equals, "jd@example.com 13812345678")
+	mustExec(c, se, `UPDATE test.customer SET email = 'jdoe@example.com'`)
+
+	// The exempted roles see the real values when they are active.
+	se = newSession(c, s.store, s.dbName)
+	c.Assert(se.Auth("support@localhost", nil, nil), IsTrue)
+	mustExec(c, se, `SET ROLE ALL`)
+	c.Assert(queryString(c, se, `SELECT CONCAT(email, ' ', phone) FROM test.customer`), Equals, "jdoe@example.com 138****5678")
+	mustExec(c, se, `SET ROLE NONE`)
+	_, err := se.Execute(`SELECT email FROM test.customer`)
+	c.Assert(err, NotNil)
+
+	mustExec(c, rootSe, `DROP MASKING POLICY email_mask`)
+	mustExec(c, rootSe, `FLUSH PRIVILEGES`)
+	se = newSession(c, s.store, s.dbName)
+	c.Assert(se.Auth("analyst@localhost", nil, nil), IsTrue)
+	c.Assert(queryString(c, se, `SELECT email FROM test.customer`), Equals, "jdoe@example.com")
+	mustExec(c, rootSe, `DROP MASKING POLICY phone_mask`)
+	mustExec(c, rootSe, `DROP ROLE 'support_role'`)
+	mustExec(c, rootSe, `DROP USER 'analyst'@'localhost', 'support'@'localhost'`)
+}
+
+func (s *testPrivilegeSuite) TestAccountLockAndExpire(c *C) {
+	defer testleak.AfterTest(c)()
+	rootSe := newSession(c, s.store, s.dbName)
+	mustExec(c, rootSe, `CREATE USER 'l_user'@'localhost' IDENTIFIED WITH sha256_password BY '123' FAILED_LOGIN_ATTEMPTS 2 PASSWORD_LOCK_TIME UNBOUNDED;`)
+	mustExec(c, rootSe, `CREATE USER 'e_user'@'localhost' IDENTIFIED WITH sha256_password BY '123' PASSWORD EXPIRE;`)
+	mustExec(c, rootSe, `FLUSH PRIVILEGES;`)
+
+	// The account is locked after 2 consecutive failed logins, even the right password is rejected.
+	se := newSession(c, s.store, s.dbName)
+	c.Assert(se.Auth("l_user@localhost", []byte("1"), nil), IsFalse)
+	c.Assert(se.Auth("l_user@localhost", []byte("123"), nil), IsTrue)
+	c.Assert(se.Auth("l_user@localhost", []byte("1"), nil), IsFalse)
+	c.Assert(se.Auth("l_user@localhost", []byte("2"), nil), IsFalse)
+	c.Assert(se.Auth("l_user@localhost", []byte("123"), nil), IsFalse)
+	mustExec(c, rootSe, `ALTER USER 'l_user'@'localhost' ACCOUNT UNLOCK;`)
+	c.Assert(se.Auth("l_user@localhost", []byte("123"), nil), IsTrue)
+
+	mustExec(c, rootSe, `ALTER USER 'l_user'@'localhost' ACCOUNT LOCK;`)
+	mustExec(c, rootSe, `FLUSH PRIVILEGES;`)
+	c.Assert(se.Auth("l_user@localhost", []byte("123"), nil), IsFalse)
+	// ALTER USER without a password doesn't change it.
+	mustExec(c, rootSe, `ALTER USER 'l_user'@'localhost' ACCOUNT UNLOCK;`)
+	mustExec(c, rootSe, `FLUSH PRIVILEGES;`)
+	c.Assert(se.Auth("l_user@localhost", []byte("123"), nil), IsTrue)
+
+	// The user with expired password can only change the password.
+	se = newSession(c, s.store, s.dbName)
+	c.Assert(se.Auth("e_user@localhost", []byte("123"), nil), IsTrue)
+	_, err := se.Execute(`SELECT 1;`)
+	c.Assert(plan.ErrMustChangePassword.Equal(err), IsTrue)
+	_, _, _, err = se.PrepareStmt(`SELECT 1;`)
+	c.Assert(plan.ErrMustChangePassword.Equal(err), IsTrue)
+	mustExec(c, se, `SET PASSWORD = '456';`)
+	mustExec(c, se, `SELECT 1;`)
+	mustExec(c, rootSe, `FLUSH PRIVILEGES;`)
+	c.Assert(se.Auth("e_user@localhost", []byte("456"), nil), IsTrue)
+	mustExec(c, se, `SELECT 1;`)
+
+	// The password expires after its lifetime.
+	mustExec(c, rootSe, `UPDATE mysql.user SET password_last_changed = '2017-01-01 00:00:00' WHERE User = 'e_user';`)
+	mustExec(c, rootSe, `FLUSH PRIVILEGES;`)
+	c.Assert(se.Auth("e_user@localhost", []byte("456"), nil), IsTrue)
+	mustExec(c, se, `SELECT 1;`)
+	stmtID, _, _, err := se.PrepareStmt(`SELECT 1;`)
+	c.Assert(err, IsNil)
+	mustExec(c, rootSe, `ALTER USER 'e_user'@'localhost' PASSWORD EXPIRE INTERVAL 30 DAY;`)
+	mustExec(c, rootSe, `FLUSH PRIVILEGES;`)
+	c.Assert(se.Auth("e_user@localhost", []byte("456"), nil), IsTrue)
+	_, err = se.Execute(`SELECT 1;`)
+	c.Assert(plan.ErrMustChangePassword.Equal(err), IsTrue)
+	// The statements prepared before the password expires can't be executed either.
+	_, err = se.ExecutePreparedStmt(stmtID)
+	c.Assert(plan.ErrMustChangePassword.Equal(err), IsTrue)
+	mustExec(c, se, `ALTER USER USER() IDENTIFIED BY '789';`)
+	mustExec(c, se, `SELECT 1;`)
+
+	mustExec(c, rootSe, `DROP USER 'l_user'@'localhost', 'e_user'@'localhost';`)
+}
+
+type mockSessionManager struct {
+	processes []util.ProcessInfo
+	killed    []uint64
+}
+
+func (sm *mockSessionManager) ShowProcessList() []util.ProcessInfo {
+	return sm.processes
+}
+
+func (sm *mockSessionManager) Kill(connID uint64, query bool) {
+	sm.killed = append(sm.killed, connID)
+}
+
+// execForPrivilege executes sql and returns the privilege error if any, the other errors are ignored.
+func execForPrivilege(se tidb.Session, sql string) error {
+	rss, err := se.Execute(sql)
+	for _, rs := range rss {
+		if _, err1 := tidb.GetRows(rs); err == nil {
+			err = err1
+		}
+		rs.Close()
+	}
+	if terror.ErrorEqual(err, plan.ErrPrivilegeCheckFail) || terror.ErrorEqual(err, executor.ErrKillDenied) {
+		return err
+	}
+	return nil
+}
+
+// TestStatementPrivileges checks the privileges required by the statements. The statement fails with the
+// grants of the case, and succeeds after the missing privileges are granted.
+func (s *testPrivilegeSuite) TestStatementPrivileges(c *C) {
+	defer testleak.AfterTest(c)()
+	rootSe := newSession(c, s.store, s.dbName)
+	mustExec(c, rootSe, `CREATE TABLE test.rename_t (a int)`)
+	sm := &mockSessionManager{processes: []util.ProcessInfo{
+		{ID: 100, User: "root", Host: "127.0.0.1"},
+		{ID: 101, Host: "127.0.0.1"},
+	}}
+	tests := []struct {
+		sql     string
+		grants  []string
+		missing []string
+	}{
+		{"SELECT * FROM test.test", nil, []string{"SELECT ON test.test"}},
+		{"INSERT INTO test.test VALUES (1, 'a')", nil, []string{"INSERT ON test.test"}},
+		{"REPLACE INTO test.test VALUES (1, 'a')", []string{"INSERT ON test.test"}, []string{"DELETE ON test.test"}},
+		{"INSERT INTO test.test VALUES (1, 'a') ON DUPLICATE KEY UPDATE name = 'b'", []string{"INSERT ON test.test"}, []string{"UPDATE ON test.test"}},
+		{"UPDATE test.test SET name = 'c'", []string{"SELECT ON test.test"}, []string{"UPDATE ON test.test"}},
+		{"DELETE FROM test.test", []string{"SELECT ON test.test"}, []string{"DELETE ON test.test"}},
+		{"LOAD DATA LOCAL INFILE '/tmp/t.csv' INTO TABLE test.test", nil, []string{"INSERT ON test.test"}},
+		{"LOAD DATA INFILE '/tmp/t.csv' INTO TABLE test.test", []string{"INSERT ON test.test"}, []string{"FILE ON *.*"}},
+		{"ANALYZE TABLE test.test", []string{"SELECT ON test.test"}, []string{"INSERT ON test.test"}},
+		{"ADMIN SHOW DDL", nil, []string{"SUPER ON *.*"}},
+		{"ADMIN CHECK TABLE test.test", []string{"SELECT ON test.test"}, []string{"SUPER ON *.*"}},
+		{"CREATE TABLE test.create_t (a int)", nil, []string{"CREATE ON test.*"}},
+		{"CREATE INDEX idx ON test.test (name)", nil, []string{"INDEX ON test.test"}},
+		{"ALTER TABLE test.test ADD COLUMN c int", nil, []string{"ALTER ON test.test"}},
+		{"TRUNCATE TABLE test.test", []string{"DELETE ON test.test"}, []string{"DROP ON test.test"}},
+		{"RENAME TABLE test.rename_t TO test.rename_t2", []string{"ALTER ON test.rename_t", "CREATE ON test.rename_t2"},
+			[]string{"DROP ON test.rename_t", "INSERT ON test.rename_t2"}},
+		{"DROP TABLE test.create_t", nil, []string{"DROP ON test.create_t"}},
+		{"FLUSH PRIVILEGES", nil, []string{"RELOAD ON *.*"}},
+		{"SET GLOBAL autocommit = 1", nil, []string{"SUPER ON *.*"}},
+		{"SET SESSION autocommit = 1", nil, nil},
+		{"BINLOG 'abc'", nil, []string{"SUPER ON *.*"}},
+		{"SHOW GRANTS", nil, nil},
+		{"SHOW GRANTS FOR 'root'@'%'", nil, []string{"SELECT ON mysql.*"}},
+		{"KILL 101", nil, nil},
+		{"KILL 100", nil, []string{"SUPER ON *.*"}},
+		{"CREATE USER 'stmt_user2'", nil, []string{"CREATE USER ON *.*"}},
+		{"DROP USER 'stmt_user2'", nil, []string{"CREATE USER ON *.*"}},
+		{"CREATE MASKING POLICY stmt_mask ON test.test(name) USING mask_null(name)", nil, []string{"SUPER ON *.*"}},
+		{"DROP MASKING POLICY stmt_mask", nil, []string{"SUPER ON *.*"}},
+	}
+	for i, t := range tests {
+		// Every case uses a new user, so the grants of the other cases don't affect it.
+		user := fmt.Sprintf("stmt_user%d", i)
+		sm.processes[1].User = user
+		mustExec(c, rootSe, fmt.Sprintf("CREATE USER '%s'@'localhost'", user))
+		for _, g := range t.grants {
+			mustExec(c, rootSe, fmt.Sprintf("GRANT %s TO '%s'@'localhost'", g, user))
+		}
+		mustExec(c, rootSe, `FLUSH PRIVILEGES`)
+		se := newSession(c, s.store, s.dbName)
+		c.Assert(se.Auth(user+"@localhost", nil, nil), IsTrue)
+		se.SetSessionManager(sm)
+		comment := Commentf("%s", t.sql)
+		if len(t.missing) > 0 {
+			c.Assert(execForPrivilege(se, t.sql), NotNil, comment)
+		}
+		for _, g := range t.missing {
+			mustExec(c, rootSe, fmt.Sprintf("GRANT %s TO '%s'@'localhost'", g, user))
+		}
+		mustExec(c, rootSe, `FLUSH PRIVILEGES`)
+		c.Assert(execForPrivilege(se, t.sql), IsNil, comment)
+		se.Close()
+	}
+	c.Assert(sm.killed, DeepEquals, []uint64{101, 100})
+}
+
+func (s *testPrivilegeSuite) TestProcessList(c *C) {
+	defer testleak.AfterTest(c)()
+	rootSe := newSession(c, s.store, s.dbName)
+	mustExec(c, rootSe, `CREATE USER 'ps_user'@'localhost'`)
+	mustExec(c, rootSe, `FLUSH PRIVILEGES`)
+	se := newSession(c, s.store, s.dbName)
+	c.Assert(se.Auth("ps_user@localhost", nil, nil), IsTrue)
+	se.SetSessionManager(&mockSessionManager{processes: []util.ProcessInfo{
+		{ID: 100, User: "root", Host: "127.0.0.1"},
+		{ID: 101, User: "ps_user", Host: "127.0.0.1"},
+	}})
+
+	// The users without the PROCESS privilege can only see their own threads.
+	c.Assert(queryString(c, se, "SHOW PROCESSLIST"), Equals, "101")
+	rs, err := se.Execute("SHOW PROCESSLIST")
+	c.Assert(err, IsNil)
+	rows, err := tidb.GetRows(rs[0])
+	c.Assert(err, IsNil)
+	c.Assert(rows, HasLen, 1)
+
+	mustExec(c, rootSe, `GRANT PROCESS ON *.* TO 'ps_user'@'localhost'`)
+	mustExec(c, rootSe, `FLUSH PRIVILEGES`)
+	rs, err = se.Execute("SHOW PROCESSLIST")
+	c.Assert(err, IsNil)
+	rows, err = tidb.GetRows(rs[0])
+	c.Assert(err, IsNil)
+	c.Assert(rows, HasLen, 2)
+	c.Assert(queryString(c, rootSe, "SHOW GRANTS FOR 'ps_user'@'localhost'"), Equals,
+		"GRANT Process ON *.* TO 'ps_user'@'localhost'")
+	mustExec(c, rootSe, `DROP USER 'ps_user'@'localhost'`)
+}
+
+func (s *testPrivilegeSuite) TestShowGrantsOfCurrentUser(c *C) {
+	defer testleak.AfterTest(c)()
+	rootSe := newSession(c, s.store, s.dbName)
+	mustExec(c, rootSe, `CREATE USER 'sg_user'@'%'`)
+	mustExec(c, rootSe, `CREATE USER 'sg_user'@'10.0.0.1'`)
+	mustExec(c, rootSe, `GRANT SELECT ON test.* TO 'sg_user'@'10.0.0.1'`)
+	mustExec(c, rootSe, `FLUSH PRIVILEGES`)
+	se := newSession(c, s.store, s.dbName)
+	c.Assert(se.Auth("sg_user@localhost", nil, nil), IsTrue)
+
+	// The user is authenticated as 'sg_user'@'%', the account of another host is another user.
+	c.Assert(se.GetSessionVars().AuthHost, Equals, "%")
+	mustExec(c, se, `SHOW GRANTS FOR 'sg_user'@'%'`)
+	mustExec(c, se, `SHOW GRANTS FOR 'sg_user'`)
+	_, err := se.Execute(`SHOW GRANTS FOR 'sg_user'@'10.0.0.1'`)
+	c.Assert(err, NotNil)
+	_, err = se.Execute(`SHOW GRANTS FOR 'sg_user'@'localhost'`)
+	c.Assert(err, NotNil)
+	mustExec(c, rootSe, `DROP USER 'sg_user'@'%', 'sg_user'@'10.0.0.1'`)
+}
+
+func queryString(c *C, se tidb.Session, sql string) string {
+	rs, err := se.Execute(sql)
+	c.Assert(err, IsNil)
+	defer rs[0].Close()
+	row, err := rs[0].Next()
+	c.Assert(err, IsNil)
+	str, err := row.Data[0].ToString()
+	c.Assert(err, IsNil)
+	return str
+}
+
 func mustExec(c *C, se tidb.Session, sql string) {
 	_, err := se.Execute(sql)
 	c.Assert(err, IsNil)
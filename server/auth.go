@@ -0,0 +1,202 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/mysql"
+)
+
+// authExchangeFunc is the server side of an auth plugin. authData is the first auth response of the client, the
+// function reads more packets from the client if the plugin needs them, and returns the auth data which is
+// verified by the privilege manager.
+type authExchangeFunc func(cc *clientConn, authData []byte) ([]byte, error)
+
+// authPlugins are the supported auth plugins.
+var authPlugins = map[string]authExchangeFunc{
+	mysql.AuthNativePassword:      nativePasswordExchange,
+	mysql.AuthCachingSha2Password: cachingSha2PasswordExchange,
+	mysql.AuthSha256Password:      sha256PasswordExchange,
+}
+
+// doAuth authenticates the user with the auth plugin of the account. authPlugin and authData are the auth plugin
+// and the auth response in the client handshake response, the server asks the client to switch to the plugin of
+// the account if they are different.
+func (cc *clientConn) doAuth(ctx QueryCtx, user, authPlugin string, authData []byte) error {
+	addr := cc.conn.RemoteAddr().String()
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return errors.Trace(mysql.NewErr(mysql.ErrAccessDenied, user, addr, "Yes"))
+	}
+	userHost := fmt.Sprintf("%s@%s", user, host)
+	plugin := ctx.AuthPlugin(userHost)
+	exchange, ok := authPlugins[plugin]
+	if !ok {
+		return errors.Trace(mysql.NewErr(mysql.ErrPluginIsNotLoaded, plugin))
+	}
+	if plugin != authPlugin {
+		if cc.capability&mysql.ClientPluginAuth == 0 {
+			// The client doesn't support the auth switch request.
+			return errors.Trace(mysql.NewErr(mysql.ErrAccessDenied, user, host, "Yes"))
+		}
+		authData, err = cc.writeAuthSwitchRequest(plugin)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	authData, err = exchange(cc, authData)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !ctx.Auth(userHost, authData, cc.salt) {
+		return errors.Trace(mysql.NewErr(mysql.ErrAccessDenied, user, host, "Yes"))
+	}
+	return nil
+}
+
+// writeAuthSwitchRequest asks the client to authenticate with another auth plugin, and returns the auth response.
+// See https://dev.mysql.com/doc/internals/en/connection-phase-packets.html#packet-Protocol::AuthSwitchRequest
+func (cc *clientConn) writeAuthSwitchRequest(plugin string) ([]byte, error) {
+	data := make([]byte, 4, 4+1+len(plugin)+1+len(cc.salt)+1)
+	data = append(data, mysql.AuthSwitchHeader)
+	data = append(data, plugin...)
+	data = append(data, 0)
+	data = append(data, cc.salt...)
+	data = append(data, 0)
+	if err := cc.writePacket(data); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := cc.flush(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	data, err := cc.readPacket()
+	return data, errors.Trace(err)
+}
+
+// writeAuthMoreData sends the extra auth data of the auth plugin to the client.
+func (cc *clientConn) writeAuthMoreData(authData []byte) error {
+	data := make([]byte, 4, 4+1+len(authData))
+	data = append(data, mysql.AuthMoreDataHeader)
+	data = append(data, authData...)
+	if err := cc.writePacket(data); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(cc.flush())
+}
+
+// nativePasswordExchange returns the SHA1 scramble sent by the client.
+func nativePasswordExchange(cc *clientConn, authData []byte) ([]byte, error) {
+	return authData, nil
+}
+
+// cachingSha2PasswordExchange returns the plaintext password of caching_sha2_password. The server doesn't cache
+// the password hash, so it always asks the client to perform the full authentication.
+// See https://dev.mysql.com/doc/dev/mysql-server/latest/page_caching_sha2_authentication_exchanges.html
+func cachingSha2PasswordExchange(cc *clientConn, authData []byte) ([]byte, error) {
+	// The client sends an empty scramble if the password is empty.
+	if len(authData) == 0 {
+		return nil, nil
+	}
+	if err := cc.writeAuthMoreData([]byte{mysql.AuthPerformFullAuthentication}); err != nil {
+		return nil, errors.Trace(err)
+	}
+	data, err := cc.readPacket()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if cc.tlsConn != nil {
+		return trimNullTerminator(data), nil
+	}
+	return cc.decryptPassword(data, mysql.AuthRequestPublicKey)
+}
+
+// sha256PasswordExchange returns the plaintext password of sha256_password, which is sent in the first auth
+// response if the connection is encrypted by TLS.
+func sha256PasswordExchange(cc *clientConn, authData []byte) ([]byte, error) {
+	// The client sends a single 0x00 if the password is empty.
+	if cc.tlsConn != nil || (len(authData) == 1 && authData[0] == 0) {
+		return trimNullTerminator(authData), nil
+	}
+	return cc.decryptPassword(authData, mysql.AuthSha256RequestPublicKey)
+}
+
+// decryptPassword decrypts the password which is encrypted by the RSA public key of the server. The client sends
+// the requestPublicKey byte to ask for the public key if it doesn't have it.
+func (cc *clientConn) decryptPassword(data []byte, requestPublicKey byte) ([]byte, error) {
+	key, pubPEM, err := cc.server.rsaKey.get()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(data) == 1 && data[0] == requestPublicKey {
+		if err = cc.writeAuthMoreData(pubPEM); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if data, err = cc.readPacket(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	plain, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, key, data, nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// The client XORs the null terminated password with the salt before the encryption.
+	for i := range plain {
+		plain[i] ^= cc.salt[i%len(cc.salt)]
+	}
+	return trimNullTerminator(plain), nil
+}
+
+func trimNullTerminator(data []byte) []byte {
+	if len(data) > 0 && data[len(data)-1] == 0 {
+		return data[:len(data)-1]
+	}
+	return data
+}
+
+// rsaKeyPair is used to encrypt the password when the client authenticates with caching_sha2_password or
+// sha256_password without TLS, it is generated on the first use.
+type rsaKeyPair struct {
+	once   sync.Once
+	key    *rsa.PrivateKey
+	pubPEM []byte
+	err    error
+}
+
+const rsaKeyBits = 2048
+
+// get returns the private key and the PEM encoded public key.
+func (k *rsaKeyPair) get() (*rsa.PrivateKey, []byte, error) {
+	k.once.Do(func() {
+		k.key, k.err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if k.err != nil {
+			return
+		}
+		var der []byte
+		der, k.err = x509.MarshalPKIXPublicKey(&k.key.PublicKey)
+		if k.err != nil {
+			return
+		}
+		k.pubPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	})
+	return k.key, k.pubPEM, errors.Trace(k.err)
+}
@@ -23,4 +23,11 @@ type Config struct {
 	ReportStatus bool   `json:"report_status" toml:"report_status"`
 	StorePath    string `json:"store_path" toml:"store_path"`
 	Store        string `json:"store" toml:"store"`
+	SSLCA        string `json:"ssl_ca" toml:"ssl_ca"`
+	SSLCert      string `json:"ssl_cert" toml:"ssl_cert"`
+	SSLKey       string `json:"ssl_key" toml:"ssl_key"`
+	TokenLimit   uint   `json:"token_limit" toml:"token_limit"`
+	// ProxyProtocolNetworks is the comma separated CIDRs of the trusted proxies, the connections from them
+	// must send the PROXY protocol header.
+	ProxyProtocolNetworks string `json:"proxy_protocol_networks" toml:"proxy_protocol_networks"`
 }
@@ -35,7 +35,9 @@
 package server
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/tls"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -43,6 +45,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/juju/errors"
@@ -52,20 +55,23 @@ import (
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/arena"
+	"github.com/pingcap/tidb/util/audit"
 	"github.com/pingcap/tidb/util/hack"
+	"github.com/pingcap/tidb/util/types"
 )
 
 var defaultCapability = mysql.ClientLongPassword | mysql.ClientLongFlag |
 	mysql.ClientConnectWithDB | mysql.ClientProtocol41 |
 	mysql.ClientTransactions | mysql.ClientSecureConnection | mysql.ClientFoundRows |
 	mysql.ClientMultiStatements | mysql.ClientMultiResults | mysql.ClientLocalFiles |
-	mysql.ClientConnectAtts
+	mysql.ClientConnectAtts | mysql.ClientCompress | mysql.ClientPluginAuth
 
 // clientConn represents a connection between server and client, it maintains connection specific state,
 // handles client query.
 type clientConn struct {
 	pkt          *packetIO // a helper to read and write data in packet format.
 	conn         net.Conn
+	tlsConn      *tls.Conn         // the TLS connection, it is nil if the client doesn't use TLS.
 	server       *Server           // a reference of server instance.
 	capability   uint32            // client capability affects the way server handles client request.
 	connectionID uint32            // atomically allocated by a global variable, unique in process scope.
@@ -78,8 +84,17 @@ type clientConn struct {
 	ctx          QueryCtx          // an interface to execute sql statements.
 	attrs        map[string]string // attributes parsed from client handshake response, not used for now.
 	killed       bool
+	status       int32 // the status of the connection, it's accessed atomically.
 }
 
+// The status of a client connection. A connection which is reading the next command can be closed by
+// the draining server at once.
+const (
+	connStatusDispatching int32 = iota
+	connStatusReading
+	connStatusShutdown
+)
+
 func (cc *clientConn) String() string {
 	collationStr := mysql.Collations[cc.collation]
 	return fmt.Sprintf("id:%d, addr:%s status:%d, collation:%s, user:%s",
@@ -107,12 +122,23 @@ func (cc *clientConn) handshake() error {
 	}
 
 	err := cc.writePacket(data)
-	cc.pkt.sequence = 0
+	cc.pkt.resetSequence()
 	if err != nil {
 		return errors.Trace(err)
 	}
 
-	return errors.Trace(cc.flush())
+	err = cc.flush()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The packets after the handshake are compressed if the client asks for it.
+	cc.pkt.compressed = cc.capability&mysql.ClientCompress > 0
+	return nil
+}
+
+// inTxn returns whether the session of the connection is in a transaction.
+func (cc *clientConn) inTxn() bool {
+	return cc.ctx.Status()&mysql.ServerStatusInTrans > 0
 }
 
 func (cc *clientConn) Close() error {
@@ -122,6 +148,7 @@ func (cc *clientConn) Close() error {
 	cc.server.rwlock.Unlock()
 	connGauge.Set(float64(connections))
 	cc.conn.Close()
+	cc.audit(audit.TypeDisconnect, cc.user, cc.dbname, nil)
 	if cc.ctx != nil {
 		return cc.ctx.Close()
 	}
@@ -144,15 +171,16 @@ func (cc *clientConn) writeInitialHandshake() error {
 	data = append(data, cc.salt[0:8]...)
 	// filler [00]
 	data = append(data, 0)
-	// capability flag lower 2 bytes, using default capability here
-	data = append(data, byte(defaultCapability), byte(defaultCapability>>8))
+	// capability flag lower 2 bytes, using server capability here
+	capability := cc.server.capability()
+	data = append(data, byte(capability), byte(capability>>8))
 	// charset, utf-8 default
 	data = append(data, uint8(mysql.DefaultCollationID))
 	//status
 	data = append(data, dumpUint16(mysql.ServerStatusAutocommit)...)
 	// below 13 byte may not be used
-	// capability flag upper 2 bytes, using default capability here
-	data = append(data, byte(defaultCapability>>16), byte(defaultCapability>>24))
+	// capability flag upper 2 bytes, using server capability here
+	data = append(data, byte(capability>>16), byte(capability>>24))
 	// filler [0x15], for wireshark dump, value is 0x15
 	data = append(data, 0x15)
 	// reserved 10 [00]
@@ -161,6 +189,9 @@ func (cc *clientConn) writeInitialHandshake() error {
 	data = append(data, cc.salt[8:]...)
 	// filler [00]
 	data = append(data, 0)
+	// auth-plugin name
+	data = append(data, mysql.AuthNativePassword...)
+	data = append(data, 0)
 	err := cc.writePacket(data)
 	if err != nil {
 		return errors.Trace(err)
@@ -182,6 +213,7 @@ type handshakeResponse41 struct {
 	User       string
 	DBName     string
 	Auth       []byte
+	AuthPlugin string
 	Attrs      map[string]string
 }
 
@@ -238,16 +270,28 @@ func handshakeResponseFromData(packet *handshakeResponse41, data []byte) (err er
 		}
 	}
 
-	if capability&mysql.ClientPluginAuth > 0 {
-		// TODO: Support mysql.ClientPluginAuth, skip it now
-		idx := bytes.IndexByte(data[pos:], 0)
-		pos = pos + idx + 1
+	parseAuthPluginAndAttrs(packet, data[pos:])
+	return nil
+}
+
+// parseAuthPluginAndAttrs parses the auth plugin name and the connection attributes at the end of the handshake
+// response and COM_CHANGE_USER packets.
+func parseAuthPluginAndAttrs(packet *handshakeResponse41, data []byte) {
+	pos := 0
+	packet.AuthPlugin = mysql.AuthNativePassword
+	if packet.Capability&mysql.ClientPluginAuth > 0 && len(data) > 0 {
+		idx := bytes.IndexByte(data, 0)
+		if idx < 0 {
+			idx = len(data)
+		}
+		packet.AuthPlugin = string(data[:idx])
+		pos = idx + 1
 	}
 
-	if capability&mysql.ClientConnectAtts > 0 {
-		if len(data[pos:]) == 0 {
+	if packet.Capability&mysql.ClientConnectAtts > 0 {
+		if len(data) <= pos {
 			// Defend some ill-formated packet, connection attribute is not important and can be ignored.
-			return nil
+			return
 		}
 		if num, null, off := parseLengthEncodedInt(data[pos:]); !null {
 			pos += off
@@ -255,12 +299,45 @@ func handshakeResponseFromData(packet *handshakeResponse41, data []byte) (err er
 			attrs, err := parseAttrs(kv)
 			if err != nil {
 				log.Warn("parse attrs error:", errors.ErrorStack(err))
-				return nil
+				return
 			}
 			packet.Attrs = attrs
-			pos += int(num)
 		}
 	}
+}
+
+// changeUserFromData parses the COM_CHANGE_USER packet without the command byte.
+// See https://dev.mysql.com/doc/internals/en/com-change-user.html
+func changeUserFromData(packet *handshakeResponse41, data []byte, capability uint32) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("change user panic, packet data: %v", data)
+			err = mysql.ErrMalformPacket
+		}
+	}()
+	packet.Capability = capability
+	pos := 0
+	// user name
+	packet.User = string(data[pos : pos+bytes.IndexByte(data[pos:], 0)])
+	pos += len(packet.User) + 1
+	if capability&mysql.ClientSecureConnection > 0 {
+		authLen := int(data[pos])
+		pos++
+		packet.Auth = data[pos : pos+authLen]
+		pos += authLen
+	} else {
+		packet.Auth = data[pos : pos+bytes.IndexByte(data[pos:], 0)]
+		pos += len(packet.Auth) + 1
+	}
+	// schema name
+	packet.DBName = string(data[pos : pos+bytes.IndexByte(data[pos:], 0)])
+	pos += len(packet.DBName) + 1
+	if len(data[pos:]) >= 2 {
+		// character set, 2 bytes
+		packet.Collation = data[pos]
+		pos += 2
+	}
+	parseAuthPluginAndAttrs(packet, data[pos:])
 	return nil
 }
 
@@ -284,44 +361,114 @@ func parseAttrs(data []byte) (map[string]string, error) {
 	return attrs, nil
 }
 
+// sslRequestLen is the length of the SSL request packet, which is a truncated handshake response packet.
+// It contains the capability, max packet size, collation and 23 reserved bytes.
+const sslRequestLen = 32
+
 func (cc *clientConn) readHandshakeResponse() error {
 	data, err := cc.readPacket()
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if len(data) == sslRequestLen && binary.LittleEndian.Uint32(data[:4])&mysql.ClientSSL > 0 {
+		// The client asks to switch to TLS, it sends the full handshake response after the TLS handshake.
+		if err = cc.upgradeToTLS(); err != nil {
+			return errors.Trace(err)
+		}
+		data, err = cc.readPacket()
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
 
 	var p handshakeResponse41
 	if err = handshakeResponseFromData(&p, data); err != nil {
 		return errors.Trace(err)
 	}
-	cc.capability = p.Capability & defaultCapability
+	cc.capability = p.Capability & cc.server.capability()
 	cc.user = p.User
 	cc.dbname = p.DBName
 	cc.collation = p.Collation
 	cc.attrs = p.Attrs
 
-	// Open session and do auth
-	cc.ctx, err = cc.server.driver.OpenCtx(uint64(cc.connectionID), cc.capability, uint8(cc.collation), cc.dbname)
+	cc.ctx, err = cc.openSessionAndDoAuth(cc.user, cc.dbname, cc.collation, p.AuthPlugin, p.Auth)
+	return errors.Trace(err)
+}
+
+// openSessionAndDoAuth opens a new session and authenticates the user with the auth response of the client.
+func (cc *clientConn) openSessionAndDoAuth(user, dbname string, collation uint8, authPlugin string, authData []byte) (QueryCtx, error) {
+	ctx, err := cc.server.driver.OpenCtx(uint64(cc.connectionID), cc.capability, collation, dbname)
 	if err != nil {
-		cc.Close()
-		return errors.Trace(err)
+		return nil, errors.Trace(err)
+	}
+	if cc.server.tlsConfig != nil {
+		ctx.SetHaveSSL()
+	}
+	if cc.tlsConn != nil {
+		tlsState := cc.tlsConn.ConnectionState()
+		ctx.SetTLSState(&tlsState)
 	}
 	if !cc.server.skipAuth() {
-		// Do Auth
-		addr := cc.conn.RemoteAddr().String()
-		host, _, err1 := net.SplitHostPort(addr)
-		if err1 != nil {
-			return errors.Trace(mysql.NewErr(mysql.ErrAccessDenied, cc.user, addr, "Yes"))
-		}
-		user := fmt.Sprintf("%s@%s", cc.user, host)
-		if !cc.ctx.Auth(user, p.Auth, cc.salt) {
-			return errors.Trace(mysql.NewErr(mysql.ErrAccessDenied, cc.user, host, "Yes"))
+		if err = cc.doAuth(ctx, user, authPlugin, authData); err != nil {
+			cc.audit(audit.TypeAuthFailed, user, dbname, err)
+			ctx.Close()
+			return nil, errors.Trace(err)
 		}
 	}
-	cc.ctx.SetSessionManager(cc.server)
+	ctx.SetSessionManager(cc.server)
+	return ctx, nil
+}
+
+// audit writes the connection event to the audit log.
+func (cc *clientConn) audit(tp, user, dbname string, err error) {
+	if !audit.Enabled() {
+		return
+	}
+	addr := cc.conn.RemoteAddr().String()
+	host, _, err1 := net.SplitHostPort(addr)
+	if err1 != nil {
+		// The unix socket address has no port.
+		host = addr
+	}
+	audit.Write(&audit.Event{
+		Time:    time.Now(),
+		Type:    tp,
+		ConnID:  uint64(cc.connectionID),
+		User:    user,
+		Host:    host,
+		DB:      dbname,
+		Class:   audit.ClassConnection,
+		ErrCode: audit.ErrCode(err),
+	})
+}
+
+// upgradeToTLS does the TLS handshake with the client, then reads and writes the packets through the TLS connection.
+func (cc *clientConn) upgradeToTLS() error {
+	if cc.server.tlsConfig == nil {
+		return errors.Trace(mysql.ErrMalformPacket)
+	}
+	// The client may send the TLS handshake data without waiting, and it may be buffered in the packet reader.
+	conn := &bufferedReadConn{Conn: cc.conn, rb: cc.pkt.rb}
+	tlsConn := tls.Server(conn, cc.server.tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return errors.Trace(err)
+	}
+	cc.conn = tlsConn
+	cc.tlsConn = tlsConn
+	cc.pkt.setConn(tlsConn)
 	return nil
 }
 
+// bufferedReadConn is a net.Conn which reads the data buffered in the bufio.Reader first.
+type bufferedReadConn struct {
+	net.Conn
+	rb *bufio.Reader
+}
+
+func (conn *bufferedReadConn) Read(b []byte) (int, error) {
+	return conn.rb.Read(b)
+}
+
 // Run reads client query and writes query result to client in for loop, if there is a panic during query handling,
 // it will be recovered and log the panic error.
 // This function returns and the connection is closed if there is an IO error or there is a panic.
@@ -339,8 +486,19 @@ func (cc *clientConn) Run() {
 	}()
 
 	for !cc.killed {
+		if !atomic.CompareAndSwapInt32(&cc.status, connStatusDispatching, connStatusReading) {
+			return
+		}
+		// The draining server closes the connections outside transactions at the command boundary.
+		if cc.server.IsDraining() && !cc.inTxn() {
+			return
+		}
 		cc.alloc.Reset()
 		data, err := cc.readPacket()
+		if !atomic.CompareAndSwapInt32(&cc.status, connStatusReading, connStatusDispatching) {
+			// The connection is closed by the draining server.
+			return
+		}
 		if err != nil {
 			if terror.ErrorNotEqual(err, io.EOF) {
 				log.Error(errors.ErrorStack(err))
@@ -368,7 +526,7 @@ func (cc *clientConn) Run() {
 			cc.writeError(err)
 		}
 		cc.addMetrics(data[0], startTime, err)
-		cc.pkt.sequence = 0
+		cc.pkt.resetSequence()
 	}
 }
 
@@ -413,8 +571,12 @@ func (cc *clientConn) addMetrics(cmd byte, startTime time.Time, err error) {
 		label = "StmtSendLongData"
 	case mysql.ComStmtReset:
 		label = "StmtReset"
+	case mysql.ComStmtFetch:
+		label = "StmtFetch"
 	case mysql.ComSetOption:
 		label = "SetOption"
+	case mysql.ComChangeUser:
+		label = "ChangeUser"
 	default:
 		label = strconv.Itoa(int(cmd))
 	}
@@ -433,9 +595,10 @@ func (cc *clientConn) dispatch(data []byte) error {
 	cmd := data[0]
 	data = data[1:]
 	cc.lastCmd = hack.String(data)
-	token := cc.server.getToken()
+	tokenLimiter := cc.server.tokenLimiter()
+	token := tokenLimiter.Get()
 	defer func() {
-		cc.server.releaseToken(token)
+		tokenLimiter.Put(token)
 	}()
 
 	switch cmd {
@@ -474,13 +637,44 @@ func (cc *clientConn) dispatch(data []byte) error {
 		return cc.handleStmtSendLongData(data)
 	case mysql.ComStmtReset:
 		return cc.handleStmtReset(data)
+	case mysql.ComStmtFetch:
+		return cc.handleStmtFetch(data)
 	case mysql.ComSetOption:
 		return cc.handleSetOption(data)
+	case mysql.ComChangeUser:
+		return cc.handleChangeUser(data)
 	default:
 		return mysql.NewErrf(mysql.ErrUnknown, "command %d not supported now", cmd)
 	}
 }
 
+// handleChangeUser authenticates the user in the COM_CHANGE_USER packet, and replaces the session with a new one
+// to reset the session state. The original session is kept if the authentication fails.
+func (cc *clientConn) handleChangeUser(data []byte) error {
+	var p handshakeResponse41
+	if err := changeUserFromData(&p, data, cc.capability); err != nil {
+		return errors.Trace(err)
+	}
+	collation := cc.collation
+	if p.Collation != 0 {
+		collation = p.Collation
+	}
+	ctx, err := cc.openSessionAndDoAuth(p.User, p.DBName, collation, p.AuthPlugin, p.Auth)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = cc.ctx.Close(); err != nil {
+		log.Errorf("[%d] close session error %v", cc.connectionID, errors.ErrorStack(err))
+	}
+	cc.ctx = ctx
+	cc.user = p.User
+	cc.dbname = p.DBName
+	cc.collation = collation
+	cc.attrs = p.Attrs
+	cc.audit(audit.TypeConnect, cc.user, cc.dbname, nil)
+	return cc.writeOK()
+}
+
 func (cc *clientConn) useDB(db string) (err error) {
 	// if input is "use `SELECT`", mysql client just send "SELECT"
 	// so we add `` around db.
@@ -549,17 +743,15 @@ func (cc *clientConn) writeError(e error) error {
 // packets following it, the "more" argument would indicates that case.
 // If "more" is true, a mysql.ServerMoreResultsExists bit would be set
 // in the packet.
-func (cc *clientConn) writeEOF(more bool) error {
+// writeEOF writes an EOF packet, serverStatus is the extra status flags besides the session status.
+func (cc *clientConn) writeEOF(serverStatus uint16) error {
 	data := cc.alloc.AllocWithLen(4, 9)
 
 	data = append(data, mysql.EOFHeader)
 	if cc.capability&mysql.ClientProtocol41 > 0 {
 		data = append(data, dumpUint16(cc.ctx.WarningCount())...)
-		status := cc.ctx.Status()
-		if more {
-			status |= mysql.ServerMoreResultsExists
-		}
-		data = append(data, dumpUint16(cc.ctx.Status())...)
+		status := cc.ctx.Status() | serverStatus
+		data = append(data, dumpUint16(status)...)
 	}
 
 	err := cc.writePacket(data)
@@ -708,7 +900,7 @@ func (cc *clientConn) handleFieldList(sql string) (err error) {
 			return errors.Trace(err)
 		}
 	}
-	if err := cc.writeEOF(false); err != nil {
+	if err := cc.writeEOF(0); err != nil {
 		return errors.Trace(err)
 	}
 	return errors.Trace(cc.flush())
@@ -732,67 +924,78 @@ func (cc *clientConn) writeResultset(rs ResultSet, binary bool, more bool) error
 		return errors.Trace(err)
 	}
 
+	if err = cc.writeColumnInfo(columns, 0); err != nil {
+		return errors.Trace(err)
+	}
+
+	data := cc.alloc.AllocWithLen(4, 1024)
+	for {
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		if err = cc.writeRow(data, columns, row, binary); err != nil {
+			return errors.Trace(err)
+		}
+		row, err = rs.Next()
+	}
+
+	var serverStatus uint16
+	if more {
+		serverStatus |= mysql.ServerMoreResultsExists
+	}
+	err = cc.writeEOF(serverStatus)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	return errors.Trace(cc.flush())
+}
+
+// writeColumnInfo writes the column count, the column definitions and the EOF packet of a result set.
+func (cc *clientConn) writeColumnInfo(columns []*ColumnInfo, serverStatus uint16) error {
 	columnLen := dumpLengthEncodedInt(uint64(len(columns)))
 	data := cc.alloc.AllocWithLen(4, 1024)
 	data = append(data, columnLen...)
-	if err = cc.writePacket(data); err != nil {
+	if err := cc.writePacket(data); err != nil {
 		return errors.Trace(err)
 	}
 
 	for _, v := range columns {
 		data = data[0:4]
 		data = append(data, v.Dump(cc.alloc)...)
-		if err = cc.writePacket(data); err != nil {
+		if err := cc.writePacket(data); err != nil {
 			return errors.Trace(err)
 		}
 	}
+	return errors.Trace(cc.writeEOF(serverStatus))
+}
 
-	if err = cc.writeEOF(false); err != nil {
-		return errors.Trace(err)
-	}
-
-	for {
+// writeRow writes a row in the text or binary protocol, data is a reused buffer with at least 4 bytes.
+func (cc *clientConn) writeRow(data []byte, columns []*ColumnInfo, row []types.Datum, binary bool) error {
+	data = data[0:4]
+	if binary {
+		rowData, err := dumpRowValuesBinary(cc.alloc, columns, row)
 		if err != nil {
 			return errors.Trace(err)
 		}
-		if row == nil {
-			break
-		}
-		data = data[0:4]
-		if binary {
-			var rowData []byte
-			rowData, err = dumpRowValuesBinary(cc.alloc, columns, row)
+		data = append(data, rowData...)
+	} else {
+		for i, value := range row {
+			if value.IsNull() {
+				data = append(data, 0xfb)
+				continue
+			}
+			valData, err := dumpTextValue(columns[i].Type, value)
 			if err != nil {
 				return errors.Trace(err)
 			}
-			data = append(data, rowData...)
-		} else {
-			for i, value := range row {
-				if value.IsNull() {
-					data = append(data, 0xfb)
-					continue
-				}
-				var valData []byte
-				valData, err = dumpTextValue(columns[i].Type, value)
-				if err != nil {
-					return errors.Trace(err)
-				}
-				data = append(data, dumpLengthEncodedString(valData, cc.alloc)...)
-			}
+			data = append(data, dumpLengthEncodedString(valData, cc.alloc)...)
 		}
-
-		if err = cc.writePacket(data); err != nil {
-			return errors.Trace(err)
-		}
-		row, err = rs.Next()
-	}
-
-	err = cc.writeEOF(more)
-	if err != nil {
-		return errors.Trace(err)
 	}
-
-	return errors.Trace(cc.flush())
+	return errors.Trace(cc.writePacket(data))
 }
 
 func (cc *clientConn) writeMultiResultset(rss []ResultSet, binary bool) error {
@@ -42,6 +42,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/hack"
+	"github.com/pingcap/tidb/util/types"
 )
 
 func (cc *clientConn) handleStmtPrepare(sql string) error {
@@ -78,7 +79,7 @@ func (cc *clientConn) handleStmtPrepare(sql string) error {
 			}
 		}
 
-		if err := cc.writeEOF(false); err != nil {
+		if err := cc.writeEOF(0); err != nil {
 			return errors.Trace(err)
 		}
 	}
@@ -93,7 +94,7 @@ func (cc *clientConn) handleStmtPrepare(sql string) error {
 			}
 		}
 
-		if err := cc.writeEOF(false); err != nil {
+		if err := cc.writeEOF(0); err != nil {
 			return errors.Trace(err)
 		}
 
@@ -118,10 +119,17 @@ func (cc *clientConn) handleStmtExecute(data []byte) (err error) {
 
 	flag := data[pos]
 	pos++
-	//now we only support CURSOR_TYPE_NO_CURSOR flag
-	if flag != 0 {
+	// Only CURSOR_TYPE_NO_CURSOR and CURSOR_TYPE_READ_ONLY are supported.
+	useCursor := false
+	switch flag {
+	case mysql.CursorTypeNoCursor:
+	case mysql.CursorTypeReadOnly:
+		useCursor = true
+	default:
 		return mysql.NewErrf(mysql.ErrUnknown, "unsupported flag %d", flag)
 	}
+	// Executing the statement again closes the open cursor.
+	stmt.StoreResultSet(nil)
 
 	//skip iteration-count, always 1
 	pos += 4
@@ -170,10 +178,109 @@ func (cc *clientConn) handleStmtExecute(data []byte) (err error) {
 	if rs == nil {
 		return errors.Trace(cc.writeOK())
 	}
+	if useCursor {
+		return errors.Trace(cc.openCursor(stmt, rs))
+	}
 
 	return errors.Trace(cc.writeResultset(rs, true, false))
 }
 
+// openCursor sends the columns of the result set and keeps the result set open on the statement, the rows are
+// sent by COM_STMT_FETCH.
+func (cc *clientConn) openCursor(stmt PreparedStatement, rs ResultSet) error {
+	cursor, err := newCursorResultSet(rs)
+	if err != nil {
+		rs.Close()
+		return errors.Trace(err)
+	}
+	stmt.StoreResultSet(cursor)
+	if err = cc.writeColumnInfo(cursor.columns, mysql.ServerStatusCursorExists); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(cc.flush())
+}
+
+// handleStmtFetch sends at most the requested number of rows of the open cursor. The cursor is closed after the
+// last row is sent.
+// See https://dev.mysql.com/doc/internals/en/com-stmt-fetch.html
+func (cc *clientConn) handleStmtFetch(data []byte) error {
+	if len(data) < 8 {
+		return mysql.ErrMalformPacket
+	}
+	stmtID := binary.LittleEndian.Uint32(data[0:4])
+	numRows := binary.LittleEndian.Uint32(data[4:8])
+	stmt := cc.ctx.GetStatement(int(stmtID))
+	if stmt == nil {
+		return mysql.NewErr(mysql.ErrUnknownStmtHandler,
+			strconv.FormatUint(uint64(stmtID), 10), "stmt_fetch")
+	}
+	rs := stmt.GetResultSet()
+	if rs == nil {
+		return mysql.NewErr(mysql.ErrStmtHasNoOpenCursor, stmtID)
+	}
+	columns, err := rs.Columns()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	serverStatus := mysql.ServerStatusCursorExists
+	data = cc.alloc.AllocWithLen(4, 1024)
+	for i := uint32(0); i < numRows; i++ {
+		row, err := rs.Next()
+		if err != nil {
+			stmt.StoreResultSet(nil)
+			return errors.Trace(err)
+		}
+		if row == nil {
+			serverStatus |= mysql.ServerStatusLastRowSend
+			stmt.StoreResultSet(nil)
+			break
+		}
+		if err = cc.writeRow(data, columns, row, true); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if err = cc.writeEOF(serverStatus); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(cc.flush())
+}
+
+// cursorResultSet is the result set kept open on a statement executed with a cursor. The first row is fetched
+// in advance, because the columns are only available after calling Next.
+type cursorResultSet struct {
+	ResultSet
+	columns  []*ColumnInfo
+	firstRow []types.Datum
+}
+
+func newCursorResultSet(rs ResultSet) (*cursorResultSet, error) {
+	row, err := rs.Next()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	columns, err := rs.Columns()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &cursorResultSet{ResultSet: rs, columns: columns, firstRow: row}, nil
+}
+
+// Columns implements ResultSet Columns method.
+func (rs *cursorResultSet) Columns() ([]*ColumnInfo, error) {
+	return rs.columns, nil
+}
+
+// Next implements ResultSet Next method.
+func (rs *cursorResultSet) Next() ([]types.Datum, error) {
+	if rs.firstRow != nil {
+		row := rs.firstRow
+		rs.firstRow = nil
+		return row, nil
+	}
+	return rs.ResultSet.Next()
+}
+
 func parseStmtArgs(args []interface{}, boundParams [][]byte, nullBitmap, paramTypes, paramValues []byte) (err error) {
 	pos := 0
 	var v []byte
@@ -371,7 +478,7 @@ func (cc *clientConn) handleSetOption(data []byte) (err error) {
 	default:
 		return mysql.ErrMalformPacket
 	}
-	if err = cc.writeEOF(false); err != nil {
+	if err = cc.writeEOF(0); err != nil {
 		return errors.Trace(err)
 	}
 
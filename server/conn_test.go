@@ -14,8 +14,13 @@
 package server
 
 import (
+	"bytes"
+	"net"
+	"sync"
+
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util"
 )
 
 type ConnTestSuite struct{}
@@ -70,6 +75,34 @@ func (ts ConnTestSuite) TestHandshakeResponseFromData(c *C) {
 	c.Assert(p.Capability&capability, Equals, capability)
 	c.Assert(p.User, Equals, "pam")
 	c.Assert(p.DBName, Equals, "test")
+	c.Assert(p.AuthPlugin, Equals, mysql.AuthNativePassword)
+}
+
+func (ts ConnTestSuite) TestChangeUserFromData(c *C) {
+	c.Parallel()
+	capability := mysql.ClientProtocol41 | mysql.ClientSecureConnection | mysql.ClientPluginAuth | mysql.ClientConnectAtts
+	data := []byte("root\x00\x03abctest\x00\x21\x00caching_sha2_password\x00\x08\x03foo\x03bar")
+	var p handshakeResponse41
+	err := changeUserFromData(&p, data, capability)
+	c.Assert(err, IsNil)
+	c.Assert(p.User, Equals, "root")
+	c.Assert(p.Auth, DeepEquals, []byte("abc"))
+	c.Assert(p.DBName, Equals, "test")
+	c.Assert(p.Collation, Equals, uint8(0x21))
+	c.Assert(p.AuthPlugin, Equals, mysql.AuthCachingSha2Password)
+	c.Assert(p.Attrs, DeepEquals, map[string]string{"foo": "bar"})
+
+	// The packet of the old clients ends after the schema.
+	p = handshakeResponse41{}
+	err = changeUserFromData(&p, []byte("root\x00\x00test\x00"), mysql.ClientProtocol41|mysql.ClientSecureConnection)
+	c.Assert(err, IsNil)
+	c.Assert(p.DBName, Equals, "test")
+	c.Assert(p.Collation, Equals, uint8(0))
+	c.Assert(p.AuthPlugin, Equals, mysql.AuthNativePassword)
+
+	p = handshakeResponse41{}
+	err = changeUserFromData(&p, []byte("root\x00\x10abc"), capability)
+	c.Assert(err, NotNil)
 }
 
 func (ts ConnTestSuite) TestIssue1768(c *C) {
@@ -110,3 +143,49 @@ func mapBelong(m1, m2 map[string]string) bool {
 	}
 	return true
 }
+
+func (ts ConnTestSuite) TestCompressedPacketIO(c *C) {
+	c.Parallel()
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	writer, reader := newPacketIO(client), newPacketIO(server)
+	writer.compressed, reader.compressed = true, true
+
+	// A short packet which is not compressed, and a large packet which spans several compressed packets.
+	payloads := [][]byte{[]byte("short"), bytes.Repeat([]byte("tidb"), mysql.MaxPayloadLen/2)}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, payload := range payloads {
+			data := make([]byte, 4, 4+len(payload))
+			data = append(data, payload...)
+			c.Check(writer.writePacket(data), IsNil)
+		}
+		// The full compressed packets are written before flushing.
+		c.Check(writer.compressedWriteBuf.Len() < mysql.MaxPayloadLen, IsTrue)
+		c.Check(writer.flush(), IsNil)
+	}()
+	for _, payload := range payloads {
+		data, err := reader.readPacket()
+		c.Assert(err, IsNil)
+		c.Assert(bytes.Equal(data, payload), IsTrue)
+	}
+	<-done
+	c.Assert(reader.compressedSequence, Equals, writer.compressedSequence)
+}
+
+func (ts ConnTestSuite) TestAllocConnID(c *C) {
+	c.Parallel()
+	s := &Server{
+		rwlock:  &sync.RWMutex{},
+		clients: make(map[uint32]*clientConn),
+	}
+	c.Assert(util.ServerIDOfConn(uint64(s.allocConnID())), Equals, uint64(0))
+	s.SetServerID(3)
+	connID := s.allocConnID()
+	c.Assert(util.ServerIDOfConn(uint64(connID)), Equals, uint64(3))
+	// The IDs of the alive connections are skipped.
+	s.clients[connID+1] = &clientConn{}
+	c.Assert(s.allocConnID(), Not(Equals), connID+1)
+}
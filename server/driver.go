@@ -14,8 +14,10 @@
 package server
 
 import (
+	"crypto/tls"
 	"fmt"
 
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -61,6 +63,12 @@ type QueryCtx interface {
 	// SetClientCapability sets client capability flags
 	SetClientCapability(uint32)
 
+	// SetHaveSSL sets have_ssl of the session to YES, it's called if the server enables TLS.
+	SetHaveSSL()
+
+	// SetTLSState sets the TLS state of the connection, it should be called before Auth.
+	SetTLSState(tlsState *tls.ConnectionState)
+
 	// Prepare prepares a statement.
 	Prepare(sql string) (statement PreparedStatement, columns, params []*ColumnInfo, err error)
 
@@ -76,6 +84,12 @@ type QueryCtx interface {
 	// Auth verifies user's authentication.
 	Auth(user string, auth []byte, salt []byte) bool
 
+	// AuthPlugin returns the auth plugin of the user which is in the format of user@host.
+	AuthPlugin(user string) string
+
+	// RequestVerification verifies the privilege of the user who is authenticated by Auth.
+	RequestVerification(db, table string, priv mysql.PrivilegeType) bool
+
 	// ShowProcess shows the information about the session.
 	ShowProcess() util.ProcessInfo
 
@@ -108,9 +122,16 @@ type PreparedStatement interface {
 	// GetParamsType returns the type for parameters.
 	GetParamsType() []byte
 
-	// Reset removes all bound parameters.
+	// Reset removes all bound parameters and closes the stored result set.
 	Reset()
 
+	// StoreResultSet stores the result set of the statement executed with a cursor, the rows are fetched by
+	// COM_STMT_FETCH later. The previously stored result set is closed.
+	StoreResultSet(rs ResultSet)
+
+	// GetResultSet returns the stored result set, it returns nil if there is no open cursor.
+	GetResultSet() ResultSet
+
 	// Close closes the statement.
 	Close() error
 }
@@ -14,13 +14,17 @@
 package server
 
 import (
+	"crypto/tls"
 	"fmt"
 
 	"github.com/juju/errors"
+	"github.com/ngaut/log"
 	"github.com/pingcap/tidb"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -52,6 +56,7 @@ type TiDBStatement struct {
 	boundParams [][]byte
 	paramsType  []byte
 	ctx         *TiDBContext
+	rs          ResultSet
 }
 
 // ID implements PreparedStatement ID method.
@@ -108,10 +113,27 @@ func (ts *TiDBStatement) Reset() {
 	for i := range ts.boundParams {
 		ts.boundParams[i] = nil
 	}
+	ts.StoreResultSet(nil)
+}
+
+// StoreResultSet implements PreparedStatement StoreResultSet method.
+func (ts *TiDBStatement) StoreResultSet(rs ResultSet) {
+	if ts.rs != nil {
+		if err := ts.rs.Close(); err != nil {
+			log.Error(errors.ErrorStack(err))
+		}
+	}
+	ts.rs = rs
+}
+
+// GetResultSet implements PreparedStatement GetResultSet method.
+func (ts *TiDBStatement) GetResultSet() ResultSet {
+	return ts.rs
 }
 
 // Close implements PreparedStatement Close method.
 func (ts *TiDBStatement) Close() error {
+	ts.StoreResultSet(nil)
 	//TODO close at tidb level
 	err := ts.ctx.session.DropPreparedStmt(ts.id)
 	if err != nil {
@@ -216,8 +238,22 @@ func (tc *TiDBContext) SetClientCapability(flags uint32) {
 	tc.session.SetClientCapability(flags)
 }
 
+// SetHaveSSL implements QueryCtx SetHaveSSL method.
+func (tc *TiDBContext) SetHaveSSL() {
+	tc.session.GetSessionVars().Systems[variable.HaveSSL] = "YES"
+}
+
+// SetTLSState implements QueryCtx SetTLSState method.
+func (tc *TiDBContext) SetTLSState(tlsState *tls.ConnectionState) {
+	tc.session.GetSessionVars().TLSConnectionState = tlsState
+}
+
 // Close implements QueryCtx Close method.
 func (tc *TiDBContext) Close() (err error) {
+	// Close the open cursors.
+	for _, stmt := range tc.stmts {
+		stmt.StoreResultSet(nil)
+	}
 	return tc.session.Close()
 }
 
@@ -226,6 +262,11 @@ func (tc *TiDBContext) Auth(user string, auth []byte, salt []byte) bool {
 	return tc.session.Auth(user, auth, salt)
 }
 
+// AuthPlugin implements QueryCtx AuthPlugin method.
+func (tc *TiDBContext) AuthPlugin(user string) string {
+	return tc.session.AuthPlugin(user)
+}
+
 // FieldList implements QueryCtx FieldList method.
 func (tc *TiDBContext) FieldList(table string) (colums []*ColumnInfo, err error) {
 	rs, err := tc.Execute("SELECT * FROM `" + table + "` LIMIT 0")
@@ -275,6 +316,15 @@ func (tc *TiDBContext) Prepare(sql string) (statement PreparedStatement, columns
 	return
 }
 
+// RequestVerification implements QueryCtx RequestVerification method.
+func (tc *TiDBContext) RequestVerification(db, table string, priv mysql.PrivilegeType) bool {
+	pm := privilege.GetPrivilegeManager(tc.session)
+	if pm == nil {
+		return true
+	}
+	return pm.RequestVerification(tc.session.GetSessionVars().ActiveRoles, db, table, "", priv)
+}
+
 // ShowProcess implements QueryCtx ShowProcess method.
 func (tc *TiDBContext) ShowProcess() util.ProcessInfo {
 	return tc.session.ShowProcess()
@@ -16,6 +16,7 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/pingcap/pd/pd-client"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/store/tikv"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/printer"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -64,12 +66,13 @@ func (s *Server) getPdClient() (pd.Client, error) {
 func (s *Server) startHTTPServer(pdClient pd.Client) {
 	router := mux.NewRouter()
 	router.HandleFunc("/status", s.handleStatus)
+	router.Handle("/config/reload", s.requirePrivilege(mysql.ReloadPriv, http.HandlerFunc(s.handleReloadConfig))).Methods("POST")
 	// HTTP path for prometheus.
 	router.Handle("/metrics", prometheus.Handler())
 
 	// HTTP path for regions
-	router.Handle("/tables/{db}/{table}/regions", s.newTableRegionsHandler(pdClient))
-	router.Handle("/regions/{regionID}", s.newRegionHandler(pdClient))
+	router.Handle("/tables/{db}/{table}/regions", s.requirePrivilege(mysql.ProcessPriv, s.newTableRegionsHandler(pdClient)))
+	router.Handle("/regions/{regionID}", s.requirePrivilege(mysql.ProcessPriv, s.newRegionHandler(pdClient)))
 
 	addr := s.cfg.StatusAddr
 	if len(addr) == 0 {
@@ -88,6 +91,8 @@ type status struct {
 	Connections int    `json:"connections"`
 	Version     string `json:"version"`
 	GitHash     string `json:"git_hash"`
+	// Draining is true when the server is shutting down, the load balancers shouldn't route to it.
+	Draining bool `json:"draining"`
 }
 
 func (s *Server) handleStatus(w http.ResponseWriter, req *http.Request) {
@@ -97,12 +102,82 @@ func (s *Server) handleStatus(w http.ResponseWriter, req *http.Request) {
 		Connections: s.ConnectionCount(),
 		Version:     mysql.ServerVersion,
 		GitHash:     printer.TiDBGitHash,
+		Draining:    s.IsDraining(),
 	}
 	js, err := json.Marshal(st)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		log.Error("Encode json error", err)
-	} else {
-		w.Write(js)
+		return
 	}
+	if st.Draining {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(js)
+}
+
+// handleReloadConfig reloads the reloadable settings of the configuration file.
+func (s *Server) handleReloadConfig(w http.ResponseWriter, req *http.Request) {
+	s.rwlock.RLock()
+	reload := s.reloadConfig
+	s.rwlock.RUnlock()
+	if reload == nil {
+		http.Error(w, "the server is not started with a config file", http.StatusBadRequest)
+		return
+	}
+	if err := reload(); err != nil {
+		log.Errorf("reload config error: %v", errors.ErrorStack(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Write([]byte("config is reloaded"))
+}
+
+// requirePrivilege wraps h to authenticate the requests by the HTTP basic authentication with a MySQL
+// account, which must have the global privilege priv.
+func (s *Server) requirePrivilege(priv mysql.PrivilegeType, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		user, pwd, ok := req.BasicAuth()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="TiDB"`)
+			http.Error(w, "authentication is required", http.StatusUnauthorized)
+			return
+		}
+		host, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			host = req.RemoteAddr
+		}
+		ctx, err := s.driver.OpenCtx(0, 0, mysql.DefaultCollationID, "")
+		if err != nil {
+			log.Errorf("open context for HTTP authentication error: %v", errors.ErrorStack(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer ctx.Close()
+		if !httpAuth(ctx, user+"@"+host, pwd) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="TiDB"`)
+			http.Error(w, fmt.Sprintf("access denied for user '%s'@'%s'", user, host), http.StatusUnauthorized)
+			return
+		}
+		if !ctx.RequestVerification("", "", priv) {
+			http.Error(w, fmt.Sprintf("the %s privilege is required", mysql.Priv2Str[priv]), http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, req)
+	})
+}
+
+// httpAuth authenticates the user with the plaintext password pwd.
+func httpAuth(ctx QueryCtx, user, pwd string) bool {
+	switch ctx.AuthPlugin(user) {
+	case mysql.AuthCachingSha2Password, mysql.AuthSha256Password:
+		return ctx.Auth(user, []byte(pwd), nil)
+	}
+	// Make the scramble of mysql_native_password as the clients do.
+	salt := randomBuf(20)
+	var auth []byte
+	if pwd != "" {
+		auth = util.CalcPassword(salt, util.Sha1Hash([]byte(pwd)))
+	}
+	return ctx.Auth(user, auth, salt)
 }
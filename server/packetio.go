@@ -36,6 +36,8 @@ package server
 
 import (
 	"bufio"
+	"bytes"
+	"compress/zlib"
 	"io"
 	"net"
 
@@ -46,6 +48,8 @@ import (
 const (
 	defaultReaderSize = 16 * 1024
 	defaultWriterSize = 16 * 1024
+	// minCompressLength is the minimum payload length to compress, a shorter payload is sent uncompressed.
+	minCompressLength = 50
 )
 
 // packetIO is a helper to read and write data in packet format.
@@ -54,6 +58,20 @@ type packetIO struct {
 	wb *bufio.Writer
 
 	sequence uint8
+
+	// compressed is set after the handshake if the client uses the compressed protocol, the packets are
+	// carried in the payload of compressed packets, which have their own sequence.
+	// See https://dev.mysql.com/doc/internals/en/compressed-packet-header.html
+	compressed         bool
+	compressedSequence uint8
+	// compressedReadBuf is the uncompressed payload of the last compressed packet which is not read yet.
+	compressedReadBuf []byte
+	// compressedWriteBuf buffers the packets to write, they are compressed when they fill a compressed packet
+	// or when flushing.
+	compressedWriteBuf bytes.Buffer
+	// zw compresses the payloads of the compressed packets into zbuf, it's reused by the packets.
+	zw   *zlib.Writer
+	zbuf bytes.Buffer
 }
 
 func newPacketIO(conn net.Conn) *packetIO {
@@ -65,29 +83,104 @@ func newPacketIO(conn net.Conn) *packetIO {
 	return p
 }
 
+// setConn switches the underlying connection, the packet sequence is kept.
+func (p *packetIO) setConn(conn net.Conn) {
+	p.rb = bufio.NewReaderSize(conn, defaultReaderSize)
+	p.wb = bufio.NewWriterSize(conn, defaultWriterSize)
+}
+
+// resetSequence resets the sequences at the beginning of a command.
+func (p *packetIO) resetSequence() {
+	p.sequence = 0
+	p.compressedSequence = 0
+}
+
 func (p *packetIO) readOnePacket() ([]byte, error) {
 	var header [4]byte
 
-	if _, err := io.ReadFull(p.rb, header[:]); err != nil {
+	if err := p.readFull(header[:]); err != nil {
 		return nil, errors.Trace(err)
 	}
 
 	sequence := uint8(header[3])
-	if sequence != p.sequence {
-		return nil, errInvalidSequence.Gen("invalid sequence %d != %d", sequence, p.sequence)
+	if p.compressed {
+		// Like MySQL, the sequence of the packets in the compressed packets is not checked, and the packets
+		// to write next are numbered from the compressed sequence.
+		p.sequence = p.compressedSequence
+	} else {
+		if sequence != p.sequence {
+			return nil, errInvalidSequence.Gen("invalid sequence %d != %d", sequence, p.sequence)
+		}
+		p.sequence++
 	}
 
-	p.sequence++
-
 	length := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
 
 	data := make([]byte, length)
-	if _, err := io.ReadFull(p.rb, data); err != nil {
+	if err := p.readFull(data); err != nil {
 		return nil, errors.Trace(err)
 	}
 	return data, nil
 }
 
+// readFull reads exactly len(buf) bytes, from the connection or the payload of the compressed packets.
+func (p *packetIO) readFull(buf []byte) error {
+	if !p.compressed {
+		_, err := io.ReadFull(p.rb, buf)
+		return errors.Trace(err)
+	}
+	for len(buf) > 0 {
+		if len(p.compressedReadBuf) == 0 {
+			if err := p.readCompressedPacket(); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		n := copy(buf, p.compressedReadBuf)
+		p.compressedReadBuf = p.compressedReadBuf[n:]
+		buf = buf[n:]
+	}
+	return nil
+}
+
+// readCompressedPacket reads a compressed packet, and keeps its uncompressed payload in compressedReadBuf.
+func (p *packetIO) readCompressedPacket() error {
+	var header [7]byte
+
+	if _, err := io.ReadFull(p.rb, header[:]); err != nil {
+		return errors.Trace(err)
+	}
+
+	sequence := uint8(header[3])
+	if sequence != p.compressedSequence {
+		return errInvalidSequence.Gen("invalid compressed sequence %d != %d", sequence, p.compressedSequence)
+	}
+	p.compressedSequence++
+
+	length := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+	uncompressedLength := int(uint32(header[4]) | uint32(header[5])<<8 | uint32(header[6])<<16)
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(p.rb, data); err != nil {
+		return errors.Trace(err)
+	}
+	if uncompressedLength == 0 {
+		// The payload is too short to be compressed.
+		p.compressedReadBuf = data
+		return nil
+	}
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer r.Close()
+	payload := make([]byte, uncompressedLength)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return errors.Trace(err)
+	}
+	p.compressedReadBuf = payload
+	return nil
+}
+
 func (p *packetIO) readPacket() ([]byte, error) {
 	data, err := p.readOnePacket()
 	if err != nil {
@@ -126,7 +219,7 @@ func (p *packetIO) writePacket(data []byte) error {
 
 		data[3] = p.sequence
 
-		if n, err := p.wb.Write(data[:4+mysql.MaxPayloadLen]); err != nil {
+		if n, err := p.write(data[:4+mysql.MaxPayloadLen]); err != nil {
 			return mysql.ErrBadConn
 		} else if n != (4 + mysql.MaxPayloadLen) {
 			return mysql.ErrBadConn
@@ -142,7 +235,7 @@ func (p *packetIO) writePacket(data []byte) error {
 	data[2] = byte(length >> 16)
 	data[3] = p.sequence
 
-	if n, err := p.wb.Write(data); err != nil {
+	if n, err := p.write(data); err != nil {
 		return errors.Trace(mysql.ErrBadConn)
 	} else if n != len(data) {
 		return errors.Trace(mysql.ErrBadConn)
@@ -152,6 +245,82 @@ func (p *packetIO) writePacket(data []byte) error {
 	}
 }
 
+// write writes to the connection, or buffers the data to compress if the compressed protocol is used.
+func (p *packetIO) write(data []byte) (int, error) {
+	if !p.compressed {
+		return p.wb.Write(data)
+	}
+	n, err := p.compressedWriteBuf.Write(data)
+	if err != nil {
+		return n, errors.Trace(err)
+	}
+	// The full compressed packets are written at once, so a large result set is not buffered in memory.
+	if err = p.writeCompressedPackets(false); err != nil {
+		return 0, errors.Trace(err)
+	}
+	return n, nil
+}
+
 func (p *packetIO) flush() error {
+	if p.compressed {
+		if err := p.writeCompressedPackets(true); err != nil {
+			return errors.Trace(err)
+		}
+	}
 	return p.wb.Flush()
 }
+
+// writeCompressedPackets writes the buffered packets in compressed packets. If all is false, only the full
+// compressed packets are written, and the rest is kept in the buffer.
+func (p *packetIO) writeCompressedPackets(all bool) error {
+	for p.compressedWriteBuf.Len() >= mysql.MaxPayloadLen || (all && p.compressedWriteBuf.Len() > 0) {
+		length := p.compressedWriteBuf.Len()
+		if length > mysql.MaxPayloadLen {
+			length = mysql.MaxPayloadLen
+		}
+		if err := p.writeCompressedPacket(p.compressedWriteBuf.Next(length)); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// writeCompressedPacket compresses the payload and writes it in a compressed packet.
+func (p *packetIO) writeCompressedPacket(payload []byte) error {
+	data := payload
+	uncompressedLength := 0
+	if len(payload) >= minCompressLength {
+		p.zbuf.Reset()
+		if p.zw == nil {
+			p.zw = zlib.NewWriter(&p.zbuf)
+		} else {
+			p.zw.Reset(&p.zbuf)
+		}
+		if _, err := p.zw.Write(payload); err != nil {
+			return errors.Trace(err)
+		}
+		if err := p.zw.Close(); err != nil {
+			return errors.Trace(err)
+		}
+		// Send the payload uncompressed if it can't be compressed.
+		if p.zbuf.Len() < len(payload) {
+			data = p.zbuf.Bytes()
+			uncompressedLength = len(payload)
+		}
+	}
+
+	length := len(data)
+	header := []byte{
+		byte(length), byte(length >> 8), byte(length >> 16),
+		p.compressedSequence,
+		byte(uncompressedLength), byte(uncompressedLength >> 8), byte(uncompressedLength >> 16),
+	}
+	if _, err := p.wb.Write(header); err != nil {
+		return errors.Trace(mysql.ErrBadConn)
+	}
+	if _, err := p.wb.Write(data); err != nil {
+		return errors.Trace(mysql.ErrBadConn)
+	}
+	p.compressedSequence++
+	return nil
+}
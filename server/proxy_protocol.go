@@ -0,0 +1,203 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// The PROXY protocol lets a proxy pass the address of the client to the server, it's sent by the proxy before
+// any data of the client. See https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt
+var (
+	proxyV1Prefix    = []byte("PROXY ")
+	proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+	// proxyHeaderTimeout is the time to wait for the PROXY protocol header of a trusted proxy.
+	proxyHeaderTimeout = 5 * time.Second
+)
+
+const (
+	// proxyV1MaxLen is the maximum length of the v1 header including the CRLF.
+	proxyV1MaxLen = 107
+
+	proxyV2CmdLocal = 0x0
+	proxyV2CmdProxy = 0x1
+
+	proxyV2FamilyInet  = 0x1
+	proxyV2FamilyInet6 = 0x2
+)
+
+var errInvalidProxyHeader = errors.New("invalid PROXY protocol header")
+
+// proxyProtocolConn is a connection from a trusted proxy, its remote address is the address of the client
+// in the PROXY protocol header.
+type proxyProtocolConn struct {
+	net.Conn
+	clientAddr net.Addr
+}
+
+// RemoteAddr implements the net.Conn RemoteAddr interface.
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	return c.clientAddr
+}
+
+// parseProxyNetworks parses the comma separated list of the trusted proxy networks, an item can be a CIDR
+// or an IP address.
+func parseProxyNetworks(networks string) ([]*net.IPNet, error) {
+	var ipNets []*net.IPNet
+	for _, item := range strings.Split(networks, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, errors.Errorf("invalid proxy network %s", item)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			ipNets = append(ipNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, errors.Errorf("invalid proxy network %s", item)
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets, nil
+}
+
+// isTrustedProxy returns whether the connection from addr must send the PROXY protocol header.
+func (s *Server) isTrustedProxy(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, ipNet := range s.proxyNetworks {
+		if ipNet.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// readProxyHeader reads the PROXY protocol header of v1 or v2 and returns the address of the client. The
+// address is nil if the proxy doesn't tell it, e.g. the health checks of the proxy.
+func readProxyHeader(conn net.Conn) (net.Addr, error) {
+	err := conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	prefix := make([]byte, len(proxyV1Prefix))
+	if _, err = io.ReadFull(conn, prefix); err != nil {
+		return nil, errors.Trace(err)
+	}
+	var addr net.Addr
+	switch {
+	case bytes.Equal(prefix, proxyV1Prefix):
+		addr, err = readProxyV1Header(conn)
+	case bytes.Equal(prefix, proxyV2Signature[:len(prefix)]):
+		addr, err = readProxyV2Header(conn)
+	default:
+		err = errInvalidProxyHeader
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return addr, errors.Trace(conn.SetReadDeadline(time.Time{}))
+}
+
+// readProxyV1Header reads the human-readable header after the "PROXY " prefix, e.g.
+// "TCP4 192.168.0.1 192.168.0.11 56324 4000\r\n".
+func readProxyV1Header(conn net.Conn) (net.Addr, error) {
+	// The header is read byte by byte, so the data of the client after it isn't consumed.
+	line := make([]byte, 0, proxyV1MaxLen-len(proxyV1Prefix))
+	b := make([]byte, 1)
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) == cap(line) {
+			return nil, errInvalidProxyHeader
+		}
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return nil, errors.Trace(err)
+		}
+		line = append(line, b[0])
+	}
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if fields[0] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 5 || (fields[0] != "TCP4" && fields[0] != "TCP6") {
+		return nil, errInvalidProxyHeader
+	}
+	ip := net.ParseIP(fields[1])
+	port, err := strconv.ParseUint(fields[3], 10, 16)
+	if ip == nil || err != nil {
+		return nil, errInvalidProxyHeader
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyV2Header reads the binary header after the first bytes of the signature.
+func readProxyV2Header(conn net.Conn) (net.Addr, error) {
+	// The rest of the signature, the version and command, the address family and the length of the addresses.
+	header := make([]byte, len(proxyV2Signature)-len(proxyV1Prefix)+4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, errors.Trace(err)
+	}
+	sigLen := len(proxyV2Signature) - len(proxyV1Prefix)
+	if !bytes.Equal(header[:sigLen], proxyV2Signature[len(proxyV1Prefix):]) || header[sigLen]>>4 != 2 {
+		return nil, errInvalidProxyHeader
+	}
+	cmd, family := header[sigLen]&0xf, header[sigLen+1]>>4
+	data := make([]byte, binary.BigEndian.Uint16(header[sigLen+2:]))
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return nil, errors.Trace(err)
+	}
+	switch cmd {
+	case proxyV2CmdLocal:
+		return nil, nil
+	case proxyV2CmdProxy:
+	default:
+		return nil, errInvalidProxyHeader
+	}
+	// The addresses are the source address, the destination address, the source port and the destination port.
+	var ipLen int
+	switch family {
+	case proxyV2FamilyInet:
+		ipLen = net.IPv4len
+	case proxyV2FamilyInet6:
+		ipLen = net.IPv6len
+	default:
+		// The addresses of the other families are ignored.
+		return nil, nil
+	}
+	if len(data) < 2*ipLen+4 {
+		return nil, errInvalidProxyHeader
+	}
+	return &net.TCPAddr{
+		IP:   net.IP(data[:ipLen]),
+		Port: int(binary.BigEndian.Uint16(data[2*ipLen:])),
+	}, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/binary"
+	"net"
+
+	. "github.com/pingcap/check"
+)
+
+type testProxyProtocolSuite struct{}
+
+var _ = Suite(&testProxyProtocolSuite{})
+
+// proxyV2Header makes a v2 header of the PROXY command for the TCP connection from src to dst.
+func proxyV2Header(src, dst *net.TCPAddr) []byte {
+	header := append([]byte{}, proxyV2Signature...)
+	family := byte(proxyV2FamilyInet6)
+	srcIP, dstIP := src.IP.To16(), dst.IP.To16()
+	if src.IP.To4() != nil {
+		family = proxyV2FamilyInet
+		srcIP, dstIP = src.IP.To4(), dst.IP.To4()
+	}
+	header = append(header, 0x20|proxyV2CmdProxy, family<<4|0x1, 0, 0)
+	binary.BigEndian.PutUint16(header[len(header)-2:], uint16(2*len(srcIP)+4))
+	header = append(header, srcIP...)
+	header = append(header, dstIP...)
+	header = append(header, byte(src.Port>>8), byte(src.Port), byte(dst.Port>>8), byte(dst.Port))
+	return header
+}
+
+func (s *testProxyProtocolSuite) TestReadProxyHeader(c *C) {
+	c.Parallel()
+	dst := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 4000}
+	v2Local := append(append([]byte{}, proxyV2Signature...), 0x20|proxyV2CmdLocal, 0, 0, 0)
+	tbl := []struct {
+		header []byte
+		addr   string
+		errMsg string
+	}{
+		{[]byte("PROXY TCP4 192.168.1.10 127.0.0.1 5000 4000\r\n"), "192.168.1.10:5000", ""},
+		{[]byte("PROXY TCP6 2001:db8::1 ::1 5000 4000\r\n"), "[2001:db8::1]:5000", ""},
+		{[]byte("PROXY UNKNOWN\r\n"), "", ""},
+		{[]byte("PROXY TCP4 192.168.1.10 127.0.0.1 x 4000\r\n"), "", "invalid PROXY protocol header"},
+		{[]byte("PROXY TCP4 192.168.1.10 127.0.0.1 5000\r\n"), "", "invalid PROXY protocol header"},
+		{append([]byte("PROXY "), make([]byte, proxyV1MaxLen)...), "", "invalid PROXY protocol header"},
+		{proxyV2Header(&net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 6000}, dst), "10.0.0.2:6000", ""},
+		{proxyV2Header(&net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 6000}, &net.TCPAddr{IP: net.ParseIP("::1"), Port: 4000}), "[2001:db8::2]:6000", ""},
+		{v2Local, "", ""},
+		{[]byte("\r\n\r\n\x00\r\nQUIX\n\x21\x11\x00\x00"), "", "invalid PROXY protocol header"},
+		{[]byte("GET / HTTP/1.1\r\n"), "", "invalid PROXY protocol header"},
+	}
+	for _, t := range tbl {
+		client, server := net.Pipe()
+		go func(header []byte) {
+			client.Write(append(header, "payload"...))
+		}(t.header)
+		addr, err := readProxyHeader(server)
+		if t.errMsg != "" {
+			c.Assert(err, ErrorMatches, t.errMsg, Commentf("%q", t.header))
+			server.Close()
+			continue
+		}
+		c.Assert(err, IsNil, Commentf("%q", t.header))
+		if t.addr == "" {
+			c.Assert(addr, IsNil)
+		} else {
+			c.Assert(addr.String(), Equals, t.addr)
+		}
+		// The data after the header isn't consumed.
+		buf := make([]byte, len("payload"))
+		_, err = server.Read(buf)
+		c.Assert(err, IsNil)
+		c.Assert(string(buf), Equals, "payload")
+		server.Close()
+	}
+}
+
+func (s *testProxyProtocolSuite) TestParseProxyNetworks(c *C) {
+	c.Parallel()
+	ipNets, err := parseProxyNetworks("")
+	c.Assert(err, IsNil)
+	c.Assert(ipNets, HasLen, 0)
+	ipNets, err = parseProxyNetworks("192.168.1.0/24, 10.0.0.1,::1")
+	c.Assert(err, IsNil)
+	server := &Server{proxyNetworks: ipNets}
+	c.Assert(server.isTrustedProxy(&net.TCPAddr{IP: net.ParseIP("192.168.1.100")}), IsTrue)
+	c.Assert(server.isTrustedProxy(&net.TCPAddr{IP: net.ParseIP("192.168.2.1")}), IsFalse)
+	c.Assert(server.isTrustedProxy(&net.TCPAddr{IP: net.ParseIP("10.0.0.1")}), IsTrue)
+	c.Assert(server.isTrustedProxy(&net.TCPAddr{IP: net.ParseIP("10.0.0.2")}), IsFalse)
+	c.Assert(server.isTrustedProxy(&net.TCPAddr{IP: net.ParseIP("::1")}), IsTrue)
+	c.Assert(server.isTrustedProxy(&net.UnixAddr{Name: "/tmp/tidb.sock", Net: "unix"}), IsFalse)
+	_, err = parseProxyNetworks("10.0.0.0/33")
+	c.Assert(err, ErrorMatches, "invalid proxy network 10.0.0.0/33")
+	_, err = parseProxyNetworks("localhost")
+	c.Assert(err, ErrorMatches, "invalid proxy network localhost")
+}
@@ -110,10 +110,20 @@ func (ts *TidbRegionHandlerTestSuite) TestRegionIndexRangeWithStartNoLimit(c *C)
 	c.Assert(end, Equals, int64(math.MaxInt64))
 }
 
+// getWithRoot sends a GET request with the basic authentication of root.
+func getWithRoot(url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth("root", "")
+	return http.DefaultClient.Do(req)
+}
+
 func (ts *TidbRegionHandlerTestSuite) TestRegionsAPI(c *C) {
 	ts.startServer(c)
 	defer ts.stopServer(c)
-	resp, err := http.Get("http://127.0.0.1:10090/tables/information_schema/SCHEMATA/regions")
+	resp, err := getWithRoot("http://127.0.0.1:10090/tables/information_schema/SCHEMATA/regions")
 	c.Assert(err, IsNil)
 	c.Assert(resp.StatusCode, Equals, http.StatusOK)
 	defer resp.Body.Close()
@@ -131,7 +141,7 @@ func (ts *TidbRegionHandlerTestSuite) TestRegionsAPI(c *C) {
 }
 
 func regionContainsTable(c *C, regionID uint64, tableID int64) bool {
-	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:10090/regions/%d", regionID))
+	resp, err := getWithRoot(fmt.Sprintf("http://127.0.0.1:10090/regions/%d", regionID))
 	c.Assert(err, IsNil)
 	c.Assert(resp.StatusCode, Equals, http.StatusOK)
 	defer resp.Body.Close()
@@ -150,7 +160,7 @@ func regionContainsTable(c *C, regionID uint64, tableID int64) bool {
 func (ts *TidbRegionHandlerTestSuite) TestListTableRegionsWithError(c *C) {
 	ts.startServer(c)
 	defer ts.stopServer(c)
-	resp, err := http.Get("http://127.0.0.1:10090/tables/fdsfds/aaa/regions")
+	resp, err := getWithRoot("http://127.0.0.1:10090/tables/fdsfds/aaa/regions")
 	defer resp.Body.Close()
 	c.Assert(err, IsNil)
 	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
@@ -159,7 +169,7 @@ func (ts *TidbRegionHandlerTestSuite) TestListTableRegionsWithError(c *C) {
 func (ts *TidbRegionHandlerTestSuite) TestGetRegionByIDWithError(c *C) {
 	ts.startServer(c)
 	defer ts.stopServer(c)
-	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:10090/regions/xxx"))
+	resp, err := getWithRoot(fmt.Sprintf("http://127.0.0.1:10090/regions/xxx"))
 	c.Assert(err, IsNil)
 	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
 	defer resp.Body.Close()
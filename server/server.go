@@ -29,6 +29,9 @@
 package server
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
 	"math/rand"
 	"net"
 	"sync"
@@ -43,6 +46,7 @@ import (
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/arena"
+	"github.com/pingcap/tidb/util/audit"
 )
 
 var (
@@ -66,6 +70,21 @@ type Server struct {
 	rwlock            *sync.RWMutex
 	concurrentLimiter *TokenLimiter
 	clients           map[uint32]*clientConn
+	// handshakeConns are the accepted connections which haven't finished the handshake, they're tracked
+	// so the draining server can close them.
+	handshakeConns map[net.Conn]struct{}
+	// tlsConfig is nil if TLS is not enabled.
+	tlsConfig *tls.Config
+	// rsaKey is used by caching_sha2_password and sha256_password when the connection isn't encrypted.
+	rsaKey rsaKeyPair
+	// reloadConfig reloads the configuration of the server, it's called by the status HTTP service.
+	reloadConfig func() error
+	// serverID is the ID of the server in the cluster, it's accessed atomically.
+	serverID uint32
+	// draining is set to 1 by GracefulShutdown, it's accessed atomically.
+	draining int32
+	// proxyNetworks are the networks of the trusted proxies which send the PROXY protocol header.
+	proxyNetworks []*net.IPNet
 
 	// When a critical error occurred, we don't want to exit the process, because there may be
 	// a supervisor automatically restart it, then new client connection will be created, but we can't server it.
@@ -82,12 +101,31 @@ func (s *Server) ConnectionCount() int {
 	return cnt
 }
 
-func (s *Server) getToken() *Token {
-	return s.concurrentLimiter.Get()
+// tokenLimiter returns the limiter of the concurrently handled client commands. The token must be
+// put back to the limiter it's got from, because the limiter is replaced when the token limit changes.
+func (s *Server) tokenLimiter() *TokenLimiter {
+	s.rwlock.RLock()
+	tl := s.concurrentLimiter
+	s.rwlock.RUnlock()
+	return tl
 }
 
-func (s *Server) releaseToken(token *Token) {
-	s.concurrentLimiter.Put(token)
+// SetTokenLimit changes the number of the client commands which are handled concurrently.
+// The commands which are running keep their tokens of the old limiter until they finish.
+func (s *Server) SetTokenLimit(count uint) {
+	tl := NewTokenLimiter(int(count))
+	s.rwlock.Lock()
+	s.concurrentLimiter = tl
+	s.rwlock.Unlock()
+	log.Infof("token limit is changed to %d", count)
+}
+
+// SetConfigReloader sets the function which reloads the configuration when the status HTTP service is
+// asked to do it.
+func (s *Server) SetConfigReloader(reload func() error) {
+	s.rwlock.Lock()
+	s.reloadConfig = reload
+	s.rwlock.Unlock()
 }
 
 // Generate a random string using ASCII characters but avoid separator character.
@@ -110,7 +148,7 @@ func (s *Server) newConn(conn net.Conn) *clientConn {
 		conn:         conn,
 		pkt:          newPacketIO(conn),
 		server:       s,
-		connectionID: atomic.AddUint32(&baseConnID, 1),
+		connectionID: s.allocConnID(),
 		collation:    mysql.DefaultCollationID,
 		alloc:        arena.NewAllocator(32 * 1024),
 	}
@@ -119,24 +157,71 @@ func (s *Server) newConn(conn net.Conn) *clientConn {
 	return cc
 }
 
+// allocConnID allocates a connection ID which isn't used by the current connections, the ID of the
+// server is encoded in it.
+func (s *Server) allocConnID() uint32 {
+	serverID := uint64(atomic.LoadUint32(&s.serverID))
+	for {
+		localConnID := uint64(atomic.AddUint32(&baseConnID, 1)) & util.MaxLocalConnID
+		if localConnID == 0 {
+			continue
+		}
+		connID := uint32(util.EncodeConnID(serverID, localConnID))
+		// The connection IDs in a server wrap around after util.MaxLocalConnID connections.
+		s.rwlock.RLock()
+		_, ok := s.clients[connID]
+		s.rwlock.RUnlock()
+		if !ok {
+			return connID
+		}
+	}
+}
+
+// SetServerID sets the ID of the server in the cluster, it's encoded in the IDs of the new connections
+// to make them unique in the cluster.
+func (s *Server) SetServerID(serverID uint64) {
+	atomic.StoreUint32(&s.serverID, uint32(serverID))
+}
+
+// capability returns the capability advertised to the clients in the initial handshake packet.
+func (s *Server) capability() uint32 {
+	if s.tlsConfig != nil {
+		return defaultCapability | mysql.ClientSSL
+	}
+	return defaultCapability
+}
+
 func (s *Server) skipAuth() bool {
 	return s.cfg.SkipAuth
 }
 
-const tokenLimit = 1000
+const defaultTokenLimit = 1000
 
 // NewServer creates a new Server.
 func NewServer(cfg *Config, driver IDriver) (*Server, error) {
+	tokenLimit := cfg.TokenLimit
+	if tokenLimit == 0 {
+		tokenLimit = defaultTokenLimit
+	}
 	s := &Server{
 		cfg:               cfg,
 		driver:            driver,
-		concurrentLimiter: NewTokenLimiter(tokenLimit),
+		concurrentLimiter: NewTokenLimiter(int(tokenLimit)),
 		rwlock:            &sync.RWMutex{},
 		clients:           make(map[uint32]*clientConn),
+		handshakeConns:    make(map[net.Conn]struct{}),
 		stopListenerCh:    make(chan struct{}, 1),
 	}
 
-	var err error
+	err := s.loadTLSCertificates()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	s.proxyNetworks, err = parseProxyNetworks(cfg.ProxyProtocolNetworks)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
 	if cfg.Socket != "" {
 		cfg.SkipAuth = true
 		s.listener, err = net.Listen("unix", cfg.Socket)
@@ -153,6 +238,37 @@ func NewServer(cfg *Config, driver IDriver) (*Server, error) {
 	return s, nil
 }
 
+// loadTLSCertificates loads the server certificate and key, and the CA certificate which verifies the client
+// certificates. TLS is not enabled if the server certificate or key is not configured.
+func (s *Server) loadTLSCertificates() error {
+	if s.cfg.SSLCert == "" || s.cfg.SSLKey == "" {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(s.cfg.SSLCert, s.cfg.SSLKey)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+	if s.cfg.SSLCA != "" {
+		caCert, err := ioutil.ReadFile(s.cfg.SSLCA)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(caCert) {
+			return errors.Errorf("failed to parse CA certificate %s", s.cfg.SSLCA)
+		}
+		tlsConfig.ClientCAs = certPool
+		// The client certificate is optional, unless the user is created with REQUIRE X509.
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	s.tlsConfig = tlsConfig
+	log.Infof("Server enables TLS for client connections, cert %s, key %s, ca %s", s.cfg.SSLCert, s.cfg.SSLKey, s.cfg.SSLCA)
+	return nil
+}
+
 // Run runs the server.
 func (s *Server) Run() error {
 
@@ -205,30 +321,122 @@ func (s *Server) Close() {
 	}
 }
 
+// drainPollInterval is the interval of checking the connections when the server is draining.
+var drainPollInterval = 100 * time.Millisecond
+
+// GracefulShutdown stops accepting new connections and drains the current ones. The connections which
+// aren't in transactions are closed at the command boundary, the others are given timeout to finish
+// their transactions and the remaining connections are killed after that.
+func (s *Server) GracefulShutdown(timeout time.Duration) {
+	atomic.StoreInt32(&s.draining, 1)
+	s.Close()
+	log.Infof("server is draining %d connections in %v", s.ConnectionCount(), timeout)
+	deadline := time.Now().Add(timeout)
+	for s.closeIdleConns() > 0 {
+		if time.Now().After(deadline) {
+			s.killAllConns()
+			return
+		}
+		time.Sleep(drainPollInterval)
+	}
+	log.Infof("server is drained")
+}
+
+// IsDraining returns whether the server is shutting down, the status HTTP service reports it to the
+// load balancers.
+func (s *Server) IsDraining() bool {
+	return atomic.LoadInt32(&s.draining) == 1
+}
+
+// closeIdleConns closes the connections which are waiting for the next command outside transactions and the
+// connections in the handshake, it returns the number of the remaining connections.
+func (s *Server) closeIdleConns() int {
+	s.rwlock.RLock()
+	defer s.rwlock.RUnlock()
+	for c := range s.handshakeConns {
+		c.Close()
+	}
+	for _, cc := range s.clients {
+		if atomic.LoadInt32(&cc.status) != connStatusReading || cc.inTxn() {
+			continue
+		}
+		if atomic.CompareAndSwapInt32(&cc.status, connStatusReading, connStatusShutdown) {
+			cc.conn.Close()
+		}
+	}
+	return len(s.clients) + len(s.handshakeConns)
+}
+
+// killAllConns kills the running statements and closes all the connections.
+func (s *Server) killAllConns() {
+	s.rwlock.RLock()
+	defer s.rwlock.RUnlock()
+	log.Warnf("kill %d connections which are not finished in time", len(s.clients)+len(s.handshakeConns))
+	for c := range s.handshakeConns {
+		c.Close()
+	}
+	for _, cc := range s.clients {
+		atomic.StoreInt32(&cc.status, connStatusShutdown)
+		cc.ctx.Cancel()
+		cc.conn.Close()
+	}
+}
+
 // onConn runs in its own goroutine, handles queries from this connection.
 func (s *Server) onConn(c net.Conn) {
-	conn := s.newConn(c)
-	defer func() {
-		log.Infof("[%d] close connection", conn.connectionID)
-	}()
-
-	if err := conn.handshake(); err != nil {
-		// Some keep alive services will send request to TiDB and disconnect immediately.
-		// So we use info log level.
-		log.Infof("handshake error %s", errors.ErrorStack(err))
+	s.rwlock.Lock()
+	if s.IsDraining() {
+		s.rwlock.Unlock()
 		c.Close()
 		return
 	}
+	s.handshakeConns[c] = struct{}{}
+	s.rwlock.Unlock()
 
+	conn := s.handshake(c)
 	s.rwlock.Lock()
-	s.clients[conn.connectionID] = conn
+	delete(s.handshakeConns, c)
+	if conn != nil {
+		s.clients[conn.connectionID] = conn
+	}
 	connections := len(s.clients)
 	s.rwlock.Unlock()
+	if conn == nil {
+		c.Close()
+		return
+	}
+	defer func() {
+		log.Infof("[%d] close connection", conn.connectionID)
+	}()
 	connGauge.Set(float64(connections))
+	conn.audit(audit.TypeConnect, conn.user, conn.dbname, nil)
 
 	conn.Run()
 }
 
+// handshake reads the PROXY protocol header from the trusted proxies and does the handshake with the client,
+// it returns nil if it fails.
+func (s *Server) handshake(c net.Conn) *clientConn {
+	if s.isTrustedProxy(c.RemoteAddr()) {
+		clientAddr, err := readProxyHeader(c)
+		if err != nil {
+			log.Warnf("read PROXY protocol header from %s error %v", c.RemoteAddr(), errors.ErrorStack(err))
+			return nil
+		}
+		if clientAddr != nil {
+			c = &proxyProtocolConn{Conn: c, clientAddr: clientAddr}
+		}
+	}
+	conn := s.newConn(c)
+	if err := conn.handshake(); err != nil {
+		// Some keep alive services will send request to TiDB and disconnect immediately.
+		// So we use info log level.
+		log.Infof("[%d] handshake error %s", conn.connectionID, errors.ErrorStack(err))
+		return nil
+	}
+	return conn
+}
+
 // ShowProcessList implements the SessionManager interface.
 func (s *Server) ShowProcessList() []util.ProcessInfo {
 	var rs []util.ProcessInfo
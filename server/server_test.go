@@ -14,10 +14,19 @@
 package server
 
 import (
+	"bytes"
+	"compress/zlib"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
 	"database/sql"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"regexp"
@@ -30,6 +39,7 @@ import (
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/executor"
 	tmysql "github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/printer"
 )
 
@@ -487,6 +497,291 @@ func runTestAuth(c *C) {
 	})
 }
 
+// runTestCompressedProtocol connects to the server by a hand-built client which uses the compressed protocol.
+func runTestCompressedProtocol(c *C) {
+	conn, err := net.Dial("tcp", "localhost:4001")
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	pkt := newPacketIO(conn)
+
+	// The initial handshake packet advertises the compressed protocol.
+	data, err := pkt.readPacket()
+	c.Assert(err, IsNil)
+	pos := 1 + bytes.IndexByte(data[1:], 0) + 1 + 4 + 8 + 1
+	capability := uint32(data[pos]) | uint32(data[pos+1])<<8
+	c.Assert(capability&tmysql.ClientCompress, Equals, uint32(tmysql.ClientCompress))
+
+	// The handshake response of root without password, the packets before OK are not compressed.
+	capability = tmysql.ClientProtocol41 | tmysql.ClientSecureConnection | tmysql.ClientLongPassword | tmysql.ClientCompress
+	data = make([]byte, 4, 64)
+	data = append(data, dumpUint32(capability)...)
+	data = append(data, 0, 0, 0, 0)
+	data = append(data, tmysql.DefaultCollationID)
+	data = append(data, make([]byte, 23)...)
+	data = append(data, "root"...)
+	data = append(data, 0, 0)
+	c.Assert(pkt.writePacket(data), IsNil)
+	c.Assert(pkt.flush(), IsNil)
+	data, err = pkt.readPacket()
+	c.Assert(err, IsNil)
+	c.Assert(data[0], Equals, byte(tmysql.OKHeader))
+
+	for i := 0; i < 2; i++ {
+		// The query is short, so it is sent in a compressed packet without compression.
+		query := "select repeat('a', 1000)"
+		payload := []byte{byte(len(query) + 1), 0, 0, 0, tmysql.ComQuery}
+		payload = append(payload, query...)
+		_, err = conn.Write(append([]byte{byte(len(payload)), 0, 0, 0, 0, 0, 0}, payload...))
+		c.Assert(err, IsNil)
+
+		// The result set is sent in a compressed packet with compression.
+		header := make([]byte, 7)
+		_, err = io.ReadFull(conn, header)
+		c.Assert(err, IsNil)
+		length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+		uncompressedLength := int(header[4]) | int(header[5])<<8 | int(header[6])<<16
+		c.Assert(header[3], Equals, byte(1))
+		c.Assert(uncompressedLength, Greater, length)
+		data = make([]byte, length)
+		_, err = io.ReadFull(conn, data)
+		c.Assert(err, IsNil)
+		r, err := zlib.NewReader(bytes.NewReader(data))
+		c.Assert(err, IsNil)
+		data, err = ioutil.ReadAll(r)
+		c.Assert(err, IsNil)
+		c.Assert(data, HasLen, uncompressedLength)
+		// The first packet is the column count, it is numbered from the compressed sequence.
+		c.Assert(data[:5], DeepEquals, []byte{1, 0, 0, 1, 1})
+		c.Assert(bytes.Contains(data, bytes.Repeat([]byte("a"), 1000)), IsTrue)
+	}
+}
+
+// testRawClient is a hand-built client used to test the protocol features which the driver doesn't support.
+type testRawClient struct {
+	c    *C
+	conn net.Conn
+	pkt  *packetIO
+	salt []byte
+}
+
+// newTestRawClient connects to the server and reads the initial handshake packet.
+func newTestRawClient(c *C) *testRawClient {
+	conn, err := net.Dial("tcp", "127.0.0.1:4001")
+	c.Assert(err, IsNil)
+	cli := &testRawClient{c: c, conn: conn, pkt: newPacketIO(conn)}
+	data, err := cli.pkt.readPacket()
+	c.Assert(err, IsNil)
+	// Skip protocol version, server version and connection id.
+	pos := 1 + bytes.IndexByte(data[1:], 0) + 1 + 4
+	cli.salt = append(cli.salt, data[pos:pos+8]...)
+	// Skip filler, capability, charset, status, capability and auth data length.
+	pos += 8 + 1 + 2 + 1 + 2 + 2 + 1
+	// Skip reserved bytes.
+	pos += 10
+	cli.salt = append(cli.salt, data[pos:pos+12]...)
+	pos += 13
+	c.Assert(string(data[pos:pos+bytes.IndexByte(data[pos:], 0)]), Equals, tmysql.AuthNativePassword)
+	return cli
+}
+
+func (cli *testRawClient) writePacket(payload ...[]byte) {
+	data := make([]byte, 4)
+	for _, p := range payload {
+		data = append(data, p...)
+	}
+	cli.c.Assert(cli.pkt.writePacket(data), IsNil)
+	cli.c.Assert(cli.pkt.flush(), IsNil)
+}
+
+func (cli *testRawClient) readPacket() []byte {
+	data, err := cli.pkt.readPacket()
+	cli.c.Assert(err, IsNil)
+	return data
+}
+
+// writeHandshakeResponse sends the handshake response with the auth response of the auth plugin.
+func (cli *testRawClient) writeHandshakeResponse(user string, auth []byte, plugin string) {
+	capability := tmysql.ClientProtocol41 | tmysql.ClientSecureConnection | tmysql.ClientLongPassword | tmysql.ClientPluginAuth
+	cli.writePacket(dumpUint32(capability), []byte{0, 0, 0, 0, tmysql.DefaultCollationID}, make([]byte, 23),
+		[]byte(user), []byte{0, byte(len(auth))}, auth, []byte(plugin), []byte{0})
+}
+
+// writeChangeUser sends COM_CHANGE_USER with the auth response of mysql_native_password.
+func (cli *testRawClient) writeChangeUser(user, password string) {
+	var auth []byte
+	if len(password) > 0 {
+		auth = util.CalcPassword(cli.salt, util.Sha1Hash([]byte(password)))
+	}
+	cli.pkt.resetSequence()
+	cli.writePacket([]byte{tmysql.ComChangeUser}, []byte(user), []byte{0, byte(len(auth))}, auth,
+		[]byte("test"), []byte{0, tmysql.DefaultCollationID, 0}, []byte(tmysql.AuthNativePassword), []byte{0})
+}
+
+// encryptPassword encrypts the password with the PEM encoded RSA public key.
+func (cli *testRawClient) encryptPassword(pubPEM []byte, password string) []byte {
+	block, _ := pem.Decode(pubPEM)
+	cli.c.Assert(block, NotNil)
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	cli.c.Assert(err, IsNil)
+	plain := append([]byte(password), 0)
+	for i := range plain {
+		plain[i] ^= cli.salt[i%len(cli.salt)]
+	}
+	data, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, pub.(*rsa.PublicKey), plain, nil)
+	cli.c.Assert(err, IsNil)
+	return data
+}
+
+// query executes the query and returns the first column of the first row, it returns "NULL" for NULL.
+func (cli *testRawClient) query(sql string) string {
+	cli.pkt.resetSequence()
+	cli.writePacket([]byte{tmysql.ComQuery}, []byte(sql))
+	data := cli.readPacket()
+	if data[0] == tmysql.OKHeader {
+		return ""
+	}
+	for {
+		// Skip column definitions.
+		if data = cli.readPacket(); data[0] == tmysql.EOFHeader {
+			break
+		}
+	}
+	data = cli.readPacket()
+	value := "NULL"
+	if data[0] != 0xfb {
+		v, _, _, err := parseLengthEncodedBytes(data)
+		cli.c.Assert(err, IsNil)
+		value = string(v)
+	}
+	cli.c.Assert(cli.readPacket()[0], Equals, tmysql.EOFHeader)
+	return value
+}
+
+func runTestAuthPlugin(c *C) {
+	db, err := sql.Open("mysql", dsn)
+	c.Assert(err, IsNil)
+	dbt := &DBTest{c, db}
+	dbt.mustExec("CREATE USER 'sha2'@'%' IDENTIFIED WITH caching_sha2_password BY '123'")
+	dbt.mustExec("CREATE USER 'sha256'@'%' IDENTIFIED WITH sha256_password BY '123'")
+	dbt.mustExec("FLUSH PRIVILEGES")
+	db.Close()
+
+	// The client authenticates with mysql_native_password, the server asks it to switch to caching_sha2_password.
+	cli := newTestRawClient(c)
+	defer cli.conn.Close()
+	cli.writeHandshakeResponse("sha2", util.CalcPassword(cli.salt, util.Sha1Hash([]byte("123"))), tmysql.AuthNativePassword)
+	data := cli.readPacket()
+	c.Assert(data[0], Equals, tmysql.AuthSwitchHeader)
+	c.Assert(string(data[1:1+bytes.IndexByte(data[1:], 0)]), Equals, tmysql.AuthCachingSha2Password)
+	cli.writePacket(bytes.Repeat([]byte{1}, 32))
+	// The server always asks for the full authentication, the client requests the public key as there is no TLS.
+	c.Assert(cli.readPacket(), DeepEquals, []byte{tmysql.AuthMoreDataHeader, tmysql.AuthPerformFullAuthentication})
+	cli.writePacket([]byte{tmysql.AuthRequestPublicKey})
+	data = cli.readPacket()
+	c.Assert(data[0], Equals, tmysql.AuthMoreDataHeader)
+	cli.writePacket(cli.encryptPassword(data[1:], "123"))
+	c.Assert(cli.readPacket()[0], Equals, tmysql.OKHeader)
+	c.Assert(cli.query("select user()"), Equals, "sha2@127.0.0.1")
+
+	// The session is kept if COM_CHANGE_USER fails.
+	cli.query("set @a = 1")
+	cli.writeChangeUser("sha2", "1234")
+	c.Assert(cli.readPacket()[0], Equals, tmysql.AuthSwitchHeader)
+	cli.writePacket([]byte{})
+	c.Assert(cli.readPacket()[0], Equals, tmysql.ErrHeader)
+	c.Assert(cli.query("select @a"), Equals, "1")
+
+	// COM_CHANGE_USER resets the session.
+	cli.writeChangeUser("root", "")
+	c.Assert(cli.readPacket()[0], Equals, tmysql.OKHeader)
+	c.Assert(cli.query("select @a"), Equals, "NULL")
+	c.Assert(cli.query("select user()"), Equals, "root@127.0.0.1")
+	c.Assert(cli.query("select database()"), Equals, "test")
+
+	// The sha256_password client requests the public key in the first auth response.
+	cli = newTestRawClient(c)
+	defer cli.conn.Close()
+	cli.writeHandshakeResponse("sha256", []byte{tmysql.AuthSha256RequestPublicKey}, tmysql.AuthSha256Password)
+	data = cli.readPacket()
+	c.Assert(data[0], Equals, tmysql.AuthMoreDataHeader)
+	cli.writePacket(cli.encryptPassword(data[1:], "456"))
+	c.Assert(cli.readPacket()[0], Equals, tmysql.ErrHeader)
+
+	cli = newTestRawClient(c)
+	defer cli.conn.Close()
+	cli.writeHandshakeResponse("sha256", []byte{tmysql.AuthSha256RequestPublicKey}, tmysql.AuthSha256Password)
+	data = cli.readPacket()
+	cli.writePacket(cli.encryptPassword(data[1:], "123"))
+	c.Assert(cli.readPacket()[0], Equals, tmysql.OKHeader)
+}
+
+// readEOFStatus reads an EOF packet and returns the server status.
+func (cli *testRawClient) readEOFStatus() uint16 {
+	data := cli.readPacket()
+	cli.c.Assert(data[0], Equals, tmysql.EOFHeader)
+	return uint16(data[3]) | uint16(data[4])<<8
+}
+
+func runTestCursorFetch(c *C) {
+	cli := newTestRawClient(c)
+	defer cli.conn.Close()
+	cli.writeHandshakeResponse("root", nil, tmysql.AuthNativePassword)
+	c.Assert(cli.readPacket()[0], Equals, tmysql.OKHeader)
+	cli.query("use test")
+	cli.query("drop table if exists cursor_fetch")
+	cli.query("create table cursor_fetch (a int)")
+	cli.query("insert cursor_fetch values (1), (2), (3), (4), (5)")
+
+	cli.pkt.resetSequence()
+	cli.writePacket([]byte{tmysql.ComStmtPrepare}, []byte("select a from cursor_fetch order by a"))
+	data := cli.readPacket()
+	c.Assert(data[0], Equals, tmysql.OKHeader)
+	stmtID := data[1:5]
+	cli.readPacket()
+	cli.readEOFStatus()
+
+	// The rows are not sent when the statement is executed with a cursor.
+	cli.pkt.resetSequence()
+	cli.writePacket([]byte{tmysql.ComStmtExecute}, stmtID, []byte{tmysql.CursorTypeReadOnly, 1, 0, 0, 0})
+	c.Assert(cli.readPacket(), DeepEquals, []byte{1})
+	cli.readPacket()
+	c.Assert(cli.readEOFStatus()&tmysql.ServerStatusCursorExists, Equals, tmysql.ServerStatusCursorExists)
+
+	fetch := func(numRows byte, expected []int, lastRow bool) {
+		cli.pkt.resetSequence()
+		cli.writePacket([]byte{tmysql.ComStmtFetch}, stmtID, []byte{numRows, 0, 0, 0})
+		for _, v := range expected {
+			// The binary row contains the header, the null bitmap and the int value.
+			data := cli.readPacket()
+			c.Assert(data, HasLen, 6)
+			c.Assert(int(data[2]), Equals, v)
+		}
+		status := cli.readEOFStatus()
+		c.Assert(status&tmysql.ServerStatusCursorExists, Equals, tmysql.ServerStatusCursorExists)
+		c.Assert(status&tmysql.ServerStatusLastRowSend > 0, Equals, lastRow)
+	}
+	fetch(2, []int{1, 2}, false)
+	fetch(2, []int{3, 4}, false)
+	fetch(2, []int{5}, true)
+
+	// The cursor is closed after the last row is sent.
+	cli.pkt.resetSequence()
+	cli.writePacket([]byte{tmysql.ComStmtFetch}, stmtID, []byte{2, 0, 0, 0})
+	c.Assert(cli.readPacket()[0], Equals, tmysql.ErrHeader)
+
+	// Executing the statement without a cursor sends all the rows.
+	cli.pkt.resetSequence()
+	cli.writePacket([]byte{tmysql.ComStmtExecute}, stmtID, []byte{tmysql.CursorTypeNoCursor, 1, 0, 0, 0})
+	c.Assert(cli.readPacket(), DeepEquals, []byte{1})
+	cli.readPacket()
+	c.Assert(cli.readEOFStatus()&tmysql.ServerStatusCursorExists, Equals, uint16(0))
+	for i := 0; i < 5; i++ {
+		cli.readPacket()
+	}
+	cli.readEOFStatus()
+	cli.query("drop table cursor_fetch")
+}
+
 func runTestIssues(c *C) {
 	// For issue #263
 	unExistsSchemaDsn := "root@tcp(localhost:4001)/unexists_schema?strict=true"
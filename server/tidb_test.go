@@ -15,11 +15,30 @@
 package server
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb"
+	tmysql "github.com/pingcap/tidb/mysql"
 )
 
 type TidbTestSuite struct {
@@ -96,6 +115,21 @@ func (ts *TidbTestSuite) TestAuth(c *C) {
 	runTestAuth(c)
 }
 
+func (ts *TidbTestSuite) TestCompressedProtocol(c *C) {
+	c.Parallel()
+	runTestCompressedProtocol(c)
+}
+
+func (ts *TidbTestSuite) TestAuthPlugin(c *C) {
+	c.Parallel()
+	runTestAuthPlugin(c)
+}
+
+func (ts *TidbTestSuite) TestCursorFetch(c *C) {
+	c.Parallel()
+	runTestCursorFetch(c)
+}
+
 func (ts *TidbTestSuite) TestIssues(c *C) {
 	runTestIssues(c)
 }
@@ -109,6 +143,76 @@ func (ts *TidbTestSuite) TestStatusAPI(c *C) {
 	runTestStatusAPI(c)
 }
 
+func (ts *TidbTestSuite) TestReloadConfig(c *C) {
+	// The status HTTP service is shared by the servers in the test process, so call the handler directly.
+	postReload := func() (int, string) {
+		req, err := http.NewRequest("POST", "/config/reload", nil)
+		c.Assert(err, IsNil)
+		rec := httptest.NewRecorder()
+		ts.server.handleReloadConfig(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+	code, _ := postReload()
+	c.Assert(code, Equals, http.StatusBadRequest)
+
+	defer ts.server.SetConfigReloader(nil)
+	ts.server.SetConfigReloader(func() error {
+		return errors.New("invalid log.level \"verbose\"")
+	})
+	code, body := postReload()
+	c.Assert(code, Equals, http.StatusBadRequest)
+	c.Assert(body, Equals, "invalid log.level \"verbose\"\n")
+
+	defer ts.server.SetTokenLimit(defaultTokenLimit)
+	ts.server.SetConfigReloader(func() error {
+		ts.server.SetTokenLimit(1)
+		return nil
+	})
+	code, _ = postReload()
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(ts.server.tokenLimiter().count, Equals, 1)
+}
+
+func (ts *TidbTestSuite) TestHTTPPrivilege(c *C) {
+	se, err := tidb.CreateSession(ts.tidbdrv.store)
+	c.Assert(err, IsNil)
+	defer se.Close()
+	for _, sql := range []string{
+		"create user 'http_user'@'%' identified by 'pwd'",
+		"grant process on *.* to 'http_user'@'%'",
+		"flush privileges",
+	} {
+		_, err = se.Execute(sql)
+		c.Assert(err, IsNil)
+	}
+	defer se.Execute("drop user 'http_user'@'%'")
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	tests := []struct {
+		priv tmysql.PrivilegeType
+		user string
+		pwd  string
+		code int
+	}{
+		{tmysql.ReloadPriv, "", "", http.StatusUnauthorized},
+		{tmysql.ReloadPriv, "http_user", "wrong", http.StatusUnauthorized},
+		{tmysql.ReloadPriv, "http_user", "pwd", http.StatusForbidden},
+		{tmysql.ProcessPriv, "http_user", "pwd", http.StatusOK},
+		{tmysql.ReloadPriv, "root", "", http.StatusOK},
+	}
+	for _, t := range tests {
+		req := httptest.NewRequest("GET", "/regions/1", nil)
+		if t.user != "" {
+			req.SetBasicAuth(t.user, t.pwd)
+		}
+		rec := httptest.NewRecorder()
+		ts.server.requirePrivilege(t.priv, handler).ServeHTTP(rec, req)
+		c.Assert(rec.Code, Equals, t.code, Commentf("%+v", t))
+	}
+}
+
 func (ts *TidbTestSuite) TestMultiStatements(c *C) {
 	c.Parallel()
 	runTestMultiStatements(c)
@@ -131,3 +235,292 @@ func (ts *TidbTestSuite) TestSocket(c *C) {
 	dsn = tcpDsn
 	server.Close()
 }
+
+func (ts *TidbTestSuite) TestTLS(c *C) {
+	dir, err := ioutil.TempDir("", "tidb-tls-test")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	caCert, caKey := generateCert(c, dir, "ca", nil, nil)
+	generateCert(c, dir, "server", caCert, caKey)
+	generateCert(c, dir, "client", caCert, caKey)
+
+	cfg := &Config{
+		Addr:       ":4002",
+		LogLevel:   "debug",
+		StatusAddr: ":10092",
+		SSLCA:      filepath.Join(dir, "ca-cert.pem"),
+		SSLCert:    filepath.Join(dir, "server-cert.pem"),
+		SSLKey:     filepath.Join(dir, "server-key.pem"),
+	}
+	server, err := NewServer(cfg, ts.tidbdrv)
+	c.Assert(err, IsNil)
+	go server.Run()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(caCert)
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "client-cert.pem"), filepath.Join(dir, "client-key.pem"))
+	c.Assert(err, IsNil)
+	err = mysql.RegisterTLSConfig("tidb-test", &tls.Config{RootCAs: rootCAs, ServerName: "tidb"})
+	c.Assert(err, IsNil)
+	err = mysql.RegisterTLSConfig("tidb-test-x509", &tls.Config{RootCAs: rootCAs, ServerName: "tidb", Certificates: []tls.Certificate{clientCert}})
+	c.Assert(err, IsNil)
+
+	runTests(c, "root@tcp(localhost:4002)/test?strict=true&tls=tidb-test", func(dbt *DBTest) {
+		var name, value string
+		err := dbt.db.QueryRow("show status like 'Ssl_cipher'").Scan(&name, &value)
+		dbt.Assert(err, IsNil)
+		dbt.Assert(value, Not(Equals), "")
+		err = dbt.db.QueryRow("select @@have_ssl").Scan(&value)
+		dbt.Assert(err, IsNil)
+		dbt.Assert(value, Equals, "YES")
+		err = dbt.db.QueryRow("select @@global.have_ssl").Scan(&value)
+		dbt.Assert(err, IsNil)
+		dbt.Assert(value, Equals, "YES")
+		dbt.mustExec("CREATE USER 'tls_ssl'@'%' IDENTIFIED BY '123' REQUIRE SSL")
+		dbt.mustExec("CREATE USER 'tls_x509'@'%' IDENTIFIED BY '123'")
+		dbt.mustExec("GRANT SELECT ON test.* TO 'tls_x509'@'%' REQUIRE X509")
+		dbt.mustExec("FLUSH PRIVILEGES")
+	})
+	runTests(c, "root@tcp(localhost:4002)/test?strict=true", func(dbt *DBTest) {
+		var name, value string
+		err := dbt.db.QueryRow("show status like 'Ssl_cipher'").Scan(&name, &value)
+		dbt.Assert(err, IsNil)
+		dbt.Assert(value, Equals, "")
+		err = dbt.db.QueryRow("select @@have_ssl").Scan(&value)
+		dbt.Assert(err, IsNil)
+		dbt.Assert(value, Equals, "YES")
+	})
+	// The other servers in the process don't enable TLS.
+	runTests(c, dsn, func(dbt *DBTest) {
+		var value string
+		err := dbt.db.QueryRow("select @@have_ssl").Scan(&value)
+		dbt.Assert(err, IsNil)
+		dbt.Assert(value, Equals, "DISABLED")
+	})
+
+	checkConnect := func(dsn string, succeed bool) {
+		db, err := sql.Open("mysql", dsn)
+		c.Assert(err, IsNil)
+		defer db.Close()
+		err = db.Ping()
+		if succeed {
+			c.Assert(err, IsNil, Commentf("dsn %s", dsn))
+		} else {
+			c.Assert(err, NotNil, Commentf("dsn %s", dsn))
+		}
+	}
+	checkConnect("tls_ssl:123@tcp(localhost:4002)/test", false)
+	checkConnect("tls_ssl:123@tcp(localhost:4002)/test?tls=tidb-test", true)
+	checkConnect("tls_x509:123@tcp(localhost:4002)/test?tls=tidb-test", false)
+	checkConnect("tls_x509:123@tcp(localhost:4002)/test?tls=tidb-test-x509", true)
+	// The server without TLS rejects the TLS connection.
+	checkConnect("root@tcp(localhost:4001)/test?tls=tidb-test", false)
+}
+
+func (ts *TidbTestSuite) TestGracefulShutdown(c *C) {
+	startServer := func() *Server {
+		cfg := &Config{
+			Addr:       ":4003",
+			LogLevel:   "debug",
+			StatusAddr: ":10093",
+		}
+		server, err := NewServer(cfg, ts.tidbdrv)
+		c.Assert(err, IsNil)
+		go server.Run()
+		time.Sleep(time.Millisecond * 100)
+		return server
+	}
+	drainDSN := "root@tcp(localhost:4003)/test?strict=true"
+	getStatus := func(server *Server) (int, status) {
+		req, err := http.NewRequest("GET", "/status", nil)
+		c.Assert(err, IsNil)
+		rec := httptest.NewRecorder()
+		server.handleStatus(rec, req)
+		var st status
+		c.Assert(json.Unmarshal(rec.Body.Bytes(), &st), IsNil)
+		return rec.Code, st
+	}
+	runTests(c, dsn, func(dbt *DBTest) {
+		dbt.mustExec("create table graceful_shutdown (a int)")
+	})
+
+	// The transactions are given time to finish, the idle connections are closed at once.
+	server := startServer()
+	code, st := getStatus(server)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(st.Draining, IsFalse)
+	db, err := sql.Open("mysql", drainDSN)
+	c.Assert(err, IsNil)
+	defer db.Close()
+	txn, err := db.Begin()
+	c.Assert(err, IsNil)
+	_, err = txn.Exec("insert graceful_shutdown values (1)")
+	c.Assert(err, IsNil)
+	idleDB, err := sql.Open("mysql", drainDSN)
+	c.Assert(err, IsNil)
+	defer idleDB.Close()
+	c.Assert(idleDB.Ping(), IsNil)
+	c.Assert(server.ConnectionCount(), Equals, 2)
+	// The connection stays in the handshake after reading the initial handshake packet.
+	handshakeConn, err := net.Dial("tcp", "localhost:4003")
+	c.Assert(err, IsNil)
+	defer handshakeConn.Close()
+	_, err = handshakeConn.Read(make([]byte, 1024))
+	c.Assert(err, IsNil)
+
+	drained := make(chan struct{})
+	go func() {
+		server.GracefulShutdown(time.Minute)
+		close(drained)
+	}()
+	for i := 0; i < 100 && server.ConnectionCount() > 1; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	c.Assert(server.ConnectionCount(), Equals, 1)
+	code, st = getStatus(server)
+	c.Assert(code, Equals, http.StatusServiceUnavailable)
+	c.Assert(st.Draining, IsTrue)
+	// The new connections are refused.
+	_, err = net.Dial("tcp", "localhost:4003")
+	c.Assert(err, NotNil)
+	_, err = idleDB.Exec("select 1")
+	c.Assert(err, NotNil)
+	_, err = txn.Exec("insert graceful_shutdown values (2)")
+	c.Assert(err, IsNil)
+	c.Assert(txn.Commit(), IsNil)
+	select {
+	case <-drained:
+	case <-time.After(5 * time.Second):
+		c.Fatal("the server is not drained after the transaction is committed")
+	}
+	c.Assert(server.ConnectionCount(), Equals, 0)
+	// The connection in the handshake is closed before the server is drained.
+	handshakeConn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = handshakeConn.Read(make([]byte, 1024))
+	c.Assert(err, Equals, io.EOF)
+
+	// The transactions which don't finish in time are killed.
+	server = startServer()
+	txn, err = db.Begin()
+	c.Assert(err, IsNil)
+	_, err = txn.Exec("insert graceful_shutdown values (3)")
+	c.Assert(err, IsNil)
+	server.GracefulShutdown(time.Millisecond * 200)
+	c.Assert(txn.Commit(), NotNil)
+
+	runTests(c, dsn, func(dbt *DBTest) {
+		rows := dbt.mustQuery("select a from graceful_shutdown")
+		var values []int
+		for rows.Next() {
+			var a int
+			c.Assert(rows.Scan(&a), IsNil)
+			values = append(values, a)
+		}
+		c.Assert(values, DeepEquals, []int{1, 2})
+		dbt.mustExec("drop table graceful_shutdown")
+	})
+}
+
+func (ts *TidbTestSuite) TestProxyProtocol(c *C) {
+	defer func(timeout time.Duration) {
+		proxyHeaderTimeout = timeout
+	}(proxyHeaderTimeout)
+	proxyHeaderTimeout = time.Millisecond * 100
+	cfg := &Config{
+		Addr:                  ":4004",
+		LogLevel:              "debug",
+		StatusAddr:            ":10094",
+		ProxyProtocolNetworks: "127.0.0.1",
+	}
+	server, err := NewServer(cfg, ts.tidbdrv)
+	c.Assert(err, IsNil)
+	go server.Run()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	runTests(c, dsn, func(dbt *DBTest) {
+		dbt.mustExec("CREATE USER 'proxy_user'@'192.168.1.10' IDENTIFIED BY '123'")
+		dbt.mustExec("FLUSH PRIVILEGES")
+	})
+	mysql.RegisterDial("proxy-v1", func(addr string) (net.Conn, error) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		_, err = conn.Write([]byte("PROXY TCP4 192.168.1.10 127.0.0.1 5000 4004\r\n"))
+		return conn, err
+	})
+	mysql.RegisterDial("proxy-v2", func(addr string) (net.Conn, error) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		src := &net.TCPAddr{IP: net.ParseIP("192.168.1.20"), Port: 5000}
+		_, err = conn.Write(proxyV2Header(src, conn.RemoteAddr().(*net.TCPAddr)))
+		return conn, err
+	})
+
+	// The address of the client in the PROXY protocol header is used for authentication.
+	runTests(c, "proxy_user:123@proxy-v1(localhost:4004)/test", func(dbt *DBTest) {
+		var user string
+		err := dbt.db.QueryRow("select user()").Scan(&user)
+		dbt.Assert(err, IsNil)
+		dbt.Assert(user, Equals, "proxy_user@192.168.1.10")
+	})
+	runTests(c, "root@proxy-v2(localhost:4004)/test", func(dbt *DBTest) {
+		var user string
+		err := dbt.db.QueryRow("select user()").Scan(&user)
+		dbt.Assert(err, IsNil)
+		dbt.Assert(user, Equals, "root@192.168.1.20")
+	})
+	db, err := sql.Open("mysql", "proxy_user:123@proxy-v2(localhost:4004)/test")
+	c.Assert(err, IsNil)
+	c.Assert(db.Ping(), NotNil)
+	db.Close()
+	// The trusted proxies must send the header.
+	db, err = sql.Open("mysql", "root@tcp(localhost:4004)/test")
+	c.Assert(err, IsNil)
+	c.Assert(db.Ping(), NotNil)
+	db.Close()
+	// The header isn't accepted from the other addresses.
+	db, err = sql.Open("mysql", "proxy_user:123@proxy-v1(localhost:4001)/test")
+	c.Assert(err, IsNil)
+	c.Assert(db.Ping(), NotNil)
+	db.Close()
+}
+
+// generateCert generates a certificate and its key in PEM format in dir, it returns the certificate and the key.
+// The certificate is self-signed if parent is nil, and it is used as a CA.
+func generateCert(c *C, dir, name string, parent *x509.Certificate, parentKey *rsa.PrivateKey) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, IsNil)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"tidb"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	c.Assert(err, IsNil)
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, IsNil)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	err = ioutil.WriteFile(filepath.Join(dir, name+"-cert.pem"), certPEM, 0600)
+	c.Assert(err, IsNil)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	err = ioutil.WriteFile(filepath.Join(dir, name+"-key.pem"), keyPEM, 0600)
+	c.Assert(err, IsNil)
+	return cert, key
+}
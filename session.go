@@ -20,7 +20,9 @@ package tidb
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -29,6 +31,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/bindinfo"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/domain"
 	"github.com/pingcap/tidb/executor"
@@ -37,6 +40,7 @@ import (
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/perfschema"
+	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/privilege/privileges"
 	"github.com/pingcap/tidb/sessionctx"
@@ -47,6 +51,8 @@ import (
 	"github.com/pingcap/tidb/store/localstore"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/execdetails"
+	"github.com/pingcap/tidb/util/kvcache"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-binlog"
 	goctx "golang.org/x/net/context"
@@ -72,6 +78,8 @@ type Session interface {
 	SetSessionManager(util.SessionManager)
 	Close() error
 	Auth(user string, auth []byte, salt []byte) bool
+	// AuthPlugin returns the auth plugin of the user which is in the format of user@host.
+	AuthPlugin(user string) string
 	// Cancel the execution of current transaction.
 	Cancel()
 	ShowProcess() util.ProcessInfo
@@ -130,6 +138,9 @@ type session struct {
 	sessionManager util.SessionManager
 
 	statsCollector *statistics.SessionStatsCollector
+
+	// preparedPlanCache caches the plans of prepared statements, it's nil if the cache is disabled.
+	preparedPlanCache *kvcache.SimpleLRUCache
 }
 
 // Cancel cancels the execution of current transaction.
@@ -149,6 +160,9 @@ func (s *session) cleanRetryInfo() {
 		retryInfo := s.sessionVars.RetryInfo
 		for _, stmtID := range retryInfo.DroppedPreparedStmtIDs {
 			delete(s.sessionVars.PreparedStmts, stmtID)
+			if s.preparedPlanCache != nil {
+				plan.DeletePSTMTPlanCache(s.preparedPlanCache, stmtID)
+			}
 		}
 		retryInfo.Clean()
 	}
@@ -264,11 +278,15 @@ func (s *session) doCommitWithRetry() error {
 	}
 	err := s.doCommit()
 	if err != nil {
-		if s.isRetryableError(err) {
+		// The statements of a pessimistic transaction can't be retried, they may have read the data
+		// written by others after the transaction started.
+		if s.isRetryableError(err) && !s.sessionVars.TxnCtx.IsPessimistic {
 			log.Warnf("[%d] retryable error: %v, txn: %v", s.sessionVars.ConnectionID, err, s.txn)
 			// Transactions will retry 2 ~ commitRetryLimit times.
 			// We make larger transactions retry less times to prevent cluster resource outage.
-			txnSizeRate := float64(txnSize) / float64(kv.TxnTotalSizeLimit)
+			// The transactions which are larger than the limit of the ones committed from memory have
+			// spilled, they retry the least times.
+			txnSizeRate := math.Min(float64(txnSize)/float64(atomic.LoadUint64(&kv.TxnTotalSizeLimit)), 1)
 			maxRetryCount := commitRetryLimit - int(float64(commitRetryLimit-1)*txnSizeRate)
 			err = s.retry(maxRetryCount)
 		}
@@ -288,7 +306,12 @@ func (s *session) doCommitWithRetry() error {
 }
 
 func (s *session) CommitTxn() error {
-	return s.doCommitWithRetry()
+	// The statement context may be changed when the transaction is retried.
+	sc := s.sessionVars.StmtCtx
+	startTime := time.Now()
+	err := s.doCommitWithRetry()
+	sc.ExecDetails.CommitTime += time.Since(startTime)
+	return errors.Trace(err)
 }
 
 func (s *session) RollbackTxn() error {
@@ -555,7 +578,8 @@ func (s *session) Execute(sql string) ([]ast.RecordSet, error) {
 		log.Warnf("[%d] parse error:\n%v\n%s", connID, err, sql)
 		return nil, errors.Trace(err)
 	}
-	sessionExecuteParseDuration.Observe(time.Since(startTS).Seconds())
+	parseTime := time.Since(startTS)
+	sessionExecuteParseDuration.Observe(parseTime.Seconds())
 
 	var rs []ast.RecordSet
 	ph := sessionctx.GetDomain(s).PerfSchema()
@@ -564,13 +588,18 @@ func (s *session) Execute(sql string) ([]ast.RecordSet, error) {
 		startTS := time.Now()
 		// Some execution is done in compile stage, so we reset it before compile.
 		resetStmtCtx(s, rst)
+		// The statements are parsed together, so the parse time is the time of parsing all of them.
+		s.sessionVars.StmtCtx.ExecDetails.ParseTime = parseTime
 		st, err1 := Compile(s, rst)
 		if err1 != nil {
 			log.Warnf("[%d] compile error:\n%v\n%s", connID, err1, sql)
+			executor.AuditCompileError(s, rst, err1)
 			s.RollbackTxn()
 			return nil, errors.Trace(err1)
 		}
-		sessionExecuteCompileDuration.Observe(time.Since(startTS).Seconds())
+		compileTime := time.Since(startTS)
+		s.sessionVars.StmtCtx.ExecDetails.CompileTime = compileTime
+		sessionExecuteCompileDuration.Observe(compileTime.Seconds())
 
 		s.stmtState = ph.StartStatement(sql, connID, perfschema.CallerNameSessionExecute, rawStmts[i])
 		s.SetValue(context.QueryString, st.OriginText())
@@ -665,6 +694,9 @@ func (s *session) ExecutePreparedStmt(stmtID uint32, args ...interface{}) (ast.R
 		return nil, errors.Trace(err)
 	}
 	s.prepareTxnCtx()
+	// The statement context isn't reset for the binary protocol, but the execution details belong to
+	// a single execution.
+	s.sessionVars.StmtCtx.ExecDetails = execdetails.ExecDetails{}
 	st := executor.CompileExecutePreparedStmt(s, stmtID, args...)
 
 	r, err := runStmt(s, st)
@@ -792,23 +824,64 @@ func (s *session) Auth(user string, auth []byte, salt []byte) bool {
 	pm := privilege.GetPrivilegeManager(s)
 
 	// Check IP.
-	if pm.ConnectionVerification(name, host, auth, salt) {
-		s.sessionVars.User = name + "@" + host
+	if pm.ConnectionVerification(name, host, auth, salt, s.sessionVars.TLSConnectionState) {
+		s.authSucceeded(pm, name, host)
 		return true
 	}
 
 	// Check Hostname.
 	for _, addr := range getHostByIP(host) {
-		if pm.ConnectionVerification(name, addr, auth, salt) {
-			s.sessionVars.User = name + "@" + addr
+		if pm.ConnectionVerification(name, addr, auth, salt, s.sessionVars.TLSConnectionState) {
+			s.authSucceeded(pm, name, addr)
 			return true
 		}
 	}
 
+	// The failed login is recorded once even if the hostnames are checked.
+	pm.LoginFailed(name, host)
 	log.Errorf("User connection verification failed %v", user)
 	return false
 }
 
+// authSucceeded sets the user of the session after the user logs in, the user can only change the password
+// if it's expired.
+func (s *session) authSucceeded(pm privilege.Manager, name, host string) {
+	s.sessionVars.User = name + "@" + host
+	s.sessionVars.AuthHost = pm.GetAuthHost(name, host)
+	s.sessionVars.ActiveRoles = pm.GetDefaultRoles(name, host)
+	var lifetime int64
+	val, err := varsutil.GetGlobalSystemVar(s.sessionVars, variable.DefaultPasswordLifetime)
+	if err == nil {
+		lifetime, err = strconv.ParseInt(val, 10, 64)
+	}
+	if err != nil {
+		log.Warnf("Invalid %s value %s: %v", variable.DefaultPasswordLifetime, val, err)
+	}
+	s.sessionVars.PasswordExpired = pm.IsPasswordExpired(name, host, lifetime)
+}
+
+// AuthPlugin returns the auth plugin of the account which the user connects as, the client should authenticate
+// with it. It returns mysql_native_password if no account matches.
+func (s *session) AuthPlugin(user string) string {
+	strs := strings.Split(user, "@")
+	if len(strs) != 2 {
+		log.Warnf("Invalid format for user: %s", user)
+		return mysql.AuthNativePassword
+	}
+	name := strs[0]
+	host := strs[1]
+	pm := privilege.GetPrivilegeManager(s)
+	if plugin := pm.GetAuthPlugin(name, host); plugin != "" {
+		return plugin
+	}
+	for _, addr := range getHostByIP(host) {
+		if plugin := pm.GetAuthPlugin(name, addr); plugin != "" {
+			return plugin
+		}
+	}
+	return mysql.AuthNativePassword
+}
+
 func getHostByIP(ip string) []string {
 	if ip == "127.0.0.1" {
 		return []string{"localhost"}
@@ -877,6 +950,22 @@ func BootstrapSession(store kv.Storage) (*domain.Domain, error) {
 		return nil, errors.Trace(err)
 	}
 	err = dom.UpdateTableStatsLoop(se1)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	se2, err := createSession(store)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = dom.LoadBindInfoLoop(se2)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	se3, err := createSession(store)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = dom.LoadAuditFilterLoop(se3)
 	return dom, errors.Trace(err)
 }
 
@@ -916,8 +1005,12 @@ func createSession(store kv.Storage) (*session, error) {
 		parser:      parser.New(),
 		sessionVars: variable.NewSessionVars(),
 	}
+	if plan.PreparedPlanCacheEnabled {
+		s.preparedPlanCache = kvcache.NewSimpleLRUCache(plan.PreparedPlanCacheCapacity)
+	}
 	s.mu.values = make(map[fmt.Stringer]interface{})
 	sessionctx.BindDomain(s, domain)
+	bindinfo.BindSessionHandle(s, bindinfo.NewSessionHandle())
 	// session implements variable.GlobalVarAccessor. Bind it to ctx.
 	s.sessionVars.GlobalVarsAccessor = s
 
@@ -926,7 +1019,7 @@ func createSession(store kv.Storage) (*session, error) {
 
 const (
 	notBootstrapped         = 0
-	currentBootstrapVersion = 6
+	currentBootstrapVersion = 14
 )
 
 func getStoreBootstrapVersion(store kv.Storage) int64 {
@@ -981,6 +1074,8 @@ const loadCommonGlobalVarsSQL = "select * from mysql.global_variables where vari
 	variable.TiDBIndexLookupSize + quoteCommaQuote +
 	variable.TiDBIndexLookupConcurrency + quoteCommaQuote +
 	variable.TiDBIndexSerialScanConcurrency + quoteCommaQuote +
+	variable.TiDBTxnMode + quoteCommaQuote +
+	variable.InnodbLockWaitTimeout + quoteCommaQuote +
 	variable.TiDBDistSQLScanConcurrency + "')"
 
 // LoadCommonGlobalVariableIfNeeded loads and applies commonly used global variables for the session.
@@ -1060,6 +1155,10 @@ func (s *session) ActivePendingTxn() error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	// The transactions started implicitly when autocommit is off are in the mode of tidb_txn_mode.
+	if s.sessionVars.InTxn() {
+		executor.SetTxnMode(s, "")
+	}
 	return nil
 }
 
@@ -1085,6 +1184,11 @@ func (s *session) InitTxnWithStartTS(startTS uint64) error {
 	return nil
 }
 
+// PreparedPlanCache implements Context.PreparedPlanCache interface.
+func (s *session) PreparedPlanCache() *kvcache.SimpleLRUCache {
+	return s.preparedPlanCache
+}
+
 func (s *session) ShowProcess() util.ProcessInfo {
 	var pi util.ProcessInfo
 	tmp := s.processInfo.Load()
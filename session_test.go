@@ -2105,10 +2105,8 @@ func (s *testSessionSuite) TestSkipWithGrant(c *C) {
 	se := newSession(c, s.store, dbName)
 	dropDBSQL := fmt.Sprintf("drop database %s;", dbName)
 
-	save1 := privileges.Enable
 	save2 := privileges.SkipWithGrant
 
-	privileges.Enable = true
 	privileges.SkipWithGrant = false
 	c.Assert(se.Auth("user_not_exist", []byte("yyy"), []byte("zzz")), IsFalse)
 
@@ -2117,7 +2115,6 @@ func (s *testSessionSuite) TestSkipWithGrant(c *C) {
 	c.Assert(se.Auth(`root@%`, []byte(""), []byte("")), IsTrue)
 	mustExecSQL(c, se, "create table t (id int)")
 
-	privileges.Enable = save1
 	privileges.SkipWithGrant = save2
 	mustExecSQL(c, se, dropDBSQL)
 }
@@ -2730,3 +2727,18 @@ func (s *testSessionSuite) TestRetryResetStmtCtx(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(se.AffectedRows(), Equals, uint64(1))
 }
+
+func (s *testSessionSuite) TestStaleReadSafePoint(c *C) {
+	defer testleak.AfterTest(c)()
+	dbName := "test_stale_read_safe_point"
+	se := newSession(c, s.store, dbName)
+	mustExecSQL(c, se, "create table t (a int)")
+	mustExecSQL(c, se, "insert t values (1)")
+
+	// The compactor of localstore drops the versions older than 20 seconds.
+	_, err := se.Execute("select * from t as of timestamp date_sub(now(), interval 1 minute)")
+	c.Assert(plan.ErrSnapshotTooOld.Equal(err), IsTrue)
+	_, err = se.Execute("start transaction read only as of timestamp date_sub(now(), interval 1 minute)")
+	c.Assert(plan.ErrSnapshotTooOld.Equal(err), IsTrue)
+	mustExecMatch(c, se, "select * from t as of timestamp now(6)", [][]interface{}{{1}})
+}
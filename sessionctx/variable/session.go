@@ -14,12 +14,16 @@
 package variable
 
 import (
+	"crypto/tls"
 	"math"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/execdetails"
 )
 
 const (
@@ -76,12 +80,18 @@ func (r *RetryInfo) GetCurrAutoIncrementID() (int64, error) {
 // TransactionContext is used to store variables that has transaction scope.
 type TransactionContext struct {
 	ForUpdate     bool
+	IsPessimistic bool
 	DirtyDB       interface{}
 	Binlog        interface{}
 	InfoSchema    interface{}
 	Histroy       interface{}
 	SchemaVersion int64
 	TableDeltaMap map[int64]TableDelta
+	// ReadOnly is true if the transaction is started by START TRANSACTION READ ONLY.
+	ReadOnly bool
+	// StaleReadTS is the timestamp of a transaction started by START TRANSACTION READ ONLY AS OF TIMESTAMP,
+	// the transaction reads the data at it.
+	StaleReadTS uint64
 }
 
 // UpdateDeltaForTable updates the delta info for some table.
@@ -124,9 +134,23 @@ type SessionVars struct {
 	// Connection ID
 	ConnectionID uint64
 
+	// TLSConnectionState is the TLS state of the connection, it is nil if the connection is not encrypted.
+	TLSConnectionState *tls.ConnectionState
+
 	// Current user
 	User string
 
+	// AuthHost is the host of the account in mysql.user which the current user is authenticated as,
+	// it may be a pattern like '%'.
+	AuthHost string
+
+	// ActiveRoles are the active roles of the current user in "user@host" format.
+	ActiveRoles []string
+
+	// PasswordExpired is true if the password of the current user is expired, only SET PASSWORD, ALTER USER and
+	// SET statements are allowed until the password is changed.
+	PasswordExpired bool
+
 	// Current DB
 	CurrentDB string
 
@@ -197,11 +221,23 @@ type SessionVars struct {
 
 	// Should we split insert data into multiple batches.
 	BatchInsert bool
+
+	// SlowLogThreshold is the execution time in milliseconds, the statements slower than it are written to the
+	// slow query log.
+	SlowLogThreshold int
+
+	// TxnMode is the mode of the explicit transactions which don't specify one, it's PessimisticTxnMode
+	// or OptimisticTxnMode.
+	TxnMode string
+
+	// LockWaitTimeout is how long a pessimistic transaction waits for a lock, it's set by innodb_lock_wait_timeout.
+	LockWaitTimeout time.Duration
 }
 
 // NewSessionVars creates a session vars object.
 func NewSessionVars() *SessionVars {
-	return &SessionVars{
+	slowLogThreshold := atomic.LoadUint64(&defaultSlowLogThreshold)
+	vars := &SessionVars{
 		Users:                      make(map[string]string),
 		Systems:                    make(map[string]string),
 		PreparedStmts:              make(map[uint32]interface{}),
@@ -217,7 +253,22 @@ func NewSessionVars() *SessionVars {
 		IndexLookupConcurrency:     DefIndexLookupConcurrency,
 		IndexSerialScanConcurrency: DefIndexSerialScanConcurrency,
 		DistSQLScanConcurrency:     DefDistSQLScanConcurrency,
+		SlowLogThreshold:           int(slowLogThreshold),
+		TxnMode:                    DefTxnMode,
+		LockWaitTimeout:            DefLockWaitTimeout * time.Second,
 	}
+	// The default value of tidb_slow_log_threshold is set by the config of tidb-server.
+	vars.Systems[TiDBSlowLogThreshold] = strconv.FormatUint(slowLogThreshold, 10)
+	return vars
+}
+
+// defaultSlowLogThreshold is the default value of tidb_slow_log_threshold, it's accessed atomically because
+// it can be reloaded when the server is running.
+var defaultSlowLogThreshold uint64 = DefSlowLogThreshold
+
+// SetDefaultSlowLogThreshold sets the default value of tidb_slow_log_threshold of the new sessions.
+func SetDefaultSlowLogThreshold(threshold uint64) {
+	atomic.StoreUint64(&defaultSlowLogThreshold, threshold)
 }
 
 const (
@@ -301,6 +352,12 @@ type StatementContext struct {
 	IgnoreTruncate       bool
 	TruncateAsWarning    bool
 	InShowWarning        bool
+	// UseCache indicates the plan of the statement is built to be cached, so
+	// parameter markers must not be folded into the plan.
+	UseCache bool
+	// ForUpdateTS is the timestamp which the statement of a pessimistic transaction reads at, the
+	// statement reads at the start timestamp of the transaction if it's 0.
+	ForUpdateTS uint64
 
 	/* Variables that changes during execution. */
 	mu struct {
@@ -309,6 +366,8 @@ type StatementContext struct {
 		foundRows    uint64
 		warnings     []error
 	}
+	// ExecDetails is written to the slow query log.
+	ExecDetails execdetails.ExecDetails
 }
 
 // AddAffectedRows adds affected rows.
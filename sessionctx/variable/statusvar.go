@@ -33,8 +33,8 @@ type StatusVal struct {
 type Statistics interface {
 	// GetScope gets the status variables scope.
 	GetScope(status string) ScopeFlag
-	// Stats returns the statistics status variables.
-	Stats() (map[string]interface{}, error)
+	// Stats returns the statistics status variables of the session.
+	Stats(vars *SessionVars) (map[string]interface{}, error)
 }
 
 // RegisterStatistics registers statistics.
@@ -42,12 +42,12 @@ func RegisterStatistics(s Statistics) {
 	statisticsList = append(statisticsList, s)
 }
 
-// GetStatusVars gets registered statistics status variables.
-func GetStatusVars() (map[string]*StatusVal, error) {
+// GetStatusVars gets registered statistics status variables of the session.
+func GetStatusVars(vars *SessionVars) (map[string]*StatusVal, error) {
 	statusVars := make(map[string]*StatusVal)
 
 	for _, statistics := range statisticsList {
-		vals, err := statistics.Stats()
+		vals, err := statistics.Stats(vars)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -59,6 +59,7 @@ const (
 	CodeUnknownStatusVar terror.ErrCode = 1
 	CodeUnknownSystemVar terror.ErrCode = 1193
 	CodeIncorrectScope   terror.ErrCode = 1238
+	CodeWrongValueForVar terror.ErrCode = 1231
 )
 
 // Variable errors
@@ -66,6 +67,8 @@ var (
 	UnknownStatusVar  = terror.ClassVariable.New(CodeUnknownStatusVar, "unknown status variable")
 	UnknownSystemVar  = terror.ClassVariable.New(CodeUnknownSystemVar, "unknown system variable '%s'")
 	ErrIncorrectScope = terror.ClassVariable.New(CodeIncorrectScope, "Incorrect variable scope")
+	// ErrWrongValueForVar is returned when a variable is set to a value it doesn't accept.
+	ErrWrongValueForVar = terror.ClassVariable.New(CodeWrongValueForVar, "Variable '%s' can't be set to the value of '%s'")
 )
 
 func init() {
@@ -78,6 +81,7 @@ func init() {
 	mySQLErrCodes := map[terror.ErrCode]uint16{
 		CodeUnknownSystemVar: mysql.ErrUnknownSystemVariable,
 		CodeIncorrectScope:   mysql.ErrIncorrectGlobalLocalVar,
+		CodeWrongValueForVar: mysql.ErrWrongValueForVar,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassVariable] = mySQLErrCodes
 }
@@ -107,7 +111,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal, "slave_pending_jobs_size_max", "16777216"},
 	{ScopeNone, "innodb_sync_array_size", "1"},
 	{ScopeSession, "rand_seed2", ""},
-	{ScopeGlobal, "validate_password_number_count", ""},
+	{ScopeGlobal, ValidatePasswordNumberCount, "1"},
 	{ScopeSession, "gtid_next", ""},
 	{ScopeGlobal | ScopeSession, "sql_select_limit", "18446744073709551615"},
 	{ScopeGlobal, "ndb_show_foreign_key_mock_tables", ""},
@@ -175,7 +179,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "innodb_log_group_home_dir", "./"},
 	{ScopeNone, "performance_schema_events_statements_history_size", "10"},
 	{ScopeGlobal, "general_log", "OFF"},
-	{ScopeGlobal, "validate_password_dictionary_file", ""},
+	{ScopeGlobal, ValidatePasswordDictionaryFile, ""},
 	{ScopeGlobal, "binlog_order_commits", "ON"},
 	{ScopeGlobal, "master_verify_checksum", "OFF"},
 	{ScopeGlobal, "key_cache_division_limit", "100"},
@@ -190,7 +194,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal, "rpl_stop_slave_timeout", "31536000"},
 	{ScopeNone, "skip_networking", "OFF"},
 	{ScopeGlobal, "innodb_monitor_reset", ""},
-	{ScopeNone, "have_ssl", "DISABLED"},
+	{ScopeNone, HaveSSL, "DISABLED"},
 	{ScopeNone, "system_time_zone", "CST"},
 	{ScopeGlobal, "innodb_print_all_deadlocks", "OFF"},
 	{ScopeNone, "innodb_autoinc_lock_mode", "1"},
@@ -244,8 +248,8 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "performance_schema_max_file_classes", "50"},
 	{ScopeGlobal, "expire_logs_days", "0"},
 	{ScopeGlobal | ScopeSession, "binlog_rows_query_log_events", "OFF"},
-	{ScopeGlobal, "validate_password_policy", ""},
-	{ScopeGlobal, "default_password_lifetime", ""},
+	{ScopeGlobal, ValidatePasswordPolicy, "MEDIUM"},
+	{ScopeGlobal, DefaultPasswordLifetime, "0"},
 	{ScopeNone, "pid_file", "/usr/local/mysql/data/localhost.pid"},
 	{ScopeNone, "innodb_undo_tablespaces", "0"},
 	{ScopeGlobal, "innodb_status_output_locks", "OFF"},
@@ -524,7 +528,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeNone, "basedir", "/usr/local/mysql"},
 	{ScopeGlobal, "innodb_old_blocks_time", "1000"},
 	{ScopeGlobal, "innodb_stats_method", "nulls_equal"},
-	{ScopeGlobal | ScopeSession, "innodb_lock_wait_timeout", "50"},
+	{ScopeGlobal | ScopeSession, InnodbLockWaitTimeout, strconv.Itoa(DefLockWaitTimeout)},
 	{ScopeGlobal, "local_infile", "ON"},
 	{ScopeGlobal | ScopeSession, "myisam_stats_method", "nulls_unequal"},
 	{ScopeNone, "version_compile_os", "osx10.8"},
@@ -557,7 +561,7 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal, "myisam_use_mmap", "OFF"},
 	{ScopeGlobal | ScopeSession, "ndb_join_pushdown", ""},
 	{ScopeGlobal | ScopeSession, "character_set_server", "latin1"},
-	{ScopeGlobal, "validate_password_special_char_count", ""},
+	{ScopeGlobal, ValidatePasswordSpecialCharCount, "1"},
 	{ScopeNone, "performance_schema_max_thread_instances", "402"},
 	{ScopeGlobal, "slave_rows_search_algorithms", "TABLE_SCAN,INDEX_SCAN"},
 	{ScopeGlobal | ScopeSession, "ndbinfo_show_hidden", ""},
@@ -568,9 +572,9 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal, "sync_relay_log_info", "10000"},
 	{ScopeGlobal | ScopeSession, "optimizer_trace_limit", "1"},
 	{ScopeNone, "innodb_ft_max_token_size", "84"},
-	{ScopeGlobal, "validate_password_length", ""},
+	{ScopeGlobal, ValidatePasswordLength, "8"},
 	{ScopeGlobal, "ndb_log_binlog_index", ""},
-	{ScopeGlobal, "validate_password_mixed_case_count", ""},
+	{ScopeGlobal, ValidatePasswordMixedCaseCount, "1"},
 	{ScopeGlobal, "innodb_api_bk_commit_interval", "5"},
 	{ScopeNone, "innodb_undo_directory", "."},
 	{ScopeNone, "bind_address", "*"},
@@ -603,6 +607,9 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal | ScopeSession, TiDBSkipDDLWait, boolToIntStr(DefSkipDDLWait)},
 	{ScopeGlobal | ScopeSession, TiDBSkipUTF8Check, boolToIntStr(DefSkipUTF8Check)},
 	{ScopeSession, TiDBBatchInsert, boolToIntStr(DefBatchInsert)},
+	{ScopeSession, TiDBSlowLogThreshold, strconv.Itoa(DefSlowLogThreshold)},
+	{ScopeGlobal | ScopeSession, TiDBTxnMode, DefTxnMode},
+	{ScopeGlobal, TiDBValidatePasswordEnable, boolToIntStr(DefValidatePasswordEnable)},
 }
 
 // SetNamesVariables is the system variable names related to set names statements.
@@ -619,6 +626,24 @@ const (
 	CharsetDatabase = "character_set_database"
 	// CollationDatabase is the name for collation_database system variable.
 	CollationDatabase = "collation_database"
+	// HaveSSL is the name for have_ssl system variable.
+	HaveSSL = "have_ssl"
+	// DefaultPasswordLifetime is the name for default_password_lifetime system variable.
+	DefaultPasswordLifetime = "default_password_lifetime"
+	// ValidatePasswordPolicy is the name for validate_password_policy system variable.
+	ValidatePasswordPolicy = "validate_password_policy"
+	// ValidatePasswordLength is the name for validate_password_length system variable.
+	ValidatePasswordLength = "validate_password_length"
+	// ValidatePasswordMixedCaseCount is the name for validate_password_mixed_case_count system variable.
+	ValidatePasswordMixedCaseCount = "validate_password_mixed_case_count"
+	// ValidatePasswordNumberCount is the name for validate_password_number_count system variable.
+	ValidatePasswordNumberCount = "validate_password_number_count"
+	// ValidatePasswordSpecialCharCount is the name for validate_password_special_char_count system variable.
+	ValidatePasswordSpecialCharCount = "validate_password_special_char_count"
+	// ValidatePasswordDictionaryFile is the name for validate_password_dictionary_file system variable.
+	ValidatePasswordDictionaryFile = "validate_password_dictionary_file"
+	// InnodbLockWaitTimeout is the name for innodb_lock_wait_timeout system variable.
+	InnodbLockWaitTimeout = "innodb_lock_wait_timeout"
 )
 
 // GlobalVarAccessor is the interface for accessing global scope system and status variables.
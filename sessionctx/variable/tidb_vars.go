@@ -87,6 +87,21 @@ const (
 	// tidb_batch_insert is used to enable/disable auto-split insert data. If set this option on, insert executor will automatically
 	// insert data into multiple batches and use a single txn for each batch. This will be helpful when inserting large data.
 	TiDBBatchInsert = "tidb_batch_insert"
+
+	// tidb_slow_log_threshold is the execution time in milliseconds, the statements slower than it are written to
+	// the slow query log. Its default value is the log.slow-threshold config of tidb-server.
+	TiDBSlowLogThreshold = "tidb_slow_log_threshold"
+
+	// tidb_txn_mode is the mode of the explicit transactions which are started without a mode, it's 'optimistic'
+	// or 'pessimistic'. A pessimistic transaction locks the rows when it writes or locks them and waits for the
+	// locks of other transactions, instead of aborting at commit when the rows are written by others.
+	TiDBTxnMode = "tidb_txn_mode"
+
+	/* Global only */
+
+	// tidb_validate_password_enable enables the password validation policy configured by the validate_password_*
+	// variables, the passwords set by CREATE USER, ALTER USER and SET PASSWORD must satisfy the policy.
+	TiDBValidatePasswordEnable = "tidb_validate_password_enable"
 )
 
 // Default TiDB system variable values.
@@ -101,4 +116,14 @@ const (
 	DefOptAggPushDown             = true
 	DefOptInSubqUnfolding         = false
 	DefBatchInsert                = false
+	DefSlowLogThreshold           = 300
+	DefValidatePasswordEnable     = false
+	DefTxnMode                    = OptimisticTxnMode
+	DefLockWaitTimeout            = 50
+)
+
+// The values of tidb_txn_mode.
+const (
+	OptimisticTxnMode  = "optimistic"
+	PessimisticTxnMode = "pessimistic"
 )
@@ -59,6 +59,10 @@ func GetGlobalSystemVar(s *variable.SessionVars, key string) (string, error) {
 	if sysVar.Scope == variable.ScopeSession {
 		return "", variable.ErrIncorrectScope
 	} else if sysVar.Scope == variable.ScopeNone {
+		// The value may be determined by the server when the session is opened, e.g. have_ssl.
+		if sVal, ok := s.Systems[key]; ok {
+			return sVal, nil
+		}
 		return sysVar.Value, nil
 	}
 	return s.GlobalVarsAccessor.GetGlobalSysVar(key)
@@ -134,6 +138,18 @@ func SetSessionSystemVar(vars *variable.SessionVars, name string, value types.Da
 		vars.IndexSerialScanConcurrency = tidbOptPositiveInt(sVal, variable.DefIndexSerialScanConcurrency)
 	case variable.TiDBBatchInsert:
 		vars.BatchInsert = tidbOptOn(sVal)
+	case variable.TiDBSlowLogThreshold:
+		vars.SlowLogThreshold = tidbOptNonNegativeInt(sVal, variable.DefSlowLogThreshold)
+	case variable.TiDBTxnMode:
+		switch strings.ToLower(sVal) {
+		case variable.OptimisticTxnMode, variable.PessimisticTxnMode:
+			sVal = strings.ToLower(sVal)
+			vars.TxnMode = sVal
+		default:
+			return variable.ErrWrongValueForVar.GenByArgs(name, sVal)
+		}
+	case variable.InnodbLockWaitTimeout:
+		vars.LockWaitTimeout = time.Duration(tidbOptPositiveInt(sVal, variable.DefLockWaitTimeout)) * time.Second
 	}
 	vars.Systems[name] = sVal
 	return nil
@@ -152,6 +168,14 @@ func tidbOptPositiveInt(opt string, defaultVal int) int {
 	return val
 }
 
+func tidbOptNonNegativeInt(opt string, defaultVal int) int {
+	val, err := strconv.Atoi(opt)
+	if err != nil || val < 0 {
+		return defaultVal
+	}
+	return val
+}
+
 func parseTimeZone(s string) *time.Location {
 	if s == "SYSTEM" {
 		// TODO: Support global time_zone variable, it should be set to global time_zone value.
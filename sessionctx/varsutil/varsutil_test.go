@@ -147,6 +147,13 @@ func (s *testVarsutilSuite) TestVarsutil(c *C) {
 	c.Assert(v.BatchInsert, IsFalse)
 	SetSessionSystemVar(v, variable.TiDBBatchInsert, types.NewStringDatum("1"))
 	c.Assert(v.BatchInsert, IsTrue)
+
+	// Test case for tidb_slow_log_threshold.
+	c.Assert(v.SlowLogThreshold, Equals, variable.DefSlowLogThreshold)
+	SetSessionSystemVar(v, variable.TiDBSlowLogThreshold, types.NewStringDatum("0"))
+	c.Assert(v.SlowLogThreshold, Equals, 0)
+	SetSessionSystemVar(v, variable.TiDBSlowLogThreshold, types.NewStringDatum("-1"))
+	c.Assert(v.SlowLogThreshold, Equals, variable.DefSlowLogThreshold)
 }
 
 type mockGlobalAccessor struct {
@@ -143,6 +143,13 @@ func (gc *localstoreCompactor) checkExpiredKeysWorker() {
 	}
 }
 
+// safePoint returns the timestamp before which the old versions may be deleted. The newest version before
+// it is kept, so the data at any timestamp after it can be read.
+func (gc *localstoreCompactor) safePoint() uint64 {
+	currentTS := time.Now().UnixNano() / int64(time.Millisecond)
+	return uint64(currentTS-int64(gc.policy.SafePoint)) << timePrecisionOffset
+}
+
 func (gc *localstoreCompactor) filterExpiredKeys(keys []kv.EncodedKey) []kv.EncodedKey {
 	var ret []kv.EncodedKey
 	first := true
@@ -119,6 +119,21 @@ func (s *testLocalstoreCompactorSuite) TestGetAllVersions(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *testLocalstoreCompactorSuite) TestSafePoint(c *C) {
+	defer testleak.AfterTest(c)()
+	store := createMemStore(time.Now().Nanosecond())
+	expired := time2TsPhysical(time.Now().Add(-21 * time.Second))
+	ver, err := store.CurrentVersion()
+	c.Assert(err, IsNil)
+
+	safePoint := store.(*dbStore).GCSafePoint()
+	c.Assert(safePoint > expired, IsTrue)
+	c.Assert(safePoint < ver.Ver, IsTrue)
+
+	err = store.Close()
+	c.Assert(err, IsNil)
+}
+
 // TestStartStop is to test `Panic: sync: WaitGroup is reused before previous Wait has returned`
 // in Stop function.
 func (s *testLocalstoreCompactorSuite) TestStartStop(c *C) {
@@ -0,0 +1,392 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package encrypted implements an engine Driver which encrypts the data of another local storage engine, like
+// goleveldb or boltdb. The values are encrypted by AES-GCM with the master keys in a key file, and the keys
+// are optionally stored with an order-preserving mapping.
+package encrypted
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/store/localstore/engine"
+	"github.com/pingcap/tidb/terror"
+)
+
+// ErrCorrupted indicates the stored data can't be decrypted, it's corrupted or encrypted with another key.
+var ErrCorrupted = errors.New("encrypted engine: corrupted data or wrong master key")
+
+var (
+	_ engine.DB    = (*db)(nil)
+	_ engine.Batch = (*batch)(nil)
+)
+
+// KeyMode decides how the keys are stored.
+type KeyMode int
+
+// KeyMode values.
+const (
+	// KeyPlain stores the keys as they are, only the values are encrypted.
+	KeyPlain KeyMode = iota
+	// KeyPrefix maps the first PrefixLen bytes of the keys, which are the table and index IDs of TiDB, and
+	// stores the rest as they are.
+	KeyPrefix
+	// KeyOrderPreserving maps the whole keys.
+	KeyOrderPreserving
+)
+
+// ParseKeyMode parses the key mode of the configuration, which is one of "plain", "prefix" and "order-preserving".
+func ParseKeyMode(s string) (KeyMode, error) {
+	switch strings.ToLower(s) {
+	case "", "plain":
+		return KeyPlain, nil
+	case "prefix":
+		return KeyPrefix, nil
+	case "order-preserving":
+		return KeyOrderPreserving, nil
+	}
+	return KeyPlain, errors.Errorf("invalid key mode %q, it should be one of plain, prefix and order-preserving", s)
+}
+
+// metaKey is the stored key of the metadata of the DB, which records how the keys are stored. It's stored
+// as it is, and sorts before the stored keys of the data: the mapped keys never start with two zero bytes,
+// and it isn't a key encoded by the local store.
+var metaKey = []byte("\x00\x00tidb-encrypted-meta")
+
+// dataStart is the first stored key after metaKey.
+var dataStart = append(append([]byte(nil), metaKey...), 0)
+
+// meta is the metadata of the DB, the DB can only be opened with the key mode it's created with.
+type meta struct {
+	KeyMode   KeyMode `json:"key-mode"`
+	PrefixLen int     `json:"prefix-len"`
+}
+
+// rotateBatchSize is the number of the entries which are checked by the key rotation in one batch.
+const rotateBatchSize = 256
+
+// Driver implements engine Driver, it encrypts the data of the DB opened by the wrapped Driver.
+//
+// The key mapping of KeyPrefix and KeyOrderPreserving is derived from the master key with the smallest ID,
+// which must be kept in the key file as long as the data exists. To rotate the master key, append a key with
+// a larger ID to the key file and restart, the values encrypted with the other keys are rewritten in the
+// background, then the old keys except the one of the key mapping can be removed. The key mapping is never
+// rotated, the stored keys stay the same after the master key is rotated.
+//
+// The key mode and the prefix length are recorded when the DB is created, the DB can't be opened with another
+// mode, and the encryption can't be enabled on a DB which is not encrypted.
+type Driver struct {
+	engine.Driver
+	// KeyFile is the path of the file of the master keys.
+	KeyFile string
+	KeyMode KeyMode
+	// PrefixLen is the number of the mapped bytes of KeyPrefix.
+	PrefixLen int
+}
+
+// Open opens or creates an encrypted local storage DB with the wrapped Driver.
+func (d Driver) Open(schema string) (engine.DB, error) {
+	keys, err := loadMasterKeys(d.KeyFile)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var mapping *keyMapping
+	switch d.KeyMode {
+	case KeyPrefix:
+		if d.PrefixLen <= 0 {
+			return nil, errors.Errorf("invalid key prefix length %d", d.PrefixLen)
+		}
+		mapping, err = newKeyMapping(keys.raw[keys.first], d.PrefixLen)
+	case KeyOrderPreserving:
+		mapping, err = newKeyMapping(keys.raw[keys.first], -1)
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	inner, err := d.Driver.Open(schema)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	m := meta{KeyMode: d.KeyMode}
+	if d.KeyMode == KeyPrefix {
+		m.PrefixLen = d.PrefixLen
+	}
+	edb := newDB(inner, keys, mapping)
+	if err = edb.checkMeta(m); err != nil {
+		inner.Close()
+		return nil, errors.Trace(err)
+	}
+	edb.startRotation()
+	return edb, nil
+}
+
+type db struct {
+	inner   engine.DB
+	keys    *masterKeys
+	mapping *keyMapping
+
+	// mu serializes the commits and the batches of the key rotation, so the rotation never overwrites
+	// a newer value.
+	mu      sync.Mutex
+	quit    chan struct{}
+	rotated chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newDB(inner engine.DB, keys *masterKeys, mapping *keyMapping) *db {
+	d := &db{
+		inner:   inner,
+		keys:    keys,
+		mapping: mapping,
+		quit:    make(chan struct{}),
+		rotated: make(chan struct{}),
+	}
+	return d
+}
+
+// checkMeta checks the DB is created with the key mode of m, it writes the metadata if the DB is empty.
+// The DB without the metadata is accepted if its first entry can be read with m, it's created before the
+// metadata is recorded.
+func (d *db) checkMeta(m meta) error {
+	expected, err := json.Marshal(m)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	data, err := d.inner.Get(metaKey)
+	if err == nil {
+		var stored meta
+		if err = json.Unmarshal(data, &stored); err != nil {
+			return errors.Errorf("encrypted engine: invalid metadata %q", data)
+		}
+		if stored != m {
+			return errors.Errorf("encrypted engine: the DB is created with %s, it can't be opened with %s", data, expected)
+		}
+		return nil
+	}
+	if !terror.ErrorEqual(err, engine.ErrNotFound) {
+		return errors.Trace(err)
+	}
+	stored, sealed, err := d.inner.Seek(nil)
+	if err == nil {
+		if _, _, err = d.decodeEntry(stored, sealed); err != nil {
+			return errors.Errorf("encrypted engine: the DB is not encrypted or is created with another key mode: %v", err)
+		}
+	} else if !terror.ErrorEqual(err, engine.ErrNotFound) {
+		return errors.Trace(err)
+	}
+	b := d.inner.NewBatch()
+	b.Put(metaKey, expected)
+	return errors.Trace(d.inner.Commit(b))
+}
+
+// startRotation starts rewriting the values in the background if there are old master keys.
+func (d *db) startRotation() {
+	if len(d.keys.aeads) > 1 {
+		d.wg.Add(1)
+		go d.rotate()
+	} else {
+		close(d.rotated)
+	}
+}
+
+func (d *db) encodeKey(key []byte) []byte {
+	if d.mapping == nil {
+		return key
+	}
+	return d.mapping.encode(key)
+}
+
+func (d *db) decodeKey(stored []byte) ([]byte, error) {
+	if d.mapping == nil {
+		return stored, nil
+	}
+	return d.mapping.decode(stored)
+}
+
+func (d *db) Get(key []byte) ([]byte, error) {
+	sealed, err := d.inner.Get(d.encodeKey(key))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	value, err := d.keys.open(key, sealed)
+	return value, errors.Trace(err)
+}
+
+func (d *db) Seek(startKey []byte) ([]byte, []byte, error) {
+	start := d.encodeKey(startKey)
+	if bytes.Compare(start, metaKey) <= 0 {
+		start = dataStart
+	}
+	stored, sealed, err := d.inner.Seek(start)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return d.decodeEntry(stored, sealed)
+}
+
+func (d *db) SeekReverse(key []byte) ([]byte, []byte, error) {
+	stored, sealed, err := d.inner.SeekReverse(d.encodeKey(key))
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	if bytes.Equal(stored, metaKey) {
+		return nil, nil, errors.Trace(engine.ErrNotFound)
+	}
+	return d.decodeEntry(stored, sealed)
+}
+
+func (d *db) decodeEntry(stored, sealed []byte) ([]byte, []byte, error) {
+	key, err := d.decodeKey(stored)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	value, err := d.keys.open(key, sealed)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return key, value, nil
+}
+
+func (d *db) NewBatch() engine.Batch {
+	return &batch{d: d, inner: d.inner.NewBatch()}
+}
+
+func (d *db) Commit(b engine.Batch) error {
+	bt, ok := b.(*batch)
+	if !ok {
+		return errors.Errorf("invalid batch type %T", b)
+	}
+	if bt.err != nil {
+		return errors.Trace(bt.err)
+	}
+	d.mu.Lock()
+	err := d.inner.Commit(bt.inner)
+	d.mu.Unlock()
+	return errors.Trace(err)
+}
+
+func (d *db) Close() error {
+	close(d.quit)
+	d.wg.Wait()
+	return d.inner.Close()
+}
+
+// rotate rewrites the values which are not encrypted with the current master key.
+func (d *db) rotate() {
+	defer d.wg.Done()
+	defer close(d.rotated)
+
+	log.Infof("[encrypted] start rewriting the values with master key %d", d.keys.current)
+	var (
+		start     []byte
+		rewritten int
+	)
+	for {
+		select {
+		case <-d.quit:
+			return
+		default:
+		}
+		next, n, err := d.rotateBatch(start)
+		if err != nil {
+			log.Errorf("[encrypted] key rotation stopped: %v", errors.ErrorStack(err))
+			return
+		}
+		rewritten += n
+		if next == nil {
+			log.Infof("[encrypted] key rotation finished, %d values are rewritten", rewritten)
+			return
+		}
+		start = next
+	}
+}
+
+// rotateBatch rewrites the values of the entries from start, it returns the stored key to continue with,
+// which is nil at the end of the DB, and the number of the rewritten values.
+func (d *db) rotateBatch(start []byte) ([]byte, int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	b := d.inner.NewBatch()
+	next := start
+	if next == nil {
+		next = dataStart
+	}
+	for i := 0; i < rotateBatchSize; i++ {
+		stored, sealed, err := d.inner.Seek(next)
+		if terror.ErrorEqual(err, engine.ErrNotFound) {
+			next = nil
+			break
+		}
+		if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+		next = append(append([]byte(nil), stored...), 0)
+		id, err := sealedKeyID(sealed)
+		if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+		if id == d.keys.current {
+			continue
+		}
+		key, value, err := d.decodeEntry(stored, sealed)
+		if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+		sealed, err = d.keys.seal(key, value)
+		if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+		b.Put(stored, sealed)
+	}
+	n := b.Len()
+	if n > 0 {
+		if err := d.inner.Commit(b); err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+	}
+	return next, n, nil
+}
+
+// batch encrypts the writes into the Batch of the wrapped DB.
+type batch struct {
+	d     *db
+	inner engine.Batch
+	// err is the first error of the encryption, it's returned by Commit.
+	err error
+}
+
+func (b *batch) Put(key []byte, value []byte) {
+	sealed, err := b.d.keys.seal(key, value)
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+		return
+	}
+	b.inner.Put(b.d.encodeKey(key), sealed)
+}
+
+func (b *batch) Delete(key []byte) {
+	b.inner.Delete(b.d.encodeKey(key))
+}
+
+func (b *batch) Len() int {
+	return b.inner.Len()
+}
@@ -0,0 +1,287 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encrypted
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/store/localstore/boltdb"
+	"github.com/pingcap/tidb/store/localstore/engine"
+	"github.com/pingcap/tidb/store/localstore/goleveldb"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	CustomVerboseFlag = true
+	TestingT(t)
+}
+
+var _ = Suite(&testSuite{})
+
+type testSuite struct {
+	dir string
+}
+
+const (
+	testKey1 = "000102030405060708090a0b0c0d0e0f"
+	testKey2 = "101112131415161718191a1b1c1d1e1f101112131415161718191a1b1c1d1e1f"
+)
+
+func (s *testSuite) SetUpTest(c *C) {
+	var err error
+	s.dir, err = ioutil.TempDir("", "encrypted")
+	c.Assert(err, IsNil)
+}
+
+func (s *testSuite) TearDownTest(c *C) {
+	os.RemoveAll(s.dir)
+}
+
+func (s *testSuite) writeKeyFile(c *C, content string) string {
+	path := filepath.Join(s.dir, "keys")
+	err := ioutil.WriteFile(path, []byte(content), 0600)
+	c.Assert(err, IsNil)
+	return path
+}
+
+func (s *testSuite) TestKeyFile(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		content string
+		errMsg  string
+	}{
+		{"", ".*has no key"},
+		{"# comment\n\n1 " + testKey1 + "\n2 " + testKey2 + "\n", ""},
+		{"1", ".*line 1: expect a key ID and a hex encoded key"},
+		{"0 " + testKey1, ".*line 1: invalid key ID \"0\""},
+		{"1 xyz", ".*line 1: invalid hex key"},
+		{"1 0102", ".*line 1: .*invalid key size 2"},
+		{"1 " + testKey1 + "\n1 " + testKey2, ".*line 2: duplicate key ID 1"},
+	}
+	for _, t := range tbl {
+		keys, err := loadMasterKeys(s.writeKeyFile(c, t.content))
+		if t.errMsg != "" {
+			c.Assert(err, ErrorMatches, t.errMsg, Commentf("%q", t.content))
+			continue
+		}
+		c.Assert(err, IsNil)
+		c.Assert(keys.current, Equals, uint32(2))
+		c.Assert(keys.first, Equals, uint32(1))
+	}
+}
+
+func (s *testSuite) TestKeyMapping(c *C) {
+	defer testleak.AfterTest(c)()
+	keys := [][]byte{
+		nil,
+		{0},
+		{0, 0},
+		{0, 1},
+		{1},
+		{1, 0, 255},
+		[]byte("m\x00Tables"),
+		[]byte("t\x80\x00\x00\x00\x00\x00\x00\x01_r\x80\x00\x00\x00\x00\x00\x00\x01"),
+		[]byte("t\x80\x00\x00\x00\x00\x00\x00\x01_r\x80\x00\x00\x00\x00\x00\x00\x02"),
+		[]byte("t\x80\x00\x00\x00\x00\x00\x00\x02"),
+		bytes.Repeat([]byte{255}, 40),
+	}
+	for _, prefixLen := range []int{-1, 1, 10} {
+		m, err := newKeyMapping([]byte("secret"), prefixLen)
+		c.Assert(err, IsNil)
+		encoded := make([][]byte, 0, len(keys))
+		for _, k := range keys {
+			e := m.encode(k)
+			d, err := m.decode(e)
+			c.Assert(err, IsNil)
+			c.Assert(d, BytesEquals, k)
+			encoded = append(encoded, e)
+		}
+		c.Assert(sort.SliceIsSorted(encoded, func(i, j int) bool {
+			return bytes.Compare(encoded[i], encoded[j]) < 0
+		}), IsTrue, Commentf("prefix length %d", prefixLen))
+	}
+}
+
+func (s *testSuite) openDB(c *C, driver engine.Driver, keyFile string, mode KeyMode) engine.DB {
+	d := Driver{Driver: driver, KeyFile: keyFile, KeyMode: mode, PrefixLen: 10}
+	edb, err := d.Open(filepath.Join(s.dir, "db"))
+	c.Assert(err, IsNil)
+	return edb
+}
+
+func (s *testSuite) TestGetSeek(c *C) {
+	defer testleak.AfterTest(c)()
+	keyFile := s.writeKeyFile(c, "1 "+testKey1)
+	for _, driver := range []engine.Driver{goleveldb.Driver{}, boltdb.Driver{}} {
+		for _, mode := range []KeyMode{KeyPlain, KeyPrefix, KeyOrderPreserving} {
+			edb := s.openDB(c, driver, keyFile, mode)
+			s.testGetSeek(c, edb)
+			edb.Close()
+			os.RemoveAll(filepath.Join(s.dir, "db"))
+		}
+	}
+}
+
+func (s *testSuite) testGetSeek(c *C, edb engine.DB) {
+	b := edb.NewBatch()
+	for i := 1; i <= 5; i++ {
+		b.Put([]byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("value%d", i)))
+	}
+	b.Delete([]byte("key3"))
+	c.Assert(edb.Commit(b), IsNil)
+
+	v, err := edb.Get([]byte("key2"))
+	c.Assert(err, IsNil)
+	c.Assert(v, BytesEquals, []byte("value2"))
+	_, err = edb.Get([]byte("key3"))
+	c.Assert(terror.ErrorEqual(err, engine.ErrNotFound), IsTrue)
+
+	k, v, err := edb.Seek([]byte("key3"))
+	c.Assert(err, IsNil)
+	c.Assert(k, BytesEquals, []byte("key4"))
+	c.Assert(v, BytesEquals, []byte("value4"))
+	k, _, err = edb.Seek(nil)
+	c.Assert(err, IsNil)
+	c.Assert(k, BytesEquals, []byte("key1"))
+	_, _, err = edb.Seek([]byte("key6"))
+	c.Assert(terror.ErrorEqual(err, engine.ErrNotFound), IsTrue)
+
+	k, v, err = edb.SeekReverse([]byte("key4"))
+	c.Assert(err, IsNil)
+	c.Assert(k, BytesEquals, []byte("key2"))
+	c.Assert(v, BytesEquals, []byte("value2"))
+	k, _, err = edb.SeekReverse(nil)
+	c.Assert(err, IsNil)
+	c.Assert(k, BytesEquals, []byte("key5"))
+	_, _, err = edb.SeekReverse([]byte("key1"))
+	c.Assert(terror.ErrorEqual(err, engine.ErrNotFound), IsTrue)
+
+	// The wrapped DB doesn't have the plain values.
+	inner := edb.(*db).inner
+	stored, sealed, err := inner.Seek(dataStart)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Contains(sealed, []byte("value")), IsFalse)
+	if edb.(*db).mapping != nil {
+		c.Assert(bytes.Contains(stored, []byte("key")), IsFalse)
+	}
+}
+
+func (s *testSuite) TestWrongKey(c *C) {
+	defer testleak.AfterTest(c)()
+	edb := s.openDB(c, goleveldb.Driver{}, s.writeKeyFile(c, "1 "+testKey1), KeyPlain)
+	b := edb.NewBatch()
+	b.Put([]byte("a"), []byte("1"))
+	c.Assert(edb.Commit(b), IsNil)
+	edb.Close()
+
+	edb = s.openDB(c, goleveldb.Driver{}, s.writeKeyFile(c, "1 "+testKey2), KeyPlain)
+	_, err := edb.Get([]byte("a"))
+	c.Assert(terror.ErrorEqual(err, ErrCorrupted), IsTrue)
+	edb.Close()
+
+	edb = s.openDB(c, goleveldb.Driver{}, s.writeKeyFile(c, "2 "+testKey1), KeyPlain)
+	_, err = edb.Get([]byte("a"))
+	c.Assert(err, ErrorMatches, ".*master key 1 is not in the key file")
+	edb.Close()
+}
+
+func (s *testSuite) TestRotate(c *C) {
+	defer testleak.AfterTest(c)()
+	edb := s.openDB(c, goleveldb.Driver{}, s.writeKeyFile(c, "1 "+testKey1), KeyOrderPreserving)
+	count := rotateBatchSize*2 + 10
+	b := edb.NewBatch()
+	for i := 0; i < count; i++ {
+		b.Put([]byte(fmt.Sprintf("key%05d", i)), []byte(fmt.Sprintf("value%d", i)))
+	}
+	c.Assert(edb.Commit(b), IsNil)
+	edb.Close()
+
+	edb = s.openDB(c, goleveldb.Driver{}, s.writeKeyFile(c, "1 "+testKey1+"\n2 "+testKey2), KeyOrderPreserving)
+	defer edb.Close()
+	<-edb.(*db).rotated
+
+	// All the values are encrypted with the new key and can still be read.
+	inner := edb.(*db).inner
+	start := dataStart
+	for i := 0; i < count; i++ {
+		stored, sealed, err := inner.Seek(start)
+		c.Assert(err, IsNil)
+		id, err := sealedKeyID(sealed)
+		c.Assert(err, IsNil)
+		c.Assert(id, Equals, uint32(2))
+		start = append(append([]byte(nil), stored...), 0)
+	}
+	_, _, err := inner.Seek(start)
+	c.Assert(terror.ErrorEqual(err, engine.ErrNotFound), IsTrue)
+	v, err := edb.Get([]byte("key00300"))
+	c.Assert(err, IsNil)
+	c.Assert(v, BytesEquals, []byte("value300"))
+}
+
+func (s *testSuite) TestMeta(c *C) {
+	defer testleak.AfterTest(c)()
+	keyFile := s.writeKeyFile(c, "1 "+testKey1)
+	path := filepath.Join(s.dir, "db")
+	edb := s.openDB(c, goleveldb.Driver{}, keyFile, KeyPrefix)
+	b := edb.NewBatch()
+	b.Put([]byte("a"), []byte("1"))
+	c.Assert(edb.Commit(b), IsNil)
+	edb.Close()
+
+	// The DB can't be opened with another key mode or prefix length.
+	for _, d := range []Driver{
+		{Driver: goleveldb.Driver{}, KeyFile: keyFile, KeyMode: KeyPlain},
+		{Driver: goleveldb.Driver{}, KeyFile: keyFile, KeyMode: KeyOrderPreserving},
+		{Driver: goleveldb.Driver{}, KeyFile: keyFile, KeyMode: KeyPrefix, PrefixLen: 5},
+	} {
+		_, err := d.Open(path)
+		c.Assert(err, ErrorMatches, ".*the DB is created with .*, it can't be opened with .*")
+	}
+	edb = s.openDB(c, goleveldb.Driver{}, keyFile, KeyPrefix)
+	k, _, err := edb.Seek(nil)
+	c.Assert(err, IsNil)
+	c.Assert(k, BytesEquals, []byte("a"))
+
+	// The DB without the metadata is accepted if it can be read with the key mode.
+	inner := edb.(*db).inner
+	b = inner.NewBatch()
+	b.Delete(metaKey)
+	c.Assert(inner.Commit(b), IsNil)
+	edb.Close()
+	_, err = Driver{Driver: goleveldb.Driver{}, KeyFile: keyFile, KeyMode: KeyPlain}.Open(path)
+	c.Assert(err, ErrorMatches, ".*the DB is not encrypted or is created with another key mode.*")
+	edb = s.openDB(c, goleveldb.Driver{}, keyFile, KeyPrefix)
+	_, err = edb.(*db).inner.Get(metaKey)
+	c.Assert(err, IsNil)
+	edb.Close()
+	os.RemoveAll(path)
+
+	// The encryption can't be enabled on a DB which is not encrypted.
+	plain, err := goleveldb.Driver{}.Open(path)
+	c.Assert(err, IsNil)
+	b = plain.NewBatch()
+	b.Put([]byte("a"), []byte("1"))
+	c.Assert(plain.Commit(b), IsNil)
+	plain.Close()
+	_, err = Driver{Driver: goleveldb.Driver{}, KeyFile: keyFile, KeyMode: KeyPlain}.Open(path)
+	c.Assert(err, ErrorMatches, ".*the DB is not encrypted or is created with another key mode.*")
+}
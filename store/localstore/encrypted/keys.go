@@ -0,0 +1,236 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encrypted
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+const (
+	// valueVersion is the first byte of the encrypted values, it's followed by the ID of the master key and the nonce.
+	valueVersion = 1
+	keyIDLen     = 4
+	headerLen    = 1 + keyIDLen
+)
+
+// masterKeys are the master keys in the key file, the one with the largest ID encrypts the new values.
+type masterKeys struct {
+	aeads   map[uint32]cipher.AEAD
+	raw     map[uint32][]byte
+	current uint32
+	first   uint32
+}
+
+// loadMasterKeys loads the key file of path. Every line of the file is a key ID, which is a positive integer,
+// and a hex encoded AES-128, AES-192 or AES-256 key separated by spaces. Blank lines and lines starting
+// with '#' are ignored.
+func loadMasterKeys(path string) (*masterKeys, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer f.Close()
+
+	keys := &masterKeys{
+		aeads: make(map[uint32]cipher.AEAD),
+		raw:   make(map[uint32][]byte),
+	}
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, errors.Errorf("key file %s line %d: expect a key ID and a hex encoded key", path, lineNo)
+		}
+		id, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil || id == 0 {
+			return nil, errors.Errorf("key file %s line %d: invalid key ID %q", path, lineNo, fields[0])
+		}
+		key, err := hex.DecodeString(fields[1])
+		if err != nil {
+			return nil, errors.Errorf("key file %s line %d: invalid hex key", path, lineNo)
+		}
+		if err = keys.add(uint32(id), key); err != nil {
+			return nil, errors.Errorf("key file %s line %d: %v", path, lineNo, err)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(keys.aeads) == 0 {
+		return nil, errors.Errorf("key file %s has no key", path)
+	}
+	return keys, nil
+}
+
+func (m *masterKeys) add(id uint32, key []byte) error {
+	if _, ok := m.aeads[id]; ok {
+		return errors.Errorf("duplicate key ID %d", id)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	m.aeads[id] = aead
+	m.raw[id] = key
+	if id > m.current {
+		m.current = id
+	}
+	if m.first == 0 || id < m.first {
+		m.first = id
+	}
+	return nil
+}
+
+// seal encrypts value with the current master key, the plain key is authenticated along with it, so the value
+// can't be moved to another key.
+func (m *masterKeys) seal(key, value []byte) ([]byte, error) {
+	aead := m.aeads[m.current]
+	nonceLen := aead.NonceSize()
+	out := make([]byte, headerLen+nonceLen, headerLen+nonceLen+len(value)+aead.Overhead())
+	out[0] = valueVersion
+	binary.BigEndian.PutUint32(out[1:headerLen], m.current)
+	nonce := out[headerLen:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return aead.Seal(out, nonce, value, key), nil
+}
+
+// open decrypts the value sealed with key.
+func (m *masterKeys) open(key, sealed []byte) ([]byte, error) {
+	id, err := sealedKeyID(sealed)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	aead, ok := m.aeads[id]
+	if !ok {
+		return nil, errors.Errorf("encrypted engine: master key %d is not in the key file", id)
+	}
+	nonceLen := aead.NonceSize()
+	if len(sealed) < headerLen+nonceLen {
+		return nil, errors.Trace(ErrCorrupted)
+	}
+	value, err := aead.Open(nil, sealed[headerLen:headerLen+nonceLen], sealed[headerLen+nonceLen:], key)
+	if err != nil {
+		return nil, errors.Trace(ErrCorrupted)
+	}
+	return value, nil
+}
+
+// sealedKeyID returns the ID of the master key which encrypts the sealed value.
+func sealedKeyID(sealed []byte) (uint32, error) {
+	if len(sealed) < headerLen || sealed[0] != valueVersion {
+		return 0, errors.Trace(ErrCorrupted)
+	}
+	return binary.BigEndian.Uint32(sealed[1:headerLen]), nil
+}
+
+// mappingTables is the number of the byte mapping tables, the byte at position i of a key is mapped by the
+// table i % mappingTables.
+const mappingTables = 16
+
+// keyMapping maps the keys to the stored keys without changing their byte order. Every byte is mapped to two
+// bytes with a strictly increasing table, so the stored keys compare in the same way as the keys and Seek
+// works on them. It hides the keys but not their order and the equality of the bytes at the same position.
+type keyMapping struct {
+	tables [mappingTables][256]uint16
+	// prefixLen is the number of the mapped bytes at the beginning of the keys, the rest are stored as they
+	// are. The whole keys are mapped if it's negative.
+	prefixLen int
+}
+
+func newKeyMapping(secret []byte, prefixLen int) (*keyMapping, error) {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("tidb local engine key mapping"))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	stream := make([]byte, mappingTables*256)
+	cipher.NewCTR(block, make([]byte, block.BlockSize())).XORKeyStream(stream, stream)
+
+	m := &keyMapping{prefixLen: prefixLen}
+	for t := range m.tables {
+		var code uint16
+		for b := range m.tables[t] {
+			// The gaps are in [1, 255], so the largest code 256 * 255 fits in uint16.
+			code += uint16(stream[t*256+b]%255) + 1
+			m.tables[t][b] = code
+		}
+	}
+	return m, nil
+}
+
+func (m *keyMapping) mappedLen(keyLen int) int {
+	if m.prefixLen >= 0 && keyLen > m.prefixLen {
+		return m.prefixLen
+	}
+	return keyLen
+}
+
+func (m *keyMapping) encode(key []byte) []byte {
+	if len(key) == 0 {
+		return key
+	}
+	n := m.mappedLen(len(key))
+	out := make([]byte, 0, len(key)+n)
+	for i := 0; i < n; i++ {
+		code := m.tables[i%mappingTables][key[i]]
+		out = append(out, byte(code>>8), byte(code))
+	}
+	return append(out, key[n:]...)
+}
+
+func (m *keyMapping) decode(stored []byte) ([]byte, error) {
+	n := len(stored) / 2
+	var rest []byte
+	if m.prefixLen >= 0 && len(stored) > 2*m.prefixLen {
+		n = m.prefixLen
+		rest = stored[2*n:]
+	} else if len(stored)%2 != 0 {
+		return nil, errors.Trace(ErrCorrupted)
+	}
+	key := make([]byte, n, n+len(rest))
+	for i := 0; i < n; i++ {
+		table := &m.tables[i%mappingTables]
+		code := binary.BigEndian.Uint16(stored[2*i:])
+		b := sort.Search(len(table), func(j int) bool { return table[j] >= code })
+		if b == len(table) || table[b] != code {
+			return nil, errors.Trace(ErrCorrupted)
+		}
+		key[i] = byte(b)
+	}
+	return append(key, rest...), nil
+}
@@ -354,6 +354,11 @@ func (s *dbStore) CurrentVersion() (kv.Version, error) {
 	return globalVersionProvider.CurrentVersion()
 }
 
+// GCSafePoint implements the gcutil.SafePointer interface.
+func (s *dbStore) GCSafePoint() uint64 {
+	return s.compactor.safePoint()
+}
+
 // Begin transaction
 func (s *dbStore) Begin() (kv.Transaction, error) {
 	s.mu.RLock()
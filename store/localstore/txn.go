@@ -131,6 +131,7 @@ type schemaLeaseChecker interface {
 }
 
 func (txn *dbTxn) close() error {
+	txn.us.Release()
 	txn.lockedKeys = nil
 	txn.valid = false
 	return nil
@@ -0,0 +1,141 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	pb "github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/store/tikv/tikvrpc"
+	goctx "golang.org/x/net/context"
+)
+
+// onePCRegion checks if the transaction can be committed by one-phase commit, which prewrites and commits
+// the mutations in one request. It returns the region of the mutations if they are in one region and small
+// enough to be sent in one request.
+func (c *twoPhaseCommitter) onePCRegion(bo *Backoffer) (RegionVerID, bool) {
+	if !c.store.supportExtReq() {
+		return RegionVerID{}, false
+	}
+	var size int
+	for _, k := range c.keys {
+		size += c.keyValueSize(k)
+	}
+	if size > txnCommitBatchSize {
+		return RegionVerID{}, false
+	}
+	// 2PC reports the error if the keys can't be grouped.
+	groups, firstRegion, err := c.store.regionCache.GroupKeysByRegion(bo, c.keys)
+	if err != nil || len(groups) != 1 {
+		return RegionVerID{}, false
+	}
+	return firstRegion, true
+}
+
+// executeOnePC commits the transaction by one-phase commit. It returns false without an error if the
+// transaction should fall back to 2PC, which happens when the region of the mutations changes or the keys
+// are read at the commitTS by others before the request arrives. It returns kv.ErrResultUndetermined if
+// a request fails to be sent, then the transaction may be committed and can't fall back to 2PC.
+func (c *twoPhaseCommitter) executeOnePC(ctx goctx.Context, region RegionVerID) (bool, error) {
+	err := c.prepareCommitTS(ctx)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+
+	mutations := make([]*pb.Mutation, len(c.keys))
+	for i, k := range c.keys {
+		mutations[i] = c.mutations[string(k)]
+	}
+	skipCheck := false
+	if skip, ok := c.txn.us.GetOption(kv.SkipCheckForWrite).(bool); ok && skip {
+		skipCheck = true
+	}
+	req := &tikvrpc.Request{
+		Type: tikvrpc.CmdOnePC,
+		OnePCReq: &tikvrpc.OnePCRequest{
+			Mutations:           mutations,
+			PrimaryLock:         c.primary(),
+			StartVersion:        c.startTS,
+			CommitVersion:       c.commitTS,
+			SkipConstraintCheck: skipCheck,
+		},
+	}
+
+	// Like committing the primary key, it's undetermined whether the transaction is committed if the response
+	// is lost, so the request is retried until the store responds.
+	bo := NewBackoffer(commitPrimaryMaxBackoff, ctx)
+	var rpcError error
+	for {
+		sender := NewRegionRequestSender(bo, c.store.regionCache, c.store.client)
+		resp, err := sender.SendExtReq(req, region, readTimeoutShort)
+		if sender.rpcError != nil {
+			rpcError = sender.rpcError
+		}
+		if err != nil {
+			if rpcError != nil {
+				log.Warnf("1PC result undetermined: %v, rpc error: %v, tid: %d", err, rpcError, c.startTS)
+				return false, errors.Trace(kv.ErrResultUndetermined)
+			}
+			return false, errors.Trace(err)
+		}
+		if regionErr := resp.RegionError; regionErr != nil {
+			if rpcError != nil {
+				log.Warnf("1PC result undetermined: %s, rpc error: %v, tid: %d", regionErr, rpcError, c.startTS)
+				return false, errors.Trace(kv.ErrResultUndetermined)
+			}
+			log.Debugf("1PC meets region error: %s, fall back to 2PC, tid: %d", regionErr, c.startTS)
+			err = bo.Backoff(boRegionMiss, errors.New(regionErr.String()))
+			return false, errors.Trace(err)
+		}
+		onePCResp := resp.OnePCResp
+		if onePCResp == nil {
+			return false, errors.Trace(errBodyMissing)
+		}
+		if onePCResp.CommitTSExpired {
+			if rpcError != nil {
+				log.Warnf("1PC result undetermined: commitTS expired, rpc error: %v, tid: %d", rpcError, c.startTS)
+				return false, errors.Trace(kv.ErrResultUndetermined)
+			}
+			log.Debugf("1PC commitTS %d expired, fall back to 2PC, tid: %d", c.commitTS, c.startTS)
+			return false, nil
+		}
+		keyErrs := onePCResp.Errors
+		if len(keyErrs) == 0 {
+			c.mu.Lock()
+			c.mu.committed = true
+			c.mu.Unlock()
+			return true, nil
+		}
+		var locks []*Lock
+		for _, keyErr := range keyErrs {
+			lock, err1 := extractLockFromKeyErr(keyErr)
+			if err1 != nil {
+				return false, errors.Trace(err1)
+			}
+			log.Debugf("1PC encounters lock: %v", lock)
+			locks = append(locks, lock)
+		}
+		ok, err := c.store.lockResolver.ResolveLocks(bo, locks)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		if !ok {
+			err = bo.Backoff(boTxnLock, errors.Errorf("1PC lockedKeys: %d", len(locks)))
+			if err != nil {
+				return false, errors.Trace(err)
+			}
+		}
+	}
+}
@@ -17,7 +17,6 @@ import (
 	"bytes"
 	"math"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/coreos/etcd/pkg/monotime"
@@ -65,7 +64,9 @@ type twoPhaseCommitter struct {
 	mutations map[string]*pb.Mutation
 	lockTTL   uint64
 	commitTS  uint64
-	mu        struct {
+	// primaryKey is the primary key of a large transaction, whose mutations are prewritten in several parts.
+	primaryKey []byte
+	mu         struct {
 		sync.RWMutex
 		writtenKeys [][]byte
 		committed   bool
@@ -113,7 +114,9 @@ func newTwoPhaseCommitter(txn *tikvTxn) (*twoPhaseCommitter, error) {
 	if len(keys) == 0 {
 		return nil, nil
 	}
-	for _, lockKey := range txn.lockKeys {
+	// The keys locked pessimistically must be prewritten to be released when the transaction commits.
+	lockKeys := append(append([][]byte(nil), txn.lockKeys...), txn.lockedKeys...)
+	for _, lockKey := range lockKeys {
 		if _, ok := mutations[string(lockKey)]; !ok {
 			mutations[string(lockKey)] = &pb.Mutation{
 				Op:  pb.Op_Lock,
@@ -124,8 +127,17 @@ func newTwoPhaseCommitter(txn *tikvTxn) (*twoPhaseCommitter, error) {
 			size += len(lockKey)
 		}
 	}
-	entrylimit := atomic.LoadUint64(&kv.TxnEntryCountLimit)
-	if len(keys) > int(entrylimit) || size > kv.TxnTotalSizeLimit {
+	// The primary key of a pessimistic transaction is the primary lock of its pessimistic locks.
+	if txn.primaryLock != nil {
+		for i, k := range keys {
+			if bytes.Equal(k, txn.primaryLock) {
+				keys[0], keys[i] = keys[i], keys[0]
+				break
+			}
+		}
+	}
+	entrylimit, sizeLimit := kv.TxnSizeLimits(txn.us.GetOption(kv.OnSpill) != nil)
+	if len(keys) > int(entrylimit) || uint64(size) > sizeLimit {
 		return nil, kv.ErrTxnTooLarge
 	}
 	const logEntryCount = 10000
@@ -150,6 +162,9 @@ func newTwoPhaseCommitter(txn *tikvTxn) (*twoPhaseCommitter, error) {
 }
 
 func (c *twoPhaseCommitter) primary() []byte {
+	if c.primaryKey != nil {
+		return c.primaryKey
+	}
 	return c.keys[0]
 }
 
@@ -474,6 +489,10 @@ func (c *twoPhaseCommitter) execute() error {
 		writtenKeys := c.mu.writtenKeys
 		committed := c.mu.committed
 		c.mu.RUnlock()
+		// The keys of a pessimistic transaction may be locked even if they are not prewritten.
+		if c.txn.pessimistic {
+			writtenKeys = c.keys
+		}
 		if !committed {
 			go func() {
 				err := c.cleanupKeys(NewBackoffer(cleanupMaxBackoff, goctx.Background()), writtenKeys)
@@ -488,6 +507,21 @@ func (c *twoPhaseCommitter) execute() error {
 
 	ctx := goctx.Background()
 	binlogChan := c.prewriteBinlog()
+	if region, ok := c.onePCRegion(NewBackoffer(prewriteMaxBackoff, ctx)); ok {
+		// The mutations are committed with the request, so the binlog must be prewritten before it's sent.
+		if binlogChan != nil {
+			binlogErr := <-binlogChan
+			if binlogErr != nil {
+				return errors.Trace(binlogErr)
+			}
+			binlogChan = nil
+		}
+		committed, err := c.executeOnePC(ctx, region)
+		if err != nil || committed {
+			return errors.Trace(err)
+		}
+	}
+
 	err := c.prewriteKeys(NewBackoffer(prewriteMaxBackoff, ctx), c.keys)
 	if binlogChan != nil {
 		binlogErr := <-binlogChan
@@ -500,6 +534,23 @@ func (c *twoPhaseCommitter) execute() error {
 		return errors.Trace(err)
 	}
 
+	if err = c.prepareCommitTS(ctx); err != nil {
+		return errors.Trace(err)
+	}
+
+	err = c.commitKeys(NewBackoffer(commitMaxBackoff, ctx), c.keys)
+	if err != nil {
+		if !c.mu.committed {
+			log.Debugf("2PC failed on commit: %v, tid: %d", err, c.startTS)
+			return errors.Trace(err)
+		}
+		log.Debugf("2PC succeed with error: %v, tid: %d", err, c.startTS)
+	}
+	return nil
+}
+
+// prepareCommitTS gets the commitTS of the transaction, and checks if the transaction can commit at it.
+func (c *twoPhaseCommitter) prepareCommitTS(ctx goctx.Context) error {
 	commitTS, err := c.store.getTimestampWithRetry(NewBackoffer(tsoMaxBackoff, ctx))
 	if err != nil {
 		log.Warnf("2PC get commitTS failed: %v, tid: %d", err, c.startTS)
@@ -523,15 +574,6 @@ func (c *twoPhaseCommitter) execute() error {
 		err = errors.Errorf("txn takes too much time, start: %d, commit: %d", c.startTS, c.commitTS)
 		return errors.Annotate(err, txnRetryableMark)
 	}
-
-	err = c.commitKeys(NewBackoffer(commitMaxBackoff, ctx), c.keys)
-	if err != nil {
-		if !c.mu.committed {
-			log.Debugf("2PC failed on commit: %v, tid: %d", err, c.startTS)
-			return errors.Trace(err)
-		}
-		log.Debugf("2PC succeed with error: %v, tid: %d", err, c.startTS)
-	}
 	return nil
 }
 
@@ -559,7 +601,7 @@ func (c *twoPhaseCommitter) prewriteBinlog() chan error {
 		bin := c.txn.us.GetOption(kv.BinlogData).(*binlog.Binlog)
 		bin.StartTs = int64(c.startTS)
 		if bin.Tp == binlog.BinlogType_Prewrite {
-			bin.PrewriteKey = c.keys[0]
+			bin.PrewriteKey = c.primary()
 		}
 		err := binloginfo.WriteBinlog(bin, c.store.clusterID)
 		ch <- errors.Trace(err)
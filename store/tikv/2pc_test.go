@@ -17,13 +17,17 @@ import (
 	"math"
 	"math/rand"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/juju/errors"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/coprocessor"
 	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/store/tikv/mock-tikv"
+	"github.com/pingcap/tidb/store/tikv/tikvrpc"
+	"github.com/pingcap/tidb/terror"
 	goctx "golang.org/x/net/context"
 )
 
@@ -303,3 +307,115 @@ func (s *testCommitterSuite) TestIllegalTso(c *C) {
 	err := txn.Commit()
 	c.Assert(err, NotNil)
 }
+
+// onePCClient wraps mock-tikv's RPCClient, it counts the requests to commit and runs the hooks around 1PC.
+type onePCClient struct {
+	*mocktikv.RPCClient
+	onePCCnt    int64
+	prewriteCnt int64
+	beforeOnePC func(req *tikvrpc.OnePCRequest)
+	// afterOnePC runs after 1PC is done by the store, the response is dropped if it returns an error.
+	afterOnePC func(req *tikvrpc.OnePCRequest) error
+}
+
+func (c *onePCClient) SendKVReq(ctx goctx.Context, addr string, req *kvrpcpb.Request, timeout time.Duration) (*kvrpcpb.Response, error) {
+	if req.GetType() == kvrpcpb.MessageType_CmdPrewrite {
+		atomic.AddInt64(&c.prewriteCnt, 1)
+	}
+	return c.RPCClient.SendKVReq(ctx, addr, req, timeout)
+}
+
+func (c *onePCClient) SendExtReq(ctx goctx.Context, addr string, req *tikvrpc.Request, timeout time.Duration) (*tikvrpc.Response, error) {
+	if req.Type == tikvrpc.CmdOnePC {
+		atomic.AddInt64(&c.onePCCnt, 1)
+		if c.beforeOnePC != nil {
+			c.beforeOnePC(req.OnePCReq)
+			c.beforeOnePC = nil
+		}
+		if c.afterOnePC != nil {
+			after := c.afterOnePC
+			c.afterOnePC = nil
+			resp, err := c.RPCClient.SendExtReq(ctx, addr, req, timeout)
+			if err == nil {
+				err = after(req.OnePCReq)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+	}
+	return c.RPCClient.SendExtReq(ctx, addr, req, timeout)
+}
+
+func (s *testCommitterSuite) TestOnePC(c *C) {
+	client := &onePCClient{RPCClient: s.store.client.(*mocktikv.RPCClient)}
+	s.store.client = client
+
+	// "a1" and "a2" are in one region.
+	s.mustCommit(c, map[string]string{"a1": "a1", "a2": "a2"})
+	c.Assert(client.onePCCnt, Equals, int64(1))
+	c.Assert(client.prewriteCnt, Equals, int64(0))
+
+	// The keys in different regions are committed by 2PC.
+	s.mustCommit(c, map[string]string{"a1": "a1-1", "b1": "b1-1"})
+	c.Assert(client.onePCCnt, Equals, int64(1))
+	c.Assert(client.prewriteCnt, Equals, int64(2))
+
+	// The keys read at commitTS fall back to 2PC.
+	client.beforeOnePC = func(req *tikvrpc.OnePCRequest) {
+		_, err := client.MvccStore.Get([]byte("a1"), req.CommitVersion)
+		c.Assert(err, IsNil)
+	}
+	s.mustCommit(c, map[string]string{"a1": "a1-2", "a2": "a2-2"})
+	c.Assert(client.onePCCnt, Equals, int64(2))
+	c.Assert(client.prewriteCnt, Equals, int64(3))
+
+	// The region split falls back to 2PC.
+	client.beforeOnePC = func(req *tikvrpc.OnePCRequest) {
+		ids := s.cluster.AllocIDs(2)
+		s.cluster.Split(s.mustGetRegionID(c, []byte("a1")), ids[0], []byte("a15"), []uint64{ids[1]}, ids[1])
+	}
+	s.mustCommit(c, map[string]string{"a1": "a1-3", "a2": "a2-3"})
+	c.Assert(client.onePCCnt, Equals, int64(3))
+	c.Assert(client.prewriteCnt, Equals, int64(5))
+}
+
+func (s *testCommitterSuite) TestOnePCUndetermined(c *C) {
+	client := &onePCClient{RPCClient: s.store.client.(*mocktikv.RPCClient)}
+	s.store.client = client
+
+	// The response is lost and the region splits before the retry, the transaction is committed, but it
+	// can't know that.
+	client.afterOnePC = func(req *tikvrpc.OnePCRequest) error {
+		ids := s.cluster.AllocIDs(2)
+		s.cluster.Split(s.mustGetRegionID(c, []byte("a1")), ids[0], []byte("a15"), []uint64{ids[1]}, ids[1])
+		return errors.New("response lost")
+	}
+	txn := s.begin(c)
+	c.Assert(txn.Set([]byte("a1"), []byte("a1-1")), IsNil)
+	c.Assert(txn.Set([]byte("a2"), []byte("a2-1")), IsNil)
+	err := txn.Commit()
+	c.Assert(terror.ErrorEqual(err, kv.ErrResultUndetermined), IsTrue, Commentf("%v", err))
+	c.Assert(client.onePCCnt, Equals, int64(1))
+	c.Assert(client.prewriteCnt, Equals, int64(0))
+	s.checkValues(c, map[string]string{"a1": "a1-1", "a2": "a2-1"})
+}
+
+func (s *testCommitterSuite) TestOnePCConflict(c *C) {
+	txn1, txn2 := s.begin(c), s.begin(c)
+	err := txn1.Set([]byte("a1"), []byte("a1-1"))
+	c.Assert(err, IsNil)
+	err = txn2.Set([]byte("a1"), []byte("a1-2"))
+	c.Assert(err, IsNil)
+	err = txn2.Set([]byte("a2"), []byte("a2-2"))
+	c.Assert(err, IsNil)
+	err = txn1.Commit()
+	c.Assert(err, IsNil)
+	err = txn2.Commit()
+	c.Assert(err, NotNil)
+	c.Assert(strings.Contains(err.Error(), txnRetryableMark), IsTrue)
+	s.checkValues(c, map[string]string{"a1": "a1-1"})
+	_, err = s.begin(c).Get([]byte("a2"))
+	c.Assert(terror.ErrorEqual(err, kv.ErrNotExist), IsTrue)
+}
@@ -116,19 +116,22 @@ func (t backoffType) String() string {
 
 // Maximum total sleep time(in ms) for kv/cop commands.
 const (
-	copBuildTaskMaxBackoff  = 5000
-	tsoMaxBackoff           = 5000
-	scannerNextMaxBackoff   = 15000
-	batchGetMaxBackoff      = 15000
-	copNextMaxBackoff       = 15000
-	getMaxBackoff           = 15000
-	prewriteMaxBackoff      = 15000
-	commitMaxBackoff        = 15000
-	commitPrimaryMaxBackoff = -1
-	cleanupMaxBackoff       = 15000
-	gcMaxBackoff            = 100000
-	gcResolveLockMaxBackoff = 100000
-	rawkvMaxBackoff         = 15000
+	copBuildTaskMaxBackoff        = 5000
+	tsoMaxBackoff                 = 5000
+	scannerNextMaxBackoff         = 15000
+	batchGetMaxBackoff            = 15000
+	copNextMaxBackoff             = 15000
+	getMaxBackoff                 = 15000
+	prewriteMaxBackoff            = 15000
+	commitMaxBackoff              = 15000
+	commitPrimaryMaxBackoff       = -1
+	cleanupMaxBackoff             = 15000
+	pessimisticLockMaxBackoff     = 15000
+	pessimisticRollbackMaxBackoff = 15000
+	txnHeartBeatMaxBackoff        = 15000
+	gcMaxBackoff                  = 100000
+	gcResolveLockMaxBackoff       = 100000
+	rawkvMaxBackoff               = 15000
 )
 
 // Backoffer is a utility for retrying queries.
@@ -24,6 +24,7 @@ import (
 	"github.com/pingcap/kvproto/pkg/kvrpcpb"
 	"github.com/pingcap/kvproto/pkg/msgpb"
 	"github.com/pingcap/kvproto/pkg/util"
+	"github.com/pingcap/tidb/store/tikv/tikvrpc"
 	goctx "golang.org/x/net/context"
 )
 
@@ -38,6 +39,13 @@ type Client interface {
 	SendCopReq(ctx goctx.Context, addr string, req *coprocessor.Request, timeout time.Duration) (*coprocessor.Response, error)
 }
 
+// extClient is a Client whose stores serve the requests defined in tikvrpc, which are not in kvproto
+// yet, so only mock-tikv serves them now.
+type extClient interface {
+	// SendExtReq sends a request defined in tikvrpc.
+	SendExtReq(ctx goctx.Context, addr string, req *tikvrpc.Request, timeout time.Duration) (*tikvrpc.Response, error)
+}
+
 const (
 	maxConnection     = 200
 	dialTimeout       = 5 * time.Second
@@ -25,6 +25,8 @@ var (
 	errInvalidResponse = errors.New("invalid response")
 	// errBodyMissing response body is missing error
 	errBodyMissing = errors.New("response body is missing")
+	// errExtReqNotSupported is returned when the store doesn't serve the requests defined in tikvrpc.
+	errExtReqNotSupported = errors.New("the request is not supported by the store")
 )
 
 // TiDB decides whether to retry transaction by checking if error message contains
@@ -27,6 +27,7 @@ import (
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/store/tikv/oracle"
+	"github.com/pingcap/tidb/util/gcutil"
 	"github.com/pingcap/tidb/util/sqlexec"
 	goctx "golang.org/x/net/context"
 )
@@ -72,7 +73,7 @@ func (w *GCWorker) Close() {
 }
 
 const (
-	gcTimeFormat = "20060102-15:04:05 -0700 MST"
+	gcTimeFormat = gcutil.TimeFormat
 
 	gcWorkerTickInterval = time.Minute
 	gcWorkerLease        = time.Minute * 2
@@ -87,7 +88,7 @@ const (
 
 	gcLifeTimeKey     = "tikv_gc_life_time"
 	gcDefaultLifeTime = time.Minute * 10
-	gcSafePointKey    = "tikv_gc_safe_point"
+	gcSafePointKey    = gcutil.SafePointKey
 )
 
 var gcVariableComments = map[string]string{
@@ -29,6 +29,7 @@ import (
 	"github.com/pingcap/tidb/store/tikv/mock-tikv"
 	"github.com/pingcap/tidb/store/tikv/oracle"
 	"github.com/pingcap/tidb/store/tikv/oracle/oracles"
+	"github.com/pingcap/tidb/store/tikv/tikvrpc"
 	goctx "golang.org/x/net/context"
 )
 
@@ -225,6 +226,17 @@ func (s *tikvStore) SendKVReq(bo *Backoffer, req *pb.Request, regionID RegionVer
 	return sender.SendKVReq(req, regionID, timeout)
 }
 
+func (s *tikvStore) SendExtReq(bo *Backoffer, req *tikvrpc.Request, regionID RegionVerID, timeout time.Duration) (*tikvrpc.Response, error) {
+	sender := NewRegionRequestSender(bo, s.regionCache, s.client)
+	return sender.SendExtReq(req, regionID, timeout)
+}
+
+// supportExtReq checks if the store serves the requests defined in tikvrpc.
+func (s *tikvStore) supportExtReq() bool {
+	_, ok := s.client.(extClient)
+	return ok
+}
+
 // ParseEtcdAddr parses path to etcd address list
 func ParseEtcdAddr(path string) (etcdAddrs []string, err error) {
 	etcdAddrs, _, err = parsePath(path)
@@ -0,0 +1,378 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/pkg/monotime"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	pb "github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/store/tikv/tikvrpc"
+	"github.com/pingcap/tipb/go-binlog"
+	goctx "golang.org/x/net/context"
+)
+
+// largeTxnLockTTL is the TTL(in ms) of the locks of a large transaction. The locks are prewritten while the
+// transaction is running, the TTL of the primary lock is extended by the heartbeats until it finishes.
+var largeTxnLockTTL uint64 = 20000
+
+// largeTxnHeartBeatInterval is the interval of the heartbeats of a large transaction.
+var largeTxnHeartBeatInterval = 5 * time.Second
+
+// largeTxnKeyBatch is the number of keys which are read from the write buffer to be committed or cleaned up
+// at a time.
+const largeTxnKeyBatch = 4096
+
+// largeTxn prewrites the mutations of an optimistic transaction in the background when its write buffer spills
+// to disk, so most of the keys are prewritten before the transaction commits.
+type largeTxn struct {
+	txn *tikvTxn
+	// primary is the first key spilled, it's the primary key of the transaction.
+	primary []byte
+	// segments are the mutations spilled, they are prewritten one by one.
+	segments    chan []*pb.Mutation
+	prewriterWg sync.WaitGroup
+	finished    bool
+	// stopHeartBeat is closed when the transaction finishes.
+	stopHeartBeat chan struct{}
+	heartBeatWg   sync.WaitGroup
+	mu            struct {
+		sync.Mutex
+		// err is the first error of the prewrites, the transaction can't commit then.
+		err error
+	}
+}
+
+func newLargeTxn(txn *tikvTxn, primary []byte) *largeTxn {
+	l := &largeTxn{
+		txn:           txn,
+		primary:       primary,
+		segments:      make(chan []*pb.Mutation, 1),
+		stopHeartBeat: make(chan struct{}),
+	}
+	l.prewriterWg.Add(1)
+	go l.runPrewriter()
+	l.heartBeatWg.Add(1)
+	go l.runHeartBeat()
+	return l
+}
+
+// onSpill is the kv.SpillFunc of the transaction, the entries spilled are prewritten in the background if the
+// transaction is optimistic.
+func (txn *tikvTxn) onSpill(it kv.Iterator) error {
+	if txn.pessimistic {
+		return nil
+	}
+	var mutations []*pb.Mutation
+	for ; it.Valid(); it.Next() {
+		m := &pb.Mutation{
+			Op:  pb.Op_Put,
+			Key: append([]byte(nil), it.Key()...),
+		}
+		if len(it.Value()) == 0 {
+			m.Op = pb.Op_Del
+		} else {
+			m.Value = append([]byte(nil), it.Value()...)
+		}
+		mutations = append(mutations, m)
+	}
+	if len(mutations) == 0 {
+		return nil
+	}
+	if txn.large == nil {
+		txn.large = newLargeTxn(txn, mutations[0].Key)
+		log.Infof("[BIG_TXN] write buffer spills, prewrite in background, primary: %q, startTS: %d",
+			txn.large.primary, txn.startTS)
+	}
+	return errors.Trace(txn.large.prewrite(mutations))
+}
+
+// prewrite queues the mutations to be prewritten, it returns the error of the previous prewrites.
+func (l *largeTxn) prewrite(mutations []*pb.Mutation) error {
+	if err := l.getErr(); err != nil {
+		return errors.Trace(err)
+	}
+	l.segments <- mutations
+	return nil
+}
+
+func (l *largeTxn) runPrewriter() {
+	defer l.prewriterWg.Done()
+	for mutations := range l.segments {
+		if l.getErr() != nil {
+			continue
+		}
+		c := l.newCommitter(mutations)
+		err := c.prewriteKeys(NewBackoffer(prewriteMaxBackoff, goctx.Background()), c.keys)
+		if err != nil {
+			log.Warnf("[BIG_TXN] prewrite in background failed: %v, tid: %d", err, l.txn.startTS)
+			l.setErr(err)
+		}
+	}
+}
+
+func (l *largeTxn) getErr() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.mu.err
+}
+
+func (l *largeTxn) setErr(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.mu.err == nil {
+		l.mu.err = err
+	}
+}
+
+// finishPrewrite waits for the mutations queued to be prewritten, it returns the error of the prewrites.
+func (l *largeTxn) finishPrewrite() error {
+	if !l.finished {
+		l.finished = true
+		close(l.segments)
+		l.prewriterWg.Wait()
+	}
+	return errors.Trace(l.getErr())
+}
+
+// newCommitter creates a twoPhaseCommitter for the mutations, whose primary key is the primary key of the
+// transaction.
+func (l *largeTxn) newCommitter(mutations []*pb.Mutation) *twoPhaseCommitter {
+	keys := make([][]byte, 0, len(mutations))
+	mutationMap := make(map[string]*pb.Mutation, len(mutations))
+	for _, m := range mutations {
+		keys = append(keys, m.Key)
+		mutationMap[string(m.Key)] = m
+	}
+	return &twoPhaseCommitter{
+		store:      l.txn.store,
+		txn:        l.txn,
+		startTS:    l.txn.startTS,
+		keys:       keys,
+		mutations:  mutationMap,
+		lockTTL:    l.lockTTL(),
+		primaryKey: l.primary,
+	}
+}
+
+// lockTTL returns the TTL of the locks, it's increased by the time the transaction has run.
+func (l *largeTxn) lockTTL() uint64 {
+	elapsed := time.Duration(monotime.Now()-l.txn.startTime) / time.Millisecond
+	return largeTxnLockTTL + uint64(elapsed)
+}
+
+func (l *largeTxn) runHeartBeat() {
+	defer l.heartBeatWg.Done()
+	ticker := time.NewTicker(largeTxnHeartBeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stopHeartBeat:
+			return
+		case <-ticker.C:
+			bo := NewBackoffer(txnHeartBeatMaxBackoff, goctx.Background())
+			ttl, err := sendTxnHeartBeat(bo, l.txn.store, l.primary, l.txn.startTS, l.lockTTL())
+			if err != nil {
+				log.Warnf("[BIG_TXN] heartbeat failed: %v, tid: %d", err, l.txn.startTS)
+			} else if ttl == 0 {
+				log.Debugf("[BIG_TXN] heartbeat finds no primary lock, tid: %d", l.txn.startTS)
+			}
+		}
+	}
+}
+
+// finish stops the background work of the transaction.
+func (l *largeTxn) finish() {
+	l.finishPrewrite()
+	l.stopHeartBeats()
+}
+
+func (l *largeTxn) stopHeartBeats() {
+	select {
+	case <-l.stopHeartBeat:
+	default:
+		close(l.stopHeartBeat)
+		l.heartBeatWg.Wait()
+	}
+}
+
+// sendTxnHeartBeat extends the TTL of the primary lock of a transaction, it returns the TTL of the lock. It
+// returns 0 if the lock doesn't exist.
+func sendTxnHeartBeat(bo *Backoffer, store *tikvStore, primary []byte, startTS, adviseTTL uint64) (uint64, error) {
+	req := &tikvrpc.Request{
+		Type: tikvrpc.CmdTxnHeartBeat,
+		TxnHeartBeatReq: &tikvrpc.TxnHeartBeatRequest{
+			PrimaryLock:   primary,
+			StartVersion:  startTS,
+			AdviseLockTTL: adviseTTL,
+		},
+	}
+	for {
+		loc, err := store.regionCache.LocateKey(bo, primary)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		resp, err := store.SendExtReq(bo, req, loc.Region, readTimeoutShort)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		if regionErr := resp.RegionError; regionErr != nil {
+			err = bo.Backoff(boRegionMiss, errors.New(regionErr.String()))
+			if err != nil {
+				return 0, errors.Trace(err)
+			}
+			continue
+		}
+		heartBeatResp := resp.TxnHeartBeatResp
+		if heartBeatResp == nil {
+			return 0, errors.Trace(errBodyMissing)
+		}
+		if heartBeatResp.Error != nil {
+			return 0, nil
+		}
+		return heartBeatResp.LockTTL, nil
+	}
+}
+
+// walkKeys calls f with the keys of the transaction in batches, the keys are read from the write buffer.
+func (l *largeTxn) walkKeys(lockKeys [][]byte, f func(keys [][]byte) error) error {
+	it, err := l.txn.us.GetMemBuffer().Seek(nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer it.Close()
+	keys := make([][]byte, 0, largeTxnKeyBatch)
+	for ; it.Valid(); it.Next() {
+		keys = append(keys, append([]byte(nil), it.Key()...))
+		if len(keys) == largeTxnKeyBatch {
+			if err = f(keys); err != nil {
+				return errors.Trace(err)
+			}
+			keys = make([][]byte, 0, largeTxnKeyBatch)
+		}
+	}
+	keys = append(keys, lockKeys...)
+	return errors.Trace(f(keys))
+}
+
+// lockMutations returns the mutations of the keys locked by the transaction which are not written.
+func (l *largeTxn) lockMutations() ([]*pb.Mutation, error) {
+	var mutations []*pb.Mutation
+	buf := l.txn.us.GetMemBuffer()
+	for _, k := range l.txn.lockKeys {
+		_, err := buf.Get(k)
+		if err == nil {
+			continue
+		}
+		if !kv.IsErrNotFound(err) {
+			return nil, errors.Trace(err)
+		}
+		mutations = append(mutations, &pb.Mutation{
+			Op:  pb.Op_Lock,
+			Key: k,
+		})
+	}
+	return mutations, nil
+}
+
+// commitLarge commits a transaction whose write buffer has spilled. The mutations are prewritten in the
+// background except the locked keys, then the keys are read from the buffer to be committed.
+func (txn *tikvTxn) commitLarge() error {
+	l := txn.large
+	committed := false
+	defer func() {
+		l.finish()
+		if !committed {
+			l.cleanup()
+		}
+	}()
+
+	// The rest of the buffer is spilled to be prewritten too.
+	if _, err := txn.us.Spill(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := l.finishPrewrite(); err != nil {
+		return errors.Trace(err)
+	}
+	lockMutations, err := l.lockMutations()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	c := l.newCommitter(lockMutations)
+
+	ctx := goctx.Background()
+	binlogChan := c.prewriteBinlog()
+	err = c.prewriteKeys(NewBackoffer(prewriteMaxBackoff, ctx), c.keys)
+	if binlogChan != nil {
+		if binlogErr := <-binlogChan; binlogErr != nil && err == nil {
+			err = binlogErr
+		}
+	}
+	if err == nil {
+		err = c.prepareCommitTS(ctx)
+	}
+	if err == nil {
+		err = c.commitKeys(NewBackoffer(commitMaxBackoff, ctx), [][]byte{l.primary})
+	}
+	c.mu.RLock()
+	committed = c.mu.committed
+	c.mu.RUnlock()
+	if !committed {
+		log.Debugf("[BIG_TXN] commit failed: %v, tid: %d", err, txn.startTS)
+		c.writeFinishBinlog(binlog.BinlogType_Rollback, 0)
+		return errors.Trace(err)
+	}
+	c.writeFinishBinlog(binlog.BinlogType_Commit, int64(c.commitTS))
+	txn.commitTS = c.commitTS
+
+	// The secondary keys are committed in the background by commitKeys, committing the primary key again
+	// does nothing.
+	err = l.walkKeys(c.keys, func(keys [][]byte) error {
+		return errors.Trace(c.commitKeys(NewBackoffer(commitMaxBackoff, ctx), keys))
+	})
+	if err != nil {
+		log.Debugf("[BIG_TXN] commit succeed with error: %v, tid: %d", err, txn.startTS)
+	}
+	return nil
+}
+
+// rollback rolls back the keys prewritten.
+func (l *largeTxn) rollback() {
+	l.finish()
+	l.cleanup()
+}
+
+// cleanup rolls back all the keys of the transaction, including the keys which may be prewritten.
+func (l *largeTxn) cleanup() {
+	c := l.newCommitter(nil)
+	lockMutations, err := l.lockMutations()
+	if err == nil {
+		var lockKeys [][]byte
+		for _, m := range lockMutations {
+			lockKeys = append(lockKeys, m.Key)
+		}
+		err = l.walkKeys(lockKeys, func(keys [][]byte) error {
+			return errors.Trace(c.cleanupKeys(NewBackoffer(cleanupMaxBackoff, goctx.Background()), keys))
+		})
+	}
+	if err != nil {
+		log.Infof("[BIG_TXN] cleanup err: %v, tid: %d", err, l.txn.startTS)
+	} else {
+		log.Infof("[BIG_TXN] clean up done, tid: %d", l.txn.startTS)
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/store/tikv/mock-tikv"
+	"github.com/pingcap/tidb/terror"
+	goctx "golang.org/x/net/context"
+)
+
+type testLargeTxnSuite struct {
+	mvccStore *mocktikv.MvccStore
+	store     *tikvStore
+	quota     uint64
+}
+
+var _ = Suite(&testLargeTxnSuite{})
+
+func (s *testLargeTxnSuite) SetUpTest(c *C) {
+	cluster := mocktikv.NewCluster()
+	mocktikv.BootstrapWithMultiRegions(cluster, []byte("k1"), []byte("k2"))
+	s.mvccStore = mocktikv.NewMvccStore()
+	client := mocktikv.NewRPCClient(cluster, s.mvccStore)
+	pdCli := &codecPDClient{mocktikv.NewPDClient(cluster)}
+	store, err := newTikvStore("mock-tikv-store", pdCli, client, false)
+	c.Assert(err, IsNil)
+	s.store = store
+	s.quota = kv.TxnMemBufferQuota
+	kv.TxnMemBufferQuota = 1024
+}
+
+func (s *testLargeTxnSuite) TearDownTest(c *C) {
+	kv.TxnMemBufferQuota = s.quota
+}
+
+func (s *testLargeTxnSuite) begin(c *C) *tikvTxn {
+	txn, err := s.store.Begin()
+	c.Assert(err, IsNil)
+	return txn.(*tikvTxn)
+}
+
+func largeTxnKey(i int) []byte {
+	return []byte(fmt.Sprintf("k%d_%04d", i%3, i))
+}
+
+// mustWriteLarge writes the keys until the buffer spills, and waits for them to be prewritten.
+func (s *testLargeTxnSuite) mustWriteLarge(c *C, txn *tikvTxn, n int) {
+	for i := 0; i < n; i++ {
+		c.Assert(txn.Set(largeTxnKey(i), []byte(fmt.Sprintf("v%d", i))), IsNil)
+	}
+	c.Assert(txn.large, NotNil)
+	c.Assert(txn.large.primary, NotNil)
+	s.mustLocked(c, txn.large.primary, txn.startTS)
+}
+
+func (s *testLargeTxnSuite) lockOf(key []byte) *mocktikv.ErrLocked {
+	for i := 0; i < 100; i++ {
+		_, err := s.mvccStore.Get(key, math.MaxUint64)
+		if locked, ok := err.(*mocktikv.ErrLocked); ok {
+			return locked
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+func (s *testLargeTxnSuite) mustNotLocked(c *C, key []byte) {
+	var err error
+	for i := 0; i < 100; i++ {
+		if _, err = s.mvccStore.Get(key, math.MaxUint64); err == nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("key %q is locked: %v", key, err)
+}
+
+func (s *testLargeTxnSuite) mustLocked(c *C, key []byte, startTS uint64) {
+	locked := s.lockOf(key)
+	c.Assert(locked, NotNil)
+	c.Assert(locked.StartTS, Equals, startTS)
+}
+
+func (s *testLargeTxnSuite) TestLargeTxnCommit(c *C) {
+	txn := s.begin(c)
+	s.mustWriteLarge(c, txn, 200)
+	// Overwrite and delete some keys after they're prewritten.
+	c.Assert(txn.Set(largeTxnKey(0), []byte("new")), IsNil)
+	c.Assert(txn.Delete(largeTxnKey(1)), IsNil)
+	c.Assert(txn.LockKeys(kv.Key("lock")), IsNil)
+	val, err := txn.Get(largeTxnKey(2))
+	c.Assert(err, IsNil)
+	c.Assert(string(val), Equals, "v2")
+	c.Assert(txn.Commit(), IsNil)
+
+	txn = s.begin(c)
+	val, err = txn.Get(largeTxnKey(0))
+	c.Assert(err, IsNil)
+	c.Assert(string(val), Equals, "new")
+	_, err = txn.Get(largeTxnKey(1))
+	c.Assert(terror.ErrorEqual(err, kv.ErrNotExist), IsTrue)
+	it, err := txn.Seek(nil)
+	c.Assert(err, IsNil)
+	var n int
+	for ; it.Valid(); it.Next() {
+		n++
+	}
+	it.Close()
+	c.Assert(n, Equals, 199)
+	s.mustNotLocked(c, []byte("lock"))
+}
+
+func (s *testLargeTxnSuite) TestLargeTxnRollback(c *C) {
+	txn := s.begin(c)
+	s.mustWriteLarge(c, txn, 200)
+	c.Assert(txn.Rollback(), IsNil)
+	var err error
+	for i := 0; i < 200; i++ {
+		_, err = s.mvccStore.Get(largeTxnKey(i), math.MaxUint64)
+		c.Assert(err, IsNil)
+	}
+
+	// The transaction fails if the keys it writes are committed by others after it starts.
+	txn = s.begin(c)
+	txn1 := s.begin(c)
+	c.Assert(txn1.Set(largeTxnKey(150), []byte("v")), IsNil)
+	c.Assert(txn1.Commit(), IsNil)
+	// The error of the prewrites in the background is returned by a write or the commit.
+	for i := 0; i < 200 && err == nil; i++ {
+		err = txn.Set(largeTxnKey(i), []byte("v"))
+	}
+	if err == nil {
+		err = txn.Commit()
+	} else {
+		c.Assert(txn.Rollback(), IsNil)
+	}
+	c.Assert(err, NotNil)
+	for i := 0; i < 200; i++ {
+		_, err := s.mvccStore.Get(largeTxnKey(i), math.MaxUint64)
+		c.Assert(err, IsNil)
+	}
+}
+
+func (s *testLargeTxnSuite) TestLargeTxnHeartBeat(c *C) {
+	defer func(ttl uint64, interval time.Duration) {
+		largeTxnLockTTL, largeTxnHeartBeatInterval = ttl, interval
+	}(largeTxnLockTTL, largeTxnHeartBeatInterval)
+	largeTxnLockTTL, largeTxnHeartBeatInterval = 300, 50*time.Millisecond
+
+	txn := s.begin(c)
+	s.mustWriteLarge(c, txn, 200)
+	time.Sleep(time.Second)
+
+	// The secondary lock is expired, but the primary lock is kept alive by the heartbeats.
+	key := largeTxnKey(3)
+	locked := s.lockOf(key)
+	c.Assert(locked, NotNil)
+	lock := &Lock{Key: key, Primary: locked.Primary, TxnID: locked.StartTS, TTL: locked.TTL}
+	c.Assert(s.store.oracle.IsExpired(lock.TxnID, lock.TTL), IsTrue)
+	ok, err := s.store.lockResolver.ResolveLocks(NewBackoffer(getMaxBackoff, goctx.Background()), []*Lock{lock})
+	c.Assert(err, IsNil)
+	c.Assert(ok, IsFalse)
+	c.Assert(txn.Commit(), IsNil)
+
+	// The locks are resolved after the heartbeats stop.
+	txn = s.begin(c)
+	s.mustWriteLarge(c, txn, 200)
+	txn.large.stopHeartBeats()
+	time.Sleep(time.Second)
+	txn1 := s.begin(c)
+	val, err := txn1.Get(largeTxnKey(0))
+	c.Assert(err, IsNil)
+	c.Assert(string(val), Equals, "v0")
+	c.Assert(txn.Commit(), NotNil)
+}
+
+func (s *testLargeTxnSuite) TestLargeTxnLimits(c *C) {
+	defer func(limit uint64) {
+		kv.TxnEntryCountLimit = limit
+	}(kv.TxnEntryCountLimit)
+	kv.TxnEntryCountLimit = 100
+
+	// The optimistic transactions are prewritten in the background, they have the limits of large transactions.
+	txn := s.begin(c)
+	s.mustWriteLarge(c, txn, 200)
+	c.Assert(txn.Commit(), IsNil)
+
+	// The pessimistic transactions are committed from memory.
+	txn = s.begin(c)
+	txn.SetOption(kv.Pessimistic, true)
+	var err error
+	for i := 0; i < 200 && err == nil; i++ {
+		err = txn.Set(largeTxnKey(i), []byte("v"))
+	}
+	c.Assert(kv.ErrTxnTooLarge.Equal(err), IsTrue)
+	c.Assert(txn.large, IsNil)
+	c.Assert(txn.Rollback(), IsNil)
+}
@@ -144,7 +144,11 @@ func (lr *LockResolver) ResolveLocks(bo *Backoffer, locks []*Lock) (ok bool, err
 
 	var expiredLocks []*Lock
 	for _, l := range locks {
-		if lr.store.oracle.IsExpired(l.TxnID, l.TTL) {
+		expired, err := lr.isExpired(bo, l)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		if expired {
 			lockResolverCounter.WithLabelValues("expired").Inc()
 			expiredLocks = append(expiredLocks, l)
 		} else {
@@ -178,6 +182,22 @@ func (lr *LockResolver) ResolveLocks(bo *Backoffer, locks []*Lock) (ok bool, err
 	return len(expiredLocks) == len(locks), nil
 }
 
+// isExpired checks if the TTL of the lock is expired. The TTL of the primary lock of a large transaction is
+// extended by its heartbeats, so it's checked too if the lock is expired.
+func (lr *LockResolver) isExpired(bo *Backoffer, l *Lock) (bool, error) {
+	if !lr.store.oracle.IsExpired(l.TxnID, l.TTL) {
+		return false, nil
+	}
+	if !lr.store.supportExtReq() {
+		return true, nil
+	}
+	ttl, err := sendTxnHeartBeat(bo, lr.store, l.Primary, l.TxnID, 0)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return lr.store.oracle.IsExpired(l.TxnID, ttl), nil
+}
+
 // GetTxnStatus queries tikv-server for a txn's status (commit/rollback).
 // If the primary key is still locked, it will launch a Rollback to abort it.
 // To avoid unnecessarily aborting too many txns, it is wiser to wait a few
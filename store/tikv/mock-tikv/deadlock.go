@@ -0,0 +1,83 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mocktikv
+
+import (
+	"sync"
+	"time"
+)
+
+// detectorEntryTTL is how long a wait-for edge is kept, the waiting transactions retry their lock
+// requests much more often, which adds the edges again.
+const detectorEntryTTL = time.Second
+
+// deadlockDetector detects the deadlocks in the wait-for graph of the transactions which wait for
+// pessimistic locks.
+type deadlockDetector struct {
+	mu sync.Mutex
+	// waitFor maps a transaction to the transactions it waits for and the last time it waited.
+	waitFor map[uint64]map[uint64]time.Time
+}
+
+func newDeadlockDetector() *deadlockDetector {
+	return &deadlockDetector{
+		waitFor: make(map[uint64]map[uint64]time.Time),
+	}
+}
+
+// detect adds the edge from txn to waitForTxn into the graph, it returns false without adding the edge
+// if the edge forms a cycle.
+func (d *deadlockDetector) detect(txn, waitForTxn uint64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if d.reachable(waitForTxn, txn, now, make(map[uint64]struct{})) {
+		return false
+	}
+	edges := d.waitFor[txn]
+	if edges == nil {
+		edges = make(map[uint64]time.Time)
+		d.waitFor[txn] = edges
+	}
+	edges[waitForTxn] = now
+	return true
+}
+
+func (d *deadlockDetector) reachable(from, to uint64, now time.Time, visited map[uint64]struct{}) bool {
+	if from == to {
+		return true
+	}
+	visited[from] = struct{}{}
+	for next, t := range d.waitFor[from] {
+		if now.Sub(t) > detectorEntryTTL {
+			delete(d.waitFor[from], next)
+			continue
+		}
+		if _, ok := visited[next]; ok {
+			continue
+		}
+		if d.reachable(next, to, now, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanUp removes the edges from txn when it doesn't wait anymore.
+func (d *deadlockDetector) cleanUp(txn uint64) {
+	d.mu.Lock()
+	delete(d.waitFor, txn)
+	d.mu.Unlock()
+}
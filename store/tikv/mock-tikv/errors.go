@@ -50,3 +50,35 @@ type ErrAlreadyCommitted uint64
 func (e ErrAlreadyCommitted) Error() string {
 	return fmt.Sprint("txn already committed")
 }
+
+// ErrConflict is returned when a key to lock pessimistically is committed after the forUpdateTS of
+// the transaction, it should read the key again with a newer forUpdateTS.
+type ErrConflict struct {
+	Key        MvccKey
+	ConflictTS uint64
+}
+
+func (e *ErrConflict) Error() string {
+	return fmt.Sprintf("write conflict, key: %q, conflictTS: %v", e.Key, e.ConflictTS)
+}
+
+// ErrDeadlock is returned when waiting for the lock of another transaction causes a deadlock.
+type ErrDeadlock struct {
+	LockKey MvccKey
+	LockTS  uint64
+}
+
+func (e *ErrDeadlock) Error() string {
+	return fmt.Sprintf("deadlock, key: %q, lockTS: %v", e.LockKey, e.LockTS)
+}
+
+// ErrCommitTSExpired is returned when the commitTS of a one-phase commit is not greater than the timestamps
+// the keys may be read at, the transaction should fall back to 2PC.
+type ErrCommitTSExpired struct {
+	CommitTS  uint64
+	MaxReadTS uint64
+}
+
+func (e *ErrCommitTSExpired) Error() string {
+	return fmt.Sprintf("commitTS expired, commitTS: %v, maxReadTS: %v", e.CommitTS, e.MaxReadTS)
+}
@@ -254,3 +254,108 @@ func (s *testMockTiKVSuite) TestResolveLock(c *C) {
 	s.mustGetOK(c, "s2", 30, "v10")
 	s.mustScanLock(c, 30, nil)
 }
+
+func (s *testMockTiKVSuite) mustPessimisticLockOK(c *C, key, primary string, startTS, forUpdateTS uint64) {
+	err := s.store.PessimisticLock([][]byte{[]byte(key)}, []byte(primary), startTS, forUpdateTS, 3000, true)
+	c.Assert(err, IsNil)
+}
+
+func (s *testMockTiKVSuite) TestPessimisticLock(c *C) {
+	s.mustPutOK(c, "x", "x5", 5, 10)
+	// The key is committed after the forUpdateTS.
+	err := s.store.PessimisticLock([][]byte{[]byte("x")}, []byte("x"), 6, 8, 3000, true)
+	c.Assert(err, FitsTypeOf, &ErrConflict{})
+	c.Assert(err.(*ErrConflict).ConflictTS, Equals, uint64(10))
+
+	s.mustPessimisticLockOK(c, "x", "x", 6, 11)
+	// Locking the key again is fine.
+	s.mustPessimisticLockOK(c, "x", "x", 6, 12)
+	// Pessimistic locks don't block reads.
+	s.mustGetOK(c, "x", 20, "x5")
+	// The other transactions can't lock or write the key.
+	err = s.store.PessimisticLock([][]byte{[]byte("y"), []byte("x")}, []byte("y"), 7, 12, 3000, true)
+	c.Assert(err, FitsTypeOf, &ErrLocked{})
+	errs := s.store.Prewrite(putMutations("x", "x13"), []byte("x"), 13, 0)
+	c.Assert(errs[0], FitsTypeOf, &ErrLocked{})
+	// The keys are either all locked or none of them is locked.
+	s.mustPessimisticLockOK(c, "y", "y", 8, 12)
+
+	// The pessimistic lock is converted to a normal lock when it's prewritten, even if the key is
+	// committed after the startTS.
+	s.mustPrewriteOK(c, putMutations("x", "x6"), "x", 6)
+	s.mustGetErr(c, "x", 20)
+	s.mustCommitOK(c, [][]byte{[]byte("x")}, 6, 15)
+	s.mustGetOK(c, "x", 20, "x6")
+
+	// PessimisticRollback only releases the pessimistic locks.
+	s.store.PessimisticRollback([][]byte{[]byte("y")}, 8)
+	s.mustPessimisticLockOK(c, "y", "y", 16, 16)
+
+	// The transaction can't lock the keys after it's rolled back, e.g. by the lock resolver.
+	s.mustPessimisticLockOK(c, "z", "z", 17, 17)
+	s.mustRollbackOK(c, [][]byte{[]byte("z")}, 17)
+	s.mustGetNone(c, "z", 20)
+	err = s.store.PessimisticLock([][]byte{[]byte("z")}, []byte("z"), 17, 18, 3000, true)
+	c.Assert(err, FitsTypeOf, ErrAbort(""))
+}
+
+func (s *testMockTiKVSuite) TestDeadlock(c *C) {
+	s.mustPessimisticLockOK(c, "a", "a", 10, 10)
+	s.mustPessimisticLockOK(c, "b", "b", 20, 20)
+	s.mustPessimisticLockOK(c, "c", "c", 30, 30)
+	// 10 waits for 20, and 20 waits for 30.
+	err := s.store.PessimisticLock([][]byte{[]byte("b")}, []byte("a"), 10, 10, 3000, true)
+	c.Assert(err, FitsTypeOf, &ErrLocked{})
+	err = s.store.PessimisticLock([][]byte{[]byte("c")}, []byte("b"), 20, 20, 3000, true)
+	c.Assert(err, FitsTypeOf, &ErrLocked{})
+	// 30 can't wait for 10.
+	err = s.store.PessimisticLock([][]byte{[]byte("a")}, []byte("c"), 30, 30, 3000, true)
+	c.Assert(err, FitsTypeOf, &ErrDeadlock{})
+	c.Assert(err.(*ErrDeadlock).LockTS, Equals, uint64(10))
+	// The transaction which doesn't wait causes no deadlock.
+	err = s.store.PessimisticLock([][]byte{[]byte("a")}, []byte("c"), 30, 30, 3000, false)
+	c.Assert(err, FitsTypeOf, &ErrLocked{})
+	// 20 doesn't wait for 30 after 30 releases its locks.
+	s.store.PessimisticRollback([][]byte{[]byte("c")}, 30)
+	s.mustPessimisticLockOK(c, "c", "b", 20, 20)
+	err = s.store.PessimisticLock([][]byte{[]byte("a")}, []byte("c"), 31, 31, 3000, true)
+	c.Assert(err, FitsTypeOf, &ErrLocked{})
+}
+
+func (s *testMockTiKVSuite) TestOnePC(c *C) {
+	s.mustPutOK(c, "x", "x-5", 5, 10)
+
+	// The keys are committed at once.
+	errs := s.store.OnePC(putMutations("x", "x-20", "y", "y-20"), []byte("x"), 20, 21)
+	c.Assert(errs, IsNil)
+	s.mustGetOK(c, "x", 20, "x-5")
+	s.mustGetOK(c, "y", 21, "y-20")
+	s.mustScanLock(c, 30, nil)
+	// The request is sent again.
+	errs = s.store.OnePC(putMutations("x", "x-20", "y", "y-20"), []byte("x"), 20, 21)
+	c.Assert(errs, IsNil)
+
+	// The keys read at commitTS or a newer timestamp can't be committed by 1PC.
+	s.mustGetOK(c, "x", 32, "x-20")
+	errs = s.store.OnePC(putMutations("x", "x-31"), []byte("x"), 31, 32)
+	c.Assert(errs, HasLen, 1)
+	_, ok := errs[0].(*ErrCommitTSExpired)
+	c.Assert(ok, IsTrue)
+	s.mustGetOK(c, "x", 40, "x-20")
+
+	// None of the keys is committed if one of them is locked.
+	s.mustPrewriteOK(c, putMutations("y", "y-41"), "y", 41)
+	errs = s.store.OnePC(putMutations("x", "x-42", "y", "y-42"), []byte("x"), 42, 43)
+	c.Assert(errs, HasLen, 1)
+	_, ok = errs[0].(*ErrLocked)
+	c.Assert(ok, IsTrue)
+	s.mustRollbackOK(c, [][]byte{[]byte("y")}, 41)
+	s.mustGetOK(c, "x", 50, "x-20")
+	s.mustScanLock(c, 50, nil)
+
+	// The keys committed after startTS conflict.
+	errs = s.store.OnePC(putMutations("x", "x-15"), []byte("x"), 15, 51)
+	c.Assert(errs, HasLen, 1)
+	_, ok = errs[0].(ErrRetryable)
+	c.Assert(ok, IsTrue)
+}
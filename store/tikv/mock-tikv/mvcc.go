@@ -15,7 +15,9 @@ package mocktikv
 
 import (
 	"bytes"
+	"math"
 	"sync"
+	"sync/atomic"
 
 	"github.com/juju/errors"
 	"github.com/petar/GoLLRB/llrb"
@@ -44,6 +46,9 @@ type mvccLock struct {
 	value   []byte
 	op      kvrpcpb.Op
 	ttl     uint64
+	// forUpdateTS is set if the lock is a pessimistic lock which isn't prewritten yet. Pessimistic locks
+	// don't block reads, they only keep other transactions from writing the key.
+	forUpdateTS uint64
 }
 
 type mvccEntry struct {
@@ -76,6 +81,8 @@ func (e *mvccEntry) Clone() *mvccEntry {
 			value:   append([]byte(nil), e.lock.value...),
 			op:      e.lock.op,
 			ttl:     e.lock.ttl,
+
+			forUpdateTS: e.lock.forUpdateTS,
 		}
 	}
 	return &entry
@@ -95,8 +102,10 @@ func (e *mvccEntry) lockErr() error {
 }
 
 func (e *mvccEntry) Get(ts uint64) ([]byte, error) {
-	if e.lock != nil {
-		if e.lock.startTS <= ts {
+	// A transaction reads at its startTS, the locks at ts are its own locks which are prewritten before it
+	// commits, like the locks of a large transaction.
+	if e.lock != nil && e.lock.forUpdateTS == 0 {
+		if e.lock.startTS < ts {
 			return nil, e.lockErr()
 		}
 	}
@@ -109,6 +118,17 @@ func (e *mvccEntry) Get(ts uint64) ([]byte, error) {
 }
 
 func (e *mvccEntry) Prewrite(mutation *kvrpcpb.Mutation, startTS uint64, primary []byte, ttl uint64) error {
+	if e.lock != nil && e.lock.startTS == startTS && e.lock.forUpdateTS != 0 {
+		// No one else can write the key after it's locked pessimistically, so there is no conflict to check.
+		e.lock = &mvccLock{
+			startTS: startTS,
+			primary: primary,
+			value:   mutation.Value,
+			op:      mutation.GetOp(),
+			ttl:     ttl,
+		}
+		return nil
+	}
 	if len(e.values) > 0 {
 		if e.values[0].commitTS >= startTS {
 			return ErrRetryable("write conflict")
@@ -118,6 +138,9 @@ func (e *mvccEntry) Prewrite(mutation *kvrpcpb.Mutation, startTS uint64, primary
 		if e.lock.startTS != startTS {
 			return e.lockErr()
 		}
+		// A large transaction prewrites the key again if it's written after it's prewritten.
+		e.lock.value = mutation.Value
+		e.lock.op = mutation.GetOp()
 		return nil
 	}
 	e.lock = &mvccLock{
@@ -130,6 +153,48 @@ func (e *mvccEntry) Prewrite(mutation *kvrpcpb.Mutation, startTS uint64, primary
 	return nil
 }
 
+func (e *mvccEntry) PessimisticLock(startTS, forUpdateTS uint64, primary []byte, ttl uint64) error {
+	if e.lock != nil {
+		if e.lock.startTS != startTS {
+			return e.lockErr()
+		}
+		// The key is locked by the transaction already.
+		if e.lock.forUpdateTS != 0 {
+			if forUpdateTS > e.lock.forUpdateTS {
+				e.lock.forUpdateTS = forUpdateTS
+			}
+			if ttl > e.lock.ttl {
+				e.lock.ttl = ttl
+			}
+		}
+		return nil
+	}
+	for _, v := range e.values {
+		if v.valueType != typeRollback {
+			if v.commitTS > forUpdateTS {
+				return &ErrConflict{Key: e.key, ConflictTS: v.commitTS}
+			}
+			break
+		}
+	}
+	for _, v := range e.values {
+		if v.commitTS < startTS {
+			break
+		}
+		if v.startTS == startTS {
+			return ErrAbort("txn is already rolled back or committed")
+		}
+	}
+	e.lock = &mvccLock{
+		startTS:     startTS,
+		primary:     primary,
+		op:          kvrpcpb.Op_Lock,
+		ttl:         ttl,
+		forUpdateTS: forUpdateTS,
+	}
+	return nil
+}
+
 func (e *mvccEntry) checkTxnCommitted(startTS uint64) (uint64, bool) {
 	for _, v := range e.values {
 		if v.startTS == startTS && v.valueType != typeRollback {
@@ -182,16 +247,20 @@ func (e *mvccEntry) Rollback(startTS uint64) error {
 
 // MvccStore is an in-memory, multi-versioned, transaction-supported kv storage.
 type MvccStore struct {
+	// maxReadTS is the max timestamp which the keys are read at, it's accessed atomically.
+	maxReadTS uint64
 	sync.RWMutex
-	tree  *llrb.LLRB
-	rawkv map[string][]byte
+	tree     *llrb.LLRB
+	rawkv    map[string][]byte
+	detector *deadlockDetector
 }
 
 // NewMvccStore creates a MvccStore.
 func NewMvccStore() *MvccStore {
 	return &MvccStore{
-		tree:  llrb.New(),
-		rawkv: make(map[string][]byte),
+		tree:     llrb.New(),
+		rawkv:    make(map[string][]byte),
+		detector: newDeadlockDetector(),
 	}
 }
 
@@ -200,6 +269,7 @@ func (s *MvccStore) Get(key []byte, startTS uint64) ([]byte, error) {
 	s.RLock()
 	defer s.RUnlock()
 
+	s.updateMaxReadTS(startTS)
 	return s.get(NewMvccKey(key), startTS)
 }
 
@@ -211,6 +281,21 @@ func (s *MvccStore) get(key MvccKey, startTS uint64) ([]byte, error) {
 	return entry.(*mvccEntry).Get(startTS)
 }
 
+// updateMaxReadTS records the timestamp which the keys are read at, the one-phase commits can't commit at
+// it or an older timestamp, or the snapshots which are read already are changed.
+func (s *MvccStore) updateMaxReadTS(ts uint64) {
+	// The reads of the latest version don't need a consistent snapshot.
+	if ts == math.MaxUint64 {
+		return
+	}
+	for {
+		old := atomic.LoadUint64(&s.maxReadTS)
+		if ts <= old || atomic.CompareAndSwapUint64(&s.maxReadTS, old, ts) {
+			return
+		}
+	}
+}
+
 // A Pair is a KV pair read from MvccStore or an error if any occurs.
 type Pair struct {
 	Key   []byte
@@ -223,6 +308,7 @@ func (s *MvccStore) BatchGet(ks [][]byte, startTS uint64) []Pair {
 	s.RLock()
 	defer s.RUnlock()
 
+	s.updateMaxReadTS(startTS)
 	var pairs []Pair
 	for _, k := range ks {
 		val, err := s.get(NewMvccKey(k), startTS)
@@ -248,6 +334,7 @@ func (s *MvccStore) Scan(startKey, endKey []byte, limit int, startTS uint64) []P
 	s.RLock()
 	defer s.RUnlock()
 
+	s.updateMaxReadTS(startTS)
 	startKey = NewMvccKey(startKey)
 	endKey = NewMvccKey(endKey)
 
@@ -280,6 +367,7 @@ func (s *MvccStore) ReverseScan(startKey, endKey []byte, limit int, startTS uint
 	s.RLock()
 	defer s.RUnlock()
 
+	s.updateMaxReadTS(startTS)
 	startKey = NewMvccKey(startKey)
 	endKey = NewMvccKey(endKey)
 
@@ -338,6 +426,93 @@ func (s *MvccStore) Prewrite(mutations []*kvrpcpb.Mutation, primary []byte, star
 	return errs
 }
 
+// OnePC prewrites and commits the mutations of a transaction at once, it's used when all the mutations
+// are in one region. The mutations are either all committed or none of them is. It returns
+// ErrCommitTSExpired if the keys may be read at commitTS or a newer timestamp already.
+func (s *MvccStore) OnePC(mutations []*kvrpcpb.Mutation, primary []byte, startTS, commitTS uint64) []error {
+	s.Lock()
+	defer s.Unlock()
+
+	// The request may be sent again if its response is lost.
+	for _, m := range mutations {
+		if m.GetOp() == kvrpcpb.Op_Lock {
+			continue
+		}
+		if item := s.tree.Get(newEntry(NewMvccKey(m.Key))); item != nil {
+			if ts, ok := item.(*mvccEntry).checkTxnCommitted(startTS); ok && ts == commitTS {
+				return nil
+			}
+		}
+		break
+	}
+	if maxReadTS := atomic.LoadUint64(&s.maxReadTS); commitTS <= maxReadTS {
+		return []error{&ErrCommitTSExpired{CommitTS: commitTS, MaxReadTS: maxReadTS}}
+	}
+
+	var errs []error
+	ents := make([]*mvccEntry, 0, len(mutations))
+	for _, m := range mutations {
+		entry := s.getOrNewEntry(NewMvccKey(m.Key))
+		if err := entry.Prewrite(m, startTS, primary, 0); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ents = append(ents, entry)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	for _, entry := range ents {
+		if err := entry.Commit(startTS, commitTS); err != nil {
+			return []error{err}
+		}
+	}
+	s.submit(ents...)
+	return nil
+}
+
+// PessimisticLock acquires the pessimistic locks on the keys for a transaction, the keys are either all
+// locked or none of them is locked. If waitLock is true and a key is locked by another transaction, the
+// transaction is going to wait for the lock, ErrDeadlock is returned if the wait causes a deadlock.
+func (s *MvccStore) PessimisticLock(keys [][]byte, primary []byte, startTS, forUpdateTS, ttl uint64, waitLock bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	// A transaction sends its lock requests one by one, it doesn't wait for the previous locks anymore.
+	s.detector.cleanUp(startTS)
+	ents := make([]*mvccEntry, 0, len(keys))
+	for _, k := range keys {
+		entry := s.getOrNewEntry(NewMvccKey(k))
+		err := entry.PessimisticLock(startTS, forUpdateTS, primary, ttl)
+		if err != nil {
+			if locked, ok := err.(*ErrLocked); ok && waitLock && !s.detector.detect(startTS, locked.StartTS) {
+				return &ErrDeadlock{LockKey: locked.Key, LockTS: locked.StartTS}
+			}
+			return err
+		}
+		ents = append(ents, entry)
+	}
+	s.submit(ents...)
+	return nil
+}
+
+// PessimisticRollback releases the pessimistic locks of a transaction which are not prewritten.
+func (s *MvccStore) PessimisticRollback(keys [][]byte, startTS uint64) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.detector.cleanUp(startTS)
+	var ents []*mvccEntry
+	for _, k := range keys {
+		entry := s.getOrNewEntry(NewMvccKey(k))
+		if entry.lock != nil && entry.lock.startTS == startTS && entry.lock.forUpdateTS != 0 {
+			entry.lock = nil
+			ents = append(ents, entry)
+		}
+	}
+	s.submit(ents...)
+}
+
 // Commit commits the lock on a key. (2nd phase of 2PC).
 func (s *MvccStore) Commit(keys [][]byte, startTS, commitTS uint64) error {
 	s.Lock()
@@ -370,6 +545,22 @@ func (s *MvccStore) Cleanup(key []byte, startTS uint64) error {
 	return nil
 }
 
+// TxnHeartBeat extends the TTL of the primary lock to adviseTTL if it's larger, it returns the TTL.
+func (s *MvccStore) TxnHeartBeat(primary []byte, startTS, adviseTTL uint64) (uint64, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	entry := s.getOrNewEntry(NewMvccKey(primary))
+	if entry.lock == nil || entry.lock.startTS != startTS {
+		return 0, ErrAbort("txn not found")
+	}
+	if adviseTTL > entry.lock.ttl {
+		entry.lock.ttl = adviseTTL
+		s.submit(entry)
+	}
+	return entry.lock.ttl, nil
+}
+
 // Rollback cleanups multiple locks, often used when rolling back a conflict txn.
 func (s *MvccStore) Rollback(keys [][]byte, startTS uint64) error {
 	s.Lock()
@@ -23,6 +23,7 @@ import (
 	"github.com/pingcap/kvproto/pkg/kvrpcpb"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/store/tikv/tikvrpc"
 	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tipb/go-tipb"
 	goctx "golang.org/x/net/context"
@@ -311,6 +312,88 @@ func (h *rpcHandler) onRawDelete(req *kvrpcpb.CmdRawDeleteRequest) *kvrpcpb.CmdR
 	return &kvrpcpb.CmdRawDeleteResponse{}
 }
 
+// handleExtRequest serves the requests defined in tikvrpc, which are not in kvproto yet.
+func (h *rpcHandler) handleExtRequest(req *tikvrpc.Request) *tikvrpc.Response {
+	resp := &tikvrpc.Response{Type: req.Type}
+	if err := h.checkContext(req.Context); err != nil {
+		resp.RegionError = err
+		return resp
+	}
+	switch req.Type {
+	case tikvrpc.CmdPessimisticLock:
+		resp.PessimisticLockResp = h.onPessimisticLock(req.PessimisticLockReq)
+	case tikvrpc.CmdPessimisticRollback:
+		resp.PessimisticRollbackResp = h.onPessimisticRollback(req.PessimisticRollbackReq)
+	case tikvrpc.CmdOnePC:
+		resp.OnePCResp = h.onOnePC(req.OnePCReq)
+	case tikvrpc.CmdTxnHeartBeat:
+		resp.TxnHeartBeatResp = h.onTxnHeartBeat(req.TxnHeartBeatReq)
+	}
+	return resp
+}
+
+func (h *rpcHandler) onPessimisticLock(req *tikvrpc.PessimisticLockRequest) *tikvrpc.PessimisticLockResponse {
+	for _, k := range req.Keys {
+		if !h.keyInRegion(k) {
+			panic("onPessimisticLock: key not in region")
+		}
+	}
+	var resp tikvrpc.PessimisticLockResponse
+	err := h.mvccStore.PessimisticLock(req.Keys, req.PrimaryLock, req.StartVersion, req.ForUpdateTS, req.LockTTL, req.WaitLock)
+	switch e := err.(type) {
+	case nil:
+	case *ErrLocked:
+		resp.Locked = convertToKeyError(err).Locked
+	case *ErrConflict:
+		resp.Conflict = &tikvrpc.WriteConflict{Key: e.Key.Raw(), ConflictTS: e.ConflictTS}
+	case *ErrDeadlock:
+		resp.Deadlock = &tikvrpc.Deadlock{LockKey: e.LockKey.Raw(), LockTS: e.LockTS}
+	default:
+		resp.Abort = err.Error()
+	}
+	return &resp
+}
+
+func (h *rpcHandler) onPessimisticRollback(req *tikvrpc.PessimisticRollbackRequest) *tikvrpc.PessimisticRollbackResponse {
+	for _, k := range req.Keys {
+		if !h.keyInRegion(k) {
+			panic("onPessimisticRollback: key not in region")
+		}
+	}
+	h.mvccStore.PessimisticRollback(req.Keys, req.StartVersion)
+	return &tikvrpc.PessimisticRollbackResponse{}
+}
+
+func (h *rpcHandler) onOnePC(req *tikvrpc.OnePCRequest) *tikvrpc.OnePCResponse {
+	for _, m := range req.Mutations {
+		if !h.keyInRegion(m.Key) {
+			panic("onOnePC: key not in region")
+		}
+	}
+	errs := h.mvccStore.OnePC(req.Mutations, req.PrimaryLock, req.StartVersion, req.CommitVersion)
+	if len(errs) == 1 {
+		if _, ok := errs[0].(*ErrCommitTSExpired); ok {
+			return &tikvrpc.OnePCResponse{CommitTSExpired: true}
+		}
+	}
+	return &tikvrpc.OnePCResponse{
+		Errors: convertToKeyErrors(errs),
+	}
+}
+
+func (h *rpcHandler) onTxnHeartBeat(req *tikvrpc.TxnHeartBeatRequest) *tikvrpc.TxnHeartBeatResponse {
+	if !h.keyInRegion(req.PrimaryLock) {
+		panic("onTxnHeartBeat: key not in region")
+	}
+	var resp tikvrpc.TxnHeartBeatResponse
+	ttl, err := h.mvccStore.TxnHeartBeat(req.PrimaryLock, req.StartVersion, req.AdviseLockTTL)
+	if err != nil {
+		resp.Error = convertToKeyError(err)
+	}
+	resp.LockTTL = ttl
+	return &resp
+}
+
 func convertToKeyError(err error) *kvrpcpb.KeyError {
 	if locked, ok := err.(*ErrLocked); ok {
 		return &kvrpcpb.KeyError{
@@ -394,6 +477,23 @@ func (c *RPCClient) SendKVReq(ctx goctx.Context, addr string, req *kvrpcpb.Reque
 	return handler.handleRequest(req), nil
 }
 
+// SendExtReq sends a request defined in tikvrpc to mock cluster.
+func (c *RPCClient) SendExtReq(ctx goctx.Context, addr string, req *tikvrpc.Request, timeout time.Duration) (*tikvrpc.Response, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	store, err := c.getAndCheckStoreByAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := newRPCHandler(c.Cluster, c.MvccStore, store.GetId())
+	return handler.handleExtRequest(req), nil
+}
+
 func (c *RPCClient) getAndCheckStoreByAddr(addr string) (*metapb.Store, error) {
 	store, err := c.Cluster.GetAndCheckStoreByAddr(addr)
 	if err != nil {
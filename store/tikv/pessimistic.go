@@ -0,0 +1,315 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"time"
+
+	"github.com/coreos/etcd/pkg/monotime"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/store/tikv/tikvrpc"
+	goctx "golang.org/x/net/context"
+)
+
+// pessimisticLockTTL is the TTL(in ms) of pessimistic locks. A transaction holds its pessimistic locks
+// between its statements, the TTL of its primary lock is extended by the heartbeats until it finishes.
+var pessimisticLockTTL uint64 = 20000
+
+// pessimisticHeartBeatInterval is the interval of the heartbeats of a pessimistic transaction.
+var pessimisticHeartBeatInterval = 5 * time.Second
+
+const (
+	// lockWaitInterval is the initial interval to retry a lock request which waits for another transaction.
+	lockWaitInterval = 5 * time.Millisecond
+	// maxLockWaitInterval is the max interval to retry a waiting lock request, it must be much shorter than
+	// the TTL of the wait-for graph of the deadlock detector, or the deadlocks can't be detected.
+	maxLockWaitInterval = 100 * time.Millisecond
+)
+
+// pessimisticStmt is a statement of a pessimistic transaction which is running.
+type pessimisticStmt struct {
+	// buf stages the writes of the statement.
+	buf         *kv.BufferStore
+	forUpdateTS uint64
+	// lockKeys are the keys passed to LockKeys by the statement.
+	lockKeys [][]byte
+}
+
+// IsPessimistic implements the kv.PessimisticTxn interface.
+func (txn *tikvTxn) IsPessimistic() bool {
+	return txn.pessimistic
+}
+
+// StartStmt implements the kv.PessimisticTxn interface.
+func (txn *tikvTxn) StartStmt(forUpdateTS uint64) {
+	txn.stmt = &pessimisticStmt{
+		buf:         kv.NewBufferStore(txn.us),
+		forUpdateTS: forUpdateTS,
+	}
+	txn.snapshot.version = kv.NewVersion(forUpdateTS)
+}
+
+// FinishStmt implements the kv.PessimisticTxn interface.
+func (txn *tikvTxn) FinishStmt(commit bool) error {
+	stmt := txn.stmt
+	txn.stmt = nil
+	txn.snapshot.version = kv.NewVersion(txn.startTS)
+	if !commit || stmt == nil {
+		return nil
+	}
+
+	keys := stmt.lockKeys
+	err := stmt.buf.WalkBuffer(func(k kv.Key, v []byte) error {
+		keys = append(keys, k.Clone())
+		return nil
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = txn.pessimisticLockKeys(keys, stmt.forUpdateTS)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(stmt.buf.SaveTo(txn.us))
+}
+
+// pessimisticLockKeys locks the keys which are not locked by the transaction yet. The first locked key
+// of the transaction is the primary lock, it's locked before the other keys.
+func (txn *tikvTxn) pessimisticLockKeys(keys [][]byte, forUpdateTS uint64) error {
+	var toLock [][]byte
+	pending := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		if _, ok := txn.lockedSet[string(k)]; ok {
+			continue
+		}
+		if _, ok := pending[string(k)]; ok {
+			continue
+		}
+		pending[string(k)] = struct{}{}
+		toLock = append(toLock, k)
+	}
+	if len(toLock) == 0 {
+		return nil
+	}
+
+	bo := NewBackoffer(pessimisticLockMaxBackoff, goctx.Background())
+	deadline := time.Now().Add(txn.lockWaitTimeout)
+	if txn.primaryLock == nil {
+		txn.primaryLock = toLock[0]
+		err := txn.pessimisticLockBatches(bo, toLock[:1], forUpdateTS, deadline)
+		if err != nil {
+			txn.primaryLock = nil
+			return errors.Trace(err)
+		}
+		toLock = toLock[1:]
+		txn.startHeartBeat()
+	}
+	return errors.Trace(txn.pessimisticLockBatches(bo, toLock, forUpdateTS, deadline))
+}
+
+// pessimisticLockTTL returns the TTL of the pessimistic locks, it's increased by the time the transaction
+// has run.
+func (txn *tikvTxn) pessimisticLockTTL() uint64 {
+	elapsed := time.Duration(monotime.Now()-txn.startTime) / time.Millisecond
+	return pessimisticLockTTL + uint64(elapsed)
+}
+
+// startHeartBeat keeps the primary lock alive until the transaction finishes, so the locks of a transaction
+// which runs longer than the TTL are not resolved by others.
+func (txn *tikvTxn) startHeartBeat() {
+	txn.stopHeartBeat = make(chan struct{})
+	txn.heartBeatWg.Add(1)
+	go txn.runHeartBeat(txn.stopHeartBeat)
+}
+
+func (txn *tikvTxn) runHeartBeat(stop chan struct{}) {
+	defer txn.heartBeatWg.Done()
+	ticker := time.NewTicker(pessimisticHeartBeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			bo := NewBackoffer(txnHeartBeatMaxBackoff, goctx.Background())
+			ttl, err := sendTxnHeartBeat(bo, txn.store, txn.primaryLock, txn.startTS, txn.pessimisticLockTTL())
+			if err != nil {
+				log.Warnf("[kv] pessimistic txn %d heartbeat failed: %v", txn.startTS, err)
+			} else if ttl == 0 {
+				log.Debugf("[kv] pessimistic txn %d heartbeat finds no primary lock", txn.startTS)
+			}
+		}
+	}
+}
+
+// stopHeartBeats stops the heartbeats of the transaction, it's called when the transaction finishes.
+func (txn *tikvTxn) stopHeartBeats() {
+	if txn.stopHeartBeat == nil {
+		return
+	}
+	close(txn.stopHeartBeat)
+	txn.heartBeatWg.Wait()
+	txn.stopHeartBeat = nil
+}
+
+// pessimisticLockBatches groups the keys by region and locks the batches one by one, the transaction may wait
+// for the locks of other transactions, so the batches are not locked concurrently to keep the waits simple.
+func (txn *tikvTxn) pessimisticLockBatches(bo *Backoffer, keys [][]byte, forUpdateTS uint64, deadline time.Time) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	groups, _, err := txn.store.regionCache.GroupKeysByRegion(bo, keys)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var batches []batchKeys
+	for id, g := range groups {
+		batches = appendBatchBySize(batches, id, g, func(k []byte) int { return len(k) }, txnCommitBatchSize)
+	}
+	for _, batch := range batches {
+		err = txn.pessimisticLockSingleBatch(bo, batch, forUpdateTS, deadline)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func (txn *tikvTxn) pessimisticLockSingleBatch(bo *Backoffer, batch batchKeys, forUpdateTS uint64, deadline time.Time) error {
+	waitInterval := lockWaitInterval
+	for {
+		req := &tikvrpc.Request{
+			Type: tikvrpc.CmdPessimisticLock,
+			PessimisticLockReq: &tikvrpc.PessimisticLockRequest{
+				Keys:         batch.keys,
+				PrimaryLock:  txn.primaryLock,
+				StartVersion: txn.startTS,
+				ForUpdateTS:  forUpdateTS,
+				LockTTL:      txn.pessimisticLockTTL(),
+				WaitLock:     txn.lockWaitTimeout > 0,
+			},
+		}
+		resp, err := txn.store.SendExtReq(bo, req, batch.region, readTimeoutShort)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if regionErr := resp.RegionError; regionErr != nil {
+			err = bo.Backoff(boRegionMiss, errors.New(regionErr.String()))
+			if err != nil {
+				return errors.Trace(err)
+			}
+			// Re-split the keys and lock them again.
+			err = txn.pessimisticLockBatches(bo, batch.keys, forUpdateTS, deadline)
+			return errors.Trace(err)
+		}
+		lockResp := resp.PessimisticLockResp
+		if lockResp == nil {
+			return errors.Trace(errBodyMissing)
+		}
+		switch {
+		case lockResp.Deadlock != nil:
+			log.Infof("[kv] pessimistic txn %d deadlocks on key %q of txn %d", txn.startTS, lockResp.Deadlock.LockKey, lockResp.Deadlock.LockTS)
+			return errors.Trace(kv.ErrDeadlock)
+		case lockResp.Conflict != nil:
+			log.Debugf("[kv] pessimistic txn %d conflicts on key %q, forUpdateTS: %d, conflictTS: %d",
+				txn.startTS, lockResp.Conflict.Key, forUpdateTS, lockResp.Conflict.ConflictTS)
+			return errors.Trace(kv.ErrWriteConflict)
+		case lockResp.Abort != "":
+			return errors.Errorf("tikv aborts txn: %s", lockResp.Abort)
+		case lockResp.Locked == nil:
+			for _, k := range batch.keys {
+				txn.lockedKeys = append(txn.lockedKeys, k)
+				txn.lockedSet[string(k)] = struct{}{}
+			}
+			return nil
+		}
+
+		// The keys are locked by another transaction, resolve the lock if it's expired, or wait for it.
+		ok, err := txn.store.lockResolver.ResolveLocks(bo, []*Lock{newLock(lockResp.Locked)})
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if ok {
+			continue
+		}
+		remain := deadline.Sub(time.Now())
+		if remain <= 0 {
+			return errors.Trace(kv.ErrLockWaitTimeout)
+		}
+		if waitInterval > remain {
+			waitInterval = remain
+		}
+		time.Sleep(waitInterval)
+		waitInterval *= 2
+		if waitInterval > maxLockWaitInterval {
+			waitInterval = maxLockWaitInterval
+		}
+	}
+}
+
+// pessimisticRollback releases the pessimistic locks of the transaction which are not prewritten.
+func (txn *tikvTxn) pessimisticRollback() error {
+	if len(txn.lockedKeys) == 0 {
+		return nil
+	}
+	bo := NewBackoffer(pessimisticRollbackMaxBackoff, goctx.Background())
+	err := txn.pessimisticRollbackKeys(bo, txn.lockedKeys)
+	if err != nil {
+		log.Warnf("[kv] pessimistic rollback txn %d err: %v", txn.startTS, err)
+		return errors.Trace(err)
+	}
+	txn.lockedKeys = nil
+	txn.lockedSet = make(map[string]struct{})
+	return nil
+}
+
+func (txn *tikvTxn) pessimisticRollbackKeys(bo *Backoffer, keys [][]byte) error {
+	groups, _, err := txn.store.regionCache.GroupKeysByRegion(bo, keys)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for id, g := range groups {
+		req := &tikvrpc.Request{
+			Type: tikvrpc.CmdPessimisticRollback,
+			PessimisticRollbackReq: &tikvrpc.PessimisticRollbackRequest{
+				Keys:         g,
+				StartVersion: txn.startTS,
+			},
+		}
+		resp, err := txn.store.SendExtReq(bo, req, id, readTimeoutShort)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if regionErr := resp.RegionError; regionErr != nil {
+			err = bo.Backoff(boRegionMiss, errors.New(regionErr.String()))
+			if err != nil {
+				return errors.Trace(err)
+			}
+			err = txn.pessimisticRollbackKeys(bo, g)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		}
+		if resp.PessimisticRollbackResp == nil {
+			return errors.Trace(errBodyMissing)
+		}
+		if keyErr := resp.PessimisticRollbackResp.Error; keyErr != nil {
+			return errors.Errorf("pessimistic rollback failed: %s", keyErr)
+		}
+	}
+	return nil
+}
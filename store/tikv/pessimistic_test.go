@@ -0,0 +1,81 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/store/tikv/mock-tikv"
+)
+
+type testPessimisticSuite struct {
+	mvccStore *mocktikv.MvccStore
+	store     *tikvStore
+}
+
+var _ = Suite(&testPessimisticSuite{})
+
+func (s *testPessimisticSuite) SetUpTest(c *C) {
+	cluster := mocktikv.NewCluster()
+	mocktikv.BootstrapWithMultiRegions(cluster, []byte("b"))
+	s.mvccStore = mocktikv.NewMvccStore()
+	client := mocktikv.NewRPCClient(cluster, s.mvccStore)
+	pdCli := &codecPDClient{mocktikv.NewPDClient(cluster)}
+	store, err := newTikvStore("mock-tikv-store", pdCli, client, false)
+	c.Assert(err, IsNil)
+	s.store = store
+}
+
+func (s *testPessimisticSuite) beginPessimistic(c *C) *tikvTxn {
+	txn, err := s.store.Begin()
+	c.Assert(err, IsNil)
+	txn.SetOption(kv.Pessimistic, true)
+	c.Assert(txn.(*tikvTxn).IsPessimistic(), IsTrue)
+	return txn.(*tikvTxn)
+}
+
+func (s *testPessimisticSuite) TestPessimisticHeartBeat(c *C) {
+	defer func(ttl uint64, interval time.Duration) {
+		pessimisticLockTTL, pessimisticHeartBeatInterval = ttl, interval
+	}(pessimisticLockTTL, pessimisticHeartBeatInterval)
+	pessimisticLockTTL, pessimisticHeartBeatInterval = 300, 50*time.Millisecond
+
+	txn := s.beginPessimistic(c)
+	txn.StartStmt(txn.startTS)
+	c.Assert(txn.Set(kv.Key("a"), []byte("a")), IsNil)
+	c.Assert(txn.Set(kv.Key("c"), []byte("c")), IsNil)
+	c.Assert(txn.FinishStmt(true), IsNil)
+	c.Assert(txn.primaryLock, DeepEquals, []byte("a"))
+	time.Sleep(time.Second)
+
+	// The TTL of the primary lock is extended while the transaction is open.
+	ttl, err := s.mvccStore.TxnHeartBeat([]byte("a"), txn.startTS, 0)
+	c.Assert(err, IsNil)
+	c.Assert(ttl, Greater, uint64(1000))
+	c.Assert(txn.Commit(), IsNil)
+	c.Assert(txn.stopHeartBeat, IsNil)
+
+	// The heartbeats stop when the transaction rolls back.
+	txn = s.beginPessimistic(c)
+	txn.StartStmt(txn.startTS)
+	c.Assert(txn.Set(kv.Key("a"), []byte("b")), IsNil)
+	c.Assert(txn.FinishStmt(true), IsNil)
+	c.Assert(txn.stopHeartBeat, NotNil)
+	c.Assert(txn.Rollback(), IsNil)
+	c.Assert(txn.stopHeartBeat, IsNil)
+	_, err = s.mvccStore.TxnHeartBeat([]byte("a"), txn.startTS, 0)
+	c.Assert(err, NotNil)
+}
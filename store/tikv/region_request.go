@@ -21,6 +21,7 @@ import (
 	"github.com/pingcap/kvproto/pkg/coprocessor"
 	"github.com/pingcap/kvproto/pkg/errorpb"
 	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/pingcap/tidb/store/tikv/tikvrpc"
 	goctx "golang.org/x/net/context"
 )
 
@@ -44,6 +45,9 @@ type RegionRequestSender struct {
 	regionCache *RegionCache
 	client      Client
 	storeAddr   string
+	// rpcError is the last error of sending the request, the request may be applied by the store even if
+	// it fails to be sent.
+	rpcError error
 }
 
 // NewRegionRequestSender creates a new sender.
@@ -136,6 +140,46 @@ func (s *RegionRequestSender) SendCopReq(req *coprocessor.Request, regionID Regi
 	}
 }
 
+// SendExtReq sends a request defined in tikvrpc to tikv server.
+func (s *RegionRequestSender) SendExtReq(req *tikvrpc.Request, regionID RegionVerID, timeout time.Duration) (*tikvrpc.Response, error) {
+	client, ok := s.client.(extClient)
+	if !ok {
+		return nil, errors.Trace(errExtReqNotSupported)
+	}
+	for {
+		ctx, err := s.regionCache.GetRPCContext(s.bo, regionID)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if ctx == nil {
+			return &tikvrpc.Response{
+				Type:        req.Type,
+				RegionError: &errorpb.Error{StaleEpoch: &errorpb.StaleEpoch{}},
+			}, nil
+		}
+
+		req.Context = ctx.KVCtx
+		resp, err := client.SendExtReq(ctx.Context, ctx.Addr, req, timeout)
+		if err != nil {
+			if e := s.onSendFail(ctx, err); e != nil {
+				return nil, errors.Trace(e)
+			}
+			continue
+		}
+
+		if regionErr := resp.RegionError; regionErr != nil {
+			retry, err := s.onRegionError(ctx, regionErr)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if retry {
+				continue
+			}
+		}
+		return resp, nil
+	}
+}
+
 func (s *RegionRequestSender) sendKVReqToRegion(ctx *RPCContext, req *kvrpcpb.Request, timeout time.Duration) (resp *kvrpcpb.Response, retry bool, err error) {
 	req.Context = ctx.KVCtx
 	resp, err = s.client.SendKVReq(ctx.Context, ctx.Addr, req, timeout)
@@ -161,6 +205,7 @@ func (s *RegionRequestSender) sendCopReqToRegion(ctx *RPCContext, req *coprocess
 }
 
 func (s *RegionRequestSender) onSendFail(ctx *RPCContext, err error) error {
+	s.rpcError = err
 	s.regionCache.OnRequestFail(ctx)
 
 	// Retry on request failure when it's not Cancelled.
@@ -0,0 +1,153 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tikvrpc defines the requests of pessimistic transactions, one-phase commit and large transactions. They are not in
+// kvproto yet, so they are only served by the stores which are linked in the process, like mock-tikv.
+package tikvrpc
+
+import (
+	"github.com/pingcap/kvproto/pkg/errorpb"
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+)
+
+// CmdType is the type of a request.
+type CmdType int
+
+// The types of the requests.
+const (
+	CmdPessimisticLock CmdType = iota + 1
+	CmdPessimisticRollback
+	CmdOnePC
+	CmdTxnHeartBeat
+)
+
+func (t CmdType) String() string {
+	switch t {
+	case CmdPessimisticLock:
+		return "PessimisticLock"
+	case CmdPessimisticRollback:
+		return "PessimisticRollback"
+	case CmdOnePC:
+		return "OnePC"
+	case CmdTxnHeartBeat:
+		return "TxnHeartBeat"
+	}
+	return "Unknown"
+}
+
+// Request is a request sent to a region.
+type Request struct {
+	Type                   CmdType
+	Context                *kvrpcpb.Context
+	PessimisticLockReq     *PessimisticLockRequest
+	PessimisticRollbackReq *PessimisticRollbackRequest
+	OnePCReq               *OnePCRequest
+	TxnHeartBeatReq        *TxnHeartBeatRequest
+}
+
+// Response is the response of a request.
+type Response struct {
+	Type                    CmdType
+	RegionError             *errorpb.Error
+	PessimisticLockResp     *PessimisticLockResponse
+	PessimisticRollbackResp *PessimisticRollbackResponse
+	OnePCResp               *OnePCResponse
+	TxnHeartBeatResp        *TxnHeartBeatResponse
+}
+
+// PessimisticLockRequest locks the keys for a pessimistic transaction. The keys are either all locked
+// or none of them is locked.
+type PessimisticLockRequest struct {
+	Keys         [][]byte
+	PrimaryLock  []byte
+	StartVersion uint64
+	// ForUpdateTS is the timestamp the statement reads the keys at, the request fails with a write
+	// conflict if a key is committed after it.
+	ForUpdateTS uint64
+	LockTTL     uint64
+	// WaitLock is true if the transaction waits for the locks of other transactions, the store detects the
+	// deadlocks of the waiting transactions.
+	WaitLock bool
+}
+
+// PessimisticLockResponse is the response of PessimisticLockRequest, at most one of the errors is set.
+type PessimisticLockResponse struct {
+	// Locked is the lock of another transaction on a key.
+	Locked *kvrpcpb.LockInfo
+	// Conflict is set if a key is committed after ForUpdateTS.
+	Conflict *WriteConflict
+	// Deadlock is set if waiting for Locked causes a deadlock.
+	Deadlock *Deadlock
+	// Abort is set if the transaction can't go on, e.g. its locks are rolled back by others.
+	Abort string
+}
+
+// WriteConflict describes a key which is committed after the ForUpdateTS of a request.
+type WriteConflict struct {
+	Key        []byte
+	ConflictTS uint64
+}
+
+// Deadlock describes the lock which a transaction can't wait for.
+type Deadlock struct {
+	LockKey []byte
+	LockTS  uint64
+}
+
+// PessimisticRollbackRequest releases the pessimistic locks of a transaction which are not prewritten.
+type PessimisticRollbackRequest struct {
+	Keys         [][]byte
+	StartVersion uint64
+}
+
+// PessimisticRollbackResponse is the response of PessimisticRollbackRequest.
+type PessimisticRollbackResponse struct {
+	Error *kvrpcpb.KeyError
+}
+
+// OnePCRequest prewrites and commits the mutations of a transaction at once, all the mutations must be
+// in the region. The mutations are either all committed or none of them is.
+type OnePCRequest struct {
+	Mutations           []*kvrpcpb.Mutation
+	PrimaryLock         []byte
+	StartVersion        uint64
+	CommitVersion       uint64
+	SkipConstraintCheck bool
+}
+
+// OnePCResponse is the response of OnePCRequest.
+type OnePCResponse struct {
+	// Errors are the errors of the mutations like the errors of prewrite.
+	Errors []*kvrpcpb.KeyError
+	// CommitTSExpired is set if the keys may be read at CommitVersion or a newer timestamp, the transaction
+	// should fall back to 2PC.
+	CommitTSExpired bool
+}
+
+// TxnHeartBeatRequest extends the TTL of the primary lock of a transaction, so the transaction isn't rolled
+// back by the readers while it's running. The TTL is never shortened, so the request with a zero
+// AdviseLockTTL just returns the TTL.
+type TxnHeartBeatRequest struct {
+	PrimaryLock  []byte
+	StartVersion uint64
+	// AdviseLockTTL is the TTL in milliseconds since StartVersion.
+	AdviseLockTTL uint64
+}
+
+// TxnHeartBeatResponse is the response of TxnHeartBeatRequest.
+type TxnHeartBeatResponse struct {
+	// LockTTL is the TTL of the primary lock after the request.
+	LockTTL uint64
+	// Error is set if the primary lock doesn't exist, the transaction is committed or rolled back then.
+	Error *kvrpcpb.KeyError
+}
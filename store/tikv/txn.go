@@ -15,6 +15,7 @@ package tikv
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/coreos/etcd/pkg/monotime"
@@ -26,7 +27,8 @@ import (
 )
 
 var (
-	_ kv.Transaction = (*tikvTxn)(nil)
+	_ kv.Transaction    = (*tikvTxn)(nil)
+	_ kv.PessimisticTxn = (*tikvTxn)(nil)
 )
 
 // tikvTxn implements kv.Transaction.
@@ -39,6 +41,24 @@ type tikvTxn struct {
 	valid     bool
 	lockKeys  [][]byte
 	dirty     bool
+
+	// The fields below are used by the pessimistic mode.
+	pessimistic     bool
+	lockWaitTimeout time.Duration
+	// snapshot is the snapshot of us, it reads at the forUpdateTS of the running statement.
+	snapshot *tikvSnapshot
+	stmt     *pessimisticStmt
+	// primaryLock is the first key locked pessimistically, it's the primary key of 2PC.
+	primaryLock []byte
+	// lockedKeys are the keys locked pessimistically.
+	lockedKeys [][]byte
+	lockedSet  map[string]struct{}
+	// stopHeartBeat is closed when the transaction finishes, it's nil if the primary lock isn't locked.
+	stopHeartBeat chan struct{}
+	heartBeatWg   sync.WaitGroup
+
+	// large prewrites the mutations in the background after the write buffer spills, it's nil before.
+	large *largeTxn
 }
 
 func newTiKVTxn(store *tikvStore) (*tikvTxn, error) {
@@ -47,26 +67,36 @@ func newTiKVTxn(store *tikvStore) (*tikvTxn, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	ver := kv.NewVersion(startTS)
-	return &tikvTxn{
-		us:        kv.NewUnionStore(newTiKVSnapshot(store, ver)),
-		store:     store,
-		startTS:   startTS,
-		startTime: monotime.Now(),
-		valid:     true,
-	}, nil
+	return newTikvTxnWithStartTS(store, startTS)
 }
 
 // newTikvTxnWithStartTS creates a txn with startTS.
 func newTikvTxnWithStartTS(store *tikvStore, startTS uint64) (*tikvTxn, error) {
-	ver := kv.NewVersion(startTS)
-	return &tikvTxn{
-		us:        kv.NewUnionStore(newTiKVSnapshot(store, ver)),
+	snapshot := newTiKVSnapshot(store, kv.NewVersion(startTS))
+	txn := &tikvTxn{
+		us:        kv.NewUnionStore(snapshot),
 		store:     store,
 		startTS:   startTS,
 		startTime: monotime.Now(),
 		valid:     true,
-	}, nil
+		snapshot:  snapshot,
+		lockedSet: make(map[string]struct{}),
+	}
+	// Only the stores serving the requests of tikvrpc can prewrite the spilled mutations in the background,
+	// the transactions are committed from memory otherwise.
+	if store.supportExtReq() {
+		txn.us.SetOption(kv.OnSpill, kv.SpillFunc(txn.onSpill))
+	}
+	return txn, nil
+}
+
+// buffer returns the buffer which the reads and writes go through, it's the buffer of the running statement
+// of a pessimistic transaction, or the union store.
+func (txn *tikvTxn) buffer() kv.MemBuffer {
+	if txn.stmt != nil {
+		return txn.stmt.buf
+	}
+	return txn.us
 }
 
 // Implement transaction interface.
@@ -75,7 +105,7 @@ func (txn *tikvTxn) Get(k kv.Key) ([]byte, error) {
 	start := time.Now()
 	defer func() { txnCmdHistogram.WithLabelValues("get").Observe(time.Since(start).Seconds()) }()
 
-	ret, err := txn.us.Get(k)
+	ret, err := txn.buffer().Get(k)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -86,7 +116,7 @@ func (txn *tikvTxn) Set(k kv.Key, v []byte) error {
 	txnCmdCounter.WithLabelValues("set").Inc()
 
 	txn.dirty = true
-	return txn.us.Set(k, v)
+	return txn.buffer().Set(k, v)
 }
 
 func (txn *tikvTxn) String() string {
@@ -98,7 +128,7 @@ func (txn *tikvTxn) Seek(k kv.Key) (kv.Iterator, error) {
 	start := time.Now()
 	defer func() { txnCmdHistogram.WithLabelValues("seek").Observe(time.Since(start).Seconds()) }()
 
-	return txn.us.Seek(k)
+	return txn.buffer().Seek(k)
 }
 
 // SeekReverse creates a reversed Iterator positioned on the first entry which key is less than k.
@@ -107,17 +137,35 @@ func (txn *tikvTxn) SeekReverse(k kv.Key) (kv.Iterator, error) {
 	start := time.Now()
 	defer func() { txnCmdHistogram.WithLabelValues("seek_reverse").Observe(time.Since(start).Seconds()) }()
 
-	return txn.us.SeekReverse(k)
+	return txn.buffer().SeekReverse(k)
 }
 
 func (txn *tikvTxn) Delete(k kv.Key) error {
 	txnCmdCounter.WithLabelValues("delete").Inc()
 
 	txn.dirty = true
-	return txn.us.Delete(k)
+	return txn.buffer().Delete(k)
 }
 
 func (txn *tikvTxn) SetOption(opt kv.Option, val interface{}) {
+	switch opt {
+	case kv.Pessimistic:
+		txn.pessimistic = val.(bool) && txn.store.supportExtReq()
+		// The pessimistic transactions are committed from memory.
+		if txn.pessimistic {
+			txn.us.DelOption(kv.OnSpill)
+		}
+		return
+	case kv.LockWaitTimeout:
+		txn.lockWaitTimeout = val.(time.Duration)
+		return
+	case kv.PresumeKeyNotExists:
+		// The keys must be read at the forUpdateTS to check if they exist, the lazy check at commit reads
+		// at the startTS.
+		if txn.pessimistic {
+			return
+		}
+	}
 	txn.us.SetOption(opt, val)
 }
 
@@ -138,13 +186,16 @@ func (txn *tikvTxn) Commit() error {
 	if err := txn.us.CheckLazyConditionPairs(); err != nil {
 		return errors.Trace(err)
 	}
+	if txn.large != nil {
+		return errors.Trace(txn.commitLarge())
+	}
 
 	committer, err := newTwoPhaseCommitter(txn)
 	if err != nil {
 		return errors.Trace(err)
 	}
 	if committer == nil {
-		return nil
+		return errors.Trace(txn.pessimisticRollback())
 	}
 	err = committer.execute()
 	if err != nil {
@@ -157,6 +208,8 @@ func (txn *tikvTxn) Commit() error {
 }
 
 func (txn *tikvTxn) close() error {
+	txn.stopHeartBeats()
+	txn.us.Release()
 	txn.valid = false
 	return nil
 }
@@ -165,15 +218,25 @@ func (txn *tikvTxn) Rollback() error {
 	if !txn.valid {
 		return kv.ErrInvalidTxn
 	}
+	if txn.large != nil {
+		txn.large.rollback()
+	}
 	txn.close()
 	log.Infof("[kv] Rollback txn %d", txn.StartTS())
 	txnCmdCounter.WithLabelValues("rollback").Inc()
 
-	return nil
+	return errors.Trace(txn.pessimisticRollback())
 }
 
 func (txn *tikvTxn) LockKeys(keys ...kv.Key) error {
 	txnCmdCounter.WithLabelValues("lock_keys").Inc()
+	if txn.stmt != nil {
+		// The keys are locked pessimistically when the statement finishes.
+		for _, key := range keys {
+			txn.stmt.lockKeys = append(txn.stmt.lockKeys, key)
+		}
+		return nil
+	}
 	for _, key := range keys {
 		txn.lockKeys = append(txn.lockKeys, key)
 	}
@@ -193,9 +256,15 @@ func (txn *tikvTxn) Valid() bool {
 }
 
 func (txn *tikvTxn) Len() int {
+	if txn.stmt != nil {
+		return txn.us.Len() + txn.stmt.buf.Len()
+	}
 	return txn.us.Len()
 }
 
 func (txn *tikvTxn) Size() int {
+	if txn.stmt != nil {
+		return txn.us.Size() + txn.stmt.buf.Size()
+	}
 	return txn.us.Size()
 }
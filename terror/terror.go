@@ -72,6 +72,7 @@ const (
 	ClassTypes
 	ClassGlobal
 	ClassMockTikv
+	ClassBindInfo
 	// Add more as needed.
 )
 
@@ -118,6 +119,8 @@ func (ec ErrClass) String() string {
 		return "global"
 	case ClassMockTikv:
 		return "mocktikv"
+	case ClassBindInfo:
+		return "bindinfo"
 	}
 	return strconv.Itoa(int(ec))
 }
@@ -20,6 +20,8 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -27,6 +29,7 @@ import (
 	"github.com/ngaut/log"
 	"github.com/ngaut/systimemon"
 	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/ddl"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/perfschema"
@@ -34,9 +37,14 @@ import (
 	"github.com/pingcap/tidb/privilege/privileges"
 	"github.com/pingcap/tidb/server"
 	"github.com/pingcap/tidb/sessionctx/binloginfo"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/store/localstore/boltdb"
+	"github.com/pingcap/tidb/store/localstore/encrypted"
+	"github.com/pingcap/tidb/store/localstore/goleveldb"
 	"github.com/pingcap/tidb/store/tikv"
+	"github.com/pingcap/tidb/util/audit"
 	"github.com/pingcap/tidb/util/printer"
+	"github.com/pingcap/tidb/util/slowlog"
 	"github.com/pingcap/tipb/go-binlog"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/push"
@@ -45,26 +53,36 @@ import (
 
 var (
 	version         = flag.Bool("V", false, "print version information and exit")
+	configPath      = flag.String("config", "", "path of the TOML configuration file, the command line flags take precedence over it")
 	store           = flag.String("store", "goleveldb", "registered store name, [memory, goleveldb, boltdb, tikv]")
 	storePath       = flag.String("path", "/tmp/tidb", "tidb storage path")
 	logLevel        = flag.String("L", "info", "log level: info, debug, warn, error, fatal")
 	host            = flag.String("host", "0.0.0.0", "tidb server host")
-	port            = flag.String("P", "4000", "tidb server port")
-	statusPort      = flag.String("status", "10080", "tidb server status port")
+	port            = flag.Uint("P", 4000, "tidb server port")
+	statusPort      = flag.Uint("status", 10080, "tidb server status port")
 	lease           = flag.String("lease", "1s", "schema lease duration, very dangerous to change only if you know what you do")
 	socket          = flag.String("socket", "", "The socket file to use for connection.")
 	enablePS        = flag.Bool("perfschema", false, "If enable performance schema.")
-	enablePrivilege = flag.Bool("privilege", false, "If enable privilege check feature. This flag will be removed in the future.")
 	reportStatus    = flag.Bool("report-status", true, "If enable status report HTTP service.")
 	logFile         = flag.String("log-file", "", "log file path")
-	joinCon         = flag.Int("join-concurrency", 5, "the number of goroutines that participate joining.")
+	joinCon         = flag.Uint("join-concurrency", 5, "the number of goroutines that participate joining.")
 	crossJoin       = flag.Bool("cross-join", true, "whether support cartesian product or not.")
 	metricsAddr     = flag.String("metrics-addr", "", "prometheus pushgateway address, leaves it empty will disable prometheus push.")
-	metricsInterval = flag.Int("metrics-interval", 15, "prometheus client push interval in second, set \"0\" to disable prometheus push.")
+	metricsInterval = flag.Uint("metrics-interval", 15, "prometheus client push interval in second, set \"0\" to disable prometheus push.")
 	binlogSocket    = flag.String("binlog-socket", "", "socket file to write binlog")
 	runDDL          = flag.Bool("run-ddl", true, "run ddl worker on this tidb-server")
-	retryLimit      = flag.Int("retry-limit", 10, "the maximum number of retries when commit a transaction")
+	retryLimit      = flag.Uint("retry-limit", 10, "the maximum number of retries when commit a transaction")
 	skipGrantTable  = flag.Bool("skip-grant-table", false, "This option causes the server to start without using the privilege system at all.")
+	planCache       = flag.Bool("plan-cache", false, "whether to cache the plans of prepared statements.")
+	planCacheSize   = flag.Uint("plan-cache-capacity", 100, "the number of cached plans of prepared statements in every session.")
+	sslCA           = flag.String("ssl-ca", "", "path of the file that contains the list of trusted SSL CAs, which verifies the client certificates.")
+	sslCert         = flag.String("ssl-cert", "", "path of the file that contains the X509 certificate in PEM format, TLS is enabled if it is set with ssl-key.")
+	sslKey          = flag.String("ssl-key", "", "path of the file that contains the X509 key in PEM format, TLS is enabled if it is set with ssl-cert.")
+
+	// cfg is the configuration of the config file and the command line flags, cfgLock protects it
+	// when the reloadable settings are reloaded.
+	cfg     *config.Config
+	cfgLock sync.Mutex
 
 	timeJumpBackCounter = prometheus.NewCounter(
 		prometheus.CounterOpts{
@@ -86,57 +104,89 @@ func main() {
 		printer.PrintRawTiDBInfo()
 		os.Exit(0)
 	}
-	if *skipGrantTable && !hasRootPrivilege() {
+	cfg = loadConfig()
+	if cfg.Security.SkipGrantTable && !hasRootPrivilege() {
 		log.Error("TiDB run with skip-grant-table need root privilege.")
 		os.Exit(-1)
 	}
 
-	leaseDuration := parseLease()
+	leaseDuration, err := cfg.Storage.LeaseDuration()
+	if err != nil {
+		log.Fatal(errors.ErrorStack(err))
+	}
 	tidb.SetSchemaLease(leaseDuration)
-	ddl.RunWorker = *runDDL
-	tidb.SetCommitRetryLimit(*retryLimit)
+	ddl.RunWorker = cfg.Server.RunDDL
+	tidb.SetCommitRetryLimit(int(cfg.Performance.RetryLimit))
 
-	cfg := &server.Config{
-		Addr:         fmt.Sprintf("%s:%s", *host, *port),
-		LogLevel:     *logLevel,
-		StatusAddr:   fmt.Sprintf(":%s", *statusPort),
-		Socket:       *socket,
-		ReportStatus: *reportStatus,
-		Store:        *store,
-		StorePath:    *storePath,
+	svrCfg := &server.Config{
+		Addr:                  fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
+		LogLevel:              cfg.Log.Level,
+		StatusAddr:            fmt.Sprintf(":%d", cfg.Status.Port),
+		Socket:                cfg.Server.Socket,
+		ReportStatus:          cfg.Status.ReportStatus,
+		Store:                 cfg.Storage.Store,
+		StorePath:             cfg.Storage.Path,
+		SSLCA:                 cfg.Security.SSLCA,
+		SSLCert:               cfg.Security.SSLCert,
+		SSLKey:                cfg.Security.SSLKey,
+		TokenLimit:            cfg.Performance.TokenLimit,
+		ProxyProtocolNetworks: cfg.Server.ProxyProtocolNetworks,
 	}
 
 	// set log options
-	if len(*logFile) > 0 {
-		err := log.SetOutputByName(*logFile)
+	if len(cfg.Log.File) > 0 {
+		err = log.SetOutputByName(cfg.Log.File)
 		if err != nil {
 			log.Fatal(errors.ErrorStack(err))
 		}
 		log.SetRotateByDay()
 		log.SetHighlighting(false)
 	}
+	if err = slowlog.SetFile(cfg.Log.SlowQueryFile); err != nil {
+		log.Fatal(errors.ErrorStack(err))
+	}
+	err = audit.Setup(audit.Config{
+		File:       cfg.Audit.File,
+		MaxSize:    int64(cfg.Audit.MaxSize) * 1024 * 1024,
+		MaxBackups: int(cfg.Audit.MaxBackups),
+		FullSQL:    cfg.Audit.FullSQL,
+	})
+	if err != nil {
+		log.Fatal(errors.ErrorStack(err))
+	}
 
-	if joinCon != nil && *joinCon > 0 {
-		plan.JoinConcurrency = *joinCon
+	if cfg.Performance.JoinConcurrency > 0 {
+		plan.JoinConcurrency = int(cfg.Performance.JoinConcurrency)
+	}
+	plan.AllowCartesianProduct = cfg.Performance.CrossJoin
+	plan.PreparedPlanCacheEnabled = cfg.Performance.PlanCache
+	if cfg.Performance.PlanCacheCapacity > 0 {
+		plan.PreparedPlanCacheCapacity = cfg.Performance.PlanCacheCapacity
 	}
-	plan.AllowCartesianProduct = *crossJoin
+	variable.SetDefaultSlowLogThreshold(uint64(cfg.Log.SlowThreshold))
+	atomic.StoreUint64(&kv.TxnMemBufferQuota, cfg.Performance.TxnMemQuota)
+	atomic.StoreUint64(&kv.TxnEntryCountLimit, cfg.Performance.TxnEntryCountLimit)
+	atomic.StoreUint64(&kv.TxnTotalSizeLimit, cfg.Performance.TxnTotalSizeLimit)
+	atomic.StoreUint64(&kv.LargeTxnEntryCountLimit, cfg.Performance.LargeTxnEntryCountLimit)
+	atomic.StoreUint64(&kv.LargeTxnTotalSizeLimit, cfg.Performance.LargeTxnTotalSizeLimit)
+	kv.TxnSpillDir = cfg.Performance.TxnSpillDir
 	// Call this before setting log level to make sure that TiDB info could be printed.
 	printer.PrintTiDBInfo()
-	log.SetLevelByString(cfg.LogLevel)
+	log.SetLevelByString(cfg.Log.Level)
+	log.Infof("Config:\n%s", cfg)
 
 	store := createStore()
 
-	if *enablePS {
+	if cfg.Server.PerfSchema {
 		perfschema.EnablePerfSchema()
 	}
-	privileges.Enable = *enablePrivilege
-	privileges.SkipWithGrant = *skipGrantTable
-	if *binlogSocket != "" {
+	privileges.SkipWithGrant = cfg.Security.SkipGrantTable
+	if cfg.Binlog.Socket != "" {
 		createBinlogClient()
 	}
 
 	// Bootstrap a session to load information schema.
-	_, err := tidb.BootstrapSession(store)
+	dom, err := tidb.BootstrapSession(store)
 	if err != nil {
 		log.Fatal(errors.ErrorStack(err))
 	}
@@ -144,7 +194,24 @@ func main() {
 	var driver server.IDriver
 	driver = server.NewTiDBDriver(store)
 	var svr *server.Server
-	svr, err = server.NewServer(cfg, driver)
+	svr, err = server.NewServer(svrCfg, driver)
+	if err != nil {
+		log.Fatal(errors.ErrorStack(err))
+	}
+	// The server ID makes the connection IDs unique in the cluster, so KILL works for the connections
+	// of the other servers.
+	serverID, err := dom.AcquireServerID(svr, svr.SetServerID)
+	if err != nil {
+		log.Fatal(errors.ErrorStack(err))
+	}
+	svr.SetServerID(serverID)
+	if *configPath != "" {
+		svr.SetConfigReloader(func() error {
+			return reloadConfig(svr)
+		})
+	}
+
+	shutdownTimeout, err := cfg.Server.GracefulShutdownDuration()
 	if err != nil {
 		log.Fatal(errors.ErrorStack(err))
 	}
@@ -156,11 +223,27 @@ func main() {
 		syscall.SIGTERM,
 		syscall.SIGQUIT)
 
+	// exited is closed when the server is drained and the store is closed.
+	exited := make(chan struct{})
 	go func() {
-		sig := <-sc
-		log.Infof("Got signal [%d] to exit.", sig)
-		svr.Close()
-		os.Exit(0)
+		for {
+			sig := <-sc
+			if sig == syscall.SIGHUP {
+				log.Infof("Got signal [%d] to reload config.", sig)
+				if err := reloadConfig(svr); err != nil {
+					log.Errorf("reload config error: %v", errors.ErrorStack(err))
+				}
+				continue
+			}
+			log.Infof("Got signal [%d] to exit.", sig)
+			svr.GracefulShutdown(shutdownTimeout)
+			dom.Close()
+			if err := store.Close(); err != nil {
+				log.Errorf("close store error: %v", errors.ErrorStack(err))
+			}
+			close(exited)
+			return
+		}
 	}()
 
 	prometheus.MustRegister(timeJumpBackCounter)
@@ -168,13 +251,22 @@ func main() {
 		timeJumpBackCounter.Inc()
 	})
 
-	pushMetric(*metricsAddr, time.Duration(*metricsInterval)*time.Second)
+	pushMetric(cfg.Status.MetricsAddr, time.Duration(cfg.Status.MetricsInterval)*time.Second)
 
-	log.Error(svr.Run())
+	if err := svr.Run(); err != nil {
+		log.Error(errors.ErrorStack(err))
+		return
+	}
+	// Run returns when the listener is closed on shutdown, wait for the connections to be drained.
+	<-exited
 }
 
 func createStore() kv.Storage {
-	fullPath := fmt.Sprintf("%s://%s", *store, *storePath)
+	name := cfg.Storage.Store
+	if cfg.Storage.EncryptionKeyFile != "" {
+		name = registerEncryptedStore()
+	}
+	fullPath := fmt.Sprintf("%s://%s", name, cfg.Storage.Path)
 	store, err := tidb.NewStore(fullPath)
 	if err != nil {
 		log.Fatal(errors.ErrorStack(err))
@@ -182,16 +274,41 @@ func createStore() kv.Storage {
 	return store
 }
 
+// registerEncryptedStore registers the local store which encrypts the data of the configured store, and returns
+// its name.
+func registerEncryptedStore() string {
+	mode, err := encrypted.ParseKeyMode(cfg.Storage.EncryptionKeyMode)
+	if err != nil {
+		log.Fatal(errors.ErrorStack(err))
+	}
+	d := encrypted.Driver{
+		KeyFile:   cfg.Storage.EncryptionKeyFile,
+		KeyMode:   mode,
+		PrefixLen: int(cfg.Storage.EncryptionKeyPrefixLength),
+	}
+	switch cfg.Storage.Store {
+	case "goleveldb":
+		d.Driver = goleveldb.Driver{}
+	case "boltdb":
+		d.Driver = boltdb.Driver{}
+	}
+	name := "encrypted-" + cfg.Storage.Store
+	if err = tidb.RegisterLocalStore(name, d); err != nil {
+		log.Fatal(errors.ErrorStack(err))
+	}
+	return name
+}
+
 func createBinlogClient() {
 	dialerOpt := grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
 		return net.DialTimeout("unix", addr, timeout)
 	})
-	clientCon, err := grpc.Dial(*binlogSocket, dialerOpt, grpc.WithInsecure())
+	clientCon, err := grpc.Dial(cfg.Binlog.Socket, dialerOpt, grpc.WithInsecure())
 	if err != nil {
 		log.Fatal(errors.ErrorStack(err))
 	}
 	binloginfo.PumpClient = binlog.NewPumpClient(clientCon)
-	log.Infof("created binlog client at %s", *binlogSocket)
+	log.Infof("created binlog client at %s", cfg.Binlog.Socket)
 }
 
 // Prometheus push.
@@ -224,16 +341,136 @@ func prometheusPushClient(addr string, interval time.Duration) {
 	}
 }
 
-// parseLease parses lease argument string.
-func parseLease() time.Duration {
-	dur, err := time.ParseDuration(*lease)
-	if err != nil {
-		dur, err = time.ParseDuration(*lease + "s")
+// loadConfig loads the config file and overrides it with the command line flags, it exits if the
+// configuration is invalid.
+func loadConfig() *config.Config {
+	c := config.NewConfig()
+	if *configPath != "" {
+		if err := c.Load(*configPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 	}
-	if err != nil || dur < 0 {
-		log.Fatalf("invalid lease duration %s", *lease)
+	overrideConfig(c)
+	if err := c.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return c
+}
+
+// overrideConfig overrides the settings with the command line flags which are set explicitly.
+func overrideConfig(c *config.Config) {
+	actualFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		actualFlags[f.Name] = true
+	})
+	if actualFlags["store"] {
+		c.Storage.Store = *store
+	}
+	if actualFlags["path"] {
+		c.Storage.Path = *storePath
+	}
+	if actualFlags["lease"] {
+		c.Storage.Lease = *lease
+	}
+	if actualFlags["L"] {
+		c.Log.Level = *logLevel
+	}
+	if actualFlags["log-file"] {
+		c.Log.File = *logFile
+	}
+	if actualFlags["host"] {
+		c.Server.Host = *host
+	}
+	if actualFlags["P"] {
+		c.Server.Port = *port
+	}
+	if actualFlags["socket"] {
+		c.Server.Socket = *socket
+	}
+	if actualFlags["run-ddl"] {
+		c.Server.RunDDL = *runDDL
+	}
+	if actualFlags["perfschema"] {
+		c.Server.PerfSchema = *enablePS
+	}
+	if actualFlags["status"] {
+		c.Status.Port = *statusPort
+	}
+	if actualFlags["report-status"] {
+		c.Status.ReportStatus = *reportStatus
+	}
+	if actualFlags["metrics-addr"] {
+		c.Status.MetricsAddr = *metricsAddr
+	}
+	if actualFlags["metrics-interval"] {
+		c.Status.MetricsInterval = *metricsInterval
+	}
+	if actualFlags["join-concurrency"] {
+		c.Performance.JoinConcurrency = *joinCon
+	}
+	if actualFlags["cross-join"] {
+		c.Performance.CrossJoin = *crossJoin
+	}
+	if actualFlags["retry-limit"] {
+		c.Performance.RetryLimit = *retryLimit
+	}
+	if actualFlags["plan-cache"] {
+		c.Performance.PlanCache = *planCache
+	}
+	if actualFlags["plan-cache-capacity"] {
+		c.Performance.PlanCacheCapacity = *planCacheSize
+	}
+	if actualFlags["binlog-socket"] {
+		c.Binlog.Socket = *binlogSocket
+	}
+	if actualFlags["skip-grant-table"] {
+		c.Security.SkipGrantTable = *skipGrantTable
+	}
+	if actualFlags["ssl-ca"] {
+		c.Security.SSLCA = *sslCA
+	}
+	if actualFlags["ssl-cert"] {
+		c.Security.SSLCert = *sslCert
+	}
+	if actualFlags["ssl-key"] {
+		c.Security.SSLKey = *sslKey
+	}
+}
+
+// reloadConfig reloads the config file and applies the reloadable settings, the changes of the other
+// settings are ignored until tidb-server restarts.
+func reloadConfig(svr *server.Server) error {
+	nc := config.NewConfig()
+	if err := nc.Load(*configPath); err != nil {
+		return errors.Trace(err)
+	}
+	overrideConfig(nc)
+	if err := nc.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+
+	cfgLock.Lock()
+	defer cfgLock.Unlock()
+	reloaded, ignored := cfg.Reload(nc)
+	if len(ignored) > 0 {
+		log.Warnf("config %v can't be reloaded, restart tidb-server to apply them", ignored)
+	}
+	log.SetLevelByString(cfg.Log.Level)
+	variable.SetDefaultSlowLogThreshold(uint64(cfg.Log.SlowThreshold))
+	atomic.StoreUint64(&kv.TxnMemBufferQuota, cfg.Performance.TxnMemQuota)
+	atomic.StoreUint64(&kv.TxnEntryCountLimit, cfg.Performance.TxnEntryCountLimit)
+	atomic.StoreUint64(&kv.TxnTotalSizeLimit, cfg.Performance.TxnTotalSizeLimit)
+	atomic.StoreUint64(&kv.LargeTxnEntryCountLimit, cfg.Performance.LargeTxnEntryCountLimit)
+	atomic.StoreUint64(&kv.LargeTxnTotalSizeLimit, cfg.Performance.LargeTxnTotalSizeLimit)
+	for _, key := range reloaded {
+		if key == "performance.token-limit" {
+			svr.SetTokenLimit(cfg.Performance.TokenLimit)
+		}
 	}
-	return dur
+	log.Infof("config is reloaded, changed: %v", reloaded)
+	return nil
 }
 
 func hasRootPrivilege() bool {
@@ -35,6 +35,7 @@ import (
 	"github.com/pingcap/tidb/store/localstore"
 	"github.com/pingcap/tidb/store/localstore/engine"
 	"github.com/pingcap/tidb/store/localstore/goleveldb"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -183,7 +184,11 @@ func runStmt(ctx context.Context, s ast.Statement) (ast.RecordSet, error) {
 	rs, err = s.Exec(ctx)
 	// All the history should be added here.
 	getHistory(ctx).add(0, s, se.sessionVars.StmtCtx)
-	if !se.sessionVars.InTxn() {
+	if se.sessionVars.InTxn() && terror.ErrorEqual(err, kv.ErrDeadlock) {
+		// Like InnoDB, the transaction is rolled back to release its locks when it's chosen as the deadlock victim.
+		log.Infof("[%d] RollbackTxn for deadlock.", se.sessionVars.ConnectionID)
+		se.RollbackTxn()
+	} else if !se.sessionVars.InTxn() {
 		if err != nil {
 			log.Info("RollbackTxn for ddl/autocommit error.")
 			se.RollbackTxn()
@@ -191,6 +196,9 @@ func runStmt(ctx context.Context, s ast.Statement) (ast.RecordSet, error) {
 			err = se.CommitTxn()
 		}
 	}
+	if rs == nil {
+		executor.FinishStatement(s, err)
+	}
 	return rs, errors.Trace(err)
 }
 
@@ -0,0 +1,311 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit writes the audit log, which records the connections and the statements of the users.
+//
+// Every event of the log is a JSON object in a single line. For example:
+//
+//	{"time":"2017-06-01T10:00:00.123456+08:00","type":"query","conn_id":1,"user":"root","host":"127.0.0.1","db":"test","class":"DDL","stmt_type":"DropTable","sql":"drop table t","affected_rows":0,"error_code":0,"prev_hash":"9f86d0..."}
+//
+// The events are chained by prev_hash, which is the SHA-256 hash of the previous line of the log, so a line
+// which is modified or removed can be detected by Verify. The chain continues across the rotated files.
+//
+// The connection events and the DDL and DCL statements are logged by default, the other statements are
+// logged only if they match an include rule of mysql.audit_log_filter. The events which match an exclude
+// rule are not logged, except the DCL statements and the statements which change the system tables or
+// reload the filter.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/terror"
+)
+
+// Event types.
+const (
+	TypeConnect    = "connect"
+	TypeDisconnect = "disconnect"
+	TypeAuthFailed = "auth_failed"
+	TypeQuery      = "query"
+)
+
+// Event classes.
+const (
+	ClassConnection = "Connection"
+	ClassDDL        = "DDL"
+	ClassDCL        = "DCL"
+	ClassDML        = "DML"
+	ClassOther      = "Other"
+)
+
+// Event is an audit log event.
+type Event struct {
+	Time   time.Time `json:"time"`
+	Type   string    `json:"type"`
+	ConnID uint64    `json:"conn_id"`
+	User   string    `json:"user"`
+	Host   string    `json:"host"`
+	DB     string    `json:"db"`
+	Class  string    `json:"class"`
+	// StmtType is the type of the statement, e.g. "DropTable", it's empty for the connection events.
+	StmtType     string `json:"stmt_type,omitempty"`
+	SQL          string `json:"sql,omitempty"`
+	AffectedRows uint64 `json:"affected_rows"`
+	ErrCode      uint16 `json:"error_code"`
+	// PrevHash is the hash of the previous line of the log, it's set by Write.
+	PrevHash string `json:"prev_hash"`
+	// System is true if the statement changes the system tables or reloads the filter, the event is
+	// logged even if it matches an exclude rule.
+	System bool `json:"-"`
+}
+
+// Config is the configuration of the audit log.
+type Config struct {
+	// File is the audit log file, the audit log is disabled if it's empty.
+	File string
+	// MaxSize is the size in bytes of the audit log file, the file is rotated when it's exceeded.
+	MaxSize int64
+	// MaxBackups is the number of the rotated files to keep, they are named File.1, File.2 and so on,
+	// File.1 is the latest one.
+	MaxBackups int
+	// FullSQL logs the full SQL text, otherwise the literals in the SQL text are replaced by "?".
+	FullSQL bool
+}
+
+var (
+	mu   sync.Mutex
+	cfg  Config
+	file *os.File
+	size int64
+	// lastHash is the hash of the last line written, the next event is chained to it.
+	lastHash string
+)
+
+// Setup sets the configuration of the audit log and opens the audit log file.
+func Setup(c Config) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		file.Close()
+		file = nil
+	}
+	cfg = c
+	if c.File == "" {
+		return nil
+	}
+	line, err := lastLine(c.File)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	lastHash = ""
+	if len(line) > 0 {
+		lastHash = hashLine(line)
+	}
+	return errors.Trace(openFile())
+}
+
+// lastLineChunk is the size of the chunks in which the last line of a file is read backwards.
+const lastLineChunk = 4096
+
+// lastLine returns the last line of the file without the newline, it's empty if the file doesn't exist.
+func lastLine(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var data []byte
+	for end := info.Size(); end > 0; {
+		start := end - lastLineChunk
+		if start < 0 {
+			start = 0
+		}
+		buf := make([]byte, end-start)
+		if _, err = f.ReadAt(buf, start); err != nil {
+			return nil, errors.Trace(err)
+		}
+		data = append(buf, data...)
+		end = start
+		line := bytes.TrimRight(data, "\n")
+		if i := bytes.LastIndexByte(line, '\n'); i >= 0 {
+			return line[i+1:], nil
+		}
+	}
+	return bytes.TrimRight(data, "\n"), nil
+}
+
+func hashLine(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+func openFile() error {
+	f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return errors.Trace(err)
+	}
+	file, size = f, info.Size()
+	return nil
+}
+
+// Enabled returns true if the audit log is enabled.
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return file != nil
+}
+
+// SQL returns the SQL text of sql which is written to the audit log.
+func SQL(sql string) string {
+	mu.Lock()
+	fullSQL := cfg.FullSQL
+	mu.Unlock()
+	if fullSQL {
+		return sql
+	}
+	return parser.Normalize(sql)
+}
+
+// Write writes the event to the audit log if it passes the filter.
+func Write(e *Event) {
+	if !GetFilter().Match(e) {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if file == nil {
+		return
+	}
+	e.PrevHash = lastHash
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Errorf("marshal audit event error: %v", err)
+		return
+	}
+	hash := hashLine(data)
+	data = append(data, '\n')
+	if size > 0 && cfg.MaxSize > 0 && size+int64(len(data)) > cfg.MaxSize {
+		if err = rotate(); err != nil {
+			log.Errorf("rotate audit log error: %v", errors.ErrorStack(err))
+			if file == nil {
+				return
+			}
+		}
+	}
+	n, err := file.Write(data)
+	size += int64(n)
+	if err != nil {
+		log.Errorf("write audit log error: %v", err)
+		return
+	}
+	lastHash = hash
+}
+
+// Verify checks that every event read from r is chained to the previous line. prevHash is the hash the
+// first event is chained to, it's the prev_hash of the first event if it's empty, e.g. the previous lines
+// are in a rotated file which is removed.
+func Verify(r io.Reader, prevHash string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	for i := 1; scanner.Scan(); i++ {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return errors.Errorf("invalid audit event at line %d: %v", i, err)
+		}
+		if i == 1 && prevHash == "" {
+			prevHash = e.PrevHash
+		}
+		if e.PrevHash != prevHash {
+			return errors.Errorf("audit log is broken at line %d, prev_hash %q != %q", i, e.PrevHash, prevHash)
+		}
+		prevHash = hashLine(scanner.Bytes())
+	}
+	return errors.Trace(scanner.Err())
+}
+
+// rotate renames the audit log file to File.1, shifting the older files, and opens a new file.
+// The file is reopened even if the renaming fails, so the later events are still logged.
+func rotate() error {
+	file.Close()
+	file = nil
+	err := renameFiles()
+	if err1 := openFile(); err1 != nil {
+		return errors.Trace(err1)
+	}
+	return errors.Trace(err)
+}
+
+func renameFiles() error {
+	if cfg.MaxBackups == 0 {
+		return errors.Trace(os.Remove(cfg.File))
+	}
+	for i := cfg.MaxBackups - 1; i > 0; i-- {
+		err := os.Rename(backupName(i), backupName(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(os.Rename(cfg.File, backupName(1)))
+}
+
+func backupName(i int) string {
+	return fmt.Sprintf("%s.%d", cfg.File, i)
+}
+
+// SplitUser splits the user name and the host of "user@host".
+func SplitUser(userHost string) (user, host string) {
+	if i := strings.LastIndex(userHost, "@"); i >= 0 {
+		return userHost[:i], userHost[i+1:]
+	}
+	return userHost, ""
+}
+
+// ErrCode returns the MySQL error code of err, it's 0 if err is nil.
+func ErrCode(err error) uint16 {
+	switch x := errors.Cause(err).(type) {
+	case nil:
+		return 0
+	case *terror.Error:
+		return x.ToSQLError().Code
+	case *mysql.SQLError:
+		return x.Code
+	}
+	return mysql.ErrUnknown
+}
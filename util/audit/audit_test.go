@@ -0,0 +1,163 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/mysql"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testAuditSuite{})
+
+type testAuditSuite struct{}
+
+func readEvents(c *C, path string) []*Event {
+	f, err := os.Open(path)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	var events []*Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		e := &Event{}
+		c.Assert(json.Unmarshal(scanner.Bytes(), e), IsNil)
+		events = append(events, e)
+	}
+	c.Assert(scanner.Err(), IsNil)
+	return events
+}
+
+func verifyFile(c *C, path, prevHash string) error {
+	f, err := os.Open(path)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	return Verify(f, prevHash)
+}
+
+func (s *testAuditSuite) TestFilter(c *C) {
+	f := NewFilter(nil)
+	c.Assert(f.Match(&Event{Class: ClassConnection}), IsTrue)
+	c.Assert(f.Match(&Event{Class: ClassDDL, StmtType: "DropTable"}), IsTrue)
+	c.Assert(f.Match(&Event{Class: ClassDCL, StmtType: "Grant"}), IsTrue)
+	c.Assert(f.Match(&Event{Class: ClassDML, StmtType: "Select"}), IsFalse)
+	c.Assert(f.Match(&Event{Class: ClassOther, StmtType: "Show"}), IsFalse)
+
+	f = NewFilter([]*Rule{
+		NewRule("app%", "%", "dml", false),
+		NewRule("%", "test", "Delete", false),
+		NewRule("monitor", "%", "%", true),
+		NewRule("%", "tmp\\_%", "CreateTable", true),
+	})
+	table := []struct {
+		e      *Event
+		expect bool
+	}{
+		{&Event{User: "app1", DB: "db", Class: ClassDML, StmtType: "Select"}, true},
+		{&Event{User: "root", DB: "db", Class: ClassDML, StmtType: "Select"}, false},
+		{&Event{User: "root", DB: "test", Class: ClassDML, StmtType: "Delete"}, true},
+		{&Event{User: "root", DB: "TEST", Class: ClassDML, StmtType: "Delete"}, true},
+		{&Event{User: "monitor", Class: ClassConnection}, false},
+		{&Event{User: "monitor", DB: "db", Class: ClassDDL, StmtType: "DropTable"}, false},
+		{&Event{User: "root", DB: "tmp_1", Class: ClassDDL, StmtType: "CreateTable"}, false},
+		{&Event{User: "root", DB: "tmpx1", Class: ClassDDL, StmtType: "CreateTable"}, true},
+		{&Event{User: "root", DB: "tmp_1", Class: ClassDDL, StmtType: "DropTable"}, true},
+		// The DCL statements and the changes of the system tables can't be excluded.
+		{&Event{User: "monitor", DB: "db", Class: ClassDCL, StmtType: "Grant"}, true},
+		{&Event{User: "monitor", DB: "db", Class: ClassDML, StmtType: "Delete", System: true}, true},
+		{&Event{User: "monitor", DB: "db", Class: ClassOther, StmtType: "Flush", System: true}, true},
+	}
+	for _, t := range table {
+		c.Assert(f.Match(t.e), Equals, t.expect, Commentf("%+v", t.e))
+	}
+}
+
+func (s *testAuditSuite) TestWrite(c *C) {
+	dir, err := ioutil.TempDir("", "audit")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	defer Setup(Config{})
+
+	c.Assert(Setup(Config{}), IsNil)
+	c.Assert(Enabled(), IsFalse)
+	c.Assert(Setup(Config{File: path}), IsNil)
+	c.Assert(Enabled(), IsTrue)
+	c.Assert(SQL("select * from t where a = 'x'"), Equals, "select * from t where a = ?")
+
+	Write(&Event{Type: TypeConnect, ConnID: 1, User: "root", Host: "127.0.0.1", Class: ClassConnection})
+	Write(&Event{Type: TypeQuery, ConnID: 1, User: "root", Class: ClassDML, StmtType: "Select", SQL: "select ?"})
+	Write(&Event{Type: TypeQuery, ConnID: 1, User: "root", DB: "test", Class: ClassDDL, StmtType: "DropTable",
+		SQL: "drop table t", ErrCode: 1051})
+	events := readEvents(c, path)
+	c.Assert(events, HasLen, 2)
+	c.Assert(events[0].Type, Equals, TypeConnect)
+	c.Assert(events[0].Host, Equals, "127.0.0.1")
+	c.Assert(events[1].StmtType, Equals, "DropTable")
+	c.Assert(events[1].DB, Equals, "test")
+	c.Assert(events[1].ErrCode, Equals, uint16(1051))
+
+	// The events are chained by the hashes of the previous lines, and the chain continues after reopening.
+	c.Assert(Setup(Config{File: path}), IsNil)
+	Write(&Event{Type: TypeDisconnect, ConnID: 1, User: "root", Class: ClassConnection})
+	events = readEvents(c, path)
+	c.Assert(events, HasLen, 3)
+	c.Assert(events[0].PrevHash, Equals, "")
+	c.Assert(events[2].PrevHash, Not(Equals), "")
+	c.Assert(verifyFile(c, path, ""), IsNil)
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	modified := bytes.Replace(data, []byte(`"error_code":1051`), []byte(`"error_code":0`), 1)
+	c.Assert(Verify(bytes.NewReader(modified), ""), ErrorMatches, "audit log is broken at line 3.*")
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	removed := append(append([]byte{}, lines[0]...), lines[2]...)
+	c.Assert(Verify(bytes.NewReader(removed), ""), ErrorMatches, "audit log is broken at line 2.*")
+	c.Assert(os.Remove(path), IsNil)
+
+	// Every event rotates the file.
+	c.Assert(Setup(Config{File: path, MaxSize: 1, MaxBackups: 2}), IsNil)
+	for i := 0; i < 4; i++ {
+		Write(&Event{Type: TypeConnect, ConnID: uint64(i), Class: ClassConnection})
+	}
+	c.Assert(readEvents(c, path)[0].ConnID, Equals, uint64(3))
+	c.Assert(readEvents(c, path+".1")[0].ConnID, Equals, uint64(2))
+	c.Assert(readEvents(c, path+".2")[0].ConnID, Equals, uint64(1))
+	_, err = os.Stat(path + ".3")
+	c.Assert(os.IsNotExist(err), IsTrue)
+	// The first event of a file is chained to the last one of the previous file.
+	line, err := lastLine(path + ".1")
+	c.Assert(err, IsNil)
+	c.Assert(verifyFile(c, path, hashLine(line)), IsNil)
+	c.Assert(verifyFile(c, path, "x"), NotNil)
+}
+
+func (s *testAuditSuite) TestErrCode(c *C) {
+	c.Assert(ErrCode(nil), Equals, uint16(0))
+	err := infoschema.ErrTableNotExists.GenByArgs("test", "t")
+	c.Assert(ErrCode(errors.Trace(err)), Equals, uint16(mysql.ErrNoSuchTable))
+	c.Assert(ErrCode(mysql.NewErr(mysql.ErrAccessDenied, "u", "h", "YES")), Equals, uint16(mysql.ErrAccessDenied))
+	c.Assert(ErrCode(errors.New("x")), Equals, uint16(mysql.ErrUnknown))
+}
@@ -0,0 +1,156 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/sqlexec"
+	"github.com/pingcap/tidb/util/stringutil"
+)
+
+// Rule actions of mysql.audit_log_filter.
+const (
+	ActionInclude = "include"
+	ActionExclude = "exclude"
+)
+
+// Rule is a rule of mysql.audit_log_filter. The patterns can contain the wildcards of LIKE, and they
+// are matched case-insensitively. The class pattern is matched with both the event class and the
+// statement type, e.g. "DML" and "Delete".
+type Rule struct {
+	User    string
+	DB      string
+	Class   string
+	Exclude bool
+
+	user, db, class pattern
+}
+
+type pattern struct {
+	chars, types []byte
+}
+
+func compilePattern(p string) pattern {
+	chars, types := stringutil.CompilePattern(p, '\\')
+	return pattern{chars, types}
+}
+
+func (p pattern) match(s string) bool {
+	return stringutil.DoMatch(s, p.chars, p.types)
+}
+
+// NewRule creates a Rule.
+func NewRule(user, db, class string, exclude bool) *Rule {
+	return &Rule{
+		User:    user,
+		DB:      db,
+		Class:   class,
+		Exclude: exclude,
+		user:    compilePattern(user),
+		db:      compilePattern(db),
+		class:   compilePattern(class),
+	}
+}
+
+func (r *Rule) match(e *Event) bool {
+	if !r.user.match(e.User) || !r.db.match(e.DB) {
+		return false
+	}
+	return r.class.match(e.Class) || (e.StmtType != "" && r.class.match(e.StmtType))
+}
+
+// Filter chooses the events to log by the rules.
+type Filter struct {
+	rules []*Rule
+}
+
+// NewFilter creates a Filter of the rules.
+func NewFilter(rules []*Rule) *Filter {
+	return &Filter{rules: rules}
+}
+
+// Match returns true if the event should be logged. The DCL statements and the statements which change the
+// system tables or reload the filter are always logged, so disabling the audit log is audited. The other
+// events which match an exclude rule are not logged, the rest are logged if they are connection events, DDL
+// statements, or they match an include rule.
+func (f *Filter) Match(e *Event) bool {
+	if e.Class == ClassDCL || e.System {
+		return true
+	}
+	var included bool
+	for _, r := range f.rules {
+		if !r.match(e) {
+			continue
+		}
+		if r.Exclude {
+			return false
+		}
+		included = true
+	}
+	if included {
+		return true
+	}
+	switch e.Class {
+	case ClassConnection, ClassDDL:
+		return true
+	}
+	return false
+}
+
+var filter atomic.Value
+
+func init() {
+	filter.Store(NewFilter(nil))
+}
+
+// GetFilter returns the filter of the audit log.
+func GetFilter() *Filter {
+	return filter.Load().(*Filter)
+}
+
+// SetFilter sets the filter of the audit log.
+func SetFilter(f *Filter) {
+	filter.Store(f)
+}
+
+// LoadFilter loads the rules of mysql.audit_log_filter through ctx, and sets them as the filter of the audit log.
+func LoadFilter(ctx context.Context) error {
+	sql := fmt.Sprintf("SELECT user, db, class, action FROM %s.%s", mysql.SystemDB, mysql.AuditLogFilterTable)
+	tmp, err := ctx.(sqlexec.SQLExecutor).Execute(sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	rs := tmp[0]
+	defer rs.Close()
+
+	var rules []*Rule
+	for {
+		row, err := rs.Next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		exclude := row.Data[3].GetMysqlEnum().String() == ActionExclude
+		rules = append(rules, NewRule(row.Data[0].GetString(), row.Data[1].GetString(), row.Data[2].GetString(), exclude))
+	}
+	SetFilter(NewFilter(rules))
+	return nil
+}
@@ -14,8 +14,13 @@
 package util
 
 import (
+	"bytes"
+	"crypto/rand"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
+	"strconv"
 
 	"github.com/juju/errors"
 )
@@ -66,3 +71,150 @@ func DecodePassword(pwd string) ([]byte, error) {
 	}
 	return x, nil
 }
+
+const (
+	// sha256PasswordPrefix is the prefix of the password hash stored by caching_sha2_password and
+	// sha256_password, followed by the number of rounds in thousands as 3 hex digits.
+	sha256PasswordPrefix = "$A$"
+	sha256SaltLen        = 20
+	sha256DigestLen      = 43
+	sha256DefaultRounds  = 5000
+	// sha256PasswordLen is the length of $A$005$ + salt + digest.
+	sha256PasswordLen = len(sha256PasswordPrefix) + 4 + sha256SaltLen + sha256DigestLen
+	crypt64Chars      = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	saltChars         = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// EncodeSHA256Password converts plaintext password to the hash string stored by caching_sha2_password
+// and sha256_password, which is $A$005$ followed by 20 bytes salt and the SHA256 crypt digest.
+// See https://www.akkadia.org/drepper/SHA-crypt.txt
+func EncodeSHA256Password(pwd string) (string, error) {
+	if len(pwd) == 0 {
+		return "", nil
+	}
+	salt := make([]byte, sha256SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", errors.Trace(err)
+	}
+	for i := range salt {
+		salt[i] = saltChars[int(salt[i])%len(saltChars)]
+	}
+	digest := sha256Crypt([]byte(pwd), salt, sha256DefaultRounds)
+	return fmt.Sprintf("%s%03X$%s%s", sha256PasswordPrefix, sha256DefaultRounds/1000, salt, digest), nil
+}
+
+// CheckSHA256Password checks whether the plaintext password matches the hash string generated by EncodeSHA256Password.
+func CheckSHA256Password(pwhash string, pwd string) (bool, error) {
+	if len(pwhash) == 0 {
+		return len(pwd) == 0, nil
+	}
+	if len(pwhash) != sha256PasswordLen || pwhash[:len(sha256PasswordPrefix)] != sha256PasswordPrefix {
+		return false, errors.Errorf("invalid SHA256 password hash %s", pwhash)
+	}
+	rest := pwhash[len(sha256PasswordPrefix):]
+	rounds, err := strconv.ParseUint(rest[:3], 16, 32)
+	if err != nil || rest[3] != '$' {
+		return false, errors.Errorf("invalid SHA256 password hash %s", pwhash)
+	}
+	salt := []byte(rest[4 : 4+sha256SaltLen])
+	digest := sha256Crypt([]byte(pwd), salt, int(rounds)*1000)
+	return bytes.Equal(digest, []byte(rest[4+sha256SaltLen:])), nil
+}
+
+// sha256Crypt implements the SHA256 crypt algorithm and returns the base64 like encoded digest.
+func sha256Crypt(plaintext, salt []byte, rounds int) []byte {
+	// Digest B = SHA256(password + salt + password).
+	h := sha256.New()
+	h.Write(plaintext)
+	h.Write(salt)
+	h.Write(plaintext)
+	b := h.Sum(nil)
+
+	// Digest A = SHA256(password + salt + B repeated to the length of password + ...).
+	h.Reset()
+	h.Write(plaintext)
+	h.Write(salt)
+	for i := len(plaintext); i > 0; i -= sha256.Size {
+		if i > sha256.Size {
+			h.Write(b)
+		} else {
+			h.Write(b[:i])
+		}
+	}
+	for i := len(plaintext); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			h.Write(b)
+		} else {
+			h.Write(plaintext)
+		}
+	}
+	a := h.Sum(nil)
+
+	// Sequence P is digest DP = SHA256(password repeated) repeated to the length of password.
+	h.Reset()
+	for range plaintext {
+		h.Write(plaintext)
+	}
+	p := repeatBytes(h.Sum(nil), len(plaintext))
+
+	// Sequence S is digest DS = SHA256(salt repeated 16+A[0] times) repeated to the length of salt.
+	h.Reset()
+	for i := 0; i < 16+int(a[0]); i++ {
+		h.Write(salt)
+	}
+	s := repeatBytes(h.Sum(nil), len(salt))
+
+	c := a
+	for i := 0; i < rounds; i++ {
+		h.Reset()
+		if i&1 != 0 {
+			h.Write(p)
+		} else {
+			h.Write(c)
+		}
+		if i%3 != 0 {
+			h.Write(s)
+		}
+		if i%7 != 0 {
+			h.Write(p)
+		}
+		if i&1 != 0 {
+			h.Write(c)
+		} else {
+			h.Write(p)
+		}
+		c = h.Sum(nil)
+	}
+
+	buf := make([]byte, 0, sha256DigestLen)
+	for _, i := range sha256CryptOrder {
+		buf = appendCrypt64(buf, uint(c[i[0]])<<16|uint(c[i[1]])<<8|uint(c[i[2]]), 4)
+	}
+	return appendCrypt64(buf, uint(c[31])<<8|uint(c[30]), 3)
+}
+
+// sha256CryptOrder is the byte order of the final digest when it is encoded.
+var sha256CryptOrder = [][3]int{
+	{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14},
+	{15, 25, 5}, {6, 16, 26}, {27, 7, 17}, {18, 28, 8}, {9, 19, 29},
+}
+
+func repeatBytes(digest []byte, length int) []byte {
+	buf := make([]byte, 0, length)
+	for len(buf) < length {
+		n := length - len(buf)
+		if n > len(digest) {
+			n = len(digest)
+		}
+		buf = append(buf, digest[:n]...)
+	}
+	return buf
+}
+
+func appendCrypt64(buf []byte, w uint, n int) []byte {
+	for ; n > 0; n-- {
+		buf = append(buf, crypt64Chars[w&0x3f])
+		w >>= 6
+	}
+	return buf
+}
@@ -43,3 +43,33 @@ func (s *testAuthSuite) TestCalcPassword(c *C) {
 	checkAuth := []byte{126, 168, 249, 64, 180, 223, 60, 240, 69, 249, 184, 57, 21, 34, 214, 219, 8, 193, 208, 55}
 	c.Assert(CalcPassword(salt, pwd), DeepEquals, checkAuth)
 }
+
+func (s *testAuthSuite) TestSHA256Crypt(c *C) {
+	defer testleak.AfterTest(c)()
+	// The test vector from https://www.akkadia.org/drepper/SHA-crypt.txt
+	digest := sha256Crypt([]byte("Hello world!"), []byte("saltstring"), 5000)
+	c.Assert(string(digest), Equals, "5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5")
+}
+
+func (s *testAuthSuite) TestSHA256Password(c *C) {
+	defer testleak.AfterTest(c)()
+	pwhash, err := EncodeSHA256Password("123")
+	c.Assert(err, IsNil)
+	c.Assert(pwhash, HasLen, sha256PasswordLen)
+	c.Assert(pwhash[:7], Equals, "$A$005$")
+	ok, err := CheckSHA256Password(pwhash, "123")
+	c.Assert(err, IsNil)
+	c.Assert(ok, IsTrue)
+	ok, err = CheckSHA256Password(pwhash, "1234")
+	c.Assert(err, IsNil)
+	c.Assert(ok, IsFalse)
+
+	pwhash, err = EncodeSHA256Password("")
+	c.Assert(err, IsNil)
+	c.Assert(pwhash, Equals, "")
+	ok, err = CheckSHA256Password("", "")
+	c.Assert(err, IsNil)
+	c.Assert(ok, IsTrue)
+	_, err = CheckSHA256Password("$A$005$abc", "123")
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,48 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package execdetails
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ExecDetails contains the execution details of a statement, they are written to the slow query log.
+type ExecDetails struct {
+	ParseTime   time.Duration
+	CompileTime time.Duration
+	CommitTime  time.Duration
+
+	// copTasks and processKeys are updated by the goroutines which fetch the coprocessor results,
+	// so they are accessed atomically.
+	copTasks    int64
+	processKeys int64
+}
+
+// AddCopTask records a finished coprocessor task which returns processKeys rows.
+// TiKV doesn't report the number of the scanned keys, so the returned rows are counted instead.
+func (d *ExecDetails) AddCopTask(processKeys int) {
+	atomic.AddInt64(&d.copTasks, 1)
+	atomic.AddInt64(&d.processKeys, int64(processKeys))
+}
+
+// CopTasks returns the number of the coprocessor tasks.
+func (d *ExecDetails) CopTasks() int64 {
+	return atomic.LoadInt64(&d.copTasks)
+}
+
+// ProcessKeys returns the number of the rows returned by the coprocessor tasks.
+func (d *ExecDetails) ProcessKeys() int64 {
+	return atomic.LoadInt64(&d.processKeys)
+}
@@ -0,0 +1,64 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcutil
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	"github.com/pingcap/tidb/util/sqlexec"
+)
+
+const (
+	// SafePointKey is the variable name of the GC safe point in mysql.tidb.
+	SafePointKey = "tikv_gc_safe_point"
+	// TimeFormat is the format of the times saved in mysql.tidb by the GC worker.
+	TimeFormat = "20060102-15:04:05 -0700 MST"
+)
+
+// SafePointer is implemented by the stores that drop the old versions by themselves instead of the GC worker,
+// e.g. localstore.
+type SafePointer interface {
+	// GCSafePoint returns the timestamp before which the old versions may be dropped.
+	GCSafePoint() uint64
+}
+
+// GetGCSafePoint returns the GC safe point of store. The versions before it may be collected,
+// it returns 0 if GC has never run.
+func GetGCSafePoint(ctx context.Context, store kv.Storage) (uint64, error) {
+	if sp, ok := store.(SafePointer); ok {
+		return sp.GCSafePoint(), nil
+	}
+	exec, ok := ctx.(sqlexec.RestrictedSQLExecutor)
+	if !ok {
+		return 0, nil
+	}
+	sql := fmt.Sprintf(`SELECT variable_value FROM mysql.tidb WHERE variable_name = '%s'`, SafePointKey)
+	rows, _, err := exec.ExecRestrictedSQL(ctx, sql)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	t, err := time.Parse(TimeFormat, rows[0].Data[0].GetString())
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return oracle.ComposeTS(oracle.GetPhysical(t), 0), nil
+}
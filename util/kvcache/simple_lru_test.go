@@ -0,0 +1,198 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvcache
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	CustomVerboseFlag = true
+	TestingT(t)
+}
+
+var _ = Suite(&testLRUCacheSuite{})
+
+type testLRUCacheSuite struct {
+}
+
+type mockCacheKey struct {
+	hash []byte
+	key  int64
+}
+
+func (mk *mockCacheKey) Hash() []byte {
+	if mk.hash != nil {
+		return mk.hash
+	}
+	mk.hash = make([]byte, 8)
+	for i := uint64(0); i < 8; i++ {
+		mk.hash[i] = byte((mk.key >> (i * 8)) & 0xff)
+	}
+	return mk.hash
+}
+
+func newMockHashKey(key int64) *mockCacheKey {
+	return &mockCacheKey{
+		key: key,
+	}
+}
+
+func (s *testLRUCacheSuite) TestPut(c *C) {
+	defer testleak.AfterTest(c)()
+	lru := NewSimpleLRUCache(3)
+	c.Assert(lru.capacity, Equals, uint(3))
+
+	keys := make([]*mockCacheKey, 5)
+	vals := make([]int64, 5)
+
+	for i := 0; i < 5; i++ {
+		keys[i] = newMockHashKey(int64(i))
+		vals[i] = int64(i)
+		lru.Put(keys[i], vals[i])
+	}
+	c.Assert(lru.size, Equals, lru.capacity)
+	c.Assert(lru.Size(), Equals, 3)
+
+	// test for non-existent elements
+	for i := 0; i < 2; i++ {
+		element, exists := lru.elements[string(keys[i].Hash())]
+		c.Assert(exists, IsFalse)
+		c.Assert(element, IsNil)
+	}
+
+	// test for existent elements
+	root := lru.cache.Front()
+	c.Assert(root, NotNil)
+	for i := 4; i >= 2; i-- {
+		entry, ok := root.Value.(*cacheEntry)
+		c.Assert(ok, IsTrue)
+		c.Assert(entry, NotNil)
+
+		key := entry.key
+		value := entry.value
+		c.Assert(key, NotNil)
+		c.Assert(value, NotNil)
+
+		element, exists := lru.elements[string(keys[i].Hash())]
+		c.Assert(exists, IsTrue)
+		c.Assert(element, NotNil)
+		c.Assert(element, Equals, root)
+
+		c.Assert(key, Equals, keys[i])
+		c.Assert(value, Equals, vals[i])
+
+		root = root.Next()
+	}
+	// test for end of double-linked list
+	c.Assert(root, IsNil)
+
+	// putting an existing key updates the value in place
+	lru.Put(keys[2], int64(100))
+	c.Assert(lru.Size(), Equals, 3)
+	value, exists := lru.Get(keys[2])
+	c.Assert(exists, IsTrue)
+	c.Assert(value, Equals, int64(100))
+}
+
+func (s *testLRUCacheSuite) TestGet(c *C) {
+	defer testleak.AfterTest(c)()
+	lru := NewSimpleLRUCache(3)
+
+	keys := make([]*mockCacheKey, 5)
+	vals := make([]int64, 5)
+
+	for i := 0; i < 5; i++ {
+		keys[i] = newMockHashKey(int64(i))
+		vals[i] = int64(i)
+		lru.Put(keys[i], vals[i])
+	}
+
+	// test for non-existent elements
+	for i := 0; i < 2; i++ {
+		value, exists := lru.Get(keys[i])
+		c.Assert(exists, IsFalse)
+		c.Assert(value, IsNil)
+	}
+
+	for i := 2; i < 5; i++ {
+		value, exists := lru.Get(keys[i])
+		c.Assert(exists, IsTrue)
+		c.Assert(value, NotNil)
+		c.Assert(value, Equals, vals[i])
+		c.Assert(lru.Size(), Equals, 3)
+
+		// a successful Get moves the element to the front
+		root := lru.cache.Front()
+		c.Assert(root, NotNil)
+		entry, ok := root.Value.(*cacheEntry)
+		c.Assert(ok, IsTrue)
+		c.Assert(entry.key, Equals, keys[i])
+		c.Assert(entry.value, Equals, vals[i])
+	}
+}
+
+func (s *testLRUCacheSuite) TestDelete(c *C) {
+	defer testleak.AfterTest(c)()
+	lru := NewSimpleLRUCache(3)
+
+	keys := make([]*mockCacheKey, 3)
+	vals := make([]int64, 3)
+
+	for i := 0; i < 3; i++ {
+		keys[i] = newMockHashKey(int64(i))
+		vals[i] = int64(i)
+		lru.Put(keys[i], vals[i])
+	}
+	c.Assert(lru.Size(), Equals, 3)
+
+	lru.Delete(keys[1])
+	value, exists := lru.Get(keys[1])
+	c.Assert(exists, IsFalse)
+	c.Assert(value, IsNil)
+	c.Assert(lru.Size(), Equals, 2)
+
+	// deleting a missing key is a no-op
+	lru.Delete(keys[1])
+	c.Assert(lru.Size(), Equals, 2)
+
+	_, exists = lru.Get(keys[0])
+	c.Assert(exists, IsTrue)
+
+	_, exists = lru.Get(keys[2])
+	c.Assert(exists, IsTrue)
+}
+
+func (s *testLRUCacheSuite) TestDeleteIf(c *C) {
+	defer testleak.AfterTest(c)()
+	lru := NewSimpleLRUCache(5)
+
+	keys := make([]*mockCacheKey, 5)
+	for i := 0; i < 5; i++ {
+		keys[i] = newMockHashKey(int64(i))
+		lru.Put(keys[i], int64(i))
+	}
+	lru.DeleteIf(func(key Key, value Value) bool {
+		return value.(int64)%2 == 0
+	})
+	c.Assert(lru.Size(), Equals, 2)
+	for i := 0; i < 5; i++ {
+		_, exists := lru.Get(keys[i])
+		c.Assert(exists, Equals, i%2 == 1)
+	}
+	c.Assert(lru.cache.Len(), Equals, 2)
+}
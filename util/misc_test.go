@@ -68,3 +68,15 @@ func (s testMiscSuite) TestRunWithRetry(c *C) {
 	c.Assert(err, NotNil)
 	c.Assert(cnt, Equals, 1)
 }
+
+func (s *testMiscSuite) TestConnID(c *C) {
+	defer testleak.AfterTest(c)()
+	connID := EncodeConnID(3, 10)
+	c.Assert(connID, Equals, uint64(3<<LocalConnIDBits|10))
+	c.Assert(connID <= 1<<32-1, IsTrue)
+	c.Assert(ServerIDOfConn(connID), Equals, uint64(3))
+	c.Assert(ServerIDOfConn(10), Equals, uint64(0))
+	connID = EncodeConnID(MaxServerID, MaxLocalConnID+2)
+	c.Assert(ServerIDOfConn(connID), Equals, uint64(MaxServerID))
+	c.Assert(connID&MaxLocalConnID, Equals, uint64(1))
+}
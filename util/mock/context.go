@@ -23,11 +23,14 @@ import (
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/kvcache"
 	goctx "golang.org/x/net/context"
 )
 
 var _ context.Context = (*Context)(nil)
 
+const defaultPlanCacheCapacity = 100
+
 // Context represents mocked context.Context.
 type Context struct {
 	values map[fmt.Stringer]interface{}
@@ -35,6 +38,7 @@ type Context struct {
 	txn         kv.Transaction
 	Store       kv.Storage
 	sessionVars *variable.SessionVars
+	pcache      *kvcache.SimpleLRUCache
 	// Fix data race in ddl test.
 	mux sync.Mutex
 }
@@ -146,6 +150,11 @@ func (c *Context) GetSessionManager() util.SessionManager {
 	return nil
 }
 
+// PreparedPlanCache implements the context.Context interface.
+func (c *Context) PreparedPlanCache() *kvcache.SimpleLRUCache {
+	return c.pcache
+}
+
 // Cancel implements the Session interface.
 func (c *Context) Cancel() {
 }
@@ -160,5 +169,6 @@ func NewContext() *Context {
 	return &Context{
 		values:      make(map[fmt.Stringer]interface{}),
 		sessionVars: variable.NewSessionVars(),
+		pcache:      kvcache.NewSimpleLRUCache(defaultPlanCacheCapacity),
 	}
 }
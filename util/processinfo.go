@@ -35,3 +35,27 @@ type SessionManager interface {
 	ShowProcessList() []ProcessInfo
 	Kill(connectionID uint64, query bool)
 }
+
+// The connection IDs are 32 bits, the same as MySQL. The high ServerIDBits bits are the ID of the
+// tidb-server which the connection belongs to, so the connection IDs are unique in the cluster.
+// The server ID 0 means the server isn't registered in the cluster, its connection IDs are local.
+const (
+	// ServerIDBits is the number of the bits of the server ID in a connection ID.
+	ServerIDBits = 10
+	// LocalConnIDBits is the number of the bits of the connection ID in a server.
+	LocalConnIDBits = 32 - ServerIDBits
+	// MaxServerID is the maximum server ID.
+	MaxServerID = 1<<ServerIDBits - 1
+	// MaxLocalConnID is the maximum connection ID in a server.
+	MaxLocalConnID = 1<<LocalConnIDBits - 1
+)
+
+// EncodeConnID makes the connection ID from the server ID and the connection ID in the server.
+func EncodeConnID(serverID uint64, localConnID uint64) uint64 {
+	return serverID<<LocalConnIDBits | localConnID&MaxLocalConnID
+}
+
+// ServerIDOfConn returns the ID of the server which the connection belongs to.
+func ServerIDOfConn(connID uint64) uint64 {
+	return connID >> LocalConnIDBits & MaxServerID
+}
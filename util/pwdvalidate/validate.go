@@ -0,0 +1,202 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pwdvalidate
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/sessionctx/varsutil"
+	"github.com/pingcap/tidb/util/stringutil"
+)
+
+// Password policy levels, see https://dev.mysql.com/doc/refman/5.7/en/validate-password-options-variables.html
+const (
+	// PolicyLow checks the password length only.
+	PolicyLow = iota
+	// PolicyMedium checks the length and the numeric, lowercase, uppercase and special characters.
+	PolicyMedium
+	// PolicyStrong checks the dictionary file in addition to PolicyMedium.
+	PolicyStrong
+)
+
+// minDictWordLength is the minimum length of the password substrings which are checked against the dictionary.
+const minDictWordLength = 4
+
+// Policy is the password validation policy configured by the validate_password_* system variables.
+type Policy struct {
+	Level            int
+	Length           int
+	MixedCaseCount   int
+	NumberCount      int
+	SpecialCharCount int
+	DictionaryFile   string
+}
+
+// Enabled returns true if the password validation policy is enabled by tidb_validate_password_enable.
+func Enabled(vars *variable.SessionVars) (bool, error) {
+	val, err := varsutil.GetGlobalSystemVar(vars, variable.TiDBValidatePasswordEnable)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return strings.EqualFold(val, "ON") || val == "1", nil
+}
+
+// LoadPolicy loads the password validation policy from the global system variables.
+func LoadPolicy(vars *variable.SessionVars) (*Policy, error) {
+	p := &Policy{}
+	policy, err := varsutil.GetGlobalSystemVar(vars, variable.ValidatePasswordPolicy)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	switch strings.ToUpper(policy) {
+	case "0", "LOW":
+		p.Level = PolicyLow
+	case "1", "MEDIUM":
+		p.Level = PolicyMedium
+	case "2", "STRONG":
+		p.Level = PolicyStrong
+	default:
+		return nil, errors.Errorf("invalid %s value '%s'", variable.ValidatePasswordPolicy, policy)
+	}
+	intVars := []struct {
+		name  string
+		value *int
+	}{
+		{variable.ValidatePasswordLength, &p.Length},
+		{variable.ValidatePasswordMixedCaseCount, &p.MixedCaseCount},
+		{variable.ValidatePasswordNumberCount, &p.NumberCount},
+		{variable.ValidatePasswordSpecialCharCount, &p.SpecialCharCount},
+	}
+	for _, v := range intVars {
+		val, err := varsutil.GetGlobalSystemVar(vars, v.name)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			return nil, errors.Errorf("invalid %s value '%s'", v.name, val)
+		}
+		*v.value = n
+	}
+	p.DictionaryFile, err = varsutil.GetGlobalSystemVar(vars, variable.ValidatePasswordDictionaryFile)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return p, nil
+}
+
+// minLength returns the effective minimum password length, like MySQL, it's never less than
+// the number of the characters required by the other options.
+func (p *Policy) minLength() int {
+	n := p.NumberCount + p.SpecialCharCount + 2*p.MixedCaseCount
+	if p.Length > n {
+		return p.Length
+	}
+	return n
+}
+
+// Validate returns true if the password of user satisfies the policy.
+func (p *Policy) Validate(pwd, user string) (bool, error) {
+	if matchUserName(pwd, user) {
+		return false, nil
+	}
+	return p.satisfies(pwd, p.Level)
+}
+
+// Strength returns the strength of the password from 0 to 100, it's the result of VALIDATE_PASSWORD_STRENGTH.
+// See https://dev.mysql.com/doc/refman/5.7/en/encryption-functions.html#function_validate-password-strength
+func (p *Policy) Strength(pwd, user string) (int, error) {
+	if utf8.RuneCountInString(pwd) < minDictWordLength || matchUserName(pwd, user) {
+		return 0, nil
+	}
+	for level := PolicyLow; level <= PolicyStrong; level++ {
+		ok, err := p.satisfies(pwd, level)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		if !ok {
+			return 25 * (level + 1), nil
+		}
+	}
+	return 100, nil
+}
+
+func (p *Policy) satisfies(pwd string, level int) (bool, error) {
+	if utf8.RuneCountInString(pwd) < p.minLength() {
+		return false, nil
+	}
+	if level == PolicyLow {
+		return true, nil
+	}
+	var lower, upper, number, special int
+	for _, r := range pwd {
+		switch {
+		case unicode.IsLower(r):
+			lower++
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsDigit(r):
+			number++
+		case !unicode.IsLetter(r):
+			special++
+		}
+	}
+	if lower < p.MixedCaseCount || upper < p.MixedCaseCount || number < p.NumberCount || special < p.SpecialCharCount {
+		return false, nil
+	}
+	if level == PolicyMedium || p.DictionaryFile == "" {
+		return true, nil
+	}
+	inDict, err := p.inDictionary(pwd)
+	return !inDict, errors.Trace(err)
+}
+
+// inDictionary returns true if any substring of the password which has at least 4 characters
+// is a word of the dictionary file, the comparison is case-insensitive.
+func (p *Policy) inDictionary(pwd string) (bool, error) {
+	content, err := ioutil.ReadFile(p.DictionaryFile)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	words := make(map[string]struct{})
+	for _, line := range strings.Split(string(content), "\n") {
+		word := strings.ToLower(strings.TrimSpace(line))
+		if utf8.RuneCountInString(word) >= minDictWordLength {
+			words[word] = struct{}{}
+		}
+	}
+	runes := []rune(strings.ToLower(pwd))
+	for i := 0; i < len(runes); i++ {
+		for j := i + minDictWordLength; j <= len(runes); j++ {
+			if _, ok := words[string(runes[i:j])]; ok {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// matchUserName returns true if the password is the user name or the user name reversed.
+func matchUserName(pwd, user string) bool {
+	if user == "" {
+		return false
+	}
+	return strings.EqualFold(pwd, user) || strings.EqualFold(pwd, stringutil.Reverse(user))
+}
@@ -0,0 +1,121 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pwdvalidate
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	CustomVerboseFlag = true
+	TestingT(t)
+}
+
+var _ = Suite(&testPwdValidateSuite{})
+
+type testPwdValidateSuite struct {
+}
+
+func defaultPolicy() *Policy {
+	return &Policy{
+		Level:            PolicyMedium,
+		Length:           8,
+		MixedCaseCount:   1,
+		NumberCount:      1,
+		SpecialCharCount: 1,
+	}
+}
+
+func (s *testPwdValidateSuite) TestValidate(c *C) {
+	defer testleak.AfterTest(c)()
+	p := defaultPolicy()
+	table := []struct {
+		pwd    string
+		user   string
+		level  int
+		expect bool
+	}{
+		{"abcd", "", PolicyLow, false},
+		{"abcdefgh", "", PolicyLow, true},
+		{"abcdefgh", "", PolicyMedium, false},
+		{"Abcdef1!", "", PolicyMedium, true},
+		{"abcdef1!", "", PolicyMedium, false},
+		{"Abcdefg!", "", PolicyMedium, false},
+		{"Abcdefg1", "", PolicyMedium, false},
+		{"Abcdef1!", "Abcdef1!", PolicyMedium, false},
+		{"!1fedcbA", "Abcdef1!", PolicyMedium, false},
+	}
+	for _, t := range table {
+		p.Level = t.level
+		ok, err := p.Validate(t.pwd, t.user)
+		c.Assert(err, IsNil)
+		c.Assert(ok, Equals, t.expect, Commentf("%v", t))
+	}
+
+	// The effective length is never less than the required characters.
+	p = defaultPolicy()
+	p.Length = 4
+	p.NumberCount = 3
+	ok, err := p.Validate("Ab1!", "")
+	c.Assert(err, IsNil)
+	c.Assert(ok, IsFalse)
+	ok, err = p.Validate("Ab123!", "")
+	c.Assert(err, IsNil)
+	c.Assert(ok, IsTrue)
+}
+
+func (s *testPwdValidateSuite) TestStrength(c *C) {
+	defer testleak.AfterTest(c)()
+	f, err := ioutil.TempFile("", "pwd_dict")
+	c.Assert(err, IsNil)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("password\nqwerty\n")
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	p := defaultPolicy()
+	p.DictionaryFile = f.Name()
+	table := []struct {
+		pwd    string
+		user   string
+		expect int
+	}{
+		{"abc", "", 0},
+		{"abcd", "", 25},
+		{"abcdefgh", "", 50},
+		{"Password1!", "", 75},
+		{"Qwerty12#x", "", 75},
+		{"Sx7!kq2#Lm", "", 100},
+		{"Sx7!kq2#Lm", "Sx7!kq2#Lm", 0},
+	}
+	for _, t := range table {
+		strength, err := p.Strength(t.pwd, t.user)
+		c.Assert(err, IsNil)
+		c.Assert(strength, Equals, t.expect, Commentf("%v", t))
+	}
+
+	p.DictionaryFile = ""
+	strength, err := p.Strength("Password1!", "")
+	c.Assert(err, IsNil)
+	c.Assert(strength, Equals, 100)
+
+	p.DictionaryFile = f.Name() + ".not_exist"
+	_, err = p.Strength("Password1!", "")
+	c.Assert(err, NotNil)
+}
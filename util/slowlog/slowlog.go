@@ -0,0 +1,279 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package slowlog writes and parses the slow query log.
+//
+// Every entry of the log starts with a "# Time:" line, followed by the "# Key: value" lines of the other fields,
+// and ends with the SQL text terminated by a semicolon, the SQL text may span multiple lines. For example:
+//
+//	# Time: 2017-06-01T10:00:00.000000+08:00
+//	# Conn_ID: 1
+//	# User: root@127.0.0.1
+//	# DB: test
+//	# Query_time: 1.200000
+//	# Parse_time: 0.000100
+//	# Compile_time: 0.000200
+//	# Execute_time: 1.199700
+//	# Commit_time: 0.000000
+//	# Process_keys: 1000
+//	# Cop_tasks: 2
+//	# Plan_digest: 0f2d...
+//	select * from t where a > 1;
+package slowlog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+// Field names of the slow query log.
+const (
+	TimeField        = "Time"
+	ConnIDField      = "Conn_ID"
+	UserField        = "User"
+	DBField          = "DB"
+	QueryTimeField   = "Query_time"
+	ParseTimeField   = "Parse_time"
+	CompileTimeField = "Compile_time"
+	ExecuteTimeField = "Execute_time"
+	CommitTimeField  = "Commit_time"
+	ProcessKeysField = "Process_keys"
+	CopTasksField    = "Cop_tasks"
+	PlanDigestField  = "Plan_digest"
+)
+
+const (
+	fieldPrefix = "# "
+	timeFormat  = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// Entry is a slow query log entry.
+type Entry struct {
+	Time        time.Time
+	ConnID      uint64
+	User        string
+	DB          string
+	QueryTime   time.Duration
+	ParseTime   time.Duration
+	CompileTime time.Duration
+	ExecuteTime time.Duration
+	CommitTime  time.Duration
+	ProcessKeys int64
+	CopTasks    int64
+	PlanDigest  string
+	SQL         string
+}
+
+// String formats the entry in the slow query log format.
+func (e *Entry) String() string {
+	var buf bytes.Buffer
+	writeField := func(name string, value interface{}) {
+		fmt.Fprintf(&buf, "%s%s: %v\n", fieldPrefix, name, value)
+	}
+	writeField(TimeField, e.Time.Format(timeFormat))
+	writeField(ConnIDField, e.ConnID)
+	writeField(UserField, e.User)
+	writeField(DBField, e.DB)
+	writeField(QueryTimeField, formatSeconds(e.QueryTime))
+	writeField(ParseTimeField, formatSeconds(e.ParseTime))
+	writeField(CompileTimeField, formatSeconds(e.CompileTime))
+	writeField(ExecuteTimeField, formatSeconds(e.ExecuteTime))
+	writeField(CommitTimeField, formatSeconds(e.CommitTime))
+	writeField(ProcessKeysField, e.ProcessKeys)
+	writeField(CopTasksField, e.CopTasks)
+	writeField(PlanDigestField, e.PlanDigest)
+	buf.WriteString(e.SQL)
+	if !strings.HasSuffix(e.SQL, ";") {
+		buf.WriteByte(';')
+	}
+	buf.WriteByte('\n')
+	return buf.String()
+}
+
+var (
+	mu   sync.Mutex
+	path string
+	file *os.File
+)
+
+// SetFile sets the path of the slow query log file. The slow queries are written to the general log
+// if the path is empty.
+func SetFile(p string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		file.Close()
+		file = nil
+	}
+	path = p
+	if p == "" {
+		return nil
+	}
+	var err error
+	file, err = os.OpenFile(p, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	return errors.Trace(err)
+}
+
+// File returns the path of the slow query log file, it's empty if the slow queries are written to the general log.
+func File() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return path
+}
+
+// Write writes the entry to the slow query log.
+func Write(e *Entry) {
+	mu.Lock()
+	defer mu.Unlock()
+	if file == nil {
+		log.Warnf("[SLOW_QUERY]\n%s", e)
+		return
+	}
+	if _, err := file.WriteString(e.String()); err != nil {
+		log.Errorf("write slow query log error: %v", err)
+	}
+}
+
+// ParseFile parses the entries of the slow query log file. It returns nil if the slow queries are written
+// to the general log.
+func ParseFile() ([]*Entry, error) {
+	p := File()
+	if p == "" {
+		return nil, nil
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse parses the entries of the slow query log. The lines before the first "# Time:" line, the unknown
+// fields and the fields with invalid values are ignored.
+//
+// The SQL text starts after the "# Plan_digest:" line, which is the last field of an entry, or at the first line
+// which isn't a field, so the SQL lines starting with "# " are not taken as fields. The SQL text ends at the line
+// terminated by a semicolon which is followed by a "# Time:" line.
+func Parse(r io.Reader) ([]*Entry, error) {
+	var (
+		entries  []*Entry
+		e        *Entry
+		sqlLines []string
+		inSQL    bool
+	)
+	finishEntry := func() {
+		if e != nil {
+			e.SQL = strings.TrimSuffix(strings.Join(sqlLines, "\n"), ";")
+			entries = append(entries, e)
+		}
+		sqlLines = sqlLines[:0]
+		inSQL = false
+	}
+	scanner := bufio.NewScanner(r)
+	// The SQL text can be very long.
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, fieldPrefix+TimeField+": ") && (!inSQL || sqlEnded(sqlLines)) {
+			finishEntry()
+			e = &Entry{}
+		}
+		if e == nil {
+			continue
+		}
+		if inSQL || !strings.HasPrefix(line, fieldPrefix) {
+			inSQL = true
+			sqlLines = append(sqlLines, line)
+			continue
+		}
+		fields := strings.SplitN(line[len(fieldPrefix):], ": ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		if fields[0] == PlanDigestField {
+			inSQL = true
+		}
+		if err := e.setField(fields[0], fields[1]); err != nil {
+			// Skip the broken field, the other fields of the entry are still useful.
+			log.Warn(err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	finishEntry()
+	return entries, nil
+}
+
+// sqlEnded checks whether the SQL text of an entry is terminated.
+func sqlEnded(sqlLines []string) bool {
+	return len(sqlLines) > 0 && strings.HasSuffix(sqlLines[len(sqlLines)-1], ";")
+}
+
+func (e *Entry) setField(name, value string) error {
+	var err error
+	switch name {
+	case TimeField:
+		e.Time, err = time.Parse(timeFormat, value)
+	case ConnIDField:
+		e.ConnID, err = strconv.ParseUint(value, 10, 64)
+	case UserField:
+		e.User = value
+	case DBField:
+		e.DB = value
+	case QueryTimeField:
+		e.QueryTime, err = parseSeconds(value)
+	case ParseTimeField:
+		e.ParseTime, err = parseSeconds(value)
+	case CompileTimeField:
+		e.CompileTime, err = parseSeconds(value)
+	case ExecuteTimeField:
+		e.ExecuteTime, err = parseSeconds(value)
+	case CommitTimeField:
+		e.CommitTime, err = parseSeconds(value)
+	case ProcessKeysField:
+		e.ProcessKeys, err = strconv.ParseInt(value, 10, 64)
+	case CopTasksField:
+		e.CopTasks, err = strconv.ParseInt(value, 10, 64)
+	case PlanDigestField:
+		e.PlanDigest = value
+	}
+	if err != nil {
+		return errors.Errorf("invalid slow query log field %s: %s", name, value)
+	}
+	return nil
+}
+
+// formatSeconds formats the duration in seconds with microsecond precision.
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 6, 64)
+}
+
+func parseSeconds(value string) (time.Duration, error) {
+	sec, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return time.Duration(sec * float64(time.Second)), nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slowlog
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testSlowLogSuite{})
+
+type testSlowLogSuite struct{}
+
+func (s *testSlowLogSuite) TestParse(c *C) {
+	t, err := time.Parse(timeFormat, "2017-06-01T10:00:00.123456+08:00")
+	c.Assert(err, IsNil)
+	e1 := &Entry{
+		Time:        t,
+		ConnID:      1,
+		User:        "root@127.0.0.1",
+		DB:          "test",
+		QueryTime:   1200 * time.Millisecond,
+		ParseTime:   100 * time.Microsecond,
+		CompileTime: 200 * time.Microsecond,
+		ExecuteTime: 1199700 * time.Microsecond,
+		ProcessKeys: 1000,
+		CopTasks:    2,
+		PlanDigest:  "abc",
+		SQL:         "select * from t\nwhere a > 1",
+	}
+	e2 := &Entry{
+		Time:       t.Add(time.Second),
+		ConnID:     2,
+		QueryTime:  time.Second,
+		CommitTime: 500 * time.Millisecond,
+		SQL:        "insert into t values (1)",
+	}
+	content := "garbage before the first entry\n" + e1.String() + e2.String()
+	c.Assert(strings.Count(content, "# Time: "), Equals, 2)
+
+	entries, err := Parse(strings.NewReader(content))
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 2)
+	c.Assert(entries[0].Time.Equal(e1.Time), IsTrue)
+	entries[0].Time = e1.Time
+	c.Assert(entries[0], DeepEquals, e1)
+	c.Assert(entries[1].Time.Equal(e2.Time), IsTrue)
+	entries[1].Time = e2.Time
+	c.Assert(entries[1], DeepEquals, e2)
+
+	// The invalid fields are skipped.
+	content = strings.Replace(e2.String(), "# Conn_ID: 2", "# Conn_ID: x", 1)
+	entries, err = Parse(strings.NewReader(content))
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 1)
+	c.Assert(entries[0].ConnID, Equals, uint64(0))
+	c.Assert(entries[0].CommitTime, Equals, e2.CommitTime)
+	c.Assert(entries[0].SQL, Equals, e2.SQL)
+
+	// The SQL lines starting with "# " are not fields.
+	e3 := &Entry{
+		Time:       t.Add(2 * time.Second),
+		ConnID:     3,
+		PlanDigest: "def",
+		SQL:        "# Conn_ID: 4\nselect 1",
+	}
+	e4 := &Entry{
+		Time:   t.Add(3 * time.Second),
+		ConnID: 4,
+		SQL:    "select '\n# Time: 2017-06-01T10:00:00.123456+08:00\n'",
+	}
+	entries, err = Parse(strings.NewReader(e3.String() + e4.String()))
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 2)
+	c.Assert(entries[0].ConnID, Equals, e3.ConnID)
+	c.Assert(entries[0].PlanDigest, Equals, e3.PlanDigest)
+	c.Assert(entries[0].SQL, Equals, e3.SQL)
+	c.Assert(entries[1].ConnID, Equals, e4.ConnID)
+	c.Assert(entries[1].SQL, Equals, e4.SQL)
+}
+
+func (s *testSlowLogSuite) TestFile(c *C) {
+	f, err := ioutil.TempFile("", "tidb-slow")
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+	defer os.Remove(f.Name())
+
+	c.Assert(SetFile(f.Name()), IsNil)
+	defer SetFile("")
+	c.Assert(File(), Equals, f.Name())
+	Write(&Entry{Time: time.Now(), ConnID: 1, SQL: "select 1"})
+	Write(&Entry{Time: time.Now(), ConnID: 2, SQL: "select 2;"})
+	entries, err := ParseFile()
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 2)
+	c.Assert(entries[0].SQL, Equals, "select 1")
+	c.Assert(entries[1].SQL, Equals, "select 2")
+
+	c.Assert(SetFile(""), IsNil)
+	entries, err = ParseFile()
+	c.Assert(err, IsNil)
+	c.Assert(entries, IsNil)
+}